@@ -0,0 +1,256 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// BO2 and BO3 are the text formats of the custom objects spawned by the
+// terrain generators TerrainControl and OpenTerrainGenerator. A BO3 file
+// consists of settings ("Author: somebody") and functions placing blocks
+// ("Block(0,1,-2,WOOL:14)"). Coordinates are relative to the spawn point
+// of the object and may be negative; ReadBO3 stores the position of the
+// minimum corner in the WorldEdit offset and WriteBO3 adds it back.
+
+// bo3Block is a placed block of a BO2 or BO3 object.
+type bo3Block struct {
+	x, y, z int
+	b       Block
+}
+
+// ReadBO3 reads an OpenTerrainGenerator object. The settings are stored in
+// the BO3Settings tag of Extra and the functions other than Block, such as
+// RandomBlock or Branch, in the BO3Functions tag. WriteBO3 writes both back
+// unchanged. The NBT files referenced by Block functions are not loaded.
+func ReadBO3(r io.Reader) (s *Schematic, err os.Error) {
+	settings := new(nbt.Compound)
+	other := &nbt.List{ElemType: nbt.TagString}
+	var blocks []bo3Block
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, rerr := br.ReadString('\n')
+		if rerr != nil && rerr != os.EOF {
+			return nil, rerr
+		}
+		if line = strings.TrimSpace(line); line != "" && line[0] != '#' {
+			if strings.HasPrefix(line, "Block(") && strings.HasSuffix(line, ")") {
+				var bl bo3Block
+				if bl, err = parseBO3Block(line[len("Block(") : len(line)-1]); err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNo, err)
+				}
+				blocks = append(blocks, bl)
+			} else if i := strings.Index(line, ":"); i > 0 && !strings.Contains(line[:i], "(") {
+				settings.Set(strings.TrimSpace(line[:i]), nbt.String(strings.TrimSpace(line[i+1:])))
+			} else {
+				other.Tags = append(other.Tags, nbt.String(line))
+			}
+		}
+		if rerr == os.EOF {
+			break
+		}
+	}
+	if s, err = fromPlacedBlocks(blocks); err != nil {
+		return nil, err
+	}
+	if settings.Len() > 0 {
+		s.Extra.Set("BO3Settings", settings)
+	}
+	if other.Len() > 0 {
+		s.Extra.Set("BO3Functions", other)
+	}
+	return
+}
+
+// parseBO3Block parses the arguments of a Block function: x,y,z,material[,nbtfile].
+func parseBO3Block(args string) (bl bo3Block, err os.Error) {
+	parts := strings.Split(args, ",")
+	if len(parts) < 4 {
+		return bl, fmt.Errorf("Block needs 4 arguments. Got: %s", args)
+	}
+	var v [3]int
+	for i := range v {
+		if v[i], err = strconv.Atoi(strings.TrimSpace(parts[i])); err != nil {
+			return
+		}
+	}
+	if bl.b, err = parseMaterial(strings.TrimSpace(parts[3])); err != nil {
+		return
+	}
+	bl.x, bl.y, bl.z = v[0], v[1], v[2]
+	return
+}
+
+// parseMaterial parses the materials accepted by OpenTerrainGenerator:
+// a numeric id or a block name, optionally followed by ":data", such as
+// "35:14", "WOOL:14", "minecraft:wool:14" or "red_wool".
+func parseMaterial(str string) (b Block, err os.Error) {
+	b, _, err = parseBlockSpec(str)
+	return
+}
+
+// parseBlockSpec is like parseMaterial, but also reports whether the
+// material leaves the data value open, as "35" and "wool" do and "35:0"
+// and "white_wool" do not.
+func parseBlockSpec(str string) (b Block, anyData bool, err os.Error) {
+	name, data := strings.ToLower(str), -1
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		if d, err := strconv.Atoi(name[i+1:]); err == nil {
+			name, data = name[:i], d
+		}
+	}
+	if data < -1 || data > 15 {
+		return b, false, fmt.Errorf("Invalid data value in %s", str)
+	}
+	if id, err := strconv.Atoi(name); err == nil {
+		if id < 0 || id > 255 {
+			return b, false, fmt.Errorf("Invalid block id in %s", str)
+		}
+		b.Id, anyData = uint16(id), true
+	} else {
+		if !strings.Contains(name, ":") {
+			name = "minecraft:" + name
+		}
+		id, ok := vanillaMapping[name]
+		if ok {
+			b.Id, anyData = id, true
+		} else if b, ok = FromState(name); !ok {
+			return b, false, fmt.Errorf("Unknown material: %s", str)
+		}
+	}
+	if data >= 0 {
+		b.Data, anyData = byte(data), false
+	}
+	return
+}
+
+// fromPlacedBlocks returns a schematic enclosing the blocks. It fails if
+// the blocks are too far apart to fit into a schematic.
+func fromPlacedBlocks(blocks []bo3Block) (*Schematic, os.Error) {
+	if len(blocks) == 0 {
+		return NewSchematic(0, 0, 0), nil
+	}
+	b := Box{blocks[0].x, blocks[0].y, blocks[0].z, blocks[0].x + 1, blocks[0].y + 1, blocks[0].z + 1}
+	for _, bl := range blocks {
+		b.MinX, b.MaxX = imin(b.MinX, bl.x), imax(b.MaxX, bl.x+1)
+		b.MinY, b.MaxY = imin(b.MinY, bl.y), imax(b.MaxY, bl.y+1)
+		b.MinZ, b.MaxZ = imin(b.MinZ, bl.z), imax(b.MaxZ, bl.z+1)
+	}
+	if err := checkDimensions(b.MaxX-b.MinX, b.MaxY-b.MinY, b.MaxZ-b.MinZ); err != nil {
+		return nil, err
+	}
+	s := NewSchematic(b.MaxX-b.MinX, b.MaxY-b.MinY, b.MaxZ-b.MinZ)
+	s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = b.MinX, b.MinY, b.MinZ
+	for _, bl := range blocks {
+		s.SetBlock(bl.x-b.MinX, bl.y-b.MinY, bl.z-b.MinZ, bl.b)
+	}
+	return s, nil
+}
+
+// ReadBO2 reads a TerrainControl BO2 object. The [META] section is ignored,
+// the [DATA] section lists the blocks as "x,y,z:id.data" with z pointing up.
+func ReadBO2(r io.Reader) (s *Schematic, err os.Error) {
+	var blocks []bo3Block
+	data := false
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, rerr := br.ReadString('\n')
+		if rerr != nil && rerr != os.EOF {
+			return nil, rerr
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || line[0] == '#':
+		case line[0] == '[':
+			data = line == "[DATA]"
+		case data:
+			var bl bo3Block
+			if bl, err = parseBO2Block(line); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			blocks = append(blocks, bl)
+		}
+		if rerr == os.EOF {
+			break
+		}
+	}
+	return fromPlacedBlocks(blocks)
+}
+
+// parseBO2Block parses "x,y,z:id" or "x,y,z:id.data".
+func parseBO2Block(line string) (bl bo3Block, err os.Error) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return bl, fmt.Errorf("Invalid block: %s", line)
+	}
+	coords := strings.Split(line[:i], ",")
+	if len(coords) != 3 {
+		return bl, fmt.Errorf("Invalid block: %s", line)
+	}
+	var v [3]int
+	for j := range v {
+		if v[j], err = strconv.Atoi(strings.TrimSpace(coords[j])); err != nil {
+			return
+		}
+	}
+	// BO2 coordinates are x, z, y with y pointing up.
+	bl.x, bl.z, bl.y = v[0], v[1], v[2]
+	if bl.b, err = parseMaterial(strings.Replace(line[i+1:], ".", ":", 1)); err != nil {
+		return
+	}
+	return
+}
+
+// WriteBO3 writes s as an OpenTerrainGenerator object. Air is not written,
+// so the object does not carve into the terrain. The blocks are named by
+// their Minecraft 1.12 registry names.
+func WriteBO3(w io.Writer, s *Schematic) (err os.Error) {
+	bw := bufio.NewWriter(w)
+	extra := s.Extra
+	if extra == nil {
+		extra = new(nbt.Compound)
+	}
+	settings, _ := extra.Get("BO3Settings").(*nbt.Compound)
+	if settings == nil || settings.Get("Version") == nil {
+		fmt.Fprintf(bw, "Version: 3\n")
+	}
+	if settings != nil {
+		for _, f := range settings.Fields {
+			v, _ := f.Tag.(nbt.String)
+			fmt.Fprintf(bw, "%s: %s\n", f.Name, v)
+		}
+	}
+	if other, ok := extra.Get("BO3Functions").(*nbt.List); ok {
+		for _, tag := range other.Tags {
+			v, _ := tag.(nbt.String)
+			fmt.Fprintf(bw, "%s\n", v)
+		}
+	}
+	for y := 0; y < s.YLen(); y++ {
+		for z := 0; z < s.ZLen(); z++ {
+			for x := 0; x < s.XLen(); x++ {
+				b := s.Block(x, y, z)
+				if b.Id == 0 {
+					continue
+				}
+				material := strconv.Itoa(int(b.Id))
+				if b.Id < uint16(len(registryNames)) && registryNames[b.Id] != "" {
+					material = strings.ToUpper(registryNames[b.Id])
+				}
+				if b.Data != 0 {
+					material += ":" + strconv.Itoa(int(b.Data))
+				}
+				fmt.Fprintf(bw, "Block(%d,%d,%d,%s)\n", x+s.WEOffsetX, y+s.WEOffsetY, z+s.WEOffsetZ, material)
+			}
+		}
+	}
+	return bw.Flush()
+}
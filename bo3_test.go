@@ -0,0 +1,106 @@
+package schematic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testBO3 = `# A small tower
+Author: somebody
+Description: A red tower
+Version: 3
+SpawnHeight: highest
+
+RandomBlock(0,3,0,CHEST,chest.nbt,50)
+Block(-1,0,0,COBBLESTONE)
+Block(0,0,0,minecraft:wool:14)
+Block(0,1,0,35:14)
+Block(0,2,0,red_wool)
+Block(1,2,-1,STONE:5)
+`
+
+func TestReadBO3(t *testing.T) {
+	s, err := ReadBO3(strings.NewReader(testBO3))
+	if err != nil {
+		t.Fatalf("ReadBO3: %v", err)
+	}
+	if s.Width != 3 || s.Height != 3 || s.Length != 2 {
+		t.Fatalf("Size: want 3x3x2, got %dx%dx%d", s.Width, s.Height, s.Length)
+	}
+	if s.WEOffsetX != -1 || s.WEOffsetY != 0 || s.WEOffsetZ != -1 {
+		t.Errorf("Offset: want -1 0 -1, got %d %d %d", s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ)
+	}
+	red := Block{35, 14}
+	for _, tt := range []struct {
+		x, y, z int
+		b       Block
+	}{{0, 0, 1, Block{4, 0}}, {1, 0, 1, red}, {1, 1, 1, red}, {1, 2, 1, red}, {2, 2, 0, Block{1, 5}}} {
+		if got := s.Block(tt.x, tt.y, tt.z); got != tt.b {
+			t.Errorf("Block(%d, %d, %d): want %v, got %v", tt.x, tt.y, tt.z, tt.b, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = WriteBO3(&buf, s); err != nil {
+		t.Fatalf("WriteBO3: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Author: somebody\n", "Version: 3\n", "RandomBlock(0,3,0,CHEST,chest.nbt,50)\n", "Block(-1,0,0,COBBLESTONE)\n", "Block(0,1,0,WOOL:14)\n", "Block(1,2,-1,STONE:5)\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteBO3: output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "Version") != 1 {
+		t.Errorf("WriteBO3: Version is written twice:\n%s", out)
+	}
+	back, err := ReadBO3(&buf)
+	if err != nil {
+		t.Fatalf("ReadBO3: %v", err)
+	}
+	if back.Fingerprint() != s.Fingerprint() {
+		t.Errorf("Round trip changed the blocks")
+	}
+
+	for _, bad := range []string{"Block(0,0,UNOBTAINIUM)", "Block(0,0,0,UNOBTAINIUM)", "Block(0,0,0,300)", "Block(x,0,0,STONE)",
+		"Block(0,0,0,STONE)\nBlock(2000000,2000000,2000000,STONE)"} {
+		if _, err = ReadBO3(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadBO3(%q): want error, got nil", bad)
+		}
+	}
+}
+
+func TestReadBO2(t *testing.T) {
+	in := "[META]\nversion=2.0\n[DATA]\n0,0,0:2\n0,0,1:35.14\n1,2,1:4.0\n"
+	s, err := ReadBO2(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadBO2: %v", err)
+	}
+	if s.Width != 2 || s.Height != 2 || s.Length != 3 {
+		t.Fatalf("Size: want 2x2x3, got %dx%dx%d", s.Width, s.Height, s.Length)
+	}
+	if s.Block(0, 1, 0) != (Block{35, 14}) || s.Block(1, 1, 2) != (Block{4, 0}) {
+		t.Errorf("Blocks: %v, data: %v", s.Blocks, s.Data)
+	}
+	if _, err = ReadBO2(strings.NewReader("[DATA]\n0,0:1\n")); err == nil {
+		t.Errorf("ReadBO2 with 2 coordinates: want error, got nil")
+	}
+	if _, err = ReadBO2(strings.NewReader("[DATA]\n0,0,0:1\n0,0,100000:1\n")); err == nil {
+		t.Errorf("ReadBO2 of a block 100000 high: want error, got nil")
+	}
+}
+
+func TestWriteBO3ModdedId(t *testing.T) {
+	s := NewSchematic(2, 1, 1)
+	s.SetBlock(0, 0, 0, Block{300, 2})
+	s.SetBlock(1, 0, 0, Block{1, 0})
+	var buf bytes.Buffer
+	if err := WriteBO3(&buf, s); err != nil {
+		t.Fatalf("WriteBO3: %v", err)
+	}
+	for _, want := range []string{"Block(0,0,0,300:2)\n", "Block(1,0,0,STONE)\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteBO3: no %q in\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// BookletOptions control WriteBooklet. A nil *BookletOptions selects the
+// defaults.
+type BookletOptions struct {
+	// Scale is the size of a block in the layer diagrams in CSS pixels.
+	// Default: 16.
+	Scale int
+
+	// SkipEmpty leaves out the layers of air.
+	SkipEmpty bool
+
+	// Language is the language of the names of the blocks. Default:
+	// English.
+	Language Language
+}
+
+// bookletStyle is the style sheet of the booklets. Every layer starts a
+// new page when printed.
+const bookletStyle = `body { font-family: sans-serif; }
+.page { page-break-after: always; margin-bottom: 2em; }
+table { border-collapse: collapse; margin-top: 1em; }
+td, th { border: 1px solid #999; padding: 2px 8px; text-align: right; }
+td.name { text-align: left; }
+.swatch { display: inline-block; width: 0.8em; height: 0.8em; border: 1px solid #000; margin-right: 0.4em; }
+`
+
+// WriteBooklet writes an HTML booklet of instructions to build s layer by
+// layer, from the bottom up, which may be printed as a PDF from a
+// browser. The first page lists the materials of the whole build. Every
+// next page shows a layer as seen from above, drawn by WriteSliceSVG, with
+// the materials of the layer and the running totals of the layers so far.
+func WriteBooklet(w io.Writer, s *Schematic, opt *BookletOptions) os.Error {
+	o := BookletOptions{Scale: 16}
+	if opt != nil {
+		o = *opt
+		if o.Scale <= 0 {
+			o.Scale = 16
+		}
+	}
+	title := s.Metadata().Name
+	if title == "" {
+		title = "Schematic"
+	}
+	layers := make([]map[Block]int, s.Height)
+	total := make(map[Block]int)
+	for y := range layers {
+		layers[y] = make(map[Block]int)
+		for z := 0; z < s.Length; z++ {
+			for x := 0; x < s.Width; x++ {
+				if b := s.Block(x, y, z); b != (Block{}) {
+					layers[y][b]++
+					total[b]++
+				}
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n",
+		htmlEscape(title), bookletStyle)
+	fmt.Fprintf(bw, "<div class=\"page\">\n<h1>%s</h1>\n<p>%d×%d×%d blocks, %d layers.</p>\n<h2>Materials</h2>\n",
+		htmlEscape(title), s.Width, s.Height, s.Length, s.Height)
+	writeMaterials(bw, total, nil, o.Language)
+	fmt.Fprintf(bw, "</div>\n")
+
+	done := make(map[Block]int)
+	var svg bytes.Buffer
+	for y, counts := range layers {
+		if o.SkipEmpty && len(counts) == 0 {
+			continue
+		}
+		for b, n := range counts {
+			done[b] += n
+		}
+		svg.Reset()
+		if err := WriteSliceSVG(&svg, s, AxisY, y, &SVGOptions{Scale: o.Scale, Grid: true, Language: o.Language}); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "<div class=\"page\">\n<h2>Layer %d of %d</h2>\n", y+1, s.Height)
+		bw.Write(svg.Bytes())
+		writeMaterials(bw, counts, done, o.Language)
+		fmt.Fprintf(bw, "</div>\n")
+	}
+	fmt.Fprintf(bw, "</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// writeMaterials writes the table of the block counts, with the running
+// totals if done is not nil.
+func writeMaterials(bw *bufio.Writer, counts, done map[Block]int, lang Language) {
+	var blocks []Block
+	for b := range counts {
+		blocks = append(blocks, b)
+	}
+	sort.Sort(blockSlice(blocks))
+	fmt.Fprintf(bw, "<table>\n<tr><th>Block</th><th>Count</th>")
+	if done != nil {
+		fmt.Fprintf(bw, "<th>Placed so far</th>")
+	}
+	fmt.Fprintf(bw, "</tr>\n")
+	for _, b := range blocks {
+		fmt.Fprintf(bw, "<tr><td class=\"name\"><span class=\"swatch\" style=\"background: %s\"></span>%s</td><td>%d</td>",
+			svgColor(b), htmlEscape(lang.DisplayName(b)), counts[b])
+		if done != nil {
+			fmt.Fprintf(bw, "<td>%d</td>", done[b])
+		}
+		fmt.Fprintf(bw, "</tr>\n")
+	}
+	fmt.Fprintf(bw, "</table>\n")
+}
+
+// htmlEscape escapes the special characters of HTML in str.
+func htmlEscape(str string) string {
+	var buf bytes.Buffer
+	for _, r := range str {
+		switch r {
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '&':
+			buf.WriteString("&amp;")
+		case '"':
+			buf.WriteString("&quot;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,45 @@
+package schematic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteBooklet(t *testing.T) {
+	// Two layers of stone with a block of wool on top, and air above.
+	s := NewSchematic(2, 4, 1)
+	for y := 0; y < 2; y++ {
+		s.SetBlock(0, y, 0, Block{1, 0})
+		s.SetBlock(1, y, 0, Block{1, 0})
+	}
+	s.SetBlock(0, 2, 0, Block{35, 14})
+	s.SetMetadata(Metadata{Name: "Tower <1>"})
+	var buf bytes.Buffer
+	if err := WriteBooklet(&buf, s, &BookletOptions{SkipEmpty: true}); err != nil {
+		t.Fatalf("WriteBooklet: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{
+		"<title>Tower &lt;1&gt;</title>",
+		"<h2>Layer 1 of 4</h2>",
+		"<h2>Layer 3 of 4</h2>",
+		"<svg ",
+		// The materials of the whole build, then of the layers with the
+		// running totals.
+		"</span>Stone</td><td>4</td></tr>",
+		"</span>Stone</td><td>2</td><td>2</td></tr>",
+		"</span>Stone</td><td>2</td><td>4</td></tr>",
+		"</span>Red Wool</td><td>1</td><td>1</td></tr>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("WriteBooklet: want %q in\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "Layer 4 of 4") {
+		t.Errorf("WriteBooklet: the layer of air is not skipped")
+	}
+	if n := strings.Count(html, `<div class="page">`); n != 4 {
+		t.Errorf("WriteBooklet: want 4 pages, got %d", n)
+	}
+}
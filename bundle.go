@@ -0,0 +1,161 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"json"
+	"os"
+	"strings"
+)
+
+// BundleExt is the file name extension of schematic bundles.
+const BundleExt = ".schempack"
+
+// bundleManifest is the name of the manifest in a bundle.
+const bundleManifest = "manifest.json"
+
+// A Bundle is a set of schematics shipped as a single file, such as the
+// parts of a large project. The file is a tar archive with a JSON manifest
+// followed by the parts stored as ordinary .schematic files, so the
+// archive can also be unpacked with standard tools.
+type Bundle struct {
+	Metadata map[string]string
+	Parts    []*BundlePart
+}
+
+// A BundlePart is a schematic of a bundle with its placement.
+type BundlePart struct {
+	// Name identifies the part; the file of the part in the archive is
+	// Name + Ext. It must not contain slashes.
+	Name string
+
+	// X, Y and Z are the position of the part in the project.
+	X, Y, Z int
+
+	Metadata  map[string]string
+	Schematic *Schematic
+}
+
+// bundleVersion is the version of the manifest written by WriteBundle.
+const bundleVersion = 1
+
+// manifest is the JSON form of a bundle without the schematics.
+type manifest struct {
+	Version  int               `json:"version"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Parts    []manifestPart    `json:"parts"`
+}
+
+type manifestPart struct {
+	Name     string            `json:"name"`
+	X        int               `json:"x"`
+	Y        int               `json:"y"`
+	Z        int               `json:"z"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// WriteBundle writes the bundle to w.
+func WriteBundle(w io.Writer, b *Bundle) os.Error {
+	return WriteBundleOptions(w, b, nil)
+}
+
+// WriteBundleOptions is like WriteBundle but writes the parts with the
+// options, as WriteSchematicOptions does.
+func WriteBundleOptions(w io.Writer, b *Bundle, opt *WriteOptions) (err os.Error) {
+	names := make(map[string]bool)
+	for _, p := range b.Parts {
+		if p.Name == "" || p.Name == "." || p.Name == ".." || strings.ContainsAny(p.Name, "/\\") {
+			return fmt.Errorf("Invalid part name: '%s'", p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("Duplicate part name: %s", p.Name)
+		}
+		if p.Schematic == nil {
+			return fmt.Errorf("Part %s has no schematic", p.Name)
+		}
+		names[p.Name] = true
+	}
+	m := &manifest{Version: bundleVersion, Metadata: b.Metadata}
+	for _, p := range b.Parts {
+		m.Parts = append(m.Parts, manifestPart{p.Name, p.X, p.Y, p.Z, p.Metadata})
+	}
+	tw := tar.NewWriter(w)
+	var data []byte
+	if data, err = json.MarshalIndent(m, "", "  "); err != nil {
+		return
+	}
+	if err = writeTarFile(tw, bundleManifest, data); err != nil {
+		return
+	}
+	for _, p := range b.Parts {
+		var buf bytes.Buffer
+		if err = WriteSchematicOptions(&buf, p.Schematic, opt); err != nil {
+			return fmt.Errorf("Part %s: %v", p.Name, err)
+		}
+		if err = writeTarFile(tw, p.Name+Ext, buf.Bytes()); err != nil {
+			return
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) (err os.Error) {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		Typeflag: tar.TypeReg,
+	}
+	if err = tw.WriteHeader(hdr); err != nil {
+		return
+	}
+	_, err = tw.Write(data)
+	return
+}
+
+// ReadBundle reads a bundle written by WriteBundle.
+func ReadBundle(r io.Reader) (b *Bundle, err os.Error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		var hdr *tar.Header
+		if hdr, err = tr.Next(); err == os.EOF {
+			break
+		} else if err != nil {
+			return
+		}
+		if files[hdr.Name], err = ioutil.ReadAll(tr); err != nil {
+			return
+		}
+	}
+	data, ok := files[bundleManifest]
+	if !ok {
+		return nil, fmt.Errorf("Not a schematic bundle: %s is missing", bundleManifest)
+	}
+	m := new(manifest)
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %v", bundleManifest, err)
+	}
+	if m.Version < 1 || m.Version > bundleVersion {
+		return nil, fmt.Errorf("Unsupported bundle version: %d", m.Version)
+	}
+	b = &Bundle{Metadata: m.Metadata}
+	for _, mp := range m.Parts {
+		p := &BundlePart{Name: mp.Name, X: mp.X, Y: mp.Y, Z: mp.Z, Metadata: mp.Metadata}
+		data, ok := files[p.Name+Ext]
+		if !ok {
+			return nil, fmt.Errorf("Part %s: %s is missing", p.Name, p.Name+Ext)
+		}
+		if p.Schematic, err = ReadSchematic(bytes.NewBuffer(data)); err != nil {
+			return nil, fmt.Errorf("Part %s: %v", p.Name, err)
+		}
+		b.Parts = append(b.Parts, p)
+	}
+	return
+}
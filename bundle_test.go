@@ -0,0 +1,98 @@
+package schematic
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	a, b := newTestVolume(), NewSchematic(1, 2, 3)
+	b.SetBlock(0, 1, 2, Block{35, 4})
+	bundle := &Bundle{
+		Metadata: map[string]string{"author": "Ivan"},
+		Parts: []*BundlePart{
+			&BundlePart{Name: "tower", X: 10, Y: 0, Z: -5, Schematic: a, Metadata: map[string]string{"floor": "1"}},
+			&BundlePart{Name: "wall", Schematic: b},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, bundle); err != nil {
+		t.Fatalf("WriteBundle: %v", err)
+	}
+
+	// The parts are plain schematic files.
+	tr := tar.NewReader(bytes.NewBuffer(buf.Bytes()))
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	if strings.Join(names, " ") != "manifest.json tower.schematic wall.schematic" {
+		t.Errorf("Files: got %v", names)
+	}
+
+	got, err := ReadBundle(&buf)
+	if err != nil {
+		t.Fatalf("ReadBundle: %v", err)
+	}
+	if got.Metadata["author"] != "Ivan" || len(got.Parts) != 2 {
+		t.Fatalf("ReadBundle: got %+v", got)
+	}
+	p := got.Parts[0]
+	if p.Name != "tower" || p.X != 10 || p.Z != -5 || p.Metadata["floor"] != "1" || p.Schematic.Fingerprint() != a.Fingerprint() {
+		t.Errorf("Part tower: got %+v", p)
+	}
+	if p = got.Parts[1]; p.Name != "wall" || p.Schematic.Block(0, 1, 2) != (Block{35, 4}) {
+		t.Errorf("Part wall: got %+v", p)
+	}
+
+	for _, parts := range [][]*BundlePart{
+		{&BundlePart{Name: "a/b", Schematic: a}},
+		{&BundlePart{Name: "a", Schematic: a}, &BundlePart{Name: "a", Schematic: b}},
+		{&BundlePart{Name: "a"}},
+	} {
+		if err = WriteBundle(&buf, &Bundle{Parts: parts}); err == nil {
+			t.Errorf("WriteBundle(%v): want error, got nil", parts)
+		}
+	}
+	if _, err = ReadBundle(strings.NewReader("junk")); err == nil {
+		t.Errorf("ReadBundle of junk: want error, got nil")
+	}
+}
+
+func TestBundleOptions(t *testing.T) {
+	bundle := &Bundle{Parts: []*BundlePart{&BundlePart{Name: "tower", Schematic: newTestVolume()}}}
+	var buf bytes.Buffer
+	if err := WriteBundleOptions(&buf, bundle, &WriteOptions{Compression: None}); err != nil {
+		t.Fatalf("WriteBundleOptions: %v", err)
+	}
+	tr := tar.NewReader(bytes.NewBuffer(buf.Bytes()))
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatalf("tower.schematic is missing: %v", err)
+		}
+		if hdr.Name != "tower.schematic" {
+			continue
+		}
+		var part bytes.Buffer
+		part.ReadFrom(tr)
+		// An uncompressed NBT file starts with the compound tag.
+		if part.Len() == 0 || part.Bytes()[0] != 10 {
+			t.Errorf("tower.schematic is compressed: %x", part.Bytes())
+		}
+		break
+	}
+	got, err := ReadBundle(&buf)
+	if err != nil {
+		t.Fatalf("ReadBundle: %v", err)
+	}
+	if len(got.Parts) != 1 || got.Parts[0].Schematic.Fingerprint() != bundle.Parts[0].Schematic.Fingerprint() {
+		t.Errorf("ReadBundle: got %+v", got)
+	}
+}
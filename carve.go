@@ -0,0 +1,201 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"math"
+	"rand"
+)
+
+// A Point is a position in blocks. The block (x, y, z) spans from the point
+// (x, y, z) to (x+1, y+1, z+1).
+type Point struct {
+	X, Y, Z float64
+}
+
+// A RadiusProfile returns the radius of a tunnel in blocks at the part t
+// of its length, from 0 at the start to 1 at the end.
+type RadiusProfile func(t float64) float64
+
+// ConstantRadius returns the profile of a tunnel of the same radius along
+// its length.
+func ConstantRadius(r float64) RadiusProfile {
+	return func(float64) float64 { return r }
+}
+
+// TaperedRadius returns the profile of a tunnel which is max wide in the
+// middle and narrows down to min at the ends.
+func TaperedRadius(min, max float64) RadiusProfile {
+	return func(t float64) float64 {
+		return min + (max-min)*math.Sin(math.Pi*t)
+	}
+}
+
+// CarveOptions control the blocks changed by the carvers. The zero value
+// carves every block into air.
+type CarveOptions struct {
+	// Fill is the block replacing the carved blocks. Default: air.
+	Fill Block
+
+	// Mask selects the blocks which may be carved, for example to keep
+	// the bedrock or the water. Nil selects all blocks.
+	Mask Mask
+}
+
+// carver removes the blocks within a distance from a path.
+type carver struct {
+	s   *Schematic
+	opt CarveOptions
+	n   int // the number of changed blocks
+}
+
+// sphere carves the blocks whose centers are within the radius of c.
+func (c *carver) sphere(p vec, radius float64) {
+	s := c.s
+	r := int(math.Ceil(radius))
+	cx, cy, cz := int(math.Floor(p[0])), int(math.Floor(p[1])), int(math.Floor(p[2]))
+	for y := imax(cy-r, 0); y <= cy+r && y < s.Height; y++ {
+		for z := imax(cz-r, 0); z <= cz+r && z < s.Length; z++ {
+			for x := imax(cx-r, 0); x <= cx+r && x < s.Width; x++ {
+				dx, dy, dz := float64(x)+0.5-p[0], float64(y)+0.5-p[1], float64(z)+0.5-p[2]
+				if dx*dx+dy*dy+dz*dz > radius*radius {
+					continue
+				}
+				if s.Block(x, y, z) == c.opt.Fill || c.opt.Mask != nil && !c.opt.Mask.Test(s, x, y, z) {
+					continue
+				}
+				s.SetBlock(x, y, z, c.opt.Fill)
+				c.n++
+			}
+		}
+	}
+}
+
+// path carves along the path of points no more than a block apart.
+func (c *carver) path(points []vec, radius RadiusProfile) {
+	for i, p := range points {
+		t := 0.0
+		if len(points) > 1 {
+			t = float64(i) / float64(len(points)-1)
+		}
+		c.sphere(p, radius(t))
+	}
+}
+
+// CarveSpline carves a tunnel along the smooth curve through the points,
+// a Catmull-Rom spline, and returns the number of changed blocks. A nil
+// opt carves every block into air.
+func (s *Schematic) CarveSpline(points []Point, radius RadiusProfile, opt *CarveOptions) int {
+	c := &carver{s: s}
+	if opt != nil {
+		c.opt = *opt
+	}
+	c.path(splinePath(points), radius)
+	return c.n
+}
+
+// splinePath returns the points of the Catmull-Rom spline through the
+// control points, about half a block apart.
+func splinePath(points []Point) []vec {
+	ctl := make([]vec, len(points))
+	for i, p := range points {
+		ctl[i] = vec{p.X, p.Y, p.Z}
+	}
+	if len(ctl) < 2 {
+		return ctl
+	}
+	var path []vec
+	for i := 0; i+1 < len(ctl); i++ {
+		p0, p1, p2, p3 := ctl[imax(i-1, 0)], ctl[i], ctl[i+1], ctl[imin(i+2, len(ctl)-1)]
+		d := vec{p2[0] - p1[0], p2[1] - p1[1], p2[2] - p1[2]}
+		steps := imax(int(math.Ceil(math.Sqrt(d[0]*d[0]+d[1]*d[1]+d[2]*d[2])*2)), 1)
+		for k := 0; k < steps; k++ {
+			t := float64(k) / float64(steps)
+			t2, t3 := t*t, t*t*t
+			var p vec
+			for j := range p {
+				p[j] = 0.5 * (2*p1[j] + (p2[j]-p0[j])*t + (2*p0[j]-5*p1[j]+4*p2[j]-p3[j])*t2 + (3*p1[j]-p0[j]-3*p2[j]+p3[j])*t3)
+			}
+			path = append(path, p)
+		}
+	}
+	return append(path, ctl[len(ctl)-1])
+}
+
+// WormOptions control CarveWorms. Zero values select the defaults.
+type WormOptions struct {
+	CarveOptions
+	Seed int64
+
+	// Count is the number of worms. Default: 4.
+	Count int
+
+	// Length is the length of every worm in blocks. Default: 64.
+	Length int
+
+	// Radius is the profile of the tunnels. Default: TaperedRadius(1, 2.5).
+	Radius RadiusProfile
+
+	// Scale is the distance in blocks over which a worm changes its
+	// heading, and Turn the largest change in radians. Defaults: 16 and 1.
+	Scale, Turn float64
+
+	// MinY and MaxY limit the heights at which the worms start. Default:
+	// the bottom and the top of the schematic.
+	MinY, MaxY int
+}
+
+// CarveWorms carves cave tunnels along the paths of worms which start at
+// random places and wander by three dimensional noise, and returns the
+// number of changed blocks. The same options always carve the same caves.
+// A nil opt selects the defaults.
+func (s *Schematic) CarveWorms(opt *WormOptions) int {
+	var o WormOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.Count <= 0 {
+		o.Count = 4
+	}
+	if o.Length <= 0 {
+		o.Length = 64
+	}
+	if o.Radius == nil {
+		o.Radius = TaperedRadius(1, 2.5)
+	}
+	if o.Scale <= 0 {
+		o.Scale = 16
+	}
+	if o.Turn <= 0 {
+		o.Turn = 1
+	}
+	if o.MaxY <= 0 || o.MaxY > s.Height {
+		o.MaxY = s.Height
+	}
+	o.MinY = imax(0, imin(o.MinY, o.MaxY-1))
+	c := &carver{s: s, opt: o.CarveOptions}
+	if s.Width == 0 || s.Height == 0 || s.Length == 0 {
+		return 0
+	}
+	rnd := rand.New(rand.NewSource(o.Seed))
+	yaw, pitch := NewNoise(o.Seed+1), NewNoise(o.Seed+2)
+	for w := 0; w < o.Count; w++ {
+		p := vec{rnd.Float64() * float64(s.Width), float64(o.MinY) + rnd.Float64()*float64(o.MaxY-o.MinY), rnd.Float64() * float64(s.Length)}
+		heading := rnd.Float64() * 2 * math.Pi
+		// Every worm reads the noise at its own offset, so the worms do
+		// not follow each other.
+		off := float64(w) * 1000
+		path := make([]vec, 0, o.Length*2)
+		for i := 0; i < o.Length*2; i++ {
+			path = append(path, p)
+			f := float64(i) / 2 / o.Scale
+			h := heading + o.Turn*2*yaw.At(f+off, 0.5, 0.5)
+			incl := o.Turn * pitch.At(f+off, 0.5, 0.5)
+			step := vec{math.Cos(h) * math.Cos(incl), math.Sin(incl), math.Sin(h) * math.Cos(incl)}
+			p = p.add(step, 0.5)
+		}
+		c.path(path, o.Radius)
+	}
+	return c.n
+}
@@ -0,0 +1,63 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func solid(w, h, l int) *Schematic {
+	s := NewSchematic(w, h, l)
+	s.Fill(RegionMask(Box{0, 0, 0, w, h, l}), Block{1, 0})
+	return s
+}
+
+func TestCarveSpline(t *testing.T) {
+	s := solid(32, 16, 16)
+	n := s.CarveSpline([]Point{{2, 8, 8}, {16, 10, 8}, {30, 8, 8}}, ConstantRadius(1.5), nil)
+	if got := 32*16*16 - s.BlockCounts()[1]; got != n || n == 0 {
+		t.Fatalf("CarveSpline returned %d, %d blocks carved", n, got)
+	}
+	// The curve passes through the points.
+	for _, p := range [][3]int{{2, 8, 8}, {16, 10, 8}, {29, 8, 8}, {9, 9, 8}} {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 0 {
+			t.Errorf("Block %v: want air, got %v", p, b)
+		}
+	}
+	for _, p := range [][3]int{{16, 2, 8}, {0, 8, 8}, {16, 10, 12}} {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 1 {
+			t.Errorf("Block %v: want stone, got %v", p, b)
+		}
+	}
+
+	s = solid(16, 16, 16)
+	s.SetBlock(8, 8, 8, Block{7, 0})
+	opt := &CarveOptions{Fill: Block{9, 0}, Mask: NotMask(IdMask(7))}
+	if n := s.CarveSpline([]Point{{4, 8.5, 8.5}, {12, 8.5, 8.5}}, TaperedRadius(0.5, 2), opt); n == 0 {
+		t.Fatalf("CarveSpline with options changed nothing")
+	}
+	if b := s.Block(8, 8, 8); b.Id != 7 {
+		t.Errorf("The masked block was carved: %v", b)
+	}
+	if b := s.Block(8, 9, 8); b.Id != 9 {
+		t.Errorf("Want water in the tunnel, got %v", b)
+	}
+	if b := s.Block(4, 10, 8); b.Id != 1 {
+		t.Errorf("The tunnel is not tapered at the end: %v", b)
+	}
+}
+
+func TestCarveWorms(t *testing.T) {
+	s := solid(48, 32, 48)
+	opt := &WormOptions{Seed: 3, MinY: 8, MaxY: 24}
+	n := s.CarveWorms(opt)
+	if got := 48*32*48 - s.BlockCounts()[1]; got != n || n < 100 {
+		t.Fatalf("CarveWorms returned %d, %d blocks carved", n, got)
+	}
+	again := solid(48, 32, 48)
+	again.CarveWorms(opt)
+	if again.Fingerprint() != s.Fingerprint() {
+		t.Errorf("CarveWorms is not deterministic")
+	}
+	if NewSchematic(0, 0, 0).CarveWorms(nil) != 0 {
+		t.Errorf("CarveWorms of an empty schematic")
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+	"json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// A CatalogEntry describes a single file of a schematic library.
+type CatalogEntry struct {
+	Path        string       `json:"path"`
+	Size        int64        `json:"size"`
+	Mtime       int64        `json:"mtime"`                  // modification time, seconds since the epoch
+	Format      string       `json:"format"`                 // "schematic", "sponge" or "litematic"
+	DataVersion int          `json:"data_version,omitempty"` // of the Sponge and Litematica files
+	Width       int          `json:"width"`
+	Height      int          `json:"height"`
+	Length      int          `json:"length"`
+	Materials   string       `json:"materials"`
+	Fingerprint string       `json:"fingerprint"`
+	Blocks      int          `json:"blocks"` // non-air blocks
+	BlockCounts []BlockCount `json:"block_counts"`
+	Entities    int          `json:"entities"`
+	Thumbnail   string       `json:"thumbnail,omitempty"` // the PNG file in CatalogOptions.ThumbnailDir
+	Error       string       `json:"error,omitempty"`     // set if the file could not be parsed or drawn
+}
+
+// DefaultThumbnailSize is the default size of the catalog thumbnails.
+const DefaultThumbnailSize = 128
+
+// CatalogOptions control BuildCatalogOptions.
+// A nil *CatalogOptions is equivalent to the zero value.
+type CatalogOptions struct {
+	// ThumbnailDir, if not empty, is the existing directory where the
+	// thumbnail of every schematic, drawn by Thumbnail, is saved. The files
+	// are named by the fingerprint and the size, so the schematics with
+	// equal blocks share a thumbnail, and the existing files are reused.
+	ThumbnailDir string
+
+	// ThumbnailSize is the width and the height of the thumbnails. Zero
+	// selects DefaultThumbnailSize.
+	ThumbnailSize int
+}
+
+// A BlockCount is the number of blocks of a single material.
+type BlockCount struct {
+	Id    uint16 `json:"id"`
+	Count int    `json:"count"`
+}
+
+type blockCountSlice []BlockCount
+
+func (p blockCountSlice) Len() int           { return len(p) }
+func (p blockCountSlice) Less(i, j int) bool { return p[i].Id < p[j].Id }
+func (p blockCountSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// sortedBlockCounts returns the block counts of s ordered by material.
+func sortedBlockCounts(s *Schematic) []BlockCount {
+	var res []BlockCount
+	for id, n := range s.BlockCounts() {
+		res = append(res, BlockCount{id, n})
+	}
+	sort.Sort(blockCountSlice(res))
+	return res
+}
+
+// NewCatalogEntry describes the schematic s stored at path. The format is
+// chosen by the extension of path, as ReadFile does. The DataVersion is
+// not known from s; ReadCatalogEntry sets it.
+func NewCatalogEntry(path string, s *Schematic) *CatalogEntry {
+	e := &CatalogEntry{
+		Path:        path,
+		Format:      fileFormat(path),
+		Width:       s.Width,
+		Height:      s.Height,
+		Length:      s.Length,
+		Materials:   s.Materials.String(),
+		Fingerprint: s.Fingerprint(),
+		BlockCounts: sortedBlockCounts(s),
+		Entities:    len(s.Entities),
+	}
+	for _, c := range e.BlockCounts {
+		if c.Id != 0 {
+			e.Blocks += c.Count
+		}
+	}
+	return e
+}
+
+// ReadCatalogEntry reads the named file with ReadFile and describes it,
+// including the DataVersion of the file before its block states were
+// translated.
+func ReadCatalogEntry(path string) (e *CatalogEntry, s *Schematic, err os.Error) {
+	var version int
+	if s, version, err = readFile(path); err != nil {
+		return
+	}
+	e = NewCatalogEntry(path, s)
+	e.DataVersion = version
+	return
+}
+
+// BuildCatalog describes every schematic file under root. Files which could
+// not be parsed are included with the Error field set.
+func BuildCatalog(root string) ([]*CatalogEntry, os.Error) {
+	return BuildCatalogOptions(root, nil)
+}
+
+// BuildCatalogOptions is like BuildCatalog but allows to save thumbnails.
+// It fails if a thumbnail cannot be saved.
+func BuildCatalogOptions(root string, opt *CatalogOptions) (entries []*CatalogEntry, err os.Error) {
+	if opt == nil {
+		opt = new(CatalogOptions)
+	}
+	err = walkFiles(root, func(path string, err os.Error) os.Error {
+		var e *CatalogEntry
+		var s *Schematic
+		if err == nil {
+			e, s, err = ReadCatalogEntry(path)
+		}
+		if err != nil {
+			e = &CatalogEntry{Path: path, Error: err.String()}
+		} else if opt.ThumbnailDir != "" {
+			if err = e.saveThumbnail(s, opt); err != nil {
+				return err
+			}
+		}
+		if fi, err := os.Stat(path); err == nil {
+			e.Size = fi.Size
+			e.Mtime = fi.Mtime_ns / 1e9
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return
+}
+
+// WriteCatalog writes the entries to w as an indented JSON array.
+func WriteCatalog(w io.Writer, entries []*CatalogEntry) (err os.Error) {
+	var data []byte
+	if data, err = json.MarshalIndent(entries, "", "  "); err != nil {
+		return
+	}
+	_, err = w.Write(append(data, '\n'))
+	return
+}
+
+// saveThumbnail sets the Thumbnail of e, saving the file if it does not exist.
+// A thumbnail which cannot be drawn is recorded as the Error of e.
+func (e *CatalogEntry) saveThumbnail(s *Schematic, opt *CatalogOptions) (err os.Error) {
+	size := opt.ThumbnailSize
+	if size == 0 {
+		size = DefaultThumbnailSize
+	}
+	name := fmt.Sprintf("%s-%d.png", e.Fingerprint, size)
+	path := filepath.Join(opt.ThumbnailDir, name)
+	if _, err = os.Stat(path); err == nil {
+		e.Thumbnail = name
+		return
+	}
+	m, err := Thumbnail(s, size)
+	if err != nil {
+		e.Error = err.String()
+		return nil
+	}
+	var f *os.File
+	if f, err = os.Create(path); err != nil {
+		return
+	}
+	if err = png.Encode(f, m); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		// A partial file would be reused by the next catalog.
+		os.Remove(path)
+		return
+	}
+	e.Thumbnail = name
+	return
+}
@@ -0,0 +1,139 @@
+package schematic
+
+import (
+	"bytes"
+	"image/png"
+	"io/ioutil"
+	"json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildCatalog(t *testing.T) {
+	entries, err := BuildCatalog("testdata")
+	if err != nil {
+		t.Fatalf("BuildCatalog: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("BuildCatalog: want 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Error != "" {
+		t.Fatalf("Error: %s", e.Error)
+	}
+	if e.Width != 128 || e.Size == 0 || e.Mtime == 0 || len(e.Fingerprint) != 40 {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	total := 0
+	for _, c := range e.BlockCounts {
+		total += c.Count
+	}
+	if total != e.Width*e.Height*e.Length {
+		t.Errorf("BlockCounts: want %d blocks in total, got %d", e.Width*e.Height*e.Length, total)
+	}
+	var buf bytes.Buffer
+	if err = WriteCatalog(&buf, entries); err != nil {
+		t.Fatalf("WriteCatalog: %v", err)
+	}
+	var got []*CatalogEntry
+	if err = json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if len(got) != 1 || got[0].Fingerprint != e.Fingerprint || got[0].Blocks != e.Blocks {
+		t.Errorf("WriteCatalog round trip: want %+v, got %+v", e, got)
+	}
+}
+
+func TestCatalogThumbnails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	opt := &CatalogOptions{ThumbnailDir: dir, ThumbnailSize: 32}
+	entries, err := BuildCatalogOptions("testdata", opt)
+	if err != nil {
+		t.Fatalf("BuildCatalogOptions: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Thumbnail != entries[0].Fingerprint+"-32.png" {
+		t.Fatalf("BuildCatalogOptions: got %+v", entries)
+	}
+	f, err := os.Open(filepath.Join(dir, entries[0].Thumbnail))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	m, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("png.Decode: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 32 || b.Dy() != 32 {
+		t.Errorf("Thumbnail size: want 32x32, got %dx%d", b.Dx(), b.Dy())
+	}
+
+	// The thumbnail is reused.
+	if entries, err = BuildCatalogOptions("testdata", opt); err != nil || entries[0].Thumbnail == "" {
+		t.Errorf("BuildCatalogOptions again: %v, %+v", err, entries)
+	}
+	if entries, err = BuildCatalogOptions("testdata", &CatalogOptions{ThumbnailDir: filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("BuildCatalogOptions with a missing directory: want error, got nil")
+	}
+}
+
+func TestCatalogFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewSchematic(2, 1, 1)
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	if err = WriteSchematicFile(filepath.Join(dir, "a"+Ext), s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	l, err := SplitLitematic(s, nil)
+	if err != nil {
+		t.Fatalf("SplitLitematic: %v", err)
+	}
+	// The catalog records the version of the file, not the translated one.
+	if err = l.Translate(LitematicDataVersion); err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	var buf bytes.Buffer
+	if err = WriteLitematic(&buf, l); err != nil {
+		t.Fatalf("WriteLitematic: %v", err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "b"+LitematicExt), buf.Bytes(), 0644)
+	sp, err := NewSponge(s)
+	if err != nil {
+		t.Fatalf("NewSponge: %v", err)
+	}
+	buf.Reset()
+	if err = WriteSponge(&buf, sp); err != nil {
+		t.Fatalf("WriteSponge: %v", err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "c"+SpongeExt), buf.Bytes(), 0644)
+
+	entries, err := BuildCatalog(dir)
+	if err != nil {
+		t.Fatalf("BuildCatalog: %v", err)
+	}
+	want := []struct {
+		format  string
+		version int
+	}{
+		{"schematic", 0},
+		{"litematic", LitematicDataVersion},
+		{"sponge", LatestDataVersion},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("BuildCatalog: want %d entries, got %d", len(want), len(entries))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Error != "" || e.Format != w.format || e.DataVersion != w.version || e.Blocks != 1 {
+			t.Errorf("%s: want format %s, data version %d, got %+v", e.Path, w.format, w.version, e)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// A Category is a set of flags describing how a block behaves.
+type Category uint
+
+const (
+	// Solid blocks fill their whole cube: mobs stand on them and
+	// liquids do not flow into them.
+	Solid Category = 1 << iota
+	// Transparent blocks let the light and the view pass.
+	Transparent
+	// Liquid blocks are water and lava.
+	Liquid
+	// Vegetation blocks are flowers, grass, crops, saplings, leaves and
+	// the like.
+	Vegetation
+	// Redstone blocks are redstone components, which power or are powered.
+	Redstone
+	// Falling blocks fall when there is nothing below them, like sand.
+	Falling
+)
+
+// A BlockInfo describes the behaviour of a block in Minecraft 1.12.
+type BlockInfo struct {
+	Category        Category
+	BlastResistance float64 // the resistance to explosions, 0 for air
+	Light           int     // the light level emitted by the block, 0 to 15
+}
+
+// blockInfos are the descriptions of the legacy ids, built by init from
+// the tables below. Data values are not distinguished, so the slabs and
+// the other blocks filling a part of their cube are not Solid.
+var blockInfos [256]BlockInfo
+
+var (
+	solidIds = []uint16{1, 2, 3, 4, 5, 7, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25,
+		29, 33, 35, 41, 42, 43, 45, 46, 47, 48, 49, 52, 56, 57, 58, 61, 62, 73, 74, 79,
+		80, 82, 84, 86, 87, 88, 89, 91, 95, 97, 98, 99, 100, 103, 110, 112, 121, 123, 124, 125,
+		129, 133, 137, 138, 152, 153, 155, 158, 159, 161, 162, 165, 168, 169, 170, 172, 173, 174, 179, 181,
+		201, 202, 204, 206, 210, 211, 212, 213, 214, 215, 216, 218, 251, 252, 255}
+	transparentIds = []uint16{0, 6, 8, 9, 10, 11, 18, 20, 26, 27, 28, 30, 31, 32, 34, 36, 37, 38, 39, 40,
+		44, 50, 51, 52, 53, 54, 55, 59, 63, 64, 65, 66, 67, 68, 69, 70, 71, 72, 75, 76,
+		77, 78, 79, 81, 83, 85, 90, 92, 93, 94, 95, 96, 101, 102, 104, 105, 106, 107, 108, 109,
+		111, 113, 114, 115, 116, 117, 118, 119, 120, 122, 126, 127, 128, 130, 131, 132, 134, 135, 136, 138,
+		139, 140, 141, 142, 143, 144, 145, 146, 147, 148, 149, 150, 151, 154, 156, 157, 160, 161, 163, 164,
+		165, 166, 167, 171, 175, 176, 177, 178, 180, 182, 183, 184, 185, 186, 187, 188, 189, 190, 191, 192,
+		193, 194, 195, 196, 197, 198, 199, 200, 203, 205, 207, 208, 209, 212, 217}
+	liquidIds   = []uint16{8, 9, 10, 11}
+	plantIds    = []uint16{6, 18, 31, 32, 37, 38, 39, 40, 59, 81, 83, 104, 105, 106, 111, 115, 127, 141, 142, 161, 175, 199, 200, 207}
+	redstoneIds = []uint16{23, 27, 28, 29, 33, 55, 69, 70, 72, 75, 76, 77, 93, 94, 123, 124, 131, 132, 143, 146,
+		147, 148, 149, 150, 151, 152, 154, 157, 158, 178, 218}
+	fallingIds = []uint16{12, 13, 122, 145, 252}
+
+	// blockLights are the light levels emitted by the blocks.
+	blockLights = map[uint16]int{10: 15, 11: 15, 39: 1, 50: 14, 51: 15, 62: 13, 74: 9, 76: 7, 89: 15, 90: 11,
+		91: 15, 94: 9, 117: 1, 119: 15, 122: 1, 124: 15, 130: 7, 138: 15, 169: 15, 198: 14, 213: 3}
+
+	// blastResistances are the resistances of the blocks other than the
+	// defaults set by init.
+	blastResistances = map[uint16]float64{
+		2: 0.6, 3: 0.5, 5: 3, 46: 0, 7: 3600000, 8: 100, 9: 100, 10: 100, 11: 100, 12: 0.5, 13: 0.6,
+		14: 3, 15: 3, 16: 3, 17: 2, 18: 0.2, 19: 0.6, 20: 0.3, 21: 3, 22: 3, 23: 3.5,
+		24: 0.8, 25: 0.8, 26: 0.2, 27: 0.7, 28: 0.7, 29: 0.5, 33: 0.5, 34: 0.5, 35: 0.8, 47: 1.5,
+		49: 1200, 52: 5, 53: 3, 54: 2.5, 56: 3, 58: 2.5, 60: 0.6, 61: 3.5, 62: 3.5, 63: 1,
+		64: 3, 65: 0.4, 66: 0.7, 68: 1, 69: 0.5, 70: 0.5, 71: 5, 72: 0.5, 73: 3, 74: 3,
+		77: 0.5, 78: 0.1, 79: 0.5, 80: 0.2, 81: 0.4, 82: 0.6, 84: 6, 85: 3, 86: 1, 87: 0.4,
+		88: 0.5, 89: 0.3, 91: 1, 92: 0.5, 95: 0.3, 96: 3, 97: 0.75, 99: 0.2, 100: 0.2, 102: 0.3,
+		103: 1, 107: 3, 110: 0.6, 116: 1200, 117: 0.5, 118: 2, 119: 3600000, 120: 3600000, 121: 9, 122: 9,
+		123: 0.3, 124: 0.3, 125: 3, 126: 3, 129: 3, 130: 600, 134: 3, 135: 3, 136: 3, 137: 3600000,
+		138: 3, 143: 0.5, 145: 1200, 146: 2.5, 147: 0.5, 148: 0.5, 152: 6, 153: 3, 154: 4.8, 155: 0.8,
+		156: 0.8, 157: 0.7, 158: 3.5, 159: 4.2, 160: 0.3, 161: 0.2, 162: 2, 163: 3, 164: 3, 165: 0,
+		166: 3600000, 168: 6, 169: 0.3, 170: 0.5, 171: 0.1, 172: 4.2, 173: 6, 174: 0.5, 179: 0.8, 180: 0.8,
+		181: 6, 182: 6, 183: 3, 184: 3, 185: 3, 186: 3, 187: 3, 188: 3, 189: 3, 190: 3, 191: 3, 192: 3,
+		193: 3, 194: 3, 195: 3, 196: 3, 197: 3, 198: 0, 199: 0.4, 200: 0.4, 201: 6, 202: 6,
+		203: 6, 204: 6, 205: 6, 206: 0.8, 207: 0, 208: 0.65, 209: 3600000, 210: 3600000, 211: 3600000, 212: 0.5,
+		213: 0.5, 214: 1, 215: 6, 216: 2, 217: 0, 218: 3.5, 251: 1.8, 252: 0.5, 255: 3600000,
+	}
+)
+
+func init() {
+	for _, t := range []struct {
+		c   Category
+		ids []uint16
+	}{{Solid, solidIds}, {Transparent, transparentIds}, {Liquid, liquidIds}, {Vegetation, plantIds},
+		{Redstone, redstoneIds}, {Falling, fallingIds}} {
+		for _, id := range t.ids {
+			blockInfos[id].Category |= t.c
+		}
+	}
+	for id := range blockInfos {
+		info := &blockInfos[id]
+		// Solid blocks resist like stone, the others like a torch.
+		if info.Category&Solid != 0 {
+			info.BlastResistance = 6
+		}
+		if r, ok := blastResistances[uint16(id)]; ok {
+			info.BlastResistance = r
+		}
+		info.Light = blockLights[uint16(id)]
+	}
+	for i := 0; i < 16; i++ {
+		blockInfos[219+i] = BlockInfo{Category: Solid, BlastResistance: 2}   // shulker boxes
+		blockInfos[235+i] = BlockInfo{Category: Solid, BlastResistance: 1.4} // glazed terracotta
+	}
+}
+
+// Info returns the description of the block. The data value is ignored.
+// ok is false for the ids unknown to LegacyState, which are described as
+// Solid blocks resisting explosions like stone, so that the analyses
+// assume the worst of them.
+func Info(b Block) (info BlockInfo, ok bool) {
+	if _, ok = LegacyState(b); !ok {
+		return BlockInfo{Category: Solid, BlastResistance: 6}, false
+	}
+	return blockInfos[b.Id], true
+}
+
+// StateInfo returns the description of the block state, such as
+// "minecraft:sand", which must be known to FromState.
+func StateInfo(state string) (info BlockInfo, ok bool) {
+	b, ok := FromState(state)
+	if !ok {
+		return BlockInfo{}, false
+	}
+	return Info(b)
+}
+
+// Is reports whether the block has all the flags of c, as described by
+// Info.
+func (b Block) Is(c Category) bool {
+	info, _ := Info(b)
+	return info.Category&c == c
+}
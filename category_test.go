@@ -0,0 +1,48 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestBlockInfo(t *testing.T) {
+	tests := []struct {
+		b          Block
+		is, isNot  Category
+		resistance float64
+		light      int
+	}{
+		{Block{}, Transparent, Solid | Liquid, 0, 0},
+		{Block{1, 3}, Solid, Transparent | Falling, 6, 0},
+		{Block{20, 0}, Solid | Transparent, Liquid, 0.3, 0},
+		{Block{9, 0}, Liquid | Transparent, Solid, 100, 0},
+		{Block{11, 0}, Liquid, Solid, 100, 15},
+		{Block{38, 2}, Vegetation | Transparent, Solid, 0, 0},
+		{Block{55, 0}, Redstone | Transparent, Solid, 0, 0},
+		{Block{12, 1}, Solid | Falling, Transparent, 0.5, 0},
+		{Block{252, 14}, Solid | Falling, Transparent, 0.5, 0},
+		{Block{50, 5}, Transparent, Solid, 0, 14},
+		{Block{49, 0}, Solid, Transparent, 1200, 0},
+		{Block{225, 0}, Solid, Transparent, 2, 0},
+	}
+	for _, tt := range tests {
+		info, ok := Info(tt.b)
+		if !ok {
+			t.Errorf("Info(%v): unknown block", tt.b)
+		}
+		if !tt.b.Is(tt.is) || info.Category&tt.isNot != 0 {
+			t.Errorf("Info(%v): category %b, want %b and not %b", tt.b, info.Category, tt.is, tt.isNot)
+		}
+		if info.BlastResistance != tt.resistance || info.Light != tt.light {
+			t.Errorf("Info(%v): want resistance %g and light %d, got %+v", tt.b, tt.resistance, tt.light, info)
+		}
+	}
+	if info, ok := Info(Block{4000, 0}); ok || info.Category != Solid {
+		t.Errorf("Info of an unknown block: got %+v, %v", info, ok)
+	}
+	if info, ok := StateInfo("minecraft:gravel"); !ok || info.Category != Solid|Falling {
+		t.Errorf("StateInfo(gravel): got %+v, %v", info, ok)
+	}
+	if _, ok := StateInfo("minecraft:kelp"); ok {
+		t.Errorf("StateInfo(kelp): want unknown")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/krasin/schematic"
+)
+
+var costCmd = &command{
+	name:  "cost",
+	args:  "-prices file.json files...",
+	short: "price the blocks by a price table",
+	batch: true,
+	flags: func() (*flag.FlagSet, func([]string) os.Error) {
+		fs := flag.NewFlagSet("cost", flag.ContinueOnError)
+		prices := fs.String("prices", "", "the JSON price table")
+		return fs, func(args []string) os.Error {
+			if *prices == "" {
+				return os.NewError("no price table given")
+			}
+			f, err := os.Open(*prices)
+			if err != nil {
+				return err
+			}
+			table, err := schematic.ReadPriceTable(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			return forEach(args, func(path string, w io.Writer) os.Error {
+				s, err := schematic.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				printCost(w, path, s.Cost(table))
+				return nil
+			})
+		}
+	},
+}
+
+func printCost(w io.Writer, path string, r *schematic.CostReport) {
+	fmt.Fprintf(w, "%s:\n", path)
+	for _, l := range r.Lines {
+		mark := ""
+		if !l.Priced {
+			mark = " (default price)"
+		}
+		fmt.Fprintf(w, "  %10d x %10.2f = %12.2f  %s [%s]%s\n", l.Count, l.Price, l.Cost, l.Name, l.Category, mark)
+	}
+	var categories []string
+	for c := range r.Budgets {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	for _, c := range categories {
+		fmt.Fprintf(w, "  budget %-18s %12.2f\n", c+":", r.Budgets[c])
+	}
+	fmt.Fprintf(w, "  total:%32.2f\n", r.Total)
+}
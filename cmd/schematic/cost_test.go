@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krasin/schematic"
+)
+
+func TestCost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic-cost")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	prices, path := filepath.Join(dir, "prices.json"), filepath.Join(dir, "a.schematic")
+	if err = ioutil.WriteFile(prices, []byte(`{"prices": {"stone": 1.5}, "default": 2}`), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	s := schematic.NewSchematic(3, 1, 1)
+	s.SetBlock(0, 0, 0, schematic.Block{Id: 1})
+	s.SetBlock(1, 0, 0, schematic.Block{Id: 1})
+	s.SetBlock(2, 0, 0, schematic.Block{Id: 4})
+	if err = schematic.WriteSchematicFile(path, s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	out := runOutput(t, "cost", "-prices", prices, path)
+	for _, want := range []string{
+		"         2 x       1.50 =         3.00  minecraft:stone [solid]\n",
+		"         1 x       2.00 =         2.00  minecraft:cobblestone [solid] (default price)\n",
+		"  budget solid:                     5.00\n",
+		"  total:                            5.00\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("cost output does not contain %q:\n%s", want, out)
+		}
+	}
+	if err = run("cost", []string{path}); err == nil {
+		t.Errorf("cost without prices: want error, got nil")
+	}
+}
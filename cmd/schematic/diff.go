@@ -0,0 +1,59 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/krasin/schematic"
+)
+
+// errDifferent is returned by the diff command if the schematics differ.
+// The tool exits with status 1 without printing it.
+var errDifferent = os.NewError("schematics differ")
+
+var diffCmd = &command{
+	name:  "diff",
+	args:  "old new",
+	short: "compare two schematics",
+	flags: func() (*flag.FlagSet, func([]string) os.Error) {
+		fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+		blocks := fs.Bool("blocks", false, "list every changed block")
+		max := fs.Int("max", 0, "list at most this many blocks; 0 means no limit")
+		return fs, func(args []string) os.Error {
+			if len(args) != 2 {
+				return os.NewError("want exactly two files")
+			}
+			a, err := schematic.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			b, err := schematic.ReadFile(args[1])
+			if err != nil {
+				return err
+			}
+			d := schematic.Compare(a, b)
+			if d.Equal() {
+				return nil
+			}
+			if d.SizeChanged {
+				fmt.Fprintf(stdout, "size: %dx%dx%d -> %dx%dx%d\n", a.Width, a.Height, a.Length, b.Width, b.Height, b.Length)
+			}
+			fmt.Fprintf(stdout, "blocks: %d added, %d removed, %d changed\n", d.Added, d.Removed, d.Changed)
+			fmt.Fprintf(stdout, "entities: %d added, %d removed\n", d.EntitiesAdded, d.EntitiesRemoved)
+			if *blocks {
+				for i, c := range d.Changes {
+					if *max > 0 && i == *max {
+						fmt.Fprintf(stdout, "... %d more\n", len(d.Changes)-i)
+						break
+					}
+					fmt.Fprintf(stdout, "%d,%d,%d: %d:%d -> %d:%d\n", c.X, c.Y, c.Z, c.Old.Id, c.Old.Data, c.New.Id, c.New.Data)
+				}
+			}
+			return errDifferent
+		}
+	},
+}
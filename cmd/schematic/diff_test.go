@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krasin/schematic"
+)
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic-diff")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.schematic"), filepath.Join(dir, "b.schematic")
+	s := schematic.NewSchematic(2, 1, 2)
+	s.SetBlock(0, 0, 0, schematic.Block{Id: 1})
+	if err = schematic.WriteSchematicFile(a, s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	s.SetBlock(0, 0, 0, schematic.Block{Id: 1, Data: 2})
+	s.SetBlock(1, 0, 1, schematic.Block{Id: 4})
+	if err = schematic.WriteSchematicFile(b, s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+
+	if out := runOutput(t, "diff", a, a); out != "" {
+		t.Errorf("diff a a: want no output, got %q", out)
+	}
+	var buf bytes.Buffer
+	stdout = &buf
+	if err = run("diff", []string{"-blocks", "-max", "1", a, b}); err != errDifferent {
+		t.Fatalf("diff a b: want errDifferent, got %v", err)
+	}
+	if status := exitStatus(err); status != 1 {
+		t.Errorf("diff a b: want exit status 1, got %d", status)
+	}
+	want := "blocks: 1 added, 0 removed, 1 changed\nentities: 0 added, 0 removed\n0,0,0: 1:0 -> 1:2\n... 1 more\n"
+	if got := buf.String(); got != want {
+		t.Errorf("diff a b: want %q, got %q", want, got)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	stdout = ioutil.Discard
+	missing := filepath.Join(os.TempDir(), "schematic-missing.schematic")
+	tests := []struct {
+		args   []string
+		status int
+	}{
+		{[]string{"../../testdata/cylinder.schematic", "../../testdata/cylinder.schematic"}, 0},
+		{[]string{"../../testdata/cylinder.schematic", missing}, 2},
+	}
+	for _, tt := range tests {
+		if status := exitStatus(run("diff", tt.args)); status != tt.status {
+			t.Errorf("diff %v: want exit status %d, got %d", tt.args, tt.status, status)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/krasin/schematic"
+)
+
+var infoCmd = &command{
+	name:  "info",
+	args:  "files...",
+	short: "print dimensions, block counts, entities and offsets",
+	batch: true,
+	flags: func() (*flag.FlagSet, func([]string) os.Error) {
+		fs := flag.NewFlagSet("info", flag.ContinueOnError)
+		blocks := fs.Bool("blocks", true, "print the number of blocks of every material")
+		return fs, func(args []string) os.Error {
+			return forEach(args, func(path string, w io.Writer) os.Error {
+				e, s, err := schematic.ReadCatalogEntry(path)
+				if err != nil {
+					return err
+				}
+				printInfo(w, e, s, *blocks)
+				return nil
+			})
+		}
+	},
+}
+
+type countSlice []schematic.BlockCount
+
+func (p countSlice) Len() int { return len(p) }
+func (p countSlice) Less(i, j int) bool {
+	if p[i].Count != p[j].Count {
+		return p[i].Count > p[j].Count
+	}
+	return p[i].Id < p[j].Id
+}
+func (p countSlice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+func printInfo(w io.Writer, e *schematic.CatalogEntry, s *schematic.Schematic, blocks bool) {
+	fmt.Fprintf(w, "%s:\n", e.Path)
+	if md := s.Metadata(); md.Name != "" || md.Author != "" {
+		fmt.Fprintf(w, "  name:        %s\n", md.Name)
+		fmt.Fprintf(w, "  author:      %s\n", md.Author)
+	}
+	if e.DataVersion != 0 {
+		fmt.Fprintf(w, "  format:      %s (DataVersion: %d, Materials: %s)\n", e.Format, e.DataVersion, e.Materials)
+	} else {
+		fmt.Fprintf(w, "  format:      %s (Materials: %s)\n", e.Format, e.Materials)
+	}
+	fmt.Fprintf(w, "  size:        %dx%dx%d (width x height x length)\n", s.Width, s.Height, s.Length)
+	fmt.Fprintf(w, "  blocks:      %d non-air of %d\n", e.Blocks, s.Width*s.Height*s.Length)
+	fmt.Fprintf(w, "  offset:      %d %d %d\n", s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ)
+	fmt.Fprintf(w, "  entities:    %d\n", len(s.Entities))
+	fmt.Fprintf(w, "  tile ticks:  %d\n", len(s.TileTicks))
+	fmt.Fprintf(w, "  fingerprint: %s\n", e.Fingerprint)
+	if !blocks {
+		return
+	}
+	counts := countSlice(e.BlockCounts)
+	sort.Sort(counts)
+	fmt.Fprintf(w, "  materials:\n")
+	for _, c := range counts {
+		fmt.Fprintf(w, "    %5d %10d  %s\n", c.Id, c.Count, schematic.DisplayName(schematic.Block{Id: c.Id}))
+	}
+}
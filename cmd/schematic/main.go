@@ -0,0 +1,224 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+
+// Command schematic inspects and converts Minecraft .schematic files.
+//
+// Usage:
+//
+//	schematic command [flags] files...
+//
+// The commands are:
+//
+//	info    print dimensions, block counts, entities and offsets
+//	render  render PNG previews
+//	show    print a layer in the terminal
+//	rotate  rotate around the vertical axis
+//	flip    mirror along an axis
+//	crop    cut out a box
+//	trim    remove the surrounding air
+//	stack   repeat along an axis
+//	replace replace a block with a pattern of blocks
+//	diff    compare two schematics
+//	validate report inconsistencies such as misplaced tile entities
+//	cost    price the blocks by a price table
+//
+// Commands taking many files also accept directories, which are searched
+// recursively for .schematic, .schem and .litematic files, and glob
+// patterns such as "lib/*.schematic". Sponge and Litematica files are
+// converted when read; the transforming commands write them back as
+// .schematic files next to them.
+// The -j flag sets the number of files processed in parallel. Errors are
+// reported per file and do not stop the processing of other files.
+//
+// The exit status is 0 on success and 2 on errors. Like diff(1), the diff
+// command exits with status 1 if the schematics differ.
+//
+// Run "schematic command -h" for the flags of a command.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krasin/schematic"
+)
+
+// A command is a subcommand of the schematic tool.
+type command struct {
+	name  string
+	args  string // synopsis of the arguments
+	short string // one line description
+	batch bool   // whether the arguments are files expanded by expandArgs
+	// flags returns the flag set of the command and the function running it.
+	flags func() (*flag.FlagSet, func(args []string) os.Error)
+}
+
+var commands = []*command{
+	infoCmd,
+	renderCmd,
+	showCmd,
+	rotateCmd,
+	flipCmd,
+	cropCmd,
+	trimCmd,
+	stackCmd,
+	replaceCmd,
+	diffCmd,
+	validateCmd,
+	costCmd,
+}
+
+// stdout is where the commands write their output. Tests replace it.
+var stdout io.Writer = os.Stdout
+
+// jobs is the number of files processed in parallel, set by the -j flag.
+var jobs = 1
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: schematic command [flags] files...\n\nThe commands are:\n\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-8s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"schematic command -h\" for the flags of a command.\n")
+	os.Exit(2)
+}
+
+// run parses the flags of the named command and runs it.
+func run(name string, args []string) os.Error {
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		fs, fn := c.flags()
+		if c.batch {
+			fs.IntVar(&jobs, "j", 1, "number of files processed in parallel")
+		}
+		fs.Usage = func() {
+			fmt.Fprintf(os.Stderr, "Usage: schematic %s [flags] %s\n\n%s.\n\nFlags:\n", c.name, c.args, c.short)
+			fs.PrintDefaults()
+		}
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		args = fs.Args()
+		if c.batch {
+			var err os.Error
+			if args, err = expandArgs(args); err != nil {
+				return err
+			}
+		}
+		return fn(args)
+	}
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// expandArgs replaces glob patterns with the matching files and directories
+// with the schematic files found in them.
+func expandArgs(args []string) (files []string, err os.Error) {
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.IndexAny(arg, "*?[") >= 0 {
+			if matches, err = filepath.Glob(arg); err != nil {
+				return
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+		}
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err != nil || !fi.IsDirectory() {
+				files = append(files, m)
+				continue
+			}
+			var found []string
+			if found, err = schematic.FindSchematics(m); err != nil {
+				return
+			}
+			files = append(files, found...)
+		}
+	}
+	return
+}
+
+// A fileResult is the outcome of processing a single file by forEach.
+type fileResult struct {
+	out bytes.Buffer
+	err os.Error
+}
+
+// forEach calls fn for every file, running up to jobs calls in parallel.
+// The output written to w is copied to stdout in the order of the files
+// and the errors are reported to stderr. forEach fails if any call does.
+func forEach(files []string, fn func(path string, w io.Writer) os.Error) os.Error {
+	if len(files) == 0 {
+		return os.NewError("no files given")
+	}
+	results := make([]chan *fileResult, len(files))
+	for i := range results {
+		results[i] = make(chan *fileResult, 1)
+	}
+	next := make(chan int)
+	go func() {
+		for i := range files {
+			next <- i
+		}
+		close(next)
+	}()
+	n := jobs
+	if n < 1 {
+		n = 1
+	}
+	for k := 0; k < n; k++ {
+		go func() {
+			for i := range next {
+				r := new(fileResult)
+				r.err = fn(files[i], &r.out)
+				results[i] <- r
+			}
+		}()
+	}
+	failed := 0
+	for i, path := range files {
+		r := <-results[i]
+		stdout.Write(r.out.Bytes())
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, r.err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(files))
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+	err := run(flag.Arg(0), flag.Args()[1:])
+	status := exitStatus(err)
+	if status == 2 {
+		fmt.Fprintf(os.Stderr, "schematic %s: %v\n", flag.Arg(0), err)
+	}
+	os.Exit(status)
+}
+
+// exitStatus returns the exit status of the tool for the error of run.
+func exitStatus(err os.Error) int {
+	switch err {
+	case nil:
+		return 0
+	case errDifferent:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krasin/schematic"
+)
+
+// runOutput runs the command and returns its output.
+func runOutput(t *testing.T, args ...string) string {
+	var buf bytes.Buffer
+	stdout = &buf
+	if err := run(args[0], args[1:]); err != nil {
+		t.Fatalf("schematic %s: %v", strings.Join(args, " "), err)
+	}
+	return buf.String()
+}
+
+func TestInfo(t *testing.T) {
+	out := runOutput(t, "info", "../../testdata/cylinder.schematic")
+	for _, want := range []string{"format:      schematic (Materials: Alpha)", "size:        128x128x128", "entities:    0", "materials:\n        1    1603996"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("info output does not contain %q:\n%s", want, out)
+		}
+	}
+	if out = runOutput(t, "info", "-blocks=false", "../../testdata/cylinder.schematic"); strings.Contains(out, "materials:") {
+		t.Errorf("info -blocks=false output contains materials:\n%s", out)
+	}
+	if err := run("info", []string{"missing.schematic"}); err == nil {
+		t.Errorf("info of a missing file: want error, got nil")
+	}
+	if err := run("bogus", nil); err == nil {
+		t.Errorf("unknown command: want error, got nil")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic-batch")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	names := []string{"a.schematic", "b.schematic", "sub/c.schematic", "sub/d.schematic"}
+	for i, name := range names {
+		if err = schematic.WriteSchematicFile(filepath.Join(dir, name), schematic.NewSchematic(i+1, 1, 1), nil); err != nil {
+			t.Fatalf("WriteSchematicFile: %v", err)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("junk"), 0644)
+
+	files, err := expandArgs([]string{filepath.Join(dir, "*.schematic"), filepath.Join(dir, "sub")})
+	if err != nil {
+		t.Fatalf("expandArgs: %v", err)
+	}
+	if len(files) != len(names) {
+		t.Fatalf("expandArgs: want %d files, got %v", len(names), files)
+	}
+	for i, name := range names {
+		if want := filepath.Join(dir, name); files[i] != want {
+			t.Errorf("expandArgs: files[%d]: want %s, got %s", i, want, files[i])
+		}
+	}
+	if _, err = expandArgs([]string{filepath.Join(dir, "*.mcedit")}); err == nil {
+		t.Errorf("expandArgs of a pattern without matches: want error, got nil")
+	}
+
+	// The output is ordered by the files, regardless of the parallelism.
+	out := runOutput(t, "info", "-blocks=false", "-j", "3", dir)
+	last := -1
+	for i, name := range names {
+		pos := strings.Index(out, fmt.Sprintf("size:        %dx1x1", i+1))
+		if pos <= last {
+			t.Errorf("info -j 3: %s is missing or out of order:\n%s", name, out)
+		}
+		last = pos
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "broken.schematic"), []byte("junk"), 0644)
+	var buf bytes.Buffer
+	stdout = &buf
+	if err = run("trim", []string{"-j", "2", dir}); err == nil || err.String() != "1 of 5 files failed" {
+		t.Errorf("trim with a broken file: want 1 of 5 files failed, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/krasin/schematic"
+)
+
+var renderCmd = &command{
+	name:  "render",
+	args:  "files...",
+	short: "render PNG previews",
+	batch: true,
+	flags: func() (*flag.FlagSet, func([]string) os.Error) {
+		fs := flag.NewFlagSet("render", flag.ContinueOnError)
+		view := fs.String("view", "top", "projection: top or iso")
+		scale := fs.Int("scale", 4, "size of a block in pixels (a quarter of a cube width for iso)")
+		angle := fs.Int("angle", 0, "clockwise rotation around the vertical axis: 0, 90, 180 or 270")
+		layers := fs.String("layers", "", "range of layers to render, min:max (max is exclusive)")
+		shadows := fs.Bool("shadows", false, "shade the top view with the shadows of higher blocks")
+		sun := fs.Int("sun", 135, "angle of the sun above the eastern horizon in degrees for -shadows: 90 is noon")
+		tiles := fs.Bool("tiles", false, "write a z/x/y.png tile pyramid of the top view into a directory named after the schematic")
+		out := fs.String("o", "", "output directory; by default, the PNG is written next to the schematic")
+		return fs, func(args []string) (err os.Error) {
+			opt := &schematic.RenderOptions{Scale: *scale, Angle: *angle, Shadows: *shadows, SunAngle: *sun}
+			switch *view {
+			case "top":
+				opt.View = schematic.TopView
+			case "iso":
+				opt.View = schematic.IsometricView
+			default:
+				return fmt.Errorf("unknown view %q", *view)
+			}
+			if opt.MinY, opt.MaxY, err = parseRange(*layers); err != nil {
+				return
+			}
+			return forEach(args, func(path string, w io.Writer) os.Error {
+				if *tiles {
+					return renderTiles(path, *out, opt.Scale)
+				}
+				return renderFile(path, *out, opt)
+			})
+		}
+	},
+}
+
+var showCmd = &command{
+	name:  "show",
+	args:  "file",
+	short: "print a layer in the terminal",
+	flags: func() (*flag.FlagSet, func([]string) os.Error) {
+		fs := flag.NewFlagSet("show", flag.ContinueOnError)
+		y := fs.Int("y", 0, "layer to print")
+		plain := fs.Bool("plain", false, "draw the blocks with letters instead of colors")
+		return fs, func(args []string) os.Error {
+			if len(args) != 1 {
+				return os.NewError("want exactly one file")
+			}
+			s, err := schematic.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			return schematic.RenderTerminal(stdout, s, schematic.AxisY, *y, &schematic.TerminalOptions{Plain: *plain})
+		}
+	},
+}
+
+// parseRange parses a "min:max" range. Both bounds may be omitted.
+func parseRange(str string) (min, max int, err os.Error) {
+	if str == "" {
+		return
+	}
+	i := strings.Index(str, ":")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q, want min:max", str)
+	}
+	if i > 0 {
+		if min, err = strconv.Atoi(str[:i]); err != nil {
+			return
+		}
+	}
+	if i+1 < len(str) {
+		if max, err = strconv.Atoi(str[i+1:]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// pngName returns the name of the preview of the schematic path.
+// If dir is empty, the preview is placed next to the schematic.
+func pngName(path, dir string) string {
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	base := filepath.Base(path)
+	if ext := filepath.Ext(base); ext != "" {
+		base = base[:len(base)-len(ext)]
+	}
+	return filepath.Join(dir, base+".png")
+}
+
+func renderFile(path, dir string, opt *schematic.RenderOptions) os.Error {
+	s, err := schematic.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m, err := schematic.Render(s, opt)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(pngName(path, dir))
+	if err != nil {
+		return err
+	}
+	if err = png.Encode(f, m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// renderTiles writes the tile pyramid of the schematic path to the
+// directory named like its preview without the extension.
+func renderTiles(path, dir string, scale int) os.Error {
+	s, err := schematic.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name := pngName(path, dir)
+	_, err = schematic.WriteTiles(name[:len(name)-len(".png")], s, &schematic.TileOptions{Scale: scale})
+	return err
+}
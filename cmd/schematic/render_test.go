@@ -0,0 +1,67 @@
+package main
+
+import (
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic-render")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	runOutput(t, "render", "-view", "iso", "-scale", "1", "-angle", "90", "-layers", "0:64", "-o", dir, "../../testdata/cylinder.schematic")
+	f, err := os.Open(filepath.Join(dir, "cylinder.png"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	m, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("png.Decode: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 512 || b.Dy() != 384 {
+		t.Errorf("Size: want 512x384, got %dx%d", b.Dx(), b.Dy())
+	}
+	runOutput(t, "render", "-tiles", "-scale", "1", "-o", dir, "../../testdata/cylinder.schematic")
+	if _, err = os.Stat(filepath.Join(dir, "cylinder", "0", "0", "0.png")); err != nil {
+		t.Errorf("render -tiles: %v", err)
+	}
+	if err = run("render", []string{"-layers", "64", "../../testdata/cylinder.schematic"}); err == nil {
+		t.Errorf("render -layers 64: want error, got nil")
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		str      string
+		min, max int
+	}{
+		{"", 0, 0},
+		{":", 0, 0},
+		{"3:", 3, 0},
+		{":7", 0, 7},
+		{"3:7", 3, 7},
+	}
+	for _, tt := range tests {
+		min, max, err := parseRange(tt.str)
+		if err != nil || min != tt.min || max != tt.max {
+			t.Errorf("parseRange(%q): want %d, %d, got %d, %d, %v", tt.str, tt.min, tt.max, min, max, err)
+		}
+	}
+}
+
+func TestShow(t *testing.T) {
+	out := runOutput(t, "show", "-y", "64", "-plain", "../../testdata/cylinder.schematic")
+	if lines := strings.Split(out, "\n"); len(lines) < 128 || len(lines[0]) != 256 || !strings.Contains(out, "a Stone\n") {
+		t.Errorf("show: got %d lines:\n%s", len(lines), out)
+	}
+	if err := run("show", []string{"-y", "128", "../../testdata/cylinder.schematic"}); err == nil {
+		t.Errorf("show -y 128: want error, got nil")
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/krasin/schematic"
+)
+
+// A transformFunc changes a schematic. It may modify s and return it.
+// Messages for the user are written to w.
+type transformFunc func(s *schematic.Schematic, w io.Writer) (*schematic.Schematic, os.Error)
+
+// transformCommand returns a command applying a transformation to every
+// file. The setup function defines the flags and returns the transformation;
+// it is called once the flags are parsed.
+func transformCommand(name, short string, setup func(fs *flag.FlagSet) func() (transformFunc, os.Error)) *command {
+	return &command{
+		name:  name,
+		args:  "files...",
+		short: short,
+		batch: true,
+		flags: func() (*flag.FlagSet, func([]string) os.Error) {
+			fs := flag.NewFlagSet(name, flag.ContinueOnError)
+			out := fs.String("o", "", "output directory; by default, the files are overwritten")
+			build := setup(fs)
+			return fs, func(args []string) os.Error {
+				f, err := build()
+				if err != nil {
+					return err
+				}
+				return forEach(args, func(path string, w io.Writer) os.Error {
+					return transformFile(path, *out, w, f)
+				})
+			}
+		},
+	}
+}
+
+func transformFile(path, dir string, w io.Writer, f transformFunc) os.Error {
+	s, err := schematic.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if s, err = f(s, w); err != nil {
+		return err
+	}
+	if dir != "" {
+		path = filepath.Join(dir, filepath.Base(path))
+	}
+	// Sponge and Litematica files are written as .schematic files next to
+	// them.
+	if ext := filepath.Ext(path); !strings.EqualFold(ext, schematic.Ext) {
+		path = path[:len(path)-len(ext)] + schematic.Ext
+	}
+	return schematic.WriteSchematicFile(path, s, nil)
+}
+
+var rotateCmd = transformCommand("rotate", "rotate around the vertical axis", func(fs *flag.FlagSet) func() (transformFunc, os.Error) {
+	angle := fs.Int("angle", 90, "clockwise rotation as seen from above: 90, 180 or 270")
+	return func() (transformFunc, os.Error) {
+		return func(s *schematic.Schematic, w io.Writer) (*schematic.Schematic, os.Error) {
+			return s.Rotate(*angle)
+		}, nil
+	}
+})
+
+var flipCmd = transformCommand("flip", "mirror along an axis", func(fs *flag.FlagSet) func() (transformFunc, os.Error) {
+	axisName := fs.String("axis", "x", "axis to mirror along: x, y or z")
+	return func() (transformFunc, os.Error) {
+		axis, err := parseAxis(*axisName)
+		if err != nil {
+			return nil, err
+		}
+		return func(s *schematic.Schematic, w io.Writer) (*schematic.Schematic, os.Error) {
+			return s.Flip(axis), nil
+		}, nil
+	}
+})
+
+var cropCmd = transformCommand("crop", "cut out a box", func(fs *flag.FlagSet) func() (transformFunc, os.Error) {
+	min := fs.String("min", "0,0,0", "the lowest corner of the box: x,y,z")
+	max := fs.String("max", "", "the corner opposite to min, exclusive: x,y,z")
+	return func() (transformFunc, os.Error) {
+		var b schematic.Box
+		var err os.Error
+		if b.MinX, b.MinY, b.MinZ, err = parsePoint(*min); err != nil {
+			return nil, err
+		}
+		if b.MaxX, b.MaxY, b.MaxZ, err = parsePoint(*max); err != nil {
+			return nil, err
+		}
+		return func(s *schematic.Schematic, w io.Writer) (*schematic.Schematic, os.Error) {
+			return s.Crop(b)
+		}, nil
+	}
+})
+
+var trimCmd = transformCommand("trim", "remove the surrounding air", func(fs *flag.FlagSet) func() (transformFunc, os.Error) {
+	return func() (transformFunc, os.Error) {
+		return func(s *schematic.Schematic, w io.Writer) (*schematic.Schematic, os.Error) {
+			return s.Trim(), nil
+		}, nil
+	}
+})
+
+var stackCmd = transformCommand("stack", "repeat along an axis", func(fs *flag.FlagSet) func() (transformFunc, os.Error) {
+	axisName := fs.String("axis", "x", "axis to repeat along: x, y or z")
+	n := fs.Int("n", 2, "number of copies")
+	return func() (transformFunc, os.Error) {
+		axis, err := parseAxis(*axisName)
+		if err != nil {
+			return nil, err
+		}
+		return func(s *schematic.Schematic, w io.Writer) (*schematic.Schematic, os.Error) {
+			return s.Stack(axis, *n)
+		}, nil
+	}
+})
+
+var replaceCmd = transformCommand("replace", "replace a block with a pattern of blocks", func(fs *flag.FlagSet) func() (transformFunc, os.Error) {
+	fromStr := fs.String("from", "", "block to replace: id or id:data; without data, all data values match")
+	toStr := fs.String("to", "", "new blocks: id, id:data or a pattern such as 50%1,50%4")
+	maskStr := fs.String("mask", "", "replace only the blocks selected by the mask, such as '~air' or '#region:0,0,0,9,9,9'")
+	return func() (transformFunc, os.Error) {
+		from, hasData, err := parseBlock(*fromStr)
+		if err != nil {
+			return nil, err
+		}
+		to, err := schematic.ParsePattern(*toStr)
+		if err != nil {
+			return nil, err
+		}
+		mask := schematic.BlockMask(from)
+		if !hasData {
+			mask = schematic.IdMask(from.Id)
+		}
+		if *maskStr != "" {
+			m, err := schematic.ParseMask(*maskStr)
+			if err != nil {
+				return nil, err
+			}
+			mask = schematic.AndMask(mask, m)
+		}
+		return func(s *schematic.Schematic, w io.Writer) (*schematic.Schematic, os.Error) {
+			n := s.Fill(mask, to)
+			fmt.Fprintf(w, "%d blocks replaced\n", n)
+			return s, nil
+		}, nil
+	}
+})
+
+func parseAxis(str string) (schematic.Axis, os.Error) {
+	switch str {
+	case "x":
+		return schematic.AxisX, nil
+	case "y":
+		return schematic.AxisY, nil
+	case "z":
+		return schematic.AxisZ, nil
+	}
+	return 0, fmt.Errorf("unknown axis %q, want x, y or z", str)
+}
+
+// parsePoint parses "x,y,z".
+func parsePoint(str string) (x, y, z int, err os.Error) {
+	parts := strings.Split(str, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("invalid point %q, want x,y,z", str)
+	}
+	var v [3]int
+	for i, p := range parts {
+		if v[i], err = strconv.Atoi(strings.TrimSpace(p)); err != nil {
+			return
+		}
+	}
+	return v[0], v[1], v[2], nil
+}
+
+// parseBlock parses "id" or "id:data" and reports whether the data is given.
+func parseBlock(str string) (b schematic.Block, hasData bool, err os.Error) {
+	id, data := str, ""
+	if i := strings.Index(str, ":"); i >= 0 {
+		id, data, hasData = str[:i], str[i+1:], true
+	}
+	var v int
+	if v, err = strconv.Atoi(id); err != nil || v < 0 || v > schematic.MaxId {
+		return b, false, fmt.Errorf("invalid block id %q", id)
+	}
+	b.Id = uint16(v)
+	if hasData {
+		if v, err = strconv.Atoi(data); err != nil || v < 0 || v > 15 {
+			return b, false, fmt.Errorf("invalid block data %q", data)
+		}
+		b.Data = byte(v)
+	}
+	return
+}
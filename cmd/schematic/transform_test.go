@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krasin/schematic"
+)
+
+func TestTransform(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic-transform")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.schematic")
+	s := schematic.NewSchematic(4, 1, 2)
+	s.SetBlock(1, 0, 0, schematic.Block{Id: 35, Data: 3})
+	if err = schematic.WriteSchematicFile(path, s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+
+	runOutput(t, "rotate", "-angle", "90", path)
+	runOutput(t, "stack", "-axis", "y", "-n", "3", path)
+	runOutput(t, "crop", "-min", "0,1,0", "-max", "2,3,4", path)
+	if out := runOutput(t, "replace", "-from", "35", "-to", "1:1", path); !strings.Contains(out, "2 blocks replaced") {
+		t.Errorf("replace: want 2 blocks replaced, got %q", out)
+	}
+	if out := runOutput(t, "replace", "-from", "1", "-to", "35", "-mask", "#region:9,9,9,9,9,9", path); !strings.Contains(out, "0 blocks replaced") {
+		t.Errorf("replace -mask: want 0 blocks replaced, got %q", out)
+	}
+	runOutput(t, "trim", path)
+	runOutput(t, "flip", "-axis", "z", "-o", dir, path)
+
+	if s, err = schematic.ReadSchematicFile(path); err != nil {
+		t.Fatalf("ReadSchematicFile: %v", err)
+	}
+	if s.Width != 1 || s.Height != 2 || s.Length != 1 || s.Block(0, 1, 0) != (schematic.Block{Id: 1, Data: 1}) {
+		t.Errorf("Result: got %dx%dx%d, blocks %v, data %v", s.Width, s.Height, s.Length, s.Blocks, s.Data)
+	}
+
+	for _, args := range [][]string{
+		{"rotate", "-angle", "30", path},
+		{"flip", "-axis", "w", path},
+		{"crop", "-max", "1,1", path},
+		{"replace", "-from", "stone", "-to", "1", path},
+		{"replace", "-from", "1", "-to", "2", "-mask", "#box", path},
+		{"replace", "-from", "1", "-to", "50%2,x", path},
+		{"trim"},
+	} {
+		if err = run(args[0], args[1:]); err == nil {
+			t.Errorf("schematic %s: want error, got nil", strings.Join(args, " "))
+		}
+	}
+}
+
+func TestParseBlock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    schematic.Block
+		hasData bool
+		ok      bool
+	}{
+		{"35", schematic.Block{Id: 35}, false, true},
+		{"35:3", schematic.Block{Id: 35, Data: 3}, true, true},
+		{"300", schematic.Block{Id: 300}, false, true},
+		{"4095:15", schematic.Block{Id: 4095, Data: 15}, true, true},
+		{"4096", schematic.Block{}, false, false},
+		{"-1", schematic.Block{}, false, false},
+		{"1:16", schematic.Block{}, false, false},
+	}
+	for _, tt := range tests {
+		b, hasData, err := parseBlock(tt.in)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("parseBlock(%q): want error, got %v", tt.in, b)
+			}
+			continue
+		}
+		if err != nil || b != tt.want || hasData != tt.hasData {
+			t.Errorf("parseBlock(%q): want %v, %v, got %v, %v, %v", tt.in, tt.want, tt.hasData, b, hasData, err)
+		}
+	}
+}
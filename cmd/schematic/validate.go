@@ -0,0 +1,70 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krasin/schematic"
+)
+
+var validateCmd = &command{
+	name:  "validate",
+	args:  "files...",
+	short: "report inconsistencies such as misplaced tile entities",
+	batch: true,
+	flags: func() (*flag.FlagSet, func([]string) os.Error) {
+		fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+		repair := fs.Bool("repair", false, "fix the recoverable problems and overwrite the files")
+		return fs, func(args []string) os.Error {
+			return forEach(args, func(path string, w io.Writer) os.Error {
+				s, err := readLenient(path)
+				if err != nil {
+					return err
+				}
+				if *repair {
+					fixes := s.Repair()
+					for _, fix := range fixes {
+						fmt.Fprintf(w, "%s: %s\n", path, fix)
+					}
+					if len(fixes) > 0 && !strings.EqualFold(filepath.Ext(path), schematic.Ext) {
+						return os.NewError("only .schematic files are repaired")
+					}
+					if len(fixes) > 0 {
+						if err = schematic.WriteSchematicFile(path, s, nil); err != nil {
+							return err
+						}
+					}
+				}
+				problems := s.Validate()
+				for _, p := range problems {
+					fmt.Fprintf(w, "%s: %v\n", path, p)
+				}
+				if len(problems) > 0 {
+					return fmt.Errorf("%d problems", len(problems))
+				}
+				return nil
+			})
+		}
+	},
+}
+
+// readLenient reads a schematic which may have Blocks and Data arrays not
+// matching its dimensions. The files of other formats are read by ReadFile.
+func readLenient(path string) (*schematic.Schematic, os.Error) {
+	if !strings.EqualFold(filepath.Ext(path), schematic.Ext) {
+		return schematic.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return schematic.ReadSchematicOptions(f, &schematic.ReadOptions{Lenient: true})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krasin/schematic"
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic-validate")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	good, bad := filepath.Join(dir, "good.schematic"), filepath.Join(dir, "bad.schematic")
+	s := schematic.NewSchematic(2, 1, 1)
+	if err = schematic.WriteSchematicFile(good, s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	s.TileEntities = append(s.TileEntities, schematic.TileEntity{X: 0, Y: 0, Z: 0, Id: "Chest"})
+	if err = schematic.WriteSchematicFile(bad, s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+
+	if out := runOutput(t, "validate", good); out != "" {
+		t.Errorf("validate of a valid file: got %q", out)
+	}
+	var buf bytes.Buffer
+	stdout = &buf
+	if err = run("validate", []string{good, bad}); err == nil {
+		t.Errorf("validate of an invalid file: want error, got nil")
+	}
+	if want := bad + ": wrong tile entity at 0,0,0: Chest on block 0:0\n"; buf.String() != want {
+		t.Errorf("validate output: want %q, got %q", want, buf.String())
+	}
+	if strings.Contains(buf.String(), good) {
+		t.Errorf("validate reported the valid file")
+	}
+
+	out := runOutput(t, "validate", "-repair", bad)
+	if want := bad + ": removed wrong tile entity: Chest at 0,0,0\n"; out != want {
+		t.Errorf("validate -repair output: want %q, got %q", want, out)
+	}
+	if out = runOutput(t, "validate", bad); out != "" {
+		t.Errorf("validate of the repaired file: got %q", out)
+	}
+
+	// WriteSchematic refuses to write arrays of the wrong size.
+	s.Blocks = s.Blocks[:1]
+	var data bytes.Buffer
+	w := nbt.NewWriter(&data)
+	if err = w.WriteTag("Schematic", s.NBT()); err != nil {
+		t.Fatalf("WriteTag: %v", err)
+	}
+	w.Flush()
+	if err = ioutil.WriteFile(bad, data.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	buf.Reset()
+	stdout = &buf
+	if err = run("validate", []string{bad}); err == nil || !strings.Contains(buf.String(), "size mismatch") {
+		t.Errorf("validate of a short Blocks array: got %v, %q", err, buf.String())
+	}
+	if out = runOutput(t, "validate", "-repair", bad); !strings.Contains(out, "padded Blocks from 1 to 2 bytes") {
+		t.Errorf("validate -repair of a short Blocks array: got %q", out)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"image"
+)
+
+// blockColors are the average colors of the block textures.
+var blockColors = map[uint16]image.RGBAColor{
+	1:   {125, 125, 125, 255}, // stone
+	2:   {95, 159, 53, 255},   // grass
+	3:   {134, 96, 67, 255},   // dirt
+	4:   {122, 122, 122, 255}, // cobblestone
+	5:   {157, 128, 79, 255},  // planks
+	6:   {72, 120, 36, 255},   // sapling
+	7:   {84, 84, 84, 255},    // bedrock
+	8:   {47, 67, 244, 255},   // flowing water
+	9:   {47, 67, 244, 255},   // water
+	10:  {207, 92, 20, 255},   // flowing lava
+	11:  {207, 92, 20, 255},   // lava
+	12:  {219, 211, 160, 255}, // sand
+	13:  {126, 124, 122, 255}, // gravel
+	14:  {143, 140, 125, 255}, // gold ore
+	15:  {136, 130, 127, 255}, // iron ore
+	16:  {115, 115, 115, 255}, // coal ore
+	17:  {102, 81, 49, 255},   // log
+	18:  {60, 125, 30, 255},   // leaves
+	19:  {194, 195, 84, 255},  // sponge
+	20:  {218, 240, 244, 255}, // glass
+	21:  {102, 112, 134, 255}, // lapis ore
+	22:  {38, 67, 137, 255},   // lapis block
+	24:  {216, 209, 157, 255}, // sandstone
+	31:  {100, 150, 50, 255},  // tall grass
+	37:  {241, 249, 2, 255},   // dandelion
+	38:  {194, 27, 27, 255},   // rose
+	41:  {249, 236, 78, 255},  // gold block
+	42:  {219, 219, 219, 255}, // iron block
+	43:  {159, 159, 159, 255}, // double slab
+	44:  {159, 159, 159, 255}, // slab
+	45:  {146, 99, 86, 255},   // bricks
+	46:  {170, 58, 32, 255},   // TNT
+	47:  {108, 88, 58, 255},   // bookshelf
+	48:  {90, 108, 90, 255},   // mossy cobblestone
+	49:  {20, 18, 29, 255},    // obsidian
+	50:  {255, 214, 91, 255},  // torch
+	53:  {157, 128, 79, 255},  // oak stairs
+	54:  {164, 116, 42, 255},  // chest
+	56:  {129, 140, 143, 255}, // diamond ore
+	57:  {97, 219, 213, 255},  // diamond block
+	58:  {107, 71, 43, 255},   // crafting table
+	60:  {115, 78, 45, 255},   // farmland
+	61:  {96, 96, 96, 255},    // furnace
+	67:  {122, 122, 122, 255}, // cobblestone stairs
+	73:  {132, 107, 107, 255}, // redstone ore
+	78:  {240, 251, 251, 255}, // snow layer
+	79:  {125, 173, 255, 255}, // ice
+	80:  {240, 251, 251, 255}, // snow
+	81:  {13, 99, 24, 255},    // cactus
+	82:  {158, 164, 176, 255}, // clay
+	85:  {157, 128, 79, 255},  // fence
+	86:  {227, 144, 29, 255},  // pumpkin
+	87:  {111, 54, 52, 255},   // netherrack
+	88:  {84, 64, 51, 255},    // soul sand
+	89:  {143, 118, 69, 255},  // glowstone
+	98:  {122, 121, 122, 255}, // stone bricks
+	103: {151, 153, 36, 255},  // melon
+	110: {111, 99, 107, 255},  // mycelium
+	112: {44, 22, 26, 255},    // nether brick
+	121: {221, 223, 165, 255}, // end stone
+	129: {109, 128, 116, 255}, // emerald ore
+	133: {81, 217, 117, 255},  // emerald block
+	152: {171, 27, 9, 255},    // redstone block
+	155: {236, 233, 226, 255}, // quartz block
+	159: {210, 178, 161, 255}, // stained clay, see dyeColors
+	172: {150, 92, 66, 255},   // hardened clay
+	173: {18, 18, 18, 255},    // coal block
+	174: {165, 194, 245, 255}, // packed ice
+}
+
+// dyeColors are the colors of the 16 wool colors, indexed by the data value.
+var dyeColors = []image.RGBAColor{
+	{233, 236, 236, 255}, // white
+	{240, 118, 19, 255},  // orange
+	{189, 68, 179, 255},  // magenta
+	{58, 175, 217, 255},  // light blue
+	{248, 198, 39, 255},  // yellow
+	{112, 185, 25, 255},  // lime
+	{237, 141, 172, 255}, // pink
+	{62, 68, 71, 255},    // gray
+	{142, 142, 134, 255}, // light gray
+	{21, 137, 145, 255},  // cyan
+	{121, 42, 172, 255},  // purple
+	{53, 57, 157, 255},   // blue
+	{114, 71, 40, 255},   // brown
+	{84, 109, 27, 255},   // green
+	{161, 39, 34, 255},   // red
+	{20, 21, 25, 255},    // black
+}
+
+// BlockColor returns the color used to draw the block in previews.
+// Air is transparent, all other colors are opaque. Blocks without a known color get a stable
+// color derived from their id.
+func BlockColor(id uint16, data byte) image.RGBAColor {
+	switch id {
+	case 0:
+		return image.RGBAColor{}
+	case 35, 95, 159, 171: // wool, stained glass, stained clay, carpet
+		return dyeColors[data&15]
+	}
+	if c, ok := blockColors[id]; ok {
+		return c
+	}
+	h := uint32(id) * 2654435761
+	return image.RGBAColor{uint8(h >> 24), uint8(h >> 16), uint8(h >> 8), 255}
+}
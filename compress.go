@@ -0,0 +1,135 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Compression is the container format of the NBT stream.
+type Compression int
+
+const (
+	Gzip Compression = iota // gzip, used by all Minecraft tools
+	Zlib                    // zlib, as in region files
+	None                    // raw NBT
+	Zstd                    // zstd, for archives; Minecraft tools do not read it
+)
+
+// WriteOptions control the encoding of a schematic.
+// A nil *WriteOptions is equivalent to the zero value.
+//
+// The writers of NBT files take them: WriteSchematicOptions,
+// WriteLitematicOptions, WriteSpongeOptions, NewWriter and
+// WriteBundleOptions, which writes the parts of the bundle with them. The
+// text and JSON writers, such as WriteBO3, WriteCSV and WriteViewerJSON,
+// compress nothing and take no options.
+//
+// With any options, the NBT writers produce the same bytes for the same
+// input: the tags are written in the order of the fields and of Extra, the
+// palettes built by conversions list the states in the order of their
+// first use and the block properties sorted by name, and the gzip header
+// holds neither a modification time nor a file name. This is not promised
+// for the JSON documents holding maps, such as the manifest of a bundle,
+// whose key order is left to the json package.
+type WriteOptions struct {
+	Compression Compression
+
+	// Level is the compression level from 1 (best speed) to 9 (best
+	// compression). Zero selects the default level.
+	Level int
+
+	// Preview makes the writers of the formats storing a preview image,
+	// such as WriteLitematicOptions, draw one with Thumbnail if there is
+	// none.
+	Preview bool
+
+	// VoidAir makes WriteSchematicOptions write air as structure voids,
+	// so that the schematic pasted as a structure keeps the blocks at
+	// its air positions. The schematic itself is not changed.
+	VoidAir bool
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() os.Error {
+	return nil
+}
+
+// compressor returns a writer compressing the data according to opt.
+// The caller must close it to flush the compressed stream.
+func (opt *WriteOptions) compressor(w io.Writer) (io.WriteCloser, os.Error) {
+	if opt == nil {
+		opt = new(WriteOptions)
+	}
+	level := opt.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if level < gzip.DefaultCompression || level > gzip.BestCompression {
+		return nil, fmt.Errorf("Invalid compression level: %d", opt.Level)
+	}
+	switch opt.Compression {
+	case Gzip:
+		return gzip.NewWriterLevel(w, level)
+	case Zlib:
+		return zlib.NewWriterLevel(w, level)
+	case None:
+		return nopCloser{w}, nil
+	case Zstd:
+		return newZstdWriter(w, level), nil
+	}
+	return nil, fmt.Errorf("Unknown compression: %d", opt.Compression)
+}
+
+// decompressor detects the compression of the stream and returns a reader
+// of the decompressed NBT data.
+func decompressor(r io.Reader) (rd io.Reader, err os.Error) {
+	br := bufio.NewReader(r)
+	var magic []byte
+	if magic, err = br.Peek(4); err != nil && len(magic) < 2 {
+		return
+	}
+	err = nil
+	switch {
+	case len(magic) == 4 && magic[0] == 0x28 && magic[1] == 0xb5 && magic[2] == 0x2f && magic[3] == 0xfd:
+		return newZstdReader(br), nil
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(br)
+	case magic[0]&0x0f == 8 && (int(magic[0])<<8|int(magic[1]))%31 == 0:
+		return zlib.NewReader(br)
+	}
+	// Uncompressed NBT starts with a compound tag.
+	return br, nil
+}
+
+// A sizeLimiter fails reads past max bytes of the underlying reader.
+type sizeLimiter struct {
+	r      io.Reader
+	n, max int64 // the bytes left and the limit
+}
+
+func (l *sizeLimiter) Read(p []byte) (n int, err os.Error) {
+	if l.n <= 0 {
+		// The data may end right at the limit.
+		var b [1]byte
+		if k, err := l.r.Read(b[:]); k == 0 && err == os.EOF {
+			return 0, os.EOF
+		}
+		return 0, fmt.Errorf("Decompressed data is larger than %d bytes", l.max)
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err = l.r.Read(p)
+	l.n -= int64(n)
+	return
+}
@@ -0,0 +1,262 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rand"
+	"testing"
+)
+
+func TestWriteOptions(t *testing.T) {
+	vol := readTestSchematic(t, "testdata/cylinder.schematic")
+	sizes := make(map[int]int)
+	for _, c := range []Compression{Gzip, Zlib, None, Zstd} {
+		for _, level := range []int{0, 1, 9} {
+			var buf bytes.Buffer
+			if err := WriteSchematicOptions(&buf, vol, &WriteOptions{Compression: c, Level: level}); err != nil {
+				t.Fatalf("WriteSchematicOptions(%d, %d): %v", c, level, err)
+			}
+			if c == Gzip {
+				sizes[level] = buf.Len()
+			}
+			got, err := ReadSchematic(&buf)
+			if err != nil {
+				t.Fatalf("ReadSchematic(%d, %d): %v", c, level, err)
+			}
+			if !bytes.Equal(got.Blocks, vol.Blocks) {
+				t.Errorf("Compression %d, level %d: Blocks differ after round trip", c, level)
+			}
+		}
+	}
+	if sizes[9] > sizes[1] {
+		t.Errorf("Level 9 output (%d bytes) is larger than level 1 output (%d bytes)", sizes[9], sizes[1])
+	}
+	if err := WriteSchematicOptions(new(bytes.Buffer), vol, &WriteOptions{Level: 10}); err == nil {
+		t.Errorf("WriteSchematicOptions with Level 10: want error, got nil")
+	}
+}
+
+// zstdInputs returns data which makes the zstd writer emit raw, RLE and
+// compressed blocks, with Huffman coded literals of both table kinds.
+func zstdInputs() [][]byte {
+	r := rand.New(rand.NewSource(1))
+	random := make([]byte, 150000)
+	for i := range random {
+		random[i] = byte(r.Intn(256))
+	}
+	blocks := make([]byte, 300000)
+	for i := 1; i < len(blocks); i++ {
+		switch r.Intn(8) {
+		case 0:
+			blocks[i] = byte(r.Intn(200))
+		case 1, 2, 3:
+			blocks[i] = blocks[i-1]
+		}
+	}
+	var text []byte
+	for i := 0; len(text) < 200000; i++ {
+		text = append(text, "stone dirt air glass "[r.Intn(16):]...)
+		text = append(text, byte('a'+r.Intn(26)))
+	}
+	return [][]byte{nil, []byte("abc"), random, blocks, text, make([]byte, 200000)}
+}
+
+func TestZstdRoundTrip(t *testing.T) {
+	for i, in := range zstdInputs() {
+		for _, level := range []int{1, 6, 9} {
+			var buf bytes.Buffer
+			z := newZstdWriter(&buf, level)
+			for p := in; len(p) > 0; p = p[imin(len(p), 50000):] {
+				z.Write(p[:imin(len(p), 50000)])
+			}
+			if err := z.Close(); err != nil {
+				t.Fatalf("%d: Close: %v", i, err)
+			}
+			if level == 6 && len(in) > 1000 && buf.Len() > len(in)+len(in)/1000 {
+				t.Errorf("%d: %d bytes compressed to %d", i, len(in), buf.Len())
+			}
+			r, err := decompressor(&buf)
+			if err != nil {
+				t.Fatalf("%d: decompressor: %v", i, err)
+			}
+			out, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Errorf("%d, level %d: ReadAll: %v", i, level, err)
+				continue
+			}
+			if !bytes.Equal(out, in) {
+				t.Errorf("%d, level %d: the data differs after round trip", i, level)
+			}
+		}
+	}
+}
+
+func TestZstdCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	z := newZstdWriter(&buf, 0)
+	z.Write(zstdInputs()[4])
+	z.Close()
+	data := buf.Bytes()
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"truncated", data[:len(data)/2]},
+		{"checksum", append(append([]byte(nil), data[:len(data)-1]...), data[len(data)-1]^1)},
+		{"window", append([]byte{0x28, 0xb5, 0x2f, 0xfd, 0, 0xf8}, data[6:]...)},
+		{"block", append(append([]byte(nil), data[:20]...), make([]byte, 100)...)},
+	}
+	for _, tt := range tests {
+		r, err := decompressor(bytes.NewBuffer(tt.in))
+		if err == nil {
+			_, err = ioutil.ReadAll(r)
+		}
+		if err == nil {
+			t.Errorf("%s: error expected", tt.name)
+		}
+	}
+}
+
+// zstdReference is a frame written by the reference zstd tool at level
+// 19, with Huffman coded literals and fitted tables for all sequence
+// symbols.
+const zstdReference = "" +
+	"28b52ffd64e7010d040042081512b0191bbc06b57d98442b376d6e39de090e04" +
+	"c09f476ccfeabd259aa86c84d8a3297b228e9df3656bdaae2795953a34975568" +
+	"79f4b7aca6ab33995ad362fa98bed78dec7596a905262890645102610283488e" +
+	"214ea82188be7f06e0ad061014e603e7edb615b8a4d9771e8255ec89d76b63dd" +
+	"51ce51575e76470409a42a094f15e2"
+
+func TestZstdReference(t *testing.T) {
+	in, err := hex.DecodeString(zstdReference)
+	if err != nil {
+		t.Fatalf("hex.DecodeString: %v", err)
+	}
+	r, err := decompressor(bytes.NewBuffer(in))
+	if err != nil {
+		t.Fatalf("decompressor: %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	var want bytes.Buffer
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&want, "block %d: stone %d\n", i, i*i%97)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("want %q, got %q", want.Bytes(), got)
+	}
+}
+
+func TestZstdFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zstd")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	want := readTestSchematic(t, "testdata/cylinder.schematic")
+	name := filepath.Join(dir, "cylinder"+Ext+ZstdExt)
+	if err = WriteSchematicFile(name, want, &WriteOptions{Compression: Zstd}); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	got, err := ReadFile(name)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !bytes.Equal(got.Blocks, want.Blocks) || !bytes.Equal(got.Data, want.Data) {
+		t.Errorf("ReadFile: the blocks differ after round trip")
+	}
+	if !isSchematicFile(name) {
+		t.Errorf("isSchematicFile(%s): want true", name)
+	}
+	if f := fileFormat("house.SCHEM" + ZstdExt); f != "sponge" {
+		t.Errorf("fileFormat: want sponge, got %s", f)
+	}
+}
+
+func TestMultistream(t *testing.T) {
+	raw := testNBT(2, 1, 1, []byte{1, 2}, []byte{0, 0}).Bytes()
+	half := len(raw) / 2
+	concat := append(gzipBytes(raw[:half]), gzipBytes(raw[half:])...)
+	garbage := append(gzipBytes(raw), "garbage"...)
+	tests := []struct {
+		in     []byte
+		strict bool
+		ok     bool
+	}{
+		{concat, false, true},
+		{concat, true, true},
+		{garbage, false, true},
+		{garbage, true, false},
+	}
+	for i, tt := range tests {
+		vol, err := ReadSchematicOptions(bytes.NewBuffer(tt.in), &ReadOptions{Strict: tt.strict})
+		if tt.ok && err != nil {
+			t.Errorf("%d: ReadSchematicOptions: %v", i, err)
+			continue
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%d: ReadSchematicOptions: want error, got nil", i)
+			continue
+		}
+		if tt.ok && !bytes.Equal(vol.Blocks, []byte{1, 2}) {
+			t.Errorf("%d: Blocks: want [1 2], got %v", i, vol.Blocks)
+		}
+	}
+}
+
+func TestReadMaxSize(t *testing.T) {
+	raw := testNBT(2, 1, 1, []byte{1, 2}, []byte{0, 0}).Bytes()
+	in := gzipBytes(raw)
+	for _, max := range []int64{int64(len(raw)), int64(len(raw)) + 1} {
+		opt := &ReadOptions{Strict: true, MaxSize: max}
+		if _, err := ReadSchematicOptions(bytes.NewBuffer(in), opt); err != nil {
+			t.Errorf("MaxSize %d of %d bytes: %v", max, len(raw), err)
+		}
+	}
+	if _, err := ReadSchematicOptions(bytes.NewBuffer(in), &ReadOptions{MaxSize: int64(len(raw)) - 1}); err == nil {
+		t.Errorf("MaxSize %d of %d bytes: want error, got nil", len(raw)-1, len(raw))
+	}
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	write := func() (schematic, litematic []byte) {
+		s := newChestVolume()
+		s.SetBlock(0, 1, 0, Block{53, 2})
+		s.setMapping(IdMapping{"minecraft:stone": 1, "minecraft:wool": 35, "minecraft:chest": 54, "minecraft:oak_stairs": 53})
+		s.SetMetadata(Metadata{Name: "Tower", Tags: []string{"b", "a"}})
+		var buf bytes.Buffer
+		if err := WriteSchematic(&buf, s); err != nil {
+			t.Fatalf("WriteSchematic: %v", err)
+		}
+		schematic = buf.Bytes()
+		l, err := SplitLitematic(s, nil)
+		if err != nil {
+			t.Fatalf("SplitLitematic: %v", err)
+		}
+		var lbuf bytes.Buffer
+		if err = WriteLitematic(&lbuf, l); err != nil {
+			t.Fatalf("WriteLitematic: %v", err)
+		}
+		return schematic, lbuf.Bytes()
+	}
+	s1, l1 := write()
+	for i := 0; i < 5; i++ {
+		s2, l2 := write()
+		if !bytes.Equal(s1, s2) {
+			t.Fatalf("WriteSchematic output differs between runs")
+		}
+		if !bytes.Equal(l1, l2) {
+			t.Fatalf("WriteLitematic output differs between runs")
+		}
+	}
+	// The gzip header has no flags, such as the file name, and no time.
+	if !bytes.Equal(s1[3:8], []byte{0, 0, 0, 0, 0}) {
+		t.Errorf("gzip header: got % x", s1[:10])
+	}
+}
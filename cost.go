@@ -0,0 +1,154 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"json"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A PriceTable holds the prices of the blocks for Cost, such as those of a
+// server economy.
+type PriceTable struct {
+	// Prices are the prices of single blocks by block state name, such as
+	// "minecraft:red_wool", or by "id:data" for the blocks without a state.
+	Prices map[string]float64 `json:"prices"`
+
+	// Categories are the budget categories of the blocks by the keys of
+	// Prices. The other blocks are in the categories named after their
+	// Info: "redstone", "liquid", "vegetation", "solid" or "other".
+	Categories map[string]string `json:"categories"`
+
+	// Default is the price of the blocks missing from Prices.
+	Default float64 `json:"default"`
+}
+
+// ReadPriceTable reads a price table stored as a JSON object with the
+// fields of PriceTable, such as
+//
+//	{"prices": {"stone": 0.5, "red_wool": 2}, "categories": {"red_wool": "decor"}, "default": 1}
+//
+// The namespace of the block state names defaults to minecraft.
+func ReadPriceTable(r io.Reader) (*PriceTable, os.Error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var t PriceTable
+	if err = json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("Invalid price table: %v", err)
+	}
+	prices := make(map[string]float64)
+	for k, p := range t.Prices {
+		prices[priceKey(k)] = p
+	}
+	categories := make(map[string]string)
+	for k, c := range t.Categories {
+		categories[priceKey(k)] = c
+	}
+	t.Prices, t.Categories = prices, categories
+	return &t, nil
+}
+
+// priceKey adds the default namespace to a block state name. The "id:data"
+// keys are kept.
+func priceKey(k string) string {
+	if strings.Index(k, ":") < 0 {
+		return "minecraft:" + k
+	}
+	return k
+}
+
+// A CostLine is the cost of the blocks of a single kind.
+type CostLine struct {
+	Id       uint16  `json:"id"`
+	Data     byte    `json:"data"`
+	Name     string  `json:"name"` // the key in the price table
+	Count    int     `json:"count"`
+	Price    float64 `json:"price"` // the price of a single block
+	Cost     float64 `json:"cost"`  // the price of all blocks
+	Category string  `json:"category"`
+	Priced   bool    `json:"priced"` // whether the price is from Prices rather than Default
+}
+
+// A CostReport is the outcome of Cost.
+type CostReport struct {
+	Lines   []CostLine         `json:"lines"` // ordered by id and data
+	Total   float64            `json:"total"`
+	Budgets map[string]float64 `json:"budgets"` // the cost of every category
+}
+
+type costLineSlice []CostLine
+
+func (p costLineSlice) Len() int { return len(p) }
+func (p costLineSlice) Less(i, j int) bool {
+	return p[i].Id < p[j].Id || p[i].Id == p[j].Id && p[i].Data < p[j].Data
+}
+func (p costLineSlice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+// Cost prices the blocks of s other than air and structure voids by the
+// price table. The blocks are told apart by their block states, so the
+// colors of wool have their own prices, while the directions of stairs
+// share one.
+func (s *Schematic) Cost(t *PriceTable) *CostReport {
+	counts := make(map[Block]int)
+	for i := range s.Blocks {
+		if b := (Block{s.id(i), s.Data[i]}); b.Id != 0 && b.Id != StructureVoid.Id {
+			counts[b]++
+		}
+	}
+	lines := make(map[string]*CostLine)
+	for b, n := range counts {
+		name, ok := LegacyState(b)
+		if !ok {
+			name = fmt.Sprintf("%d:%d", b.Id, b.Data)
+		}
+		if l := lines[name]; l != nil {
+			l.Count += n
+			if b.Id < l.Id || b.Id == l.Id && b.Data < l.Data {
+				l.Id, l.Data = b.Id, b.Data
+			}
+			continue
+		}
+		l := &CostLine{Id: b.Id, Data: b.Data, Name: name, Count: n}
+		l.Price, l.Priced = t.Prices[name]
+		if !l.Priced {
+			l.Price = t.Default
+		}
+		if l.Category = t.Categories[name]; l.Category == "" {
+			l.Category = categoryName(b)
+		}
+		lines[name] = l
+	}
+	r := &CostReport{Budgets: make(map[string]float64)}
+	for _, l := range lines {
+		l.Cost = l.Price * float64(l.Count)
+		r.Lines = append(r.Lines, *l)
+		r.Total += l.Cost
+		r.Budgets[l.Category] += l.Cost
+	}
+	sort.Sort(costLineSlice(r.Lines))
+	return r
+}
+
+// categoryName returns the default budget category of the block.
+func categoryName(b Block) string {
+	info, _ := Info(b)
+	switch c := info.Category; {
+	case c&Redstone != 0:
+		return "redstone"
+	case c&Liquid != 0:
+		return "liquid"
+	case c&Vegetation != 0:
+		return "vegetation"
+	case c&Solid != 0:
+		return "solid"
+	}
+	return "other"
+}
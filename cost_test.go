@@ -0,0 +1,47 @@
+package schematic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCost(t *testing.T) {
+	table, err := ReadPriceTable(strings.NewReader(`{
+		"prices": {"stone": 0.5, "minecraft:red_wool": 2, "redstone_wire": 1},
+		"categories": {"red_wool": "decor"},
+		"default": 3
+	}`))
+	if err != nil {
+		t.Fatalf("ReadPriceTable: %v", err)
+	}
+	s := NewSchematic(4, 2, 1)
+	for x := 0; x < 4; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+	}
+	s.SetBlock(0, 1, 0, Block{35, 14})
+	s.SetBlock(1, 1, 0, Block{35, 14})
+	s.SetBlock(2, 1, 0, Block{35, 0})
+	s.SetBlock(3, 1, 0, Block{55, 0})
+	r := s.Cost(table)
+	want := []CostLine{
+		{1, 0, "minecraft:stone", 4, 0.5, 2, "solid", true},
+		{35, 0, "minecraft:white_wool", 1, 3, 3, "solid", false},
+		{35, 14, "minecraft:red_wool", 2, 2, 4, "decor", true},
+		{55, 0, "minecraft:redstone_wire", 1, 1, 1, "redstone", true},
+	}
+	if len(r.Lines) != len(want) {
+		t.Fatalf("Cost: want %v, got %v", want, r.Lines)
+	}
+	for i := range want {
+		if r.Lines[i] != want[i] {
+			t.Errorf("Line %d: want %v, got %v", i, want[i], r.Lines[i])
+		}
+	}
+	if r.Total != 10 || r.Budgets["solid"] != 5 || r.Budgets["decor"] != 4 || r.Budgets["redstone"] != 1 {
+		t.Errorf("Cost: want total 10 and budgets solid 5, decor 4, redstone 1, got %v and %v", r.Total, r.Budgets)
+	}
+
+	if _, err = ReadPriceTable(strings.NewReader(`{"prices": [1]}`)); err == nil {
+		t.Errorf("ReadPriceTable of an invalid table: want error, got nil")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"rand"
+)
+
+// A DensityMap returns a factor of the density of a plant at the column
+// (x, z), from 0 to 1, such as a NoiseMask turned into a forest.
+type DensityMap func(x, z int) float64
+
+// A Plant is placed by Decorate on the surface of a terrain. It is a
+// single block, such as tall grass or a flower, a tree grown by GrowTree
+// or a schematic pasted with the center of its bottom layer above the
+// surface block.
+type Plant struct {
+	Block     Block
+	Tree      *TreeStyle
+	Schematic *Schematic
+
+	// Density is the chance of the plant on a suitable column, from 0 to
+	// 1. If DensityMap is set, Density is multiplied by it.
+	Density    float64
+	DensityMap DensityMap
+
+	// MaxSlope is the largest height difference between the column and
+	// its neighbors at which the plant grows. A negative value allows any
+	// slope. Default: 1.
+	MaxSlope int
+
+	// On lists the surface blocks the plant grows on. Default: grass and
+	// dirt.
+	On []Block
+}
+
+// DecorateOptions control Decorate.
+type DecorateOptions struct {
+	Seed   int64
+	Plants []Plant
+}
+
+// Decorate places the plants on the surface of a terrain: the highest
+// block of every column, if the block above it is air. On every column
+// the plants are tried in order, and the first one passing the roll of its
+// density is placed. Decorate returns the number of placed plants. The
+// same options always give the same result.
+func (s *Schematic) Decorate(opt *DecorateOptions) (n int) {
+	if opt == nil || len(opt.Plants) == 0 {
+		return 0
+	}
+	rnd := rand.New(rand.NewSource(opt.Seed))
+	h := s.heights()
+	rolls := make([]float64, len(opt.Plants))
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			i := z*s.Width + x
+			y := int(h[i]) - 1
+			if y < 0 || y+1 >= s.Height || s.GetV(x, y+1, z) != 0 {
+				continue
+			}
+			slope := 0
+			s.neighbors4(i, func(j int) {
+				slope = imax(slope, iabs(int(h[j])-int(h[i])))
+			})
+			// The same numbers are drawn for every column, so that adding
+			// a plant does not move the others.
+			for k := range rolls {
+				rolls[k] = rnd.Float64()
+			}
+			seed := rnd.Int63()
+			for k, p := range opt.Plants {
+				d := p.Density
+				if p.DensityMap != nil {
+					d *= p.DensityMap(x, z)
+				}
+				if rolls[k] >= d || !p.grows(s.Block(x, y, z), slope) {
+					continue
+				}
+				switch {
+				case p.Tree != nil:
+					s.GrowTree(x, y+1, z, p.Tree, seed)
+				case p.Schematic != nil:
+					s.Paste(p.Schematic, x-p.Schematic.Width/2, y+1, z-p.Schematic.Length/2, &PasteOptions{SkipAir: true})
+				default:
+					s.SetBlock(x, y+1, z, p.Block)
+				}
+				n++
+				break
+			}
+		}
+	}
+	return
+}
+
+// grows reports whether the plant grows on the block at the slope.
+func (p *Plant) grows(b Block, slope int) bool {
+	max := p.MaxSlope
+	if max == 0 {
+		max = 1
+	}
+	if max > 0 && slope > max {
+		return false
+	}
+	on := p.On
+	if on == nil {
+		on = []Block{{2, 0}, {3, 0}}
+	}
+	for _, o := range on {
+		if o == b {
+			return true
+		}
+	}
+	return false
+}
+
+func iabs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,62 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestDecorate(t *testing.T) {
+	// A flat meadow of grass on the left, sand on the right and a cliff in
+	// the middle.
+	s := NewSchematic(32, 24, 32)
+	s.Fill(RegionMask(Box{0, 0, 0, 16, 4, 32}), Block{2, 0})
+	s.Fill(RegionMask(Box{16, 0, 0, 32, 4, 32}), Block{12, 0})
+	s.Fill(RegionMask(Box{15, 4, 0, 16, 10, 32}), Block{2, 0})
+	bush := NewSchematic(3, 1, 3)
+	bush.SetBlock(1, 0, 1, Block{18, 0})
+	opt := &DecorateOptions{
+		Seed: 2,
+		Plants: []Plant{
+			{Tree: OakTree, Density: 0.01},
+			{Schematic: bush, Density: 0.05},
+			{Block: Block{81, 0}, Density: 0.1, On: []Block{{12, 0}}},
+			{Block: Block{31, 1}, Density: 0.5, DensityMap: func(x, z int) float64 {
+				if z < 16 {
+					return 1
+				}
+				return 0
+			}},
+		},
+	}
+	n := s.Decorate(opt)
+	if n == 0 {
+		t.Fatalf("Decorate placed nothing")
+	}
+	var grass, cactus, logs int
+	for z := 0; z < 32; z++ {
+		for x := 0; x < 32; x++ {
+			switch b := s.Block(x, 4, z); b.Id {
+			case 31:
+				grass++
+				if z >= 16 || x >= 15 {
+					t.Errorf("Grass at (%d, %d)", x, z)
+				}
+			case 81:
+				cactus++
+				if x < 16 {
+					t.Errorf("Cactus on grass at (%d, %d)", x, z)
+				}
+			case 17:
+				logs++
+			}
+		}
+		if b := s.Block(15, 10, z); b.Id != 0 && b.Id != 18 {
+			t.Errorf("Plant on the cliff at z = %d: %v", z, b)
+		}
+	}
+	if grass < 50 || cactus < 10 || logs == 0 {
+		t.Errorf("Want grass, cactus and trees, got %d, %d and %d", grass, cactus, logs)
+	}
+	if s.Decorate(nil) != 0 {
+		t.Errorf("Decorate(nil) placed plants")
+	}
+}
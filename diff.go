@@ -0,0 +1,74 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"github.com/krasin/schematic/nbt"
+)
+
+// A BlockChange is a block which differs between two schematics.
+type BlockChange struct {
+	X, Y, Z  int
+	Old, New Block
+}
+
+// A Diff lists the differences between two schematics. Blocks outside
+// of a schematic are treated as air, so schematics of different sizes
+// are compared by their common corner at (0, 0, 0).
+type Diff struct {
+	SizeChanged bool
+	Added       int           // air replaced by a block
+	Removed     int           // block replaced by air
+	Changed     int           // block replaced by another block or data value
+	Changes     []BlockChange // all changed blocks ordered by y, z and x
+
+	// EntitiesAdded and EntitiesRemoved count the entities present
+	// in only one of the schematics.
+	EntitiesAdded, EntitiesRemoved int
+}
+
+// Equal reports whether the schematics have the same size, blocks and entities.
+func (d *Diff) Equal() bool {
+	return !d.SizeChanged && len(d.Changes) == 0 && d.EntitiesAdded == 0 && d.EntitiesRemoved == 0
+}
+
+// Compare returns the changes turning the schematic a into b.
+func Compare(a, b *Schematic) *Diff {
+	d := &Diff{SizeChanged: a.Width != b.Width || a.Height != b.Height || a.Length != b.Length}
+	for y := 0; y < imax(a.Height, b.Height); y++ {
+		for z := 0; z < imax(a.Length, b.Length); z++ {
+			for x := 0; x < imax(a.Width, b.Width); x++ {
+				old, cur := a.Block(x, y, z), b.Block(x, y, z)
+				if old == cur {
+					continue
+				}
+				switch {
+				case old.Id == 0:
+					d.Added++
+				case cur.Id == 0:
+					d.Removed++
+				default:
+					d.Changed++
+				}
+				d.Changes = append(d.Changes, BlockChange{x, y, z, old, cur})
+			}
+		}
+	}
+	// Entities are compared by their complete NBT.
+	count := make(map[string]int)
+	for _, e := range a.Entities {
+		count[nbt.FormatSNBT(e.compound(), "")]++
+	}
+	for _, e := range b.Entities {
+		count[nbt.FormatSNBT(e.compound(), "")]--
+	}
+	for _, n := range count {
+		if n > 0 {
+			d.EntitiesRemoved += n
+		} else {
+			d.EntitiesAdded -= n
+		}
+	}
+	return d
+}
@@ -0,0 +1,32 @@
+package schematic
+
+import (
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestCompare(t *testing.T) {
+	a := newTestVolume()
+	if d := Compare(a, a); !d.Equal() {
+		t.Errorf("Compare(a, a): want equal, got %+v", d)
+	}
+	b, _ := a.Stack(AxisX, 2)
+	b.SetBlock(0, 0, 0, Block{})
+	b.SetBlock(2, 0, 1, Block{35, 1})
+	b.Entities[0].NBT.Set("Health", nbt.Short(10))
+	d := Compare(a, b)
+	if d.Equal() || !d.SizeChanged {
+		t.Errorf("Compare: want different sizes, got %+v", d)
+	}
+	// The second copy adds stone, wool and cobblestone.
+	if d.Added != 3 || d.Removed != 1 || d.Changed != 1 || len(d.Changes) != 5 {
+		t.Errorf("Compare: want 3 added, 1 removed and 1 changed, got %d, %d, %d", d.Added, d.Removed, d.Changed)
+	}
+	if c := d.Changes[0]; c != (BlockChange{0, 0, 0, Block{1, 0}, Block{}}) {
+		t.Errorf("Changes[0]: got %+v", c)
+	}
+	if d.EntitiesAdded != 2 || d.EntitiesRemoved != 1 {
+		t.Errorf("Entities: want 2 added and 1 removed, got %d and %d", d.EntitiesAdded, d.EntitiesRemoved)
+	}
+}
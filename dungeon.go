@@ -0,0 +1,321 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+	"rand"
+)
+
+// The maze and dungeon generators lay out a plan of tiles and then build
+// it in the schematic: a floor layer at y = 0 and walls of WallHeight
+// blocks above it.
+
+const (
+	tileVoid = iota // outside of the build, left as air
+	tileFloor
+	tileWall
+	tileDoor
+)
+
+// tileMap is the plan of a maze or a dungeon, Width x Length tiles.
+type tileMap struct {
+	width, length int
+	tiles         []byte
+}
+
+func newTileMap(width, length int) *tileMap {
+	return &tileMap{width, length, make([]byte, width*length)}
+}
+
+func (m *tileMap) get(x, z int) byte {
+	if x < 0 || z < 0 || x >= m.width || z >= m.length {
+		return tileVoid
+	}
+	return m.tiles[z*m.width+x]
+}
+
+func (m *tileMap) set(x, z int, t byte) {
+	if x >= 0 && z >= 0 && x < m.width && z < m.length {
+		m.tiles[z*m.width+x] = t
+	}
+}
+
+// rect sets the tiles x0 <= x < x1, z0 <= z < z1.
+func (m *tileMap) rect(x0, z0, x1, z1 int, t byte) {
+	for z := z0; z < z1; z++ {
+		for x := x0; x < x1; x++ {
+			m.set(x, z, t)
+		}
+	}
+}
+
+// isDoor reports whether the block is one of the doors, which take two
+// blocks: the bottom half and the top half with the data value 8.
+func isDoor(b Block) bool {
+	return b.Id == 64 || b.Id == 71 || b.Id >= 193 && b.Id <= 197
+}
+
+// build returns the schematic built from the plan.
+func (m *tileMap) build(wallHeight int, wall, floor, door Block) *Schematic {
+	s := NewSchematic(m.width, wallHeight+1, m.length)
+	for z := 0; z < m.length; z++ {
+		for x := 0; x < m.width; x++ {
+			switch m.get(x, z) {
+			case tileFloor:
+				s.SetBlock(x, 0, z, floor)
+			case tileWall:
+				for y := 0; y <= wallHeight; y++ {
+					s.SetBlock(x, y, z, wall)
+				}
+			case tileDoor:
+				s.SetBlock(x, 0, z, floor)
+				top := 1
+				if door != (Block{}) {
+					s.SetBlock(x, 1, z, door)
+					if isDoor(door) && wallHeight >= 2 {
+						s.SetBlock(x, 2, z, Block{door.Id, 8})
+						top = 2
+					}
+				} else {
+					top = imin(2, wallHeight)
+				}
+				// The wall above the door.
+				for y := top + 1; y <= wallHeight; y++ {
+					s.SetBlock(x, y, z, wall)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// MazeOptions control GenerateMaze. Zero values select the defaults.
+type MazeOptions struct {
+	// Cols and Rows are the number of cells along the x and z axes.
+	Cols, Rows int
+
+	// CellSize is the width of the corridors. Default: 1.
+	CellSize int
+
+	// WallHeight is the height of the walls above the floor. Default: 3.
+	WallHeight int
+
+	// Wall and Floor default to stone bricks and cobblestone. If Door is
+	// not air, it is placed in the entrance and the exit.
+	Wall, Floor, Door Block
+
+	Seed int64
+}
+
+// GenerateMaze returns a perfect maze: every cell is reachable from any other
+// in exactly one way. The walls are one block thick. The entrance is on the
+// west side of the cell at the minimum corner and the exit on the east side
+// of the opposite cell.
+func GenerateMaze(opt *MazeOptions) (*Schematic, os.Error) {
+	if opt.Cols <= 0 || opt.Rows <= 0 {
+		return nil, fmt.Errorf("Invalid maze size: %dx%d", opt.Cols, opt.Rows)
+	}
+	o := *opt
+	if o.CellSize == 0 {
+		o.CellSize = 1
+	}
+	if o.WallHeight == 0 {
+		o.WallHeight = 3
+	}
+	if o.Wall == (Block{}) {
+		o.Wall = Block{98, 0}
+	}
+	if o.Floor == (Block{}) {
+		o.Floor = Block{4, 0}
+	}
+	c := o.CellSize + 1
+	m := newTileMap(o.Cols*c+1, o.Rows*c+1)
+	m.rect(0, 0, m.width, m.length, tileWall)
+	cell := func(i int) (x, z int) {
+		return 1 + i%o.Cols*c, 1 + i/o.Cols*c
+	}
+	for i := 0; i < o.Cols*o.Rows; i++ {
+		x, z := cell(i)
+		m.rect(x, z, x+o.CellSize, z+o.CellSize, tileFloor)
+	}
+
+	// A depth first search carving the passages between the cells.
+	rnd := rand.New(rand.NewSource(o.Seed))
+	visited := make([]bool, o.Cols*o.Rows)
+	visited[0] = true
+	stack := []int{0}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		var next []int
+		if i%o.Cols > 0 && !visited[i-1] {
+			next = append(next, i-1)
+		}
+		if i%o.Cols < o.Cols-1 && !visited[i+1] {
+			next = append(next, i+1)
+		}
+		if i >= o.Cols && !visited[i-o.Cols] {
+			next = append(next, i-o.Cols)
+		}
+		if i+o.Cols < len(visited) && !visited[i+o.Cols] {
+			next = append(next, i+o.Cols)
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		j := next[rnd.Intn(len(next))]
+		visited[j] = true
+		stack = append(stack, j)
+		x, z := cell(imin(i, j))
+		if j == i-1 || j == i+1 {
+			m.rect(x+o.CellSize, z, x+c, z+o.CellSize, tileFloor)
+		} else {
+			m.rect(x, z+o.CellSize, x+o.CellSize, z+c, tileFloor)
+		}
+	}
+
+	x, z := cell(0)
+	m.rect(0, z, 1, z+o.CellSize, tileDoor)
+	x, z = cell(o.Cols*o.Rows - 1)
+	m.rect(x+o.CellSize, z, x+c, z+o.CellSize, tileDoor)
+	return m.build(o.WallHeight, o.Wall, o.Floor, o.Door), nil
+}
+
+// DungeonOptions control GenerateDungeon. Zero values select the defaults.
+type DungeonOptions struct {
+	// Width and Length are the size of the dungeon in blocks.
+	Width, Length int
+
+	// Rooms is the number of rooms to place. Fewer rooms are placed if
+	// they do not fit. Default: 8.
+	Rooms int
+
+	// MinRoom and MaxRoom limit the width and length of the rooms inside
+	// the walls. Defaults: 3 and 9.
+	MinRoom, MaxRoom int
+
+	// CorridorWidth is the width of the corridors. Default: 1.
+	CorridorWidth int
+
+	// WallHeight is the height of the walls above the floor. Default: 3.
+	WallHeight int
+
+	// Wall and Floor default to stone bricks and cobblestone. If Door is
+	// not air, it is placed where the corridors enter the rooms.
+	Wall, Floor, Door Block
+
+	Seed int64
+}
+
+// GenerateDungeon returns a dungeon of rectangular rooms joined by corridors
+// and the boxes of the rooms, without their walls, for furnishing them.
+// All rooms are reachable from each other.
+func GenerateDungeon(opt *DungeonOptions) (s *Schematic, rooms []Box, err os.Error) {
+	o := *opt
+	if o.Rooms == 0 {
+		o.Rooms = 8
+	}
+	if o.MinRoom == 0 {
+		o.MinRoom = 3
+	}
+	if o.MaxRoom == 0 {
+		o.MaxRoom = 9
+	}
+	if o.CorridorWidth == 0 {
+		o.CorridorWidth = 1
+	}
+	if o.WallHeight == 0 {
+		o.WallHeight = 3
+	}
+	if o.Wall == (Block{}) {
+		o.Wall = Block{98, 0}
+	}
+	if o.Floor == (Block{}) {
+		o.Floor = Block{4, 0}
+	}
+	if o.MinRoom < 1 || o.MaxRoom < o.MinRoom || o.CorridorWidth < 1 {
+		return nil, nil, fmt.Errorf("Invalid room sizes %d to %d or corridor width %d", o.MinRoom, o.MaxRoom, o.CorridorWidth)
+	}
+	if o.Width < o.MinRoom+2 || o.Length < o.MinRoom+2 {
+		return nil, nil, fmt.Errorf("Dungeon of %dx%d is too small for rooms of %d", o.Width, o.Length, o.MinRoom)
+	}
+
+	// Place the rooms at random, at least one block apart, trying a few
+	// times for each room.
+	rnd := rand.New(rand.NewSource(o.Seed))
+	for try := 0; try < o.Rooms*20 && len(rooms) < o.Rooms; try++ {
+		w := o.MinRoom + rnd.Intn(imin(o.MaxRoom, o.Width-2)-o.MinRoom+1)
+		l := o.MinRoom + rnd.Intn(imin(o.MaxRoom, o.Length-2)-o.MinRoom+1)
+		x := 1 + rnd.Intn(o.Width-w-1)
+		z := 1 + rnd.Intn(o.Length-l-1)
+		r := Box{x, 1, z, x + w, o.WallHeight + 1, z + l}
+		ok := true
+		for _, q := range rooms {
+			if r.MinX < q.MaxX+3 && q.MinX < r.MaxX+3 && r.MinZ < q.MaxZ+3 && q.MinZ < r.MaxZ+3 {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			rooms = append(rooms, r)
+		}
+	}
+
+	m := newTileMap(o.Width, o.Length)
+	for _, r := range rooms {
+		m.rect(r.MinX, r.MinZ, r.MaxX, r.MaxZ, tileFloor)
+	}
+	// Join every room to the previous one with an L-shaped corridor
+	// between their centers.
+	cw := o.CorridorWidth
+	for i := 1; i < len(rooms); i++ {
+		ax, az := corridorStart(rooms[i-1], cw)
+		bx, bz := corridorStart(rooms[i], cw)
+		x0, x1 := imin(ax, bx), imax(ax, bx)
+		z0, z1 := imin(az, bz), imax(az, bz)
+		m.rect(x0, az, x1+cw, az+cw, tileFloor)
+		m.rect(bx, z0, bx+cw, z1+cw, tileFloor)
+	}
+	// The corridor tiles crossing the line of the walls of a room are
+	// doors; the tiles around the floor become walls.
+	open := func(x, z int) bool {
+		t := m.get(x, z)
+		return t == tileFloor || t == tileDoor
+	}
+	for _, r := range rooms {
+		for z := r.MinZ - 1; z <= r.MaxZ; z++ {
+			for x := r.MinX - 1; x <= r.MaxX; x++ {
+				side := (x == r.MinX-1 || x == r.MaxX) && z >= r.MinZ && z < r.MaxZ && open(x-1, z) && open(x+1, z)
+				end := (z == r.MinZ-1 || z == r.MaxZ) && x >= r.MinX && x < r.MaxX && open(x, z-1) && open(x, z+1)
+				if m.get(x, z) == tileFloor && (side || end) {
+					m.set(x, z, tileDoor)
+				}
+			}
+		}
+	}
+	for z := 0; z < m.length; z++ {
+		for x := 0; x < m.width; x++ {
+			if m.get(x, z) != tileVoid {
+				continue
+			}
+			for i := 0; i < 9; i++ {
+				if t := m.get(x+i%3-1, z+i/3-1); t == tileFloor || t == tileDoor {
+					m.set(x, z, tileWall)
+					break
+				}
+			}
+		}
+	}
+	return m.build(o.WallHeight, o.Wall, o.Floor, o.Door), rooms, nil
+}
+
+// corridorStart returns the tile of a room where its corridors start: near
+// the center, with room for a corridor of the width.
+func corridorStart(r Box, width int) (x, z int) {
+	x = imax(r.MinX, imin((r.MinX+r.MaxX)/2, r.MaxX-width))
+	z = imax(r.MinZ, imin((r.MinZ+r.MaxZ)/2, r.MaxZ-width))
+	return
+}
@@ -0,0 +1,86 @@
+package schematic
+
+import (
+	"testing"
+)
+
+// reachable returns the number of walkable blocks reachable from (x, z):
+// those with a floor below and no wall at y = 1.
+func reachable(s *Schematic, x, z int, wall Block) int {
+	walkable := func(x, z int) bool {
+		return x >= 0 && z >= 0 && x < s.Width && z < s.Length && s.GetV(x, 0, z) != 0 && s.Block(x, 1, z) != wall
+	}
+	seen := make(map[int]bool)
+	queue := []int{z*s.Width + x}
+	seen[queue[0]] = true
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		x, z := i%s.Width, i/s.Width
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			nx, nz := x+d[0], z+d[1]
+			if j := nz*s.Width + nx; walkable(nx, nz) && !seen[j] {
+				seen[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+	return len(seen)
+}
+
+func TestGenerateMaze(t *testing.T) {
+	s, err := GenerateMaze(&MazeOptions{Cols: 6, Rows: 4, CellSize: 2, Door: Block{64, 0}, Seed: 3})
+	if err != nil {
+		t.Fatalf("GenerateMaze: %v", err)
+	}
+	if s.Width != 19 || s.Height != 4 || s.Length != 13 {
+		t.Fatalf("GenerateMaze: want 19x4x13, got %dx%dx%d", s.Width, s.Height, s.Length)
+	}
+	if s.Block(0, 1, 1) != (Block{64, 0}) || s.Block(0, 2, 2) != (Block{64, 8}) || s.Block(0, 3, 1) != (Block{98, 0}) {
+		t.Errorf("Entrance: got %v, %v, %v", s.Block(0, 1, 1), s.Block(0, 2, 2), s.Block(0, 3, 1))
+	}
+	if s.GetV(18, 1, 10) != 64 {
+		t.Errorf("Exit: want a door, got %v", s.Block(18, 1, 10))
+	}
+	// 24 cells of 4 blocks, 23 passages of 2 blocks and 4 door blocks.
+	if n := reachable(s, 1, 1, Block{98, 0}); n != 24*4+23*2+4 {
+		t.Errorf("Reachable blocks: want %d, got %d", 24*4+23*2+4, n)
+	}
+	if _, err = GenerateMaze(&MazeOptions{Cols: 0, Rows: 3}); err == nil {
+		t.Errorf("GenerateMaze with no columns: want error, got nil")
+	}
+}
+
+func TestGenerateDungeon(t *testing.T) {
+	s, rooms, err := GenerateDungeon(&DungeonOptions{Width: 64, Length: 48, Rooms: 6, CorridorWidth: 2, Door: Block{5, 0}, Seed: 11})
+	if err != nil {
+		t.Fatalf("GenerateDungeon: %v", err)
+	}
+	if s.Width != 64 || s.Height != 4 || s.Length != 48 {
+		t.Fatalf("GenerateDungeon: want 64x4x48, got %dx%dx%d", s.Width, s.Height, s.Length)
+	}
+	if len(rooms) < 3 {
+		t.Fatalf("GenerateDungeon: want at least 3 rooms, got %d", len(rooms))
+	}
+	for _, r := range rooms {
+		if r.MaxX-r.MinX < 3 || r.MaxX-r.MinX > 9 || r.MaxY != 4 {
+			t.Errorf("Room %v: wrong size", r)
+		}
+		if s.GetV(r.MinX-1, 1, r.MinZ-1) != 98 || s.GetV(r.MinX, 0, r.MinZ) != 4 || s.GetV(r.MinX, 1, r.MinZ) != 0 {
+			t.Errorf("Room %v: no walls or floor", r)
+		}
+	}
+	if n := len(s.selected(IdMask(5))); n == 0 {
+		t.Errorf("GenerateDungeon: no doors")
+	}
+	// The doors are not walls, so all rooms are reachable from the first one.
+	n := reachable(s, rooms[0].MinX, rooms[0].MinZ, Block{98, 0})
+	for _, r := range rooms {
+		if m := reachable(s, r.MinX, r.MinZ, Block{98, 0}); m != n {
+			t.Errorf("Room %v: %d reachable blocks, want %d", r, m, n)
+		}
+	}
+	if _, _, err = GenerateDungeon(&DungeonOptions{Width: 4, Length: 4}); err == nil {
+		t.Errorf("GenerateDungeon of 4x4: want error, got nil")
+	}
+}
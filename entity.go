@@ -0,0 +1,27 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// FilterEntities keeps the entities for which keep returns true and
+// returns the number of removed ones.
+func (s *Schematic) FilterEntities(keep func(e Entity) bool) (n int) {
+	var kept []Entity
+	for _, e := range s.Entities {
+		if keep(e) {
+			kept = append(kept, e)
+		} else {
+			n++
+		}
+	}
+	s.Entities = kept
+	return
+}
+
+// RemoveEntities removes the entities with the id, such as "Pig" or
+// "minecraft:item_frame", and returns their number.
+func (s *Schematic) RemoveEntities(id string) int {
+	return s.FilterEntities(func(e Entity) bool {
+		return e.Id != id
+	})
+}
@@ -0,0 +1,16 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestFilterEntities(t *testing.T) {
+	s := newTestVolume()
+	s.Entities = append(s.Entities, Entity{Id: "Cow"}, Entity{Id: "Pig"}, Entity{Id: "minecraft:pig"})
+	if n := s.RemoveEntities("Pig"); n != 2 || len(s.Entities) != 2 {
+		t.Errorf("RemoveEntities(Pig): want 2 removed, got %d, left %v", n, s.Entities)
+	}
+	if n := s.FilterEntities(func(e Entity) bool { return e.Id == "Cow" }); n != 1 || len(s.Entities) != 1 || s.Entities[0].Id != "Cow" {
+		t.Errorf("FilterEntities: want 1 removed, got %d, left %v", n, s.Entities)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"math"
+)
+
+// The terrain operations below treat a schematic as a height field: every
+// column of blocks is ground up to its highest non-air block and air
+// above it. They compute new heights and rebuild the columns which
+// changed: the top blocks of a column, such as the grass and the dirt
+// under it, move with the surface, and a raised column repeats the block
+// below them. Tile entities of the replaced blocks are removed and
+// entities are kept as they are.
+
+// surfaceDepth is the number of the top blocks of a column which move with
+// the surface.
+const surfaceDepth = 4
+
+// heightField is the height of every column of a schematic, indexed by
+// z*Width + x. A height is the number of blocks up to and including the
+// highest non-air block.
+type heightField []float64
+
+// heights returns the height field of s.
+func (s *Schematic) heights() heightField {
+	h := make(heightField, s.Width*s.Length)
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			y := s.Height - 1
+			for ; y >= 0 && s.GetV(x, y, z) == 0; y-- {
+			}
+			h[z*s.Width+x] = float64(y + 1)
+		}
+	}
+	return h
+}
+
+// setHeights rebuilds the columns of s whose rounded height differs from
+// the height field.
+func (s *Schematic) setHeights(h heightField) {
+	old := s.heights()
+	column := make([]Block, s.Height)
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			i := z*s.Width + x
+			h0 := int(old[i])
+			h1 := imax(0, imin(s.Height, int(math.Floor(h[i]+0.5))))
+			if h1 == h0 {
+				continue
+			}
+			for y := range column {
+				column[y] = s.Block(x, y, z)
+			}
+			for y := 0; y < s.Height; y++ {
+				var b Block
+				if d := h1 - 1 - y; d >= 0 && h0 > 0 {
+					src := imin(y, h0-1-surfaceDepth)
+					if d < surfaceDepth {
+						src = h0 - 1 - d
+					}
+					b = column[imax(src, 0)]
+				}
+				if s.Block(x, y, z) != b {
+					s.SetBlock(x, y, z, b)
+				}
+			}
+		}
+	}
+}
+
+// neighbors4 calls f with the index of every horizontal neighbor of the
+// column i in the height field of s.
+func (s *Schematic) neighbors4(i int, f func(j int)) {
+	x, z := i%s.Width, i/s.Width
+	if x > 0 {
+		f(i - 1)
+	}
+	if x < s.Width-1 {
+		f(i + 1)
+	}
+	if z > 0 {
+		f(i - s.Width)
+	}
+	if z < s.Length-1 {
+		f(i + s.Width)
+	}
+}
+
+// SmoothOptions control Smooth. Zero values select the defaults.
+type SmoothOptions struct {
+	// Radius is the distance in columns over which the heights are
+	// averaged. Default: 2.
+	Radius int
+
+	// Gaussian weights the heights by a Gaussian of the distance with the
+	// standard deviation of Radius/2 instead of equally.
+	Gaussian bool
+
+	// Iterations is the number of smoothing passes. Default: 1.
+	Iterations int
+}
+
+// Smooth replaces the height of every column with the average of the
+// heights around it, rounding off the edges of the terrain. A nil opt
+// selects the defaults.
+func (s *Schematic) Smooth(opt *SmoothOptions) {
+	var o SmoothOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.Radius <= 0 {
+		o.Radius = 2
+	}
+	if o.Iterations <= 0 {
+		o.Iterations = 1
+	}
+	r := o.Radius
+	weight := make([]float64, (2*r+1)*(2*r+1))
+	for dz := -r; dz <= r; dz++ {
+		for dx := -r; dx <= r; dx++ {
+			w := 1.0
+			if o.Gaussian {
+				sigma := float64(r) / 2
+				w = math.Exp(-float64(dx*dx+dz*dz) / (2 * sigma * sigma))
+			}
+			weight[(dz+r)*(2*r+1)+dx+r] = w
+		}
+	}
+	h := s.heights()
+	for it := 0; it < o.Iterations; it++ {
+		next := make(heightField, len(h))
+		for z := 0; z < s.Length; z++ {
+			for x := 0; x < s.Width; x++ {
+				var sum, total float64
+				for dz := imax(-r, -z); dz <= r && z+dz < s.Length; dz++ {
+					for dx := imax(-r, -x); dx <= r && x+dx < s.Width; dx++ {
+						w := weight[(dz+r)*(2*r+1)+dx+r]
+						sum += w * h[(z+dz)*s.Width+x+dx]
+						total += w
+					}
+				}
+				next[z*s.Width+x] = sum / total
+			}
+		}
+		h = next
+	}
+	s.setHeights(h)
+}
+
+// ErosionOptions control ThermalErosion and HydraulicErosion. Zero values
+// select the defaults. The heights and the amounts of water are measured
+// in blocks.
+type ErosionOptions struct {
+	// Iterations is the number of erosion passes. Default: 50.
+	Iterations int
+
+	// Talus is the height difference between neighboring columns above
+	// which ThermalErosion moves the ground down. Default: 1.
+	Talus float64
+
+	// The parameters of HydraulicErosion: the water falling on every
+	// column in a pass (default 1), the ground dissolved by a unit of
+	// water (default 0.1), the part of the water evaporating in a pass
+	// (default 0.5) and the ground a unit of water can carry (default 0.3).
+	Rain, Solubility, Evaporation, Capacity float64
+}
+
+func (opt *ErosionOptions) defaults() ErosionOptions {
+	var o ErosionOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.Iterations <= 0 {
+		o.Iterations = 50
+	}
+	if o.Talus <= 0 {
+		o.Talus = 1
+	}
+	if o.Rain <= 0 {
+		o.Rain = 1
+	}
+	if o.Solubility <= 0 {
+		o.Solubility = 0.1
+	}
+	if o.Evaporation <= 0 {
+		o.Evaporation = 0.5
+	}
+	if o.Capacity <= 0 {
+		o.Capacity = 0.3
+	}
+	return o
+}
+
+// ThermalErosion lets the ground slide down the slopes steeper than Talus,
+// as weathered rock crumbles off cliffs, until the slopes settle. The
+// amount of ground is kept. A nil opt selects the defaults.
+func (s *Schematic) ThermalErosion(opt *ErosionOptions) {
+	o := opt.defaults()
+	h := s.heights()
+	delta := make(heightField, len(h))
+	for it := 0; it < o.Iterations; it++ {
+		moved := false
+		for i := range h {
+			// Move half of the excess over the talus of the steepest
+			// slope, shared by the lower neighbors in proportion to
+			// their excess.
+			var max, total float64
+			s.neighbors4(i, func(j int) {
+				if d := h[i] - h[j]; d > o.Talus {
+					total += d - o.Talus
+					max = math.Fmax(max, d-o.Talus)
+				}
+			})
+			if total == 0 {
+				continue
+			}
+			moved = true
+			amount := max / 2
+			s.neighbors4(i, func(j int) {
+				if d := h[i] - h[j]; d > o.Talus {
+					m := amount * (d - o.Talus) / total
+					delta[i] -= m
+					delta[j] += m
+				}
+			})
+		}
+		for i := range h {
+			h[i] += delta[i]
+			delta[i] = 0
+		}
+		if !moved {
+			break
+		}
+	}
+	s.setHeights(h)
+}
+
+// HydraulicErosion simulates rain dissolving the ground, running down the
+// slopes and depositing the ground where the water slows down or
+// evaporates. It carves valleys and fills the low places. The amount of
+// ground is kept. A nil opt selects the defaults.
+func (s *Schematic) HydraulicErosion(opt *ErosionOptions) {
+	o := opt.defaults()
+	h := s.heights()
+	water := make(heightField, len(h))
+	sediment := make(heightField, len(h))
+	dw := make(heightField, len(h))
+	ds := make(heightField, len(h))
+	for it := 0; it < o.Iterations; it++ {
+		for i := range h {
+			water[i] += o.Rain
+			m := math.Fmin(o.Solubility*water[i], h[i])
+			h[i] -= m
+			sediment[i] += m
+		}
+		// The water flows to the lower neighbors, in proportion to the
+		// differences of the water surfaces, carrying its sediment.
+		for i := range h {
+			a := h[i] + water[i]
+			var total float64
+			var n int
+			s.neighbors4(i, func(j int) {
+				if d := a - h[j] - water[j]; d > 0 {
+					total += d
+					n++
+				}
+			})
+			if total == 0 {
+				continue
+			}
+			out := math.Fmin(water[i], total/float64(n+1))
+			s.neighbors4(i, func(j int) {
+				if d := a - h[j] - water[j]; d > 0 {
+					w := out * d / total
+					m := sediment[i] * w / water[i]
+					dw[i] -= w
+					dw[j] += w
+					ds[i] -= m
+					ds[j] += m
+				}
+			})
+		}
+		for i := range h {
+			water[i] += dw[i]
+			sediment[i] += ds[i]
+			dw[i], ds[i] = 0, 0
+			water[i] *= 1 - o.Evaporation
+			if max := o.Capacity * water[i]; sediment[i] > max {
+				h[i] += sediment[i] - max
+				sediment[i] = max
+			}
+		}
+	}
+	for i := range h {
+		h[i] += sediment[i]
+	}
+	s.setHeights(h)
+}
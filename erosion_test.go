@@ -0,0 +1,108 @@
+package schematic
+
+import (
+	"testing"
+)
+
+// plateau returns a ground of stone under a layer of grass, 4 blocks high,
+// with a 12 blocks high square plateau in the middle.
+func plateau() *Schematic {
+	s := NewSchematic(16, 20, 16)
+	for z := 0; z < 16; z++ {
+		for x := 0; x < 16; x++ {
+			top := 4
+			if x >= 6 && x < 10 && z >= 6 && z < 10 {
+				top = 12
+			}
+			for y := 0; y < top-1; y++ {
+				s.SetBlock(x, y, z, Block{1, 0})
+			}
+			s.SetBlock(x, top-1, z, Block{2, 0})
+		}
+	}
+	return s
+}
+
+func maxSlope(s *Schematic) (max float64) {
+	h := s.heights()
+	for i := range h {
+		s.neighbors4(i, func(j int) {
+			if d := h[i] - h[j]; d > max {
+				max = d
+			}
+		})
+	}
+	return
+}
+
+func groundVolume(h heightField) (v float64) {
+	for _, x := range h {
+		v += x
+	}
+	return
+}
+
+func checkColumns(t *testing.T, s *Schematic) {
+	h := s.heights()
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			top := int(h[z*s.Width+x]) - 1
+			if b := s.Block(x, top, z); b.Id != 2 {
+				t.Fatalf("(%d, %d, %d): want grass on top, got %v", x, top, z, b)
+			}
+			for y := 0; y < top; y++ {
+				if b := s.Block(x, y, z); b.Id != 1 {
+					t.Fatalf("(%d, %d, %d): want stone, got %v", x, y, z, b)
+				}
+			}
+		}
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	for _, gaussian := range []bool{false, true} {
+		s := plateau()
+		s.Smooth(&SmoothOptions{Gaussian: gaussian})
+		checkColumns(t, s)
+		if m := maxSlope(s); m >= 8 || m < 1 {
+			t.Errorf("Gaussian %v: max slope after smoothing: %v", gaussian, m)
+		}
+	}
+	s := plateau()
+	s.Smooth(&SmoothOptions{Radius: 8, Iterations: 10})
+	if m := maxSlope(s); m > 1 {
+		t.Errorf("Max slope after a strong smoothing: %v", m)
+	}
+}
+
+func TestThermalErosion(t *testing.T) {
+	s := plateau()
+	before := groundVolume(s.heights())
+	s.ThermalErosion(&ErosionOptions{Talus: 2})
+	checkColumns(t, s)
+	if m := maxSlope(s); m > 3 {
+		t.Errorf("Max slope after thermal erosion: %v", m)
+	}
+	if after := groundVolume(s.heights()); after < before-20 || after > before+20 {
+		t.Errorf("Volume: before %v, after %v", before, after)
+	}
+}
+
+func TestHydraulicErosion(t *testing.T) {
+	s := plateau()
+	before := groundVolume(s.heights())
+	s.HydraulicErosion(nil)
+	checkColumns(t, s)
+	if s.heights()[8*16+8] >= 12 {
+		t.Errorf("The top of the plateau was not eroded")
+	}
+	if after := groundVolume(s.heights()); after < before-30 || after > before+30 {
+		t.Errorf("Volume: before %v, after %v", before, after)
+	}
+	flat := NewSchematic(4, 4, 4)
+	flat.Fill(RegionMask(Box{0, 0, 0, 4, 2, 4}), Block{1, 0})
+	flat.HydraulicErosion(nil)
+	if got := flat.BlockCounts()[1]; got != 32 {
+		t.Errorf("Flat ground changed: %d blocks of stone", got)
+	}
+}
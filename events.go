@@ -0,0 +1,58 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// A ChangeFunc is called after a block of a schematic has changed, with
+// its position and the blocks before and after the change.
+type ChangeFunc func(x, y, z int, old, new Block)
+
+type changeListener struct {
+	f ChangeFunc
+}
+
+// OnChange registers f to be called after every change of a block made
+// with the methods of Schematic, such as SetBlock, SetData, Fill, Replace,
+// Paste or Restore, and returns a function which unregisters it. Writes
+// which leave a block as it was are not reported. The functions are called
+// in the order of registration and must not change s.
+func (s *Schematic) OnChange(f ChangeFunc) (cancel func()) {
+	l := &changeListener{f}
+	s.listeners = append(s.listeners, l)
+	return func() {
+		var rest []*changeListener
+		for _, m := range s.listeners {
+			if m != l {
+				rest = append(rest, m)
+			}
+		}
+		s.listeners = rest
+	}
+}
+
+// set sets the block at the position i in Blocks and reports the change.
+func (s *Schematic) set(i int, b Block) {
+	var old Block
+	if len(s.listeners) > 0 {
+		old = Block{s.id(i), s.Data[i]}
+	}
+	s.setId(i, b.Id)
+	s.Data[i] = b.Data
+	s.changed(i, old)
+}
+
+// changed reports the change of the block at the position i in Blocks
+// from old to the listeners.
+func (s *Schematic) changed(i int, old Block) {
+	if len(s.listeners) == 0 {
+		return
+	}
+	b := Block{s.id(i), s.Data[i]}
+	if b == old {
+		return
+	}
+	x, y, z := s.coords(i)
+	for _, l := range s.listeners {
+		l.f(x, y, z, old, b)
+	}
+}
@@ -0,0 +1,47 @@
+package schematic
+
+import (
+	"reflect"
+	"testing"
+)
+
+type change struct {
+	X, Y, Z  int
+	Old, New Block
+}
+
+func TestOnChange(t *testing.T) {
+	s := NewSchematic(4, 4, 4)
+	var got []change
+	cancel := s.OnChange(func(x, y, z int, old, new Block) {
+		got = append(got, change{x, y, z, old, new})
+	})
+	snap := s.Snapshot()
+	s.SetBlock(1, 2, 3, Block{1, 0})
+	s.SetBlock(1, 2, 3, Block{1, 0}) // no change
+	s.SetData(1, 2, 3, 2)
+	s.Replace(Block{1, 2}, Block{300, 1}, false)
+	src := NewSchematic(1, 1, 1)
+	src.SetBlock(0, 0, 0, Block{4, 0})
+	s.Paste(src, 3, 3, 3, nil)
+	if err := s.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+	want := []change{
+		{1, 2, 3, Block{}, Block{1, 0}},
+		{1, 2, 3, Block{1, 0}, Block{1, 2}},
+		{1, 2, 3, Block{1, 2}, Block{300, 1}},
+		{3, 3, 3, Block{}, Block{4, 0}},
+		{1, 2, 3, Block{300, 1}, Block{}},
+		{3, 3, 3, Block{4, 0}, Block{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes:\nwant %v\ngot  %v", want, got)
+	}
+	cancel()
+	got = nil
+	s.Fill(RegionMask(Box{0, 0, 0, 4, 4, 4}), Block{1, 0})
+	if got != nil {
+		t.Errorf("Changes after cancel: %v", got)
+	}
+}
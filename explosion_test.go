@@ -0,0 +1,43 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestExplode(t *testing.T) {
+	// A stone cube with an obsidian wall at x = 8 and TNT in its center.
+	s := NewSchematic(11, 9, 9)
+	s.Fill(RegionMask(Box{0, 0, 0, 11, 9, 9}), Block{1, 0})
+	s.Fill(RegionMask(Box{8, 0, 0, 9, 9, 9}), Block{49, 0})
+	center := Point{4.5, 4.5, 4.5}
+	blasts := []Explosion{{center, TNTPower}}
+	opt := &ExplosionOptions{Seed: 1}
+	got := s.Explode(blasts, opt)
+	if len(got) == 0 {
+		t.Fatalf("Explode: no blocks destroyed")
+	}
+	for _, p := range got {
+		dx, dy, dz := float64(p.X)+0.5-center.X, float64(p.Y)+0.5-center.Y, float64(p.Z)+0.5-center.Z
+		if dx*dx+dy*dy+dz*dz > 4*4 {
+			t.Errorf("Explode: %v is too far", p)
+		}
+	}
+	if again := s.Explode(blasts, opt); len(again) != len(got) {
+		t.Errorf("Explode: the same seed destroyed %d and %d blocks", len(got), len(again))
+	}
+
+	// In the air, the blast reaches the wall, which holds.
+	s.Fill(RegionMask(Box{0, 0, 0, 8, 9, 9}), Block{})
+	s.SetBlock(3, 4, 4, Block{20, 0})
+	opt.Apply = true
+	got = s.Explode(blasts, opt)
+	if len(got) != 1 || got[0] != (BlockPos{3, 4, 4}) {
+		t.Errorf("Explode: want the glass destroyed, got %v", got)
+	}
+	if b := s.Block(3, 4, 4); b.Id != 0 {
+		t.Errorf("Explode with Apply: the glass is left: %v", b)
+	}
+	if b := s.Block(8, 4, 4); b.Id != 49 {
+		t.Errorf("Explode: the wall is destroyed: %v", b)
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+// An Expr is a compiled math expression of the variables x, y and z, such
+// as "x^2 + y^2 + z^2 < 1". The expressions use the numbers, the usual
+// arithmetic operators including % and ^ (power), the comparisons, &&, ||
+// and !, the constants pi and e and the functions abs, acos, asin, atan,
+// atan2, cbrt, ceil, cos, cosh, exp, floor, hypot, ln, log10, max, min,
+// pow, round, sin, sinh, sqrt, tan and tanh. A comparison is 1 if it holds
+// and 0 otherwise, and a value is true if it is not 0.
+type Expr struct {
+	eval exprFunc
+}
+
+type exprFunc func(v *[3]float64) float64
+
+// ParseExpr compiles the expression.
+func ParseExpr(str string) (*Expr, os.Error) {
+	p := &exprParser{str: str}
+	p.next()
+	f := p.or()
+	if p.err == nil && p.tok != "" {
+		p.fail()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Expr{f}, nil
+}
+
+// Eval returns the value of e at the point.
+func (e *Expr) Eval(x, y, z float64) float64 {
+	return e.eval(&[3]float64{x, y, z})
+}
+
+// Generate sets the blocks of the box at which the expression is above 0
+// to the block id, like the //g command of WorldEdit, and returns the number
+// of blocks set. The expression is evaluated for x, y and z scaled to run
+// from -1 to 1 across the box, so "x^2 + y^2 + z^2 < 1" fills a sphere or
+// an ellipsoid touching the sides of the box. The part of the box outside
+// of s is skipped.
+func (s *Schematic) Generate(b Box, expr string, id uint16) (n int, err os.Error) {
+	e, err := ParseExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	norm := func(v, min, max int) float64 {
+		c := float64(min+max-1) / 2
+		half := float64(max-1-min) / 2
+		if half == 0 {
+			half = 1
+		}
+		return (float64(v) - c) / half
+	}
+	m := MaskFunc(func(_ *Schematic, x, y, z int) bool {
+		if x < b.MinX || y < b.MinY || z < b.MinZ || x >= b.MaxX || y >= b.MaxY || z >= b.MaxZ {
+			return false
+		}
+		return e.Eval(norm(x, b.MinX, b.MaxX), norm(y, b.MinY, b.MaxY), norm(z, b.MinZ, b.MaxZ)) > 0
+	})
+	return s.Fill(m, Block{id, 0}), nil
+}
+
+// exprParser is a recursive descent parser of expressions. The methods
+// parsing the grammar rules return nil after an error.
+type exprParser struct {
+	str string
+	pos int    // the position after tok
+	tok string // the current token, "" at the end
+	at  int    // the position of tok
+	err os.Error
+}
+
+func isExprLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isExprDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.'
+}
+
+// next reads the next token.
+func (p *exprParser) next() {
+	for p.pos < len(p.str) && (p.str[p.pos] == ' ' || p.str[p.pos] == '\t') {
+		p.pos++
+	}
+	p.at = p.pos
+	if p.pos == len(p.str) {
+		p.tok = ""
+		return
+	}
+	c := p.str[p.pos]
+	switch {
+	case isExprLetter(c):
+		for p.pos < len(p.str) && (isExprLetter(p.str[p.pos]) || isExprDigit(p.str[p.pos])) {
+			p.pos++
+		}
+	case isExprDigit(c):
+		for p.pos < len(p.str) && isExprDigit(p.str[p.pos]) {
+			p.pos++
+		}
+		// An exponent, such as 1e-3.
+		if p.pos+1 < len(p.str) && (p.str[p.pos] == 'e' || p.str[p.pos] == 'E') {
+			i := p.pos + 1
+			if p.str[i] == '+' || p.str[i] == '-' {
+				i++
+			}
+			if i < len(p.str) && p.str[i] >= '0' && p.str[i] <= '9' {
+				for p.pos = i; p.pos < len(p.str) && p.str[p.pos] >= '0' && p.str[p.pos] <= '9'; p.pos++ {
+				}
+			}
+		}
+	default:
+		p.pos++
+		if p.pos < len(p.str) {
+			switch two := p.str[p.pos-1 : p.pos+1]; two {
+			case "<=", ">=", "==", "!=", "&&", "||":
+				p.pos++
+			}
+		}
+	}
+	p.tok = p.str[p.at:p.pos]
+}
+
+func (p *exprParser) fail() {
+	if p.err != nil {
+		return
+	}
+	if p.tok == "" {
+		p.err = fmt.Errorf("Unexpected end of expression %q", p.str)
+	} else {
+		p.err = fmt.Errorf("Unexpected %q at %d in expression %q", p.tok, p.at, p.str)
+	}
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *exprParser) or() exprFunc {
+	f := p.and()
+	for p.err == nil && p.tok == "||" {
+		p.next()
+		a, b := f, p.and()
+		f = func(v *[3]float64) float64 { return truth(a(v) != 0 || b(v) != 0) }
+	}
+	return f
+}
+
+func (p *exprParser) and() exprFunc {
+	f := p.cmp()
+	for p.err == nil && p.tok == "&&" {
+		p.next()
+		a, b := f, p.cmp()
+		f = func(v *[3]float64) float64 { return truth(a(v) != 0 && b(v) != 0) }
+	}
+	return f
+}
+
+func (p *exprParser) cmp() exprFunc {
+	a := p.sum()
+	op := p.tok
+	switch op {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return a
+	}
+	p.next()
+	b := p.sum()
+	switch op {
+	case "<":
+		return func(v *[3]float64) float64 { return truth(a(v) < b(v)) }
+	case "<=":
+		return func(v *[3]float64) float64 { return truth(a(v) <= b(v)) }
+	case ">":
+		return func(v *[3]float64) float64 { return truth(a(v) > b(v)) }
+	case ">=":
+		return func(v *[3]float64) float64 { return truth(a(v) >= b(v)) }
+	case "==":
+		return func(v *[3]float64) float64 { return truth(a(v) == b(v)) }
+	}
+	return func(v *[3]float64) float64 { return truth(a(v) != b(v)) }
+}
+
+func (p *exprParser) sum() exprFunc {
+	f := p.term()
+	for p.err == nil && (p.tok == "+" || p.tok == "-") {
+		op := p.tok
+		p.next()
+		a, b := f, p.term()
+		if op == "+" {
+			f = func(v *[3]float64) float64 { return a(v) + b(v) }
+		} else {
+			f = func(v *[3]float64) float64 { return a(v) - b(v) }
+		}
+	}
+	return f
+}
+
+func (p *exprParser) term() exprFunc {
+	f := p.unary()
+	for p.err == nil && (p.tok == "*" || p.tok == "/" || p.tok == "%") {
+		op := p.tok
+		p.next()
+		a, b := f, p.unary()
+		switch op {
+		case "*":
+			f = func(v *[3]float64) float64 { return a(v) * b(v) }
+		case "/":
+			f = func(v *[3]float64) float64 { return a(v) / b(v) }
+		default:
+			f = func(v *[3]float64) float64 { return math.Fmod(a(v), b(v)) }
+		}
+	}
+	return f
+}
+
+func (p *exprParser) unary() exprFunc {
+	switch p.tok {
+	case "-":
+		p.next()
+		a := p.unary()
+		return func(v *[3]float64) float64 { return -a(v) }
+	case "+":
+		p.next()
+		return p.unary()
+	case "!":
+		p.next()
+		a := p.unary()
+		return func(v *[3]float64) float64 { return truth(a(v) == 0) }
+	}
+	return p.power()
+}
+
+// power parses a power, which binds tighter than a unary minus on its left
+// and is right associative: -2^2 is -4 and 2^3^2 is 512.
+func (p *exprParser) power() exprFunc {
+	a := p.primary()
+	if p.err != nil || p.tok != "^" {
+		return a
+	}
+	p.next()
+	b := p.unary()
+	return func(v *[3]float64) float64 { return math.Pow(a(v), b(v)) }
+}
+
+var exprFuncs1 = map[string]func(float64) float64{
+	"abs":   math.Fabs,
+	"acos":  math.Acos,
+	"asin":  math.Asin,
+	"atan":  math.Atan,
+	"cbrt":  math.Cbrt,
+	"ceil":  math.Ceil,
+	"cos":   math.Cos,
+	"cosh":  math.Cosh,
+	"exp":   math.Exp,
+	"floor": math.Floor,
+	"ln":    math.Log,
+	"log10": math.Log10,
+	"round": func(a float64) float64 { return math.Floor(a + 0.5) },
+	"sin":   math.Sin,
+	"sinh":  math.Sinh,
+	"sqrt":  math.Sqrt,
+	"tan":   math.Tan,
+	"tanh":  math.Tanh,
+}
+
+var exprFuncs2 = map[string]func(float64, float64) float64{
+	"atan2": math.Atan2,
+	"hypot": math.Hypot,
+	"max":   math.Fmax,
+	"min":   math.Fmin,
+	"pow":   math.Pow,
+}
+
+func (p *exprParser) primary() exprFunc {
+	tok := p.tok
+	switch {
+	case tok == "(":
+		p.next()
+		f := p.or()
+		if p.err == nil && p.tok != ")" {
+			p.fail()
+		}
+		p.next()
+		return f
+	case tok != "" && isExprDigit(tok[0]):
+		c, err := strconv.Atof64(tok)
+		if err != nil {
+			p.fail()
+			return nil
+		}
+		p.next()
+		return func(*[3]float64) float64 { return c }
+	case tok != "" && isExprLetter(tok[0]):
+		p.next()
+		if p.tok == "(" {
+			return p.call(tok)
+		}
+		switch tok {
+		case "x", "y", "z":
+			k := int(tok[0] - 'x')
+			return func(v *[3]float64) float64 { return v[k] }
+		case "pi":
+			return func(*[3]float64) float64 { return math.Pi }
+		case "e":
+			return func(*[3]float64) float64 { return math.E }
+		}
+		p.err = fmt.Errorf("Unknown variable %s in expression %q", tok, p.str)
+		return nil
+	}
+	p.fail()
+	return nil
+}
+
+// call parses the arguments of the function name.
+func (p *exprParser) call(name string) exprFunc {
+	var args []exprFunc
+	p.next()
+	for p.err == nil && p.tok != ")" {
+		if len(args) > 0 {
+			if p.tok != "," {
+				p.fail()
+				return nil
+			}
+			p.next()
+		}
+		args = append(args, p.or())
+	}
+	if p.err != nil {
+		return nil
+	}
+	p.next()
+	if f, ok := exprFuncs1[name]; ok && len(args) == 1 {
+		a := args[0]
+		return func(v *[3]float64) float64 { return f(a(v)) }
+	}
+	if f, ok := exprFuncs2[name]; ok && len(args) == 2 {
+		a, b := args[0], args[1]
+		return func(v *[3]float64) float64 { return f(a(v), b(v)) }
+	}
+	p.err = fmt.Errorf("Unknown function %s with %d arguments in expression %q", name, len(args), p.str)
+	return nil
+}
@@ -0,0 +1,66 @@
+package schematic
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpr(t *testing.T) {
+	tests := []struct {
+		expr    string
+		x, y, z float64
+		want    float64
+	}{
+		{"1 + 2 * 3", 0, 0, 0, 7},
+		{"(1 + 2) * 3", 0, 0, 0, 9},
+		{"-2^2", 0, 0, 0, -4},
+		{"2^3^2", 0, 0, 0, 512},
+		{"7 % 3 - 1e1", 0, 0, 0, -9},
+		{"x*100 + y*10 + z", 1, 2, 3, 123},
+		{"x^2 + y^2 + z^2 < 1", 0.5, 0.5, 0.5, 1},
+		{"x^2 + y^2 + z^2 < 1", 1, 0.5, 0.5, 0},
+		{"x > 0 && y > 0 || !z", -1, 1, 0, 1},
+		{"min(x, y) + max(2, abs(-3)) + sqrt(16)", 1, 2, 0, 8},
+		{"round(cos(pi)) + floor(e)", 0, 0, 0, 1},
+		{"atan2(1, 1) * 4 == pi", 0, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := e.Eval(tt.x, tt.y, tt.z); math.Fabs(got-tt.want) > 1e-9 {
+			t.Errorf("%q at (%v, %v, %v): want %v, got %v", tt.expr, tt.x, tt.y, tt.z, tt.want, got)
+		}
+	}
+	for _, expr := range []string{"", "1 +", "(1", "1 2", "w", "sin(1, 2)", "foo(1)", "max(1,", "2 $ 3"} {
+		if _, err := ParseExpr(expr); err == nil {
+			t.Errorf("ParseExpr(%q): want error", expr)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s := NewSchematic(12, 12, 12)
+	n, err := s.Generate(Box{1, 1, 1, 10, 10, 10}, "x^2 + y^2 + z^2 <= 1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.BlockCounts()[1]; got != n || n == 0 {
+		t.Errorf("Generate returned %d, %d blocks set", n, got)
+	}
+	for _, p := range [][3]int{{5, 5, 5}, {1, 5, 5}, {9, 5, 5}, {5, 9, 5}} {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 1 {
+			t.Errorf("Block %v: want stone, got %v", p, b)
+		}
+	}
+	for _, p := range [][3]int{{1, 1, 1}, {0, 5, 5}, {10, 5, 5}, {9, 9, 9}} {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 0 {
+			t.Errorf("Block %v: want air, got %v", p, b)
+		}
+	}
+	if _, err := s.Generate(Box{0, 0, 0, 2, 2, 2}, "x +", 1); err == nil {
+		t.Errorf("Generate with a broken expression: want error")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// FarmOptions control SpawnRates. A nil *FarmOptions selects the
+// defaults.
+type FarmOptions struct {
+	SpawnOptions
+
+	// Player is the position where the player waits, in the coordinates of
+	// the schematic.
+	Player Point
+
+	// MinDistance and MaxDistance are the radiuses of the spawning sphere
+	// around the player. Defaults: 24 and 128, as in Minecraft.
+	MinDistance, MaxDistance float64
+
+	// Floor is the height in the world of the bottom layer of the
+	// schematic, above the bottom of the world.
+	Floor int
+}
+
+// A FarmReport is the outcome of SpawnRates.
+type FarmReport struct {
+	Layers []int // the number of the spots in each layer
+	Spots  int   // the number of the spots in total
+
+	// Rate is the expected number of the spots hit by a spawn attempt in
+	// a column of the schematic, summed over the columns. It compares the
+	// designs of a farm: the spots in the columns with fewer blocks above
+	// the bottom of the world are more likely to be tried.
+	Rate float64
+}
+
+// SpawnRates estimates the throughput of a mob farm. It counts the spots
+// of SpawnSpots by layer, taking those whose centers are within the
+// spawning sphere around the player. As in Minecraft 1.13 and later, a
+// spawn attempt picks a column and a height up to one above the highest
+// block of the column, so a spot adds 1 / (Floor + top + 1) to Rate, where
+// top is the height above the bottom of the schematic of the block above
+// the highest non-air block of its column.
+func (s *Schematic) SpawnRates(opt *FarmOptions) *FarmReport {
+	var o FarmOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.MinDistance <= 0 {
+		o.MinDistance = 24
+	}
+	if o.MaxDistance <= 0 {
+		o.MaxDistance = 128
+	}
+	r := &FarmReport{Layers: make([]int, s.Height)}
+	heights := s.heights()
+	for _, p := range s.SpawnSpots(&o.SpawnOptions) {
+		dx, dy, dz := float64(p.X)+0.5-o.Player.X, float64(p.Y)-o.Player.Y, float64(p.Z)+0.5-o.Player.Z
+		if d := dx*dx + dy*dy + dz*dz; d < o.MinDistance*o.MinDistance || d > o.MaxDistance*o.MaxDistance {
+			continue
+		}
+		r.Layers[p.Y]++
+		r.Spots++
+		r.Rate += 1 / (float64(o.Floor) + heights[p.Z*s.Width+p.X] + 1)
+	}
+	return r
+}
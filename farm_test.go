@@ -0,0 +1,28 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestSpawnRates(t *testing.T) {
+	// Two dark stone floors, at y=0 and y=3, 40 blocks long, with the
+	// player waiting above the start of the upper one.
+	s := NewSchematic(40, 6, 1)
+	for x := 0; x < 40; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+		s.SetBlock(x, 3, 0, Block{1, 0})
+	}
+	opt := &FarmOptions{Player: Point{0.5, 5, 0.5}, Floor: 60}
+	r := s.SpawnRates(opt)
+	// The blocks up to x=23 on both floors are too close.
+	if r.Layers[1] != 16 || r.Layers[4] != 16 || r.Spots != 32 {
+		t.Fatalf("SpawnRates: want 16 spots at y=1 and y=4, got %v", r.Layers)
+	}
+	if want := 32.0 / 65; r.Rate < want-1e-9 || r.Rate > want+1e-9 {
+		t.Errorf("Rate: want %v, got %v", want, r.Rate)
+	}
+	opt.MaxDistance = 30
+	if r = s.SpawnRates(opt); r.Spots != 12 {
+		t.Errorf("SpawnRates within 30 blocks: want 12 spots, got %v", r.Layers)
+	}
+}
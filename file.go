@@ -0,0 +1,213 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Ext is the file name extension of schematic files.
+const Ext = ".schematic"
+
+// ZstdExt is appended to the extension of the files written with Zstd
+// compression, as in "house.schem.zst". ReadFile detects the compression
+// of any file, and uses the extension before ZstdExt to pick the format.
+const ZstdExt = ".zst"
+
+// ReadSchematicFile reads the schematic stored in the named file.
+func ReadSchematicFile(name string) (s *Schematic, err os.Error) {
+	var f *os.File
+	if f, err = os.Open(name); err != nil {
+		return
+	}
+	defer f.Close()
+	return ReadSchematic(f)
+}
+
+// WriteSchematicFile writes s to the named file, creating or truncating it.
+func WriteSchematicFile(name string, s *Schematic, opt *WriteOptions) (err os.Error) {
+	var f *os.File
+	if f, err = os.Create(name); err != nil {
+		return
+	}
+	if err = WriteSchematicOptions(f, s, opt); err != nil {
+		f.Close()
+		return
+	}
+	return f.Close()
+}
+
+// ReadFile reads a schematic file of any supported format, chosen by the
+// extension: Sponge and Litematica files are converted with their
+// Schematic methods, the other files are read by ReadSchematic.
+func ReadFile(name string) (s *Schematic, err os.Error) {
+	s, _, err = readFile(name)
+	return
+}
+
+// readFile is like ReadFile but also returns the DataVersion of Sponge
+// and Litematica files, or zero for the other files.
+func readFile(name string) (s *Schematic, dataVersion int, err os.Error) {
+	format := fileFormat(name)
+	if format == "schematic" {
+		s, err = ReadSchematicFile(name)
+		return
+	}
+	var f *os.File
+	if f, err = os.Open(name); err != nil {
+		return
+	}
+	defer f.Close()
+	if format == "sponge" {
+		var sp *Sponge
+		if sp, err = readSponge(f); err != nil {
+			return
+		}
+		dataVersion = sp.DataVersion
+		if err = sp.Translate(LatestDataVersion); err == nil {
+			s, err = sp.Schematic()
+		}
+		return
+	}
+	var l *Litematic
+	if l, err = readLitematicRegions(f); err != nil {
+		return
+	}
+	dataVersion = l.DataVersion
+	if err = l.Translate(LatestDataVersion); err == nil {
+		s, err = l.Schematic()
+	}
+	return
+}
+
+// formatName returns the name in lower case without ZstdExt, so that its
+// extension is the one of the format.
+func formatName(name string) string {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ZstdExt) {
+		lower = lower[:len(lower)-len(ZstdExt)]
+	}
+	return lower
+}
+
+// fileFormat returns the format of the named file as chosen by ReadFile:
+// "sponge", "litematic" or "schematic".
+func fileFormat(name string) string {
+	lower := formatName(name)
+	switch {
+	case strings.HasSuffix(lower, SpongeExt):
+		return "sponge"
+	case strings.HasSuffix(lower, LitematicExt):
+		return "litematic"
+	}
+	return "schematic"
+}
+
+// isSchematicFile reports whether the file name has the extension of a
+// format read by ReadFile.
+func isSchematicFile(name string) bool {
+	lower := formatName(name)
+	for _, ext := range []string{Ext, SpongeExt, LitematicExt} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// A WalkFunc is called by WalkSchematics for every schematic file found.
+// If the file could not be parsed, s is nil and err describes the problem;
+// a directory which could not be read is reported the same way.
+// Returning an error stops the walk.
+type WalkFunc func(path string, s *Schematic, err os.Error) os.Error
+
+// WalkSchematics parses all schematic, Sponge and Litematica files in the
+// directory tree rooted at root, in lexical order, with ReadFile and calls
+// fn for each of them. The root may be a symbolic link.
+func WalkSchematics(root string, fn WalkFunc) os.Error {
+	return walkFiles(root, func(path string, err os.Error) os.Error {
+		if err != nil {
+			return fn(path, nil, err)
+		}
+		s, err := ReadFile(path)
+		return fn(path, s, err)
+	})
+}
+
+// FindSchematics returns the names of all schematic, Sponge and Litematica
+// files in the directory tree rooted at root, in lexical order. The
+// directories which cannot be read are skipped.
+func FindSchematics(root string) (names []string, err os.Error) {
+	err = walkFiles(root, func(path string, err os.Error) os.Error {
+		if err == nil {
+			names = append(names, path)
+		}
+		return nil
+	})
+	return
+}
+
+// walkFiles calls fn for every schematic file in the tree rooted at root.
+// The errors of reading the directories below root are passed to fn with
+// their paths, so that one of them does not stop the walk.
+func walkFiles(root string, fn func(path string, err os.Error) os.Error) os.Error {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDirectory() {
+		if !isSchematicFile(root) {
+			return nil
+		}
+		return fn(root, nil)
+	}
+	names, err := readDirNames(root)
+	if err != nil {
+		return err
+	}
+	return walkNames(root, names, fn)
+}
+
+// walkNames walks the entries of the directory dir.
+func walkNames(dir string, names []string, fn func(path string, err os.Error) os.Error) os.Error {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		fi, err := os.Lstat(path)
+		switch {
+		case err != nil:
+			err = fn(path, err)
+		case fi.IsDirectory():
+			var sub []string
+			if sub, err = readDirNames(path); err != nil {
+				err = fn(path, err)
+			} else {
+				err = walkNames(path, sub, fn)
+			}
+		case isSchematicFile(path):
+			err = fn(path, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDirNames returns the sorted names of the entries of the directory.
+func readDirNames(dir string) ([]string, os.Error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,108 @@
+package schematic
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalkSchematics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	vol := readTestSchematic(t, "testdata/cylinder.schematic")
+	if err = os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	files := []string{"b.schematic", "sub/a.schematic"}
+	for _, name := range files {
+		if err = WriteSchematicFile(filepath.Join(dir, name), vol, nil); err != nil {
+			t.Fatalf("WriteSchematicFile: %v", err)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(dir, "broken.schematic"), []byte("junk"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("junk"), 0644)
+
+	var found []string
+	var broken int
+	err = WalkSchematics(dir, func(path string, s *Schematic, err os.Error) os.Error {
+		if err != nil {
+			broken++
+			return nil
+		}
+		if s.Width != vol.Width {
+			t.Errorf("%s: Width: want %d, got %d", path, vol.Width, s.Width)
+		}
+		found = append(found, filepath.ToSlash(path[len(dir)+1:]))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkSchematics: %v", err)
+	}
+	if len(found) != 2 || found[0] != files[0] || found[1] != files[1] {
+		t.Errorf("WalkSchematics: want %v, got %v", files, found)
+	}
+	if broken != 1 {
+		t.Errorf("WalkSchematics: want 1 broken file, got %d", broken)
+	}
+
+	names, err := FindSchematics(dir)
+	if err != nil {
+		t.Fatalf("FindSchematics: %v", err)
+	}
+	if len(names) != 3 || names[0] != filepath.Join(dir, "b.schematic") {
+		t.Errorf("FindSchematics: want broken, b and sub/a, got %v", names)
+	}
+}
+
+func TestWalkFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewSchematic(2, 1, 1)
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	l, err := SplitLitematic(s, nil)
+	if err != nil {
+		t.Fatalf("SplitLitematic: %v", err)
+	}
+	var buf bytes.Buffer
+	if err = WriteLitematic(&buf, l); err != nil {
+		t.Fatalf("WriteLitematic: %v", err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "a"+LitematicExt), buf.Bytes(), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b"+SpongeExt), []byte("junk"), 0644)
+
+	// The root is a symbolic link to the directory.
+	link := dir + "-link"
+	if err = os.Symlink(dir, link); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	defer os.Remove(link)
+	var found []string
+	err = WalkSchematics(link, func(path string, s *Schematic, err os.Error) os.Error {
+		found = append(found, filepath.Base(path))
+		switch filepath.Ext(path) {
+		case LitematicExt:
+			if err != nil || s.Block(0, 0, 0) != (Block{1, 0}) {
+				t.Errorf("%s: want stone, got %v, %v", path, s, err)
+			}
+		case SpongeExt:
+			if err == nil {
+				t.Errorf("%s: want error, got nil", path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkSchematics: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("WalkSchematics of a link: want a.litematic and b.schem, got %v", found)
+	}
+}
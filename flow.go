@@ -0,0 +1,178 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"sort"
+)
+
+// The distances the liquids flow over a flat floor, as in the Overworld.
+const (
+	waterSpread = 7
+	lavaSpread  = 3
+)
+
+// falling is the level of the liquids falling down.
+const falling = 8
+
+// FlowOptions control Flow. A nil *FlowOptions selects the defaults.
+type FlowOptions struct {
+	// Region is the space meant to hold the liquids, such as the inside
+	// of an aquarium. The liquids leaving it are reported as leaks and
+	// are not followed further. An empty box means the whole schematic.
+	Region Box
+
+	// Apply fills the blocks reached by the liquids with flowing water
+	// and lava.
+	Apply bool
+}
+
+// A Leak is a place where a liquid leaves the region of Flow.
+type Leak struct {
+	Pos    BlockPos  // the last block of the liquid in the region
+	Face   Direction // the direction in which the liquid leaves
+	Source BlockPos  // the source block of the liquid
+}
+
+// A FlowResult is the outcome of Flow.
+type FlowResult struct {
+	Flooded []BlockPos // the blocks reached by the liquids, other than the sources
+	Leaks   []Leak
+}
+
+// Flow simulates the spreading of the water and the lava from their
+// source blocks, those with the data value 0, and reports the blocks the
+// liquids reach and where they leave the region. As in Minecraft, a
+// liquid falls down whenever it can and spreads sideways over the
+// blocks below it, 7 blocks for water and 3 for lava, again from where
+// it lands; it fills the blocks which are neither Solid nor source
+// blocks, breaking plants and torches. Unlike in Minecraft, the liquids
+// do not prefer the nearest drops, and water meeting lava makes no stone:
+// the liquid arriving first keeps the block. The world outside of the
+// schematic is air.
+func (s *Schematic) Flow(opt *FlowOptions) *FlowResult {
+	var o FlowOptions
+	if opt != nil {
+		o = *opt
+	}
+	region := o.Region
+	if region.Empty() {
+		region = Box{0, 0, 0, s.Width, s.Height, s.Length}
+	}
+	level := make([]int, len(s.Blocks)) // the level of the liquid plus 1, or 0
+	liquid := make([]uint16, len(s.Blocks))
+	source := make([]int, len(s.Blocks))
+	var queue []int
+	for i := range s.Blocks {
+		id := s.id(i)
+		if (id == 8 || id == 9 || id == 10 || id == 11) && s.Data[i] == 0 {
+			x, y, z := s.coords(i)
+			if !(BlockPos{x, y, z}).In(region) {
+				continue
+			}
+			liquid[i], source[i] = id&^1, i // flowing water and lava have even ids
+			level[i] = spreadOf(liquid[i]) + 1
+			queue = append(queue, i)
+		}
+	}
+	isSource := func(i int) bool { return source[i] == i && level[i] > 0 }
+	// free reports whether the liquid may flow into the position, which
+	// is outside of the schematic or in it.
+	free := func(p BlockPos) bool {
+		b := s.Block(p.X, p.Y, p.Z)
+		if b.Is(Solid) {
+			return false
+		}
+		return !s.Inside(p.X, p.Y, p.Z) || !isSource(s.index(p.X, p.Y, p.Z))
+	}
+	leaks := make(map[Leak]bool)
+	var flooded []int
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		x, y, z := s.coords(i)
+		p := BlockPos{x, y, z}
+		sx, sy, sz := s.coords(source[i])
+		flow := func(d Direction, l int) {
+			q := p.Step(d)
+			if !free(q) {
+				return
+			}
+			if !q.In(region) {
+				leaks[Leak{p, d, BlockPos{sx, sy, sz}}] = true
+				return
+			}
+			j := s.index(q.X, q.Y, q.Z)
+			if liquid[j] != 0 && liquid[j] != liquid[i] || level[j] >= l+1 {
+				return
+			}
+			if level[j] == 0 {
+				flooded = append(flooded, j)
+			}
+			level[j], liquid[j], source[j] = l+1, liquid[i], source[i]
+			queue = append(queue, j)
+		}
+		if free(p.Step(Down)) {
+			flow(Down, falling)
+			continue
+		}
+		l := level[i] - 1
+		if l == falling {
+			l = spreadOf(liquid[i])
+		}
+		if l > 0 {
+			for _, d := range []Direction{East, South, West, North} {
+				flow(d, l-1)
+			}
+		}
+	}
+
+	r := new(FlowResult)
+	sort.Ints(flooded)
+	for _, i := range flooded {
+		x, y, z := s.coords(i)
+		r.Flooded = append(r.Flooded, BlockPos{x, y, z})
+		if o.Apply {
+			// The data value of flowing liquids is the distance from the
+			// source, or 8 for the falling ones.
+			data := byte(falling)
+			if l := level[i] - 1; l != falling {
+				data = byte(spreadOf(liquid[i]) - l)
+			}
+			s.SetBlock(x, y, z, Block{liquid[i], data})
+		}
+	}
+	for l := range leaks {
+		r.Leaks = append(r.Leaks, l)
+	}
+	sort.Sort(leakSlice{s, r.Leaks})
+	return r
+}
+
+// spreadOf returns the distance the liquid of the flowing id spreads.
+func spreadOf(id uint16) int {
+	if id == 10 {
+		return lavaSpread
+	}
+	return waterSpread
+}
+
+// leakSlice orders the leaks by position, face and source.
+type leakSlice struct {
+	s     *Schematic
+	leaks []Leak
+}
+
+func (p leakSlice) Len() int { return len(p.leaks) }
+func (p leakSlice) Less(i, j int) bool {
+	a, b := p.leaks[i], p.leaks[j]
+	if a.Pos != b.Pos {
+		return p.s.index(a.Pos.X, a.Pos.Y, a.Pos.Z) < p.s.index(b.Pos.X, b.Pos.Y, b.Pos.Z)
+	}
+	if a.Face != b.Face {
+		return a.Face < b.Face
+	}
+	return p.s.index(a.Source.X, a.Source.Y, a.Source.Z) < p.s.index(b.Source.X, b.Source.Y, b.Source.Z)
+}
+func (p leakSlice) Swap(i, j int) { p.leaks[i], p.leaks[j] = p.leaks[j], p.leaks[i] }
@@ -0,0 +1,85 @@
+package schematic
+
+import (
+	"testing"
+)
+
+// pool returns a stone pool of 3x3 blocks of water space with a water
+// source in the middle.
+func pool() *Schematic {
+	s := NewSchematic(5, 3, 5)
+	for z := 0; z < 5; z++ {
+		for x := 0; x < 5; x++ {
+			s.SetBlock(x, 0, z, Block{1, 0})
+			if x == 0 || z == 0 || x == 4 || z == 4 {
+				s.SetBlock(x, 1, z, Block{1, 0})
+				s.SetBlock(x, 2, z, Block{1, 0})
+			}
+		}
+	}
+	s.SetBlock(2, 1, 2, Block{9, 0})
+	return s
+}
+
+func TestFlow(t *testing.T) {
+	s := pool()
+	r := s.Flow(&FlowOptions{Apply: true})
+	if len(r.Flooded) != 8 || len(r.Leaks) != 0 {
+		t.Errorf("Flow in a pool: want 8 blocks and no leaks, got %v", r)
+	}
+	if b := s.Block(3, 1, 2); b != (Block{8, 1}) {
+		t.Errorf("Flow: want flowing water at (3, 1, 2), got %v", b)
+	}
+	if b := s.Block(3, 1, 3); b != (Block{8, 2}) {
+		t.Errorf("Flow: want flowing water at (3, 1, 3), got %v", b)
+	}
+
+	// A hole in the wall leaks out of the schematic and out of the inside
+	// of the pool.
+	s = pool()
+	s.SetBlock(4, 1, 2, Block{})
+	r = s.Flow(nil)
+	if len(r.Leaks) != 1 || r.Leaks[0] != (Leak{BlockPos{4, 1, 2}, East, BlockPos{2, 1, 2}}) {
+		t.Errorf("Leaks: got %v", r.Leaks)
+	}
+	r = s.Flow(&FlowOptions{Region: Box{1, 1, 1, 4, 3, 4}})
+	if len(r.Leaks) != 1 || r.Leaks[0] != (Leak{BlockPos{3, 1, 2}, East, BlockPos{2, 1, 2}}) {
+		t.Errorf("Leaks from the region: got %v", r.Leaks)
+	}
+	if s.Block(3, 1, 2) != (Block{}) {
+		t.Errorf("Flow without Apply changed the blocks")
+	}
+}
+
+func TestFlowFalling(t *testing.T) {
+	// Lava on a ledge falls down to a floor and spreads 3 blocks from
+	// where it lands.
+	s := NewSchematic(7, 3, 1)
+	for x := 0; x < 7; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+	}
+	s.SetBlock(0, 1, 0, Block{1, 0})
+	s.SetBlock(0, 2, 0, Block{11, 0})
+	r := s.Flow(&FlowOptions{Apply: true})
+	want := []BlockPos{{1, 1, 0}, {2, 1, 0}, {3, 1, 0}, {4, 1, 0}, {1, 2, 0}}
+	if len(r.Flooded) != len(want) {
+		t.Fatalf("Flow: want %v, got %v", want, r.Flooded)
+	}
+	for i, p := range want {
+		if r.Flooded[i] != p {
+			t.Errorf("Flow: want %v, got %v", want, r.Flooded)
+			break
+		}
+	}
+	if b := s.Block(1, 1, 0); b != (Block{10, 8}) {
+		t.Errorf("Flow: want falling lava at (1, 1, 0), got %v", b)
+	}
+	if b := s.Block(4, 1, 0); b != (Block{10, 3}) {
+		t.Errorf("Flow: want flowing lava at (4, 1, 0), got %v", b)
+	}
+	// The schematic is a single block long, so the lava leaks to the
+	// north and to the south too.
+	if len(r.Leaks) != 9 || r.Leaks[7] != (Leak{BlockPos{0, 2, 0}, West, BlockPos{0, 2, 0}}) {
+		t.Errorf("Leaks: got %v", r.Leaks)
+	}
+}
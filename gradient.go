@@ -0,0 +1,79 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"math"
+)
+
+// A Measure returns the value of a position which a GradientPattern maps
+// to a block, such as the height or the distance from a point.
+type Measure func(x, y, z int) float64
+
+// HeightMeasure measures the height of a block.
+func HeightMeasure(x, y, z int) float64 {
+	return float64(y)
+}
+
+// DistanceMeasure returns the measure of the distance of a block center
+// from the point.
+func DistanceMeasure(p Point) Measure {
+	return func(x, y, z int) float64 {
+		dx, dy, dz := float64(x)+0.5-p.X, float64(y)+0.5-p.Y, float64(z)+0.5-p.Z
+		return math.Sqrt(dx*dx + dy*dy + dz*dz)
+	}
+}
+
+// AxisDistanceMeasure returns the measure of the distance of a block center
+// from the line along the axis through the point.
+func AxisDistanceMeasure(a Axis, p Point) Measure {
+	return func(x, y, z int) float64 {
+		d := [3]float64{float64(x) + 0.5 - p.X, float64(y) + 0.5 - p.Y, float64(z) + 0.5 - p.Z}
+		d[a] = 0
+		return math.Sqrt(d[0]*d[0] + d[1]*d[1] + d[2]*d[2])
+	}
+}
+
+// A GradientPattern maps the measure of a position through a list of
+// blocks: the values from Start to End are split into equal bands, one
+// for every block, and the values outside of the range get the first or
+// the last block. End may be below Start to reverse the order.
+//
+// With Blend 0 the bands have sharp edges. A higher Blend mixes the blocks
+// of neighboring bands at random near their edges, over Blend of the width
+// of a band; with Blend 1 the blocks change gradually from the center of
+// every band to the center of the next one, making a smooth gradient. The
+// choice depends only on the position and the seed.
+type GradientPattern struct {
+	Blocks     []Block
+	Measure    Measure
+	Start, End float64
+	Blend      float64
+	Seed       int64
+}
+
+// BlockAt returns the block of the band of the position.
+func (p *GradientPattern) BlockAt(x, y, z int) Block {
+	n := len(p.Blocks)
+	t := 0.0
+	if p.End != p.Start {
+		t = (p.Measure(x, y, z) - p.Start) / (p.End - p.Start)
+	}
+	t = math.Fmin(math.Fmax(t, 0), 1)
+	// The position between the centers of the bands k and k+1.
+	f := t*float64(n) - 0.5
+	k := int(math.Floor(f))
+	frac := f - float64(k)
+	var upper float64 // the chance of the band k+1
+	switch {
+	case p.Blend > 0:
+		upper = math.Fmin(math.Fmax((frac-0.5)/p.Blend+0.5, 0), 1)
+	case frac >= 0.5:
+		upper = 1
+	}
+	if float64(hashPos(p.Seed, x, y, z)>>11)/(1<<53) < upper {
+		k++
+	}
+	return p.Blocks[imax(0, imin(k, n-1))]
+}
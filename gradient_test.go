@@ -0,0 +1,54 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestGradientPattern(t *testing.T) {
+	blocks := []Block{{1, 0}, {4, 0}, {3, 0}, {2, 0}}
+	p := &GradientPattern{Blocks: blocks, Measure: HeightMeasure, Start: 0, End: 8}
+	for y, want := range []uint16{1, 1, 4, 4, 3, 3, 2, 2, 2, 2} {
+		if b := p.BlockAt(5, y, 7); b.Id != want {
+			t.Errorf("Band at y = %d: want %d, got %v", y, want, b)
+		}
+	}
+	if b := p.BlockAt(0, -3, 0); b.Id != 1 {
+		t.Errorf("Below the start: want the first block, got %v", b)
+	}
+	p.Start, p.End = 8, 0
+	if b := p.BlockAt(0, 0, 0); b.Id != 2 {
+		t.Errorf("Reversed: want the last block at the bottom, got %v", b)
+	}
+
+	// A smooth blend of two blocks over 100 layers changes from the first
+	// block at the height of 25 to the second at the height of 75.
+	p = &GradientPattern{Blocks: []Block{{1, 0}, {2, 0}}, Measure: HeightMeasure, Start: 0, End: 100, Blend: 1, Seed: 3}
+	for _, tt := range []struct{ y, min, max int }{{20, 0, 0}, {30, 50, 160}, {60, 600, 830}, {80, 1024, 1024}} {
+		n := 0
+		for z := 0; z < 32; z++ {
+			for x := 0; x < 32; x++ {
+				if p.BlockAt(x, tt.y, z).Id == 2 {
+					n++
+				}
+			}
+		}
+		if n < tt.min || n > tt.max {
+			t.Errorf("y = %d: want %d to %d of 1024 blocks of grass, got %d", tt.y, tt.min, tt.max, n)
+		}
+	}
+
+	s := NewSchematic(9, 3, 9)
+	r := &GradientPattern{Blocks: blocks[:3], Measure: AxisDistanceMeasure(AxisY, Point{4.5, 0, 4.5}), End: 4.5}
+	s.Fill(RegionMask(Box{0, 0, 0, 9, 3, 9}), r)
+	for _, tt := range []struct {
+		x, z int
+		want uint16
+	}{{4, 4, 1}, {4, 3, 1}, {4, 1, 3}, {0, 0, 3}} {
+		if b := s.Block(tt.x, 2, tt.z); b.Id != tt.want {
+			t.Errorf("Ring at (%d, %d): want %d, got %v", tt.x, tt.z, tt.want, b)
+		}
+	}
+	if d := DistanceMeasure(Point{0, 0, 0})(2, 5, 3); d < 6.9 || d > 7 {
+		t.Errorf("DistanceMeasure: got %v", d)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// A FallingStack is a column of Falling blocks, such as sand, gravel or
+// concrete powder, with nothing to hold it, which collapses as soon as
+// the world loads it.
+type FallingStack struct {
+	Bottom BlockPos // the lowest block of the stack
+	Height int      // the number of the blocks of the stack
+	Drop   int      // the number of the blocks the stack falls through in the schematic
+}
+
+// fallsInto reports whether the falling blocks fall through b: air, the
+// liquids, fire and the plants replaced by blocks, like tall grass.
+func fallsInto(b Block) bool {
+	switch b.Id {
+	case 0, 51, 31, 32, 78, 106, 175:
+		return true
+	}
+	return b.Is(Liquid)
+}
+
+// FallingStacks returns the stacks of Falling blocks of s resting on air,
+// a liquid or a plant, from the bottom to the top. The blocks of the
+// bottom layer rest on the world, and are not checked.
+func (s *Schematic) FallingStacks() (stacks []FallingStack) {
+	for y := 1; y < s.Height; y++ {
+		for z := 0; z < s.Length; z++ {
+			for x := 0; x < s.Width; x++ {
+				if !s.Block(x, y, z).Is(Falling) || !fallsInto(s.Block(x, y-1, z)) {
+					continue
+				}
+				st := FallingStack{Bottom: BlockPos{x, y, z}}
+				for h := y; h < s.Height && s.Block(x, h, z).Is(Falling); h++ {
+					st.Height++
+				}
+				for h := y - 1; h >= 0 && fallsInto(s.Block(x, h, z)); h-- {
+					st.Drop++
+				}
+				stacks = append(stacks, st)
+			}
+		}
+	}
+	return
+}
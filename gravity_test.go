@@ -0,0 +1,31 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestFallingStacks(t *testing.T) {
+	s := NewSchematic(4, 5, 1)
+	// Sand on the ground, gravel on a stone pillar and a stack of sand
+	// and concrete powder floating over water.
+	s.SetBlock(0, 0, 0, Block{12, 0})
+	s.SetBlock(1, 0, 0, Block{1, 0})
+	s.SetBlock(1, 1, 0, Block{13, 0})
+	s.SetBlock(2, 0, 0, Block{9, 0})
+	s.SetBlock(2, 2, 0, Block{12, 0})
+	s.SetBlock(2, 3, 0, Block{252, 4})
+	s.SetBlock(2, 4, 0, Block{1, 0})
+	// Sand hanging under stone.
+	s.SetBlock(3, 4, 0, Block{1, 0})
+	s.SetBlock(3, 3, 0, Block{12, 1})
+	stacks := s.FallingStacks()
+	want := []FallingStack{{BlockPos{2, 2, 0}, 2, 2}, {BlockPos{3, 3, 0}, 1, 3}}
+	if len(stacks) != len(want) {
+		t.Fatalf("FallingStacks: want %v, got %v", want, stacks)
+	}
+	for i := range want {
+		if stacks[i] != want[i] {
+			t.Errorf("FallingStacks: want %v, got %v", want, stacks)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"sort"
+)
+
+// ConvexHull fills the air inside of the convex hull of the non-air blocks
+// with b and returns the number of filled blocks. A block is inside if its
+// center is inside of the hull or on its surface; the hull wraps the whole
+// cubes of the blocks.
+func (s *Schematic) ConvexHull(b Block) (n int) {
+	faces := hullFaces(s.hullPoints())
+	if faces == nil {
+		return 0
+	}
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			// Every face limits the range of z in the row; the centers
+			// of the blocks are at the odd doubled coordinates.
+			lo, hi := int64(0), int64(s.Length-1)
+			for _, f := range faces {
+				r := 2*f.d - f.n[0]*int64(2*x+1) - f.n[1]*int64(2*y+1)
+				switch nz := f.n[2]; {
+				case nz > 0: // 2z+1 <= r/nz
+					hi = min64(hi, floorDiv(floorDiv(r, nz)-1, 2))
+				case nz < 0: // 2z+1 >= r/nz
+					lo = max64(lo, ceilDiv(ceilDiv(r, nz)-1, 2))
+				case r < 0:
+					hi = -1
+				}
+			}
+			for z := int(lo); int64(z) <= hi; z++ {
+				if s.GetV(x, y, z) == 0 {
+					s.SetBlock(x, y, z, b)
+					n++
+				}
+			}
+		}
+	}
+	return
+}
+
+type hullPoint [3]int64
+
+func (a hullPoint) sub(b hullPoint) hullPoint {
+	return hullPoint{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func (a hullPoint) cross(b hullPoint) hullPoint {
+	return hullPoint{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func (a hullPoint) dot(b hullPoint) int64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+// hullPoints returns the corners of the blocks of s which may be vertices
+// of the convex hull: those on the 2D convex hulls of the layers of x.
+func (s *Schematic) hullPoints() (points []hullPoint) {
+	seen := make(map[hullPoint]bool)
+	for x := 0; x < s.Width; x++ {
+		var layer [][2]int64
+		for y := 0; y < s.Height; y++ {
+			min, max := -1, -1
+			for z := 0; z < s.Length; z++ {
+				if s.GetV(x, y, z) != 0 {
+					if min < 0 {
+						min = z
+					}
+					max = z
+				}
+			}
+			if min < 0 {
+				continue
+			}
+			layer = append(layer, [2]int64{int64(y), int64(min)}, [2]int64{int64(y + 1), int64(min)},
+				[2]int64{int64(y), int64(max + 1)}, [2]int64{int64(y + 1), int64(max + 1)})
+		}
+		for _, p := range hull2D(layer) {
+			for dx := 0; dx <= 1; dx++ {
+				q := hullPoint{int64(x + dx), p[0], p[1]}
+				if !seen[q] {
+					seen[q] = true
+					points = append(points, q)
+				}
+			}
+		}
+	}
+	return
+}
+
+type points2D [][2]int64
+
+func (p points2D) Len() int      { return len(p) }
+func (p points2D) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p points2D) Less(i, j int) bool {
+	return p[i][0] < p[j][0] || p[i][0] == p[j][0] && p[i][1] < p[j][1]
+}
+
+// hull2D returns the vertices of the convex hull of the points, by the
+// monotone chain algorithm.
+func hull2D(p points2D) points2D {
+	if len(p) < 3 {
+		return p
+	}
+	sort.Sort(p)
+	cross := func(o, a, b [2]int64) int64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+	h := make(points2D, 0, 2*len(p))
+	for _, q := range p {
+		for len(h) >= 2 && cross(h[len(h)-2], h[len(h)-1], q) <= 0 {
+			h = h[:len(h)-1]
+		}
+		h = append(h, q)
+	}
+	for i, t := len(p)-2, len(h)+1; i >= 0; i-- {
+		for len(h) >= t && cross(h[len(h)-2], h[len(h)-1], p[i]) <= 0 {
+			h = h[:len(h)-1]
+		}
+		h = append(h, p[i])
+	}
+	return h[:len(h)-1]
+}
+
+// A hullFace is a triangle of the hull with the outward normal n: the
+// points p inside of the hull have n·p <= d.
+type hullFace struct {
+	v [3]int // the vertices, counterclockwise seen from outside
+	n hullPoint
+	d int64
+}
+
+func newHullFace(points []hullPoint, a, b, c int) hullFace {
+	n := points[b].sub(points[a]).cross(points[c].sub(points[a]))
+	return hullFace{[3]int{a, b, c}, n, n.dot(points[a])}
+}
+
+// hullFaces returns the faces of the convex hull of the points, built
+// incrementally, or nil if the points do not span a volume.
+func hullFaces(points []hullPoint) []hullFace {
+	if len(points) < 4 {
+		return nil
+	}
+	// The first tetrahedron.
+	i1, i2, i3 := -1, -1, -1
+	for i := 1; i < len(points) && i1 < 0; i++ {
+		if points[i] != points[0] {
+			i1 = i
+		}
+	}
+	if i1 < 0 {
+		return nil
+	}
+	for i := 1; i < len(points) && i2 < 0; i++ {
+		if n := points[i1].sub(points[0]).cross(points[i].sub(points[0])); n != (hullPoint{}) {
+			i2 = i
+		}
+	}
+	if i2 < 0 {
+		return nil
+	}
+	f := newHullFace(points, 0, i1, i2)
+	for i := 1; i < len(points) && i3 < 0; i++ {
+		if f.n.dot(points[i]) != f.d {
+			i3 = i
+		}
+	}
+	if i3 < 0 {
+		return nil
+	}
+	if f.n.dot(points[i3]) > f.d {
+		i1, i2 = i2, i1
+	}
+	faces := []hullFace{
+		newHullFace(points, 0, i1, i2),
+		newHullFace(points, 0, i3, i1),
+		newHullFace(points, i1, i3, i2),
+		newHullFace(points, i2, i3, 0),
+	}
+	for p := range points {
+		var visible []hullFace
+		var kept []hullFace
+		for _, f := range faces {
+			if f.n.dot(points[p]) > f.d {
+				visible = append(visible, f)
+			} else {
+				kept = append(kept, f)
+			}
+		}
+		if visible == nil {
+			continue
+		}
+		// The horizon consists of the edges of the visible faces whose
+		// other face is not visible.
+		edges := make(map[[2]int]bool)
+		for _, f := range visible {
+			for k := 0; k < 3; k++ {
+				edges[[2]int{f.v[k], f.v[(k+1)%3]}] = true
+			}
+		}
+		for _, f := range visible {
+			for k := 0; k < 3; k++ {
+				a, b := f.v[k], f.v[(k+1)%3]
+				if !edges[[2]int{b, a}] {
+					kept = append(kept, newHullFace(points, a, b, p))
+				}
+			}
+		}
+		faces = kept
+	}
+	return faces
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// floorDiv returns a/b rounded down.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// ceilDiv returns a/b rounded up.
+func ceilDiv(a, b int64) int64 {
+	return -floorDiv(-a, b)
+}
+
+// Shrinkwrap drapes a shell of b one block thick over the top of the
+// blocks, as a sheet laid over the build from above: it covers the top
+// block of every column and runs down the sides to the top of the lower
+// neighbors or to the bottom of the schematic. The shell is closed for
+// water flowing down from above. Only air is replaced. Shrinkwrap returns
+// the number of placed blocks.
+func (s *Schematic) Shrinkwrap(b Block) (n int) {
+	h := s.heights()
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			i := z*s.Width + x
+			top := int(h[i])
+			s.neighbors4(i, func(j int) {
+				top = imax(top, int(h[j]))
+			})
+			if top == 0 {
+				continue
+			}
+			for y := int(h[i]); y <= top && y < s.Height; y++ {
+				if s.GetV(x, y, z) == 0 {
+					s.SetBlock(x, y, z, b)
+					n++
+				}
+			}
+		}
+	}
+	return
+}
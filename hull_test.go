@@ -0,0 +1,58 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestConvexHull(t *testing.T) {
+	s := NewSchematic(6, 6, 6)
+	if n := s.ConvexHull(Block{20, 0}); n != 0 {
+		t.Errorf("ConvexHull of nothing filled %d blocks", n)
+	}
+	for _, p := range [][3]int{{0, 0, 0}, {4, 0, 0}, {0, 0, 4}, {4, 0, 4}} {
+		s.SetBlock(p[0], p[1], p[2], Block{1, 0})
+	}
+	if n := s.ConvexHull(Block{20, 0}); n != 21 {
+		t.Errorf("ConvexHull of four corners: want 21 blocks, got %d", n)
+	}
+
+	// A pyramid over a square base.
+	s = NewSchematic(6, 6, 6)
+	s.Fill(RegionMask(Box{0, 0, 0, 5, 1, 5}), Block{1, 0})
+	s.SetBlock(2, 4, 2, Block{1, 0})
+	n := s.ConvexHull(Block{20, 0})
+	for _, p := range [][3]int{{2, 3, 2}, {1, 2, 1}, {3, 2, 3}, {0, 1, 0}, {2, 1, 2}} {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 20 {
+			t.Errorf("Block %v: want glass, got %v", p, b)
+		}
+	}
+	for _, p := range [][3]int{{0, 2, 0}, {4, 2, 4}, {0, 3, 2}, {5, 0, 5}, {2, 5, 2}} {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 0 {
+			t.Errorf("Block %v: want air, got %v", p, b)
+		}
+	}
+	if got := s.BlockCounts()[20]; got != n {
+		t.Errorf("ConvexHull returned %d, %d blocks of glass", n, got)
+	}
+}
+
+func TestShrinkwrap(t *testing.T) {
+	s := NewSchematic(7, 6, 7)
+	s.Fill(RegionMask(Box{0, 0, 0, 5, 1, 5}), Block{1, 0})
+	s.Fill(RegionMask(Box{3, 1, 3, 4, 3, 4}), Block{1, 0})
+	n := s.Shrinkwrap(Block{20, 0})
+	glass := [][3]int{{3, 3, 3}, {2, 1, 3}, {2, 3, 3}, {3, 2, 4}, {0, 1, 0}, {5, 0, 2}, {5, 1, 2}}
+	for _, p := range glass {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 20 {
+			t.Errorf("Block %v: want glass, got %v", p, b)
+		}
+	}
+	for _, p := range [][3]int{{3, 4, 3}, {1, 2, 1}, {6, 0, 2}, {5, 0, 5}, {2, 4, 3}} {
+		if b := s.Block(p[0], p[1], p[2]); b.Id != 0 {
+			t.Errorf("Block %v: want air, got %v", p, b)
+		}
+	}
+	if got := s.BlockCounts()[20]; got != n {
+		t.Errorf("Shrinkwrap returned %d, %d blocks of glass", n, got)
+	}
+}
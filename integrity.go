@@ -0,0 +1,89 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// IntegrityOptions control Integrity. A nil *IntegrityOptions selects the
+// defaults.
+type IntegrityOptions struct {
+	// MaxSpan is the longest distance from the ground at which a block
+	// holds. Default: 8, a common setting of the physics plugins.
+	MaxSpan int
+}
+
+// An IntegrityReport is the outcome of Integrity. The per-block slices
+// are in the order of Blocks.
+type IntegrityReport struct {
+	// Distance is the length of the shortest path from the block to the
+	// ground through the Solid blocks: 0 for the ground, -1 for the blocks
+	// which are not Solid or are not connected to the ground.
+	Distance []int
+
+	// Risk estimates the chance of the block to fall, from 0 for the
+	// ground and the blocks which are not Solid to 1 for the blocks
+	// farther than MaxSpan from the ground and those not connected to it.
+	Risk []float64
+
+	Unsupported []BlockPos // the blocks farther than MaxSpan from the ground
+	Floating    []BlockPos // the Solid blocks not connected to the ground
+	Overhangs   []BlockPos // the Solid blocks above a block which is not Solid
+}
+
+// Integrity estimates how well the blocks of s hold together, for the
+// servers whose plugins make the blocks without support fall. The ground
+// is the Solid blocks of the bottom layer, and a block is supported
+// through the Solid blocks sharing a face with it, in any direction, so a
+// beam or an arch holds as long as it reaches the ground within MaxSpan
+// blocks. The real plugins differ in their rules; the report is meant to
+// point at the weak parts of a build, not to predict the collapse.
+func (s *Schematic) Integrity(opt *IntegrityOptions) *IntegrityReport {
+	o := IntegrityOptions{MaxSpan: 8}
+	if opt != nil && opt.MaxSpan > 0 {
+		o.MaxSpan = opt.MaxSpan
+	}
+	r := &IntegrityReport{
+		Distance: make([]int, len(s.Blocks)),
+		Risk:     make([]float64, len(s.Blocks)),
+	}
+	solid := make([]bool, len(s.Blocks))
+	var queue []int
+	for i := range s.Blocks {
+		r.Distance[i] = -1
+		solid[i] = Block{s.id(i), s.Data[i]}.Is(Solid)
+		if solid[i] && i < s.Width*s.Length {
+			r.Distance[i] = 0
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		s.neighbors6(i, func(j int) {
+			if solid[j] && r.Distance[j] < 0 {
+				r.Distance[j] = r.Distance[i] + 1
+				queue = append(queue, j)
+			}
+		})
+	}
+	for i, d := range r.Distance {
+		if !solid[i] {
+			continue
+		}
+		x, y, z := s.coords(i)
+		p := BlockPos{x, y, z}
+		switch {
+		case d < 0:
+			r.Risk[i] = 1
+			r.Floating = append(r.Floating, p)
+		case d > o.MaxSpan:
+			r.Risk[i] = 1
+			r.Unsupported = append(r.Unsupported, p)
+		default:
+			r.Risk[i] = float64(d) / float64(o.MaxSpan+1)
+		}
+		if y > 0 && !solid[s.index(x, y-1, z)] {
+			r.Overhangs = append(r.Overhangs, p)
+		}
+	}
+	return r
+}
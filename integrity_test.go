@@ -0,0 +1,61 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestIntegrity(t *testing.T) {
+	// A pillar of 3 blocks with a beam of 4 blocks on its top and a
+	// floating block.
+	s := NewSchematic(5, 4, 1)
+	for y := 0; y < 3; y++ {
+		s.SetBlock(0, y, 0, Block{1, 0})
+	}
+	for x := 1; x < 5; x++ {
+		s.SetBlock(x, 2, 0, Block{1, 0})
+	}
+	s.SetBlock(3, 0, 0, Block{1, 0})
+	s.SetBlock(2, 0, 0, Block{50, 0}) // a torch is not Solid
+	s.SetBlock(4, 3, 0, Block{20, 0})
+	r := s.Integrity(&IntegrityOptions{MaxSpan: 4})
+	for _, tt := range []struct {
+		x, y, dist int
+		risk       float64
+	}{
+		{0, 0, 0, 0},
+		{0, 2, 2, 0.4},
+		{3, 2, 5, 1},
+		{4, 3, 7, 1},
+		{2, 0, -1, 0},
+		{3, 1, -1, 0},
+	} {
+		i := s.index(tt.x, tt.y, 0)
+		if r.Distance[i] != tt.dist || r.Risk[i] != tt.risk {
+			t.Errorf("(%d, %d): want distance %d and risk %v, got %d and %v", tt.x, tt.y, tt.dist, tt.risk, r.Distance[i], r.Risk[i])
+		}
+	}
+	want := []BlockPos{{3, 2, 0}, {4, 2, 0}, {4, 3, 0}}
+	if len(r.Unsupported) != len(want) {
+		t.Fatalf("Unsupported: want %v, got %v", want, r.Unsupported)
+	}
+	for i := range want {
+		if r.Unsupported[i] != want[i] {
+			t.Errorf("Unsupported: want %v, got %v", want, r.Unsupported)
+		}
+	}
+	if len(r.Floating) != 0 {
+		t.Errorf("Floating: want none, got %v", r.Floating)
+	}
+	if len(r.Overhangs) != 4 {
+		t.Errorf("Overhangs: want 4, got %v", r.Overhangs)
+	}
+
+	s.SetBlock(4, 2, 0, Block{})
+	r = s.Integrity(nil)
+	if len(r.Floating) != 1 || r.Floating[0] != (BlockPos{4, 3, 0}) {
+		t.Errorf("Floating: want [(4, 3, 0)], got %v", r.Floating)
+	}
+	if len(r.Unsupported) != 0 {
+		t.Errorf("Unsupported: want none, got %v", r.Unsupported)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+	"rand"
+)
+
+// A Direction is one of the six directions along the axes.
+type Direction int
+
+const (
+	East  Direction = iota // +x
+	South                  // +z
+	West                   // -x
+	North                  // -z
+	Up                     // +y
+	Down                   // -y
+)
+
+var directionNames = []string{"east", "south", "west", "north", "up", "down"}
+
+func (d Direction) String() string {
+	if d < 0 || int(d) >= len(directionNames) {
+		return fmt.Sprintf("Direction(%d)", int(d))
+	}
+	return directionNames[d]
+}
+
+// Opposite returns the direction pointing the other way.
+func (d Direction) Opposite() Direction {
+	switch d {
+	case Up:
+		return Down
+	case Down:
+		return Up
+	}
+	return (d + 2) % 4
+}
+
+// Rotate returns the direction rotated clockwise around the vertical axis,
+// as seen from above, like Schematic.Rotate. Up and Down do not change.
+func (d Direction) Rotate(angle int) Direction {
+	if d == Up || d == Down {
+		return d
+	}
+	return (d + Direction((angle/90%4+4)%4)) % 4
+}
+
+// Step returns the unit vector of the direction.
+func (d Direction) Step() (dx, dy, dz int) {
+	switch d {
+	case East:
+		return 1, 0, 0
+	case South:
+		return 0, 0, 1
+	case West:
+		return -1, 0, 0
+	case North:
+		return 0, 0, -1
+	case Up:
+		return 0, 1, 0
+	}
+	return 0, -1, 0
+}
+
+// A Connector is a block of a piece where another piece may be attached.
+// A connector is joined to a connector of the piece whose Name is the
+// Target of the first one, facing the opposite way, so that the two
+// connector blocks are next to each other.
+type Connector struct {
+	Name, Target string
+	X, Y, Z      int
+	Facing       Direction // pointing out of the piece
+}
+
+// A Piece is a part of an assembled structure, such as a room or a corridor.
+type Piece struct {
+	Name       string
+	Schematic  *Schematic
+	Connectors []Connector
+}
+
+// A Placement is a piece placed by Assemble.
+type Placement struct {
+	Piece *Piece
+
+	// Angle is the clockwise rotation of the piece, as for Schematic.Rotate.
+	Angle int
+
+	// Box is the position of the rotated piece relative to the minimum
+	// corner of the start piece.
+	Box Box
+}
+
+// AssemblyOptions control Assemble. A nil *AssemblyOptions is equivalent
+// to the zero value.
+type AssemblyOptions struct {
+	// MaxPieces limits the number of pieces, including the start piece.
+	// Default: 32.
+	MaxPieces int
+
+	// Seed selects the pieces at random among the fitting ones.
+	Seed int64
+}
+
+// Assemble builds a structure of pieces like the jigsaw blocks of Minecraft.
+// Starting from the start piece, it attaches pieces from the pool to the
+// free connectors, breadth first, rotating them around the vertical axis
+// as needed. A piece is only placed if it does not overlap the placed
+// pieces; connectors without a fitting piece stay free. The WorldEdit
+// offset of the result is the position of its minimum corner relative to
+// the minimum corner of the start piece. Assemble fails if the structure is
+// larger than a schematic file can hold.
+func Assemble(start *Piece, pool []*Piece, opt *AssemblyOptions) (s *Schematic, placed []Placement, err os.Error) {
+	if opt == nil {
+		opt = new(AssemblyOptions)
+	}
+	max := opt.MaxPieces
+	if max == 0 {
+		max = 32
+	}
+	rnd := rand.New(rand.NewSource(opt.Seed))
+	rotated := make(map[*Piece][4]*Schematic)
+	rotate := func(p *Piece, angle int) (*Schematic, os.Error) {
+		r := rotated[p]
+		if r[angle/90] == nil {
+			var err os.Error
+			if r[angle/90], err = p.Schematic.Rotate(angle); err != nil {
+				return nil, err
+			}
+			rotated[p] = r
+		}
+		return r[angle/90], nil
+	}
+
+	// The free connectors, with the positions and the facing of the
+	// placed pieces.
+	var open []Connector
+	place := func(p *Piece, angle, x, y, z int, used int) {
+		t, _ := rotate(p, angle)
+		placed = append(placed, Placement{p, angle, Box{x, y, z, x + t.Width, y + t.Height, z + t.Length}})
+		for i, c := range p.Connectors {
+			if i == used {
+				continue
+			}
+			cx, cy, cz := rotatePoint(c.X, c.Y, c.Z, p.Schematic, angle)
+			c.X, c.Y, c.Z, c.Facing = x+cx, y+cy, z+cz, c.Facing.Rotate(angle)
+			open = append(open, c)
+		}
+	}
+	place(start, 0, 0, 0, 0, -1)
+
+	type candidate struct {
+		p     *Piece
+		conn  int
+		angle int
+	}
+	for len(open) > 0 && len(placed) < max {
+		c := open[0]
+		open = open[1:]
+		var cands []candidate
+		for _, p := range pool {
+			for i, d := range p.Connectors {
+				if d.Name != c.Target {
+					continue
+				}
+				for angle := 0; angle < 360; angle += 90 {
+					if d.Facing.Rotate(angle) == c.Facing.Opposite() {
+						cands = append(cands, candidate{p, i, angle})
+					}
+				}
+			}
+		}
+		dx, dy, dz := c.Facing.Step()
+		for _, i := range rnd.Perm(len(cands)) {
+			cand := cands[i]
+			t, err := rotate(cand.p, cand.angle)
+			if err != nil {
+				return nil, nil, err
+			}
+			d := cand.p.Connectors[cand.conn]
+			px, py, pz := rotatePoint(d.X, d.Y, d.Z, cand.p.Schematic, cand.angle)
+			x, y, z := c.X+dx-px, c.Y+dy-py, c.Z+dz-pz
+			b := Box{x, y, z, x + t.Width, y + t.Height, z + t.Length}
+			if overlaps(b, placed) {
+				continue
+			}
+			place(cand.p, cand.angle, x, y, z, cand.conn)
+			break
+		}
+	}
+
+	all := placed[0].Box
+	for _, pl := range placed {
+		b := pl.Box
+		all = Box{imin(all.MinX, b.MinX), imin(all.MinY, b.MinY), imin(all.MinZ, b.MinZ),
+			imax(all.MaxX, b.MaxX), imax(all.MaxY, b.MaxY), imax(all.MaxZ, b.MaxZ)}
+	}
+	if err = checkDimensions(all.MaxX-all.MinX, all.MaxY-all.MinY, all.MaxZ-all.MinZ); err != nil {
+		return nil, nil, err
+	}
+	s = NewSchematic(all.MaxX-all.MinX, all.MaxY-all.MinY, all.MaxZ-all.MinZ)
+	s.Materials = start.Schematic.Materials
+	s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = all.MinX, all.MinY, all.MinZ
+	for _, pl := range placed {
+		t, _ := rotate(pl.Piece, pl.Angle)
+		s.pasteSolid(t, pl.Box.MinX-all.MinX, pl.Box.MinY-all.MinY, pl.Box.MinZ-all.MinZ)
+	}
+	return
+}
+
+// rotatePoint returns the position of the block (x, y, z) of s in s rotated
+// by the angle.
+func rotatePoint(x, y, z int, s *Schematic, angle int) (int, int, int) {
+	switch (angle%360 + 360) % 360 {
+	case 90:
+		return s.Length - 1 - z, y, x
+	case 180:
+		return s.Width - 1 - x, y, s.Length - 1 - z
+	case 270:
+		return z, y, s.Width - 1 - x
+	}
+	return x, y, z
+}
+
+// overlaps reports whether the box overlaps any of the placed pieces.
+func overlaps(b Box, placed []Placement) bool {
+	for _, pl := range placed {
+		p := pl.Box
+		if b.MinX < p.MaxX && p.MinX < b.MaxX && b.MinY < p.MaxY && p.MinY < b.MaxY && b.MinZ < p.MaxZ && p.MinZ < b.MaxZ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestDirection(t *testing.T) {
+	if East.Rotate(90) != South || North.Rotate(-90) != West || Up.Rotate(90) != Up || West.Rotate(540) != East {
+		t.Errorf("Rotate: wrong directions")
+	}
+	if East.Opposite() != West || South.Opposite() != North || Down.Opposite() != Up {
+		t.Errorf("Opposite: wrong directions")
+	}
+	if North.String() != "north" || Direction(9).String() != "Direction(9)" {
+		t.Errorf("String: got %s, %s", North, Direction(9))
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	// A 3x1x3 room with doors to the east and to the north, and a corridor
+	// of 4 blocks with doors at both ends.
+	room := &Piece{
+		Name:      "room",
+		Schematic: NewSchematic(3, 1, 3),
+		Connectors: []Connector{
+			{Name: "door", Target: "door", X: 2, Y: 0, Z: 1, Facing: East},
+			{Name: "door", Target: "door", X: 1, Y: 0, Z: 0, Facing: North},
+		},
+	}
+	room.Schematic.Fill(IdMask(0), Block{1, 0})
+	corridor := &Piece{
+		Name:      "corridor",
+		Schematic: NewSchematic(4, 1, 1),
+		Connectors: []Connector{
+			{Name: "door", Target: "door", X: 0, Y: 0, Z: 0, Facing: West},
+			{Name: "door", Target: "door", X: 3, Y: 0, Z: 0, Facing: East},
+		},
+	}
+	corridor.Schematic.Fill(IdMask(0), Block{5, 0})
+
+	s, placed, err := Assemble(room, []*Piece{corridor}, &AssemblyOptions{MaxPieces: 5})
+	if err != nil {
+		t.Fatalf("Assemble: %v", err)
+	}
+	if len(placed) != 5 {
+		t.Fatalf("Assemble: want 5 pieces, got %d", len(placed))
+	}
+	// Two corridors go east and two north, turned by 90 degrees.
+	if s.Width != 11 || s.Height != 1 || s.Length != 11 || s.WEOffsetZ != -8 || s.WEOffsetX != 0 {
+		t.Errorf("Assemble: got %dx%dx%d with offset %d,%d,%d", s.Width, s.Height, s.Length, s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ)
+	}
+	if n := len(s.selected(IdMask(5))); n != 16 {
+		t.Errorf("Assemble: want 16 planks, got %d", n)
+	}
+	if s.GetV(10, 0, 9) != 5 || s.GetV(1, 0, 0) != 5 || s.GetV(0, 0, 9) != 1 {
+		t.Errorf("Assemble: wrong blocks")
+	}
+	for _, pl := range placed[1:] {
+		if pl.Piece != corridor || pl.Box.MaxY != 1 {
+			t.Errorf("Placement %v: wrong piece or box", pl)
+		}
+	}
+
+	// The second wing would overlap the first one.
+	room = &Piece{Name: "room", Schematic: room.Schematic, Connectors: []Connector{
+		{Name: "a", Target: "wing", X: 2, Y: 0, Z: 0, Facing: East},
+		{Name: "a", Target: "wing", X: 2, Y: 0, Z: 2, Facing: East},
+	}}
+	wing := &Piece{Name: "wing", Schematic: NewSchematic(2, 1, 3), Connectors: []Connector{
+		{Name: "wing", Target: "a", X: 0, Y: 0, Z: 1, Facing: West},
+	}}
+	_, placed, err = Assemble(room, []*Piece{wing}, nil)
+	if err != nil {
+		t.Fatalf("Assemble: %v", err)
+	}
+	if len(placed) != 2 || placed[1].Box != (Box{3, 0, -1, 5, 1, 2}) {
+		t.Errorf("Assemble with overlapping pieces: got %v", placed)
+	}
+}
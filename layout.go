@@ -0,0 +1,176 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+)
+
+// A Layout is the order of the axes in a flat array of blocks, from the
+// slowest changing to the fastest. Schematic uses YZX; XZY keeps the
+// columns of blocks contiguous, which suits algorithms scanning them, such
+// as height maps and lighting.
+type Layout int
+
+const (
+	LayoutYZX Layout = iota
+	LayoutYXZ
+	LayoutXZY
+	LayoutXYZ
+	LayoutZYX
+	LayoutZXY
+)
+
+// layoutAxes lists the axes of every layout from the slowest to the fastest.
+var layoutAxes = [...][3]Axis{
+	LayoutYZX: {AxisY, AxisZ, AxisX},
+	LayoutYXZ: {AxisY, AxisX, AxisZ},
+	LayoutXZY: {AxisX, AxisZ, AxisY},
+	LayoutXYZ: {AxisX, AxisY, AxisZ},
+	LayoutZYX: {AxisZ, AxisY, AxisX},
+	LayoutZXY: {AxisZ, AxisX, AxisY},
+}
+
+func (l Layout) String() string {
+	if l < 0 || int(l) >= len(layoutAxes) {
+		return fmt.Sprintf("Layout(%d)", int(l))
+	}
+	var name []byte
+	for _, a := range layoutAxes[l] {
+		name = append(name, "XYZ"[a])
+	}
+	return string(name)
+}
+
+// A BlockArray stores the blocks of a box in the order of its Layout. It
+// is a Volume, so the code written for Volume works with any layout, and
+// code scanning the arrays directly can use Index and Strides.
+type BlockArray struct {
+	Layout                Layout
+	Width, Height, Length int
+	Ids                   []uint16
+	Data                  []byte
+}
+
+// NewBlockArray returns a block array of the layout and size filled with
+// air. It panics if the layout is unknown.
+func NewBlockArray(l Layout, width, height, length int) *BlockArray {
+	if l < 0 || int(l) >= len(layoutAxes) {
+		panic(fmt.Sprintf("schematic: unknown layout %v", l))
+	}
+	n := width * height * length
+	return &BlockArray{l, width, height, length, make([]uint16, n), make([]byte, n)}
+}
+
+// XLen is the number of blocks by X axis.
+func (a *BlockArray) XLen() int {
+	return a.Width
+}
+
+// YLen is the number of blocks by Y axis.
+func (a *BlockArray) YLen() int {
+	return a.Height
+}
+
+// ZLen is the number of blocks by Z axis.
+func (a *BlockArray) ZLen() int {
+	return a.Length
+}
+
+// Strides returns the distances in Ids and Data between the neighbors
+// along every axis.
+func (a *BlockArray) Strides() (sx, sy, sz int) {
+	size := [3]int{a.Width, a.Height, a.Length}
+	var stride [3]int
+	n := 1
+	axes := layoutAxes[a.Layout]
+	for i := 2; i >= 0; i-- {
+		stride[axes[i]] = n
+		n *= size[axes[i]]
+	}
+	return stride[AxisX], stride[AxisY], stride[AxisZ]
+}
+
+// Inside reports whether the position is inside of the array.
+func (a *BlockArray) Inside(x, y, z int) bool {
+	return x >= 0 && y >= 0 && z >= 0 && x < a.Width && y < a.Height && z < a.Length
+}
+
+// Index returns the position of the block (x, y, z) in Ids and Data. It
+// panics if the position is outside of the array.
+func (a *BlockArray) Index(x, y, z int) int {
+	if !a.Inside(x, y, z) {
+		panic(fmt.Sprintf("schematic: BlockArray.Index(%d, %d, %d) out of range", x, y, z))
+	}
+	sx, sy, sz := a.Strides()
+	return x*sx + y*sy + z*sz
+}
+
+// Coords returns the position of the block at the index i of Ids and Data.
+// It panics if i is out of range.
+func (a *BlockArray) Coords(i int) (x, y, z int) {
+	if i < 0 || i >= len(a.Ids) {
+		panic(fmt.Sprintf("schematic: BlockArray.Coords(%d) out of range", i))
+	}
+	sx, sy, sz := a.Strides()
+	size := [3]int{a.Width, a.Height, a.Length}
+	stride := [3]int{sx, sy, sz}
+	var pos [3]int
+	for _, ax := range layoutAxes[a.Layout] {
+		pos[ax] = i / stride[ax] % size[ax]
+	}
+	return pos[AxisX], pos[AxisY], pos[AxisZ]
+}
+
+// Block returns the block at the specified position.
+// The blocks outside of the array are air.
+func (a *BlockArray) Block(x, y, z int) Block {
+	if !a.Inside(x, y, z) {
+		return Block{}
+	}
+	i := a.Index(x, y, z)
+	return Block{a.Ids[i], a.Data[i]}
+}
+
+// SetBlock sets the block at the specified position.
+// It panics if the position is outside of the array.
+func (a *BlockArray) SetBlock(x, y, z int, b Block) {
+	i := a.Index(x, y, z)
+	a.Ids[i], a.Data[i] = b.Id, b.Data
+}
+
+// Convert returns a copy of a in the layout.
+func (a *BlockArray) Convert(l Layout) *BlockArray {
+	c := NewBlockArray(l, a.Width, a.Height, a.Length)
+	a.copyTo(c)
+	return c
+}
+
+// copyTo copies the blocks of a to the volume v of the same size.
+func (a *BlockArray) copyTo(v Volume) {
+	for y := 0; y < a.Height; y++ {
+		for z := 0; z < a.Length; z++ {
+			for x := 0; x < a.Width; x++ {
+				v.SetBlock(x, y, z, a.Block(x, y, z))
+			}
+		}
+	}
+}
+
+// BlockArray returns a copy of the blocks of s in the layout.
+func (s *Schematic) BlockArray(l Layout) *BlockArray {
+	a := NewBlockArray(l, s.Width, s.Height, s.Length)
+	for i := range s.Blocks {
+		x, y, z := s.coords(i)
+		a.SetBlock(x, y, z, Block{s.id(i), s.Data[i]})
+	}
+	return a
+}
+
+// Schematic returns a schematic of the blocks of a.
+func (a *BlockArray) Schematic() *Schematic {
+	s := NewSchematic(a.Width, a.Height, a.Length)
+	a.copyTo(s)
+	return s
+}
@@ -0,0 +1,40 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestBlockArray(t *testing.T) {
+	s := NewSchematic(3, 4, 5)
+	for i := range s.Blocks {
+		s.Blocks[i], s.Data[i] = byte(i), byte(i%16)
+	}
+	s.SetBlock(1, 2, 3, Block{300, 2})
+	ref := s.BlockArray(LayoutYZX)
+	for i := range s.Blocks {
+		if x, y, z := s.Coords(i); ref.Index(x, y, z) != i {
+			t.Fatalf("YZX is not the layout of Schematic at %d", i)
+		}
+	}
+	for l := LayoutYZX; l <= LayoutZXY; l++ {
+		a := ref.Convert(l)
+		for i := range a.Ids {
+			if x, y, z := a.Coords(i); a.Index(x, y, z) != i {
+				t.Errorf("%v: Index(Coords(%d)) = %d", l, i, a.Index(x, y, z))
+			}
+		}
+		sx, sy, sz := a.Strides()
+		if i := a.Index(1, 2, 3); i != sx+2*sy+3*sz || a.Ids[i] != 300 || a.Data[i] != 2 {
+			t.Errorf("%v: block (1, 2, 3) at %d is %d:%d", l, i, a.Ids[i], a.Data[i])
+		}
+		if a.Convert(LayoutYZX).Schematic().Fingerprint() != s.Fingerprint() {
+			t.Errorf("%v: the blocks changed in a round trip", l)
+		}
+	}
+	if a := ref.Convert(LayoutXZY); a.Index(0, 1, 0) != 1 {
+		t.Errorf("XZY: the columns are not contiguous")
+	}
+	if LayoutZYX.String() != "ZYX" || Layout(9).String() != "Layout(9)" {
+		t.Errorf("String: got %s and %s", LayoutZYX, Layout(9))
+	}
+}
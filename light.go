@@ -0,0 +1,64 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// neighbors6 calls f with the indexes of the blocks sharing a face with
+// the block i inside of s.
+func (s *Schematic) neighbors6(i int, f func(j int)) {
+	x, y, z := s.coords(i)
+	for _, n := range Neighbors6(x, y, z) {
+		if s.Inside(n.X, n.Y, n.Z) {
+			f(s.index(n.X, n.Y, n.Z))
+		}
+	}
+}
+
+// Light returns the light levels of the blocks of s, from 0 to 15, in the
+// order of Blocks. The light of the blocks described by Info spreads
+// through the Transparent blocks, losing a level with every block. With
+// sky, the columns are lit from above by the daylight, which goes down
+// through the Transparent blocks other than liquids and leaves without
+// loss and spreads from them like the light of blocks. The world outside
+// of the schematic is dark, and the blocks which are not Transparent are
+// dark unless they emit light.
+func (s *Schematic) Light(sky bool) []byte {
+	light := make([]byte, len(s.Blocks))
+	var queue [16][]int // the blocks to spread the light from by level
+	cat := make([]Category, len(s.Blocks))
+	for i := range s.Blocks {
+		info, _ := Info(Block{s.id(i), s.Data[i]})
+		cat[i] = info.Category
+		if info.Light > 0 {
+			light[i] = byte(info.Light)
+			queue[info.Light] = append(queue[info.Light], i)
+		}
+	}
+	if sky {
+		layer := s.Width * s.Length
+		for c := 0; c < layer; c++ {
+			for i := c + (s.Height-1)*layer; i >= 0; i -= layer {
+				if cat[i]&Transparent == 0 || cat[i]&(Liquid|Vegetation) != 0 {
+					break
+				}
+				light[i] = 15
+				queue[15] = append(queue[15], i)
+			}
+		}
+	}
+	for level := 15; level > 1; level-- {
+		for _, i := range queue[level] {
+			if int(light[i]) != level {
+				continue
+			}
+			s.neighbors6(i, func(j int) {
+				if cat[j]&Transparent != 0 && int(light[j]) < level-1 {
+					light[j] = byte(level - 1)
+					queue[level-1] = append(queue[level-1], j)
+				}
+			})
+		}
+		queue[level] = nil
+	}
+	return light
+}
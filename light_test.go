@@ -0,0 +1,38 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestLight(t *testing.T) {
+	// A torch in a corridor closed by stone.
+	s := NewSchematic(6, 1, 1)
+	s.SetBlock(0, 0, 0, Block{50, 5})
+	s.SetBlock(4, 0, 0, Block{1, 0})
+	light := s.Light(false)
+	want := []byte{14, 13, 12, 11, 0, 0}
+	for x, l := range want {
+		if light[s.index(x, 0, 0)] != l {
+			t.Errorf("Light at %d: want %d, got %d", x, l, light[s.index(x, 0, 0)])
+		}
+	}
+
+	// Daylight goes down through glass without loss, stops at leaves, which
+	// it enters from the side, and spreads under the roof of stone.
+	s = NewSchematic(3, 3, 1)
+	s.SetBlock(0, 2, 0, Block{20, 0})
+	s.SetBlock(1, 2, 0, Block{18, 0})
+	s.SetBlock(2, 2, 0, Block{1, 0})
+	light = s.Light(true)
+	for _, tt := range []struct {
+		x, y int
+		l    byte
+	}{{0, 2, 15}, {0, 0, 15}, {1, 2, 14}, {1, 1, 14}, {2, 1, 13}, {2, 0, 13}} {
+		if l := light[s.index(tt.x, tt.y, 0)]; l != tt.l {
+			t.Errorf("Daylight at (%d, %d): want %d, got %d", tt.x, tt.y, tt.l, l)
+		}
+	}
+	if light = s.Light(false); light[s.index(0, 0, 0)] != 0 {
+		t.Errorf("Light without the sky: want dark, got %v", light)
+	}
+}
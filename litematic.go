@@ -0,0 +1,582 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// LitematicExt is the file name extension of Litematica schematics.
+const LitematicExt = ".litematic"
+
+// LitematicMetadata is the description of a Litematica schematic stored
+// in its Metadata tag.
+type LitematicMetadata struct {
+	Name         string
+	Author       string
+	Description  string
+	RegionCount  int
+	TotalBlocks  int    // non-air blocks
+	TotalVolume  int    // the sum of the region volumes
+	Size         [3]int // the size of the box enclosing all regions (EnclosingSize)
+	TimeCreated  int64  // milliseconds since the epoch
+	TimeModified int64  // milliseconds since the epoch
+
+	// Preview is the thumbnail taken by Litematica, or nil if there is none.
+	Preview image.Image
+}
+
+// readRoot reads the root compound of a compressed or raw NBT stream.
+func readRoot(r io.Reader) (name string, c *nbt.Compound, err os.Error) {
+	var rd io.Reader
+	if rd, err = decompressor(r); err != nil {
+		return
+	}
+	var tag nbt.Tag
+	if name, tag, err = nbt.NewReader(rd).ReadTag(); err != nil {
+		return
+	}
+	var ok bool
+	if c, ok = tag.(*nbt.Compound); !ok {
+		return "", nil, os.NewError("Top level tag must be compound")
+	}
+	return
+}
+
+// readLitematic reads a Litematica file and checks its version.
+func readLitematic(r io.Reader) (root *nbt.Compound, err os.Error) {
+	if _, root, err = readRoot(r); err != nil {
+		return
+	}
+	if _, ok := root.Get("Regions").(*nbt.Compound); !ok {
+		return nil, os.NewError("Not a Litematica schematic: Regions tag is missing")
+	}
+	if v := intField(root, "Version"); v < 1 || v > 7 {
+		return nil, fmt.Errorf("Unsupported Litematica version: %d", v)
+	}
+	return
+}
+
+// ReadLitematicMetadata reads the metadata of a Litematica schematic.
+func ReadLitematicMetadata(r io.Reader) (md *LitematicMetadata, err os.Error) {
+	var root *nbt.Compound
+	if root, err = readLitematic(r); err != nil {
+		return
+	}
+	return newLitematicMetadata(root)
+}
+
+func newLitematicMetadata(root *nbt.Compound) (md *LitematicMetadata, err os.Error) {
+	c, ok := root.Get("Metadata").(*nbt.Compound)
+	if !ok {
+		return nil, os.NewError("Metadata tag is missing")
+	}
+	str := func(name string) string {
+		s, _ := c.Get(name).(nbt.String)
+		return string(s)
+	}
+	md = &LitematicMetadata{
+		Name:         str("Name"),
+		Author:       str("Author"),
+		Description:  str("Description"),
+		RegionCount:  intField(c, "RegionCount"),
+		TotalBlocks:  intField(c, "TotalBlocks"),
+		TotalVolume:  intField(c, "TotalVolume"),
+		TimeCreated:  int64Field(c, "TimeCreated"),
+		TimeModified: int64Field(c, "TimeModified"),
+	}
+	if size, ok := c.Get("EnclosingSize").(*nbt.Compound); ok {
+		md.Size = [3]int{intField(size, "x"), intField(size, "y"), intField(size, "z")}
+	}
+	if data, ok := c.Get("PreviewImageData").(nbt.IntArray); ok && len(data) > 0 {
+		if md.Preview, err = decodePreview(data); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// int64Field is like intField for 64-bit values.
+func int64Field(c *nbt.Compound, name string) int64 {
+	if v, ok := c.Get(name).(nbt.Long); ok {
+		return int64(v)
+	}
+	return int64(intField(c, name))
+}
+
+// decodePreview converts the square image of ARGB pixels stored by Litematica.
+func decodePreview(data nbt.IntArray) (image.Image, os.Error) {
+	side := 0
+	for side*side < len(data) {
+		side++
+	}
+	if side*side != len(data) {
+		return nil, fmt.Errorf("Preview image is not square: %d pixels", len(data))
+	}
+	m := image.NewNRGBA(side, side)
+	for i, v := range data {
+		p := uint32(v)
+		m.Set(i%side, i/side, image.NRGBAColor{uint8(p >> 16), uint8(p >> 8), uint8(p), uint8(p >> 24)})
+	}
+	return m, nil
+}
+
+// LitematicPreviewSize is the side of the preview images taken by
+// Litematica. Larger previews are scaled down to it on write.
+const LitematicPreviewSize = 140
+
+// encodePreview converts m to the square image of ARGB pixels stored by
+// Litematica. Images which are not square are centered on a transparent
+// background.
+func encodePreview(m image.Image) nbt.IntArray {
+	b := m.Bounds()
+	side := imin(imax(b.Dx(), b.Dy()), LitematicPreviewSize)
+	sq := fitSquare(m, side)
+	data := make(nbt.IntArray, side*side)
+	for i := range data {
+		c := sq.At(i%side, i/side).(image.NRGBAColor)
+		data[i] = int32(uint32(c.A)<<24 | uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B))
+	}
+	return data
+}
+
+// LitematicDataVersion is the MinecraftDataVersion of the schematics
+// created by SplitLitematic.
+const LitematicDataVersion = DataVersion1_13
+
+// A Litematic is a Litematica schematic: a set of named regions placed
+// relative to a common origin.
+type Litematic struct {
+	Metadata    LitematicMetadata
+	DataVersion int // MinecraftDataVersion
+	Regions     []*LitematicRegion
+}
+
+// A LitematicRegion is a named box of block states. Tile entities and
+// pending block ticks of the regions are not preserved.
+type LitematicRegion struct {
+	Name string
+
+	// X, Y and Z are the position of the minimum corner of the region
+	// relative to the origin of the schematic.
+	X, Y, Z int
+
+	StateVolume
+}
+
+// ReadLitematic reads a Litematica schematic with all regions and translates
+// the block states to LatestDataVersion.
+func ReadLitematic(r io.Reader) (l *Litematic, err os.Error) {
+	if l, err = readLitematicRegions(r); err != nil {
+		return
+	}
+	if err = l.Translate(LatestDataVersion); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// readLitematicRegions reads a Litematica schematic without translating
+// the block states.
+func readLitematicRegions(r io.Reader) (l *Litematic, err os.Error) {
+	var root *nbt.Compound
+	if root, err = readLitematic(r); err != nil {
+		return
+	}
+	var md *LitematicMetadata
+	if md, err = newLitematicMetadata(root); err != nil {
+		return
+	}
+	l = &Litematic{Metadata: *md, DataVersion: intField(root, "MinecraftDataVersion")}
+	if l.DataVersion == 0 {
+		l.DataVersion = DataVersion1_13
+	}
+	for _, f := range root.Get("Regions").(*nbt.Compound).Fields {
+		c, ok := f.Tag.(*nbt.Compound)
+		if !ok {
+			return nil, fmt.Errorf("Region %s must be a compound", f.Name)
+		}
+		var reg *LitematicRegion
+		if reg, err = newLitematicRegion(f.Name, l.DataVersion, c); err != nil {
+			return nil, fmt.Errorf("Region %s: %v", f.Name, err)
+		}
+		reg.movePaletteAir()
+		l.Regions = append(l.Regions, reg)
+	}
+	return
+}
+
+// vecField returns the x, y and z fields of a compound.
+func vecField(c *nbt.Compound, name string) (x, y, z int, ok bool) {
+	v, ok := c.Get(name).(*nbt.Compound)
+	if !ok {
+		return
+	}
+	return intField(v, "x"), intField(v, "y"), intField(v, "z"), true
+}
+
+// minCorner returns the minimum coordinate and the length of a region side
+// starting at pos. Negative sizes extend the region towards negative coordinates.
+func minCorner(pos, size int) (int, int) {
+	if size < 0 {
+		return pos + size + 1, -size
+	}
+	return pos, size
+}
+
+// volume returns w*h*l. ok is false if a dimension is negative or the
+// volume is above max, which the readers derive from the length of the
+// block data, so that a corrupted size cannot make them allocate more.
+func volume(w, h, l int, max int64) (n int, ok bool) {
+	v := int64(1)
+	for _, d := range []int{w, h, l} {
+		if d < 0 || d > 0 && v > max/int64(d) {
+			return 0, false
+		}
+		v *= int64(d)
+	}
+	return int(v), true
+}
+
+func newLitematicRegion(name string, version int, c *nbt.Compound) (reg *LitematicRegion, err os.Error) {
+	px, py, pz, ok := vecField(c, "Position")
+	if !ok {
+		return nil, os.NewError("Position tag is missing")
+	}
+	sx, sy, sz, ok := vecField(c, "Size")
+	if !ok {
+		return nil, os.NewError("Size tag is missing")
+	}
+	reg = &LitematicRegion{Name: name}
+	reg.DataVersion = version
+	reg.X, reg.Width = minCorner(px, sx)
+	reg.Y, reg.Height = minCorner(py, sy)
+	reg.Z, reg.Length = minCorner(pz, sz)
+
+	palette, ok := c.Get("BlockStatePalette").(*nbt.List)
+	if !ok || palette.Len() == 0 {
+		return nil, os.NewError("BlockStatePalette tag is missing")
+	}
+	for _, tag := range palette.Tags {
+		st, ok := tag.(*nbt.Compound)
+		if !ok {
+			return nil, os.NewError("BlockStatePalette must be a list of compounds")
+		}
+		reg.Palette = append(reg.Palette, formatState(st))
+	}
+	packed, ok := c.Get("BlockStates").(nbt.LongArray)
+	if !ok {
+		return nil, os.NewError("BlockStates tag is missing")
+	}
+	n, ok := volume(reg.Width, reg.Height, reg.Length, int64(len(packed))*64)
+	if !ok {
+		return nil, fmt.Errorf("BlockStates size mismatch: %dx%dx%d blocks in %d longs", reg.Width, reg.Height, reg.Length, len(packed))
+	}
+	if reg.States, err = unpackStates(packed, n, len(reg.Palette)); err != nil {
+		return nil, err
+	}
+
+	// The entity positions are relative to Position, which is the maximum
+	// corner along the axes of negative size.
+	if list, ok := c.Get("Entities").(*nbt.List); ok {
+		for _, tag := range list.Tags {
+			if e, ok := tag.(*nbt.Compound); ok {
+				id, _ := e.Get("id").(nbt.String)
+				reg.Entities = append(reg.Entities, shiftEntity(Entity{Id: string(id), NBT: e}, px-reg.X, py-reg.Y, pz-reg.Z))
+			}
+		}
+	}
+	return
+}
+
+// shiftEntity returns e with the position and the tile position, if any,
+// moved by (dx, dy, dz).
+func shiftEntity(e Entity, dx, dy, dz int) Entity {
+	if dx == 0 && dy == 0 && dz == 0 || e.NBT == nil {
+		return e
+	}
+	c := nbt.Clone(e.NBT).(*nbt.Compound)
+	if pos, ok := c.Get("Pos").(*nbt.List); ok && pos.Len() == 3 && pos.ElemType == nbt.TagDouble {
+		c.Set("Pos", nbt.NewList(nbt.TagDouble,
+			pos.Tags[0].(nbt.Double)+nbt.Double(dx),
+			pos.Tags[1].(nbt.Double)+nbt.Double(dy),
+			pos.Tags[2].(nbt.Double)+nbt.Double(dz)))
+	}
+	if _, ok := c.Get("TileX").(nbt.Int); ok {
+		c.Set("TileX", nbt.Int(intField(c, "TileX")+dx))
+		c.Set("TileY", nbt.Int(intField(c, "TileY")+dy))
+		c.Set("TileZ", nbt.Int(intField(c, "TileZ")+dz))
+	}
+	return Entity{Id: e.Id, NBT: c}
+}
+
+// formatState returns the string form of a palette entry:
+// the Name followed by the Properties in brackets, sorted by name as in
+// the block states written by Minecraft, so that the same state read from
+// different tools is one palette entry.
+func formatState(c *nbt.Compound) string {
+	name, _ := c.Get("Name").(nbt.String)
+	props, ok := c.Get("Properties").(*nbt.Compound)
+	if !ok || props.Len() == 0 {
+		return string(name)
+	}
+	var parts []string
+	for _, f := range props.Fields {
+		v, _ := f.Tag.(nbt.String)
+		parts = append(parts, f.Name+"="+string(v))
+	}
+	sort.Strings(parts)
+	return string(name) + "[" + strings.Join(parts, ",") + "]"
+}
+
+// parseState is the reverse of formatState.
+func parseState(state string) *nbt.Compound {
+	c := new(nbt.Compound)
+	i := strings.Index(state, "[")
+	if i < 0 || !strings.HasSuffix(state, "]") {
+		return c.Set("Name", nbt.String(state))
+	}
+	c.Set("Name", nbt.String(state[:i]))
+	props := new(nbt.Compound)
+	for _, p := range strings.Split(state[i+1:len(state)-1], ",") {
+		if j := strings.Index(p, "="); j >= 0 {
+			props.Set(p[:j], nbt.String(p[j+1:]))
+		}
+	}
+	return c.Set("Properties", props)
+}
+
+// stateBits returns the number of bits used to store a palette index.
+func stateBits(paletteLen int) uint {
+	bits := uint(2)
+	for 1<<bits < paletteLen {
+		bits++
+	}
+	return bits
+}
+
+// unpackStates decodes n palette indexes packed into the bits of longs.
+// An index may span two longs.
+func unpackStates(packed nbt.LongArray, n, paletteLen int) ([]int, os.Error) {
+	bits := stateBits(paletteLen)
+	if want := (n*int(bits) + 63) / 64; len(packed) < want {
+		return nil, fmt.Errorf("BlockStates size mismatch: want %d, got %d", want, len(packed))
+	}
+	mask := uint64(1)<<bits - 1
+	states := make([]int, n)
+	for i := range states {
+		start := uint(i) * bits
+		word, off := start/64, start%64
+		v := uint64(packed[word]) >> off
+		if off+bits > 64 {
+			v |= uint64(packed[word+1]) << (64 - off)
+		}
+		if states[i] = int(v & mask); states[i] >= paletteLen {
+			return nil, fmt.Errorf("Palette index out of range: %d", states[i])
+		}
+	}
+	return states, nil
+}
+
+// packStates is the reverse of unpackStates.
+func packStates(states []int, paletteLen int) nbt.LongArray {
+	bits := stateBits(paletteLen)
+	packed := make(nbt.LongArray, (len(states)*int(bits)+63)/64)
+	for i, s := range states {
+		start := uint(i) * bits
+		word, off := start/64, start%64
+		packed[word] |= int64(uint64(s) << off)
+		if off+bits > 64 {
+			packed[word+1] |= int64(uint64(s) >> (64 - off))
+		}
+	}
+	return packed
+}
+
+// Bounds returns the box enclosing all regions, relative to the origin.
+func (l *Litematic) Bounds() (b Box) {
+	for i, r := range l.Regions {
+		rb := Box{r.X, r.Y, r.Z, r.X + r.Width, r.Y + r.Height, r.Z + r.Length}
+		if i == 0 {
+			b = rb
+			continue
+		}
+		b = Box{imin(b.MinX, rb.MinX), imin(b.MinY, rb.MinY), imin(b.MinZ, rb.MinZ),
+			imax(b.MaxX, rb.MaxX), imax(b.MaxY, rb.MaxY), imax(b.MaxZ, rb.MaxZ)}
+	}
+	return
+}
+
+// Translate renames the block states of all regions to the data version.
+// See StateVolume.Translate.
+func (l *Litematic) Translate(version int) os.Error {
+	for _, r := range l.Regions {
+		v := r.StateVolume
+		if err := v.Translate(version); err != nil {
+			return fmt.Errorf("Region %s: %v", r.Name, err)
+		}
+	}
+	for _, r := range l.Regions {
+		r.Translate(version)
+	}
+	l.DataVersion = version
+	return nil
+}
+
+// Schematic flattens the regions into one schematic covering Bounds.
+// Later regions overwrite the blocks of earlier ones, but air does not
+// overwrite anything. The block states are converted with
+// StateVolume.Schematic, so their properties are lost. The WorldEdit
+// offset is set to the position of the schematic relative to the
+// Litematica origin, and the name, author, description and creation time
+// are stored as the Metadata of the schematic.
+func (l *Litematic) Schematic() (s *Schematic, err os.Error) {
+	return l.Legacy(FallbackError)
+}
+
+// Legacy is like Schematic, but the states without a legacy block are
+// handled according to fallback. See StateVolume.Legacy.
+func (l *Litematic) Legacy(fallback Fallback) (s *Schematic, err os.Error) {
+	b := l.Bounds()
+	s = NewSchematic(b.MaxX-b.MinX, b.MaxY-b.MinY, b.MaxZ-b.MinZ)
+	s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = b.MinX, b.MinY, b.MinZ
+	md := &l.Metadata
+	s.SetMetadata(Metadata{Name: md.Name, Author: md.Author, Description: md.Description, Date: md.TimeCreated})
+	for _, r := range l.Regions {
+		var part *Schematic
+		if part, err = r.StateVolume.Legacy(fallback); err != nil {
+			return nil, fmt.Errorf("Region %s: %v", r.Name, err)
+		}
+		s.pasteSolid(part, r.X-b.MinX, r.Y-b.MinY, r.Z-b.MinZ)
+	}
+	return
+}
+
+// pasteSolid copies the non-air blocks, the entities and the tile ticks
+// of src into s at the offset. Those outside of s are dropped.
+func (s *Schematic) pasteSolid(src *Schematic, dx, dy, dz int) {
+	s.Paste(src, dx, dy, dz, &PasteOptions{SkipAir: true, KeepVoid: true})
+}
+
+// A RegionBox is a named part of a schematic, used by SplitLitematic.
+type RegionBox struct {
+	Name string
+	Box  Box
+}
+
+// SplitLitematic converts s to a Litematica schematic with a region for
+// every box. If there are no boxes, the whole schematic is a single region
+// named "Main". The blocks are converted with LegacyState. The name,
+// author, description and date are taken from the Metadata of s; its tags
+// are lost.
+func SplitLitematic(s *Schematic, boxes []RegionBox) (l *Litematic, err os.Error) {
+	if len(boxes) == 0 {
+		boxes = []RegionBox{{"Main", Box{0, 0, 0, s.Width, s.Height, s.Length}}}
+	}
+	l = &Litematic{DataVersion: LitematicDataVersion}
+	md := s.Metadata()
+	l.Metadata.Name, l.Metadata.Author, l.Metadata.Description = md.Name, md.Author, md.Description
+	l.Metadata.TimeCreated, l.Metadata.TimeModified = md.Date, md.Date
+	for _, rb := range boxes {
+		var part *Schematic
+		if part, err = s.Crop(rb.Box); err != nil {
+			return nil, fmt.Errorf("Region %s: %v", rb.Name, err)
+		}
+		var v *StateVolume
+		if v, err = NewStateVolume(part); err != nil {
+			return nil, fmt.Errorf("Region %s: %v", rb.Name, err)
+		}
+		r := &LitematicRegion{Name: rb.Name, X: rb.Box.MinX, Y: rb.Box.MinY, Z: rb.Box.MinZ, StateVolume: *v}
+		for _, n := range r.States {
+			if n != 0 {
+				l.Metadata.TotalBlocks++
+			}
+		}
+		l.Metadata.TotalVolume += len(r.States)
+		l.Regions = append(l.Regions, r)
+	}
+	b := l.Bounds()
+	l.Metadata.Size = [3]int{b.MaxX - b.MinX, b.MaxY - b.MinY, b.MaxZ - b.MinZ}
+	l.Metadata.RegionCount = len(l.Regions)
+	return
+}
+
+// WriteLitematic writes l in the Litematica format version 4.
+func WriteLitematic(w io.Writer, l *Litematic) os.Error {
+	return WriteLitematicOptions(w, l, nil)
+}
+
+// WriteLitematicOptions is like WriteLitematic but allows to choose the
+// compression and to generate the preview image.
+func WriteLitematicOptions(w io.Writer, l *Litematic, opt *WriteOptions) (err os.Error) {
+	md := &l.Metadata
+	preview := md.Preview
+	if preview == nil && opt != nil && opt.Preview {
+		var s *Schematic
+		if s, err = l.Legacy(FallbackClosest); err != nil {
+			return
+		}
+		if preview, err = Thumbnail(s, LitematicPreviewSize); err != nil {
+			return
+		}
+	}
+	vec := func(x, y, z int) *nbt.Compound {
+		return nbt.NewCompound().Set("x", nbt.Int(x)).Set("y", nbt.Int(y)).Set("z", nbt.Int(z))
+	}
+	meta := nbt.NewCompound().
+		Set("Name", nbt.String(md.Name)).
+		Set("Author", nbt.String(md.Author)).
+		Set("Description", nbt.String(md.Description)).
+		Set("RegionCount", nbt.Int(len(l.Regions))).
+		Set("TotalBlocks", nbt.Int(md.TotalBlocks)).
+		Set("TotalVolume", nbt.Int(md.TotalVolume)).
+		Set("EnclosingSize", vec(md.Size[0], md.Size[1], md.Size[2])).
+		Set("TimeCreated", nbt.Long(md.TimeCreated)).
+		Set("TimeModified", nbt.Long(md.TimeModified))
+	if preview != nil {
+		meta.Set("PreviewImageData", encodePreview(preview))
+	}
+	regions := new(nbt.Compound)
+	version := l.DataVersion
+	if version == 0 {
+		version = LitematicDataVersion
+	}
+	for _, r := range l.Regions {
+		if r.DataVersion != version {
+			return fmt.Errorf("Region %s: data version %d differs from %d of the schematic", r.Name, r.DataVersion, version)
+		}
+		if len(r.States) != r.Width*r.Height*r.Length {
+			return fmt.Errorf("Region %s: States size mismatch: want %d, got %d", r.Name, r.Width*r.Height*r.Length, len(r.States))
+		}
+		palette := &nbt.List{ElemType: nbt.TagCompound}
+		for _, state := range r.Palette {
+			palette.Tags = append(palette.Tags, parseState(state))
+		}
+		entities := &nbt.List{ElemType: nbt.TagCompound}
+		for _, e := range r.Entities {
+			entities.Tags = append(entities.Tags, e.compound())
+		}
+		regions.Set(r.Name, nbt.NewCompound().
+			Set("Position", vec(r.X, r.Y, r.Z)).
+			Set("Size", vec(r.Width, r.Height, r.Length)).
+			Set("BlockStatePalette", palette).
+			Set("BlockStates", packStates(r.States, len(r.Palette))).
+			Set("Entities", entities).
+			Set("TileEntities", &nbt.List{ElemType: nbt.TagCompound}).
+			Set("PendingBlockTicks", &nbt.List{ElemType: nbt.TagCompound}))
+	}
+	root := nbt.NewCompound().
+		Set("MinecraftDataVersion", nbt.Int(version)).
+		Set("Version", nbt.Int(4)).
+		Set("Metadata", meta).
+		Set("Regions", regions)
+	return writeRoot(w, "", root, opt)
+}
@@ -0,0 +1,260 @@
+package schematic
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// litematicBytes returns an uncompressed Litematica file with the root compound.
+func litematicBytes(t *testing.T, root *nbt.Compound) *bytes.Buffer {
+	var buf bytes.Buffer
+	w := nbt.NewWriter(&buf)
+	if err := w.WriteTag("", root); err != nil {
+		t.Fatalf("WriteTag: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	return &buf
+}
+
+func TestReadLitematicMetadata(t *testing.T) {
+	md := nbt.NewCompound().
+		Set("Name", nbt.String("Tower")).
+		Set("Author", nbt.String("builder")).
+		Set("Description", nbt.String("A tall tower")).
+		Set("RegionCount", nbt.Int(2)).
+		Set("TotalBlocks", nbt.Int(120)).
+		Set("TotalVolume", nbt.Int(1000)).
+		Set("TimeCreated", nbt.Long(1310000000000)).
+		Set("TimeModified", nbt.Long(1310000001000)).
+		Set("EnclosingSize", nbt.NewCompound().Set("x", nbt.Int(10)).Set("y", nbt.Int(20)).Set("z", nbt.Int(5))).
+		Set("PreviewImageData", nbt.IntArray{-16711936, 0x7f0000ff, 0, -1})
+	root := nbt.NewCompound().Set("Version", nbt.Int(5)).Set("Metadata", md).Set("Regions", nbt.NewCompound())
+
+	got, err := ReadLitematicMetadata(litematicBytes(t, root))
+	if err != nil {
+		t.Fatalf("ReadLitematicMetadata: %v", err)
+	}
+	if got.Name != "Tower" || got.Author != "builder" || got.Description != "A tall tower" {
+		t.Errorf("Strings: got %q, %q, %q", got.Name, got.Author, got.Description)
+	}
+	if got.RegionCount != 2 || got.TotalBlocks != 120 || got.TotalVolume != 1000 || got.Size != [3]int{10, 20, 5} {
+		t.Errorf("Counts: got %+v", got)
+	}
+	if got.TimeCreated != 1310000000000 || got.TimeModified != 1310000001000 {
+		t.Errorf("Times: got %d, %d", got.TimeCreated, got.TimeModified)
+	}
+	if got.Preview == nil || got.Preview.Bounds().Dx() != 2 {
+		t.Fatalf("Preview: want a 2x2 image, got %v", got.Preview)
+	}
+	want := []image.NRGBAColor{{0, 255, 0, 255}, {0, 0, 255, 127}, {0, 0, 0, 0}, {255, 255, 255, 255}}
+	for i, c := range want {
+		if p := got.Preview.At(i%2, i/2); p != c {
+			t.Errorf("Preview.At(%d, %d): want %v, got %v", i%2, i/2, c, p)
+		}
+	}
+
+	md.Set("PreviewImageData", nbt.IntArray{1, 2, 3})
+	if _, err = ReadLitematicMetadata(litematicBytes(t, root)); err == nil {
+		t.Errorf("Non-square preview: want error, got nil")
+	}
+	if _, err = ReadLitematicMetadata(litematicBytes(t, nbt.NewCompound().Set("Version", nbt.Int(5)))); err == nil {
+		t.Errorf("Missing Regions: want error, got nil")
+	}
+}
+
+func TestPackStates(t *testing.T) {
+	// 5 bits per index, so some of them span two longs.
+	states := make([]int, 100)
+	for i := range states {
+		states[i] = (i * 7) % 20
+	}
+	packed := packStates(states, 20)
+	if len(packed) != 8 {
+		t.Fatalf("packStates: want 8 longs, got %d", len(packed))
+	}
+	got, err := unpackStates(packed, len(states), 20)
+	if err != nil {
+		t.Fatalf("unpackStates: %v", err)
+	}
+	for i := range states {
+		if got[i] != states[i] {
+			t.Fatalf("unpackStates: index %d: want %d, got %d", i, states[i], got[i])
+		}
+	}
+	if _, err = unpackStates(packed[:7], len(states), 20); err == nil {
+		t.Errorf("unpackStates of short data: want error, got nil")
+	}
+}
+
+func TestLitematicRegions(t *testing.T) {
+	s := newTestVolume()
+	s.SetBlock(0, 1, 1, Block{53, 2}) // oak stairs facing west
+	l, err := SplitLitematic(s, []RegionBox{
+		{"Left", Box{0, 0, 0, 1, 2, 2}},
+		{"Right", Box{1, 0, 0, 3, 2, 2}},
+	})
+	if err != nil {
+		t.Fatalf("SplitLitematic: %v", err)
+	}
+	l.Metadata.Name = "Test"
+	if l.Metadata.TotalBlocks != 4 || l.Metadata.TotalVolume != 12 || l.Metadata.Size != [3]int{3, 2, 2} {
+		t.Errorf("Metadata: got %+v", l.Metadata)
+	}
+	var buf bytes.Buffer
+	if err = WriteLitematic(&buf, l); err != nil {
+		t.Fatalf("WriteLitematic: %v", err)
+	}
+	if l, err = ReadLitematic(&buf); err != nil {
+		t.Fatalf("ReadLitematic: %v", err)
+	}
+	if len(l.Regions) != 2 || l.Regions[1].Name != "Right" || l.Regions[1].X != 1 || l.Metadata.Name != "Test" {
+		t.Fatalf("ReadLitematic: got %+v", l)
+	}
+	if r := l.Regions[1]; len(r.Entities) != 1 || len(r.Palette) != 3 {
+		t.Errorf("Right region: want 1 entity and 3 states, got %d and %v", len(r.Entities), r.Palette)
+	}
+	got, err := l.Schematic()
+	if err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	// The stairs lose the direction.
+	s.SetBlock(0, 1, 1, Block{53, 0})
+	if got.Fingerprint() != s.Fingerprint() {
+		t.Errorf("Schematic: got blocks %v, data %v, want %v, %v", got.Blocks, got.Data, s.Blocks, s.Data)
+	}
+	if len(got.Entities) != 1 || entityPos(got.Entities[0]) != entityPos(s.Entities[0]) {
+		t.Errorf("Schematic: entities %v", got.Entities)
+	}
+
+	if err = l.Translate(LatestDataVersion); err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	buf.Reset()
+	if err = WriteLitematic(&buf, l); err != nil {
+		t.Fatalf("WriteLitematic: %v", err)
+	}
+	if l, err = ReadLitematic(&buf); err != nil {
+		t.Fatalf("ReadLitematic: %v", err)
+	}
+	if l.DataVersion != LatestDataVersion || l.Regions[0].DataVersion != LatestDataVersion {
+		t.Errorf("DataVersion: want %d, got %d and %d", LatestDataVersion, l.DataVersion, l.Regions[0].DataVersion)
+	}
+	l.Regions[0].DataVersion = DataVersion1_13
+	if err = WriteLitematic(&buf, l); err == nil {
+		t.Errorf("WriteLitematic with mixed data versions: want error, got nil")
+	}
+}
+
+func TestFormatState(t *testing.T) {
+	props := nbt.NewCompound().Set("waterlogged", nbt.String("false")).Set("facing", nbt.String("east"))
+	c := nbt.NewCompound().Set("Name", nbt.String("minecraft:chest")).Set("Properties", props)
+	if got, want := formatState(c), "minecraft:chest[facing=east,waterlogged=false]"; got != want {
+		t.Errorf("formatState: want %s, got %s", want, got)
+	}
+}
+
+func TestLitematicNegativeSize(t *testing.T) {
+	vec := func(x, y, z int) *nbt.Compound {
+		return nbt.NewCompound().Set("x", nbt.Int(x)).Set("y", nbt.Int(y)).Set("z", nbt.Int(z))
+	}
+	palette := nbt.NewList(nbt.TagCompound,
+		nbt.NewCompound().Set("Name", nbt.String("minecraft:air")),
+		nbt.NewCompound().Set("Name", nbt.String("minecraft:red_wool")),
+		nbt.NewCompound().Set("Name", nbt.String("minecraft:oak_log")).Set("Properties", nbt.NewCompound().Set("axis", nbt.String("x"))))
+	region := nbt.NewCompound().
+		Set("Position", vec(5, 0, 0)).
+		Set("Size", vec(-2, 1, 1)).
+		Set("BlockStatePalette", palette).
+		Set("BlockStates", packStates([]int{1, 2}, 3)).
+		Set("Entities", nbt.NewList(nbt.TagCompound, nbt.NewCompound().
+			Set("id", nbt.String("minecraft:pig")).
+			Set("Pos", nbt.NewList(nbt.TagDouble, nbt.Double(-0.5), nbt.Double(0), nbt.Double(0.5)))))
+	root := nbt.NewCompound().
+		Set("Version", nbt.Int(5)).
+		Set("Metadata", nbt.NewCompound()).
+		Set("Regions", nbt.NewCompound().Set("R", region))
+	l, err := ReadLitematic(litematicBytes(t, root))
+	if err != nil {
+		t.Fatalf("ReadLitematic: %v", err)
+	}
+	r := l.Regions[0]
+	if r.X != 4 || r.Width != 2 || r.Palette[2] != "minecraft:oak_log[axis=x]" {
+		t.Errorf("Region: got %+v", r)
+	}
+	// The pig stands on the first block; its position is relative to
+	// Position, the block at x = 5.
+	if len(r.Entities) != 1 || entityPos(r.Entities[0]) != [3]float64{0.5, 0, 0.5} {
+		t.Errorf("Region entities: got %v", r.Entities)
+	}
+	s, err := l.Schematic()
+	if err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	if s.WEOffsetX != 4 || s.Block(0, 0, 0) != (Block{35, 14}) || s.Block(1, 0, 0) != (Block{17, 0}) {
+		t.Errorf("Schematic: offset %d, blocks %v", s.WEOffsetX, s.Blocks)
+	}
+	if len(s.Entities) != 1 || entityPos(s.Entities[0]) != [3]float64{0.5, 0, 0.5} {
+		t.Errorf("Schematic entities: got %v", s.Entities)
+	}
+
+	palette.Tags[1] = nbt.NewCompound().Set("Name", nbt.String("minecraft:deepslate"))
+	if l, err = ReadLitematic(litematicBytes(t, root)); err != nil {
+		t.Fatalf("ReadLitematic: %v", err)
+	}
+	if _, err = l.Schematic(); err == nil {
+		t.Errorf("Schematic with deepslate: want error, got nil")
+	}
+	// A corrupted size must not make the reader allocate the volume.
+	region.Set("Size", vec(-1<<31, 1<<30, 1<<30))
+	if _, err = ReadLitematic(litematicBytes(t, root)); err == nil {
+		t.Errorf("ReadLitematic with a huge size: want error, got nil")
+	}
+}
+
+func TestLitematicPreview(t *testing.T) {
+	l, err := SplitLitematic(newTestVolume(), nil)
+	if err != nil {
+		t.Fatalf("SplitLitematic: %v", err)
+	}
+	var buf bytes.Buffer
+	if err = WriteLitematicOptions(&buf, l, &WriteOptions{Preview: true}); err != nil {
+		t.Fatalf("WriteLitematicOptions: %v", err)
+	}
+	md, err := ReadLitematicMetadata(&buf)
+	if err != nil {
+		t.Fatalf("ReadLitematicMetadata: %v", err)
+	}
+	if md.Preview == nil || md.Preview.Bounds().Dx() != LitematicPreviewSize {
+		t.Errorf("Generated preview: got %v", md.Preview)
+	}
+
+	// A 4x2 preview is centered on a 4x4 square.
+	red := image.NRGBAColor{255, 0, 0, 255}
+	m := image.NewNRGBA(4, 2)
+	for x := 0; x < 4; x++ {
+		m.Set(x, 0, red)
+		m.Set(x, 1, red)
+	}
+	l.Metadata.Preview = m
+	buf.Reset()
+	if err = WriteLitematicOptions(&buf, l, &WriteOptions{Preview: true}); err != nil {
+		t.Fatalf("WriteLitematicOptions: %v", err)
+	}
+	if md, err = ReadLitematicMetadata(&buf); err != nil {
+		t.Fatalf("ReadLitematicMetadata: %v", err)
+	}
+	if b := md.Preview.Bounds(); b.Dx() != 4 || b.Dy() != 4 {
+		t.Fatalf("Preview size: want 4x4, got %dx%d", b.Dx(), b.Dy())
+	}
+	if got := md.Preview.At(0, 1); got != red {
+		t.Errorf("Preview.At(0, 1): want %v, got %v", red, got)
+	}
+	if got := md.Preview.At(0, 0); got.(image.NRGBAColor).A != 0 {
+		t.Errorf("Preview.At(0, 0): want transparent, got %v", got)
+	}
+}
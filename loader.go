@@ -0,0 +1,89 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"container/list"
+	"os"
+	"sync"
+)
+
+// A Loader reads schematic files and keeps the most recently used ones in
+// memory. A cached schematic is read again when the modification time or
+// the size of its file changes. The returned schematics are shared between
+// the callers and must not be modified. A Loader is safe for concurrent use.
+type Loader struct {
+	mu      sync.Mutex
+	max     int
+	lru     *list.List // of *loaderEntry, the most recently used at the front
+	entries map[string]*list.Element
+}
+
+type loaderEntry struct {
+	path  string
+	mtime int64
+	size  int64
+	s     *Schematic
+}
+
+// NewLoader returns a Loader caching up to max schematics.
+func NewLoader(max int) *Loader {
+	return &Loader{max: max, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Load returns the schematic stored in the named file.
+func (l *Loader) Load(path string) (s *Schematic, err os.Error) {
+	var fi *os.FileInfo
+	if fi, err = os.Stat(path); err != nil {
+		l.Forget(path)
+		return
+	}
+	l.mu.Lock()
+	if el, ok := l.entries[path]; ok {
+		e := el.Value.(*loaderEntry)
+		if e.mtime == fi.Mtime_ns && e.size == fi.Size {
+			l.lru.MoveToFront(el)
+			l.mu.Unlock()
+			return e.s, nil
+		}
+	}
+	l.mu.Unlock()
+
+	if s, err = ReadSchematicFile(path); err != nil {
+		l.Forget(path)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := &loaderEntry{path: path, mtime: fi.Mtime_ns, size: fi.Size, s: s}
+	if el, ok := l.entries[path]; ok {
+		el.Value = e
+		l.lru.MoveToFront(el)
+	} else {
+		l.entries[path] = l.lru.PushFront(e)
+	}
+	for l.lru.Len() > l.max {
+		el := l.lru.Back()
+		l.lru.Remove(el)
+		l.entries[el.Value.(*loaderEntry).path] = nil, false
+	}
+	return
+}
+
+// Forget drops the named file from the cache.
+func (l *Loader) Forget(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[path]; ok {
+		l.lru.Remove(el)
+		l.entries[path] = nil, false
+	}
+}
+
+// Len returns the number of cached schematics.
+func (l *Loader) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
@@ -0,0 +1,63 @@
+package schematic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	vol := readTestSchematic(t, "testdata/cylinder.schematic")
+	var paths []string
+	for _, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(dir, name+Ext)
+		if err = WriteSchematicFile(path, vol, nil); err != nil {
+			t.Fatalf("WriteSchematicFile: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	l := NewLoader(2)
+	a, err := l.Load(paths[0])
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if again, _ := l.Load(paths[0]); again != a {
+		t.Errorf("Load: want the cached schematic")
+	}
+	l.Load(paths[1])
+	l.Load(paths[2])
+	if l.Len() != 2 {
+		t.Errorf("Len: want 2, got %d", l.Len())
+	}
+	if again, _ := l.Load(paths[0]); again == a {
+		t.Errorf("Load: want the evicted schematic to be read again")
+	}
+
+	// Changing the file invalidates the cache.
+	b, _ := l.Load(paths[1])
+	small := &Schematic{Width: 1, Height: 1, Length: 1, Blocks: []byte{1}, Data: []byte{0}}
+	if err = WriteSchematicFile(paths[1], small, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	got, err := l.Load(paths[1])
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got == b || got.Width != 1 {
+		t.Errorf("Load: want the modified file to be read again, got Width %d", got.Width)
+	}
+
+	os.Remove(paths[1])
+	if _, err = l.Load(paths[1]); err == nil {
+		t.Errorf("Load of a removed file: want error, got nil")
+	}
+	if l.Len() != 1 {
+		t.Errorf("Len: want 1 after the removed file is forgotten, got %d", l.Len())
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A Mask selects the blocks of a schematic that an operation may change,
+// like the masks of WorldEdit.
+type Mask interface {
+	// Test reports whether the block at the position is selected.
+	Test(s *Schematic, x, y, z int) bool
+}
+
+// The MaskFunc type is an adapter to allow the use of ordinary functions
+// as masks.
+type MaskFunc func(s *Schematic, x, y, z int) bool
+
+// Test calls f(s, x, y, z).
+func (f MaskFunc) Test(s *Schematic, x, y, z int) bool {
+	return f(s, x, y, z)
+}
+
+// BlockMask selects the blocks equal to one of the given blocks.
+func BlockMask(blocks ...Block) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		b := s.Block(x, y, z)
+		for _, c := range blocks {
+			if b == c {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// IdMask selects the blocks with one of the given ids and any data value.
+func IdMask(ids ...uint16) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		id := s.GetV(x, y, z)
+		for _, c := range ids {
+			if id == c {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RegionMask selects the blocks inside the box.
+func RegionMask(b Box) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		return x >= b.MinX && y >= b.MinY && z >= b.MinZ && x < b.MaxX && y < b.MaxY && z < b.MaxZ
+	})
+}
+
+// AdjacentMask selects the blocks with at least one of the six neighbours
+// selected by m. The blocks outside of the schematic are air, so
+// AdjacentMask(IdMask(0)) selects the blocks touching air, including those
+// on the faces of the schematic.
+func AdjacentMask(m Mask) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		for _, n := range Neighbors6(x, y, z) {
+			if m.Test(s, n.X, n.Y, n.Z) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FaceMask selects the blocks whose neighbour on one of the faces is
+// selected by m. For example, FaceMask(IdMask(0), Up) selects the blocks
+// with air or the outside of the schematic above them.
+func FaceMask(m Mask, faces ...Direction) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		for _, d := range faces {
+			dx, dy, dz := d.Step()
+			if m.Test(s, x+dx, y+dy, z+dz) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// CountMask selects the blocks with at least min and at most max of the
+// six neighbours selected by m, so CountMask(IdMask(0), 4, 6) selects the
+// blocks sticking out of a surface.
+func CountMask(m Mask, min, max int) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		n := 0
+		for _, nb := range Neighbors6(x, y, z) {
+			if m.Test(s, nb.X, nb.Y, nb.Z) {
+				n++
+			}
+		}
+		return n >= min && n <= max
+	})
+}
+
+// TopMask selects the blocks selected by m with no block selected by m
+// above them in their column, that is, the top-most selected block of
+// every column. TopMask(NotMask(IdMask(0))) selects the top-most block
+// other than air.
+//
+// The top of every column is found once, when the mask is first tested on
+// a schematic, so the mask must not be reused after the blocks of that
+// schematic are changed. Fill and the other methods taking a mask test it
+// before changing anything.
+func TopMask(m Mask) Mask {
+	var mu sync.Mutex
+	var last *Schematic
+	var top []int
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		if x < 0 || z < 0 || x >= s.Width || z >= s.Length {
+			return false
+		}
+		mu.Lock()
+		if s != last {
+			last, top = s, s.columnTops(m)
+		}
+		h := top[z*s.Width+x]
+		mu.Unlock()
+		return y == h
+	})
+}
+
+// columnTops returns the height of the top-most block selected by m in
+// every column, ordered by z and x, or -1 for the columns without one.
+func (s *Schematic) columnTops(m Mask) []int {
+	top := make([]int, s.Width*s.Length)
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			h := s.Height - 1
+			for h >= 0 && !m.Test(s, x, h, z) {
+				h--
+			}
+			top[z*s.Width+x] = h
+		}
+	}
+	return top
+}
+
+// NotMask selects the blocks not selected by m.
+func NotMask(m Mask) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		return !m.Test(s, x, y, z)
+	})
+}
+
+// AndMask selects the blocks selected by all the masks.
+func AndMask(masks ...Mask) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		for _, m := range masks {
+			if !m.Test(s, x, y, z) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// OrMask selects the blocks selected by any of the masks.
+func OrMask(masks ...Mask) Mask {
+	return MaskFunc(func(s *Schematic, x, y, z int) bool {
+		for _, m := range masks {
+			if m.Test(s, x, y, z) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ParseMask parses a mask written in a syntax close to the one of WorldEdit.
+// The mask is a space separated list of conditions which all must hold:
+//
+//	stone,35:14,red_wool  the listed blocks; without a data value, like
+//	                      stone and 35, all data values are selected
+//	!cond                 the blocks not selected by cond
+//	~cond                 the blocks next to a block selected by cond
+//	N~cond                the blocks with at least N of the six
+//	                      neighbours selected by cond, where N is 1 to 6
+//	>cond                 the blocks right above a block selected by cond
+//	<cond                 the blocks right below a block selected by cond
+//	#top                  the top-most block other than air of every
+//	                      column
+//	#existing             the blocks other than air
+//	#region:x1,y1,z1,x2,y2,z2
+//	                      the blocks of the box with the corners at
+//	                      (x1, y1, z1) and (x2, y2, z2), both included
+//
+// For example, "!air ~air" selects the surface of a build, "stone <air"
+// the stone open to the sky and "!air 4~air" the blocks sticking out of
+// it.
+func ParseMask(str string) (Mask, os.Error) {
+	var masks []Mask
+	for _, cond := range strings.Fields(str) {
+		m, err := parseCondition(cond)
+		if err != nil {
+			return nil, err
+		}
+		masks = append(masks, m)
+	}
+	switch len(masks) {
+	case 0:
+		return nil, os.NewError("Empty mask")
+	case 1:
+		return masks[0], nil
+	}
+	return AndMask(masks...), nil
+}
+
+func parseCondition(cond string) (Mask, os.Error) {
+	switch {
+	case len(cond) > 1 && cond[0] >= '1' && cond[0] <= '6' && cond[1] == '~':
+		m, err := parseCondition(cond[2:])
+		if err != nil {
+			return nil, err
+		}
+		return CountMask(m, int(cond[0]-'0'), 6), nil
+	case strings.HasPrefix(cond, "!") || strings.HasPrefix(cond, "~") ||
+		strings.HasPrefix(cond, ">") || strings.HasPrefix(cond, "<"):
+		m, err := parseCondition(cond[1:])
+		if err != nil {
+			return nil, err
+		}
+		switch cond[0] {
+		case '!':
+			return NotMask(m), nil
+		case '>':
+			return FaceMask(m, Down), nil
+		case '<':
+			return FaceMask(m, Up), nil
+		}
+		return AdjacentMask(m), nil
+	case cond == "#existing":
+		return NotMask(IdMask(0)), nil
+	case cond == "#top":
+		return TopMask(NotMask(IdMask(0))), nil
+	case strings.HasPrefix(cond, "#region:"):
+		parts := strings.Split(cond[len("#region:"):], ",")
+		if len(parts) != 6 {
+			return nil, fmt.Errorf("Invalid region: %s", cond)
+		}
+		var v [6]int
+		for i, p := range parts {
+			var err os.Error
+			if v[i], err = strconv.Atoi(p); err != nil {
+				return nil, fmt.Errorf("Invalid region: %s", cond)
+			}
+		}
+		return RegionMask(Box{imin(v[0], v[3]), imin(v[1], v[4]), imin(v[2], v[5]),
+			imax(v[0], v[3]) + 1, imax(v[1], v[4]) + 1, imax(v[2], v[5]) + 1}), nil
+	case cond == "" || strings.HasPrefix(cond, "#"):
+		return nil, fmt.Errorf("Invalid mask condition: '%s'", cond)
+	}
+	var blocks []Block
+	var ids []uint16
+	for _, spec := range strings.Split(cond, ",") {
+		b, anyData, err := parseBlockSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if anyData {
+			ids = append(ids, b.Id)
+		} else {
+			blocks = append(blocks, b)
+		}
+	}
+	return OrMask(IdMask(ids...), BlockMask(blocks...)), nil
+}
+
+// selected returns the indexes of the blocks selected by m.
+func (s *Schematic) selected(m Mask) (sel []int) {
+	for y := 0; y < s.YLen(); y++ {
+		for z := 0; z < s.ZLen(); z++ {
+			for x := 0; x < s.XLen(); x++ {
+				if m.Test(s, x, y, z) {
+					sel = append(sel, s.index(x, y, z))
+				}
+			}
+		}
+	}
+	return
+}
+
+// Fill sets the blocks selected by m to the blocks of the pattern, which
+// may be a single Block, and returns their number. The mask is tested
+// against the schematic before the change, so masks depending on the
+// neighbours of a block, such as AdjacentMask, see none of the new blocks.
+func (s *Schematic) Fill(m Mask, p Pattern) int {
+	sel := s.selected(m)
+	for _, i := range sel {
+		x, y, z := s.coords(i)
+		s.SetBlock(x, y, z, p.BlockAt(x, y, z))
+	}
+	return len(sel)
+}
+
+// Select returns a copy of s with the blocks not selected by m replaced
+// with air. Transforming the result, such as with Rotate, restricts the
+// transformation to the selected blocks.
+func (s *Schematic) Select(m Mask) *Schematic {
+	t := s.transform(s.Width, s.Height, s.Length, translate(0, 0, 0))
+	t.Fill(NotMask(MaskFunc(func(_ *Schematic, x, y, z int) bool {
+		return m.Test(s, x, y, z)
+	})), Block{})
+	return t
+}
@@ -0,0 +1,109 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestParseMask(t *testing.T) {
+	// A column of stone on top of a dirt floor.
+	s := NewSchematic(3, 3, 3)
+	for x := 0; x < 3; x++ {
+		for z := 0; z < 3; z++ {
+			s.SetBlock(x, 0, z, Block{3, 0})
+		}
+	}
+	s.SetBlock(1, 1, 1, Block{1, 0})
+	s.SetBlock(1, 2, 1, Block{35, 14})
+	tests := []struct {
+		mask string
+		n    int
+	}{
+		{"dirt", 9},
+		{"3:1", 0},
+		{"stone,red_wool", 2},
+		{"35", 1},
+		{"#existing", 11},
+		{"!air", 11},
+		{"air ~stone", 4},
+		{"dirt !~air", 0},
+		{"#region:0,0,0,1,0,1", 4},
+		{"#region:1,2,1,1,0,1 !air", 3},
+		{"#top", 9},
+		{"dirt <air", 8},
+		{"red_wool <air", 1},
+		{">dirt", 9},
+		{">dirt air", 8},
+		{"!air 4~air", 6},
+		{"!air 6~air", 0},
+	}
+	for _, tt := range tests {
+		m, err := ParseMask(tt.mask)
+		if err != nil {
+			t.Errorf("ParseMask(%s): %v", tt.mask, err)
+			continue
+		}
+		if n := len(s.selected(m)); n != tt.n {
+			t.Errorf("%s: want %d blocks, got %d", tt.mask, tt.n, n)
+		}
+	}
+	for _, str := range []string{"", "#foo", "!", "unknown_block", "#region:1,2,3", "35:16", "1~", "7~air"} {
+		if _, err := ParseMask(str); err == nil {
+			t.Errorf("ParseMask(%q): want error, got nil", str)
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	s := NewSchematic(3, 1, 3)
+	s.SetBlock(1, 0, 1, Block{1, 0})
+	// The mask sees the schematic before the fill, so the ring around the
+	// stone is filled, not the whole layer.
+	if n := s.Fill(AndMask(IdMask(0), AdjacentMask(IdMask(1))), Block{1, 0}); n != 4 {
+		t.Errorf("Fill: want 4 blocks, got %d", n)
+	}
+	if s.Block(0, 0, 0) != (Block{}) || s.Block(0, 0, 1) != (Block{1, 0}) {
+		t.Errorf("Fill: got blocks %v", s.Blocks)
+	}
+
+	t2 := s.Select(RegionMask(Box{0, 0, 0, 1, 1, 1}))
+	if t2.Bounds() != (Box{}) {
+		t.Errorf("Select of air: want no blocks, got %v", t2.Blocks)
+	}
+	t2 = s.Select(RegionMask(Box{0, 0, 1, 3, 1, 2}))
+	if n := len(t2.selected(IdMask(1))); n != 3 || s.GetV(1, 0, 0) != 1 {
+		t.Errorf("Select: want 3 stone blocks, got %v", t2.Blocks)
+	}
+}
+
+func TestAdjacencyMasks(t *testing.T) {
+	// A stone hill with a lone block of dirt on its top.
+	s := NewSchematic(3, 3, 1)
+	for x := 0; x < 3; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+	}
+	s.SetBlock(1, 1, 0, Block{1, 0})
+	s.SetBlock(1, 2, 0, Block{3, 0})
+	// Grass on the stone open to the sky, without touching the dirt.
+	if n := s.Fill(AndMask(IdMask(1), FaceMask(IdMask(0), Up)), Block{2, 0}); n != 2 {
+		t.Errorf("Fill under the sky: want 2 blocks, got %d", n)
+	}
+	if s.GetV(0, 0, 0) != 2 || s.GetV(2, 0, 0) != 2 || s.GetV(1, 1, 0) != 1 {
+		t.Errorf("Fill under the sky: got %v", s.Blocks)
+	}
+	top := TopMask(IdMask(1))
+	if n := len(s.selected(top)); n != 1 {
+		t.Errorf("TopMask: want 1 block, got %d", n)
+	}
+	// The mask is reused for another schematic.
+	c := NewSchematic(2, 3, 1)
+	c.SetBlock(0, 0, 0, Block{1, 0})
+	c.SetBlock(0, 2, 0, Block{1, 0})
+	if !top.Test(c, 0, 2, 0) || top.Test(c, 0, 0, 0) || top.Test(c, 1, 0, 0) || top.Test(c, 2, 0, 0) {
+		t.Errorf("TopMask on another schematic: got %v", c.Blocks)
+	}
+	// The outside of the schematic is air, so only the stone in the middle
+	// has fewer than 5 faces in the open.
+	if n := len(s.selected(AndMask(IdMask(1, 2, 3), CountMask(IdMask(0), 5, 6)))); n != 3 {
+		t.Errorf("CountMask: want 3 blocks, got %d", n)
+	}
+}
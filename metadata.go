@@ -0,0 +1,96 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"github.com/krasin/schematic/nbt"
+)
+
+// Metadata describes who made a schematic and what it shows.
+//
+// It is kept in the Metadata tag of Extra under the names used by the
+// Sponge format, so WriteSchematic and the transformations copying Extra
+// preserve it. Sponge.Schematic copies the Metadata tag of the Sponge file
+// and NewSponge copies it back for WriteSponge, and Litematic.Schematic
+// and SplitLitematic convert it from and to the Litematica metadata, which
+// has no tags.
+type Metadata struct {
+	Name        string   `json:"name,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Date is the creation time in milliseconds since the epoch, or 0.
+	Date int64 `json:"date,omitempty"`
+}
+
+// Metadata returns the metadata of s. The fields missing in the Metadata
+// tag are empty.
+func (s *Schematic) Metadata() (md Metadata) {
+	if s.Extra == nil {
+		return
+	}
+	c, ok := s.Extra.Get("Metadata").(*nbt.Compound)
+	if !ok {
+		return
+	}
+	str := func(name string) string {
+		v, _ := c.Get(name).(nbt.String)
+		return string(v)
+	}
+	md.Name = str("Name")
+	md.Author = str("Author")
+	md.Description = str("Description")
+	md.Date = int64Field(c, "Date")
+	if list, ok := c.Get("Tags").(*nbt.List); ok {
+		for _, tag := range list.Tags {
+			if v, ok := tag.(nbt.String); ok {
+				md.Tags = append(md.Tags, string(v))
+			}
+		}
+	}
+	return
+}
+
+// SetMetadata stores md in the Metadata tag. Empty fields are removed from
+// the tag, the other tags it contains, such as the ones written by
+// WorldEdit, are kept.
+func (s *Schematic) SetMetadata(md Metadata) {
+	if s.Extra == nil {
+		s.Extra = new(nbt.Compound)
+	}
+	old, _ := s.Extra.Get("Metadata").(*nbt.Compound)
+	c := new(nbt.Compound)
+	if old != nil {
+		for _, f := range old.Fields {
+			switch f.Name {
+			case "Name", "Author", "Description", "Tags", "Date":
+			default:
+				c.Set(f.Name, f.Tag)
+			}
+		}
+	}
+	setString := func(name, v string) {
+		if v != "" {
+			c.Set(name, nbt.String(v))
+		}
+	}
+	setString("Name", md.Name)
+	setString("Author", md.Author)
+	setString("Description", md.Description)
+	if len(md.Tags) > 0 {
+		tags := &nbt.List{ElemType: nbt.TagString}
+		for _, tag := range md.Tags {
+			tags.Tags = append(tags.Tags, nbt.String(tag))
+		}
+		c.Set("Tags", tags)
+	}
+	if md.Date != 0 {
+		c.Set("Date", nbt.Long(md.Date))
+	}
+	if c.Len() == 0 {
+		s.Extra.Delete("Metadata")
+		return
+	}
+	s.Extra.Set("Metadata", c)
+}
@@ -0,0 +1,107 @@
+package schematic
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestMetadata(t *testing.T) {
+	s := newTestVolume()
+	if md := s.Metadata(); !reflect.DeepEqual(md, Metadata{}) {
+		t.Errorf("Metadata without the tag: got %+v", md)
+	}
+	s.Extra.Set("Metadata", nbt.NewCompound().Set("WEOffsetX", nbt.Int(5)).Set("Name", nbt.String("Old")))
+	want := Metadata{Name: "Tower", Author: "krasin", Tags: []string{"medieval", "tower"}, Date: 1316000000000}
+	s.SetMetadata(want)
+	var buf bytes.Buffer
+	if err := WriteSchematic(&buf, s); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	if got, err = got.Rotate(90); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if md := got.Metadata(); !reflect.DeepEqual(md, want) {
+		t.Errorf("Metadata: want %+v, got %+v", want, md)
+	}
+	c := got.Extra.Get("Metadata").(*nbt.Compound)
+	if intField(c, "WEOffsetX") != 5 || c.Len() != 5 {
+		t.Errorf("Metadata tag: got %v", c)
+	}
+
+	got.SetMetadata(Metadata{})
+	if c := got.Extra.Get("Metadata").(*nbt.Compound); c.Len() != 1 {
+		t.Errorf("Metadata tag after clearing: got %v", c)
+	}
+	s.Extra.Delete("Metadata")
+	s.SetMetadata(Metadata{})
+	if s.Extra.Get("Metadata") != nil {
+		t.Errorf("SetMetadata of empty metadata added the tag")
+	}
+}
+
+func TestMetadataConversions(t *testing.T) {
+	s := newTestVolume()
+	md := Metadata{Name: "Tower", Author: "krasin", Description: "A tower", Tags: []string{"tower"}, Date: 1316000000000}
+	s.SetMetadata(md)
+	l, err := SplitLitematic(s, nil)
+	if err != nil {
+		t.Fatalf("SplitLitematic: %v", err)
+	}
+	var buf bytes.Buffer
+	if err = WriteLitematic(&buf, l); err != nil {
+		t.Fatalf("WriteLitematic: %v", err)
+	}
+	if l, err = ReadLitematic(&buf); err != nil {
+		t.Fatalf("ReadLitematic: %v", err)
+	}
+	if l.Metadata.Name != "Tower" || l.Metadata.Description != "A tower" || l.Metadata.TimeCreated != md.Date {
+		t.Errorf("Litematica metadata: got %+v", l.Metadata)
+	}
+	if s, err = l.Schematic(); err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	// Litematica has no tags.
+	md.Tags = nil
+	if got := s.Metadata(); !reflect.DeepEqual(got, md) {
+		t.Errorf("Metadata from Litematica: want %+v, got %+v", md, got)
+	}
+
+	s = newTestVolume()
+	md.Tags = []string{"tower", "stone"}
+	s.SetMetadata(md)
+	sp, err := NewSponge(s)
+	if err != nil {
+		t.Fatalf("NewSponge: %v", err)
+	}
+	buf.Reset()
+	if err = WriteSponge(&buf, sp); err != nil {
+		t.Fatalf("WriteSponge: %v", err)
+	}
+	if sp, err = ReadSponge(&buf); err != nil {
+		t.Fatalf("ReadSponge: %v", err)
+	}
+	if s, err = sp.Schematic(); err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	if got := s.Metadata(); !reflect.DeepEqual(got, md) {
+		t.Errorf("Metadata from a written Sponge: want %+v, got %+v", md, got)
+	}
+
+	sp, err = ReadSponge(bytes.NewBuffer(spongeBytes(t, 2)))
+	if err != nil {
+		t.Fatalf("ReadSponge: %v", err)
+	}
+	if s, err = sp.Schematic(); err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	if got := s.Metadata(); got.Name != "Path" {
+		t.Errorf("Metadata from Sponge: got %+v", got)
+	}
+}
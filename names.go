@@ -0,0 +1,82 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"json"
+	"os"
+	"strings"
+)
+
+// displayNames are the English names of the blocks which are not their
+// state names in title case.
+var displayNames = map[string]string{
+	"coal_block":     "Block of Coal",
+	"comparator":     "Redstone Comparator",
+	"diamond_block":  "Block of Diamond",
+	"emerald_block":  "Block of Emerald",
+	"gold_block":     "Block of Gold",
+	"hay_block":      "Hay Bale",
+	"iron_block":     "Block of Iron",
+	"jack_o_lantern": "Jack o'Lantern",
+	"lapis_block":    "Lapis Lazuli Block",
+	"lapis_ore":      "Lapis Lazuli Ore",
+	"quartz_block":   "Block of Quartz",
+	"redstone_block": "Block of Redstone",
+	"repeater":       "Redstone Repeater",
+	"tnt":            "TNT",
+}
+
+// DisplayName returns the English name of the block shown by Minecraft,
+// such as "Oak Planks" for Block{5, 0}, or the id and the data value,
+// such as "4000:0", for the blocks unknown to LegacyState.
+func DisplayName(b Block) string {
+	state, ok := LegacyState(b)
+	if !ok {
+		return fmt.Sprintf("%d:%d", b.Id, b.Data)
+	}
+	name := state[len("minecraft:"):]
+	if d, ok := displayNames[name]; ok {
+		return d
+	}
+	words := strings.Split(name, "_")
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// A Language maps the translation keys of Minecraft, such as
+// "block.minecraft.oak_planks", to the names in a language. A nil
+// Language is English.
+type Language map[string]string
+
+// LoadLanguage reads a language file of Minecraft 1.13 or later, such as
+// assets/minecraft/lang/de_de.json of the client, which is a JSON object
+// of the translation keys.
+func LoadLanguage(r io.Reader) (Language, os.Error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var l Language
+	if err = json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("Invalid language file: %v", err)
+	}
+	return l, nil
+}
+
+// DisplayName returns the name of the block in the language, or the
+// English name returned by DisplayName if the language has none.
+func (l Language) DisplayName(b Block) string {
+	if state, ok := LegacyState(b); ok {
+		if name, ok := l["block.minecraft."+state[len("minecraft:"):]]; ok {
+			return name
+		}
+	}
+	return DisplayName(b)
+}
@@ -0,0 +1,45 @@
+package schematic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplayName(t *testing.T) {
+	tests := []struct {
+		b    Block
+		name string
+	}{
+		{Block{5, 0}, "Oak Planks"},
+		{Block{35, 14}, "Red Wool"},
+		{Block{46, 0}, "TNT"},
+		{Block{41, 0}, "Block of Gold"},
+		{Block{4000, 3}, "4000:3"},
+	}
+	for _, tt := range tests {
+		if name := DisplayName(tt.b); name != tt.name {
+			t.Errorf("DisplayName(%v): want %q, got %q", tt.b, tt.name, name)
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	l, err := LoadLanguage(strings.NewReader(`{"block.minecraft.oak_planks": "Eichenholzbretter", "item.minecraft.stick": "Stock"}`))
+	if err != nil {
+		t.Fatalf("LoadLanguage: %v", err)
+	}
+	if name := l.DisplayName(Block{5, 0}); name != "Eichenholzbretter" {
+		t.Errorf("DisplayName of oak planks: got %q", name)
+	}
+	// The names missing in the language are English.
+	if name := l.DisplayName(Block{1, 0}); name != "Stone" {
+		t.Errorf("DisplayName of stone: got %q", name)
+	}
+	var english Language
+	if name := english.DisplayName(Block{5, 0}); name != "Oak Planks" {
+		t.Errorf("DisplayName in English: got %q", name)
+	}
+	if _, err = LoadLanguage(strings.NewReader("[1, 2]")); err == nil {
+		t.Errorf("LoadLanguage of an array: want error, got nil")
+	}
+}
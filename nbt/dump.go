@@ -0,0 +1,65 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package nbt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// dumpPreview is the number of array elements shown by Dump.
+const dumpPreview = 8
+
+// Dump writes a human readable, indented tree of the tag to w.
+// Arrays are shown with their size and the first few elements:
+//
+//	compound (2 fields)
+//	  Width: short 16
+//	  Blocks: byte_array [4096] 0 0 1 1 3 3 3 3 ...
+func Dump(w io.Writer, tag Tag) os.Error {
+	bw := bufio.NewWriter(w)
+	dump(bw, tag, "")
+	return bw.Flush()
+}
+
+func dump(w *bufio.Writer, tag Tag, indent string) {
+	fmt.Fprintf(w, "%s", TypeName(tag.Type()))
+	switch v := tag.(type) {
+	case Byte, Short, Int, Long:
+		fmt.Fprintf(w, " %d\n", v)
+	case Float:
+		fmt.Fprintf(w, " %s\n", strconv.Ftoa32(float32(v), 'g', -1))
+	case Double:
+		fmt.Fprintf(w, " %s\n", strconv.Ftoa64(float64(v), 'g', -1))
+	case String:
+		fmt.Fprintf(w, " %q\n", string(v))
+	case ByteArray, IntArray, LongArray:
+		n := arrayLen(v)
+		fmt.Fprintf(w, " [%d]", n)
+		for i := 0; i < n && i < dumpPreview; i++ {
+			fmt.Fprintf(w, " %d", arrayElem(v, i))
+		}
+		if n > dumpPreview {
+			fmt.Fprintf(w, " ...")
+		}
+		fmt.Fprintf(w, "\n")
+	case *List:
+		fmt.Fprintf(w, " of %s [%d]\n", TypeName(v.ElemType), len(v.Tags))
+		for i, elem := range v.Tags {
+			fmt.Fprintf(w, "%s  [%d]: ", indent, i)
+			dump(w, elem, indent+"  ")
+		}
+	case *Compound:
+		fmt.Fprintf(w, " (%d fields)\n", len(v.Fields))
+		for _, f := range v.Fields {
+			fmt.Fprintf(w, "%s  %s: ", indent, f.Name)
+			dump(w, f.Tag, indent+"  ")
+		}
+	default:
+		fmt.Fprintf(w, "\n")
+	}
+}
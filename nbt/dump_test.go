@@ -0,0 +1,30 @@
+package nbt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	c := NewCompound().
+		Set("Width", Short(16)).
+		Set("Blocks", make(ByteArray, 10)).
+		Set("Pos", NewList(TagDouble, Double(0.5), Double(64))).
+		Set("Entity", NewCompound().Set("id", String("Pig")))
+	want := `compound (4 fields)
+  Width: short 16
+  Blocks: byte_array [10] 0 0 0 0 0 0 0 0 ...
+  Pos: list of double [2]
+    [0]: double 0.5
+    [1]: double 64
+  Entity: compound (1 fields)
+    id: string "Pig"
+`
+	var buf bytes.Buffer
+	if err := Dump(&buf, c); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("Dump: want\n%s\ngot\n%s", want, got)
+	}
+}
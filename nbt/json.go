@@ -0,0 +1,234 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package nbt
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"json"
+	"math"
+	"os"
+	"strconv"
+)
+
+// The JSON form of a tag is an object with the tag type and its value:
+//
+//	{"name": "Schematic", "type": "compound", "value": [
+//		{"name": "Width", "type": "short", "value": 16},
+//		{"name": "Pos", "type": "list", "elem": "double", "value": [
+//			{"type": "double", "value": 0.5}, ...]}]}
+//
+// Compound fields are kept in an array to preserve their order. Longs are
+// written as strings and non-finite floats as "NaN", "+Inf" or "-Inf",
+// so that the conversion is lossless.
+
+type jsonTag struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type"`
+	Elem  string      `json:"elem,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+type jsonRawTag struct {
+	Name  string
+	Type  string
+	Elem  string
+	Value json.RawMessage
+}
+
+// EncodeJSON writes the named tag to w in the JSON form.
+func EncodeJSON(w io.Writer, name string, tag Tag) (err os.Error) {
+	var data []byte
+	if data, err = json.MarshalIndent(toJSON(name, tag), "", "  "); err != nil {
+		return
+	}
+	_, err = w.Write(append(data, '\n'))
+	return
+}
+
+// DecodeJSON reads a named tag written by EncodeJSON.
+func DecodeJSON(r io.Reader) (name string, tag Tag, err os.Error) {
+	var data []byte
+	if data, err = ioutil.ReadAll(r); err != nil {
+		return
+	}
+	var jt jsonRawTag
+	if err = json.Unmarshal(data, &jt); err != nil {
+		return
+	}
+	tag, err = fromJSON(&jt)
+	return jt.Name, tag, err
+}
+
+func jsonFloat(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+func toJSON(name string, tag Tag) *jsonTag {
+	jt := &jsonTag{Name: name, Type: TypeName(tag.Type())}
+	switch v := tag.(type) {
+	case Byte, Short, Int, String:
+		jt.Value = v
+	case Long:
+		jt.Value = strconv.Itoa64(int64(v))
+	case Float:
+		jt.Value = jsonFloat(float64(v))
+	case Double:
+		jt.Value = jsonFloat(float64(v))
+	case ByteArray:
+		arr := make([]int, len(v))
+		for i, x := range v {
+			arr[i] = int(x)
+		}
+		jt.Value = arr
+	case IntArray:
+		jt.Value = []int32(v)
+	case LongArray:
+		arr := make([]string, len(v))
+		for i, x := range v {
+			arr[i] = strconv.Itoa64(x)
+		}
+		jt.Value = arr
+	case *List:
+		jt.Elem = TypeName(v.ElemType)
+		elems := make([]*jsonTag, len(v.Tags))
+		for i, elem := range v.Tags {
+			elems[i] = toJSON("", elem)
+		}
+		jt.Value = elems
+	case *Compound:
+		fields := make([]*jsonTag, len(v.Fields))
+		for i, f := range v.Fields {
+			fields[i] = toJSON(f.Name, f.Tag)
+		}
+		jt.Value = fields
+	}
+	return jt
+}
+
+// parseJSONFloat decodes a number or one of the strings written by jsonFloat.
+func parseJSONFloat(raw json.RawMessage) (v float64, err os.Error) {
+	if json.Unmarshal(raw, &v) == nil {
+		return
+	}
+	var str string
+	if err = json.Unmarshal(raw, &str); err != nil {
+		return
+	}
+	switch str {
+	case "NaN":
+		return math.NaN(), nil
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	}
+	return 0, fmt.Errorf("Invalid float value: %s", str)
+}
+
+func fromJSON(jt *jsonRawTag) (tag Tag, err os.Error) {
+	typ, ok := typeByName(jt.Type)
+	if !ok {
+		return nil, fmt.Errorf("Unknown tag type: %s", jt.Type)
+	}
+	switch typ {
+	case TagByte:
+		var v int8
+		err = json.Unmarshal(jt.Value, &v)
+		tag = Byte(v)
+	case TagShort:
+		var v int16
+		err = json.Unmarshal(jt.Value, &v)
+		tag = Short(v)
+	case TagInt:
+		var v int32
+		err = json.Unmarshal(jt.Value, &v)
+		tag = Int(v)
+	case TagLong:
+		var str string
+		var v int64
+		if err = json.Unmarshal(jt.Value, &str); err == nil {
+			v, err = strconv.Atoi64(str)
+		}
+		tag = Long(v)
+	case TagFloat:
+		var v float64
+		v, err = parseJSONFloat(jt.Value)
+		tag = Float(v)
+	case TagDouble:
+		var v float64
+		v, err = parseJSONFloat(jt.Value)
+		tag = Double(v)
+	case TagString:
+		var v string
+		err = json.Unmarshal(jt.Value, &v)
+		tag = String(v)
+	case TagByteArray:
+		var v []uint8
+		err = json.Unmarshal(jt.Value, &v)
+		tag = ByteArray(v)
+	case TagIntArray:
+		var v []int32
+		err = json.Unmarshal(jt.Value, &v)
+		tag = IntArray(v)
+	case TagLongArray:
+		var strs []string
+		if err = json.Unmarshal(jt.Value, &strs); err != nil {
+			break
+		}
+		arr := make(LongArray, len(strs))
+		for i, str := range strs {
+			if arr[i], err = strconv.Atoi64(str); err != nil {
+				break
+			}
+		}
+		tag = arr
+	case TagList:
+		list := &List{ElemType: TagEnd, Tags: []Tag{}}
+		if jt.Elem != "" {
+			if list.ElemType, ok = typeByName(jt.Elem); !ok {
+				return nil, fmt.Errorf("Unknown list element type: %s", jt.Elem)
+			}
+		}
+		var elems []jsonRawTag
+		if err = json.Unmarshal(jt.Value, &elems); err != nil {
+			break
+		}
+		for i := range elems {
+			var elem Tag
+			if elem, err = fromJSON(&elems[i]); err != nil {
+				return nil, err
+			}
+			if elem.Type() != list.ElemType {
+				return nil, fmt.Errorf("List element has type %s, want: %s", elems[i].Type, jt.Elem)
+			}
+			list.Tags = append(list.Tags, elem)
+		}
+		tag = list
+	case TagCompound:
+		var fields []jsonRawTag
+		if err = json.Unmarshal(jt.Value, &fields); err != nil {
+			break
+		}
+		c := new(Compound)
+		for i := range fields {
+			var elem Tag
+			if elem, err = fromJSON(&fields[i]); err != nil {
+				return nil, err
+			}
+			c.Fields = append(c.Fields, Field{fields[i].Name, elem})
+		}
+		tag = c
+	default:
+		return nil, fmt.Errorf("Unsupported tag type: %s", jt.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,42 @@
+package nbt
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	want := testCompound()
+	want.Set("Huge", Long(math.MaxInt64))
+	want.Set("Inf", Float(float32(math.Inf(-1))))
+	var buf bytes.Buffer
+	if err := EncodeJSON(&buf, "Root", want); err != nil {
+		t.Fatalf("EncodeJSON: %v", err)
+	}
+	name, got, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatalf("DecodeJSON: %v", err)
+	}
+	if name != "Root" {
+		t.Errorf("DecodeJSON: want name Root, got %s", name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeJSON: want %v, got %v", want, got)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"type": "bogus", "value": 1}`,
+		`{"type": "byte", "value": 300}`,
+		`{"type": "list", "elem": "int", "value": [{"type": "short", "value": 1}]}`,
+		`{"type": "long", "value": "x"}`,
+	} {
+		if _, _, err := DecodeJSON(strings.NewReader(in)); err == nil {
+			t.Errorf("DecodeJSON(%s): want error, got nil", in)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+
+// Package nbt implements reading and writing of the Named Binary Tag format
+// used by Minecraft.
+package nbt
+
+import (
+	"fmt"
+)
+
+const (
+	TagEnd       = 0
+	TagByte      = 1
+	TagShort     = 2
+	TagInt       = 3
+	TagLong      = 4
+	TagFloat     = 5
+	TagDouble    = 6
+	TagByteArray = 7
+	TagString    = 8
+	TagList      = 9
+	TagCompound  = 10
+	TagIntArray  = 11
+	TagLongArray = 12
+)
+
+var typeNames = []string{
+	TagEnd:       "end",
+	TagByte:      "byte",
+	TagShort:     "short",
+	TagInt:       "int",
+	TagLong:      "long",
+	TagFloat:     "float",
+	TagDouble:    "double",
+	TagByteArray: "byte_array",
+	TagString:    "string",
+	TagList:      "list",
+	TagCompound:  "compound",
+	TagIntArray:  "int_array",
+	TagLongArray: "long_array",
+}
+
+// TypeName returns a lower case name of the tag type, e.g. "byte_array".
+func TypeName(typ byte) string {
+	if int(typ) >= len(typeNames) {
+		return fmt.Sprintf("type%d", typ)
+	}
+	return typeNames[typ]
+}
+
+// typeByName is the inverse of TypeName.
+func typeByName(name string) (typ byte, ok bool) {
+	for i, n := range typeNames {
+		if n == name {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
+
+// A Tag is a single NBT value.
+type Tag interface {
+	// Type returns the tag type, one of the Tag* constants.
+	Type() byte
+}
+
+type Byte int8
+type Short int16
+type Int int32
+type Long int64
+type Float float32
+type Double float64
+type ByteArray []byte
+type String string
+type IntArray []int32
+type LongArray []int64
+
+// A List is a sequence of unnamed tags of the same type.
+type List struct {
+	ElemType byte
+	Tags     []Tag
+}
+
+// A Field is a named tag inside of a Compound.
+type Field struct {
+	Name string
+	Tag  Tag
+}
+
+// A Compound is a collection of uniquely named tags.
+// The order of the fields is preserved.
+type Compound struct {
+	Fields []Field
+}
+
+func (Byte) Type() byte      { return TagByte }
+func (Short) Type() byte     { return TagShort }
+func (Int) Type() byte       { return TagInt }
+func (Long) Type() byte      { return TagLong }
+func (Float) Type() byte     { return TagFloat }
+func (Double) Type() byte    { return TagDouble }
+func (ByteArray) Type() byte { return TagByteArray }
+func (String) Type() byte    { return TagString }
+func (IntArray) Type() byte  { return TagIntArray }
+func (LongArray) Type() byte { return TagLongArray }
+func (*List) Type() byte     { return TagList }
+func (*Compound) Type() byte { return TagCompound }
+
+// NewCompound returns a compound holding the given fields.
+// Together with Set it allows to build trees in a single expression:
+//
+//	nbt.NewCompound().
+//		Set("id", nbt.String("Chest")).
+//		Set("Items", nbt.NewList(nbt.TagCompound))
+func NewCompound(fields ...Field) *Compound {
+	c := new(Compound)
+	for _, f := range fields {
+		c.Set(f.Name, f.Tag)
+	}
+	return c
+}
+
+// Get returns the tag with the given name or nil if there is no such tag.
+func (c *Compound) Get(name string) Tag {
+	for _, f := range c.Fields {
+		if f.Name == name {
+			return f.Tag
+		}
+	}
+	return nil
+}
+
+// Set replaces the tag with the given name or appends it if there is none.
+// It returns c.
+func (c *Compound) Set(name string, tag Tag) *Compound {
+	for i, f := range c.Fields {
+		if f.Name == name {
+			c.Fields[i].Tag = tag
+			return c
+		}
+	}
+	c.Fields = append(c.Fields, Field{name, tag})
+	return c
+}
+
+// Delete removes the tag with the given name, if any. It returns c.
+func (c *Compound) Delete(name string) *Compound {
+	for i, f := range c.Fields {
+		if f.Name == name {
+			c.Fields = append(c.Fields[:i], c.Fields[i+1:]...)
+			break
+		}
+	}
+	return c
+}
+
+// Len returns the number of fields in the compound.
+func (c *Compound) Len() int {
+	return len(c.Fields)
+}
+
+// NewList returns a list of the given element type holding tags.
+// It panics if a tag has a different type.
+func NewList(elemType byte, tags ...Tag) *List {
+	l := &List{ElemType: elemType, Tags: []Tag{}}
+	return l.Append(tags...)
+}
+
+// Append adds tags to the end of the list and returns l.
+// It panics if a tag has a type different from l.ElemType.
+func (l *List) Append(tags ...Tag) *List {
+	for _, tag := range tags {
+		if tag.Type() != l.ElemType {
+			panic(fmt.Sprintf("nbt: cannot append %s to a list of %s", TypeName(tag.Type()), TypeName(l.ElemType)))
+		}
+		l.Tags = append(l.Tags, tag)
+	}
+	return l
+}
+
+// Len returns the number of elements in the list.
+func (l *List) Len() int {
+	return len(l.Tags)
+}
+
+// Clone returns a deep copy of the tag.
+func Clone(tag Tag) Tag {
+	switch v := tag.(type) {
+	case ByteArray:
+		return append(ByteArray{}, v...)
+	case IntArray:
+		return append(IntArray{}, v...)
+	case LongArray:
+		return append(LongArray{}, v...)
+	case *List:
+		l := &List{ElemType: v.ElemType, Tags: make([]Tag, len(v.Tags))}
+		for i, elem := range v.Tags {
+			l.Tags[i] = Clone(elem)
+		}
+		return l
+	case *Compound:
+		c := &Compound{Fields: make([]Field, len(v.Fields))}
+		for i, f := range v.Fields {
+			c.Fields[i] = Field{f.Name, Clone(f.Tag)}
+		}
+		return c
+	}
+	return tag
+}
@@ -0,0 +1,185 @@
+package nbt
+
+import (
+	"bytes"
+	"rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testCompound() *Compound {
+	pos := &List{ElemType: TagDouble, Tags: []Tag{Double(1.5), Double(-64), Double(3)}}
+	entity := new(Compound)
+	entity.Set("id", String("Creeper"))
+	entity.Set("Pos", pos)
+	c := new(Compound)
+	c.Set("Byte", Byte(-1))
+	c.Set("Short", Short(-300))
+	c.Set("Int", Int(-70000))
+	c.Set("Long", Long(-1<<40))
+	c.Set("Float", Float(0.25))
+	c.Set("Double", Double(1e100))
+	c.Set("ByteArray", ByteArray{1, 2, 3})
+	c.Set("String", String("hello"))
+	c.Set("IntArray", IntArray{-1, 0, 1})
+	c.Set("LongArray", LongArray{-1 << 50, 7})
+	c.Set("Entities", &List{ElemType: TagCompound, Tags: []Tag{entity}})
+	c.Set("Empty", &List{ElemType: TagEnd, Tags: []Tag{}})
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := testCompound()
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteTag("Root", want); err != nil {
+		t.Fatalf("WriteTag: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	n := buf.Len()
+	r := NewReader(&buf)
+	name, got, err := r.ReadTag()
+	if err != nil {
+		t.Fatalf("ReadTag: %v", err)
+	}
+	if name != "Root" {
+		t.Errorf("ReadTag: want name Root, got %s", name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadTag: want %v, got %v", want, got)
+	}
+	if r.Offset() != int64(n) {
+		t.Errorf("Offset: want %d, got %d", n, r.Offset())
+	}
+}
+
+func TestErrorPath(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteTag("Root", testCompound())
+	w.Flush()
+	// Cut the stream inside of Entities[0].Pos.
+	data := buf.Bytes()
+	data = data[:bytes.Index(data, []byte("Pos"))+10]
+	r := NewReader(bytes.NewBuffer(data))
+	if _, _, err := r.ReadTag(); err == nil {
+		t.Fatalf("ReadTag: want error, got nil")
+	}
+	if got := r.Path(); got != "Root.Entities[0].Pos[0]" {
+		t.Errorf("Path: want Root.Entities[0].Pos[0], got %s", got)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	c := NewCompound(Field{"id", String("Chest")}).
+		Set("Items", NewList(TagCompound,
+			NewCompound().Set("Slot", Byte(0)).Set("id", Short(1)),
+			NewCompound().Set("Slot", Byte(1)).Set("id", Short(4)))).
+		Set("Lock", String("")).
+		Delete("Lock")
+	if c.Len() != 2 {
+		t.Fatalf("Len: want 2, got %d", c.Len())
+	}
+	items := c.Get("Items").(*List)
+	if items.Len() != 2 || items.Tags[1].(*Compound).Get("id") != Short(4) {
+		t.Errorf("Items: got %v", items)
+	}
+	clone := Clone(c).(*Compound)
+	clone.Get("Items").(*List).Tags[0].(*Compound).Set("Slot", Byte(5))
+	if items.Tags[0].(*Compound).Get("Slot") != Byte(0) {
+		t.Errorf("Clone shares data with the original")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Append of a wrong type: want panic")
+		}
+	}()
+	items.Append(Int(1))
+}
+
+func TestHugeLength(t *testing.T) {
+	// A byte array, a list and an int array claiming 2^31-1 elements.
+	for _, typ := range []byte{TagByteArray, TagList, TagIntArray} {
+		data := []byte{TagCompound, 0, 0, typ, 0, 1, 'a'}
+		if typ == TagList {
+			data = append(data, TagLong)
+		}
+		data = append(data, 0x7f, 0xff, 0xff, 0xff, 1, 2, 3)
+		if _, _, err := NewReader(bytes.NewBuffer(data)).ReadTag(); err == nil {
+			t.Errorf("ReadTag of type %d: want error, got nil", typ)
+		}
+	}
+}
+
+func TestDeepNesting(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write([]byte{TagList, 0, 0})
+	for i := 0; i < MaxDepth*2; i++ {
+		buf.Write([]byte{TagList, 0, 0, 0, 1})
+	}
+	if _, _, err := NewReader(&buf).ReadTag(); err == nil || !strings.Contains(err.String(), "nested") {
+		t.Errorf("ReadTag of nested lists: want nesting error, got %v", err)
+	}
+	str := strings.Repeat("[", MaxDepth*2) + strings.Repeat("]", MaxDepth*2)
+	if _, err := ParseSNBT(str); err == nil || !strings.Contains(err.String(), "nested") {
+		t.Errorf("ParseSNBT of nested lists: want nesting error, got %v", err)
+	}
+	str = strings.Repeat("[", MaxDepth/2) + strings.Repeat("]", MaxDepth/2)
+	if _, err := ParseSNBT(str); err != nil {
+		t.Errorf("ParseSNBT of %d nested lists: %v", MaxDepth/2, err)
+	}
+}
+
+// mutate returns a copy of data with a few bytes changed and the end
+// possibly cut off.
+func mutate(rnd *rand.Rand, data []byte) []byte {
+	m := append([]byte(nil), data...)
+	for n := rnd.Intn(4) + 1; n > 0; n-- {
+		i := rnd.Intn(len(m))
+		switch rnd.Intn(3) {
+		case 0:
+			m[i] ^= 1 << uint(rnd.Intn(8))
+		case 1:
+			m[i] = 0xff
+		case 2:
+			m[i] = byte(rnd.Intn(256))
+		}
+	}
+	if rnd.Intn(4) == 0 {
+		m = m[:rnd.Intn(len(m))]
+	}
+	return m
+}
+
+func TestMalformed(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteTag("Root", testCompound())
+	w.Flush()
+	data := buf.Bytes()
+	str := FormatSNBT(testCompound(), "")
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		m := mutate(rnd, data)
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					t.Errorf("ReadTag(%q) panics: %v", m, e)
+				}
+			}()
+			NewReader(bytes.NewBuffer(m)).ReadTag()
+		}()
+		ms := string(mutate(rnd, []byte(str)))
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					t.Errorf("ParseSNBT(%q) panics: %v", ms, e)
+				}
+			}()
+			ParseSNBT(ms)
+		}()
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package nbt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A path selects tags inside of a tree, e.g. Entities[2].Pos[0].
+// It is a sequence of elements separated by dots:
+//
+//	name     the field of a compound; quote it ("a b") if it contains special characters
+//	*        all fields of a compound
+//	[n]      the n-th element of a list or an array; negative n counts from the end
+//	[*]      all elements of a list or an array
+
+type pathElem struct {
+	name  string
+	index int
+	isIdx bool // [index] rather than a name
+	any   bool // * or [*]
+}
+
+func parsePath(path string) (elems []pathElem, err os.Error) {
+	for i := 0; i < len(path); {
+		switch c := path[i]; {
+		case c == '.':
+			i++
+		case c == '[':
+			end := strings.Index(path[i:], "]")
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated '[' in path: %s", path)
+			}
+			str := path[i+1 : i+end]
+			i += end + 1
+			if str == "*" {
+				elems = append(elems, pathElem{isIdx: true, any: true})
+				continue
+			}
+			var idx int
+			if idx, err = strconv.Atoi(str); err != nil {
+				return nil, fmt.Errorf("Invalid index in path: %s", str)
+			}
+			elems = append(elems, pathElem{isIdx: true, index: idx})
+		case c == '"':
+			end := strings.Index(path[i+1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated '\"' in path: %s", path)
+			}
+			elems = append(elems, pathElem{name: path[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			name := path[i : i+end]
+			elems = append(elems, pathElem{name: name, any: name == "*"})
+			i += end
+		}
+	}
+	return
+}
+
+// arrayLen returns the number of elements of a list or an array tag, or -1
+// for other tags.
+func arrayLen(tag Tag) int {
+	switch v := tag.(type) {
+	case *List:
+		return len(v.Tags)
+	case ByteArray:
+		return len(v)
+	case IntArray:
+		return len(v)
+	case LongArray:
+		return len(v)
+	}
+	return -1
+}
+
+func arrayElem(tag Tag, i int) Tag {
+	switch v := tag.(type) {
+	case *List:
+		return v.Tags[i]
+	case ByteArray:
+		return Byte(v[i])
+	case IntArray:
+		return Int(v[i])
+	case LongArray:
+		return Long(v[i])
+	}
+	return nil
+}
+
+// indexes returns the indexes of the array elements selected by e.
+func (e pathElem) indexes(tag Tag) []int {
+	n := arrayLen(tag)
+	if n < 0 {
+		return nil
+	}
+	if e.any {
+		idx := make([]int, n)
+		for i := range idx {
+			idx[i] = i
+		}
+		return idx
+	}
+	i := e.index
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		return nil
+	}
+	return []int{i}
+}
+
+// step returns the children of tag selected by e.
+func (e pathElem) step(tag Tag) (res []Tag) {
+	if e.isIdx {
+		for _, i := range e.indexes(tag) {
+			res = append(res, arrayElem(tag, i))
+		}
+		return
+	}
+	c, ok := tag.(*Compound)
+	if !ok {
+		return
+	}
+	for _, f := range c.Fields {
+		if e.any || f.Name == e.name {
+			res = append(res, f.Tag)
+		}
+	}
+	return
+}
+
+func walkPath(root Tag, elems []pathElem) []Tag {
+	cur := []Tag{root}
+	for _, e := range elems {
+		var next []Tag
+		for _, tag := range cur {
+			next = append(next, e.step(tag)...)
+		}
+		cur = next
+	}
+	return cur
+}
+
+// Query returns all tags under root selected by the path. It returns an
+// empty slice if nothing matches.
+func Query(root Tag, path string) (res []Tag, err os.Error) {
+	var elems []pathElem
+	if elems, err = parsePath(path); err != nil {
+		return
+	}
+	return walkPath(root, elems), nil
+}
+
+// Assign replaces all tags under root selected by the path with value and
+// returns the number of replaced tags. If the last element of the path is a
+// name, the field is added to the matching compounds that do not have it.
+func Assign(root Tag, path string, value Tag) (n int, err os.Error) {
+	var elems []pathElem
+	if elems, err = parsePath(path); err != nil {
+		return
+	}
+	if len(elems) == 0 {
+		return 0, os.NewError("Cannot assign to the root tag")
+	}
+	last := elems[len(elems)-1]
+	for _, parent := range walkPath(root, elems[:len(elems)-1]) {
+		if !last.isIdx {
+			c, ok := parent.(*Compound)
+			if !ok {
+				continue
+			}
+			if !last.any {
+				c.Set(last.name, value)
+				n++
+				continue
+			}
+			for i := range c.Fields {
+				c.Fields[i].Tag = value
+				n++
+			}
+			continue
+		}
+		for _, i := range last.indexes(parent) {
+			if err = setElem(parent, i, value); err != nil {
+				return
+			}
+			n++
+		}
+	}
+	return
+}
+
+// setElem replaces the i-th element of a list or an array.
+func setElem(tag Tag, i int, value Tag) os.Error {
+	switch v := tag.(type) {
+	case *List:
+		if value.Type() != v.ElemType {
+			return fmt.Errorf("Cannot assign %s to an element of a list of %s", TypeName(value.Type()), TypeName(v.ElemType))
+		}
+		v.Tags[i] = value
+		return nil
+	case ByteArray:
+		if x, ok := value.(Byte); ok {
+			v[i] = byte(x)
+			return nil
+		}
+	case IntArray:
+		if x, ok := value.(Int); ok {
+			v[i] = int32(x)
+			return nil
+		}
+	case LongArray:
+		if x, ok := value.(Long); ok {
+			v[i] = int64(x)
+			return nil
+		}
+	}
+	return fmt.Errorf("Cannot assign %s to an element of %s", TypeName(value.Type()), TypeName(tag.Type()))
+}
@@ -0,0 +1,65 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	root := testCompound()
+	tests := []struct {
+		path string
+		want []Tag
+	}{
+		{"Int", []Tag{Int(-70000)}},
+		{"Entities[0].Pos[1]", []Tag{Double(-64)}},
+		{"Entities[-1].Pos[*]", []Tag{Double(1.5), Double(-64), Double(3)}},
+		{`Entities[*]."id"`, []Tag{String("Creeper")}},
+		{"IntArray[2]", []Tag{Int(1)}},
+		{"Entities[*].*", []Tag{String("Creeper"), root.Get("Entities").(*List).Tags[0].(*Compound).Get("Pos")}},
+		{"Missing.Pos", nil},
+		{"Entities[5]", nil},
+	}
+	for _, tt := range tests {
+		got, err := Query(root, tt.path)
+		if err != nil {
+			t.Errorf("Query(%s): %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%s): want %v, got %v", tt.path, tt.want, got)
+		}
+	}
+	for _, path := range []string{"Entities[0", "Entities[x]", `"open`} {
+		if _, err := Query(root, path); err == nil {
+			t.Errorf("Query(%s): want error, got nil", path)
+		}
+	}
+}
+
+func TestAssign(t *testing.T) {
+	root := testCompound()
+	if n, err := Assign(root, "Entities[*].Pos[1]", Double(70)); n != 1 || err != nil {
+		t.Fatalf("Assign: want 1, nil; got %d, %v", n, err)
+	}
+	if n, err := Assign(root, "Entities[0].Motion", Int(3)); n != 1 || err != nil {
+		t.Fatalf("Assign: want 1, nil; got %d, %v", n, err)
+	}
+	if n, err := Assign(root, "IntArray[*]", Int(9)); n != 3 || err != nil {
+		t.Fatalf("Assign: want 3, nil; got %d, %v", n, err)
+	}
+	if _, err := Assign(root, "Entities[0].Pos[0]", Int(1)); err == nil {
+		t.Errorf("Assign of an int to a list of doubles: want error, got nil")
+	}
+	got, _ := Query(root, "Entities[0].Pos[1]")
+	if !reflect.DeepEqual(got, []Tag{Double(70)}) {
+		t.Errorf("Pos[1]: want 70, got %v", got)
+	}
+	got, _ = Query(root, "Entities[0].Motion")
+	if !reflect.DeepEqual(got, []Tag{Int(3)}) {
+		t.Errorf("Motion: want 3, got %v", got)
+	}
+	if !reflect.DeepEqual(root.Get("IntArray"), IntArray{9, 9, 9}) {
+		t.Errorf("IntArray: want [9 9 9], got %v", root.Get("IntArray"))
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package nbt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// A Reader reads NBT data from an uncompressed stream. It keeps track of
+// the byte offset and the path of the tag being read, so that callers can
+// report the location of malformed data.
+//
+// The Reader is safe to use on untrusted data: the memory it allocates is
+// proportional to the data read, whatever lengths the data claims, and
+// tags nested deeper than MaxDepth are rejected.
+type Reader struct {
+	r    *bufio.Reader
+	off  int64 // number of bytes consumed from the stream
+	path []string
+}
+
+// MaxDepth is the maximum nesting of lists and compounds, as in Minecraft.
+const MaxDepth = 512
+
+// allocChunk is the number of elements allocated in advance for arrays
+// and lists. Longer ones grow as their elements are read.
+const allocChunk = 1 << 16
+
+// NewReader returns a Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Offset returns the number of bytes consumed so far.
+func (r *Reader) Offset() int64 {
+	return r.off
+}
+
+// Push descends into the tag with the given name or list index ("[3]").
+func (r *Reader) Push(elem string) {
+	r.path = append(r.path, elem)
+}
+
+// Pop returns to the parent of the current tag.
+func (r *Reader) Pop() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+// Path returns the path of the current tag, e.g. Schematic.Entities[3].Pos.
+func (r *Reader) Path() string {
+	var path []string
+	for _, elem := range r.path {
+		if len(path) > 0 && !strings.HasPrefix(elem, "[") {
+			path = append(path, ".")
+		}
+		path = append(path, elem)
+	}
+	return strings.Join(path, "")
+}
+
+func (r *Reader) readFull(buf []byte) (err os.Error) {
+	var n int
+	n, err = io.ReadFull(r.r, buf)
+	r.off += int64(n)
+	return
+}
+
+func (r *Reader) ReadByte() (b byte, err os.Error) {
+	if b, err = r.r.ReadByte(); err == nil {
+		r.off++
+	}
+	return
+}
+
+func (r *Reader) ReadString() (str string, err os.Error) {
+	var l int
+	if l, err = r.ReadShort(); err != nil {
+		return
+	}
+	data := make([]byte, l)
+	if err = r.readFull(data); err != nil {
+		return
+	}
+	return string(data), nil
+}
+
+func (r *Reader) ReadShort() (val int, err os.Error) {
+	buf := [2]byte{}
+	if err = r.readFull(buf[:]); err != nil {
+		return
+	}
+	val = int(buf[1]) + (int(buf[0]) << 8) // Big Endian
+	return
+}
+
+func (r *Reader) ReadInt() (val int, err os.Error) {
+	buf := [4]byte{}
+	if err = r.readFull(buf[:]); err != nil {
+		return
+	}
+	var v int32
+	for i := 0; i < 4; i++ {
+		v <<= 8
+		v += int32(buf[i])
+	}
+	return int(v), nil
+}
+
+func (r *Reader) ReadLong() (val int64, err os.Error) {
+	buf := [8]byte{}
+	if err = r.readFull(buf[:]); err != nil {
+		return
+	}
+	for i := 0; i < 8; i++ {
+		val <<= 8
+		val += int64(buf[i])
+	}
+	return
+}
+
+func (r *Reader) ReadTagTyp() (typ byte, err os.Error) {
+	typ, err = r.ReadByte()
+	return
+}
+
+func (r *Reader) ReadTagName() (typ byte, name string, err os.Error) {
+	if typ, err = r.ReadByte(); err != nil {
+		return
+	}
+	if typ == TagEnd {
+		return
+	}
+	name, err = r.ReadString()
+	return
+}
+
+// readLen reads the length of an array or a list.
+func (r *Reader) readLen() (l int, err os.Error) {
+	if l, err = r.ReadInt(); err != nil {
+		return
+	}
+	if int32(l) < 0 {
+		return 0, fmt.Errorf("Negative length: %d", int32(l))
+	}
+	return
+}
+
+func (r *Reader) ReadByteArray() (data []byte, err os.Error) {
+	var l int
+	if l, err = r.readLen(); err != nil {
+		return
+	}
+	data = make([]byte, 0, imin(l, allocChunk))
+	for len(data) < l {
+		start := len(data)
+		data = append(data, make([]byte, imin(l-start, allocChunk))...)
+		if err = r.readFull(data[start:]); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ReadTag reads a complete named tag. For TagEnd, the name and the tag are empty.
+func (r *Reader) ReadTag() (name string, tag Tag, err os.Error) {
+	var typ byte
+	if typ, name, err = r.ReadTagName(); err != nil || typ == TagEnd {
+		return
+	}
+	r.Push(name)
+	if tag, err = r.ReadValue(typ); err != nil {
+		return
+	}
+	r.Pop()
+	return
+}
+
+// ReadValue reads the payload of a tag of the given type.
+func (r *Reader) ReadValue(typ byte) (tag Tag, err os.Error) {
+	if (typ == TagList || typ == TagCompound) && len(r.path) > MaxDepth {
+		return nil, fmt.Errorf("Tags nested deeper than %d", MaxDepth)
+	}
+	switch typ {
+	case TagByte:
+		var v byte
+		v, err = r.ReadByte()
+		tag = Byte(int8(v))
+	case TagShort:
+		var v int
+		v, err = r.ReadShort()
+		tag = Short(int16(v))
+	case TagInt:
+		var v int
+		v, err = r.ReadInt()
+		tag = Int(int32(v))
+	case TagLong:
+		var v int64
+		v, err = r.ReadLong()
+		tag = Long(v)
+	case TagFloat:
+		var v int
+		v, err = r.ReadInt()
+		tag = Float(math.Float32frombits(uint32(v)))
+	case TagDouble:
+		var v int64
+		v, err = r.ReadLong()
+		tag = Double(math.Float64frombits(uint64(v)))
+	case TagByteArray:
+		var v []byte
+		v, err = r.ReadByteArray()
+		tag = ByteArray(v)
+	case TagString:
+		var v string
+		v, err = r.ReadString()
+		tag = String(v)
+	case TagList:
+		tag, err = r.ReadList()
+	case TagCompound:
+		tag, err = r.ReadCompound()
+	case TagIntArray:
+		var l, v int
+		if l, err = r.readLen(); err != nil {
+			return
+		}
+		arr := make(IntArray, 0, imin(l, allocChunk))
+		for i := 0; i < l; i++ {
+			if v, err = r.ReadInt(); err != nil {
+				return
+			}
+			arr = append(arr, int32(v))
+		}
+		tag = arr
+	case TagLongArray:
+		var l int
+		if l, err = r.readLen(); err != nil {
+			return
+		}
+		arr := make(LongArray, 0, imin(l, allocChunk))
+		for i := 0; i < l; i++ {
+			var v int64
+			if v, err = r.ReadLong(); err != nil {
+				return
+			}
+			arr = append(arr, v)
+		}
+		tag = arr
+	default:
+		err = fmt.Errorf("Unknown tag type: %d", typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// ReadList reads the payload of a list tag.
+func (r *Reader) ReadList() (list *List, err os.Error) {
+	list = new(List)
+	if list.ElemType, err = r.ReadTagTyp(); err != nil {
+		return nil, err
+	}
+	var l int
+	if l, err = r.readLen(); err != nil {
+		return nil, err
+	}
+	list.Tags = make([]Tag, 0, imin(l, allocChunk))
+	for i := 0; i < l; i++ {
+		r.Push(fmt.Sprintf("[%d]", i))
+		var tag Tag
+		if tag, err = r.ReadValue(list.ElemType); err != nil {
+			return nil, err
+		}
+		list.Tags = append(list.Tags, tag)
+		r.Pop()
+	}
+	return
+}
+
+// ReadCompound reads the payload of a compound tag, up to and including its TagEnd.
+func (r *Reader) ReadCompound() (c *Compound, err os.Error) {
+	c = new(Compound)
+	for {
+		var name string
+		var tag Tag
+		if name, tag, err = r.ReadTag(); err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			break
+		}
+		c.Fields = append(c.Fields, Field{name, tag})
+	}
+	return
+}
@@ -0,0 +1,448 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package nbt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SNBT is the textual form of NBT used by the in-game commands, e.g.
+// {id:"minecraft:chest",Items:[{Slot:0b,Count:1b}],Pos:[I;1,2,3]}.
+
+var (
+	snbtInt   = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)$`)
+	snbtFloat = regexp.MustCompile(`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+)
+
+// ParseSNBT parses a tag written in the stringified NBT syntax.
+func ParseSNBT(str string) (tag Tag, err os.Error) {
+	p := &snbtParser{s: str}
+	if tag, err = p.value(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, p.errorf("Unexpected trailing data")
+	}
+	return
+}
+
+type snbtParser struct {
+	s     string
+	pos   int
+	depth int
+}
+
+func (p *snbtParser) errorf(format string, args ...interface{}) os.Error {
+	return fmt.Errorf("SNBT offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *snbtParser) skipSpace() {
+	for p.pos < len(p.s) && strings.Contains(" \t\r\n", p.s[p.pos:p.pos+1]) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space character or 0 at the end of input.
+func (p *snbtParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *snbtParser) expect(c byte) os.Error {
+	if p.peek() != c {
+		return p.errorf("Expected '%c'", c)
+	}
+	p.pos++
+	return nil
+}
+
+func isUnquoted(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c == '_' || c == '-' || c == '.' || c == '+'
+}
+
+// str reads a quoted or an unquoted string.
+func (p *snbtParser) str() (str string, quoted bool, err os.Error) {
+	q := p.peek()
+	if q != '"' && q != '\'' {
+		start := p.pos
+		for p.pos < len(p.s) && isUnquoted(p.s[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start {
+			return "", false, p.errorf("Expected a value")
+		}
+		return p.s[start:p.pos], false, nil
+	}
+	p.pos++
+	var buf bytes.Buffer
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == q:
+			return buf.String(), true, nil
+		case c == '\\' && p.pos < len(p.s):
+			buf.WriteByte(p.s[p.pos])
+			p.pos++
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", true, p.errorf("Unterminated string")
+}
+
+func (p *snbtParser) value() (tag Tag, err os.Error) {
+	switch p.peek() {
+	case '{', '[':
+		if p.depth >= MaxDepth {
+			return nil, p.errorf("Tags nested deeper than %d", MaxDepth)
+		}
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.peek() == '{' {
+			return p.compound()
+		}
+		return p.list()
+	case 0:
+		return nil, p.errorf("Unexpected end of input")
+	}
+	str, quoted, err := p.str()
+	if err != nil {
+		return nil, err
+	}
+	if quoted {
+		return String(str), nil
+	}
+	return scalar(str), nil
+}
+
+// scalar interprets an unquoted token as a number, a boolean or a string.
+func scalar(str string) Tag {
+	switch str {
+	case "true":
+		return Byte(1)
+	case "false":
+		return Byte(0)
+	}
+	body, suffix := str, byte(0)
+	if len(str) > 1 {
+		if c := str[len(str)-1] | 0x20; strings.Contains("bslfd", string(c)) {
+			body, suffix = str[:len(str)-1], c
+		}
+	}
+	switch suffix {
+	case 'b', 's', 'l', 0:
+		if !snbtInt.MatchString(body) {
+			break
+		}
+		v, err := strconv.Atoi64(body)
+		if err != nil {
+			break
+		}
+		switch {
+		case suffix == 'b' && v >= -128 && v <= 127:
+			return Byte(v)
+		case suffix == 's' && v >= -32768 && v <= 32767:
+			return Short(v)
+		case suffix == 'l':
+			return Long(v)
+		case suffix == 0 && v >= -1<<31 && v < 1<<31:
+			return Int(v)
+		}
+	}
+	switch suffix {
+	case 'f', 'd', 0:
+		if !snbtFloat.MatchString(body) || suffix == 0 && !strings.Contains(body, ".") {
+			break
+		}
+		v, err := strconv.Atof64(body)
+		if err != nil {
+			break
+		}
+		if suffix == 'f' {
+			return Float(v)
+		}
+		return Double(v)
+	}
+	return String(str)
+}
+
+func (p *snbtParser) compound() (c *Compound, err os.Error) {
+	p.pos++ // '{'
+	c = new(Compound)
+	if p.peek() == '}' {
+		p.pos++
+		return
+	}
+	for {
+		var name string
+		if name, _, err = p.str(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(':'); err != nil {
+			return nil, err
+		}
+		var tag Tag
+		if tag, err = p.value(); err != nil {
+			return nil, err
+		}
+		c.Set(name, tag)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return
+		default:
+			return nil, p.errorf("Expected ',' or '}'")
+		}
+	}
+	panic("unreachable")
+}
+
+func (p *snbtParser) list() (tag Tag, err os.Error) {
+	p.pos++ // '['
+	p.skipSpace()
+	if p.pos+1 < len(p.s) && p.s[p.pos+1] == ';' {
+		return p.array()
+	}
+	list := &List{ElemType: TagEnd, Tags: []Tag{}}
+	if p.peek() == ']' {
+		p.pos++
+		return list, nil
+	}
+	for {
+		var elem Tag
+		if elem, err = p.value(); err != nil {
+			return nil, err
+		}
+		if len(list.Tags) == 0 {
+			list.ElemType = elem.Type()
+		} else if elem.Type() != list.ElemType {
+			return nil, p.errorf("List elements must have the same type")
+		}
+		list.Tags = append(list.Tags, elem)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return list, nil
+		default:
+			return nil, p.errorf("Expected ',' or ']'")
+		}
+	}
+	panic("unreachable")
+}
+
+// array reads the rest of [B;...], [I;...] or [L;...].
+func (p *snbtParser) array() (tag Tag, err os.Error) {
+	kind := p.s[p.pos]
+	p.pos += 2
+	var elems []Tag
+	if p.peek() == ']' {
+		p.pos++
+	} else {
+		for {
+			var elem Tag
+			if elem, err = p.value(); err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+			if p.peek() == ']' {
+				p.pos++
+				break
+			}
+			if err = p.expect(','); err != nil {
+				return nil, err
+			}
+		}
+	}
+	switch kind {
+	case 'B':
+		arr := make(ByteArray, len(elems))
+		for i, elem := range elems {
+			v, ok := elem.(Byte)
+			if !ok {
+				return nil, p.errorf("Byte array element must be a byte, got: %v", elem)
+			}
+			arr[i] = byte(v)
+		}
+		return arr, nil
+	case 'I':
+		arr := make(IntArray, len(elems))
+		for i, elem := range elems {
+			v, ok := elem.(Int)
+			if !ok {
+				return nil, p.errorf("Int array element must be an int, got: %v", elem)
+			}
+			arr[i] = int32(v)
+		}
+		return arr, nil
+	case 'L':
+		arr := make(LongArray, len(elems))
+		for i, elem := range elems {
+			switch v := elem.(type) {
+			case Long:
+				arr[i] = int64(v)
+			case Int:
+				arr[i] = int64(v)
+			default:
+				return nil, p.errorf("Long array element must be a long, got: %v", elem)
+			}
+		}
+		return arr, nil
+	}
+	return nil, p.errorf("Unknown array type: %c", kind)
+}
+
+// FormatSNBT returns the stringified NBT form of the tag. If indent is empty,
+// the output is compact; otherwise compounds and lists of them are split
+// over several lines, each level indented with indent.
+func FormatSNBT(tag Tag, indent string) string {
+	p := &snbtPrinter{indent: indent}
+	p.print(tag, 0)
+	return p.buf.String()
+}
+
+type snbtPrinter struct {
+	buf    bytes.Buffer
+	indent string
+}
+
+func (p *snbtPrinter) newline(depth int) {
+	if p.indent == "" {
+		return
+	}
+	p.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		p.buf.WriteString(p.indent)
+	}
+}
+
+func (p *snbtPrinter) sep() {
+	p.buf.WriteByte(',')
+	if p.indent != "" {
+		p.buf.WriteByte(' ')
+	}
+}
+
+func quoteSNBT(str string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		if str[i] == '"' || str[i] == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(str[i])
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// snbtKey returns name unquoted if possible.
+func snbtKey(name string) string {
+	if name == "" {
+		return `""`
+	}
+	for i := 0; i < len(name); i++ {
+		if !isUnquoted(name[i]) {
+			return quoteSNBT(name)
+		}
+	}
+	return name
+}
+
+func (p *snbtPrinter) print(tag Tag, depth int) {
+	switch v := tag.(type) {
+	case Byte:
+		fmt.Fprintf(&p.buf, "%db", int(v))
+	case Short:
+		fmt.Fprintf(&p.buf, "%ds", int(v))
+	case Int:
+		fmt.Fprintf(&p.buf, "%d", int(v))
+	case Long:
+		fmt.Fprintf(&p.buf, "%dL", int64(v))
+	case Float:
+		p.buf.WriteString(strconv.Ftoa32(float32(v), 'g', -1) + "f")
+	case Double:
+		p.buf.WriteString(strconv.Ftoa64(float64(v), 'g', -1) + "d")
+	case String:
+		p.buf.WriteString(quoteSNBT(string(v)))
+	case ByteArray:
+		p.buf.WriteString("[B;")
+		for i, x := range v {
+			if i > 0 {
+				p.sep()
+			}
+			fmt.Fprintf(&p.buf, "%db", int(int8(x)))
+		}
+		p.buf.WriteByte(']')
+	case IntArray:
+		p.buf.WriteString("[I;")
+		for i, x := range v {
+			if i > 0 {
+				p.sep()
+			}
+			fmt.Fprintf(&p.buf, "%d", x)
+		}
+		p.buf.WriteByte(']')
+	case LongArray:
+		p.buf.WriteString("[L;")
+		for i, x := range v {
+			if i > 0 {
+				p.sep()
+			}
+			fmt.Fprintf(&p.buf, "%dL", x)
+		}
+		p.buf.WriteByte(']')
+	case *List:
+		nested := v.ElemType == TagCompound || v.ElemType == TagList
+		p.buf.WriteByte('[')
+		for i, elem := range v.Tags {
+			if i > 0 {
+				p.buf.WriteByte(',')
+				if !nested && p.indent != "" {
+					p.buf.WriteByte(' ')
+				}
+			}
+			if nested {
+				p.newline(depth + 1)
+			}
+			p.print(elem, depth+1)
+		}
+		if nested && len(v.Tags) > 0 {
+			p.newline(depth)
+		}
+		p.buf.WriteByte(']')
+	case *Compound:
+		p.buf.WriteByte('{')
+		for i, f := range v.Fields {
+			if i > 0 {
+				p.buf.WriteByte(',')
+			}
+			p.newline(depth + 1)
+			p.buf.WriteString(snbtKey(f.Name))
+			p.buf.WriteByte(':')
+			if p.indent != "" {
+				p.buf.WriteByte(' ')
+			}
+			p.print(f.Tag, depth+1)
+		}
+		if len(v.Fields) > 0 {
+			p.newline(depth)
+		}
+		p.buf.WriteByte('}')
+	}
+}
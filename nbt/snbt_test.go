@@ -0,0 +1,68 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSNBTRoundTrip(t *testing.T) {
+	want := testCompound()
+	for _, indent := range []string{"", "  "} {
+		str := FormatSNBT(want, indent)
+		got, err := ParseSNBT(str)
+		if err != nil {
+			t.Fatalf("ParseSNBT(%q): %v", str, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseSNBT(FormatSNBT(tag, %q)): want %v, got %v", indent, want, got)
+		}
+	}
+}
+
+func TestParseSNBT(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Tag
+	}{
+		{"1b", Byte(1)},
+		{"true", Byte(1)},
+		{"-2S", Short(-2)},
+		{"42", Int(42)},
+		{"3000000000", String("3000000000")},
+		{"7L", Long(7)},
+		{"1.5f", Float(1.5)},
+		{"1.5", Double(1.5)},
+		{"2d", Double(2)},
+		{"stone", String("stone")},
+		{`'it\'s'`, String("it's")},
+		{"[I; 1, -2]", IntArray{1, -2}},
+		{"[B;]", ByteArray{}},
+		{"[]", &List{ElemType: TagEnd, Tags: []Tag{}}},
+		{`{ "a b" : [1s, 2s] }`, &Compound{Fields: []Field{{"a b", &List{ElemType: TagShort, Tags: []Tag{Short(1), Short(2)}}}}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSNBT(tt.in)
+		if err != nil {
+			t.Errorf("ParseSNBT(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSNBT(%q): want %#v, got %#v", tt.in, tt.want, got)
+		}
+	}
+	for _, in := range []string{"{a:1", "[1, 2b]", "{a 1}", `"open`, "[I; 1b]", "1 2"} {
+		if _, err := ParseSNBT(in); err == nil {
+			t.Errorf("ParseSNBT(%q): want error, got nil", in)
+		}
+	}
+}
+
+func TestFormatSNBT(t *testing.T) {
+	c := new(Compound)
+	c.Set("id", String("minecraft:chest"))
+	c.Set("Pos", IntArray{1, 2, 3})
+	want := `{id:"minecraft:chest",Pos:[I;1,2,3]}`
+	if got := FormatSNBT(c, ""); got != want {
+		t.Errorf("FormatSNBT: want %s, got %s", want, got)
+	}
+}
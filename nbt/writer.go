@@ -0,0 +1,168 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package nbt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// A Writer writes NBT data to an uncompressed stream.
+// Call Flush when done to make sure all data reached the underlying writer.
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() os.Error {
+	return w.w.Flush()
+}
+
+// Write writes raw bytes, such as the elements of a byte array whose tag
+// name and length were written before.
+func (w *Writer) Write(p []byte) (n int, err os.Error) {
+	return w.w.Write(p)
+}
+
+func (w *Writer) WriteByte(b byte) os.Error {
+	return w.w.WriteByte(b)
+}
+
+func (w *Writer) WriteShort(val int) (err os.Error) {
+	_, err = w.w.Write([]byte{byte(val >> 8), byte(val)}) // Big Endian
+	return
+}
+
+func (w *Writer) WriteInt(val int) (err os.Error) {
+	_, err = w.w.Write([]byte{byte(val >> 24), byte(val >> 16), byte(val >> 8), byte(val)})
+	return
+}
+
+func (w *Writer) WriteLong(val int64) (err os.Error) {
+	buf := [8]byte{}
+	for i := 7; i >= 0; i-- {
+		buf[i] = byte(val)
+		val >>= 8
+	}
+	_, err = w.w.Write(buf[:])
+	return
+}
+
+func (w *Writer) WriteString(str string) (err os.Error) {
+	if len(str) > math.MaxUint16 {
+		return fmt.Errorf("String is too long: %d bytes", len(str))
+	}
+	if err = w.WriteShort(len(str)); err != nil {
+		return
+	}
+	_, err = w.w.WriteString(str)
+	return
+}
+
+func (w *Writer) WriteByteArray(data []byte) (err os.Error) {
+	if err = w.WriteInt(len(data)); err != nil {
+		return
+	}
+	_, err = w.w.Write(data)
+	return
+}
+
+func (w *Writer) WriteTagName(typ byte, name string) (err os.Error) {
+	if err = w.WriteByte(typ); err != nil {
+		return
+	}
+	return w.WriteString(name)
+}
+
+// WriteTag writes a complete named tag.
+func (w *Writer) WriteTag(name string, tag Tag) (err os.Error) {
+	if err = w.WriteTagName(tag.Type(), name); err != nil {
+		return
+	}
+	return w.WriteValue(tag)
+}
+
+// WriteValue writes the payload of the tag.
+func (w *Writer) WriteValue(tag Tag) (err os.Error) {
+	switch v := tag.(type) {
+	case Byte:
+		err = w.WriteByte(byte(v))
+	case Short:
+		err = w.WriteShort(int(v))
+	case Int:
+		err = w.WriteInt(int(v))
+	case Long:
+		err = w.WriteLong(int64(v))
+	case Float:
+		err = w.WriteInt(int(math.Float32bits(float32(v))))
+	case Double:
+		err = w.WriteLong(int64(math.Float64bits(float64(v))))
+	case ByteArray:
+		err = w.WriteByteArray(v)
+	case String:
+		err = w.WriteString(string(v))
+	case IntArray:
+		if err = w.WriteInt(len(v)); err != nil {
+			return
+		}
+		for _, x := range v {
+			if err = w.WriteInt(int(x)); err != nil {
+				return
+			}
+		}
+	case LongArray:
+		if err = w.WriteInt(len(v)); err != nil {
+			return
+		}
+		for _, x := range v {
+			if err = w.WriteLong(x); err != nil {
+				return
+			}
+		}
+	case *List:
+		err = w.WriteList(v)
+	case *Compound:
+		err = w.WriteCompound(v)
+	default:
+		err = fmt.Errorf("Unsupported tag: %T", tag)
+	}
+	return
+}
+
+// WriteList writes the payload of a list tag.
+func (w *Writer) WriteList(list *List) (err os.Error) {
+	if err = w.WriteByte(list.ElemType); err != nil {
+		return
+	}
+	if err = w.WriteInt(len(list.Tags)); err != nil {
+		return
+	}
+	for _, tag := range list.Tags {
+		if tag.Type() != list.ElemType {
+			return fmt.Errorf("List element has type %d, want: %d", tag.Type(), list.ElemType)
+		}
+		if err = w.WriteValue(tag); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WriteCompound writes the payload of a compound tag, including the trailing TagEnd.
+func (w *Writer) WriteCompound(c *Compound) (err os.Error) {
+	for _, f := range c.Fields {
+		if err = w.WriteTag(f.Name, f.Tag); err != nil {
+			return
+		}
+	}
+	return w.WriteByte(TagEnd)
+}
@@ -0,0 +1,59 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// Directions lists the six directions in the order of their values.
+var Directions = []Direction{East, South, West, North, Up, Down}
+
+// A Neighbor is a block sharing a face with another one.
+type Neighbor struct {
+	X, Y, Z int
+	Face    Direction // the shared face, as seen from the other block
+}
+
+// Pos returns the position of the neighbor.
+func (n Neighbor) Pos() BlockPos {
+	return BlockPos{n.X, n.Y, n.Z}
+}
+
+// Neighbors6 returns the six blocks sharing a face with the block at
+// (x, y, z), in the order of Directions. Some of them may be outside of
+// the schematic.
+func Neighbors6(x, y, z int) (n [6]Neighbor) {
+	for i, d := range Directions {
+		dx, dy, dz := d.Step()
+		n[i] = Neighbor{x + dx, y + dy, z + dz, d}
+	}
+	return
+}
+
+// IsExposed reports whether the block at (x, y, z) is not air and has a
+// face next to air or to the outside of the schematic, that is, whether
+// it can be seen from somewhere.
+func (s *Schematic) IsExposed(x, y, z int) bool {
+	if s.GetV(x, y, z) == 0 {
+		return false
+	}
+	for _, n := range Neighbors6(x, y, z) {
+		if s.GetV(n.X, n.Y, n.Z) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ExposedFaces returns the faces of the block at (x, y, z) next to air or
+// to the outside of the schematic, in the order of Directions. Air has no
+// exposed faces.
+func (s *Schematic) ExposedFaces(x, y, z int) (faces []Direction) {
+	if s.GetV(x, y, z) == 0 {
+		return nil
+	}
+	for _, n := range Neighbors6(x, y, z) {
+		if s.GetV(n.X, n.Y, n.Z) == 0 {
+			faces = append(faces, n.Face)
+		}
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package schematic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNeighbors6(t *testing.T) {
+	n := Neighbors6(1, 2, 3)
+	want := [6]Neighbor{{2, 2, 3, East}, {1, 2, 4, South}, {0, 2, 3, West}, {1, 2, 2, North}, {1, 3, 3, Up}, {1, 1, 3, Down}}
+	if n != want {
+		t.Errorf("Neighbors6(1, 2, 3): want %v, got %v", want, n)
+	}
+	for _, d := range Directions {
+		dx, dy, dz := d.Step()
+		ox, oy, oz := d.Opposite().Step()
+		if dx+ox != 0 || dy+oy != 0 || dz+oz != 0 {
+			t.Errorf("%v and %v are not opposite", d, d.Opposite())
+		}
+	}
+}
+
+func TestExposed(t *testing.T) {
+	s := NewSchematic(3, 3, 3)
+	s.Fill(RegionMask(Box{0, 0, 0, 3, 3, 3}), Block{1, 0})
+	if s.IsExposed(1, 1, 1) {
+		t.Errorf("IsExposed of the center of a cube: want false")
+	}
+	if got, want := s.ExposedFaces(1, 0, 0), []Direction{North, Down}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExposedFaces(1, 0, 0): want %v, got %v", want, got)
+	}
+	s.SetBlock(1, 2, 1, Block{})
+	if !s.IsExposed(1, 1, 1) || !reflect.DeepEqual(s.ExposedFaces(1, 1, 1), []Direction{Up}) {
+		t.Errorf("Center below a hole: exposed %v, faces %v", s.IsExposed(1, 1, 1), s.ExposedFaces(1, 1, 1))
+	}
+	if s.IsExposed(1, 2, 1) || s.ExposedFaces(1, 2, 1) != nil {
+		t.Errorf("Air is exposed")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"math"
+	"rand"
+)
+
+// Noise is a seeded three dimensional Perlin noise function, the basis of
+// the procedural generators.
+type Noise struct {
+	perm [512]int
+}
+
+// NewNoise returns the noise function for the seed.
+func NewNoise(seed int64) *Noise {
+	n := new(Noise)
+	p := rand.New(rand.NewSource(seed)).Perm(256)
+	for i := range n.perm {
+		n.perm[i] = p[i&255]
+	}
+	return n
+}
+
+// At returns the noise at the point, a value between -1 and 1 which
+// changes smoothly with the coordinates and is 0 at integer points.
+func (n *Noise) At(x, y, z float64) float64 {
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	X, Y, Z := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+	u, v, w := fade(x), fade(y), fade(z)
+	p := &n.perm
+	a := p[X] + Y
+	aa, ab := p[a]+Z, p[a+1]+Z
+	b := p[X+1] + Y
+	ba, bb := p[b]+Z, p[b+1]+Z
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(p[aa], x, y, z), grad(p[ba], x-1, y, z)),
+			lerp(u, grad(p[ab], x, y-1, z), grad(p[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(p[aa+1], x, y, z-1), grad(p[ba+1], x-1, y, z-1)),
+			lerp(u, grad(p[ab+1], x, y-1, z-1), grad(p[bb+1], x-1, y-1, z-1))))
+}
+
+// Octaves sums the given number of octaves of the noise, each of twice the
+// frequency and persistence times the amplitude of the previous one. The
+// result is scaled back to the range from -1 to 1.
+func (n *Noise) Octaves(x, y, z float64, octaves int, persistence float64) float64 {
+	var sum, max float64
+	amp := 1.0
+	for i := 0; i < octaves; i++ {
+		sum += amp * n.At(x, y, z)
+		max += amp
+		amp *= persistence
+		x, y, z = x*2, y*2, z*2
+	}
+	if max == 0 {
+		return 0
+	}
+	return sum / max
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// grad returns the dot product of (x, y, z) with one of the twelve
+// gradient directions of the improved Perlin noise.
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u, v := x, y
+	if h >= 8 {
+		u = y
+	}
+	if h >= 4 {
+		v = z
+		if h == 12 || h == 14 {
+			v = x
+		}
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// A NoiseMask selects the blocks where the noise scaled by Scale blocks is
+// above Threshold. Combined with Fill, it spreads variations of a material
+// in natural looking patches.
+type NoiseMask struct {
+	Noise     *Noise
+	Scale     float64 // the size of the patches in blocks
+	Threshold float64 // from -1 to 1; 0 selects about half of the blocks
+}
+
+// Test reports whether the noise at the block is above the threshold.
+func (m *NoiseMask) Test(s *Schematic, x, y, z int) bool {
+	return m.Noise.Octaves(float64(x)/m.Scale, float64(y)/m.Scale, float64(z)/m.Scale, 2, 0.5) > m.Threshold
+}
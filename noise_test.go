@@ -0,0 +1,56 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	n := NewNoise(42)
+	if v := n.At(3, 4, 5); v != 0 {
+		t.Errorf("At(3, 4, 5): want 0, got %f", v)
+	}
+	var min, max float64
+	for i := 0; i < 1000; i++ {
+		x, y, z := float64(i)*0.37, float64(i%17)*0.51, float64(i%29)*0.23
+		v := n.Octaves(x, y, z, 3, 0.5)
+		if v < -1 || v > 1 {
+			t.Fatalf("Octaves(%f, %f, %f): %f out of range", x, y, z, v)
+		}
+		min, max = fmin(min, v), fmax(max, v)
+		if d := abs(n.At(x, y, z) - n.At(x+0.01, y, z)); d > 0.05 {
+			t.Errorf("At(%f, %f, %f): jump of %f", x, y, z, d)
+		}
+	}
+	if min > -0.2 || max < 0.2 {
+		t.Errorf("Octaves: values from %f to %f, want a wider range", min, max)
+	}
+	if NewNoise(42).At(1.5, 2.5, 3.5) != n.At(1.5, 2.5, 3.5) {
+		t.Errorf("The noise with the same seed differs")
+	}
+	if NewNoise(43).At(1.5, 2.5, 3.5) == n.At(1.5, 2.5, 3.5) {
+		t.Errorf("The noise with another seed is the same")
+	}
+}
+
+func TestNoiseMask(t *testing.T) {
+	s := NewSchematic(32, 8, 32)
+	s.Fill(IdMask(0), Block{1, 0})
+	n := s.Fill(&NoiseMask{Noise: NewNoise(1), Scale: 8}, Block{1, 5})
+	if total := len(s.Blocks); n < total/4 || n > total*3/4 {
+		t.Errorf("NoiseMask: want about half of %d blocks, got %d", total, n)
+	}
+}
+
+func fmin(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func fmax(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,81 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// The arrays written by WriteNpyBlocks and WriteNpyData have the shape
+// (Height, Length, Width) in C order, which is the order of Blocks, so in
+// NumPy a[y, z, x] is the value of the block (x, y, z).
+
+// WriteNpyBlocks writes the block ids of s as a NumPy .npy array of uint8,
+// or of uint16 if there are ids above 255.
+func WriteNpyBlocks(w io.Writer, s *Schematic) os.Error {
+	if s.packAdd() == nil {
+		return writeNpy(w, s, "|u1", s.Blocks)
+	}
+	ids := make([]byte, 2*len(s.Blocks))
+	for i := range s.Blocks {
+		id := s.id(i)
+		ids[2*i], ids[2*i+1] = byte(id), byte(id>>8)
+	}
+	return writeNpy(w, s, "<u2", ids)
+}
+
+// WriteNpyData writes the data values of s as a NumPy .npy array of uint8.
+func WriteNpyData(w io.Writer, s *Schematic) os.Error {
+	return writeNpy(w, s, "|u1", s.Data)
+}
+
+// writeNpy writes an array of the size of s in the .npy format version 1.0.
+func writeNpy(w io.Writer, s *Schematic, descr string, values []byte) os.Error {
+	if err := s.checkSize(); err != nil {
+		return err
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d, %d), }", descr, s.Height, s.Length, s.Width)
+	// The header is padded with spaces and ends with a newline, so that
+	// the data starts at a multiple of 64 bytes.
+	const prefix = 10
+	pad := 64 - (prefix+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += strings.Repeat(" ", pad) + "\n"
+	if len(header) > 65535 {
+		return os.NewError("NumPy header is too long")
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("\x93NUMPY\x01\x00")
+	bw.WriteByte(byte(len(header)))
+	bw.WriteByte(byte(len(header) >> 8))
+	bw.WriteString(header)
+	bw.Write(values)
+	return bw.Flush()
+}
+
+// WriteNpz writes the block ids and the data values of s as a NumPy .npz
+// archive of the arrays "blocks" and "data", which numpy.load reads.
+func WriteNpz(w io.Writer, s *Schematic) (err os.Error) {
+	zw := zip.NewWriter(w)
+	for _, a := range []struct {
+		name  string
+		write func(io.Writer, *Schematic) os.Error
+	}{{"blocks.npy", WriteNpyBlocks}, {"data.npy", WriteNpyData}} {
+		var f io.Writer
+		if f, err = zw.Create(a.name); err != nil {
+			return
+		}
+		if err = a.write(f, s); err != nil {
+			return
+		}
+	}
+	return zw.Close()
+}
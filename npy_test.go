@@ -0,0 +1,94 @@
+package schematic
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// parseNpy checks the .npy header and returns the header dictionary and
+// the values.
+func parseNpy(t *testing.T, data []byte) (string, []byte) {
+	if len(data) < 10 || string(data[:8]) != "\x93NUMPY\x01\x00" {
+		t.Fatalf("Bad .npy magic: %q", data[:imin(len(data), 10)])
+	}
+	n := int(data[8]) | int(data[9])<<8
+	if (10+n)%64 != 0 || data[10+n-1] != '\n' {
+		t.Errorf("Header of %d bytes is not aligned or not terminated", n)
+	}
+	return strings.TrimSpace(string(data[10 : 10+n])), data[10+n:]
+}
+
+// readerAt reads from a byte slice at any offset.
+type readerAt []byte
+
+func (r readerAt) ReadAt(p []byte, off int64) (int, os.Error) {
+	if off >= int64(len(r)) {
+		return 0, os.EOF
+	}
+	n := copy(p, r[off:])
+	if n < len(p) {
+		return n, os.EOF
+	}
+	return n, nil
+}
+
+func TestWriteNpy(t *testing.T) {
+	s := NewSchematic(3, 2, 4)
+	s.SetBlock(2, 1, 3, Block{5, 3})
+	var buf bytes.Buffer
+	if err := WriteNpyBlocks(&buf, s); err != nil {
+		t.Fatalf("WriteNpyBlocks: %v", err)
+	}
+	header, values := parseNpy(t, buf.Bytes())
+	if want := "{'descr': '|u1', 'fortran_order': False, 'shape': (2, 4, 3), }"; header != want {
+		t.Errorf("Header: want %s, got %s", want, header)
+	}
+	if !bytes.Equal(values, s.Blocks) {
+		t.Errorf("Values: want %v, got %v", s.Blocks, values)
+	}
+
+	s.SetBlock(0, 0, 1, Block{300, 0})
+	buf.Reset()
+	if err := WriteNpyBlocks(&buf, s); err != nil {
+		t.Fatalf("WriteNpyBlocks: %v", err)
+	}
+	header, values = parseNpy(t, buf.Bytes())
+	if !strings.Contains(header, "'<u2'") || len(values) != 48 {
+		t.Fatalf("Want 24 values of uint16, got %s and %d bytes", header, len(values))
+	}
+	if i := s.Index(0, 0, 1); values[2*i] != 44 || values[2*i+1] != 1 {
+		t.Errorf("Block 300 is written as %v", values[2*i:2*i+2])
+	}
+
+	buf.Reset()
+	if err := WriteNpz(&buf, s); err != nil {
+		t.Fatalf("WriteNpz: %v", err)
+	}
+	zr, err := zip.NewReader(readerAt(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name != "data.npy" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if _, values := parseNpy(t, data); !bytes.Equal(values, s.Data) {
+			t.Errorf("data.npy: want %v, got %v", s.Data, values)
+		}
+	}
+	if strings.Join(names, " ") != "blocks.npy data.npy" {
+		t.Errorf("Archive: got %v", names)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+)
+
+// An Octree summarizes the blocks of a volume in cubes: the root cube
+// covers the whole volume and every cube which is not made of a single
+// kind of block is split into eight. It answers whether a cube is uniform
+// without scanning it, so renderers can draw distant parts at a lower
+// level of detail and exporters can skip large runs of the same block.
+// The blocks outside of the volume are air. The octree is not updated
+// when the volume changes.
+type Octree struct {
+	Size int // the edge of the root cube, a power of two
+	root *octNode
+}
+
+type octNode struct {
+	block    Block       // the block of a uniform cube, or the most common one
+	children *[8]octNode // nil if the cube is uniform
+}
+
+// NewOctree builds the octree of the volume.
+func NewOctree(v Volume) *Octree {
+	size := 1
+	for size < v.XLen() || size < v.YLen() || size < v.ZLen() {
+		size *= 2
+	}
+	t := &Octree{Size: size}
+	t.root = new(octNode)
+	t.build(v, t.root, 0, 0, 0, size)
+	return t
+}
+
+// octSmall is the size of the cubes which are checked for uniformity by
+// scanning before they are split, which saves the nodes of the blocks.
+const octSmall = 8
+
+func (t *Octree) build(v Volume, n *octNode, x, y, z, size int) {
+	if size <= octSmall {
+		first := v.Block(x, y, z)
+		if uniformCube(v, x, y, z, size, first) {
+			n.block = first
+			return
+		}
+	}
+	half := size / 2
+	n.children = new([8]octNode)
+	uniform := true
+	for i := range n.children {
+		c := &n.children[i]
+		cx, cy, cz := octChild(i, x, y, z, half)
+		t.build(v, c, cx, cy, cz, half)
+		uniform = uniform && c.children == nil && c.block == n.children[0].block
+	}
+	if uniform {
+		n.block, n.children = n.children[0].block, nil
+		return
+	}
+	n.block = mostCommon(n.children)
+}
+
+// octChild returns the minimum corner of the i-th eighth of the cube.
+func octChild(i, x, y, z, half int) (int, int, int) {
+	return x + i&1*half, y + i>>1&1*half, z + i>>2&1*half
+}
+
+func uniformCube(v Volume, x0, y0, z0, size int, b Block) bool {
+	for y := y0; y < y0+size; y++ {
+		for z := z0; z < z0+size; z++ {
+			for x := x0; x < x0+size; x++ {
+				if v.Block(x, y, z) != b {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// mostCommon returns the most common of the blocks of the eighths,
+// preferring blocks other than air in a tie.
+func mostCommon(children *[8]octNode) Block {
+	best, bestCount := Block{}, 0
+	for i := range children {
+		b := children[i].block
+		count := 0
+		for j := range children {
+			if children[j].block == b {
+				count++
+			}
+		}
+		if count > bestCount || count == bestCount && best.Id == 0 {
+			best, bestCount = b, count
+		}
+	}
+	return best
+}
+
+// Uniform reports whether the cube of the size with the minimum corner at
+// (x, y, z) is made of a single kind of block, and returns it. The size
+// must be a power of two not above Size and the corner a multiple of it;
+// otherwise Uniform panics.
+func (t *Octree) Uniform(x, y, z, size int) (b Block, ok bool) {
+	if size <= 0 || size&(size-1) != 0 || size > t.Size || x%size != 0 || y%size != 0 || z%size != 0 ||
+		x < 0 || y < 0 || z < 0 || x >= t.Size || y >= t.Size || z >= t.Size {
+		panic(fmt.Sprintf("schematic: Octree.Uniform(%d, %d, %d, %d) is not a cube of the octree", x, y, z, size))
+	}
+	n := t.root
+	for s := t.Size; n.children != nil; s /= 2 {
+		if s == size {
+			return Block{}, false
+		}
+		half := s / 2
+		n = &n.children[(x&half)/half|(y&half)/half<<1|(z&half)/half<<2]
+	}
+	return n.block, true
+}
+
+// Walk visits the cubes of the octree down to the size, a power of two:
+// f is called for every uniform cube of the size or larger, and for every
+// cube of the size which is not uniform, with the most common block of
+// its parts. A size of 1 visits the exact contents.
+func (t *Octree) Walk(size int, f func(x, y, z, size int, b Block, uniform bool)) {
+	t.walk(t.root, 0, 0, 0, t.Size, size, f)
+}
+
+func (t *Octree) walk(n *octNode, x, y, z, s, min int, f func(x, y, z, size int, b Block, uniform bool)) {
+	if n.children == nil || s <= min {
+		f(x, y, z, s, n.block, n.children == nil)
+		return
+	}
+	half := s / 2
+	for i := range n.children {
+		cx, cy, cz := octChild(i, x, y, z, half)
+		t.walk(&n.children[i], cx, cy, cz, half, min, f)
+	}
+}
@@ -0,0 +1,79 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestOctree(t *testing.T) {
+	s := NewSchematic(40, 20, 32)
+	s.Fill(RegionMask(Box{0, 0, 0, 40, 8, 32}), Block{1, 0})
+	s.SetBlock(5, 3, 5, Block{14, 0})
+	tr := NewOctree(s)
+	if tr.Size != 64 {
+		t.Fatalf("Size: want 64, got %d", tr.Size)
+	}
+	tests := []struct {
+		x, y, z, size int
+		want          Block
+		ok            bool
+	}{
+		{0, 0, 0, 64, Block{}, false},
+		{0, 0, 0, 8, Block{1, 0}, false},
+		{8, 0, 0, 8, Block{1, 0}, true},
+		{0, 0, 0, 4, Block{1, 0}, true},
+		{4, 0, 4, 4, Block{1, 0}, false},
+		{5, 3, 5, 1, Block{14, 0}, true},
+		{0, 8, 0, 8, Block{}, true},
+		{32, 0, 0, 8, Block{1, 0}, true},
+		{40, 0, 0, 8, Block{}, true},
+		{32, 32, 32, 32, Block{}, true},
+	}
+	for _, tt := range tests {
+		b, ok := tr.Uniform(tt.x, tt.y, tt.z, tt.size)
+		if ok != tt.ok || ok && b != tt.want {
+			t.Errorf("Uniform(%d, %d, %d, %d): want %v %v, got %v %v", tt.x, tt.y, tt.z, tt.size, tt.want, tt.ok, b, ok)
+		}
+	}
+
+	// Walking down to single blocks covers every block exactly.
+	n := 0
+	tr.Walk(1, func(x, y, z, size int, b Block, uniform bool) {
+		if !uniform {
+			t.Fatalf("Walk(1): a cube of size %d is not uniform", size)
+		}
+		n += size * size * size
+		for dy := 0; dy < size; dy++ {
+			for dz := 0; dz < size; dz++ {
+				for dx := 0; dx < size; dx++ {
+					if got := s.Block(x+dx, y+dy, z+dz); got != b {
+						t.Fatalf("Walk(1): cube at (%d, %d, %d) of size %d is %v, but has %v", x, y, z, size, b, got)
+					}
+				}
+			}
+		}
+	})
+	if n != 64*64*64 {
+		t.Errorf("Walk(1) covered %d blocks", n)
+	}
+	mixed := 0
+	tr.Walk(8, func(x, y, z, size int, b Block, uniform bool) {
+		if size < 8 {
+			t.Errorf("Walk(8) visited a cube of size %d", size)
+		}
+		if !uniform {
+			mixed++
+			if b != (Block{1, 0}) {
+				t.Errorf("Walk(8): the most common block of the mixed cube is %v", b)
+			}
+		}
+	})
+	if mixed != 1 {
+		t.Errorf("Walk(8): want 1 mixed cube, got %d", mixed)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Uniform of an unaligned cube: want a panic")
+		}
+	}()
+	tr.Uniform(4, 0, 0, 8)
+}
@@ -0,0 +1,89 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A Pattern chooses the blocks placed by Fill, like the patterns of WorldEdit.
+type Pattern interface {
+	// BlockAt returns the block to place at the position.
+	BlockAt(x, y, z int) Block
+}
+
+// BlockAt returns b, so that a single block is a pattern.
+func (b Block) BlockAt(x, y, z int) Block {
+	return b
+}
+
+// A RandomPattern places blocks chosen at random with the given weights.
+// The choice depends only on the position and the seed, so filling the
+// same region twice gives the same result.
+type RandomPattern struct {
+	Blocks  []Block
+	Weights []float64 // Weights[i] is the weight of Blocks[i]
+	Seed    int64
+}
+
+// BlockAt returns the block chosen for the position.
+func (p *RandomPattern) BlockAt(x, y, z int) Block {
+	var total float64
+	for _, w := range p.Weights {
+		total += w
+	}
+	r := total * float64(hashPos(p.Seed, x, y, z)>>11) / (1 << 53)
+	for i, w := range p.Weights {
+		if r < w {
+			return p.Blocks[i]
+		}
+		r -= w
+	}
+	return p.Blocks[len(p.Blocks)-1]
+}
+
+// hashPos returns a pseudo-random number for the position.
+func hashPos(seed int64, x, y, z int) uint64 {
+	h := uint64(seed) ^ uint64(int64(x))*0x9e3779b97f4a7c15 ^ uint64(int64(y))*0xc2b2ae3d27d4eb4f ^ uint64(int64(z))*0x165667b19e3779f9
+	// The finalizer of SplitMix64.
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// ParsePattern parses a pattern written in the syntax of WorldEdit: a comma
+// separated list of blocks, each optionally preceded by a weight, such as
+// "50%stone,30%cobblestone,20%andesite". The weights need not add up to 100;
+// a block without a weight has the weight 1. The blocks are written as
+// for ParseMask, and a block without a data value has the data value 0.
+func ParsePattern(str string) (Pattern, os.Error) {
+	p := new(RandomPattern)
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		weight := 1.0
+		if i := strings.Index(item, "%"); i >= 0 {
+			w, err := strconv.Atof64(item[:i])
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("Invalid weight in %s", item)
+			}
+			weight, item = w, item[i+1:]
+		}
+		b, _, err := parseBlockSpec(item)
+		if err != nil {
+			return nil, err
+		}
+		p.Blocks = append(p.Blocks, b)
+		p.Weights = append(p.Weights, weight)
+	}
+	if len(p.Blocks) == 1 {
+		return p.Blocks[0], nil
+	}
+	return p, nil
+}
@@ -0,0 +1,42 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	p, err := ParsePattern("35:14")
+	if err != nil {
+		t.Fatalf("ParsePattern: %v", err)
+	}
+	if b, ok := p.(Block); !ok || b != (Block{35, 14}) {
+		t.Errorf("ParsePattern(35:14): want Block{35, 14}, got %v", p)
+	}
+
+	if p, err = ParsePattern("50%stone, 30%cobblestone,20%andesite"); err != nil {
+		t.Fatalf("ParsePattern: %v", err)
+	}
+	s := NewSchematic(40, 10, 40)
+	if n := s.Fill(IdMask(0), p); n != 16000 {
+		t.Errorf("Fill: want 16000 blocks, got %d", n)
+	}
+	counts := make(map[Block]int)
+	for i := range s.Blocks {
+		counts[Block{uint16(s.Blocks[i]), s.Data[i]}]++
+	}
+	for b, want := range map[Block]int{Block{1, 0}: 8000, Block{4, 0}: 4800, Block{1, 5}: 3200} {
+		if got := counts[b]; got < want*9/10 || got > want*11/10 {
+			t.Errorf("%v: want about %d blocks, got %d", b, want, got)
+		}
+	}
+	// The blocks depend only on the position.
+	if a, b := p.BlockAt(5, 6, 7), p.BlockAt(5, 6, 7); a != b {
+		t.Errorf("BlockAt(5, 6, 7): got %v, then %v", a, b)
+	}
+
+	for _, str := range []string{"", "x%stone", "-5%stone", "50%", "stone,,dirt"} {
+		if _, err = ParsePattern(str); err == nil {
+			t.Errorf("ParsePattern(%q): want error, got nil", str)
+		}
+	}
+}
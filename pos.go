@@ -0,0 +1,79 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+)
+
+// A BlockPos is the position of a block. The coordinates follow Minecraft:
+// X grows to the east, Y up and Z to the south; in a schematic they start
+// at 0 in the minimum corner. Passing a BlockPos instead of three ints
+// keeps the coordinates from being mixed up.
+type BlockPos struct {
+	X, Y, Z int
+}
+
+// Pos returns the position (x, y, z).
+func Pos(x, y, z int) BlockPos {
+	return BlockPos{x, y, z}
+}
+
+func (p BlockPos) String() string {
+	return fmt.Sprintf("(%d, %d, %d)", p.X, p.Y, p.Z)
+}
+
+// Add returns the position p moved by q.
+func (p BlockPos) Add(q BlockPos) BlockPos {
+	return BlockPos{p.X + q.X, p.Y + q.Y, p.Z + q.Z}
+}
+
+// Sub returns the position p moved back by q.
+func (p BlockPos) Sub(q BlockPos) BlockPos {
+	return BlockPos{p.X - q.X, p.Y - q.Y, p.Z - q.Z}
+}
+
+// Step returns the neighbor of p in the direction.
+func (p BlockPos) Step(d Direction) BlockPos {
+	dx, dy, dz := d.Step()
+	return BlockPos{p.X + dx, p.Y + dy, p.Z + dz}
+}
+
+// In reports whether the block is inside of the box.
+func (p BlockPos) In(b Box) bool {
+	return p.X >= b.MinX && p.Y >= b.MinY && p.Z >= b.MinZ && p.X < b.MaxX && p.Y < b.MaxY && p.Z < b.MaxZ
+}
+
+// Min returns the minimum corner of the box.
+func (b Box) Min() BlockPos {
+	return BlockPos{b.MinX, b.MinY, b.MinZ}
+}
+
+// Max returns the position after the maximum corner of the box.
+func (b Box) Max() BlockPos {
+	return BlockPos{b.MaxX, b.MaxY, b.MaxZ}
+}
+
+// At returns the block at p, like Block.
+func (s *Schematic) At(p BlockPos) Block {
+	return s.Block(p.X, p.Y, p.Z)
+}
+
+// SetAt sets the block at p, like SetBlock.
+func (s *Schematic) SetAt(p BlockPos, b Block) {
+	s.SetBlock(p.X, p.Y, p.Z, b)
+}
+
+// IndexOf returns the position of the block at p in Blocks and Data.
+// It panics if p is outside of the schematic.
+func (s *Schematic) IndexOf(p BlockPos) int {
+	return s.Index(p.X, p.Y, p.Z)
+}
+
+// PosOf returns the position of the block stored at the index i of Blocks
+// and Data. It panics if i is out of range.
+func (s *Schematic) PosOf(i int) BlockPos {
+	x, y, z := s.Coords(i)
+	return BlockPos{x, y, z}
+}
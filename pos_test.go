@@ -0,0 +1,55 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestBlockPos(t *testing.T) {
+	p := Pos(1, 2, 3)
+	if q := p.Add(Pos(1, 1, 1)).Sub(Pos(0, 0, 2)); q != Pos(2, 3, 2) {
+		t.Errorf("Add and Sub: got %v", q)
+	}
+	if q := p.Step(North).Step(Up); q != Pos(1, 3, 2) {
+		t.Errorf("Step: got %v", q)
+	}
+	for _, n := range Neighbors6(p.X, p.Y, p.Z) {
+		if n.Pos() != p.Step(n.Face) {
+			t.Errorf("Neighbor %v: want %v, got %v", n.Face, p.Step(n.Face), n.Pos())
+		}
+	}
+	if p.String() != "(1, 2, 3)" {
+		t.Errorf("String: got %s", p)
+	}
+	b := Box{0, 0, 0, 2, 3, 4}
+	if !p.In(b) || Pos(2, 0, 0).In(b) || b.Min() != Pos(0, 0, 0) || b.Max() != Pos(2, 3, 4) {
+		t.Errorf("In, Min or Max is wrong")
+	}
+
+	s := NewSchematic(2, 3, 4)
+	s.SetAt(p, Block{5, 1})
+	if got := s.At(p); got != (Block{5, 1}) {
+		t.Errorf("At: got %v", got)
+	}
+	for i := range s.Blocks {
+		if q := s.PosOf(i); s.IndexOf(q) != i {
+			t.Errorf("IndexOf(PosOf(%d)) = %d", i, s.IndexOf(q))
+		}
+	}
+	if i := s.IndexOf(p); s.Blocks[i] != 5 || s.Data[i] != 1 {
+		t.Errorf("IndexOf%v = %d, wrong block", p, i)
+	}
+	for _, f := range []func(){
+		func() { s.IndexOf(Pos(2, 0, 0)) },
+		func() { s.PosOf(24) },
+		func() { s.PosOf(-1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Want a panic out of range")
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+// The last field number of the Volume message.
+const protoLastField = 9
+
+// ToProto encodes s as the Volume message defined in schematic.proto.
+// Tile ticks, tile entities and the tags in s.Extra are not part of the message.
+func ToProto(s *Schematic) (data []byte, err os.Error) {
+	if err = s.checkSize(); err != nil {
+		return
+	}
+	var palette []uint64
+	index := make(map[uint64]uint64)
+	blocks := new(protoBuffer)
+	for i := range s.Blocks {
+		key := uint64(s.id(i))<<4 | uint64(s.Data[i]&15)
+		idx, ok := index[key]
+		if !ok {
+			idx = uint64(len(palette))
+			index[key] = idx
+			palette = append(palette, key)
+		}
+		blocks.varint(idx)
+	}
+	pal := new(protoBuffer)
+	for _, key := range palette {
+		pal.varint(key)
+	}
+	b := new(protoBuffer)
+	b.field(1, wireVarint)
+	b.varint(uint64(s.Width))
+	b.field(2, wireVarint)
+	b.varint(uint64(s.Height))
+	b.field(3, wireVarint)
+	b.varint(uint64(s.Length))
+	b.bytesField(4, pal.Bytes())
+	b.bytesField(5, blocks.Bytes())
+	for _, e := range s.Entities {
+		var tag bytes.Buffer
+		w := nbt.NewWriter(&tag)
+		if err = w.WriteTag("", e.compound()); err != nil {
+			return
+		}
+		if err = w.Flush(); err != nil {
+			return
+		}
+		msg := new(protoBuffer)
+		msg.bytesField(1, []byte(e.Id))
+		msg.bytesField(2, tag.Bytes())
+		b.bytesField(6, msg.Bytes())
+	}
+	for i, v := range []int{s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ} {
+		if v != 0 {
+			b.field(7+i, wireVarint)
+			b.varint(uint64(int64(v)<<1 ^ int64(v)>>63)) // zigzag
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// FromProto decodes a Volume message, as written by ToProto or another
+// protocol buffer encoder. The repeated fields may be packed, split into
+// several packed records or not packed at all. Unknown fields are skipped.
+func FromProto(data []byte) (s *Schematic, err os.Error) {
+	s = new(Schematic)
+	var palette, blocks []uint64
+	p := &protoParser{data: data}
+	for !p.done() {
+		var num, wire int
+		if num, wire, err = p.field(); err != nil {
+			return nil, err
+		}
+		var v uint64
+		var b []byte
+		switch wire {
+		case wireVarint:
+			v, err = p.varint()
+		case wireBytes:
+			b, err = p.bytes()
+		default:
+			if err = p.skip(num, wire); err == nil && num <= protoLastField {
+				err = fmt.Errorf("Unexpected wire type %d of field %d", wire, num)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch num {
+		case 1:
+			s.Width = int(v)
+		case 2:
+			s.Height = int(v)
+		case 3:
+			s.Length = int(v)
+		case 4:
+			palette, err = appendVarints(palette, wire, v, b)
+		case 5:
+			blocks, err = appendVarints(blocks, wire, v, b)
+		case 6:
+			var e Entity
+			e, err = protoEntity(b)
+			s.Entities = append(s.Entities, e)
+		case 7, 8, 9:
+			off := int(int64(v>>1) ^ -int64(v&1))
+			switch num {
+			case 7:
+				s.WEOffsetX = off
+			case 8:
+				s.WEOffsetY = off
+			case 9:
+				s.WEOffsetZ = off
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = checkDimensions(s.Width, s.Height, s.Length); err != nil {
+		return nil, err
+	}
+	n := s.Width * s.Height * s.Length
+	if len(blocks) != n {
+		return nil, &SizeError{Field: "Blocks", Want: n, Got: len(blocks)}
+	}
+	s.Blocks = make([]byte, n)
+	s.Data = make([]byte, n)
+	for i, idx := range blocks {
+		if idx >= uint64(len(palette)) {
+			return nil, fmt.Errorf("Palette index out of range: %d", idx)
+		}
+		if id := palette[idx] >> 4; id > MaxId {
+			return nil, fmt.Errorf("Block id %d is above %d", id, MaxId)
+		}
+		s.setId(i, uint16(palette[idx]>>4))
+		s.Data[i] = byte(palette[idx] & 15)
+	}
+	return
+}
+
+func protoEntity(data []byte) (e Entity, err os.Error) {
+	p := &protoParser{data: data}
+	for !p.done() {
+		var num, wire int
+		if num, wire, err = p.field(); err != nil {
+			return
+		}
+		if wire != wireBytes {
+			if err = p.skip(num, wire); err == nil && num <= 2 {
+				err = fmt.Errorf("Unexpected wire type %d of entity field %d", wire, num)
+			}
+			if err != nil {
+				return
+			}
+			continue
+		}
+		var b []byte
+		if b, err = p.bytes(); err != nil {
+			return
+		}
+		switch num {
+		case 1:
+			e.Id = string(b)
+		case 2:
+			var tag nbt.Tag
+			if _, tag, err = nbt.NewReader(bytes.NewBuffer(b)).ReadTag(); err != nil {
+				return
+			}
+			var ok bool
+			if e.NBT, ok = tag.(*nbt.Compound); !ok {
+				err = os.NewError("Entity NBT must be a compound")
+				return
+			}
+		}
+	}
+	return
+}
+
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	b.WriteByte(byte(v))
+}
+
+func (b *protoBuffer) field(num, wire int) {
+	b.varint(uint64(num<<3 | wire))
+}
+
+func (b *protoBuffer) bytesField(num int, data []byte) {
+	b.field(num, wireBytes)
+	b.varint(uint64(len(data)))
+	b.Write(data)
+}
+
+type protoParser struct {
+	data []byte
+	pos  int
+}
+
+var errProtoTruncated = os.NewError("Truncated protocol buffer")
+
+func (p *protoParser) done() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *protoParser) varint() (v uint64, err os.Error) {
+	for shift := uint(0); shift < 64; shift += 7 {
+		if p.done() {
+			return 0, errProtoTruncated
+		}
+		c := p.data[p.pos]
+		p.pos++
+		v |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, os.NewError("Varint overflow")
+}
+
+func (p *protoParser) field() (num, wire int, err os.Error) {
+	var v uint64
+	if v, err = p.varint(); err != nil {
+		return
+	}
+	return int(v >> 3), int(v & 7), nil
+}
+
+func (p *protoParser) bytes() (b []byte, err os.Error) {
+	var n uint64
+	if n, err = p.varint(); err != nil {
+		return
+	}
+	if n > uint64(len(p.data)-p.pos) {
+		return nil, errProtoTruncated
+	}
+	b = p.data[p.pos : p.pos+int(n)]
+	p.pos += int(n)
+	return
+}
+
+// skip skips the value of a field with the number and the wire type, which
+// have already been read.
+func (p *protoParser) skip(num, wire int) (err os.Error) {
+	switch wire {
+	case wireVarint:
+		_, err = p.varint()
+	case wireFixed64:
+		err = p.advance(8)
+	case wireBytes:
+		_, err = p.bytes()
+	case wireFixed32:
+		err = p.advance(4)
+	case wireStartGroup:
+		for {
+			var n, w int
+			if n, w, err = p.field(); err != nil {
+				return
+			}
+			if w == wireEndGroup {
+				if n != num {
+					return fmt.Errorf("Unmatched end of group %d in group %d", n, num)
+				}
+				return nil
+			}
+			if err = p.skip(n, w); err != nil {
+				return
+			}
+		}
+	default:
+		err = fmt.Errorf("Invalid wire type %d of field %d", wire, num)
+	}
+	return
+}
+
+func (p *protoParser) advance(n int) os.Error {
+	if n > len(p.data)-p.pos {
+		return errProtoTruncated
+	}
+	p.pos += n
+	return nil
+}
+
+// appendVarints appends an occurrence of a repeated varint field to res:
+// the value v if the field is not packed, or the values packed in data.
+func appendVarints(res []uint64, wire int, v uint64, data []byte) ([]uint64, os.Error) {
+	if wire == wireVarint {
+		return append(res, v), nil
+	}
+	p := &protoParser{data: data}
+	for !p.done() {
+		v, err := p.varint()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
@@ -0,0 +1,121 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProto(t *testing.T) {
+	vol := readTestSchematic(t, "testdata/cylinder.schematic")
+	vol.WEOffsetX, vol.WEOffsetY, vol.WEOffsetZ = -64, 0, 300
+	vol.Data[5] = 7
+	vol.Entities = []Entity{{Id: "Pig"}}
+	data, err := ToProto(vol)
+	if err != nil {
+		t.Fatalf("ToProto: %v", err)
+	}
+	got, err := FromProto(data)
+	if err != nil {
+		t.Fatalf("FromProto: %v", err)
+	}
+	if got.Width != vol.Width || got.Height != vol.Height || got.Length != vol.Length {
+		t.Errorf("Dimensions: want %dx%dx%d, got %dx%dx%d", vol.Width, vol.Height, vol.Length, got.Width, got.Height, got.Length)
+	}
+	if !bytes.Equal(got.Blocks, vol.Blocks) || !bytes.Equal(got.Data, vol.Data) {
+		t.Errorf("Blocks or Data differ after round trip")
+	}
+	if got.WEOffsetX != -64 || got.WEOffsetY != 0 || got.WEOffsetZ != 300 {
+		t.Errorf("Offsets: want (-64, 0, 300), got (%d, %d, %d)", got.WEOffsetX, got.WEOffsetY, got.WEOffsetZ)
+	}
+	if len(got.Entities) != 1 || got.Entities[0].Id != "Pig" || got.Entities[0].NBT.Get("id") == nil {
+		t.Errorf("Entities: got %+v", got.Entities)
+	}
+	if _, err = FromProto(data[:len(data)/2]); err == nil {
+		t.Errorf("FromProto of truncated data: want error, got nil")
+	}
+}
+
+func TestFromProtoRepeated(t *testing.T) {
+	// The palette is split into two packed records and the blocks are
+	// partly unpacked, as other encoders may write them.
+	b := new(protoBuffer)
+	for num, v := range map[int]uint64{1: 2, 2: 1, 3: 2} {
+		b.field(num, wireVarint)
+		b.varint(v)
+	}
+	for _, key := range []uint64{1 << 4, 35<<4 | 14} {
+		pal := new(protoBuffer)
+		pal.varint(key)
+		b.bytesField(4, pal.Bytes())
+	}
+	for _, idx := range []uint64{0, 1, 1} {
+		b.field(5, wireVarint)
+		b.varint(idx)
+	}
+	rest := new(protoBuffer)
+	rest.varint(0)
+	b.bytesField(5, rest.Bytes())
+	s, err := FromProto(b.Bytes())
+	if err != nil {
+		t.Fatalf("FromProto: %v", err)
+	}
+	red := Block{35, 14}
+	if s.Block(0, 0, 0) != (Block{1, 0}) || s.Block(1, 0, 0) != red || s.Block(0, 0, 1) != red || s.Block(1, 0, 1) != (Block{1, 0}) {
+		t.Errorf("FromProto: got %v %v %v %v", s.Block(0, 0, 0), s.Block(1, 0, 0), s.Block(0, 0, 1), s.Block(1, 0, 1))
+	}
+}
+
+func TestFromProtoUnknownFields(t *testing.T) {
+	b := new(protoBuffer)
+	for _, num := range []int{1, 2, 3} {
+		b.field(num, wireVarint)
+		b.varint(1)
+	}
+	pal := new(protoBuffer)
+	pal.varint(1 << 4)
+	b.bytesField(4, pal.Bytes())
+	b.field(5, wireVarint)
+	b.varint(0)
+	// Fields added by a later version of the format.
+	b.field(10, wireFixed64)
+	b.Write(make([]byte, 8))
+	b.field(11, wireFixed32)
+	b.Write(make([]byte, 4))
+	b.field(12, wireVarint)
+	b.varint(300)
+	b.bytesField(13, []byte("new"))
+	b.field(14, wireStartGroup)
+	b.field(1, wireFixed32)
+	b.Write(make([]byte, 4))
+	b.field(14, wireEndGroup)
+	entity := new(protoBuffer)
+	entity.bytesField(1, []byte("Pig"))
+	entity.field(3, wireFixed32)
+	entity.Write(make([]byte, 4))
+	b.bytesField(6, entity.Bytes())
+	s, err := FromProto(b.Bytes())
+	if err != nil {
+		t.Fatalf("FromProto: %v", err)
+	}
+	if s.Block(0, 0, 0) != (Block{1, 0}) || len(s.Entities) != 1 || s.Entities[0].Id != "Pig" {
+		t.Errorf("FromProto: got %v, entities %v", s.Block(0, 0, 0), s.Entities)
+	}
+
+	truncated := append(b.Bytes(), 10<<3|wireFixed64, 0, 0)
+	if _, err = FromProto(truncated); err == nil {
+		t.Errorf("FromProto of a truncated fixed64: want error, got nil")
+	}
+}
+
+func TestFromProtoSize(t *testing.T) {
+	for _, size := range [][3]uint64{{1 << 40, 1 << 40, 1 << 40}, {1 << 63, 2, 1}, {MaxDimension + 1, 1, 1}, {60000, 60000, 60000}} {
+		b := new(protoBuffer)
+		for i, v := range size {
+			b.field(i+1, wireVarint)
+			b.varint(v)
+		}
+		if _, err := FromProto(b.Bytes()); err == nil {
+			t.Errorf("FromProto of size %v: want error, got nil", size)
+		}
+	}
+}
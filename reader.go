@@ -4,29 +4,52 @@
 package schematic
 
 import (
-	"bufio"
-	"compress/gzip"
+	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"os"
+
+	"github.com/krasin/schematic/nbt"
 )
 
+// An Entity is a mob, an item or another non-block object in the schematic.
+type Entity struct {
+	Id  string
+	NBT *nbt.Compound // the complete entity compound, including Id
+}
+
+// Materials identifies the block set used by a schematic.
+type Materials int
+
 const (
-	tagEnd       = 0
-	tagByte      = 1
-	tagShort     = 2
-	tagInt       = 3
-	tagLong      = 4
-	tagFloat     = 5
-	tagDouble    = 6
-	tagByteArray = 7
-	tagString    = 8
-	tagList      = 9
-	tagCompound  = 10
+	Alpha   Materials = iota // Minecraft Alpha and later
+	Classic                  // Minecraft Classic
+	Pocket                   // Minecraft Pocket Edition
 )
 
-type Entity struct {
-	Id string
+var materialsNames = []string{
+	Alpha:   "Alpha",
+	Classic: "Classic",
+	Pocket:  "Pocket",
+}
+
+// String returns the value of the Materials tag for m.
+func (m Materials) String() string {
+	if m < 0 || int(m) >= len(materialsNames) {
+		return fmt.Sprintf("Materials(%d)", int(m))
+	}
+	return materialsNames[m]
+}
+
+// parseMaterials returns the Materials value named by str.
+func parseMaterials(str string) (m Materials, err os.Error) {
+	for i, name := range materialsNames {
+		if name == str {
+			return Materials(i), nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown Materials value: '%s'", str)
 }
 
 // A Schematic contains the data from .schematic file and is returned by ReadSchematic.
@@ -37,22 +60,180 @@ type Schematic struct {
 	WEOffsetX int
 	WEOffsetY int
 	WEOffsetZ int
-	Materials string
+	Materials Materials
 	Blocks    []byte
 	Data      []byte
 	Entities  []Entity
+	TileTicks []TileTick
+
+	// TileEntities are looked up by position with TileEntityAt.
+	TileEntities []TileEntity
+
+	// Extra holds the tags of the Schematic compound which are not
+	// represented by the fields above. WriteSchematic writes them back.
+	Extra *nbt.Compound
+
+	tileIndex map[int]int // block index -> position in TileEntities
+	tileCount int         // the length of TileEntities when tileIndex was built
+
+	// add holds the high bits of the block ids, one byte per block, or is
+	// nil if all ids fit into Blocks. It is read from and written to
+	// the AddBlocks tag.
+	add []byte
+
+	snapshots []*Snapshot // oldest first
+	trackers  []*ChangeTracker
+	listeners []*changeListener
+}
+
+// MaxId is the largest block id a schematic can hold. The high bits of the
+// ids above 255 are stored in the AddBlocks tag, 4 bits per block, as written by
+// WorldEdit and Schematica for modded blocks.
+const MaxId = 4095
+
+// MaxDimension is the largest width, height or length of a schematic
+// file, which stores them as unsigned shorts.
+const MaxDimension = math.MaxUint16
+
+// checkDimensions verifies that a schematic of the size can be written:
+// every dimension fits into MaxDimension and the number of blocks into the
+// length of an NBT byte array.
+func checkDimensions(width, height, length int) os.Error {
+	for _, d := range []int{width, height, length} {
+		if d < 0 || d > MaxDimension {
+			return fmt.Errorf("Invalid schematic size: %dx%dx%d", width, height, length)
+		}
+	}
+	if size := int64(width) * int64(height) * int64(length); size > math.MaxInt32 {
+		return fmt.Errorf("Schematic is too large: %d blocks", size)
+	}
+	return nil
+}
+
+// A SizeError is returned by ReadSchematic when the length of the Blocks
+// or Data array does not match Width*Height*Length.
+type SizeError struct {
+	Field string // "Blocks" or "Data"
+	Want  int    // Width*Height*Length
+	Got   int    // actual length of the array
+}
+
+func (e *SizeError) String() string {
+	return fmt.Sprintf("%s size mismatch: want %d, got %d", e.Field, e.Want, e.Got)
+}
+
+// ReadOptions control the decoding of a schematic.
+// A nil *ReadOptions is equivalent to the zero value.
+type ReadOptions struct {
+	// Strict requires the input to end right after the Schematic tag.
+	// By default, the data following the tag is not read, so trailing
+	// garbage and a broken checksum of the last gzip member are ignored.
+	// Concatenated gzip members are accepted in both modes.
+	Strict bool
+
+	// Remap, if not nil, converts the ids of schematics saved by the
+	// Schematica mod to this mapping. See Schematic.Remap.
+	Remap IdMapping
+
+	// Lenient accepts Blocks and Data arrays not matching the dimensions.
+	// Such schematics must be fixed with Schematic.Repair before use.
+	Lenient bool
+
+	// MaxSize, if positive, limits the decompressed NBT stream to this
+	// number of bytes, so that a small compressed input cannot expand
+	// into a huge schematic.
+	MaxSize int64
 }
 
 // ReadSchematic reads .schematic file from the input.
+// The compression of the file is detected automatically.
 func ReadSchematic(input io.Reader) (vol *Schematic, err os.Error) {
+	return ReadSchematicOptions(input, nil)
+}
+
+// ReadFrom replaces s with the schematic read from r like ReadSchematic
+// and returns the number of bytes read, which may include data buffered
+// after the end of the schematic. It implements io.ReaderFrom. On error,
+// s is not changed.
+func (s *Schematic) ReadFrom(r io.Reader) (n int64, err os.Error) {
+	cr := &countReader{r: r}
+	var got *Schematic
+	if got, err = ReadSchematic(cr); err != nil {
+		return cr.n, err
+	}
+	*s = *got
+	return cr.n, nil
+}
+
+// UnmarshalBinary replaces s with the schematic in data, which is in any
+// of the encodings accepted by ReadSchematic. On error, s is not changed.
+func (s *Schematic) UnmarshalBinary(data []byte) os.Error {
+	got, err := ReadSchematic(bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	*s = *got
+	return nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (n int, err os.Error) {
+	n, err = r.r.Read(p)
+	r.n += int64(n)
+	return
+}
+
+// ReadSchematicOptions is like ReadSchematic but allows to control
+// the strictness of the decoder and the conversion of block ids.
+func ReadSchematicOptions(input io.Reader, opt *ReadOptions) (vol *Schematic, err os.Error) {
 	var r *schematicReader
-	if r, err = newSchematicReader(input); err != nil {
+	var max int64
+	if opt != nil {
+		max = opt.MaxSize
+	}
+	if r, err = newSchematicReader(input, max); err != nil {
+		return
+	}
+	r.lenient = opt != nil && opt.Lenient
+	if vol, err = r.Parse(); err != nil {
 		return
 	}
-	vol, err = r.Parse()
+	if opt != nil && opt.Strict {
+		if _, err = r.r.ReadByte(); err != os.EOF {
+			if err == nil {
+				err = os.NewError("Unexpected data after the Schematic tag")
+			}
+			return nil, r.wrap(err)
+		}
+		err = nil
+	}
+	if opt != nil && opt.Remap != nil {
+		if err = vol.Remap(opt.Remap); err != nil {
+			return nil, err
+		}
+	}
 	return
 }
 
+// FromNBT builds a schematic from the contents of a Schematic tag,
+// such as the one returned by NBT.
+func FromNBT(c *nbt.Compound) (s *Schematic, err os.Error) {
+	var buf bytes.Buffer
+	w := nbt.NewWriter(&buf)
+	if err = w.WriteTag("Schematic", c); err != nil {
+		return
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+	r := &schematicReader{r: nbt.NewReader(&buf)}
+	return r.Parse()
+}
+
 // XLen is the number of blocks by X axis.
 func (s *Schematic) XLen() int {
 	return s.Width
@@ -74,61 +255,305 @@ func (s *Schematic) Get(x, y, z int) bool {
 }
 
 // GetV returns the material of the specified block.
+// The blocks outside of the schematic are air; see GetVChecked.
 func (s *Schematic) GetV(x, y, z int) uint16 {
-	if x < 0 || y < 0 || z < 0 || x >= s.XLen() || y >= s.YLen() || z >= s.ZLen() {
+	if !s.Inside(x, y, z) {
 		return 0
 	}
-	index := y*s.XLen()*s.ZLen() + z*s.XLen() + x
-	return uint16(s.Blocks[index])
+	return s.id(s.index(x, y, z))
 }
 
-type schematicReader struct {
-	r *nbtReader
+// Inside reports whether the position is inside the schematic.
+func (s *Schematic) Inside(x, y, z int) bool {
+	return x >= 0 && y >= 0 && z >= 0 && x < s.XLen() && y < s.YLen() && z < s.ZLen()
 }
 
-func newSchematicReader(r io.Reader) (sr *schematicReader, err os.Error) {
-	var nr *nbtReader
-	if nr, err = newNbtReader(r); err != nil {
-		return
+// A RangeError is returned by the checked accessors, such as GetVChecked,
+// for a position outside of the schematic.
+type RangeError struct {
+	X, Y, Z               int
+	Width, Height, Length int
+}
+
+func (e *RangeError) String() string {
+	return fmt.Sprintf("Position %d,%d,%d is outside of the schematic of size %dx%dx%d", e.X, e.Y, e.Z, e.Width, e.Height, e.Length)
+}
+
+func (s *Schematic) rangeError(x, y, z int) os.Error {
+	return &RangeError{x, y, z, s.Width, s.Height, s.Length}
+}
+
+// GetVChecked is like GetV, but fails with a *RangeError outside of the
+// schematic instead of returning air, for the callers which never look
+// outside and want to know if they do.
+func (s *Schematic) GetVChecked(x, y, z int) (uint16, os.Error) {
+	if !s.Inside(x, y, z) {
+		return 0, s.rangeError(x, y, z)
 	}
-	return &schematicReader{r: nr}, nil
+	return s.id(s.index(x, y, z)), nil
 }
 
-func (r *schematicReader) ReadEntity() (entity Entity, err os.Error) {
-	for {
-		var typ byte
-		var name string
-		if typ, name, err = r.r.ReadTagName(); err != nil {
-			return
+// BlockChecked is like Block, but fails with a *RangeError outside of the
+// schematic.
+func (s *Schematic) BlockChecked(x, y, z int) (Block, os.Error) {
+	if !s.Inside(x, y, z) {
+		return Block{}, s.rangeError(x, y, z)
+	}
+	return s.Block(x, y, z), nil
+}
+
+// GetData returns the data value of the specified block, such as the
+// color of wool or the direction of stairs. The blocks outside of the
+// schematic have data value 0.
+func (s *Schematic) GetData(x, y, z int) byte {
+	if !s.Inside(x, y, z) {
+		return 0
+	}
+	return s.Data[s.index(x, y, z)]
+}
+
+// SetData sets the data value of the specified block, keeping its id.
+// It panics if the position is outside of the schematic.
+func (s *Schematic) SetData(x, y, z int, data byte) {
+	if !s.Inside(x, y, z) {
+		panic(fmt.Sprintf("schematic: SetData(%d, %d, %d) out of range", x, y, z))
+	}
+	i := s.index(x, y, z)
+	old := Block{s.id(i), s.Data[i]}
+	s.touch(i)
+	s.Data[i] = data
+	s.changed(i, old)
+}
+
+// id returns the block id at the position i in Blocks.
+func (s *Schematic) id(i int) uint16 {
+	if i < len(s.add) {
+		return uint16(s.add[i])<<8 | uint16(s.Blocks[i])
+	}
+	return uint16(s.Blocks[i])
+}
+
+// setId sets the block id at the position i in Blocks.
+// It panics if the id is above MaxId.
+func (s *Schematic) setId(i int, id uint16) {
+	if id > MaxId {
+		panic(fmt.Sprintf("schematic: block id %d above MaxId", id))
+	}
+	if id > 255 && len(s.add) < len(s.Blocks) {
+		s.add = append(s.add, make([]byte, len(s.Blocks)-len(s.add))...)
+	}
+	s.touch(i)
+	s.Blocks[i] = byte(id)
+	if i < len(s.add) {
+		s.add[i] = byte(id >> 8)
+	}
+}
+
+// The blocks are stored in Blocks and Data in the YZX order: x changes
+// fastest, then z, then y. The block (x, y, z) is at the index
+//
+//	x*StrideX + z*s.StrideZ() + y*s.StrideY()
+//
+// so a layer of the same y is a contiguous run of Width*Length blocks and
+// a row along x a contiguous run of Width blocks.
+
+// StrideX is the distance in Blocks between the neighbors along X.
+const StrideX = 1
+
+// StrideZ returns the distance in Blocks between the neighbors along Z.
+func (s *Schematic) StrideZ() int {
+	return s.Width
+}
+
+// StrideY returns the distance in Blocks between the neighbors along Y.
+func (s *Schematic) StrideY() int {
+	return s.Width * s.Length
+}
+
+// Index returns the position of the block (x, y, z) in Blocks and Data.
+// It panics if the position is outside of the schematic.
+func (s *Schematic) Index(x, y, z int) int {
+	if !s.Inside(x, y, z) {
+		panic(fmt.Sprintf("schematic: Index(%d, %d, %d) out of range", x, y, z))
+	}
+	return s.index(x, y, z)
+}
+
+// Coords returns the position of the block stored at the index i of Blocks
+// and Data. It panics if i is out of range.
+func (s *Schematic) Coords(i int) (x, y, z int) {
+	if i < 0 || i >= s.Width*s.Height*s.Length {
+		panic(fmt.Sprintf("schematic: Coords(%d) out of range", i))
+	}
+	return s.coords(i)
+}
+
+// index returns the position of the block in Blocks and Data.
+func (s *Schematic) index(x, y, z int) int {
+	return y*s.XLen()*s.ZLen() + z*s.XLen() + x
+}
+
+// coords returns the position of the block at the index i.
+func (s *Schematic) coords(i int) (x, y, z int) {
+	return i % s.Width, i / (s.Width * s.Length), i / s.Width % s.Length
+}
+
+// checkSize verifies that Blocks and Data are consistent with the dimensions.
+func (s *Schematic) checkSize() os.Error {
+	want := s.Width * s.Height * s.Length
+	if len(s.Blocks) != want {
+		return &SizeError{Field: "Blocks", Want: want, Got: len(s.Blocks)}
+	}
+	if len(s.Data) != want {
+		return &SizeError{Field: "Data", Want: want, Got: len(s.Data)}
+	}
+	if s.add != nil && len(s.add) != want {
+		return &SizeError{Field: "AddBlocks", Want: want, Got: len(s.add)}
+	}
+	return nil
+}
+
+// unpackAdd sets the high bits of the block ids from the AddBlocks tag,
+// which holds them for two blocks in a byte, the first one in the low
+// nibble, or from the Add tag of old MCEdit versions, with a byte per block.
+// WorldEdit writes n/2+1 bytes of AddBlocks, one more than needed for an
+// even number of blocks; the padding is ignored. If lenient is true, the
+// tags not matching Blocks are cut or padded.
+func (s *Schematic) unpackAdd(addBlocks, add []byte, lenient bool) os.Error {
+	n := len(s.Blocks)
+	switch {
+	case addBlocks != nil:
+		if len(addBlocks) != (n+1)/2 && len(addBlocks) != n/2+1 && !lenient {
+			return &SizeError{Field: "AddBlocks", Want: (n + 1) / 2, Got: len(addBlocks)}
 		}
-		if typ == tagEnd {
-			break
+		s.add = make([]byte, n)
+		for i := range s.add {
+			if i/2 < len(addBlocks) {
+				s.add[i] = addBlocks[i/2] >> (uint(i&1) * 4) & 15
+			}
 		}
-		switch name {
-		default:
-			err = fmt.Errorf("Unknown entity field: %s", name)
-			return
+	case add != nil:
+		if len(add) != n && !lenient {
+			return &SizeError{Field: "Add", Want: n, Got: len(add)}
+		}
+		s.add = make([]byte, n)
+		for i := 0; i < n && i < len(add); i++ {
+			s.add[i] = add[i] & 15
 		}
 	}
-	return
+	for _, v := range s.add {
+		if v != 0 {
+			return nil
+		}
+	}
+	s.add = nil
+	return nil
 }
 
-func (r *schematicReader) ReadEntities() (entities []Entity, err os.Error) {
-	for {
-		var typ byte
-		if typ, err = r.r.ReadTagTyp(); err != nil {
-			return
+// packAdd returns the AddBlocks tag of s, or nil if all ids fit into Blocks.
+func (s *Schematic) packAdd() nbt.ByteArray {
+	var packed nbt.ByteArray
+	for i, v := range s.add {
+		if v == 0 {
+			continue
 		}
-		if typ == tagEnd {
-			break
+		if packed == nil {
+			packed = make(nbt.ByteArray, (len(s.add)+1)/2)
 		}
-		if typ == tagCompound {
-		}
-		var entity Entity
-		if entity, err = r.ReadEntity(); err != nil {
-			return
-		}
-		entities = append(entities, entity)
+		packed[i/2] |= v << (uint(i&1) * 4)
+	}
+	return packed
+}
+
+// A ParseError records where in the input a schematic failed to parse.
+type ParseError struct {
+	Offset int64    // byte offset into the decompressed NBT stream
+	Path   string   // path of the tag being read, e.g. Schematic.Entities[3].Pos
+	Err    os.Error // the underlying error
+}
+
+func (e *ParseError) String() string {
+	if e.Path == "" {
+		return fmt.Sprintf("offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("%s (offset %d): %v", e.Path, e.Offset, e.Err)
+}
+
+// ErrSponge is returned by ReadSchematic for the Sponge schematic format
+// written by WorldEdit 7 and later (.schem files). Such files store
+// namespaced block states instead of the numeric block ids of Schematic;
+// use ReadSponge to read them.
+var ErrSponge = os.NewError("Sponge .schem files must be read with ReadSponge")
+
+type schematicReader struct {
+	r       *nbt.Reader
+	lenient bool // whether to skip checkSize
+}
+
+// wrap annotates err with the current offset and tag path.
+func (r *schematicReader) wrap(err os.Error) os.Error {
+	if _, ok := err.(*ParseError); ok {
+		return err
+	}
+	return &ParseError{Offset: r.r.Offset(), Path: r.r.Path(), Err: err}
+}
+
+func newSchematicReader(r io.Reader, max int64) (sr *schematicReader, err os.Error) {
+	var rd io.Reader
+	if rd, err = decompressor(r); err != nil {
+		return
+	}
+	if max > 0 {
+		rd = &sizeLimiter{r: rd, n: max, max: max}
+	}
+	return &schematicReader{r: nbt.NewReader(rd)}, nil
+}
+
+// readCompounds reads the payload of a list of compounds.
+func (r *schematicReader) readCompounds(name string) (tags []*nbt.Compound, err os.Error) {
+	var list *nbt.List
+	if list, err = r.r.ReadList(); err != nil {
+		return
+	}
+	if len(list.Tags) > 0 && list.ElemType != nbt.TagCompound {
+		return nil, fmt.Errorf("%s must be a list of compounds. Got: %d", name, list.ElemType)
+	}
+	for _, tag := range list.Tags {
+		tags = append(tags, tag.(*nbt.Compound))
+	}
+	return
+}
+
+func (r *schematicReader) ReadEntities() (entities []Entity, err os.Error) {
+	var tags []*nbt.Compound
+	if tags, err = r.readCompounds("Entities"); err != nil {
+		return
+	}
+	for _, c := range tags {
+		id, _ := c.Get("id").(nbt.String)
+		entities = append(entities, Entity{Id: string(id), NBT: c})
+	}
+	return
+}
+
+func (r *schematicReader) ReadTileTicks() (ticks []TileTick, err os.Error) {
+	var tags []*nbt.Compound
+	if tags, err = r.readCompounds("TileTicks"); err != nil {
+		return
+	}
+	for _, c := range tags {
+		ticks = append(ticks, newTileTick(c))
+	}
+	return
+}
+
+func (r *schematicReader) ReadTileEntities() (tiles []TileEntity, err os.Error) {
+	var tags []*nbt.Compound
+	if tags, err = r.readCompounds("TileEntities"); err != nil {
+		return
+	}
+	for _, c := range tags {
+		tiles = append(tiles, newTileEntity(c))
 	}
 	return
 }
@@ -137,22 +562,26 @@ func (r *schematicReader) Parse() (s *Schematic, err os.Error) {
 	var typ byte
 	var name string
 	if typ, name, err = r.r.ReadTagName(); err != nil {
-		return
+		return nil, r.wrap(err)
 	}
-	if typ != tagCompound {
-		return nil, fmt.Errorf("Top level tag must be compound. Got: %d", typ)
+	if typ != nbt.TagCompound {
+		return nil, r.wrap(fmt.Errorf("Top level tag must be compound. Got: %d", typ))
 	}
 	if name != "Schematic" {
-		return nil, fmt.Errorf("Unexpected tag name: %s, want: Schematic", name)
+		return nil, r.wrap(fmt.Errorf("Unexpected tag name: %s, want: Schematic", name))
 	}
-	s = new(Schematic)
+	s = &Schematic{Extra: new(nbt.Compound)}
+	hasMaterials := false
+	var addBlocks, add []byte
+	r.r.Push(name)
 	for {
 		if typ, name, err = r.r.ReadTagName(); err != nil {
-			return
+			return nil, r.wrap(err)
 		}
-		if typ == tagEnd {
+		if typ == nbt.TagEnd {
 			break
 		}
+		r.r.Push(name)
 		switch name {
 		case "Width":
 			s.Width, err = r.r.ReadShort()
@@ -161,11 +590,19 @@ func (r *schematicReader) Parse() (s *Schematic, err os.Error) {
 		case "Height":
 			s.Height, err = r.r.ReadShort()
 		case "Materials":
-			s.Materials, err = r.r.ReadString()
+			var str string
+			if str, err = r.r.ReadString(); err == nil {
+				s.Materials, err = parseMaterials(str)
+				hasMaterials = true
+			}
 		case "Blocks":
 			s.Blocks, err = r.r.ReadByteArray()
 		case "Data":
 			s.Data, err = r.r.ReadByteArray()
+		case "AddBlocks":
+			addBlocks, err = r.r.ReadByteArray()
+		case "Add":
+			add, err = r.r.ReadByteArray()
 		case "WEOffsetX":
 			s.WEOffsetX, err = r.r.ReadInt()
 		case "WEOffsetY":
@@ -174,86 +611,36 @@ func (r *schematicReader) Parse() (s *Schematic, err os.Error) {
 			s.WEOffsetZ, err = r.r.ReadInt()
 		case "Entities":
 			s.Entities, err = r.ReadEntities()
+		case "TileTicks":
+			s.TileTicks, err = r.ReadTileTicks()
+		case "TileEntities":
+			s.TileEntities, err = r.ReadTileEntities()
 		default:
-			return nil, fmt.Errorf("Unexpected tag: %d, name: %s\n", typ, name)
+			var tag nbt.Tag
+			if tag, err = r.r.ReadValue(typ); err == nil {
+				s.Extra.Set(name, tag)
+			}
 		}
 		if err != nil {
-			return
+			return nil, r.wrap(err)
 		}
+		r.r.Pop()
 	}
-	if s.Materials != "Alpha" {
-		return nil, fmt.Errorf("Materials must have 'Alpha' value, got: '%s'", s.Materials)
-	}
-	return
-}
-
-type nbtReader struct {
-	r *bufio.Reader
-}
-
-func newNbtReader(r io.Reader) (nr *nbtReader, err os.Error) {
-	var rd io.Reader
-	if rd, err = gzip.NewReader(r); err != nil {
-		return
-	}
-	return &nbtReader{r: bufio.NewReader(rd)}, nil
-}
-
-func (r *nbtReader) ReadString() (str string, err os.Error) {
-	var l int
-	if l, err = r.ReadShort(); err != nil {
-		return
-	}
-	data := make([]byte, l)
-	if _, err = io.ReadFull(r.r, data); err != nil {
-		return
-	}
-	return string(data), nil
-}
-
-func (r *nbtReader) ReadShort() (val int, err os.Error) {
-	buf := [2]byte{}
-	if _, err = io.ReadFull(r.r, buf[:]); err != nil {
-		return
-	}
-	val = int(buf[1]) + (int(buf[0]) << 8) // Big Endian
-	return
-}
-
-func (r *nbtReader) ReadInt() (val int, err os.Error) {
-	buf := [4]byte{}
-	if _, err = io.ReadFull(r.r, buf[:]); err != nil {
-		return
-	}
-	for i := 0; i < 4; i++ {
-		val <<= 8
-		val += int(buf[i])
-	}
-	return
-}
-
-func (r *nbtReader) ReadTagTyp() (typ byte, err os.Error) {
-	typ, err = r.r.ReadByte()
-	return
-}
-
-func (r *nbtReader) ReadTagName() (typ byte, name string, err os.Error) {
-	if typ, err = r.r.ReadByte(); err != nil {
-		return
+	r.r.Pop()
+	if !hasMaterials {
+		if s.Extra.Get("Version") != nil && (s.Extra.Get("Palette") != nil || s.Extra.Get("Blocks") != nil) {
+			return nil, ErrSponge
+		}
+		return nil, os.NewError("Materials tag is missing")
 	}
-	if typ == tagEnd {
-		return
+	if !r.lenient {
+		if err = s.checkSize(); err != nil {
+			return nil, err
+		}
 	}
-	name, err = r.ReadString()
-	return
-}
-
-func (r *nbtReader) ReadByteArray() (data []byte, err os.Error) {
-	var l int
-	if l, err = r.ReadInt(); err != nil {
-		return
+	if err = s.unpackAdd(addBlocks, add, r.lenient); err != nil {
+		return nil, err
 	}
-	data = make([]byte, l)
-	_, err = io.ReadFull(r.r, data)
+	s.indexTileEntities()
 	return
 }
@@ -1,8 +1,13 @@
 package schematic
 
 import (
+	"bytes"
+	"compress/gzip"
 	"os"
+	"rand"
 	"testing"
+
+	"github.com/krasin/schematic/nbt"
 )
 
 func TestSchematic(t *testing.T) {
@@ -26,3 +31,358 @@ func TestSchematic(t *testing.T) {
 		t.Fatalf("vol.Get(0,0,0): expected false, but got true")
 	}
 }
+
+// nbtBuilder assembles raw NBT streams for tests.
+type nbtBuilder struct {
+	bytes.Buffer
+}
+
+func (b *nbtBuilder) putShort(v int) {
+	b.WriteByte(byte(v >> 8))
+	b.WriteByte(byte(v))
+}
+
+func (b *nbtBuilder) putInt(v int) {
+	for i := 3; i >= 0; i-- {
+		b.WriteByte(byte(v >> uint(8*i)))
+	}
+}
+
+func (b *nbtBuilder) putName(typ byte, name string) {
+	b.WriteByte(typ)
+	b.putShort(len(name))
+	b.WriteString(name)
+}
+
+func (b *nbtBuilder) putByteArray(name string, data []byte) {
+	b.putName(nbt.TagByteArray, name)
+	b.putInt(len(data))
+	b.Write(data)
+}
+
+func gzipBytes(data []byte) []byte {
+	var out bytes.Buffer
+	w, _ := gzip.NewWriter(&out)
+	w.Write(data)
+	w.Close()
+	return out.Bytes()
+}
+
+// testSchematic returns a gzipped schematic with the given dimensions and arrays.
+func testSchematic(w, h, l int, blocks, data []byte) []byte {
+	return gzipBytes(testNBT(w, h, l, blocks, data).Bytes())
+}
+
+// testNBT returns the uncompressed NBT of a schematic.
+func testNBT(w, h, l int, blocks, data []byte) *nbtBuilder {
+	b := new(nbtBuilder)
+	b.putName(nbt.TagCompound, "Schematic")
+	b.putName(nbt.TagShort, "Width")
+	b.putShort(w)
+	b.putName(nbt.TagShort, "Height")
+	b.putShort(h)
+	b.putName(nbt.TagShort, "Length")
+	b.putShort(l)
+	b.putName(nbt.TagString, "Materials")
+	b.putShort(len("Alpha"))
+	b.WriteString("Alpha")
+	b.putByteArray("Blocks", blocks)
+	b.putByteArray("Data", data)
+	b.WriteByte(nbt.TagEnd)
+	return b
+}
+
+func TestSizeMismatch(t *testing.T) {
+	tests := []struct {
+		blocks, data int
+		field        string
+		got          int
+	}{
+		{7, 8, "Blocks", 7},
+		{8, 9, "Data", 9},
+	}
+	for _, tt := range tests {
+		in := testSchematic(2, 2, 2, make([]byte, tt.blocks), make([]byte, tt.data))
+		_, err := ReadSchematic(bytes.NewBuffer(in))
+		e, ok := err.(*SizeError)
+		if !ok {
+			t.Errorf("ReadSchematic: want *SizeError, got %v", err)
+			continue
+		}
+		if e.Field != tt.field || e.Want != 8 || e.Got != tt.got {
+			t.Errorf("SizeError: want {%s 8 %d}, got %+v", tt.field, tt.got, e)
+		}
+	}
+	if _, err := ReadSchematic(bytes.NewBuffer(testSchematic(2, 2, 2, make([]byte, 8), make([]byte, 8)))); err != nil {
+		t.Errorf("ReadSchematic: %v", err)
+	}
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	raw := testNBT(2, 2, 2, make([]byte, 8), make([]byte, 8)).Bytes()
+	// Cut the stream in the middle of the Data array.
+	raw = raw[:len(raw)-5]
+	_, err := ReadSchematic(bytes.NewBuffer(gzipBytes(raw)))
+	e, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("ReadSchematic: want *ParseError, got %v", err)
+	}
+	if e.Path != "Schematic.Data" {
+		t.Errorf("Path: want Schematic.Data, got %s", e.Path)
+	}
+	if e.Offset != int64(len(raw)) {
+		t.Errorf("Offset: want %d, got %d", len(raw), e.Offset)
+	}
+}
+
+func TestMaterials(t *testing.T) {
+	for _, m := range []Materials{Alpha, Classic, Pocket} {
+		vol := &Schematic{Width: 1, Height: 1, Length: 1, Materials: m, Blocks: []byte{1}, Data: []byte{0}}
+		var buf bytes.Buffer
+		if err := WriteSchematic(&buf, vol); err != nil {
+			t.Fatalf("WriteSchematic: %v", err)
+		}
+		got, err := ReadSchematic(&buf)
+		if err != nil {
+			t.Fatalf("ReadSchematic(%v): %v", m, err)
+		}
+		if got.Materials != m {
+			t.Errorf("Materials: want %v, got %v", m, got.Materials)
+		}
+	}
+	raw := testNBT(1, 1, 1, []byte{1}, []byte{0}).Bytes()
+	raw = bytes.Replace(raw, []byte("Alpha"), []byte("Gamma"), 1)
+	if _, err := ReadSchematic(bytes.NewBuffer(gzipBytes(raw))); err == nil {
+		t.Errorf("ReadSchematic: want error for unknown Materials, got nil")
+	}
+}
+
+// rawBytes returns the decompressed content of a file.
+func rawBytes(t *testing.T, data []byte) []byte {
+	r, err := decompressor(bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("decompressor: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err = buf.ReadFrom(r); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// mutate returns a copy of data with a few bytes changed and the end
+// possibly cut off.
+func mutate(rnd *rand.Rand, data []byte) []byte {
+	m := append([]byte(nil), data...)
+	for n := rnd.Intn(4) + 1; n > 0; n-- {
+		i := rnd.Intn(len(m))
+		switch rnd.Intn(3) {
+		case 0:
+			m[i] ^= 1 << uint(rnd.Intn(8))
+		case 1:
+			m[i] = 0xff
+		case 2:
+			m[i] = byte(rnd.Intn(256))
+		}
+	}
+	if rnd.Intn(4) == 0 {
+		m = m[:rnd.Intn(len(m))]
+	}
+	return m
+}
+
+// TestMalformed checks that the readers return errors rather than panic
+// on corrupted files, and that the results they accept can be used.
+func TestMalformed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSchematicOptions(&buf, newChestVolume(), &WriteOptions{Compression: None}); err != nil {
+		t.Fatalf("WriteSchematicOptions: %v", err)
+	}
+	classic := buf.Bytes()
+	l, err := SplitLitematic(newChestVolume(), nil)
+	if err != nil {
+		t.Fatalf("SplitLitematic: %v", err)
+	}
+	var lbuf bytes.Buffer
+	if err = WriteLitematicOptions(&lbuf, l, &WriteOptions{Compression: None}); err != nil {
+		t.Fatalf("WriteLitematicOptions: %v", err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		read func(data []byte)
+	}{
+		{"schematic", classic, func(data []byte) {
+			if s, err := ReadSchematicOptions(bytes.NewBuffer(data), &ReadOptions{Lenient: true}); err == nil {
+				s.Repair()
+				s.Validate()
+			}
+		}},
+		{"sponge v2", rawBytes(t, spongeBytes(t, 2)), func(data []byte) {
+			if sp, err := ReadSponge(bytes.NewBuffer(data)); err == nil {
+				sp.Schematic()
+			}
+		}},
+		{"sponge v3", rawBytes(t, spongeBytes(t, 3)), func(data []byte) {
+			if sp, err := ReadSponge(bytes.NewBuffer(data)); err == nil {
+				sp.Schematic()
+			}
+		}},
+		{"litematic", lbuf.Bytes(), func(data []byte) {
+			if l, err := ReadLitematic(bytes.NewBuffer(data)); err == nil {
+				l.Schematic()
+			}
+		}},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		for i := 0; i < 2000; i++ {
+			m := mutate(rnd, tt.data)
+			func() {
+				defer func() {
+					if e := recover(); e != nil {
+						t.Errorf("%s %q: panic: %v", tt.name, m, e)
+					}
+				}()
+				tt.read(m)
+			}()
+		}
+	}
+}
+
+func TestAddBlocks(t *testing.T) {
+	s := NewSchematic(3, 1, 1)
+	s.SetBlock(0, 0, 0, Block{300, 2})
+	s.SetBlock(2, 0, 0, Block{MaxId, 0})
+	if s.GetV(0, 0, 0) != 300 || s.Block(2, 0, 0) != (Block{MaxId, 0}) || s.GetV(1, 0, 0) != 0 {
+		t.Fatalf("Blocks: got %v %v %v", s.Block(0, 0, 0), s.Block(1, 0, 0), s.Block(2, 0, 0))
+	}
+	c := s.NBT()
+	if add, ok := c.Get("AddBlocks").(nbt.ByteArray); !ok || !bytes.Equal(add, []byte{0x01, 0x0f}) {
+		t.Errorf("AddBlocks: got %v", c.Get("AddBlocks"))
+	}
+	var buf bytes.Buffer
+	if err := WriteSchematic(&buf, s); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	if got.Block(0, 0, 0) != (Block{300, 2}) || got.GetV(2, 0, 0) != MaxId || got.Extra.Get("AddBlocks") != nil {
+		t.Errorf("After round trip: got %v %v", got.Block(0, 0, 0), got.Block(2, 0, 0))
+	}
+	if got.Fingerprint() == NewSchematic(3, 1, 1).Fingerprint() {
+		t.Errorf("Fingerprint ignores the ids above 255")
+	}
+	got.SetBlock(0, 0, 0, Block{44, 0})
+	got.SetBlock(2, 0, 0, Block{})
+	if got.NBT().Get("AddBlocks") != nil {
+		t.Errorf("AddBlocks written without ids above 255")
+	}
+
+	// The Add tag of old MCEdit versions has a byte per block.
+	b := testNBT(2, 1, 1, []byte{1, 2}, []byte{0, 0})
+	b.Truncate(b.Len() - 1)
+	b.putByteArray("Add", []byte{0, 3})
+	b.WriteByte(nbt.TagEnd)
+	if got, err = ReadSchematic(bytes.NewBuffer(b.Bytes())); err != nil {
+		t.Fatalf("ReadSchematic with Add: %v", err)
+	}
+	if got.GetV(0, 0, 0) != 1 || got.GetV(1, 0, 0) != 0x302 {
+		t.Errorf("Add: got %d %d", got.GetV(0, 0, 0), got.GetV(1, 0, 0))
+	}
+
+	// WorldEdit writes n/2+1 bytes of AddBlocks, with a padding nibble for
+	// an even number of blocks.
+	b = testNBT(2, 1, 1, []byte{1, 2}, []byte{0, 0})
+	b.Truncate(b.Len() - 1)
+	b.putByteArray("AddBlocks", []byte{0x20, 0x0f})
+	b.WriteByte(nbt.TagEnd)
+	if got, err = ReadSchematic(bytes.NewBuffer(b.Bytes())); err != nil {
+		t.Fatalf("ReadSchematic with WorldEdit AddBlocks: %v", err)
+	}
+	if got.GetV(0, 0, 0) != 1 || got.GetV(1, 0, 0) != 0x202 {
+		t.Errorf("WorldEdit AddBlocks: got %d %d", got.GetV(0, 0, 0), got.GetV(1, 0, 0))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SetBlock with id %d did not panic", MaxId+1)
+		}
+	}()
+	s.SetBlock(1, 0, 0, Block{MaxId + 1, 0})
+}
+
+func TestData(t *testing.T) {
+	s := NewSchematic(3, 2, 2)
+	s.SetBlock(2, 1, 0, Block{35, 14})
+	if d := s.GetData(2, 1, 0); d != 14 {
+		t.Errorf("GetData(2, 1, 0): want 14, got %d", d)
+	}
+	s.SetData(2, 1, 0, 5)
+	if b := s.Block(2, 1, 0); b != (Block{35, 5}) {
+		t.Errorf("Block after SetData: want 35:5, got %v", b)
+	}
+	if d := s.GetData(3, 1, 0); d != 0 {
+		t.Errorf("GetData outside: want 0, got %d", d)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SetData(0, 2, 0) did not panic")
+		}
+	}()
+	s.SetData(0, 2, 0, 1)
+}
+
+func TestChecked(t *testing.T) {
+	s := newTestVolume()
+	if id, err := s.GetVChecked(2, 0, 1); id != 35 || err != nil {
+		t.Errorf("GetVChecked(2, 0, 1): want 35, nil, got %d, %v", id, err)
+	}
+	if b, err := s.BlockChecked(2, 0, 1); b != (Block{35, 14}) || err != nil {
+		t.Errorf("BlockChecked(2, 0, 1): want 35:14, nil, got %v, %v", b, err)
+	}
+	for _, pos := range [][3]int{{-1, 0, 0}, {3, 0, 0}, {0, 2, 0}, {0, 0, 2}} {
+		if s.Inside(pos[0], pos[1], pos[2]) {
+			t.Errorf("Inside(%v): want false", pos)
+		}
+		_, err := s.GetVChecked(pos[0], pos[1], pos[2])
+		if e, ok := err.(*RangeError); !ok || e.X != pos[0] || e.Width != 3 {
+			t.Errorf("GetVChecked(%v): want *RangeError, got %v", pos, err)
+		}
+		if _, err = s.BlockChecked(pos[0], pos[1], pos[2]); err == nil {
+			t.Errorf("BlockChecked(%v): want error, got nil", pos)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	s := NewSchematic(3, 4, 5)
+	if s.StrideY() != 15 || s.StrideZ() != 3 {
+		t.Errorf("Strides: want 15 and 3, got %d and %d", s.StrideY(), s.StrideZ())
+	}
+	s.SetBlock(2, 1, 3, Block{7, 0})
+	i := s.Index(2, 1, 3)
+	if i != 2*StrideX+3*s.StrideZ()+1*s.StrideY() || s.Blocks[i] != 7 {
+		t.Errorf("Index(2, 1, 3) = %d", i)
+	}
+	for i := range s.Blocks {
+		if x, y, z := s.Coords(i); s.Index(x, y, z) != i {
+			t.Errorf("Coords(%d) = %d, %d, %d", i, x, y, z)
+		}
+	}
+	for _, f := range []func(){
+		func() { s.Index(0, 4, 0) },
+		func() { s.Coords(60) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Want a panic out of range")
+				}
+			}()
+			f()
+		}()
+	}
+}
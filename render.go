@@ -0,0 +1,372 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+)
+
+// View is the projection used by Render.
+type View int
+
+const (
+	TopView       View = iota // orthographic view from above
+	IsometricView             // isometric view from above the south-east corner
+)
+
+// RenderOptions control the rendering of a schematic.
+// A nil *RenderOptions is equivalent to the zero value.
+type RenderOptions struct {
+	View View
+
+	// Scale is the size of a block: a side of a square in the top view
+	// and a quarter of the width of a cube in the isometric view.
+	// Values less than 1 mean 1.
+	Scale int
+
+	// Angle rotates the schematic clockwise around the vertical axis
+	// before projecting. It must be a multiple of 90 degrees.
+	Angle int
+
+	// MinY and MaxY restrict the rendering to the layers MinY <= y < MaxY.
+	// A zero MaxY means the height of the schematic.
+	MinY, MaxY int
+
+	// Shadows darkens the top view where the sun is hidden by higher
+	// blocks, with soft edges, and where the ground is surrounded by
+	// higher blocks, so that the elevation of a map is seen at a glance.
+	Shadows bool
+
+	// SunAngle is the angle of the sun above the eastern horizon in
+	// degrees for Shadows, following the time of day: 90 is noon and
+	// the sun sets at 180. It is not changed by Angle. Zero means 135,
+	// the afternoon sun.
+	SunAngle int
+}
+
+// rotatedView maps the coordinates of a rotated schematic to the original ones.
+type rotatedView struct {
+	s          *Schematic
+	angle      int
+	xlen, zlen int
+	minY, maxY int
+}
+
+func newRotatedView(s *Schematic, opt *RenderOptions) (v *rotatedView, err os.Error) {
+	v = &rotatedView{s: s, angle: (opt.Angle%360 + 360) % 360, xlen: s.XLen(), zlen: s.ZLen(), minY: opt.MinY, maxY: opt.MaxY}
+	if v.angle%90 != 0 {
+		return nil, fmt.Errorf("Angle must be a multiple of 90 degrees. Got: %d", opt.Angle)
+	}
+	if v.angle == 90 || v.angle == 270 {
+		v.xlen, v.zlen = v.zlen, v.xlen
+	}
+	if v.maxY == 0 {
+		v.maxY = s.YLen()
+	}
+	if v.minY < 0 || v.maxY > s.YLen() || v.minY >= v.maxY {
+		return nil, fmt.Errorf("Invalid layer range: [%d, %d) for height %d", opt.MinY, opt.MaxY, s.YLen())
+	}
+	return
+}
+
+// at returns the block id and data at the rotated coordinates.
+// The blocks outside of the layer range are air.
+func (v *rotatedView) at(x, y, z int) (id uint16, data byte) {
+	if x < 0 || z < 0 || x >= v.xlen || z >= v.zlen || y < v.minY || y >= v.maxY {
+		return 0, 0
+	}
+	switch v.angle {
+	case 90:
+		x, z = z, v.xlen-1-x
+	case 180:
+		x, z = v.xlen-1-x, v.zlen-1-z
+	case 270:
+		x, z = v.zlen-1-z, x
+	}
+	if id = v.s.GetV(x, y, z); id != 0 {
+		data = v.s.GetData(x, y, z)
+	}
+	return
+}
+
+// Render draws the schematic as specified by opt.
+func Render(s *Schematic, opt *RenderOptions) (m *image.RGBA, err os.Error) {
+	if opt == nil {
+		opt = new(RenderOptions)
+	}
+	var v *rotatedView
+	if v, err = newRotatedView(s, opt); err != nil {
+		return
+	}
+	scale := opt.Scale
+	if scale < 1 {
+		scale = 1
+	}
+	switch opt.View {
+	case TopView:
+		var light []float64
+		if opt.Shadows {
+			sun := opt.SunAngle
+			if sun == 0 {
+				sun = 135
+			}
+			light = v.sunLight(sun)
+		}
+		return v.renderTop(scale, light), nil
+	case IsometricView:
+		return v.renderIsometric(scale), nil
+	}
+	return nil, fmt.Errorf("Unknown view: %d", opt.View)
+}
+
+// RenderTop returns a top-down view of the schematic, drawing every block
+// column as a scale×scale square in the color of its highest block. The
+// colors are shaded by height: higher blocks are lighter. It fails like
+// Render, such as for an empty schematic.
+func RenderTop(s *Schematic, scale int) (*image.RGBA, os.Error) {
+	return Render(s, &RenderOptions{Scale: scale})
+}
+
+// Thumbnail draws the isometric view of s with the largest scale fitting
+// into a size×size square, centered on a transparent background. Views
+// which do not fit even at scale 1 are scaled down.
+func Thumbnail(s *Schematic, size int) (image.Image, os.Error) {
+	if size < 1 {
+		return nil, fmt.Errorf("Invalid thumbnail size: %d", size)
+	}
+	if s.Width*s.Height*s.Length == 0 {
+		return image.NewNRGBA(size, size), nil
+	}
+	// The isometric view of scale 1 is 2(x+z) pixels wide and x+z+2y high.
+	w, h := 2*(s.XLen()+s.ZLen()), s.XLen()+s.ZLen()+2*s.YLen()
+	scale := 1
+	for (scale+1)*w <= size && (scale+1)*h <= size {
+		scale++
+	}
+	m, err := Render(s, &RenderOptions{View: IsometricView, Scale: scale})
+	if err != nil {
+		return nil, err
+	}
+	return fitSquare(m, size), nil
+}
+
+// fitSquare centers m on a transparent size×size image, scaling it down
+// with the nearest neighbour if it is larger.
+func fitSquare(m image.Image, size int) *image.NRGBA {
+	b := m.Bounds()
+	num, den := 1, 1
+	if side := imax(b.Dx(), b.Dy()); side > size {
+		num, den = size, side
+	}
+	w, h := b.Dx()*num/den, b.Dy()*num/den
+	ox, oy := (size-w)/2, (size-h)/2
+	t := image.NewNRGBA(size, size)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			t.Set(ox+x, oy+y, m.At(b.Min.X+x*den/num, b.Min.Y+y*den/num))
+		}
+	}
+	return t
+}
+
+// renderTop draws the top view. The colors of the columns are multiplied
+// by light, if it is not nil.
+func (v *rotatedView) renderTop(scale int, light []float64) *image.RGBA {
+	m := image.NewRGBA(v.xlen*scale, v.zlen*scale)
+	for z := 0; z < v.zlen; z++ {
+		for x := 0; x < v.xlen; x++ {
+			c, ok := v.topColor(x, z)
+			if !ok {
+				continue
+			}
+			if light != nil {
+				c = shade(c, light[z*v.xlen+x])
+			}
+			for dz := 0; dz < scale; dz++ {
+				for dx := 0; dx < scale; dx++ {
+					m.Set(x*scale+dx, z*scale+dz, c)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// topColor returns the color of the column in the top view, shaded by
+// height, and reports whether the column has a block.
+func (v *rotatedView) topColor(x, z int) (image.RGBAColor, bool) {
+	for y := v.maxY - 1; y >= v.minY; y-- {
+		if id, data := v.at(x, y, z); id != 0 {
+			return shade(BlockColor(id, data), 0.6+0.4*float64(y+1)/float64(v.s.YLen())), true
+		}
+	}
+	return image.RGBAColor{}, false
+}
+
+// sunLight returns the light of every column of the top view, from 0.5 in
+// a full shadow to 1, for the sun at the angle above the eastern horizon
+// in degrees, darkened further by the higher columns around.
+func (v *rotatedView) sunLight(angle int) []float64 {
+	h := make([]float64, v.xlen*v.zlen)
+	top := float64(v.minY)
+	for z := 0; z < v.zlen; z++ {
+		for x := 0; x < v.xlen; x++ {
+			i := z*v.xlen + x
+			h[i] = float64(v.minY)
+			for y := v.maxY - 1; y >= v.minY; y-- {
+				if id, _ := v.at(x, y, z); id != 0 {
+					h[i] = float64(y + 1)
+					break
+				}
+			}
+			top = math.Fmax(top, h[i])
+		}
+	}
+	height := func(x, z int) (float64, bool) {
+		if x < 0 || z < 0 || x >= v.xlen || z >= v.zlen {
+			return 0, false
+		}
+		return h[z*v.xlen+x], true
+	}
+
+	// The sun moves from the east to the west, that is, along the x
+	// axis of the schematic. ux and uz point towards it in the view and
+	// rise is the climb of its rays per block.
+	a := float64(angle) * math.Pi / 180
+	ux, uz := 1.0, 0.0
+	if math.Cos(a) < 0 {
+		ux = -1
+	}
+	switch v.angle {
+	case 90:
+		ux, uz = 0, ux
+	case 180:
+		ux = -ux
+	case 270:
+		ux, uz = 0, -ux
+	}
+	rise := math.Inf(1)
+	if c := math.Fabs(math.Cos(a)); c > 1e-9 {
+		rise = math.Sin(a) / c
+	}
+
+	light := make([]float64, len(h))
+	for z := 0; z < v.zlen; z++ {
+		for x := 0; x < v.xlen; x++ {
+			h0 := h[z*v.xlen+x]
+			// The rays passing close over a higher column give the
+			// penumbra of its shadow.
+			l := 1.0
+			if math.Sin(a) <= 0 {
+				l = 0
+			}
+			for t := 1; l > 0 && h0+rise*float64(t) < top; t++ {
+				hh, ok := height(x+int(ux)*t, z+int(uz)*t)
+				if !ok {
+					break
+				}
+				if hh > h0 {
+					l = math.Fmin(l, math.Fmax(0, 4*(h0+rise*float64(t)-hh)/float64(t)))
+				}
+			}
+			// Every higher column within 3 blocks hides a part of the
+			// sky.
+			var occ float64
+			for dz := -3; dz <= 3; dz++ {
+				for dx := -3; dx <= 3; dx++ {
+					if hh, ok := height(x+dx, z+dz); ok && hh > h0 {
+						d := math.Sqrt(float64(dx*dx + dz*dz))
+						occ += (hh - h0) / (hh - h0 + d) / d
+					}
+				}
+			}
+			light[z*v.xlen+x] = (0.5 + 0.5*l) * (1 - 0.3*math.Fmin(1, occ/4))
+		}
+	}
+	return light
+}
+
+// renderIsometric draws every block as a cube 4*scale pixels wide and
+// 4*scale pixels high, from the back to the front.
+func (v *rotatedView) renderIsometric(scale int) *image.RGBA {
+	h := scale
+	layers := v.maxY - v.minY
+	m := image.NewRGBA((v.xlen+v.zlen)*2*h, (v.xlen+v.zlen)*h+layers*2*h)
+	// The screen position of the cube top-left corner is
+	// ((x-z)*2h + zlen*2h - 2h, (x+z)*h - (y-minY)*2h + (layers-1)*2h).
+	for y := v.minY; y < v.maxY; y++ {
+		for z := 0; z < v.zlen; z++ {
+			for x := 0; x < v.xlen; x++ {
+				id, data := v.at(x, y, z)
+				if id == 0 || v.hidden(x, y, z) {
+					continue
+				}
+				c := BlockColor(id, data)
+				px := (x - z + v.zlen - 1) * 2 * h
+				py := (x+z)*h + (layers-1-(y-v.minY))*2*h
+				drawCube(m, px, py, h, c, shade(c, 0.8), shade(c, 0.6))
+			}
+		}
+	}
+	return m
+}
+
+// hidden reports whether the block is covered by its top, south and east neighbours.
+func (v *rotatedView) hidden(x, y, z int) bool {
+	for _, d := range [][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+		if id, _ := v.at(x+d[0], y+d[1], z+d[2]); id == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// drawCube draws an isometric cube of size 4h×4h with the top-left corner
+// at (px, py). The top, left and right faces are filled with the given colors.
+func drawCube(m *image.RGBA, px, py, h int, top, left, right image.RGBAColor) {
+	fh := float64(h)
+	for v := 0; v < 4*h; v++ {
+		for u := 0; u < 4*h; u++ {
+			fu := float64(u) + 0.5 - 2*fh
+			fv := float64(v) + 0.5
+			a := 1 - abs(fu)/(2*fh) // 1 in the middle, 0 on the sides
+			var c image.RGBAColor
+			switch {
+			case abs(fv-fh) <= a*fh:
+				c = top
+			case fv > fh+a*fh && fv <= 3*fh+a*fh:
+				if fu < 0 {
+					c = left
+				} else {
+					c = right
+				}
+			default:
+				continue
+			}
+			m.Set(px+u, py+v, c)
+		}
+	}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// shade multiplies the color components by f.
+func shade(c image.RGBAColor, f float64) image.RGBAColor {
+	scale := func(v uint8) uint8 {
+		r := float64(v) * f
+		if r > 255 {
+			r = 255
+		}
+		return uint8(r)
+	}
+	return image.RGBAColor{scale(c.R), scale(c.G), scale(c.B), c.A}
+}
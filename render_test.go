@@ -0,0 +1,156 @@
+package schematic
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRenderTop(t *testing.T) {
+	s := &Schematic{Width: 2, Height: 2, Length: 1, Blocks: []byte{1, 0, 0, 0}, Data: make([]byte, 4)}
+	s.Blocks[s.index(1, 1, 0)] = 35
+	s.Data[s.index(1, 1, 0)] = 14
+	m, err := RenderTop(s, 2)
+	if err != nil {
+		t.Fatalf("RenderTop: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 4 || b.Dy() != 2 {
+		t.Fatalf("Size: want 4x2, got %dx%d", b.Dx(), b.Dy())
+	}
+	stone := shade(BlockColor(1, 0), 0.8)
+	wool := BlockColor(35, 14)
+	if got := m.At(1, 1); got != stone {
+		t.Errorf("At(1, 1): want %v, got %v", stone, got)
+	}
+	if got := m.At(2, 0); got != wool {
+		t.Errorf("At(2, 0): want %v, got %v", wool, got)
+	}
+	if _, err = RenderTop(NewSchematic(2, 0, 2), 1); err == nil {
+		t.Errorf("RenderTop of an empty schematic: want error, got nil")
+	}
+}
+
+func TestRenderOptions(t *testing.T) {
+	s := &Schematic{Width: 2, Height: 2, Length: 1, Blocks: []byte{1, 0, 0, 0}, Data: make([]byte, 4)}
+	s.Blocks[s.index(1, 1, 0)] = 35
+	s.Data[s.index(1, 1, 0)] = 14
+	stone := shade(BlockColor(1, 0), 0.8)
+	wool := BlockColor(35, 14)
+
+	m, err := Render(s, &RenderOptions{Angle: 90})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 1 || b.Dy() != 2 {
+		t.Fatalf("Rotated size: want 1x2, got %dx%d", b.Dx(), b.Dy())
+	}
+	if got := m.At(0, 0); got != stone {
+		t.Errorf("Rotated At(0, 0): want %v, got %v", stone, got)
+	}
+	if got := m.At(0, 1); got != wool {
+		t.Errorf("Rotated At(0, 1): want %v, got %v", wool, got)
+	}
+
+	if m, err = Render(s, &RenderOptions{MaxY: 1}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := m.At(1, 0); got.(image.RGBAColor).A != 0 {
+		t.Errorf("Layer range: At(1, 0): want transparent, got %v", got)
+	}
+
+	if m, err = Render(s, &RenderOptions{View: IsometricView, Scale: 2}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 12 || b.Dy() != 14 {
+		t.Fatalf("Isometric size: want 12x14, got %dx%d", b.Dx(), b.Dy())
+	}
+	// The top face of the wool block is in the middle of the upper cube.
+	if got := m.At(8, 3); got != wool {
+		t.Errorf("Isometric At(8, 3): want %v, got %v", wool, got)
+	}
+
+	for _, opt := range []*RenderOptions{{Angle: 45}, {MinY: 2}, {MinY: 1, MaxY: 1}, {View: 7}} {
+		if _, err = Render(s, opt); err == nil {
+			t.Errorf("Render(%+v): want error, got nil", *opt)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	s := &Schematic{Width: 2, Height: 2, Length: 1, Blocks: []byte{1, 0, 0, 0}, Data: make([]byte, 4)}
+	s.Blocks[s.index(1, 1, 0)] = 35
+	s.Data[s.index(1, 1, 0)] = 14
+	wool := BlockColor(35, 14)
+
+	// The isometric view of scale 2 is 12x14, centered at (4, 3).
+	m, err := Thumbnail(s, 20)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 20 || b.Dy() != 20 {
+		t.Fatalf("Size: want 20x20, got %dx%d", b.Dx(), b.Dy())
+	}
+	if got, want := m.At(12, 6), (image.NRGBAColor{wool.R, wool.G, wool.B, 255}); got != want {
+		t.Errorf("At(12, 6): want %v, got %v", want, got)
+	}
+	if got := m.At(0, 0); got.(image.NRGBAColor).A != 0 {
+		t.Errorf("At(0, 0): want transparent, got %v", got)
+	}
+
+	if m, err = Thumbnail(s, 5); err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 5 || b.Dy() != 5 {
+		t.Errorf("Scaled down size: want 5x5, got %dx%d", b.Dx(), b.Dy())
+	}
+	if m, err = Thumbnail(NewSchematic(0, 0, 0), 5); err != nil || m.Bounds().Dx() != 5 {
+		t.Errorf("Thumbnail of an empty schematic: got %v, %v", m, err)
+	}
+	if _, err = Thumbnail(s, 0); err == nil {
+		t.Errorf("Thumbnail of size 0: want error, got nil")
+	}
+}
+
+func TestRenderShadows(t *testing.T) {
+	// A tower of stone in the middle of a stone floor.
+	s := NewSchematic(11, 4, 1)
+	for x := 0; x < 11; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+	}
+	for y := 1; y < 4; y++ {
+		s.SetBlock(5, y, 0, Block{1, 0})
+	}
+	flat, _ := RenderTop(s, 1)
+	m, err := Render(s, &RenderOptions{Shadows: true})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	lum := func(m *image.RGBA, x int) int {
+		c := m.At(x, 0).(image.RGBAColor)
+		return int(c.R) + int(c.G) + int(c.B)
+	}
+	// The afternoon sun casts the shadow to the east.
+	if east, west := lum(m, 6), lum(m, 4); east >= west {
+		t.Errorf("Shadow: want the east of the tower darker than the west, got %d and %d", east, west)
+	}
+	if lum(m, 6) >= lum(flat, 6) || lum(m, 4) >= lum(flat, 4) {
+		t.Errorf("Shadow: want the ground next to the tower darkened")
+	}
+	for _, x := range []int{0, 5, 10} {
+		if m.At(x, 0) != flat.At(x, 0) {
+			t.Errorf("Shadow: At(%d, 0): want %v, got %v", x, flat.At(x, 0), m.At(x, 0))
+		}
+	}
+	// In the morning, the shadow is cast to the west, also when rotated.
+	if m, err = Render(s, &RenderOptions{Shadows: true, SunAngle: 45}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if east, west := lum(m, 6), lum(m, 4); east <= west {
+		t.Errorf("Morning shadow: want the west of the tower darker than the east, got %d and %d", west, east)
+	}
+	if m, err = Render(s, &RenderOptions{Shadows: true, SunAngle: 45, Angle: 180}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if east, west := lum(m, 4), lum(m, 6); east <= west {
+		t.Errorf("Rotated morning shadow: want the west of the tower darker than the east, got %d and %d", west, east)
+	}
+}
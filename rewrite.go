@@ -0,0 +1,160 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A BlockMatcher selects block states by name and properties.
+type BlockMatcher struct {
+	// Name is the name of the block, such as "minecraft:oak_stairs".
+	// The minecraft namespace may be omitted. An empty name matches
+	// all blocks.
+	Name string
+
+	// Props are the properties which must have the given values.
+	Props map[string]string
+}
+
+// ParseBlockMatcher parses a matcher written like a block state, such as
+// "oak_stairs[facing=north,half=top]". The name "*" matches all blocks.
+func ParseBlockMatcher(str string) (m BlockMatcher, err os.Error) {
+	name, props, ok := splitState(str)
+	if !ok || name == "" {
+		return m, fmt.Errorf("Invalid block matcher: %s", str)
+	}
+	if name != "*" {
+		m.Name = name
+	}
+	m.Props = props
+	return
+}
+
+// Match reports whether the block state matches.
+func (m BlockMatcher) Match(state string) bool {
+	name, props, ok := splitState(state)
+	if !ok || m.Name != "" && qualifiedName(m.Name) != qualifiedName(name) {
+		return false
+	}
+	for k, v := range m.Props {
+		if props[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// A Rewrite changes the properties of the block states matching a matcher.
+type Rewrite struct {
+	Match BlockMatcher
+
+	// Set maps the property names to the new values. An empty value
+	// removes the property.
+	Set map[string]string
+}
+
+// Apply returns the state rewritten by r, or the state itself if it does
+// not match. The properties of a rewritten state are sorted by name.
+func (r Rewrite) Apply(state string) string {
+	if !r.Match.Match(state) {
+		return state
+	}
+	name, props, _ := splitState(state)
+	for k, v := range r.Set {
+		if v == "" {
+			props[k] = "", false
+		} else {
+			props[k] = v
+		}
+	}
+	return joinState(name, props)
+}
+
+// Rewrite applies the rules, in order, to all blocks of the volume and
+// returns the number of changed blocks.
+func (v *StateVolume) Rewrite(rules ...Rewrite) int {
+	return v.mapPalette(func(state string) string {
+		for _, r := range rules {
+			state = r.Apply(state)
+		}
+		return state
+	})
+}
+
+// mapPalette replaces every state of the palette by f(state), merges the
+// duplicates and returns the number of changed blocks.
+func (v *StateVolume) mapPalette(f func(state string) string) (n int) {
+	index := make(map[string]int)
+	var palette []string
+	remap := make([]int, len(v.Palette))
+	changed := make([]bool, len(v.Palette))
+	for i, state := range v.Palette {
+		res := f(state)
+		j, ok := index[res]
+		if !ok {
+			j = len(palette)
+			index[res] = j
+			palette = append(palette, res)
+		}
+		remap[i], changed[i] = j, res != state
+	}
+	for i, s := range v.States {
+		if changed[s] {
+			n++
+		}
+		v.States[i] = remap[s]
+	}
+	v.Palette = palette
+	v.movePaletteAir()
+	return
+}
+
+// splitState splits a block state into the name and the properties.
+func splitState(state string) (name string, props map[string]string, ok bool) {
+	props = make(map[string]string)
+	i := strings.Index(state, "[")
+	if i < 0 {
+		return state, props, true
+	}
+	if !strings.HasSuffix(state, "]") {
+		return "", nil, false
+	}
+	name = state[:i]
+	if i+2 == len(state) {
+		return name, props, true
+	}
+	for _, p := range strings.Split(state[i+1:len(state)-1], ",") {
+		j := strings.Index(p, "=")
+		if j <= 0 {
+			return "", nil, false
+		}
+		props[p[:j]] = p[j+1:]
+	}
+	return name, props, true
+}
+
+// joinState is the reverse of splitState. The properties are sorted by name.
+func joinState(name string, props map[string]string) string {
+	if len(props) == 0 {
+		return name
+	}
+	var parts []string
+	for k, v := range props {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return name + "[" + strings.Join(parts, ",") + "]"
+}
+
+// qualifiedName adds the minecraft namespace to a block name without one.
+func qualifiedName(name string) string {
+	if strings.Index(name, ":") < 0 {
+		return "minecraft:" + name
+	}
+	return name
+}
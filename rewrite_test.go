@@ -0,0 +1,66 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestBlockMatcher(t *testing.T) {
+	tests := []struct {
+		matcher, state string
+		want           bool
+	}{
+		{"oak_stairs", "minecraft:oak_stairs[facing=north,half=top]", true},
+		{"minecraft:oak_stairs[facing=north]", "minecraft:oak_stairs[facing=north,half=top]", true},
+		{"oak_stairs[facing=north]", "minecraft:oak_stairs[facing=south,half=top]", false},
+		{"oak_stairs[facing=north]", "minecraft:oak_stairs", false},
+		{"*[waterlogged=true]", "minecraft:oak_fence[waterlogged=true]", true},
+		{"*", "mod:machine", true},
+		{"stone", "mod:stone", false},
+	}
+	for _, tt := range tests {
+		m, err := ParseBlockMatcher(tt.matcher)
+		if err != nil {
+			t.Errorf("ParseBlockMatcher(%s): %v", tt.matcher, err)
+			continue
+		}
+		if got := m.Match(tt.state); got != tt.want {
+			t.Errorf("%s: Match(%s): want %v, got %v", tt.matcher, tt.state, tt.want, got)
+		}
+	}
+	for _, str := range []string{"", "stone[facing", "stone[north]"} {
+		if _, err := ParseBlockMatcher(str); err == nil {
+			t.Errorf("ParseBlockMatcher(%q): want error, got nil", str)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	v := &StateVolume{
+		Width: 4, Height: 1, Length: 1,
+		DataVersion: LatestDataVersion,
+		Palette: []string{
+			"minecraft:air",
+			"minecraft:oak_stairs[half=bottom,facing=north]",
+			"minecraft:oak_stairs[facing=south,half=bottom]",
+			"minecraft:oak_fence[waterlogged=true]",
+		},
+		States: []int{1, 2, 3, 1},
+	}
+	n := v.Rewrite(
+		Rewrite{BlockMatcher{"oak_stairs", map[string]string{"facing": "north"}}, map[string]string{"facing": "south"}},
+		Rewrite{BlockMatcher{Props: map[string]string{"waterlogged": "true"}}, map[string]string{"waterlogged": ""}},
+	)
+	if n != 3 {
+		t.Errorf("Rewrite: want 3 changed blocks, got %d", n)
+	}
+	want := []string{"minecraft:oak_stairs[facing=south,half=bottom]", "minecraft:oak_stairs[facing=south,half=bottom]", "minecraft:oak_fence", "minecraft:oak_stairs[facing=south,half=bottom]"}
+	for x, state := range want {
+		if got := v.State(x, 0, 0); got != state {
+			t.Errorf("State(%d, 0, 0): want %s, got %s", x, state, got)
+		}
+	}
+	// The two stairs states are merged.
+	if len(v.Palette) != 3 {
+		t.Errorf("Palette: want 3 states, got %v", v.Palette)
+	}
+}
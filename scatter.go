@@ -0,0 +1,132 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"math"
+	"rand"
+)
+
+// A YDistribution tells how the clusters of an ore are spread between its
+// MinY and MaxY.
+type YDistribution int
+
+const (
+	// UniformY spreads the clusters evenly.
+	UniformY YDistribution = iota
+	// TriangleY puts most clusters in the middle of the range, with fewer
+	// towards its ends, like the ores of modern Minecraft.
+	TriangleY
+)
+
+// An Ore describes the clusters of a block scattered through the target
+// blocks by Scatter.
+type Ore struct {
+	Block Block
+
+	// Percent is the part of the target blocks in the range of heights
+	// which is replaced. It is ignored if Count is set.
+	Percent float64
+
+	// Count is the number of clusters.
+	Count int
+
+	// Size is the number of blocks of a cluster. The clusters are smaller
+	// where they run into other blocks than the target. Default: 8.
+	Size int
+
+	// MinY and MaxY limit the heights of the clusters; Distribution
+	// spreads the clusters between them. Default: the bottom and the top
+	// of the schematic.
+	MinY, MaxY   int
+	Distribution YDistribution
+}
+
+// ScatterOptions control Scatter.
+type ScatterOptions struct {
+	Seed int64
+
+	// Target is the block replaced by the ores. Default: stone.
+	Target Block
+
+	Ores []Ore
+}
+
+// Scatter replaces some of the target blocks with blob shaped clusters of
+// the ores, such as coal and iron in the stone of a generated terrain, and
+// returns the number of replaced blocks. The ores are placed in order. The
+// same options always give the same result.
+func (s *Schematic) Scatter(opt *ScatterOptions) (n int) {
+	if opt == nil {
+		return 0
+	}
+	target := opt.Target
+	if target == (Block{}) {
+		target = Block{1, 0}
+	}
+	if s.Width == 0 || s.Height == 0 || s.Length == 0 {
+		return 0
+	}
+	rnd := rand.New(rand.NewSource(opt.Seed))
+	for _, ore := range opt.Ores {
+		if ore.Size <= 0 {
+			ore.Size = 8
+		}
+		if ore.MaxY <= 0 || ore.MaxY > s.Height {
+			ore.MaxY = s.Height
+		}
+		ore.MinY = imax(0, imin(ore.MinY, ore.MaxY-1))
+		count := ore.Count
+		if count == 0 {
+			targets := s.countRange(target, ore.MinY, ore.MaxY)
+			count = int(math.Floor(float64(targets)*ore.Percent/100/float64(ore.Size) + 0.5))
+		}
+		for i := 0; i < count; i++ {
+			x, z := rnd.Intn(s.Width), rnd.Intn(s.Length)
+			span := ore.MaxY - ore.MinY
+			var y int
+			switch ore.Distribution {
+			case TriangleY:
+				y = ore.MinY + (rnd.Intn(span)+rnd.Intn(span)+1)/2
+			default:
+				y = ore.MinY + rnd.Intn(span)
+			}
+			n += s.growCluster(x, imin(y, ore.MaxY-1), z, ore.Size, target, ore.Block, rnd)
+		}
+	}
+	return
+}
+
+// countRange returns the number of the blocks b in the layers from minY up
+// to maxY.
+func (s *Schematic) countRange(b Block, minY, maxY int) (n int) {
+	layer := s.Width * s.Length
+	for i := minY * layer; i < maxY*layer; i++ {
+		if s.id(i) == b.Id && s.Data[i] == b.Data {
+			n++
+		}
+	}
+	return
+}
+
+// growCluster replaces up to size target blocks with the ore, starting at
+// (x, y, z) and growing the cluster from random blocks of it to their
+// neighbors. It returns the number of replaced blocks.
+func (s *Schematic) growCluster(x, y, z, size int, target, ore Block, rnd *rand.Rand) int {
+	if s.Block(x, y, z) != target {
+		return 0
+	}
+	s.SetBlock(x, y, z, ore)
+	cluster := [][3]int{{x, y, z}}
+	for tries := 0; len(cluster) < size && tries < size*8; tries++ {
+		p := cluster[rnd.Intn(len(cluster))]
+		nb := Neighbors6(p[0], p[1], p[2])[rnd.Intn(6)]
+		if !s.Inside(nb.X, nb.Y, nb.Z) || s.Block(nb.X, nb.Y, nb.Z) != target {
+			continue
+		}
+		s.SetBlock(nb.X, nb.Y, nb.Z, ore)
+		cluster = append(cluster, [3]int{nb.X, nb.Y, nb.Z})
+	}
+	return len(cluster)
+}
@@ -0,0 +1,43 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestScatter(t *testing.T) {
+	s := solid(32, 32, 32)
+	s.Fill(RegionMask(Box{0, 28, 0, 32, 32, 32}), Block{3, 0})
+	opt := &ScatterOptions{
+		Seed: 5,
+		Ores: []Ore{
+			{Block: Block{16, 0}, Percent: 2},
+			{Block: Block{15, 0}, Count: 10, Size: 4, MinY: 4, MaxY: 12, Distribution: TriangleY},
+		},
+	}
+	n := s.Scatter(opt)
+	counts := s.BlockCounts()
+	if counts[16]+counts[15] != n {
+		t.Errorf("Scatter returned %d, %d blocks of ore", n, counts[16]+counts[15])
+	}
+	if counts[3] != 32*4*32 {
+		t.Errorf("Scatter replaced dirt: %d blocks left", counts[3])
+	}
+	// 2% of 28672 blocks of stone.
+	if c := counts[16]; c < 400 || c > 600 {
+		t.Errorf("Want about 570 blocks of coal, got %d", c)
+	}
+	if c := counts[15]; c == 0 || c > 40 {
+		t.Errorf("Want up to 40 blocks of iron, got %d", c)
+	}
+	for i := range s.Blocks {
+		if y := i / (32 * 32); s.Blocks[i] == 15 && (y < 3 || y > 12) {
+			t.Errorf("Iron at y = %d", y)
+		}
+	}
+	again := solid(32, 32, 32)
+	again.Fill(RegionMask(Box{0, 28, 0, 32, 32, 32}), Block{3, 0})
+	again.Scatter(opt)
+	if again.Fingerprint() != s.Fingerprint() {
+		t.Errorf("Scatter is not deterministic")
+	}
+}
@@ -0,0 +1,65 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestRemap(t *testing.T) {
+	s := NewSchematic(3, 1, 1)
+	s.Blocks = []byte{1, 200, 201}
+	mapping := nbt.NewCompound().
+		Set("minecraft:stone", nbt.Short(1)).
+		Set("mymod:ore", nbt.Short(200)).
+		Set("mymod:lamp", nbt.Short(201)).
+		Set("mymod:unused", nbt.Short(202))
+	s.Extra.Set("SchematicaMapping", mapping)
+	s.Extra.Set("Icon", nbt.NewCompound().Set("id", nbt.String("minecraft:grass")).Set("Count", nbt.Byte(1)).Set("Damage", nbt.Short(0)))
+	var buf bytes.Buffer
+	if err := WriteSchematic(&buf, s); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	data := buf.Bytes()
+
+	target := VanillaMapping()
+	target["mymod:lamp"] = 180
+	if _, err := ReadSchematicOptions(bytes.NewBuffer(data), &ReadOptions{Remap: target}); err == nil {
+		t.Errorf("Remap without mymod:ore: want error, got nil")
+	}
+	target["mymod:ore"] = 181
+	got, err := ReadSchematicOptions(bytes.NewBuffer(data), &ReadOptions{Remap: target})
+	if err != nil {
+		t.Fatalf("ReadSchematicOptions: %v", err)
+	}
+	if !bytes.Equal(got.Blocks, []byte{1, 181, 180}) {
+		t.Errorf("Blocks: want [1 181 180], got %v", got.Blocks)
+	}
+	m, ok := got.Mapping()
+	if !ok || len(m) != 3 || m["mymod:ore"] != 181 || m["minecraft:stone"] != 1 {
+		t.Errorf("Mapping: got %v, %v", m, ok)
+	}
+	// The mapping is written ordered by id.
+	if c := got.Extra.Get("SchematicaMapping").(*nbt.Compound); c.Fields[1].Name != "mymod:lamp" {
+		t.Errorf("SchematicaMapping order: got %v", c.Fields)
+	}
+	if item, damage, ok := got.Icon(); !ok || item != "minecraft:grass" || damage != 0 {
+		t.Errorf("Icon: want minecraft:grass 0, got %s %d %v", item, damage, ok)
+	}
+
+	target["mymod:ore"] = 300
+	if err = got.Remap(target); err != nil {
+		t.Fatalf("Remap to id 300: %v", err)
+	}
+	if got.GetV(1, 0, 0) != 300 {
+		t.Errorf("Remap to id 300: got %d", got.GetV(1, 0, 0))
+	}
+	target["mymod:ore"] = 5000
+	if err = got.Remap(target); err == nil {
+		t.Errorf("Remap to id 5000: want error, got nil")
+	}
+	if vanilla := VanillaMapping(); vanilla["minecraft:silver_glazed_terracotta"] != 243 || vanilla["minecraft:wool"] != 35 {
+		t.Errorf("VanillaMapping: wrong ids for silver_glazed_terracotta or wool")
+	}
+}
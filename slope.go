@@ -0,0 +1,80 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"math"
+)
+
+// A SurfaceSample describes the shape of the terrain at the top block of
+// a column.
+type SurfaceSample struct {
+	Pos    BlockPos // the top block other than air of the column
+	Normal Point    // the unit vector perpendicular to the terrain, pointing up
+	Slope  float64  // the angle between the terrain and the horizon, in degrees
+}
+
+// SurfaceSamples returns the samples of the blocks returned by
+// TopSurface, in the same order. The normals are estimated from the
+// heights of the neighbouring columns, which are smooth for terrains, but
+// see no overhangs; OccupancyNormal sees those. The columns of air count
+// as columns of height 0.
+func (s *Schematic) SurfaceSamples() (samples []SurfaceSample) {
+	h := s.heights()
+	// dh returns the change of the height per block between the columns i
+	// and j at the distance d from each other.
+	dh := func(i, j, d int) float64 {
+		if d == 0 {
+			return 0
+		}
+		return (h[j] - h[i]) / float64(d)
+	}
+	for _, pos := range s.TopSurface() {
+		x0, x1 := imax(pos.X-1, 0), imin(pos.X+1, s.Width-1)
+		z0, z1 := imax(pos.Z-1, 0), imin(pos.Z+1, s.Length-1)
+		dx := dh(pos.Z*s.Width+x0, pos.Z*s.Width+x1, x1-x0)
+		dz := dh(z0*s.Width+pos.X, z1*s.Width+pos.X, z1-z0)
+		n := unit(Point{-dx, 1, -dz})
+		samples = append(samples, SurfaceSample{pos, n, SlopeAngle(n)})
+	}
+	return
+}
+
+// OccupancyNormal estimates the normal of the surface at the block
+// (x, y, z) from the blocks around it: the unit vector points from the
+// block towards the air within the radius r, and the outside of the
+// schematic is air. Unlike SurfaceSamples, it follows cliffs and
+// overhangs. The result is zero if the air is on all sides alike, such as
+// for a block buried in stone or floating in the air.
+func (s *Schematic) OccupancyNormal(x, y, z, r int) Point {
+	var sum Point
+	for dy := -r; dy <= r; dy++ {
+		for dz := -r; dz <= r; dz++ {
+			for dx := -r; dx <= r; dx++ {
+				if dx*dx+dy*dy+dz*dz > r*r || s.GetV(x+dx, y+dy, z+dz) != 0 {
+					continue
+				}
+				sum.X += float64(dx)
+				sum.Y += float64(dy)
+				sum.Z += float64(dz)
+			}
+		}
+	}
+	return unit(sum)
+}
+
+// SlopeAngle returns the angle between the horizon and the surface with
+// the unit normal n, in degrees.
+func SlopeAngle(n Point) float64 {
+	return math.Acos(math.Fmin(1, math.Fabs(n.Y))) * 180 / math.Pi
+}
+
+// unit returns p scaled to the length 1, or zero if p is zero.
+func unit(p Point) Point {
+	l := math.Sqrt(p.X*p.X + p.Y*p.Y + p.Z*p.Z)
+	if l < 1e-9 {
+		return Point{}
+	}
+	return Point{p.X / l, p.Y / l, p.Z / l}
+}
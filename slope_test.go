@@ -0,0 +1,57 @@
+package schematic
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSurfaceSamples(t *testing.T) {
+	// A ramp rising by one block per block along x, on a flat floor along
+	// z.
+	s := NewSchematic(4, 5, 3)
+	for x := 0; x < 4; x++ {
+		for z := 0; z < 3; z++ {
+			for y := 0; y <= x; y++ {
+				s.SetBlock(x, y, z, Block{1, 0})
+			}
+		}
+	}
+	samples := s.SurfaceSamples()
+	if len(samples) != 12 {
+		t.Fatalf("SurfaceSamples: want 12 samples, got %d", len(samples))
+	}
+	for _, sm := range samples {
+		if sm.Pos.Y != sm.Pos.X {
+			t.Errorf("Sample at %v: want the top of the ramp", sm.Pos)
+		}
+		r := 1 / math.Sqrt(2)
+		if math.Fabs(sm.Normal.X+r) > 1e-9 || math.Fabs(sm.Normal.Y-r) > 1e-9 || sm.Normal.Z != 0 {
+			t.Errorf("Sample at %v: want normal (%.3f, %.3f, 0), got %v", sm.Pos, -r, r, sm.Normal)
+		}
+		if math.Fabs(sm.Slope-45) > 1e-9 {
+			t.Errorf("Sample at %v: want slope 45, got %f", sm.Pos, sm.Slope)
+		}
+	}
+}
+
+func TestOccupancyNormal(t *testing.T) {
+	// A wall of stone facing east.
+	s := NewSchematic(6, 5, 5)
+	for y := 0; y < 5; y++ {
+		for z := 0; z < 5; z++ {
+			for x := 0; x < 3; x++ {
+				s.SetBlock(x, y, z, Block{1, 0})
+			}
+		}
+	}
+	n := s.OccupancyNormal(2, 2, 2, 2)
+	if math.Fabs(n.X-1) > 1e-9 || math.Fabs(n.Y) > 1e-9 || math.Fabs(n.Z) > 1e-9 {
+		t.Errorf("OccupancyNormal of the wall: want (1, 0, 0), got %v", n)
+	}
+	if n := s.OccupancyNormal(1, 2, 2, 1); n != (Point{}) {
+		t.Errorf("OccupancyNormal of a buried block: want zero, got %v", n)
+	}
+	if a := SlopeAngle(n); math.Fabs(a-90) > 1e-9 {
+		t.Errorf("SlopeAngle of the wall: want 90, got %f", a)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"os"
+	"sort"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// snapshotChunk is the number of blocks saved together when a block is
+// changed after a snapshot.
+const snapshotChunk = 4096
+
+// A Snapshot is a saved state of a schematic, which Restore brings back.
+//
+// Taking a snapshot copies no blocks: the first change of a chunk of
+// blocks after it saves the chunk, so the cost of a snapshot is
+// proportional to the blocks changed until the next one. The blocks must
+// be changed with the methods of Schematic, such as SetBlock, Replace,
+// Fill or Paste, or with a BoxView; the changes made by writing to Blocks
+// and Data directly are not seen. The lists of entities, tile entities
+// and tile ticks are copied, but their NBT compounds are shared, so the
+// changes made to those in place are not undone. Extra is copied.
+type Snapshot struct {
+	width, height, length int
+	weOffset              [3]int
+	materials             Materials
+	chunks                map[int]*savedChunk // the content before the first change
+	entities              []Entity
+	tileEntities          []TileEntity
+	tileTicks             []TileTick
+	extra                 *nbt.Compound
+}
+
+type savedChunk struct {
+	blocks, data, add []byte
+}
+
+// Snapshot saves the state of s. Snapshots are kept until they are
+// restored over or discarded, so an editor may take one before every
+// change and call Restore to undo any number of changes.
+func (s *Schematic) Snapshot() *Snapshot {
+	snap := &Snapshot{
+		width:     s.Width,
+		height:    s.Height,
+		length:    s.Length,
+		weOffset:  [3]int{s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ},
+		materials: s.Materials,
+		chunks:    make(map[int]*savedChunk),
+	}
+	snap.entities, snap.tileEntities, snap.tileTicks, snap.extra = s.copyObjects()
+	s.snapshots = append(s.snapshots, snap)
+	return snap
+}
+
+// copyObjects returns copies of the entities, the tile entities, the tile
+// ticks and Extra of s.
+func (s *Schematic) copyObjects() ([]Entity, []TileEntity, []TileTick, *nbt.Compound) {
+	var extra *nbt.Compound
+	if s.Extra != nil {
+		extra = nbt.Clone(s.Extra).(*nbt.Compound)
+	}
+	return append([]Entity(nil), s.Entities...),
+		append([]TileEntity(nil), s.TileEntities...),
+		append([]TileTick(nil), s.TileTicks...),
+		extra
+}
+
+// touch is called before the block i is changed. It saves the chunk of
+// the block in the latest snapshot and marks its section for the change
+// trackers.
+func (s *Schematic) touch(i int) {
+	for _, t := range s.trackers {
+		t.mark(i)
+	}
+	if len(s.snapshots) == 0 {
+		return
+	}
+	snap := s.snapshots[len(s.snapshots)-1]
+	c := i / snapshotChunk
+	if snap.chunks[c] != nil {
+		return
+	}
+	start, end := c*snapshotChunk, imin((c+1)*snapshotChunk, len(s.Blocks))
+	saved := &savedChunk{
+		blocks: append([]byte(nil), s.Blocks[start:end]...),
+		data:   append([]byte(nil), s.Data[start:end]...),
+	}
+	if start < len(s.add) {
+		saved.add = append([]byte(nil), s.add[start:imin(end, len(s.add))]...)
+	}
+	snap.chunks[c] = saved
+}
+
+// findSnapshot returns the position of snap in the snapshots of s, or -1.
+func (s *Schematic) findSnapshot(snap *Snapshot) int {
+	for i, t := range s.snapshots {
+		if t == snap {
+			return i
+		}
+	}
+	return -1
+}
+
+// Restore brings s back to the state saved by snap. The snapshot stays
+// valid, so s may be restored to it again, while the later snapshots are
+// discarded. Restore fails if snap was taken of another schematic or
+// discarded, or if the number of blocks of s has changed since.
+func (s *Schematic) Restore(snap *Snapshot) os.Error {
+	k := s.findSnapshot(snap)
+	if k < 0 {
+		return os.NewError("Snapshot is not of this schematic or was discarded")
+	}
+	if n := snap.width * snap.height * snap.length; len(s.Blocks) != n || len(s.Data) != n {
+		return &SizeError{Field: "Blocks", Want: n, Got: len(s.Blocks)}
+	}
+	var before map[int][]Block
+	if len(s.listeners) > 0 || len(s.trackers) > 0 {
+		before = s.savedBlocks(k)
+	}
+	// A chunk is saved by the first snapshot after which it changed, so
+	// the older snapshots are applied last.
+	for i := len(s.snapshots) - 1; i >= k; i-- {
+		for c, saved := range s.snapshots[i].chunks {
+			start := c * snapshotChunk
+			copy(s.Blocks[start:], saved.blocks)
+			copy(s.Data[start:], saved.data)
+			if s.add != nil {
+				end := start + len(saved.blocks)
+				for j := start; j < end; j++ {
+					s.add[j] = 0
+				}
+				copy(s.add[start:], saved.add)
+			}
+		}
+	}
+	s.snapshots = s.snapshots[:k+1]
+	snap.chunks = make(map[int]*savedChunk)
+
+	s.Width, s.Height, s.Length = snap.width, snap.height, snap.length
+	s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = snap.weOffset[0], snap.weOffset[1], snap.weOffset[2]
+	s.Materials = snap.materials
+	s.Entities, s.TileEntities, s.TileTicks, s.Extra = snap.copyObjects()
+	s.indexTileEntities()
+	var keys []int
+	for c := range before {
+		keys = append(keys, c)
+	}
+	sort.Ints(keys)
+	for _, c := range keys {
+		for j, b := range before[c] {
+			i := c*snapshotChunk + j
+			if b == (Block{s.id(i), s.Data[i]}) {
+				continue
+			}
+			for _, t := range s.trackers {
+				t.mark(i)
+			}
+			s.changed(i, b)
+		}
+	}
+	return nil
+}
+
+// savedBlocks returns the current blocks of the chunks saved by the
+// snapshots of s starting from the k-th one.
+func (s *Schematic) savedBlocks(k int) map[int][]Block {
+	m := make(map[int][]Block)
+	for _, snap := range s.snapshots[k:] {
+		for c, saved := range snap.chunks {
+			if m[c] != nil {
+				continue
+			}
+			blocks := make([]Block, len(saved.blocks))
+			for j := range blocks {
+				i := c*snapshotChunk + j
+				blocks[j] = Block{s.id(i), s.Data[i]}
+			}
+			m[c] = blocks
+		}
+	}
+	return m
+}
+
+func (snap *Snapshot) copyObjects() ([]Entity, []TileEntity, []TileTick, *nbt.Compound) {
+	t := &Schematic{Entities: snap.entities, TileEntities: snap.tileEntities, TileTicks: snap.tileTicks, Extra: snap.extra}
+	return t.copyObjects()
+}
+
+// Discard drops snap, which can no longer be restored. The changes saved
+// by it are kept by the previous snapshot, if any.
+func (s *Schematic) Discard(snap *Snapshot) {
+	k := s.findSnapshot(snap)
+	if k < 0 {
+		return
+	}
+	if k > 0 {
+		prev := s.snapshots[k-1]
+		for c, saved := range snap.chunks {
+			if prev.chunks[c] == nil {
+				prev.chunks[c] = saved
+			}
+		}
+	}
+	s.snapshots = append(s.snapshots[:k], s.snapshots[k+1:]...)
+}
@@ -0,0 +1,75 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := NewSchematic(100, 100, 10) // several chunks
+	s.SetBlock(1, 2, 3, Block{1, 0})
+	orig := append([]byte(nil), s.Blocks...)
+
+	first := s.Snapshot()
+	s.SetBlock(1, 2, 3, Block{300, 1})
+	s.SetData(99, 99, 9, 7)
+	s.Entities = append(s.Entities, Entity{Id: "Pig"})
+	s.Extra.Set("Note", nbt.String("changed"))
+	afterFirst := append([]byte(nil), s.Blocks...)
+
+	second := s.Snapshot()
+	s.Fill(RegionMask(Box{0, 0, 0, 100, 1, 10}), Block{7, 0})
+	s.SetBlock(1, 2, 3, Block{2, 0})
+	if len(second.chunks) == 0 || len(second.chunks) == len(s.Blocks)/snapshotChunk {
+		t.Errorf("The second snapshot saved %d chunks", len(second.chunks))
+	}
+
+	if err := s.Restore(second); err != nil {
+		t.Fatalf("Restore(second): %v", err)
+	}
+	if !bytes.Equal(s.Blocks, afterFirst) || s.Block(1, 2, 3) != (Block{300, 1}) || len(s.Entities) != 1 {
+		t.Errorf("After Restore(second): block %v, %d entities", s.Block(1, 2, 3), len(s.Entities))
+	}
+	// Restoring twice to the same snapshot works.
+	s.SetBlock(0, 0, 0, Block{3, 0})
+	if err := s.Restore(second); err != nil || s.GetV(0, 0, 0) != 0 {
+		t.Errorf("Restore(second) again: %v, block %d", err, s.GetV(0, 0, 0))
+	}
+
+	if err := s.Restore(first); err != nil {
+		t.Fatalf("Restore(first): %v", err)
+	}
+	if !bytes.Equal(s.Blocks, orig) || s.GetData(99, 99, 9) != 0 || s.GetV(1, 2, 3) != 1 {
+		t.Errorf("After Restore(first): block %v, data %d", s.Block(1, 2, 3), s.GetData(99, 99, 9))
+	}
+	if len(s.Entities) != 0 || s.Extra.Get("Note") != nil {
+		t.Errorf("After Restore(first): entities %v, extra %v", s.Entities, s.Extra)
+	}
+	if err := s.Restore(second); err == nil {
+		t.Errorf("Restore of a snapshot newer than the restored one: want error, got nil")
+	}
+	if err := NewSchematic(1, 1, 1).Restore(first); err == nil {
+		t.Errorf("Restore of a snapshot of another schematic: want error, got nil")
+	}
+}
+
+func TestSnapshotDiscard(t *testing.T) {
+	s := NewSchematic(2, 1, 1)
+	first := s.Snapshot()
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	second := s.Snapshot()
+	s.SetBlock(1, 0, 0, Block{2, 0})
+	s.Discard(second)
+	s.SetBlock(0, 0, 0, Block{3, 0})
+	if err := s.Restore(second); err == nil {
+		t.Errorf("Restore of a discarded snapshot: want error, got nil")
+	}
+	if err := s.Restore(first); err != nil {
+		t.Fatalf("Restore(first): %v", err)
+	}
+	if s.GetV(0, 0, 0) != 0 || s.GetV(1, 0, 0) != 0 {
+		t.Errorf("After Restore(first): got %v", s.Blocks)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// SpawnOptions control SpawnSpots. A nil *SpawnOptions selects the
+// defaults.
+type SpawnOptions struct {
+	// MaxLight is the highest light level at which the monsters spawn.
+	// Default: 7, as in Minecraft 1.12. Negative means 0, as in 1.18.
+	MaxLight int
+
+	// Clearance is the free height the monsters need. Default: 2, for
+	// zombies and skeletons; 3 for endermen.
+	Clearance int
+
+	// Sky counts the daylight, as in the day; by default the sky is dark,
+	// as at night.
+	Sky bool
+}
+
+// spawnBlockers are the blocks which are not Solid, but in which the
+// monsters do not spawn: rails, slabs and carpets.
+var spawnBlockers = map[uint16]bool{27: true, 28: true, 44: true, 66: true, 126: true, 157: true, 171: true, 182: true, 205: true}
+
+// SpawnSpots returns the positions where monsters could spawn in s, so
+// that a builder can light them up or cover them. A spot is a block which
+// is neither Solid, nor a liquid, nor a Redstone component such as a
+// pressure plate, nor a rail, a slab or a carpet, standing on a Solid block
+// which is not Transparent, with the blocks above it free for Clearance
+// blocks in total and the light level of Light(Sky) at most MaxLight.
+// The space above the schematic is free, and the blocks of the bottom
+// layer have nothing to stand on. The rules are those of Minecraft
+// 1.12 without the exceptions, such as upside-down slabs; blocks of
+// unknown ids are Solid.
+func (s *Schematic) SpawnSpots(opt *SpawnOptions) (spots []BlockPos) {
+	o := SpawnOptions{MaxLight: 7, Clearance: 2}
+	if opt != nil {
+		o = *opt
+		if o.MaxLight == 0 {
+			o.MaxLight = 7
+		}
+		if o.MaxLight < 0 {
+			o.MaxLight = 0
+		}
+		if o.Clearance <= 0 {
+			o.Clearance = 2
+		}
+	}
+	light := s.Light(o.Sky)
+	free := func(b Block) bool {
+		info, _ := Info(b)
+		return info.Category&(Solid|Liquid) == 0
+	}
+	for y := 1; y < s.Height; y++ {
+		for z := 0; z < s.Length; z++ {
+			for x := 0; x < s.Width; x++ {
+				b := s.Block(x, y, z)
+				if !free(b) || b.Is(Redstone) || spawnBlockers[b.Id] {
+					continue
+				}
+				if under := s.Block(x, y-1, z); !under.Is(Solid) || under.Is(Transparent) {
+					continue
+				}
+				if int(light[s.index(x, y, z)]) > o.MaxLight {
+					continue
+				}
+				clear := true
+				for h := 1; h < o.Clearance && y+h < s.Height && clear; h++ {
+					clear = free(s.Block(x, y+h, z))
+				}
+				if clear {
+					spots = append(spots, BlockPos{x, y, z})
+				}
+			}
+		}
+	}
+	return
+}
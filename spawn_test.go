@@ -0,0 +1,39 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestSpawnSpots(t *testing.T) {
+	// A torch at the start of a long stone floor, a rail and a low glass
+	// roof at the end.
+	s := NewSchematic(20, 3, 1)
+	for x := 0; x < 20; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+	}
+	s.SetBlock(0, 1, 0, Block{50, 5})
+	s.SetBlock(7, 1, 0, Block{66, 0})
+	for x := 15; x < 20; x++ {
+		s.SetBlock(x, 2, 0, Block{20, 0})
+	}
+	// The torch lights the blocks up to x=6 above 7; the rail, the roof
+	// and the glass itself are no spots.
+	spots := s.SpawnSpots(nil)
+	if len(spots) != 7 {
+		t.Fatalf("SpawnSpots: want 7 spots, got %v", spots)
+	}
+	for i, p := range spots {
+		if p != (BlockPos{8 + i, 1, 0}) {
+			t.Errorf("Spot %d: want (%d, 1, 0), got %v", i, 8+i, p)
+		}
+	}
+	if spots = s.SpawnSpots(&SpawnOptions{MaxLight: -1}); len(spots) != 1 || spots[0] != (BlockPos{14, 1, 0}) {
+		t.Errorf("SpawnSpots in darkness only: got %v", spots)
+	}
+	if spots = s.SpawnSpots(&SpawnOptions{Clearance: 1}); len(spots) != 12 {
+		t.Errorf("SpawnSpots of small monsters: want 12 spots, got %v", spots)
+	}
+	if spots = s.SpawnSpots(&SpawnOptions{Sky: true}); len(spots) != 0 {
+		t.Errorf("SpawnSpots in the day: got %v", spots)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+)
+
+// A Tile is a part of a schematic returned by SplitMaxSize.
+type Tile struct {
+	// X, Y and Z are the position of the tile in the split schematic.
+	X, Y, Z int
+
+	// Schematic holds the blocks of the tile. Its WorldEdit offset is
+	// moved by the position, so pasting all tiles from the same spot
+	// rebuilds the original.
+	Schematic *Schematic
+}
+
+// SplitOptions control SplitMaxSize. A nil *SplitOptions is equivalent to
+// the zero value.
+type SplitOptions struct {
+	// ChunkAligned puts the borders of the tiles on the borders of the
+	// 16x16 chunks of the world, so that every tile covers whole chunks
+	// along the x and z axes. The tiles are then at most maxX and maxZ
+	// rounded down to a multiple of 16 long.
+	ChunkAligned bool
+
+	// X and Z are the world position of the minimum corner of the
+	// schematic, used for the chunk alignment.
+	X, Z int
+
+	// SkipEmpty leaves out the tiles without blocks and entities.
+	SkipEmpty bool
+}
+
+// SplitMaxSize splits s into tiles of at most maxX x maxY x maxZ blocks,
+// such as the size limits of structure blocks or of a WorldEdit paste.
+// The tiles are ordered by y, z and x.
+func (s *Schematic) SplitMaxSize(maxX, maxY, maxZ int, opt *SplitOptions) (tiles []Tile, err os.Error) {
+	if opt == nil {
+		opt = new(SplitOptions)
+	}
+	if maxX < 1 || maxY < 1 || maxZ < 1 {
+		return nil, fmt.Errorf("Invalid tile size: %dx%dx%d", maxX, maxY, maxZ)
+	}
+	var xs, zs []int
+	if opt.ChunkAligned {
+		if maxX < 16 || maxZ < 16 {
+			return nil, fmt.Errorf("Chunk aligned tiles need at least 16 blocks along x and z. Got: %dx%d", maxX, maxZ)
+		}
+		xs, zs = cuts(s.Width, maxX/16*16, opt.X), cuts(s.Length, maxZ/16*16, opt.Z)
+	} else {
+		xs, zs = cuts(s.Width, maxX, 0), cuts(s.Length, maxZ, 0)
+	}
+	ys := cuts(s.Height, maxY, 0)
+	for j := 1; j < len(ys); j++ {
+		for k := 1; k < len(zs); k++ {
+			for i := 1; i < len(xs); i++ {
+				b := Box{xs[i-1], ys[j-1], zs[k-1], xs[i], ys[j], zs[k]}
+				t, err := s.Crop(b)
+				if err != nil {
+					return nil, err
+				}
+				if opt.SkipEmpty && t.Bounds().Empty() && len(t.Entities) == 0 {
+					continue
+				}
+				tiles = append(tiles, Tile{b.MinX, b.MinY, b.MinZ, t})
+			}
+		}
+	}
+	return
+}
+
+// cuts returns the borders of the tiles splitting a side of n blocks into
+// parts of at most step blocks. The inner borders are at the multiples of
+// step in the coordinates where the side starts at origin.
+func cuts(n, step, origin int) []int {
+	c := []int{0}
+	if n == 0 {
+		return c
+	}
+	next := step - (origin%step+step)%step
+	for ; next < n; next += step {
+		c = append(c, next)
+	}
+	return append(c, n)
+}
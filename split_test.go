@@ -0,0 +1,70 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestCuts(t *testing.T) {
+	tests := []struct {
+		n, step, origin int
+		want            []int
+	}{
+		{10, 4, 0, []int{0, 4, 8, 10}},
+		{8, 4, 0, []int{0, 4, 8}},
+		{40, 32, 8, []int{0, 24, 40}},
+		{40, 16, -3, []int{0, 3, 19, 35, 40}},
+		{5, 16, 0, []int{0, 5}},
+	}
+	for _, tt := range tests {
+		got := cuts(tt.n, tt.step, tt.origin)
+		if len(got) != len(tt.want) {
+			t.Errorf("cuts(%d, %d, %d): want %v, got %v", tt.n, tt.step, tt.origin, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("cuts(%d, %d, %d): want %v, got %v", tt.n, tt.step, tt.origin, tt.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestSplitMaxSize(t *testing.T) {
+	s := newTestVolume()
+	tiles, err := s.SplitMaxSize(2, 2, 2, nil)
+	if err != nil {
+		t.Fatalf("SplitMaxSize: %v", err)
+	}
+	// Paste the tiles back together.
+	r := NewSchematic(s.Width, s.Height, s.Length)
+	for _, tile := range tiles {
+		ts := tile.Schematic
+		if ts.Width > 2 || ts.Height > 2 || ts.Length > 2 {
+			t.Errorf("Tile at %d,%d,%d: size %dx%dx%d", tile.X, tile.Y, tile.Z, ts.Width, ts.Height, ts.Length)
+		}
+		if ts.WEOffsetX != s.WEOffsetX+tile.X || ts.WEOffsetZ != s.WEOffsetZ+tile.Z {
+			t.Errorf("Tile at %d,%d,%d: offset %d,%d,%d", tile.X, tile.Y, tile.Z, ts.WEOffsetX, ts.WEOffsetY, ts.WEOffsetZ)
+		}
+		r.pasteSolid(ts, tile.X, tile.Y, tile.Z)
+	}
+	if r.Fingerprint() != s.Fingerprint() {
+		t.Errorf("The tiles do not add up to the schematic")
+	}
+
+	big := NewSchematic(40, 3, 20)
+	big.SetBlock(39, 0, 0, Block{1, 0})
+	tiles, err = big.SplitMaxSize(40, 3, 40, &SplitOptions{ChunkAligned: true, X: 8, Z: 0, SkipEmpty: true})
+	if err != nil {
+		t.Fatalf("SplitMaxSize: %v", err)
+	}
+	if len(tiles) != 1 || tiles[0].X != 24 || tiles[0].Schematic.Width != 16 || tiles[0].Schematic.Length != 20 {
+		t.Errorf("Chunk aligned tiles: got %v", tiles)
+	}
+	if _, err = big.SplitMaxSize(8, 8, 8, &SplitOptions{ChunkAligned: true}); err == nil {
+		t.Errorf("Chunk aligned tiles of 8 blocks: want error, got nil")
+	}
+	if _, err = big.SplitMaxSize(0, 8, 8, nil); err == nil {
+		t.Errorf("Tiles of size 0: want error, got nil")
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// A Sponge is a schematic in the Sponge format, saved by WorldEdit 7
+// and later as .schem files. Block entities and biomes are not preserved.
+type Sponge struct {
+	StateVolume
+
+	Version int // format version: 1, 2 or 3
+
+	// OffsetX, OffsetY and OffsetZ are the position of the minimum corner
+	// relative to the origin, like the WorldEdit offset of Schematic.
+	// WorldEdit writes the world position of the corner here instead, and
+	// the offset to WEOffsetX, WEOffsetY and WEOffsetZ in Metadata.
+	OffsetX, OffsetY, OffsetZ int
+
+	// Metadata is the Metadata tag, such as the name of the schematic or the
+	// WorldEdit specific fields. It may be nil.
+	Metadata *nbt.Compound
+}
+
+// ReadSponge reads a Sponge schematic of any version and translates the
+// block states to LatestDataVersion.
+func ReadSponge(r io.Reader) (sp *Sponge, err os.Error) {
+	if sp, err = readSponge(r); err != nil {
+		return
+	}
+	if err = sp.Translate(LatestDataVersion); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// readSponge reads a Sponge schematic without translating the block states.
+func readSponge(r io.Reader) (sp *Sponge, err os.Error) {
+	var root *nbt.Compound
+	if _, root, err = readRoot(r); err != nil {
+		return
+	}
+	// Version 3 nests everything into a Schematic tag.
+	if c, ok := root.Get("Schematic").(*nbt.Compound); ok {
+		root = c
+	}
+	sp = &Sponge{Version: intField(root, "Version")}
+	if sp.Version < 1 || sp.Version > 3 {
+		return nil, fmt.Errorf("Unsupported Sponge schematic version: %d", sp.Version)
+	}
+	sp.Width = intField(root, "Width") & 0xffff
+	sp.Height = intField(root, "Height") & 0xffff
+	sp.Length = intField(root, "Length") & 0xffff
+	if sp.DataVersion = intField(root, "DataVersion"); sp.DataVersion == 0 {
+		// Version 1 was written by Minecraft 1.13 tools only.
+		sp.DataVersion = DataVersion1_13
+	}
+	if off, ok := root.Get("Offset").(nbt.IntArray); ok && len(off) == 3 {
+		sp.OffsetX, sp.OffsetY, sp.OffsetZ = int(off[0]), int(off[1]), int(off[2])
+	}
+	sp.Metadata, _ = root.Get("Metadata").(*nbt.Compound)
+
+	blocks := root
+	if sp.Version == 3 {
+		if blocks, _ = root.Get("Blocks").(*nbt.Compound); blocks == nil {
+			return nil, os.NewError("Blocks tag is missing")
+		}
+	}
+	palette, ok := blocks.Get("Palette").(*nbt.Compound)
+	if !ok {
+		return nil, os.NewError("Palette tag is missing")
+	}
+	sp.Palette = make([]string, palette.Len())
+	for _, f := range palette.Fields {
+		i := intField(palette, f.Name)
+		if i < 0 || i >= len(sp.Palette) || sp.Palette[i] != "" {
+			return nil, fmt.Errorf("Invalid palette index of %s: %d", f.Name, i)
+		}
+		sp.Palette[i] = f.Name
+	}
+	dataName := "BlockData"
+	if sp.Version == 3 {
+		dataName = "Data"
+	}
+	data, ok := blocks.Get(dataName).(nbt.ByteArray)
+	if !ok {
+		return nil, fmt.Errorf("%s tag is missing", dataName)
+	}
+	// Every block takes at least a byte.
+	n, ok := volume(sp.Width, sp.Height, sp.Length, int64(len(data)))
+	if !ok {
+		return nil, fmt.Errorf("Block data is too short: want %dx%dx%d blocks, got %d bytes", sp.Width, sp.Height, sp.Length, len(data))
+	}
+	if sp.States, err = readVarints(data, n, len(sp.Palette)); err != nil {
+		return nil, err
+	}
+	sp.movePaletteAir()
+
+	if list, ok := root.Get("Entities").(*nbt.List); ok {
+		for _, tag := range list.Tags {
+			if e, ok := tag.(*nbt.Compound); ok {
+				id, _ := e.Get("Id").(nbt.String)
+				sp.Entities = append(sp.Entities, Entity{Id: string(id), NBT: e})
+			}
+		}
+	}
+	return
+}
+
+// readVarints decodes n palette indexes stored as unsigned LEB128 varints.
+func readVarints(data []byte, n, paletteLen int) (states []int, err os.Error) {
+	states = make([]int, n)
+	pos := 0
+	for i := range states {
+		v, shift := 0, uint(0)
+		for {
+			if pos >= len(data) {
+				return nil, fmt.Errorf("Block data is too short: want %d blocks, got %d", n, i)
+			}
+			b := data[pos]
+			pos++
+			v |= int(b&0x7f) << shift
+			if b&0x80 == 0 {
+				break
+			}
+			if shift += 7; shift > 28 {
+				return nil, os.NewError("Block data varint is too long")
+			}
+		}
+		if v >= paletteLen {
+			return nil, fmt.Errorf("Palette index out of range: %d", v)
+		}
+		states[i] = v
+	}
+	return
+}
+
+// movePaletteAir makes air the first palette entry, as StateVolume requires.
+func (v *StateVolume) movePaletteAir() {
+	air := -1
+	for i, state := range v.Palette {
+		if state == "minecraft:air" {
+			air = i
+			break
+		}
+	}
+	if air == 0 {
+		return
+	}
+	if air < 0 {
+		v.Palette = append(v.Palette, "minecraft:air")
+		air = len(v.Palette) - 1
+	}
+	v.Palette[0], v.Palette[air] = v.Palette[air], v.Palette[0]
+	for i, n := range v.States {
+		switch n {
+		case 0:
+			v.States[i] = air
+		case air:
+			v.States[i] = 0
+		}
+	}
+}
+
+// Schematic converts sp to legacy blocks like StateVolume.Schematic and
+// sets the WorldEdit offset. If Metadata has the offset written by
+// WorldEdit, the origin is set too, see Schematic.Origin. The Metadata tag
+// is copied to Extra, see Schematic.Metadata.
+func (sp *Sponge) Schematic() (s *Schematic, err os.Error) {
+	if s, err = sp.StateVolume.Schematic(); err != nil {
+		return
+	}
+	s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = sp.OffsetX, sp.OffsetY, sp.OffsetZ
+	if md := sp.Metadata; md != nil && md.Get("WEOffsetX") != nil {
+		s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = intField(md, "WEOffsetX"), intField(md, "WEOffsetY"), intField(md, "WEOffsetZ")
+		s.SetOrigin(sp.OffsetX-s.WEOffsetX, sp.OffsetY-s.WEOffsetY, sp.OffsetZ-s.WEOffsetZ)
+	}
+	if sp.Metadata != nil {
+		s.Extra.Set("Metadata", nbt.Clone(sp.Metadata))
+	}
+	return
+}
+
+// NewSponge converts s to a Sponge schematic of version 2 with the blocks
+// translated to LatestDataVersion. The Metadata tag of Extra is copied, see
+// Schematic.Metadata, and the origin is stored like WorldEdit does, so
+// Sponge.Schematic restores the WorldEdit offset and the origin of s.
+func NewSponge(s *Schematic) (sp *Sponge, err os.Error) {
+	var v *StateVolume
+	if v, err = NewStateVolume(s); err != nil {
+		return
+	}
+	if err = v.Translate(LatestDataVersion); err != nil {
+		return
+	}
+	sp = &Sponge{StateVolume: *v, Version: 2}
+	sp.OffsetX, sp.OffsetY, sp.OffsetZ = s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ
+	if s.Extra != nil {
+		if md, ok := s.Extra.Get("Metadata").(*nbt.Compound); ok {
+			sp.Metadata = nbt.Clone(md).(*nbt.Compound)
+		}
+	}
+	if x, y, z, ok := s.Origin(); ok {
+		if sp.Metadata == nil {
+			sp.Metadata = new(nbt.Compound)
+		}
+		sp.Metadata.Set("WEOffsetX", nbt.Int(s.WEOffsetX)).Set("WEOffsetY", nbt.Int(s.WEOffsetY)).Set("WEOffsetZ", nbt.Int(s.WEOffsetZ))
+		sp.OffsetX, sp.OffsetY, sp.OffsetZ = x+s.WEOffsetX, y+s.WEOffsetY, z+s.WEOffsetZ
+	} else if sp.Metadata != nil {
+		sp.Metadata.Delete("WEOffsetX")
+		sp.Metadata.Delete("WEOffsetY")
+		sp.Metadata.Delete("WEOffsetZ")
+	}
+	return
+}
+
+// WriteSponge writes sp in the Sponge format version 2 with the default
+// options. The Metadata tag is written as is.
+func WriteSponge(w io.Writer, sp *Sponge) os.Error {
+	return WriteSpongeOptions(w, sp, nil)
+}
+
+// WriteSpongeOptions is like WriteSponge but allows to choose the compression.
+func WriteSpongeOptions(w io.Writer, sp *Sponge, opt *WriteOptions) os.Error {
+	if err := checkDimensions(sp.Width, sp.Height, sp.Length); err != nil {
+		return err
+	}
+	if len(sp.States) != sp.Width*sp.Height*sp.Length {
+		return fmt.Errorf("States size mismatch: want %d, got %d", sp.Width*sp.Height*sp.Length, len(sp.States))
+	}
+	palette := new(nbt.Compound)
+	for i, state := range sp.Palette {
+		palette.Set(state, nbt.Int(i))
+	}
+	entities := &nbt.List{ElemType: nbt.TagCompound}
+	for _, e := range sp.Entities {
+		c := new(nbt.Compound)
+		if e.NBT != nil {
+			c.Fields = append(c.Fields, e.NBT.Fields...)
+		}
+		c.Delete("id")
+		entities.Tags = append(entities.Tags, c.Set("Id", nbt.String(e.Id)))
+	}
+	root := nbt.NewCompound().
+		Set("Version", nbt.Int(2)).
+		Set("DataVersion", nbt.Int(sp.DataVersion)).
+		Set("Width", nbt.Short(sp.Width)).
+		Set("Height", nbt.Short(sp.Height)).
+		Set("Length", nbt.Short(sp.Length)).
+		Set("Offset", nbt.IntArray{int32(sp.OffsetX), int32(sp.OffsetY), int32(sp.OffsetZ)})
+	if sp.Metadata != nil {
+		root.Set("Metadata", sp.Metadata)
+	}
+	root.Set("PaletteMax", nbt.Int(len(sp.Palette))).
+		Set("Palette", palette).
+		Set("BlockData", writeVarints(sp.States)).
+		Set("BlockEntities", &nbt.List{ElemType: nbt.TagCompound}).
+		Set("Entities", entities)
+	return writeRoot(w, "Schematic", root, opt)
+}
+
+// writeVarints is the reverse of readVarints.
+func writeVarints(states []int) nbt.ByteArray {
+	data := make(nbt.ByteArray, 0, len(states))
+	for _, v := range states {
+		for v >= 0x80 {
+			data = append(data, byte(v)|0x80)
+			v >>= 7
+		}
+		data = append(data, byte(v))
+	}
+	return data
+}
@@ -0,0 +1,148 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// spongeBytes returns a gzipped Sponge schematic of 2x1x1 blocks:
+// a grass path and air.
+func spongeBytes(t *testing.T, version int) []byte {
+	palette := nbt.NewCompound().Set("minecraft:grass_path", nbt.Int(0)).Set("minecraft:air", nbt.Int(1))
+	c := nbt.NewCompound().
+		Set("Version", nbt.Int(version)).
+		Set("Width", nbt.Short(2)).
+		Set("Height", nbt.Short(1)).
+		Set("Length", nbt.Short(1)).
+		Set("Offset", nbt.IntArray{-1, 2, 3}).
+		Set("Metadata", nbt.NewCompound().Set("Name", nbt.String("Path")))
+	if version > 1 {
+		c.Set("DataVersion", nbt.Int(DataVersion1_16))
+	}
+	if version == 3 {
+		c.Set("Blocks", nbt.NewCompound().Set("Palette", palette).Set("Data", nbt.ByteArray{0, 1}))
+	} else {
+		c.Set("Palette", palette).Set("BlockData", nbt.ByteArray{0, 1})
+	}
+	name, root := "Schematic", c
+	if version == 3 {
+		name, root = "", nbt.NewCompound().Set("Schematic", c)
+	}
+	var buf bytes.Buffer
+	if err := writeRoot(&buf, name, root, nil); err != nil {
+		t.Fatalf("writeRoot: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadSponge(t *testing.T) {
+	for version := 1; version <= 3; version++ {
+		data := spongeBytes(t, version)
+		if _, err := ReadSchematic(bytes.NewBuffer(data)); err != ErrSponge && version < 3 {
+			t.Errorf("v%d: ReadSchematic: want ErrSponge, got %v", version, err)
+		}
+		sp, err := ReadSponge(bytes.NewBuffer(data))
+		if err != nil {
+			t.Fatalf("v%d: ReadSponge: %v", version, err)
+		}
+		if sp.Version != version || sp.Width != 2 || sp.OffsetX != -1 || sp.OffsetZ != 3 {
+			t.Errorf("v%d: got version %d, width %d, offset %d %d %d", version, sp.Version, sp.Width, sp.OffsetX, sp.OffsetY, sp.OffsetZ)
+		}
+		// The air is moved to the front of the palette and the states are upgraded.
+		if sp.Palette[0] != "minecraft:air" || sp.State(0, 0, 0) != "minecraft:dirt_path" || sp.State(1, 0, 0) != "minecraft:air" {
+			t.Errorf("v%d: palette %v, states %v", version, sp.Palette, sp.States)
+		}
+		if sp.DataVersion != LatestDataVersion {
+			t.Errorf("v%d: DataVersion: want %d, got %d", version, LatestDataVersion, sp.DataVersion)
+		}
+		if name, _ := sp.Metadata.Get("Name").(nbt.String); name != "Path" {
+			t.Errorf("v%d: Metadata Name: want Path, got %q", version, name)
+		}
+		s, err := sp.Schematic()
+		if err != nil {
+			t.Fatalf("v%d: Schematic: %v", version, err)
+		}
+		if s.Block(0, 0, 0) != (Block{208, 0}) || s.WEOffsetY != 2 {
+			t.Errorf("v%d: Schematic: blocks %v, offset %d", version, s.Blocks, s.WEOffsetY)
+		}
+	}
+}
+
+func TestReadSpongeHugeSize(t *testing.T) {
+	data := rawBytes(t, spongeBytes(t, 2))
+	for _, name := range []string{"Width", "Height", "Length"} {
+		i := bytes.Index(data, []byte(name)) + len(name)
+		data[i], data[i+1] = 0xff, 0xff
+	}
+	if _, err := ReadSponge(bytes.NewBuffer(data)); err == nil {
+		t.Errorf("ReadSponge of 65535x65535x65535 blocks: want error, got nil")
+	}
+}
+
+func TestReadVarints(t *testing.T) {
+	got, err := readVarints([]byte{0x81, 0x01, 0x05, 0xff, 0x7f}, 3, 1<<14)
+	if err != nil {
+		t.Fatalf("readVarints: %v", err)
+	}
+	if got[0] != 129 || got[1] != 5 || got[2] != 16383 {
+		t.Errorf("readVarints: want [129 5 16383], got %v", got)
+	}
+	if _, err = readVarints([]byte{0x81}, 1, 200); err == nil {
+		t.Errorf("readVarints of a truncated varint: want error, got nil")
+	}
+	if _, err = readVarints([]byte{5}, 1, 5); err == nil {
+		t.Errorf("readVarints with index out of range: want error, got nil")
+	}
+}
+
+func TestWriteSponge(t *testing.T) {
+	s := newTestVolume()
+	s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = -1, 0, -2
+	s.SetOrigin(100, 64, 200)
+	sp, err := NewSponge(s)
+	if err != nil {
+		t.Fatalf("NewSponge: %v", err)
+	}
+	if sp.OffsetX != 99 || sp.OffsetY != 64 || sp.OffsetZ != 198 {
+		t.Errorf("NewSponge: want offset 99 64 198, got %d %d %d", sp.OffsetX, sp.OffsetY, sp.OffsetZ)
+	}
+	var buf bytes.Buffer
+	if err = WriteSponge(&buf, sp); err != nil {
+		t.Fatalf("WriteSponge: %v", err)
+	}
+	if sp, err = ReadSponge(&buf); err != nil {
+		t.Fatalf("ReadSponge: %v", err)
+	}
+	got, err := sp.Schematic()
+	if err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	if !bytes.Equal(got.Blocks, s.Blocks) || !bytes.Equal(got.Data, s.Data) {
+		t.Errorf("blocks: want %v %v, got %v %v", s.Blocks, s.Data, got.Blocks, got.Data)
+	}
+	if got.WEOffsetX != -1 || got.WEOffsetZ != -2 {
+		t.Errorf("WorldEdit offset: want -1 0 -2, got %d %d %d", got.WEOffsetX, got.WEOffsetY, got.WEOffsetZ)
+	}
+	if x, y, z, ok := got.Origin(); !ok || x != 100 || y != 64 || z != 200 {
+		t.Errorf("Origin: want 100 64 200, got %d %d %d %v", x, y, z, ok)
+	}
+	if len(got.Entities) != 1 || got.Entities[0].Id != "Pig" {
+		t.Errorf("Entities: got %v", got.Entities)
+	}
+}
+
+func TestWriteVarints(t *testing.T) {
+	states := []int{129, 5, 16383, 0}
+	got, err := readVarints(writeVarints(states), len(states), 1<<14)
+	if err != nil {
+		t.Fatalf("readVarints: %v", err)
+	}
+	for i := range states {
+		if got[i] != states[i] {
+			t.Errorf("want %v, got %v", states, got)
+			break
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"strings"
+)
+
+// Minecraft 1.13 replaced the numeric block ids and data values with
+// namespaced block states, such as "minecraft:oak_stairs[facing=east]".
+// The tables below map the legacy blocks to the names of the states.
+// Properties, such as the direction of stairs, are not mapped.
+
+// dyes are the names of the 16 colors, indexed by the data value.
+var dyes = []string{
+	"white", "orange", "magenta", "light_blue", "yellow", "lime", "pink", "gray",
+	"light_gray", "cyan", "purple", "blue", "brown", "green", "red", "black",
+}
+
+// legacyNames are the block state names of the legacy block ids,
+// without the minecraft: namespace.
+var legacyNames = [256]string{
+	0: "air", 1: "stone", 2: "grass_block", 3: "dirt", 4: "cobblestone",
+	5: "oak_planks", 6: "oak_sapling", 7: "bedrock", 8: "water", 9: "water",
+	10: "lava", 11: "lava", 12: "sand", 13: "gravel", 14: "gold_ore",
+	15: "iron_ore", 16: "coal_ore", 17: "oak_log", 18: "oak_leaves", 19: "sponge",
+	20: "glass", 21: "lapis_ore", 22: "lapis_block", 23: "dispenser", 24: "sandstone",
+	25: "note_block", 26: "red_bed", 27: "powered_rail", 28: "detector_rail", 29: "sticky_piston",
+	30: "cobweb", 31: "grass", 32: "dead_bush", 33: "piston", 34: "piston_head",
+	35: "white_wool", 36: "moving_piston", 37: "dandelion", 38: "poppy", 39: "brown_mushroom",
+	40: "red_mushroom", 41: "gold_block", 42: "iron_block", 43: "stone_slab", 44: "stone_slab",
+	45: "bricks", 46: "tnt", 47: "bookshelf", 48: "mossy_cobblestone", 49: "obsidian",
+	50: "torch", 51: "fire", 52: "spawner", 53: "oak_stairs", 54: "chest",
+	55: "redstone_wire", 56: "diamond_ore", 57: "diamond_block", 58: "crafting_table", 59: "wheat",
+	60: "farmland", 61: "furnace", 62: "furnace", 63: "sign", 64: "oak_door",
+	65: "ladder", 66: "rail", 67: "cobblestone_stairs", 68: "wall_sign", 69: "lever",
+	70: "stone_pressure_plate", 71: "iron_door", 72: "oak_pressure_plate", 73: "redstone_ore", 74: "redstone_ore",
+	75: "redstone_torch", 76: "redstone_torch", 77: "stone_button", 78: "snow", 79: "ice",
+	80: "snow_block", 81: "cactus", 82: "clay", 83: "sugar_cane", 84: "jukebox",
+	85: "oak_fence", 86: "carved_pumpkin", 87: "netherrack", 88: "soul_sand", 89: "glowstone",
+	90: "nether_portal", 91: "jack_o_lantern", 92: "cake", 93: "repeater", 94: "repeater",
+	95: "white_stained_glass", 96: "oak_trapdoor", 97: "infested_stone", 98: "stone_bricks", 99: "brown_mushroom_block",
+	100: "red_mushroom_block", 101: "iron_bars", 102: "glass_pane", 103: "melon", 104: "pumpkin_stem",
+	105: "melon_stem", 106: "vine", 107: "oak_fence_gate", 108: "brick_stairs", 109: "stone_brick_stairs",
+	110: "mycelium", 111: "lily_pad", 112: "nether_bricks", 113: "nether_brick_fence", 114: "nether_brick_stairs",
+	115: "nether_wart", 116: "enchanting_table", 117: "brewing_stand", 118: "cauldron", 119: "end_portal",
+	120: "end_portal_frame", 121: "end_stone", 122: "dragon_egg", 123: "redstone_lamp", 124: "redstone_lamp",
+	125: "oak_slab", 126: "oak_slab", 127: "cocoa", 128: "sandstone_stairs", 129: "emerald_ore",
+	130: "ender_chest", 131: "tripwire_hook", 132: "tripwire", 133: "emerald_block", 134: "spruce_stairs",
+	135: "birch_stairs", 136: "jungle_stairs", 137: "command_block", 138: "beacon", 139: "cobblestone_wall",
+	140: "flower_pot", 141: "carrots", 142: "potatoes", 143: "oak_button", 144: "skeleton_skull",
+	145: "anvil", 146: "trapped_chest", 147: "light_weighted_pressure_plate", 148: "heavy_weighted_pressure_plate", 149: "comparator",
+	150: "comparator", 151: "daylight_detector", 152: "redstone_block", 153: "nether_quartz_ore", 154: "hopper",
+	155: "quartz_block", 156: "quartz_stairs", 157: "activator_rail", 158: "dropper", 159: "white_terracotta",
+	160: "white_stained_glass_pane", 161: "acacia_leaves", 162: "acacia_log", 163: "acacia_stairs", 164: "dark_oak_stairs",
+	165: "slime_block", 166: "barrier", 167: "iron_trapdoor", 168: "prismarine", 169: "sea_lantern",
+	170: "hay_block", 171: "white_carpet", 172: "terracotta", 173: "coal_block", 174: "packed_ice",
+	175: "sunflower", 176: "white_banner", 177: "white_wall_banner", 178: "daylight_detector", 179: "red_sandstone",
+	180: "red_sandstone_stairs", 181: "red_sandstone_slab", 182: "red_sandstone_slab", 183: "spruce_fence_gate", 184: "birch_fence_gate",
+	185: "jungle_fence_gate", 186: "dark_oak_fence_gate", 187: "acacia_fence_gate", 188: "spruce_fence", 189: "birch_fence",
+	190: "jungle_fence", 191: "dark_oak_fence", 192: "acacia_fence", 193: "spruce_door", 194: "birch_door",
+	195: "jungle_door", 196: "acacia_door", 197: "dark_oak_door", 198: "end_rod", 199: "chorus_plant",
+	200: "chorus_flower", 201: "purpur_block", 202: "purpur_pillar", 203: "purpur_stairs", 204: "purpur_slab",
+	205: "purpur_slab", 206: "end_stone_bricks", 207: "beetroots", 208: "grass_path", 209: "end_gateway",
+	210: "repeating_command_block", 211: "chain_command_block", 212: "frosted_ice", 213: "magma_block", 214: "nether_wart_block",
+	215: "red_nether_bricks", 216: "bone_block", 217: "structure_void", 218: "observer",
+	251: "white_concrete", 252: "white_concrete_powder", 255: "structure_block",
+}
+
+// A legacyVariant lists the state names selected by the data value of a block.
+type legacyVariant struct {
+	mask  byte // bits of the data value selecting the name
+	names []string
+}
+
+var legacyVariants = map[uint16]legacyVariant{
+	1:   {15, []string{"stone", "granite", "polished_granite", "diorite", "polished_diorite", "andesite", "polished_andesite"}},
+	3:   {3, []string{"dirt", "coarse_dirt", "podzol"}},
+	5:   {7, []string{"oak_planks", "spruce_planks", "birch_planks", "jungle_planks", "acacia_planks", "dark_oak_planks"}},
+	6:   {7, []string{"oak_sapling", "spruce_sapling", "birch_sapling", "jungle_sapling", "acacia_sapling", "dark_oak_sapling"}},
+	12:  {1, []string{"sand", "red_sand"}},
+	17:  {3, []string{"oak_log", "spruce_log", "birch_log", "jungle_log"}},
+	18:  {3, []string{"oak_leaves", "spruce_leaves", "birch_leaves", "jungle_leaves"}},
+	19:  {1, []string{"sponge", "wet_sponge"}},
+	24:  {3, []string{"sandstone", "chiseled_sandstone", "cut_sandstone"}},
+	31:  {3, []string{"dead_bush", "grass", "fern"}},
+	35:  {15, colored("wool")},
+	38:  {15, []string{"poppy", "blue_orchid", "allium", "azure_bluet", "red_tulip", "orange_tulip", "white_tulip", "pink_tulip", "oxeye_daisy"}},
+	43:  {7, stoneSlabs},
+	44:  {7, stoneSlabs},
+	95:  {15, colored("stained_glass")},
+	97:  {7, []string{"infested_stone", "infested_cobblestone", "infested_stone_bricks", "infested_mossy_stone_bricks", "infested_cracked_stone_bricks", "infested_chiseled_stone_bricks"}},
+	98:  {3, []string{"stone_bricks", "mossy_stone_bricks", "cracked_stone_bricks", "chiseled_stone_bricks"}},
+	125: {7, woodenSlabs},
+	126: {7, woodenSlabs},
+	139: {1, []string{"cobblestone_wall", "mossy_cobblestone_wall"}},
+	155: {7, []string{"quartz_block", "chiseled_quartz_block", "quartz_pillar", "quartz_pillar", "quartz_pillar"}},
+	159: {15, colored("terracotta")},
+	160: {15, colored("stained_glass_pane")},
+	161: {1, []string{"acacia_leaves", "dark_oak_leaves"}},
+	162: {1, []string{"acacia_log", "dark_oak_log"}},
+	168: {3, []string{"prismarine", "prismarine_bricks", "dark_prismarine"}},
+	171: {15, colored("carpet")},
+	175: {7, []string{"sunflower", "lilac", "tall_grass", "large_fern", "rose_bush", "peony"}},
+	179: {3, []string{"red_sandstone", "chiseled_red_sandstone", "cut_red_sandstone"}},
+	251: {15, colored("concrete")},
+	252: {15, colored("concrete_powder")},
+}
+
+var stoneSlabs = []string{"stone_slab", "sandstone_slab", "petrified_oak_slab", "cobblestone_slab", "brick_slab", "stone_brick_slab", "nether_brick_slab", "quartz_slab"}
+
+var woodenSlabs = []string{"oak_slab", "spruce_slab", "birch_slab", "jungle_slab", "acacia_slab", "dark_oak_slab"}
+
+// colored returns the names of a block in all dye colors.
+func colored(name string) []string {
+	names := make([]string, len(dyes))
+	for i, dye := range dyes {
+		names[i] = dye + "_" + name
+	}
+	return names
+}
+
+func init() {
+	// Shulker boxes and glazed terracotta have an id for every color.
+	for i, dye := range dyes {
+		legacyNames[219+i] = dye + "_shulker_box"
+		legacyNames[235+i] = dye + "_glazed_terracotta"
+	}
+	stateBlocks = makeStateBlocks()
+}
+
+// stateBlocks maps the state names to the legacy blocks. If several blocks
+// have the same name, the one with the lowest id and data value is used,
+// except that slabs map to the single slabs rather than the double ones.
+var stateBlocks map[string]Block
+
+// doubleSlabs are the ids of double slabs, which share the names of the
+// single slabs with the next id.
+var doubleSlabs = map[uint16]bool{43: true, 125: true, 181: true, 204: true}
+
+func makeStateBlocks() map[string]Block {
+	m := make(map[string]Block)
+	add := func(name string, b Block) {
+		if _, ok := m[name]; !ok && name != "" {
+			m[name] = b
+		}
+	}
+	for id := range legacyNames {
+		if doubleSlabs[uint16(id)] {
+			continue
+		}
+		if v, ok := legacyVariants[uint16(id)]; ok {
+			for data, name := range v.names {
+				add(name, Block{uint16(id), byte(data)})
+			}
+		}
+		add(legacyNames[id], Block{uint16(id), 0})
+	}
+	return m
+}
+
+// LegacyState returns the name of the block state corresponding to a legacy
+// block, such as "minecraft:red_wool" for Block{35, 14}. Data values which
+// select properties, such as the direction of stairs, are ignored.
+// ok is false for unknown ids.
+func LegacyState(b Block) (state string, ok bool) {
+	if b.Id >= uint16(len(legacyNames)) || legacyNames[b.Id] == "" {
+		return "", false
+	}
+	if v, ok := legacyVariants[b.Id]; ok {
+		if i := int(b.Data & v.mask); i < len(v.names) {
+			return "minecraft:" + v.names[i], true
+		}
+		return "minecraft:" + v.names[0], true
+	}
+	return "minecraft:" + legacyNames[b.Id], true
+}
+
+// FromState returns the legacy block corresponding to a block state, the
+// reverse of LegacyState. The namespace defaults to minecraft and the
+// properties in brackets are ignored. ok is false for blocks added in
+// Minecraft 1.13 and later and for blocks of other namespaces.
+func FromState(state string) (b Block, ok bool) {
+	if i := strings.Index(state, "["); i >= 0 {
+		state = state[:i]
+	}
+	if i := strings.Index(state, ":"); i >= 0 {
+		if state[:i] != "minecraft" {
+			return Block{}, false
+		}
+		state = state[i+1:]
+	}
+	b, ok = stateBlocks[state]
+	return
+}
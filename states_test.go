@@ -0,0 +1,58 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestLegacyState(t *testing.T) {
+	tests := []struct {
+		b     Block
+		state string
+	}{
+		{Block{0, 0}, "minecraft:air"},
+		{Block{1, 5}, "minecraft:andesite"},
+		{Block{35, 14}, "minecraft:red_wool"},
+		{Block{17, 6}, "minecraft:birch_log"},
+		{Block{126, 9}, "minecraft:spruce_slab"},
+		{Block{53, 3}, "minecraft:oak_stairs"},
+		{Block{1, 12}, "minecraft:stone"},
+		{Block{220, 0}, "minecraft:orange_shulker_box"},
+		{Block{250, 0}, "minecraft:black_glazed_terracotta"},
+	}
+	for _, tt := range tests {
+		if got, ok := LegacyState(tt.b); !ok || got != tt.state {
+			t.Errorf("LegacyState(%v): want %s, got %s, %v", tt.b, tt.state, got, ok)
+		}
+	}
+	if _, ok := LegacyState(Block{253, 0}); ok {
+		t.Errorf("LegacyState(253): want false")
+	}
+}
+
+func TestFromState(t *testing.T) {
+	tests := []struct {
+		state string
+		b     Block
+	}{
+		{"minecraft:air", Block{0, 0}},
+		{"andesite", Block{1, 5}},
+		{"minecraft:red_wool", Block{35, 14}},
+		{"minecraft:oak_stairs[facing=east,half=top]", Block{53, 0}},
+		{"minecraft:water[level=0]", Block{8, 0}},
+		{"minecraft:furnace[lit=true]", Block{61, 0}},
+		{"minecraft:quartz_pillar", Block{155, 2}},
+		{"minecraft:light_gray_shulker_box", Block{227, 0}},
+		{"minecraft:spruce_slab[type=double]", Block{126, 1}},
+		{"minecraft:stone_brick_slab", Block{44, 5}},
+	}
+	for _, tt := range tests {
+		if got, ok := FromState(tt.state); !ok || got != tt.b {
+			t.Errorf("FromState(%s): want %v, got %v, %v", tt.state, tt.b, got, ok)
+		}
+	}
+	for _, state := range []string{"minecraft:deepslate", "mod:stone", ""} {
+		if b, ok := FromState(state); ok {
+			t.Errorf("FromState(%q): want false, got %v", state, b)
+		}
+	}
+}
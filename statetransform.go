@@ -0,0 +1,252 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+// The transforms of StateVolume take keepWater, which tells whether the
+// waterlogged blocks keep their water. Rotate and Mirror strip it by
+// setting waterlogged=false; Fill and Hollow leave water where a
+// waterlogged block was replaced, as Replace does.
+
+// Rotate returns v rotated clockwise, as seen from above, by the angle in
+// degrees, which must be a multiple of 90. The directional properties of
+// the states are rotated with the blocks.
+func (v *StateVolume) Rotate(angle int, keepWater bool) (*StateVolume, os.Error) {
+	w, l := float64(v.Width), float64(v.Length)
+	a := (angle%360 + 360) % 360
+	steps := a / 90
+	switch a {
+	case 0:
+		return v.transform(v.Width, v.Height, v.Length, func(x, y, z float64) (float64, float64, float64) {
+			return x, y, z
+		}, rotateState(steps, keepWater)), nil
+	case 90:
+		return v.transform(v.Length, v.Height, v.Width, func(x, y, z float64) (float64, float64, float64) {
+			return l - z, y, x
+		}, rotateState(steps, keepWater)), nil
+	case 180:
+		return v.transform(v.Width, v.Height, v.Length, func(x, y, z float64) (float64, float64, float64) {
+			return w - x, y, l - z
+		}, rotateState(steps, keepWater)), nil
+	case 270:
+		return v.transform(v.Length, v.Height, v.Width, func(x, y, z float64) (float64, float64, float64) {
+			return z, y, w - x
+		}, rotateState(steps, keepWater)), nil
+	}
+	return nil, fmt.Errorf("Angle must be a multiple of 90 degrees. Got: %d", angle)
+}
+
+// Mirror returns v mirrored along the axis. The directional properties of
+// the states are mirrored with the blocks.
+func (v *StateVolume) Mirror(axis Axis, keepWater bool) *StateVolume {
+	w, h, l := float64(v.Width), float64(v.Height), float64(v.Length)
+	return v.transform(v.Width, v.Height, v.Length, func(x, y, z float64) (float64, float64, float64) {
+		switch axis {
+		case AxisX:
+			x = w - x
+		case AxisY:
+			y = h - y
+		case AxisZ:
+			z = l - z
+		}
+		return x, y, z
+	}, mirrorState(axis, keepWater))
+}
+
+// Fill sets the blocks inside the box to the state and returns the number
+// of changed blocks. The box is clipped to the volume. If keepWater is
+// true, the filled waterlogged blocks keep their water as with Replace.
+func (v *StateVolume) Fill(b Box, state string, keepWater bool) (n int, err os.Error) {
+	if name, _, ok := splitState(state); !ok || name == "" {
+		return 0, fmt.Errorf("Invalid block state: %s", state)
+	}
+	index := make(map[int]int)
+	for y := imax(b.MinY, 0); y < imin(b.MaxY, v.Height); y++ {
+		for z := imax(b.MinZ, 0); z < imin(b.MaxZ, v.Length); z++ {
+			for x := imax(b.MinX, 0); x < imin(b.MaxX, v.Width); x++ {
+				i := (y*v.Length+z)*v.Width + x
+				old := v.States[i]
+				j, ok := index[old]
+				if !ok {
+					j = v.paletteIndex(waterlog(v.Palette[old], state, keepWater))
+					index[old] = j
+				}
+				if j != old {
+					v.States[i] = j
+					n++
+				}
+			}
+		}
+	}
+	return
+}
+
+// Hollow replaces by air the blocks which are surrounded by non-air
+// blocks on all six sides, and returns the number of changed blocks. The
+// blocks on the faces of the volume are kept. If keepWater is true, the
+// removed waterlogged blocks leave water.
+func (v *StateVolume) Hollow(keepWater bool) (n int) {
+	var inner []int
+	for y := 1; y < v.Height-1; y++ {
+		for z := 1; z < v.Length-1; z++ {
+			for x := 1; x < v.Width-1; x++ {
+				if v.State(x, y, z) != "minecraft:air" &&
+					v.State(x-1, y, z) != "minecraft:air" && v.State(x+1, y, z) != "minecraft:air" &&
+					v.State(x, y-1, z) != "minecraft:air" && v.State(x, y+1, z) != "minecraft:air" &&
+					v.State(x, y, z-1) != "minecraft:air" && v.State(x, y, z+1) != "minecraft:air" {
+					inner = append(inner, (y*v.Length+z)*v.Width+x)
+				}
+			}
+		}
+	}
+	for _, i := range inner {
+		v.States[i] = v.paletteIndex(waterlog(v.Palette[v.States[i]], "minecraft:air", keepWater))
+		n++
+	}
+	return
+}
+
+// transform returns a volume of the given size with the blocks of v moved
+// by f and their states changed by state.
+func (v *StateVolume) transform(width, height, length int, f pointFunc, state func(string) string) *StateVolume {
+	t := &StateVolume{
+		Width:       width,
+		Height:      height,
+		Length:      length,
+		DataVersion: v.DataVersion,
+		Palette:     make([]string, len(v.Palette)),
+		States:      make([]int, width*height*length),
+	}
+	for i, s := range v.Palette {
+		t.Palette[i] = state(s)
+	}
+	for y := 0; y < v.Height; y++ {
+		for z := 0; z < v.Length; z++ {
+			for x := 0; x < v.Width; x++ {
+				fx, fy, fz := f(float64(x)+0.5, float64(y)+0.5, float64(z)+0.5)
+				nx, ny, nz := int(math.Floor(fx)), int(math.Floor(fy)), int(math.Floor(fz))
+				if nx < 0 || ny < 0 || nz < 0 || nx >= width || ny >= height || nz >= length {
+					continue
+				}
+				t.States[(ny*length+nz)*width+nx] = v.States[(y*v.Length+z)*v.Width+x]
+			}
+		}
+	}
+	bounds := &Schematic{Width: width, Height: height, Length: length}
+	for _, e := range v.Entities {
+		if e, ok := bounds.moveEntity(e, f); ok {
+			t.Entities = append(t.Entities, e)
+		}
+	}
+	t.mapPalette(func(s string) string { return s })
+	return t
+}
+
+// horizontalNames are the horizontal directions in clockwise order.
+var horizontalNames = []string{"north", "east", "south", "west"}
+
+// rotateDirection rotates a horizontal direction clockwise by steps of 90
+// degrees. Other values are returned unchanged.
+func rotateDirection(dir string, steps int) string {
+	for i, d := range horizontalNames {
+		if d == dir {
+			return horizontalNames[(i+steps)%4]
+		}
+	}
+	return dir
+}
+
+var swapAxis = map[string]string{"x": "z", "z": "x"}
+
+// rotateState returns the function which rotates a block state clockwise
+// by steps of 90 degrees.
+func rotateState(steps int, keepWater bool) func(string) string {
+	return func(state string) string {
+		name, props, ok := splitState(state)
+		if !ok {
+			return state
+		}
+		res := make(map[string]string)
+		for k, p := range props {
+			switch k {
+			case "facing":
+				p = rotateDirection(p, steps)
+			case "axis":
+				if s, ok := swapAxis[p]; ok && steps%2 == 1 {
+					p = s
+				}
+			case "rotation":
+				if r, err := strconv.Atoi(p); err == nil {
+					p = strconv.Itoa((r + 4*steps) % 16)
+				}
+			case "north", "east", "south", "west":
+				k = rotateDirection(k, steps)
+			}
+			res[k] = p
+		}
+		dryState(res, keepWater)
+		return joinState(name, res)
+	}
+}
+
+// mirrorState returns the function which mirrors a block state along the axis.
+func mirrorState(axis Axis, keepWater bool) func(string) string {
+	var swap map[string]string
+	switch axis {
+	case AxisX:
+		swap = map[string]string{"east": "west", "west": "east"}
+	case AxisY:
+		swap = map[string]string{"up": "down", "down": "up", "top": "bottom", "bottom": "top"}
+	case AxisZ:
+		swap = map[string]string{"north": "south", "south": "north"}
+	}
+	hand := map[string]string{
+		"left": "right", "right": "left",
+		"inner_left": "inner_right", "inner_right": "inner_left",
+		"outer_left": "outer_right", "outer_right": "outer_left",
+	}
+	return func(state string) string {
+		name, props, ok := splitState(state)
+		if !ok {
+			return state
+		}
+		res := make(map[string]string)
+		for k, p := range props {
+			switch k {
+			case "facing", "half", "type":
+				if s, ok := swap[p]; ok {
+					p = s
+				} else if s, ok := hand[p]; ok && axis != AxisY {
+					p = s
+				}
+			case "shape", "hinge":
+				if s, ok := hand[p]; ok && axis != AxisY {
+					p = s
+				}
+			case "rotation":
+				if r, err := strconv.Atoi(p); err == nil {
+					switch axis {
+					case AxisX:
+						p = strconv.Itoa((16 - r) % 16)
+					case AxisZ:
+						p = strconv.Itoa((24 - r) % 16)
+					}
+				}
+			case "north", "east", "south", "west":
+				if s, ok := swap[k]; ok {
+					k = s
+				}
+			}
+			res[k] = p
+		}
+		dryState(res, keepWater)
+		return joinState(name, res)
+	}
+}
@@ -0,0 +1,121 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func newStairsVolume() *StateVolume {
+	return &StateVolume{
+		Width: 2, Height: 1, Length: 1,
+		DataVersion: LatestDataVersion,
+		Palette: []string{
+			"minecraft:air",
+			"minecraft:oak_stairs[facing=east,half=bottom,shape=inner_left,waterlogged=true]",
+		},
+		States: []int{1, 0},
+	}
+}
+
+func TestStateVolumeRotate(t *testing.T) {
+	tests := []struct {
+		angle     int
+		keepWater bool
+		x, z      int
+		want      string
+	}{
+		{90, true, 0, 0, "minecraft:oak_stairs[facing=south,half=bottom,shape=inner_left,waterlogged=true]"},
+		{90, false, 0, 0, "minecraft:oak_stairs[facing=south,half=bottom,shape=inner_left,waterlogged=false]"},
+		{180, true, 1, 0, "minecraft:oak_stairs[facing=west,half=bottom,shape=inner_left,waterlogged=true]"},
+		{-90, true, 0, 1, "minecraft:oak_stairs[facing=north,half=bottom,shape=inner_left,waterlogged=true]"},
+	}
+	for _, tt := range tests {
+		r, err := newStairsVolume().Rotate(tt.angle, tt.keepWater)
+		if err != nil {
+			t.Errorf("Rotate(%d): %v", tt.angle, err)
+			continue
+		}
+		if got := r.State(tt.x, 0, tt.z); got != tt.want {
+			t.Errorf("Rotate(%d, %v): want %s at %d,%d, got %s", tt.angle, tt.keepWater, tt.want, tt.x, tt.z, got)
+		}
+		if r.Palette[0] != "minecraft:air" {
+			t.Errorf("Rotate(%d): air moved: %v", tt.angle, r.Palette)
+		}
+	}
+	if _, err := newStairsVolume().Rotate(45, true); err == nil {
+		t.Errorf("Rotate(45): error expected")
+	}
+}
+
+func TestStateVolumeMirror(t *testing.T) {
+	v := newStairsVolume()
+	m := v.Mirror(AxisX, true)
+	if want, got := "minecraft:oak_stairs[facing=west,half=bottom,shape=inner_right,waterlogged=true]", m.State(1, 0, 0); got != want {
+		t.Errorf("Mirror(x, true): want %s, got %s", want, got)
+	}
+	m = v.Mirror(AxisY, false)
+	if want, got := "minecraft:oak_stairs[facing=east,half=top,shape=inner_left,waterlogged=false]", m.State(0, 0, 0); got != want {
+		t.Errorf("Mirror(y, false): want %s, got %s", want, got)
+	}
+	if got := v.State(0, 0, 0); !IsWaterlogged(got) {
+		t.Errorf("Mirror changed the source: %s", got)
+	}
+}
+
+func TestStateVolumeFill(t *testing.T) {
+	tests := []struct {
+		state     string
+		keepWater bool
+		want      string
+		n         int
+	}{
+		{"minecraft:air", true, "minecraft:water[level=0]", 2},
+		{"minecraft:air", false, "minecraft:air", 2},
+		{"minecraft:oak_fence[waterlogged=false]", true, "minecraft:oak_fence[waterlogged=true]", 1},
+		{"minecraft:oak_fence[waterlogged=false]", false, "minecraft:oak_fence[waterlogged=false]", 1},
+	}
+	for _, tt := range tests {
+		v := newWaterlogVolume()
+		n, err := v.Fill(Box{0, 0, 0, 2, 1, 1}, tt.state, tt.keepWater)
+		if err != nil || n != tt.n {
+			t.Errorf("Fill(%s, %v): want %d changed blocks, got %d, %v", tt.state, tt.keepWater, tt.n, n, err)
+			continue
+		}
+		if got := v.State(0, 0, 0); got != tt.want {
+			t.Errorf("Fill(%s, %v): want %s, got %s", tt.state, tt.keepWater, tt.want, got)
+		}
+		if got := v.State(1, 0, 0); got != tt.state {
+			t.Errorf("Fill(%s, %v): want %s at 1,0,0, got %s", tt.state, tt.keepWater, tt.state, got)
+		}
+	}
+	if _, err := newWaterlogVolume().Fill(Box{0, 0, 0, 1, 1, 1}, "[waterlogged=true]", true); err == nil {
+		t.Errorf("Fill with an invalid state: error expected")
+	}
+}
+
+func TestStateVolumeHollow(t *testing.T) {
+	for _, keepWater := range []bool{true, false} {
+		v := &StateVolume{
+			Width: 3, Height: 3, Length: 3,
+			DataVersion: LatestDataVersion,
+			Palette:     []string{"minecraft:air", "minecraft:stone", "minecraft:oak_slab[type=bottom,waterlogged=true]"},
+			States:      make([]int, 27),
+		}
+		for i := range v.States {
+			v.States[i] = 1
+		}
+		v.States[13] = 2
+		if n := v.Hollow(keepWater); n != 1 {
+			t.Errorf("Hollow(%v): want 1 changed block, got %d", keepWater, n)
+		}
+		want := "minecraft:air"
+		if keepWater {
+			want = stillWater
+		}
+		if got := v.State(1, 1, 1); got != want {
+			t.Errorf("Hollow(%v): want %s, got %s", keepWater, want, got)
+		}
+		if got := v.State(0, 1, 1); got != "minecraft:stone" {
+			t.Errorf("Hollow(%v): the shell changed: %s", keepWater, got)
+		}
+	}
+}
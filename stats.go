@@ -0,0 +1,36 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// BlockCounts returns the number of blocks of every material in the schematic.
+func (s *Schematic) BlockCounts() map[uint16]int {
+	counts := make(map[uint16]int)
+	for y := 0; y < s.YLen(); y++ {
+		for z := 0; z < s.ZLen(); z++ {
+			for x := 0; x < s.XLen(); x++ {
+				counts[s.GetV(x, y, z)]++
+			}
+		}
+	}
+	return counts
+}
+
+// Fingerprint returns a hex encoded SHA-1 hash of the dimensions and the
+// block data. Schematics with the same blocks have the same fingerprint,
+// regardless of the compression, the entities or the tag order in the file.
+func (s *Schematic) Fingerprint() string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%d %d %d\n", s.Width, s.Height, s.Length)
+	h.Write(s.Blocks)
+	h.Write(s.Data)
+	if add := s.packAdd(); add != nil {
+		h.Write(add)
+	}
+	return fmt.Sprintf("%x", h.Sum())
+}
@@ -0,0 +1,278 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"json"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// A KeyValue is the storage of a Store: a map from keys to values. It may
+// be kept in a directory, in memory or in a database, such as a table of
+// an SQLite file accessed through a driver. The keys are short names of
+// letters and digits.
+type KeyValue interface {
+	Get(key string) ([]byte, os.Error)
+	Put(key string, value []byte) os.Error
+}
+
+// MemKeyValue keeps the values in memory.
+type MemKeyValue map[string][]byte
+
+// Get returns the value of the key.
+func (m MemKeyValue) Get(key string) ([]byte, os.Error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("Key %s not found", key)
+	}
+	return v, nil
+}
+
+// Put sets the value of the key.
+func (m MemKeyValue) Put(key string, value []byte) os.Error {
+	m[key] = value
+	return nil
+}
+
+// DirKeyValue keeps every value in a file of the directory named after
+// the key.
+type DirKeyValue string
+
+// Get returns the contents of the file of the key.
+func (d DirKeyValue) Get(key string) ([]byte, os.Error) {
+	return ioutil.ReadFile(filepath.Join(string(d), key))
+}
+
+// Put writes the value to the file of the key.
+func (d DirKeyValue) Put(key string, value []byte) os.Error {
+	return ioutil.WriteFile(filepath.Join(string(d), key), value, 0644)
+}
+
+// storeChunk is the edge of the cubes of blocks stored by a Store.
+const storeChunk = 16
+
+// storeMeta is the value of the key "meta" of a Store.
+type storeMeta struct {
+	Version               int
+	Width, Height, Length int
+	Materials             Materials
+	Chunks                []int // the chunks which are not all air, see Store.chunk
+}
+
+const storeVersion = 1
+
+// A Store keeps the blocks of a schematic in a KeyValue in cubes of 16
+// blocks, so that a server can read the parts of an enormous schematic
+// without reading all of it. Every cube is stored with the palette of its
+// blocks followed by their indexes, and the cubes of air are not stored.
+// Entities, tile entities and tile ticks are not stored.
+type Store struct {
+	Width, Height, Length int
+	Materials             Materials
+
+	kv      KeyValue
+	present map[int]bool
+}
+
+// CreateStore writes the blocks of s to kv and returns the store.
+func CreateStore(kv KeyValue, s *Schematic) (st *Store, err os.Error) {
+	if err = s.checkSize(); err != nil {
+		return
+	}
+	st = &Store{Width: s.Width, Height: s.Height, Length: s.Length, Materials: s.Materials, kv: kv, present: make(map[int]bool)}
+	meta := &storeMeta{Version: storeVersion, Width: s.Width, Height: s.Height, Length: s.Length, Materials: s.Materials}
+	nx, ny, nz := st.chunks()
+	for cy := 0; cy < ny; cy++ {
+		for cz := 0; cz < nz; cz++ {
+			for cx := 0; cx < nx; cx++ {
+				b := st.chunkBox(cx, cy, cz)
+				data, air := encodeChunk(s, b)
+				if air {
+					continue
+				}
+				c := st.chunk(cx, cy, cz)
+				if err = kv.Put(chunkKey(c), data); err != nil {
+					return nil, err
+				}
+				meta.Chunks = append(meta.Chunks, c)
+				st.present[c] = true
+			}
+		}
+	}
+	var data []byte
+	if data, err = json.Marshal(meta); err != nil {
+		return nil, err
+	}
+	if err = kv.Put("meta", data); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// OpenStore opens a store written by CreateStore.
+func OpenStore(kv KeyValue) (st *Store, err os.Error) {
+	var data []byte
+	if data, err = kv.Get("meta"); err != nil {
+		return
+	}
+	meta := new(storeMeta)
+	if err = json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("Store meta: %v", err)
+	}
+	if meta.Version != storeVersion {
+		return nil, fmt.Errorf("Unsupported store version: %d", meta.Version)
+	}
+	// The size must be one CreateStore accepts, and the chunks must be
+	// within it, so that a corrupted meta cannot make ReadRegion allocate
+	// more than the schematic held.
+	if err = checkDimensions(meta.Width, meta.Height, meta.Length); err != nil {
+		return nil, fmt.Errorf("Store meta: %v", err)
+	}
+	st = &Store{Width: meta.Width, Height: meta.Height, Length: meta.Length, Materials: meta.Materials, kv: kv, present: make(map[int]bool)}
+	nx, ny, nz := st.chunks()
+	for _, c := range meta.Chunks {
+		if c < 0 || c >= nx*ny*nz || st.present[c] {
+			return nil, fmt.Errorf("Store meta: invalid chunk %d of %d", c, nx*ny*nz)
+		}
+		st.present[c] = true
+	}
+	return
+}
+
+// chunks returns the number of chunks along the axes.
+func (st *Store) chunks() (nx, ny, nz int) {
+	return (st.Width + storeChunk - 1) / storeChunk, (st.Height + storeChunk - 1) / storeChunk, (st.Length + storeChunk - 1) / storeChunk
+}
+
+// chunk returns the number of the chunk, which orders the chunks by y, z
+// and x like the blocks.
+func (st *Store) chunk(cx, cy, cz int) int {
+	nx, _, nz := st.chunks()
+	return (cy*nz+cz)*nx + cx
+}
+
+func chunkKey(c int) string {
+	return "c" + strconv.Itoa(c)
+}
+
+// chunkBox returns the blocks of the chunk, clipped to the store.
+func (st *Store) chunkBox(cx, cy, cz int) Box {
+	x, y, z := cx*storeChunk, cy*storeChunk, cz*storeChunk
+	return Box{x, y, z, imin(x+storeChunk, st.Width), imin(y+storeChunk, st.Height), imin(z+storeChunk, st.Length)}
+}
+
+// encodeChunk returns the encoding of the blocks of s in the box, and
+// whether they are all air.
+func encodeChunk(s *Schematic, b Box) (data []byte, air bool) {
+	index := make(map[Block]int)
+	var palette []Block
+	var idx []int
+	air = true
+	for y := b.MinY; y < b.MaxY; y++ {
+		for z := b.MinZ; z < b.MaxZ; z++ {
+			for x := b.MinX; x < b.MaxX; x++ {
+				bl := s.Block(x, y, z)
+				i, ok := index[bl]
+				if !ok {
+					i = len(palette)
+					index[bl] = i
+					palette = append(palette, bl)
+				}
+				idx = append(idx, i)
+				air = air && bl == Block{}
+			}
+		}
+	}
+	data = []byte{byte(len(palette)), byte(len(palette) >> 8)}
+	for _, bl := range palette {
+		data = append(data, byte(bl.Id), byte(bl.Id>>8), bl.Data)
+	}
+	for _, i := range idx {
+		if len(palette) > 256 {
+			data = append(data, byte(i), byte(i>>8))
+		} else {
+			data = append(data, byte(i))
+		}
+	}
+	return
+}
+
+// decodeChunk copies the encoded blocks of the box to s, moved by the
+// offset.
+func decodeChunk(data []byte, b Box, s *Schematic, dx, dy, dz int) os.Error {
+	bad := os.NewError("Store chunk is corrupt")
+	if len(data) < 2 {
+		return bad
+	}
+	n := int(data[0]) | int(data[1])<<8
+	if len(data) < 2+3*n {
+		return bad
+	}
+	palette := make([]Block, n)
+	for i := range palette {
+		p := data[2+3*i:]
+		palette[i] = Block{uint16(p[0]) | uint16(p[1])<<8, p[2]}
+		if palette[i].Id > MaxId {
+			return bad
+		}
+	}
+	data = data[2+3*n:]
+	size := 1
+	if n > 256 {
+		size = 2
+	}
+	if len(data) != size*(b.MaxX-b.MinX)*(b.MaxY-b.MinY)*(b.MaxZ-b.MinZ) {
+		return bad
+	}
+	for y := b.MinY; y < b.MaxY; y++ {
+		for z := b.MinZ; z < b.MaxZ; z++ {
+			for x := b.MinX; x < b.MaxX; x++ {
+				i := int(data[0])
+				if size == 2 {
+					i |= int(data[1]) << 8
+				}
+				data = data[size:]
+				if i >= n {
+					return bad
+				}
+				if s.Inside(x+dx, y+dy, z+dz) {
+					s.SetBlock(x+dx, y+dy, z+dz, palette[i])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ReadRegion reads the blocks of the box, which must be inside of the
+// store, reading only the chunks which overlap it.
+func (st *Store) ReadRegion(b Box) (s *Schematic, err os.Error) {
+	if b.Empty() || b.MinX < 0 || b.MinY < 0 || b.MinZ < 0 || b.MaxX > st.Width || b.MaxY > st.Height || b.MaxZ > st.Length {
+		return nil, fmt.Errorf("Invalid region %v for size %dx%dx%d", b, st.Width, st.Height, st.Length)
+	}
+	s = NewSchematic(b.MaxX-b.MinX, b.MaxY-b.MinY, b.MaxZ-b.MinZ)
+	s.Materials = st.Materials
+	for cy := b.MinY / storeChunk; cy*storeChunk < b.MaxY; cy++ {
+		for cz := b.MinZ / storeChunk; cz*storeChunk < b.MaxZ; cz++ {
+			for cx := b.MinX / storeChunk; cx*storeChunk < b.MaxX; cx++ {
+				c := st.chunk(cx, cy, cz)
+				if !st.present[c] {
+					continue
+				}
+				var data []byte
+				if data, err = st.kv.Get(chunkKey(c)); err != nil {
+					return nil, err
+				}
+				if err = decodeChunk(data, st.chunkBox(cx, cy, cz), s, -b.MinX, -b.MinY, -b.MinZ); err != nil {
+					return nil, fmt.Errorf("%s: %v", chunkKey(c), err)
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package schematic
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func storeSample() *Schematic {
+	s := NewSchematic(40, 20, 33)
+	for i := 0; i < 600; i++ {
+		x, y, z := i*7%40, i*3%16, i*11%33
+		s.SetBlock(x, y, z, Block{uint16(i % 300), byte(i % 16)})
+	}
+	s.Materials = Pocket
+	return s
+}
+
+func TestStore(t *testing.T) {
+	s := storeSample()
+	kv := make(MemKeyValue)
+	if _, err := CreateStore(kv, s); err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	st, err := OpenStore(kv)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	// The top layer of chunks is all air and is not stored.
+	if _, err := kv.Get(chunkKey(st.chunk(1, 1, 1))); err == nil {
+		t.Errorf("A chunk of air is stored")
+	}
+	if _, err := kv.Get(chunkKey(st.chunk(1, 0, 1))); err != nil {
+		t.Errorf("A chunk of blocks is not stored: %v", err)
+	}
+	if st.Width != 40 || st.Height != 20 || st.Length != 33 || st.Materials != Pocket {
+		t.Errorf("OpenStore: got %dx%dx%d %v", st.Width, st.Height, st.Length, st.Materials)
+	}
+	for _, b := range []Box{{0, 0, 0, 40, 20, 33}, {5, 3, 14, 31, 19, 17}, {16, 16, 16, 17, 17, 17}} {
+		r, err := st.ReadRegion(b)
+		if err != nil {
+			t.Fatalf("ReadRegion(%v): %v", b, err)
+		}
+		want, _ := s.Crop(b)
+		if r.Fingerprint() != want.Fingerprint() {
+			t.Errorf("ReadRegion(%v) differs from Crop", b)
+		}
+	}
+	if _, err := st.ReadRegion(Box{0, 0, 0, 41, 1, 1}); err == nil {
+		t.Errorf("ReadRegion outside of the store must fail")
+	}
+}
+
+func TestStoreCorrupt(t *testing.T) {
+	s := NewSchematic(2, 2, 2)
+	s.SetBlock(1, 1, 1, Block{1, 0})
+	kv := make(MemKeyValue)
+	if _, err := CreateStore(kv, s); err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	data := kv["c0"]
+	kv["c0"] = data[:len(data)-1]
+	st, err := OpenStore(kv)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	if _, err := st.ReadRegion(Box{0, 0, 0, 2, 2, 2}); err == nil {
+		t.Errorf("ReadRegion of a corrupt chunk must fail")
+	}
+	// A palette of one block with id MaxId+1.
+	kv["c0"] = append([]byte{1, 0, 0, 0x10, 0}, make([]byte, 8)...)
+	if _, err := st.ReadRegion(Box{0, 0, 0, 2, 2, 2}); err == nil {
+		t.Errorf("ReadRegion of a chunk with id %d must fail", MaxId+1)
+	}
+	if _, err := OpenStore(make(MemKeyValue)); err == nil {
+		t.Errorf("OpenStore of an empty store must fail")
+	}
+	for _, meta := range []string{
+		`{"Version":1,"Width":100000,"Height":100000,"Length":100000,"Chunks":[0]}`,
+		`{"Version":1,"Width":60000,"Height":60000,"Length":60000,"Chunks":[0]}`,
+		`{"Version":1,"Width":2,"Height":2,"Length":2,"Chunks":[1]}`,
+		`{"Version":1,"Width":2,"Height":2,"Length":2,"Chunks":[0,0]}`,
+	} {
+		kv["meta"] = []byte(meta)
+		if _, err := OpenStore(kv); err == nil {
+			t.Errorf("OpenStore with meta %s must fail", meta)
+		}
+	}
+}
+
+func TestDirKeyValue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := storeSample()
+	if _, err := CreateStore(DirKeyValue(dir), s); err != nil {
+		t.Fatalf("CreateStore: %v", err)
+	}
+	st, err := OpenStore(DirKeyValue(dir))
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	r, err := st.ReadRegion(Box{8, 0, 8, 24, 16, 24})
+	if err != nil {
+		t.Fatalf("ReadRegion: %v", err)
+	}
+	want, _ := s.Crop(Box{8, 0, 8, 24, 16, 24})
+	if r.Fingerprint() != want.Fingerprint() {
+		t.Errorf("ReadRegion differs from Crop")
+	}
+}
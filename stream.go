@@ -0,0 +1,298 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// A Writer writes a .schematic file block by block, so that generators can
+// produce schematics too large to be held in memory. The dimensions are
+// declared by NewWriter and the blocks are appended in the order of
+// Schematic.Blocks: x changes fastest, then z, then y, so a slab of whole
+// layers can be written at once.
+//
+// The block ids go to the output as they are written. The data values
+// follow the ids in the file, so they are kept in a temporary file until
+// Close. So are the high bits of the block ids above 255, up to MaxId,
+// which are written as AddBlocks. The exported fields are written by Close
+// and may be set at any time before it.
+type Writer struct {
+	Materials                       Materials
+	WEOffsetX, WEOffsetY, WEOffsetZ int
+	Entities                        []Entity
+	TileEntities                    []TileEntity
+	TileTicks                       []TileTick
+	Extra                           *nbt.Compound
+
+	width, height, length int
+	n, size               int64 // the number of blocks written and declared
+	voidAir               bool
+
+	zw     io.WriteCloser
+	nw     *nbt.Writer
+	tmp    *os.File
+	data   *bufio.Writer
+	addTmp *os.File      // nil until an id above 255 is written
+	add    *bufio.Writer // the AddBlocks nibbles
+	addLow byte          // the nibble of the last block if sw.n is odd
+	err    os.Error
+}
+
+// NewWriter starts writing a schematic of the given size to w. The
+// compression and the encoding of air are chosen by opt, as for
+// WriteSchematicOptions. The caller must call Close to complete the file.
+func NewWriter(w io.Writer, width, height, length int, opt *WriteOptions) (sw *Writer, err os.Error) {
+	if err = checkDimensions(width, height, length); err != nil {
+		return nil, err
+	}
+	size := int64(width) * int64(height) * int64(length)
+	sw = &Writer{width: width, height: height, length: length, size: size}
+	sw.voidAir = opt != nil && opt.VoidAir
+	if sw.zw, err = opt.compressor(w); err != nil {
+		return nil, err
+	}
+	if sw.tmp, err = ioutil.TempFile("", "schematic"); err != nil {
+		return nil, err
+	}
+	sw.data = bufio.NewWriter(sw.tmp)
+	sw.nw = nbt.NewWriter(sw.zw)
+	sw.writeHeader()
+	if sw.err != nil {
+		sw.removeTemp()
+		return nil, sw.err
+	}
+	return
+}
+
+func (sw *Writer) writeHeader() {
+	nw := sw.nw
+	sw.check(nw.WriteTagName(nbt.TagCompound, "Schematic"))
+	sw.check(nw.WriteTagName(nbt.TagShort, "Width"))
+	sw.check(nw.WriteShort(sw.width))
+	sw.check(nw.WriteTagName(nbt.TagShort, "Height"))
+	sw.check(nw.WriteShort(sw.height))
+	sw.check(nw.WriteTagName(nbt.TagShort, "Length"))
+	sw.check(nw.WriteShort(sw.length))
+	sw.check(nw.WriteTagName(nbt.TagByteArray, "Blocks"))
+	sw.check(nw.WriteInt(int(sw.size)))
+}
+
+// check records the first error.
+func (sw *Writer) check(err os.Error) {
+	if sw.err == nil {
+		sw.err = err
+	}
+}
+
+// Written returns the number of blocks written so far.
+func (sw *Writer) Written() int64 {
+	return sw.n
+}
+
+// WriteBlocks appends the blocks. It fails if they do not fit into the
+// declared size.
+func (sw *Writer) WriteBlocks(blocks []Block) os.Error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if int64(len(blocks)) > sw.size-sw.n {
+		return fmt.Errorf("Too many blocks: %d written, %d more, want %d", sw.n, len(blocks), sw.size)
+	}
+	ids := make([]byte, len(blocks))
+	data := make([]byte, len(blocks))
+	var high []byte
+	for i, b := range blocks {
+		if b.Id > MaxId {
+			return fmt.Errorf("Block id %d is above %d", b.Id, MaxId)
+		}
+		ids[i], data[i] = byte(b.Id), b.Data
+		if sw.voidAir && b.Id == 0 {
+			ids[i] = byte(StructureVoid.Id)
+		}
+		if b.Id > 255 {
+			if high == nil {
+				high = make([]byte, len(blocks))
+			}
+			high[i] = byte(b.Id >> 8)
+		}
+	}
+	sw.write(ids, data, high)
+	return sw.err
+}
+
+// write appends the ids, the data values and the high bits of the ids,
+// which may be nil if they are all zero.
+func (sw *Writer) write(ids, data, high []byte) {
+	_, err := sw.nw.Write(ids)
+	sw.check(err)
+	_, err = sw.data.Write(data)
+	sw.check(err)
+	if high != nil && sw.add == nil && sw.err == nil {
+		// The blocks written before have no high bits.
+		if sw.addTmp, err = ioutil.TempFile("", "schematic"); err != nil {
+			sw.check(err)
+			return
+		}
+		sw.add = bufio.NewWriter(sw.addTmp)
+		_, err = sw.add.Write(make([]byte, sw.n/2))
+		sw.check(err)
+	}
+	if sw.add != nil {
+		for i := range ids {
+			var v byte
+			if high != nil {
+				v = high[i] & 15
+			}
+			if (sw.n+int64(i))&1 == 0 {
+				sw.addLow = v
+			} else {
+				sw.check(sw.add.WriteByte(sw.addLow | v<<4))
+			}
+		}
+	}
+	sw.n += int64(len(ids))
+}
+
+// WriteSlab appends the layers of s, which must have the width and the
+// length of the schematic and start at a layer boundary. The entities,
+// tile entities and tile ticks of s are moved to the layers and added to
+// those of sw.
+func (sw *Writer) WriteSlab(s *Schematic) os.Error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if s.Width != sw.width || s.Length != sw.length {
+		return fmt.Errorf("Slab size mismatch: want %dx?x%d, got %dx%dx%d", sw.width, sw.length, s.Width, s.Height, s.Length)
+	}
+	layer := int64(sw.width * sw.length)
+	if layer > 0 && sw.n%layer != 0 {
+		return fmt.Errorf("Slab does not start at a layer: %d blocks written", sw.n)
+	}
+	if err := s.checkSize(); err != nil {
+		return err
+	}
+	if int64(len(s.Blocks)) > sw.size-sw.n {
+		return fmt.Errorf("Too many blocks: %d written, %d more, want %d", sw.n, len(s.Blocks), sw.size)
+	}
+	dy := 0
+	if layer > 0 {
+		dy = int(sw.n / layer)
+	}
+	ids := s.Blocks
+	if sw.voidAir {
+		ids = make([]byte, len(s.Blocks))
+		for i, id := range s.Blocks {
+			// The high bits of air are zero, so the void needs no AddBlocks.
+			if ids[i] = id; s.id(i) == 0 {
+				ids[i] = byte(StructureVoid.Id)
+			}
+		}
+	}
+	var high []byte
+	if s.packAdd() != nil {
+		high = s.add
+	}
+	sw.write(ids, s.Data, high)
+
+	dst := &Schematic{Width: sw.width, Height: sw.height, Length: sw.length}
+	move := translate(0, dy, 0)
+	for _, e := range s.Entities {
+		if e, ok := dst.moveEntity(e, move); ok {
+			sw.Entities = append(sw.Entities, e)
+		}
+	}
+	for _, t := range s.TileEntities {
+		sw.TileEntities = append(sw.TileEntities, t.moveTo(t.X, t.Y+dy, t.Z))
+	}
+	for _, t := range s.TileTicks {
+		t.Y += dy
+		sw.TileTicks = append(sw.TileTicks, t)
+	}
+	return sw.err
+}
+
+// Close completes the schematic and removes the temporary file. It fails
+// if fewer blocks than declared were written. Close does not close the
+// underlying writer.
+func (sw *Writer) Close() (err os.Error) {
+	defer sw.removeTemp()
+	if sw.err != nil {
+		return sw.err
+	}
+	if sw.n != sw.size {
+		sw.err = fmt.Errorf("Schematic is incomplete: %d of %d blocks written", sw.n, sw.size)
+		return sw.err
+	}
+	nw := sw.nw
+	sw.check(nw.WriteTagName(nbt.TagByteArray, "Data"))
+	sw.check(nw.WriteInt(int(sw.size)))
+	sw.check(sw.data.Flush())
+	if sw.err == nil {
+		_, err = sw.tmp.Seek(0, 0)
+		sw.check(err)
+	}
+	if sw.err == nil {
+		_, err = io.Copy(nw, sw.tmp)
+		sw.check(err)
+	}
+	if sw.add != nil && sw.err == nil {
+		if sw.n&1 == 1 {
+			sw.check(sw.add.WriteByte(sw.addLow))
+		}
+		sw.check(nw.WriteTagName(nbt.TagByteArray, "AddBlocks"))
+		sw.check(nw.WriteInt(int((sw.size + 1) / 2)))
+		sw.check(sw.add.Flush())
+		if sw.err == nil {
+			_, err = sw.addTmp.Seek(0, 0)
+			sw.check(err)
+		}
+		if sw.err == nil {
+			_, err = io.Copy(nw, sw.addTmp)
+			sw.check(err)
+		}
+	}
+	s := &Schematic{
+		Materials:    sw.Materials,
+		WEOffsetX:    sw.WEOffsetX,
+		WEOffsetY:    sw.WEOffsetY,
+		WEOffsetZ:    sw.WEOffsetZ,
+		Entities:     sw.Entities,
+		TileEntities: sw.TileEntities,
+		TileTicks:    sw.TileTicks,
+		Extra:        sw.Extra,
+	}
+	for _, f := range s.NBT().Fields {
+		switch f.Name {
+		case "Width", "Height", "Length", "Blocks", "Data":
+		default:
+			sw.check(nw.WriteTag(f.Name, f.Tag))
+		}
+	}
+	sw.check(nw.WriteByte(nbt.TagEnd))
+	sw.check(nw.Flush())
+	sw.check(sw.zw.Close())
+	if sw.err == nil {
+		// Further calls fail.
+		sw.err = os.NewError("Writer is closed")
+		return nil
+	}
+	return sw.err
+}
+
+func (sw *Writer) removeTemp() {
+	for _, f := range []*os.File{sw.tmp, sw.addTmp} {
+		if f != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	sw.tmp, sw.addTmp = nil, nil
+}
@@ -0,0 +1,136 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	s := newChestVolume()
+	s.WEOffsetY = -3
+	var buf bytes.Buffer
+	sw, err := NewWriter(&buf, s.Width, s.Height, s.Length, nil)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	// The first layer block by block, the second one as a slab.
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			if err = sw.WriteBlocks([]Block{s.Block(x, 0, z)}); err != nil {
+				t.Fatalf("WriteBlocks: %v", err)
+			}
+		}
+	}
+	sw.Entities, sw.TileTicks, sw.WEOffsetY = s.Entities, s.TileTicks, s.WEOffsetY
+	if err = sw.WriteSlab(NewSchematic(s.Width, 1, 1)); err == nil {
+		t.Errorf("WriteSlab of a wrong size: want error, got nil")
+	}
+	slab, err := s.Crop(Box{0, 1, 0, s.Width, 2, s.Length})
+	if err != nil {
+		t.Fatalf("Crop: %v", err)
+	}
+	if err = sw.WriteSlab(slab); err != nil {
+		t.Fatalf("WriteSlab: %v", err)
+	}
+	if sw.Written() != int64(len(s.Blocks)) {
+		t.Errorf("Written: want %d, got %d", len(s.Blocks), sw.Written())
+	}
+	if err = sw.WriteBlocks([]Block{{1, 0}}); err == nil {
+		t.Errorf("WriteBlocks past the end: want error, got nil")
+	}
+	if err = sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	if !bytes.Equal(got.Blocks, s.Blocks) || !bytes.Equal(got.Data, s.Data) {
+		t.Errorf("Blocks: want %v %v, got %v %v", s.Blocks, s.Data, got.Blocks, got.Data)
+	}
+	if got.WEOffsetY != -3 || len(got.Entities) != 1 || len(got.TileTicks) != 1 || got.TileTicks[0].X != 2 {
+		t.Errorf("Schematic: got %+v", got)
+	}
+	if te := got.TileEntityAt(2, 1, 1); te == nil || te.Id != "Chest" {
+		t.Errorf("TileEntityAt(2, 1, 1): got %+v", te)
+	}
+}
+
+func TestWriterIncomplete(t *testing.T) {
+	if _, err := NewWriter(new(bytes.Buffer), MaxDimension+1, 1, 1, nil); err == nil {
+		t.Errorf("NewWriter of width %d: want error, got nil", MaxDimension+1)
+	}
+	sw, err := NewWriter(new(bytes.Buffer), 2, 1, 1, &WriteOptions{VoidAir: true})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	sw.WriteBlocks([]Block{{}})
+	if err = sw.Close(); err == nil {
+		t.Errorf("Close after 1 of 2 blocks: want error, got nil")
+	}
+}
+
+func TestWriterAddBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	sw, err := NewWriter(&buf, 3, 2, 1, nil)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	// The first id above 255 comes after an odd number of blocks.
+	if err = sw.WriteBlocks([]Block{{1, 0}}); err != nil {
+		t.Fatalf("WriteBlocks: %v", err)
+	}
+	if err = sw.WriteBlocks([]Block{{MaxId + 1, 0}}); err == nil {
+		t.Errorf("WriteBlocks of id %d: want error, got nil", MaxId+1)
+	}
+	if err = sw.WriteBlocks([]Block{{300, 2}, {MaxId, 0}}); err != nil {
+		t.Fatalf("WriteBlocks: %v", err)
+	}
+	slab := NewSchematic(3, 1, 1)
+	slab.SetBlock(1, 0, 0, Block{2000, 5})
+	if err = sw.WriteSlab(slab); err != nil {
+		t.Fatalf("WriteSlab: %v", err)
+	}
+	if err = sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	want := []Block{{1, 0}, {300, 2}, {MaxId, 0}, {}, {2000, 5}, {}}
+	for i, b := range want {
+		x, y, z := got.coords(i)
+		if g := got.Block(x, y, z); g != b {
+			t.Errorf("Block(%d, %d, %d): want %v, got %v", x, y, z, b, g)
+		}
+	}
+}
+
+func TestWriterVoidAirAddBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	sw, err := NewWriter(&buf, 4, 1, 1, &WriteOptions{VoidAir: true})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	slab := NewSchematic(4, 1, 1)
+	slab.SetBlock(0, 0, 0, Block{256, 0})
+	slab.SetBlock(1, 0, 0, Block{512, 3})
+	if err = sw.WriteSlab(slab); err != nil {
+		t.Fatalf("WriteSlab: %v", err)
+	}
+	if err = sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	want := []Block{{256, 0}, {512, 3}, {StructureVoid.Id, 0}, {StructureVoid.Id, 0}}
+	for x, b := range want {
+		if g := got.Block(x, 0, 0); g != b {
+			t.Errorf("Block(%d, 0, 0): want %v, got %v", x, b, g)
+		}
+	}
+}
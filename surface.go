@@ -0,0 +1,34 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+// TopSurface returns the positions of the highest block other than air
+// of every column, with z changing slower than x, like the blocks
+// selected by TopMask(NotMask(IdMask(0))). The columns of air have none.
+func (s *Schematic) TopSurface() (top []BlockPos) {
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			for y := s.Height - 1; y >= 0; y-- {
+				if s.GetV(x, y, z) != 0 {
+					top = append(top, BlockPos{x, y, z})
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+// RepaintSurface sets the blocks returned by TopSurface to the blocks of
+// the pattern, which may be a single Block, and returns their number. The
+// blocks under the surface are left as they are, so a terrain may be
+// restyled, such as by covering it with sand, without changing its shape
+// or what is inside of it.
+func (s *Schematic) RepaintSurface(p Pattern) int {
+	top := s.TopSurface()
+	for _, pos := range top {
+		s.SetBlock(pos.X, pos.Y, pos.Z, p.BlockAt(pos.X, pos.Y, pos.Z))
+	}
+	return len(top)
+}
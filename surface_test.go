@@ -0,0 +1,38 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestTopSurface(t *testing.T) {
+	// Stone steps, a floating block of wool above the first step and an
+	// empty column.
+	s := NewSchematic(3, 4, 1)
+	for x := 0; x < 2; x++ {
+		for y := 0; y <= x; y++ {
+			s.SetBlock(x, y, 0, Block{1, 0})
+		}
+	}
+	s.SetBlock(0, 3, 0, Block{35, 14})
+	want := []BlockPos{{0, 3, 0}, {1, 1, 0}}
+	top := s.TopSurface()
+	if len(top) != len(want) {
+		t.Fatalf("TopSurface: want %v, got %v", want, top)
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Errorf("TopSurface: want %v, got %v", want, top)
+		}
+	}
+	if n := s.RepaintSurface(Block{12, 0}); n != 2 {
+		t.Errorf("RepaintSurface: want 2 blocks, got %d", n)
+	}
+	for _, c := range []struct {
+		x, y int
+		b    Block
+	}{{0, 0, Block{1, 0}}, {0, 3, Block{12, 0}}, {1, 0, Block{1, 0}}, {1, 1, Block{12, 0}}, {2, 0, Block{}}} {
+		if b := s.Block(c.x, c.y, 0); b != c.b {
+			t.Errorf("Block(%d, %d, 0) after RepaintSurface: want %v, got %v", c.x, c.y, c.b, b)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// SVGOptions control WriteSliceSVG. A nil *SVGOptions selects the
+// defaults.
+type SVGOptions struct {
+	// Scale is the size of a block in SVG units. Default: 16.
+	Scale int
+
+	// Grid draws the lines between the blocks.
+	Grid bool
+
+	// Labels numbers every fifth row and column of blocks on the margins
+	// and adds a legend of the materials with their counts.
+	Labels bool
+
+	// Language is the language of the names of the blocks. Default:
+	// English.
+	Language Language
+}
+
+// A sliceRect is a rectangle of the same blocks of a slice, in blocks.
+type sliceRect struct {
+	u, v, w, h int
+}
+
+// slice is a cross-section of a schematic: a rectangle of blocks where u
+// grows to the right and v down.
+type slice struct {
+	w, h  int
+	block func(u, v int) Block
+}
+
+// newSlice returns the cross-section of s perpendicular to the axis at
+// the position. The horizontal layers are seen from above with the north
+// up; the vertical ones are seen from the south or the east with the top
+// up.
+func newSlice(s *Schematic, axis Axis, pos int) (*slice, os.Error) {
+	var n int
+	sl := new(slice)
+	switch axis {
+	case AxisY:
+		n, sl.w, sl.h = s.Height, s.Width, s.Length
+		sl.block = func(u, v int) Block { return s.Block(u, pos, v) }
+	case AxisZ:
+		n, sl.w, sl.h = s.Length, s.Width, s.Height
+		sl.block = func(u, v int) Block { return s.Block(u, s.Height-1-v, pos) }
+	case AxisX:
+		n, sl.w, sl.h = s.Width, s.Length, s.Height
+		sl.block = func(u, v int) Block { return s.Block(pos, s.Height-1-v, u) }
+	default:
+		return nil, fmt.Errorf("Unknown axis: %d", axis)
+	}
+	if pos < 0 || pos >= n {
+		return nil, fmt.Errorf("Slice %d is out of range [0, %d)", pos, n)
+	}
+	return sl, nil
+}
+
+// rects merges the blocks of the slice other than air into rectangles of
+// the same blocks: runs along u are merged with the equal runs below them.
+func (sl *slice) rects() map[Block][]sliceRect {
+	m := make(map[Block][]sliceRect)
+	open := make(map[sliceRect]int) // the runs of the previous row, with h unset, to their rectangles
+	for v := 0; v < sl.h; v++ {
+		next := make(map[sliceRect]int)
+		for u := 0; u < sl.w; {
+			b := sl.block(u, v)
+			end := u + 1
+			for end < sl.w && sl.block(end, v) == b {
+				end++
+			}
+			if b != (Block{}) {
+				run := sliceRect{u, 0, end - u, 0}
+				if i, ok := open[run]; ok && sl.block(u, v-1) == b {
+					m[b][i].h++
+					next[run] = i
+				} else {
+					m[b] = append(m[b], sliceRect{u, v, end - u, 1})
+					next[run] = len(m[b]) - 1
+				}
+			}
+			u = end
+		}
+		open = next
+	}
+	return m
+}
+
+// svgColor returns the color of the block in SVG syntax.
+func svgColor(b Block) string {
+	c := BlockColor(b.Id, b.Data)
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+type blockSlice []Block
+
+func (p blockSlice) Len() int { return len(p) }
+func (p blockSlice) Less(i, j int) bool {
+	return p[i].Id < p[j].Id || p[i].Id == p[j].Id && p[i].Data < p[j].Data
+}
+func (p blockSlice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+// WriteSliceSVG draws the cross-section of s perpendicular to the axis at
+// the position as an SVG image, such as the layer y of a blueprint with
+// AxisY. The layers are seen from above with the north up, and the
+// vertical slices from the south for AxisZ and from the east for AxisX.
+// The blocks of a material are merged into few rectangles, so the image
+// stays small and crisp at any zoom.
+func WriteSliceSVG(w io.Writer, s *Schematic, axis Axis, pos int, opt *SVGOptions) os.Error {
+	sl, err := newSlice(s, axis, pos)
+	if err != nil {
+		return err
+	}
+	o := SVGOptions{Scale: 16}
+	if opt != nil {
+		o = *opt
+		if o.Scale <= 0 {
+			o.Scale = 16
+		}
+	}
+	rects := sl.rects()
+	var blocks []Block
+	for b := range rects {
+		blocks = append(blocks, b)
+	}
+	sort.Sort(blockSlice(blocks))
+
+	k := o.Scale
+	margin, legend := 0, 0
+	if o.Labels {
+		margin = 2 * k
+		legend = (len(blocks) + 1) * k
+	}
+	width, height := sl.w*k+margin, sl.h*k+margin+legend
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(bw, `<g transform="translate(%d,%d)" shape-rendering="crispEdges">`+"\n", margin, margin)
+	for _, b := range blocks {
+		fmt.Fprintf(bw, `<g fill="%s"><title>%s</title>`, svgColor(b), htmlEscape(o.Language.DisplayName(b)))
+		for _, r := range rects[b] {
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d"/>`, r.u*k, r.v*k, r.w*k, r.h*k)
+		}
+		fmt.Fprintf(bw, "</g>\n")
+	}
+	if o.Grid {
+		fmt.Fprintf(bw, `<path stroke="#808080" stroke-width="1" fill="none" d="`)
+		for u := 0; u <= sl.w; u++ {
+			fmt.Fprintf(bw, "M%d 0V%d", u*k, sl.h*k)
+		}
+		for v := 0; v <= sl.h; v++ {
+			fmt.Fprintf(bw, "M0 %dH%d", v*k, sl.w*k)
+		}
+		fmt.Fprintf(bw, "\"/>\n")
+	}
+	fmt.Fprintf(bw, "</g>\n")
+	if o.Labels {
+		writeSVGLabels(bw, sl, rects, blocks, k, o.Language)
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// writeSVGLabels writes the numbers of the rows and the columns and the
+// legend of the materials.
+func writeSVGLabels(bw *bufio.Writer, sl *slice, rects map[Block][]sliceRect, blocks []Block, k int, lang Language) {
+	text := func(x, y int, anchor, str string) {
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" font-family="sans-serif" text-anchor="%s">%s</text>`+"\n",
+			x, y, k*3/4, anchor, str)
+	}
+	margin := 2 * k
+	for u := 0; u < sl.w; u += 5 {
+		text(margin+u*k+k/2, margin-k/2, "middle", strconv.Itoa(u))
+	}
+	for v := 0; v < sl.h; v += 5 {
+		text(margin-k/4, margin+v*k+k*3/4, "end", strconv.Itoa(v))
+	}
+	y := margin + sl.h*k + k
+	for _, b := range blocks {
+		n := 0
+		for _, r := range rects[b] {
+			n += r.w * r.h
+		}
+		fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#000000"/>`+"\n", margin, y, k*3/4, k*3/4, svgColor(b))
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" font-family="sans-serif">%s × %d</text>`+"\n", margin+k, y+k*5/8, k*3/4, htmlEscape(lang.DisplayName(b)), n)
+		y += k
+	}
+}
@@ -0,0 +1,78 @@
+package schematic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSliceRects(t *testing.T) {
+	// An L of stone with a block of wool in its corner.
+	s := NewSchematic(4, 1, 3)
+	for x := 0; x < 4; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+	}
+	for z := 1; z < 3; z++ {
+		s.SetBlock(0, 0, z, Block{1, 0})
+		s.SetBlock(1, 0, z, Block{1, 0})
+	}
+	s.SetBlock(3, 0, 2, Block{35, 14})
+	sl, err := newSlice(s, AxisY, 0)
+	if err != nil {
+		t.Fatalf("newSlice: %v", err)
+	}
+	rects := sl.rects()
+	stone := rects[Block{1, 0}]
+	if len(stone) != 2 || stone[0] != (sliceRect{0, 0, 4, 1}) || stone[1] != (sliceRect{0, 1, 2, 2}) {
+		t.Errorf("Stone: got %v", stone)
+	}
+	if wool := rects[Block{35, 14}]; len(wool) != 1 || wool[0] != (sliceRect{3, 2, 1, 1}) {
+		t.Errorf("Wool: got %v", wool)
+	}
+	if len(rects) != 2 {
+		t.Errorf("Rects: want 2 materials, got %v", rects)
+	}
+
+	// The vertical slices have the top up.
+	if sl, err = newSlice(s, AxisZ, 2); err != nil {
+		t.Fatalf("newSlice: %v", err)
+	}
+	if b := sl.block(3, 0); sl.w != 4 || sl.h != 1 || b != (Block{35, 14}) {
+		t.Errorf("Slice of z=2: got %dx%d, %v", sl.w, sl.h, b)
+	}
+	for _, pos := range []int{-1, 1} {
+		if _, err = newSlice(s, AxisY, pos); err == nil {
+			t.Errorf("newSlice(y=%d): want error, got nil", pos)
+		}
+	}
+}
+
+func TestWriteSliceSVG(t *testing.T) {
+	s := NewSchematic(2, 1, 2)
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	s.SetBlock(1, 0, 0, Block{1, 0})
+	s.SetBlock(1, 0, 1, Block{4000, 0})
+	var buf bytes.Buffer
+	if err := WriteSliceSVG(&buf, s, AxisY, 0, &SVGOptions{Scale: 10, Grid: true, Labels: true}); err != nil {
+		t.Fatalf("WriteSliceSVG: %v", err)
+	}
+	svg := buf.String()
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="40" height="70"`,
+		`<g fill="#7d7d7d"><title>Stone</title><rect x="0" y="0" width="20" height="10"/></g>`,
+		`<title>4000:0</title><rect x="10" y="10" width="10" height="10"/>`,
+		"M0 20H20",
+		">Stone × 2</text>",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("WriteSliceSVG: want %q in\n%s", want, svg)
+		}
+	}
+	buf.Reset()
+	if err := WriteSliceSVG(&buf, s, AxisY, 0, nil); err != nil {
+		t.Fatalf("WriteSliceSVG: %v", err)
+	}
+	if svg = buf.String(); strings.Contains(svg, "<text") || strings.Contains(svg, "<path") {
+		t.Errorf("WriteSliceSVG without grid and labels: got\n%s", svg)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"sync"
+)
+
+// A SyncedVolume is a Volume which may be used by several goroutines at
+// once, such as a web server reading a schematic while a background job
+// edits it. Every section of 16x16x16 blocks has its own lock, so the
+// reads of a section wait only for the edits of the same section. The
+// edits are applied one at a time, because changing a block of a Schematic
+// also updates its tile entities and its snapshots.
+type SyncedVolume struct {
+	v          Volume
+	nx, ny, nz int // the number of sections along the axes
+	sections   []sync.RWMutex
+	mu         sync.Mutex // held by SetBlock
+}
+
+// Synced returns a SyncedVolume guarding v. The size of v must not change
+// and v must not be used directly while it is shared.
+func Synced(v Volume) *SyncedVolume {
+	nx := (v.XLen() + sectionSize - 1) / sectionSize
+	ny := (v.YLen() + sectionSize - 1) / sectionSize
+	nz := (v.ZLen() + sectionSize - 1) / sectionSize
+	return &SyncedVolume{v: v, nx: nx, ny: ny, nz: nz, sections: make([]sync.RWMutex, nx*ny*nz)}
+}
+
+// XLen is the number of blocks by X axis.
+func (sv *SyncedVolume) XLen() int {
+	return sv.v.XLen()
+}
+
+// YLen is the number of blocks by Y axis.
+func (sv *SyncedVolume) YLen() int {
+	return sv.v.YLen()
+}
+
+// ZLen is the number of blocks by Z axis.
+func (sv *SyncedVolume) ZLen() int {
+	return sv.v.ZLen()
+}
+
+// section returns the lock of the section of the position, or nil if the
+// position is outside of the volume.
+func (sv *SyncedVolume) section(x, y, z int) *sync.RWMutex {
+	if x < 0 || y < 0 || z < 0 || x >= sv.v.XLen() || y >= sv.v.YLen() || z >= sv.v.ZLen() {
+		return nil
+	}
+	return &sv.sections[(y/sectionSize*sv.nz+z/sectionSize)*sv.nx+x/sectionSize]
+}
+
+// Block returns the block at the specified position.
+func (sv *SyncedVolume) Block(x, y, z int) Block {
+	l := sv.section(x, y, z)
+	if l == nil {
+		return Block{}
+	}
+	l.RLock()
+	defer l.RUnlock()
+	return sv.v.Block(x, y, z)
+}
+
+// SetBlock sets the block at the specified position.
+// It panics if the position is outside of the volume.
+func (sv *SyncedVolume) SetBlock(x, y, z int, b Block) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	l := sv.section(x, y, z)
+	if l == nil || sv.growsAdd(b) {
+		// The volume panics for a position outside of it, and a block id
+		// above 255 may reallocate the high bits of all block ids.
+		sv.lockAll()
+		defer sv.unlockAll()
+		sv.v.SetBlock(x, y, z, b)
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	sv.v.SetBlock(x, y, z, b)
+}
+
+// growsAdd reports whether setting b reallocates the high bits of the
+// block ids of the underlying schematic.
+func (sv *SyncedVolume) growsAdd(b Block) bool {
+	var s *Schematic
+	switch v := sv.v.(type) {
+	case *Schematic:
+		s = v
+	case *BoxView:
+		s = v.s
+	default:
+		return false
+	}
+	return b.Id > 255 && len(s.add) < len(s.Blocks)
+}
+
+// The sections are always locked in the same order, so lockAll and Read
+// do not deadlock each other.
+
+func (sv *SyncedVolume) lockAll() {
+	for i := range sv.sections {
+		sv.sections[i].Lock()
+	}
+}
+
+func (sv *SyncedVolume) unlockAll() {
+	for i := range sv.sections {
+		sv.sections[i].Unlock()
+	}
+}
+
+// Read calls f with the underlying volume while no edits are applied, so
+// that f sees a consistent state, for example to write the schematic to a
+// file. The function must not change the volume.
+func (sv *SyncedVolume) Read(f func(v Volume)) {
+	for i := range sv.sections {
+		sv.sections[i].RLock()
+	}
+	defer func() {
+		for i := range sv.sections {
+			sv.sections[i].RUnlock()
+		}
+	}()
+	f(sv.v)
+}
@@ -0,0 +1,54 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestSynced(t *testing.T) {
+	s := NewSchematic(40, 20, 40)
+	sv := Synced(s)
+	if sv.XLen() != 40 || sv.YLen() != 20 || sv.ZLen() != 40 {
+		t.Fatalf("Wrong size: %dx%dx%d", sv.XLen(), sv.YLen(), sv.ZLen())
+	}
+	done := make(chan bool)
+	for w := 0; w < 4; w++ {
+		go func(w int) {
+			for y := w; y < sv.YLen(); y += 4 {
+				for z := 0; z < sv.ZLen(); z++ {
+					for x := 0; x < sv.XLen(); x++ {
+						sv.SetBlock(x, y, z, Block{uint16(1 + (x+y+z)%300), 0})
+					}
+				}
+			}
+			done <- true
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		go func() {
+			for i := 0; i < 10000; i++ {
+				if b := sv.Block(i%40, i/40%20, i/800%40); b.Id > 300 {
+					t.Errorf("Block(%d): unexpected id %d", i, b.Id)
+				}
+			}
+			sv.Read(func(v Volume) {
+				v.Block(0, 0, 0)
+			})
+			done <- true
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+	for y := 0; y < 20; y++ {
+		for z := 0; z < 40; z++ {
+			for x := 0; x < 40; x++ {
+				if b, want := sv.Block(x, y, z), uint16(1+(x+y+z)%300); b.Id != want {
+					t.Fatalf("Block(%d, %d, %d): want %d, got %d", x, y, z, want, b.Id)
+				}
+			}
+		}
+	}
+	if b := sv.Block(-1, 0, 0); b != (Block{}) {
+		t.Errorf("Block outside: want air, got %v", b)
+	}
+}
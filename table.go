@@ -0,0 +1,62 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A BlockRecord describes a block as a row of a table.
+type BlockRecord struct {
+	X, Y, Z int
+	Block
+	Name string // the block state name returned by LegacyState, or "" if unknown
+}
+
+// TableOptions control Records and WriteCSV.
+type TableOptions struct {
+	// SkipAir leaves out the air blocks.
+	SkipAir bool
+}
+
+// Records calls f for every block of s in the order of Blocks. The record
+// is reused, so f must copy it to keep it. A nil opt selects the defaults.
+func (s *Schematic) Records(opt *TableOptions, f func(r *BlockRecord)) {
+	skipAir := opt != nil && opt.SkipAir
+	names := make(map[Block]string)
+	var r BlockRecord
+	for i := range s.Blocks {
+		b := Block{s.id(i), s.Data[i]}
+		if skipAir && b.Id == 0 {
+			continue
+		}
+		name, ok := names[b]
+		if !ok {
+			name, _ = LegacyState(b)
+			names[b] = name
+		}
+		x, y, z := s.coords(i)
+		r = BlockRecord{x, y, z, b, name}
+		f(&r)
+	}
+}
+
+// WriteCSV writes the blocks of s to w as CSV with the columns x, y, z,
+// id, data and name after a header row, for loading into databases and
+// data analysis tools. A nil opt selects the defaults.
+func WriteCSV(w io.Writer, s *Schematic, opt *TableOptions) os.Error {
+	if err := s.checkSize(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "x,y,z,id,data,name\n")
+	s.Records(opt, func(r *BlockRecord) {
+		// The state names contain no commas or quotes.
+		fmt.Fprintf(bw, "%d,%d,%d,%d,%d,%s\n", r.X, r.Y, r.Z, r.Id, r.Data, r.Name)
+	})
+	return bw.Flush()
+}
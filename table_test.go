@@ -0,0 +1,32 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	s := NewSchematic(2, 1, 2)
+	s.SetBlock(1, 0, 0, Block{35, 14})
+	s.SetBlock(0, 0, 1, Block{4000, 0})
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, s, nil); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	want := "x,y,z,id,data,name\n" +
+		"0,0,0,0,0,minecraft:air\n" +
+		"1,0,0,35,14,minecraft:red_wool\n" +
+		"0,0,1,4000,0,\n" +
+		"1,0,1,0,0,minecraft:air\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV:\nwant %q\ngot  %q", want, buf.String())
+	}
+	buf.Reset()
+	if err := WriteCSV(&buf, s, &TableOptions{SkipAir: true}); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	want = "x,y,z,id,data,name\n1,0,0,35,14,minecraft:red_wool\n0,0,1,4000,0,\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV without air:\nwant %q\ngot  %q", want, buf.String())
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+)
+
+// StructureVoid is the structure void block, a common marker in templates.
+var StructureVoid = Block{217, 0}
+
+// A Param replaces the marker blocks of a template, such as red wool or
+// structure void, when the template is instantiated. Exactly one of
+// Pattern and Schematic must be set.
+type Param struct {
+	Marker Block
+
+	// Pattern replaces every marker block.
+	Pattern Pattern
+
+	// Schematic is pasted at every group of connected marker blocks, with
+	// its minimum corner at the minimum corner of the group. The marker
+	// blocks become air, and the air of Schematic does not replace the
+	// blocks of the template. The blocks outside of the template are
+	// dropped.
+	Schematic *Schematic
+}
+
+// Instantiate returns a copy of the template with the marker blocks
+// substituted by the params, in order. Markers without a param are kept.
+func Instantiate(tmpl *Schematic, params ...Param) (*Schematic, os.Error) {
+	s := tmpl.transform(tmpl.Width, tmpl.Height, tmpl.Length, translate(0, 0, 0))
+	for _, p := range params {
+		switch {
+		case (p.Pattern == nil) == (p.Schematic == nil):
+			return nil, fmt.Errorf("Param for %d:%d needs either a pattern or a schematic", p.Marker.Id, p.Marker.Data)
+		case p.Pattern != nil:
+			s.Fill(BlockMask(p.Marker), p.Pattern)
+		default:
+			for _, g := range s.groups(p.Marker) {
+				s.Fill(AndMask(BlockMask(p.Marker), RegionMask(g)), Block{})
+				s.pasteSolid(p.Schematic, g.MinX, g.MinY, g.MinZ)
+			}
+		}
+	}
+	return s, nil
+}
+
+// groups returns the bounds of the groups of connected blocks equal to b,
+// in the order of their first blocks.
+func (s *Schematic) groups(b Block) (boxes []Box) {
+	seen := make([]bool, len(s.Blocks))
+	for _, start := range s.selected(BlockMask(b)) {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		g := Box{s.Width, s.Height, s.Length, 0, 0, 0}
+		queue := []int{start}
+		for len(queue) > 0 {
+			i := queue[0]
+			queue = queue[1:]
+			x, y, z := s.coords(i)
+			g.MinX, g.MaxX = imin(g.MinX, x), imax(g.MaxX, x+1)
+			g.MinY, g.MaxY = imin(g.MinY, y), imax(g.MaxY, y+1)
+			g.MinZ, g.MaxZ = imin(g.MinZ, z), imax(g.MaxZ, z+1)
+			for _, d := range [][3]int{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}} {
+				nx, ny, nz := x+d[0], y+d[1], z+d[2]
+				if nx < 0 || ny < 0 || nz < 0 || nx >= s.Width || ny >= s.Height || nz >= s.Length || s.Block(nx, ny, nz) != b {
+					continue
+				}
+				if j := s.index(nx, ny, nz); !seen[j] {
+					seen[j] = true
+					queue = append(queue, j)
+				}
+			}
+		}
+		boxes = append(boxes, g)
+	}
+	return
+}
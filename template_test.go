@@ -0,0 +1,55 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestInstantiate(t *testing.T) {
+	// A floor of red wool with two windows of structure void in a wall.
+	tmpl := NewSchematic(8, 4, 3)
+	for x := 0; x < 8; x++ {
+		for z := 0; z < 3; z++ {
+			tmpl.SetBlock(x, 0, z, Block{35, 14})
+		}
+		for y := 1; y < 4; y++ {
+			tmpl.SetBlock(x, y, 0, Block{1, 0})
+		}
+	}
+	for _, x := range []int{1, 2, 5, 6} {
+		tmpl.SetBlock(x, 2, 0, StructureVoid)
+	}
+	window := NewSchematic(2, 1, 2)
+	window.SetBlock(0, 0, 0, Block{20, 0})
+	window.SetBlock(1, 0, 0, Block{20, 0})
+	window.SetBlock(0, 0, 1, Block{50, 5}) // a torch behind the window
+
+	s, err := Instantiate(tmpl,
+		Param{Marker: Block{35, 14}, Pattern: Block{5, 2}},
+		Param{Marker: StructureVoid, Schematic: window})
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	if n := len(s.selected(BlockMask(Block{5, 2}))); n != 24 {
+		t.Errorf("Floor: want 24 planks, got %d", n)
+	}
+	if n := len(s.selected(IdMask(20))); n != 4 || s.GetV(2, 2, 0) != 20 || s.GetV(5, 2, 0) != 20 {
+		t.Errorf("Windows: want 4 glass blocks, got %d", n)
+	}
+	if s.GetV(1, 2, 1) != 50 || s.GetV(5, 2, 1) != 50 {
+		t.Errorf("Torches: got %v, %v", s.Block(1, 2, 1), s.Block(5, 2, 1))
+	}
+	if n := len(s.selected(BlockMask(StructureVoid))); n != 0 {
+		t.Errorf("Markers left: %d", n)
+	}
+	if tmpl.GetV(1, 2, 0) != 217 {
+		t.Errorf("Instantiate changed the template")
+	}
+
+	// A marker without a param is kept.
+	if s, _ = Instantiate(tmpl); s.Fingerprint() != tmpl.Fingerprint() {
+		t.Errorf("Instantiate without params changed the blocks")
+	}
+	if _, err = Instantiate(tmpl, Param{Marker: StructureVoid}); err == nil {
+		t.Errorf("Instantiate with an empty param: want error, got nil")
+	}
+}
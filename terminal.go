@@ -0,0 +1,95 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// TerminalOptions control RenderTerminal. A nil *TerminalOptions selects
+// the defaults.
+type TerminalOptions struct {
+	// Plain draws the blocks with letters instead of ANSI colors, for
+	// the terminals without 24-bit colors and for logs.
+	Plain bool
+
+	// Language is the language of the names of the blocks. Default:
+	// English.
+	Language Language
+}
+
+// terminalLetters are the letters of the materials in the plain previews.
+const terminalLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// RenderTerminal prints the cross-section of s perpendicular to the axis
+// at the position, oriented like in WriteSliceSVG, followed by a legend of
+// the materials. Every block is two characters wide, so that it looks
+// square. The blocks are colored with ANSI escape codes, or drawn with a
+// letter per material if Plain is set; air is blank, or a dot. The
+// materials beyond the 62 letters are drawn with '?'.
+func RenderTerminal(w io.Writer, s *Schematic, axis Axis, pos int, opt *TerminalOptions) os.Error {
+	sl, err := newSlice(s, axis, pos)
+	if err != nil {
+		return err
+	}
+	var o TerminalOptions
+	if opt != nil {
+		o = *opt
+	}
+	index := make(map[Block]int)
+	var legend []Block
+	bw := bufio.NewWriter(w)
+	for v := 0; v < sl.h; v++ {
+		for u := 0; u < sl.w; u++ {
+			b := sl.block(u, v)
+			if b == (Block{}) {
+				if o.Plain {
+					bw.WriteString(". ")
+				} else {
+					bw.WriteString("  ")
+				}
+				continue
+			}
+			i, ok := index[b]
+			if !ok {
+				i = len(legend)
+				index[b] = i
+				legend = append(legend, b)
+			}
+			if o.Plain {
+				c := byte('?')
+				if i < len(terminalLetters) {
+					c = terminalLetters[i]
+				}
+				bw.WriteByte(c)
+				bw.WriteByte(c)
+			} else {
+				fmt.Fprintf(bw, "%s  \x1b[0m", ansiBackground(b))
+			}
+		}
+		bw.WriteString("\n")
+	}
+	for i, b := range legend {
+		if o.Plain {
+			c := byte('?')
+			if i < len(terminalLetters) {
+				c = terminalLetters[i]
+			}
+			fmt.Fprintf(bw, "%c %s\n", c, o.Language.DisplayName(b))
+		} else {
+			fmt.Fprintf(bw, "%s  \x1b[0m %s\n", ansiBackground(b), o.Language.DisplayName(b))
+		}
+	}
+	return bw.Flush()
+}
+
+// ansiBackground returns the escape code setting the background to the
+// color of the block.
+func ansiBackground(b Block) string {
+	c := BlockColor(b.Id, b.Data)
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+}
@@ -0,0 +1,37 @@
+package schematic
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderTerminal(t *testing.T) {
+	s := NewSchematic(3, 1, 2)
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	s.SetBlock(1, 0, 0, Block{1, 0})
+	s.SetBlock(2, 0, 1, Block{35, 14})
+	var buf bytes.Buffer
+	if err := RenderTerminal(&buf, s, AxisY, 0, &TerminalOptions{Plain: true}); err != nil {
+		t.Fatalf("RenderTerminal: %v", err)
+	}
+	want := "aaaa. \n. . bb\na Stone\nb Red Wool\n"
+	if buf.String() != want {
+		t.Errorf("RenderTerminal:\nwant %q\ngot  %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := RenderTerminal(&buf, s, AxisY, 0, nil); err != nil {
+		t.Fatalf("RenderTerminal: %v", err)
+	}
+	c := BlockColor(1, 0)
+	stone := fmt.Sprintf("\x1b[48;2;%d;%d;%dm  \x1b[0m", c.R, c.G, c.B)
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 5 || lines[0] != stone+stone+"  " || lines[2] != stone+" Stone" {
+		t.Errorf("RenderTerminal: got %q", buf.String())
+	}
+	if err := RenderTerminal(&buf, s, AxisY, 1, nil); err == nil {
+		t.Errorf("RenderTerminal out of range: want error, got nil")
+	}
+}
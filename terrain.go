@@ -0,0 +1,131 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+)
+
+// TerrainOptions control GenerateTerrain. The zero values of the fields
+// other than the size select the defaults given below.
+type TerrainOptions struct {
+	Width, Height, Length int
+	Seed                  int64
+
+	// Scale is the horizontal size of the hills in blocks. Default: 32.
+	Scale float64
+
+	// Octaves is the number of noise octaves adding detail. Default: 4.
+	Octaves int
+
+	// BaseHeight is the average height of the surface. Default: Height/2.
+	BaseHeight int
+
+	// Amplitude is the typical distance of the highest and the lowest
+	// parts of the surface from BaseHeight. Default: Height/4.
+	Amplitude float64
+
+	// WaterLevel is the height up to which the air above the surface is
+	// filled with water. The surface under water is made of Shore.
+	// Zero means no water.
+	WaterLevel int
+
+	// The layers of the terrain from the top: a single block of Surface,
+	// FillerDepth blocks of Filler and Stone down to the bottom.
+	// Defaults: grass, dirt, stone, sand and 3.
+	Surface, Filler, Stone, Shore Block
+	FillerDepth                   int
+
+	// Caves enables tunnels carved by three dimensional noise below the
+	// surface. CaveScale is the size of the cave system in blocks
+	// (default 16) and CaveWidth the width of the tunnels relative to
+	// it (default 0.1).
+	Caves     bool
+	CaveScale float64
+	CaveWidth float64
+}
+
+// defaults returns a copy of opt with the defaults filled in.
+func (opt *TerrainOptions) defaults() *TerrainOptions {
+	o := *opt
+	if o.Scale == 0 {
+		o.Scale = 32
+	}
+	if o.Octaves == 0 {
+		o.Octaves = 4
+	}
+	if o.BaseHeight == 0 {
+		o.BaseHeight = o.Height / 2
+	}
+	if o.Amplitude == 0 {
+		o.Amplitude = float64(o.Height) / 4
+	}
+	if o.Surface == (Block{}) {
+		o.Surface = Block{2, 0}
+	}
+	if o.Filler == (Block{}) {
+		o.Filler = Block{3, 0}
+	}
+	if o.Stone == (Block{}) {
+		o.Stone = Block{1, 0}
+	}
+	if o.Shore == (Block{}) {
+		o.Shore = Block{12, 0}
+	}
+	if o.FillerDepth == 0 {
+		o.FillerDepth = 3
+	}
+	if o.CaveScale == 0 {
+		o.CaveScale = 16
+	}
+	if o.CaveWidth == 0 {
+		o.CaveWidth = 0.1
+	}
+	return &o
+}
+
+// GenerateTerrain returns a landscape shaped by noise: a height map of
+// layered ground, optionally with water and caves. The same options always
+// give the same terrain.
+func GenerateTerrain(opt *TerrainOptions) (*Schematic, os.Error) {
+	if opt.Width <= 0 || opt.Height <= 0 || opt.Length <= 0 {
+		return nil, fmt.Errorf("Invalid terrain size: %dx%dx%d", opt.Width, opt.Height, opt.Length)
+	}
+	o := opt.defaults()
+	height := NewNoise(o.Seed)
+	caves := NewNoise(o.Seed + 1)
+	caves2 := NewNoise(o.Seed + 2)
+	s := NewSchematic(o.Width, o.Height, o.Length)
+	for z := 0; z < o.Length; z++ {
+		for x := 0; x < o.Width; x++ {
+			n := height.Octaves(float64(x)/o.Scale, 0.5, float64(z)/o.Scale, o.Octaves, 0.5)
+			top := imin(o.BaseHeight+int(n*o.Amplitude*2), o.Height-1)
+			for y := 0; y <= top; y++ {
+				b := o.Stone
+				switch {
+				case y == top && top < o.WaterLevel:
+					b = o.Shore
+				case y == top:
+					b = o.Surface
+				case y > top-o.FillerDepth:
+					b = o.Filler
+				}
+				if o.Caves && y > 0 && y < top {
+					// Tunnels follow the zero surfaces of two noise
+					// functions, where both are close to zero.
+					fx, fy, fz := float64(x)/o.CaveScale, float64(y)/o.CaveScale, float64(z)/o.CaveScale
+					if abs(caves.At(fx, fy, fz)) < o.CaveWidth && abs(caves2.At(fx, fy, fz)) < o.CaveWidth {
+						b = Block{}
+					}
+				}
+				s.SetBlock(x, y, z, b)
+			}
+			for y := top + 1; y < o.WaterLevel && y < o.Height; y++ {
+				s.SetBlock(x, y, z, Block{9, 0})
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,59 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestGenerateTerrain(t *testing.T) {
+	opt := &TerrainOptions{Width: 48, Height: 32, Length: 48, Seed: 7, WaterLevel: 16}
+	s, err := GenerateTerrain(opt)
+	if err != nil {
+		t.Fatalf("GenerateTerrain: %v", err)
+	}
+	if s.Width != 48 || s.Height != 32 || s.Length != 48 {
+		t.Fatalf("GenerateTerrain: got size %dx%dx%d", s.Width, s.Height, s.Length)
+	}
+	var grass, water, tops int
+	for z := 0; z < s.Length; z++ {
+		for x := 0; x < s.Width; x++ {
+			if s.GetV(x, 0, z) != 1 {
+				t.Fatalf("(%d, 0, %d): want stone, got %v", x, z, s.Block(x, 0, z))
+			}
+			y := s.Height - 1
+			for ; y >= 0 && s.GetV(x, y, z) == 0; y-- {
+			}
+			switch s.GetV(x, y, z) {
+			case 2:
+				grass++
+				if s.GetV(x, y-1, z) != 3 {
+					t.Errorf("(%d, %d, %d): want dirt under grass, got %v", x, y-1, z, s.Block(x, y-1, z))
+				}
+			case 9:
+				water++
+				if y != 15 {
+					t.Errorf("(%d, %d, %d): water above the water level", x, y, z)
+				}
+			}
+			tops += y
+		}
+	}
+	if grass == 0 || water == 0 {
+		t.Errorf("Want both land and water, got %d grass and %d water columns", grass, water)
+	}
+	if avg := tops / (48 * 48); avg < 12 || avg > 20 {
+		t.Errorf("Average height: want about 16, got %d", avg)
+	}
+
+	again, _ := GenerateTerrain(opt)
+	if again.Fingerprint() != s.Fingerprint() {
+		t.Errorf("GenerateTerrain is not deterministic")
+	}
+	opt.Caves = true
+	caves, _ := GenerateTerrain(opt)
+	if Compare(s, caves).Removed == 0 {
+		t.Errorf("Caves: no blocks removed")
+	}
+	if _, err = GenerateTerrain(&TerrainOptions{Width: 10}); err == nil {
+		t.Errorf("GenerateTerrain with zero height: want error, got nil")
+	}
+}
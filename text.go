@@ -0,0 +1,154 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"strings"
+	"utf8"
+)
+
+// A VoxelFont is a bitmap font for RenderText.
+type VoxelFont struct {
+	// Width and Height are the size of the glyphs in blocks.
+	Width, Height int
+
+	// Glyphs maps the characters to their rows, from the top. The bit
+	// 1<<(Width-1) of a row is the leftmost column. ASCII lower case letters
+	// missing from the font are drawn as upper case ones, other missing
+	// characters as the question mark.
+	Glyphs map[int][]uint32
+
+	// Depth is the thickness of the letters; less than 1 means 1.
+	Depth int
+
+	// Spacing and LineSpacing are the numbers of empty columns between the
+	// characters and of empty rows between the lines.
+	Spacing, LineSpacing int
+}
+
+// Font5x7 is a font of 5x7 blocks with the digits, upper case letters and
+// the common punctuation of ASCII. Copy it to change the depth or spacing.
+var Font5x7 = VoxelFont{
+	Width:       5,
+	Height:      7,
+	Depth:       1,
+	Spacing:     1,
+	LineSpacing: 1,
+	Glyphs: map[int][]uint32{
+		' ':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		'!':  {0x04, 0x04, 0x04, 0x04, 0x00, 0x00, 0x04},
+		'"':  {0x0a, 0x0a, 0x0a, 0x00, 0x00, 0x00, 0x00},
+		'#':  {0x0a, 0x0a, 0x1f, 0x0a, 0x1f, 0x0a, 0x0a},
+		'%':  {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+		'\'': {0x0c, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+		'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+		')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+		'*':  {0x00, 0x04, 0x15, 0x0e, 0x15, 0x04, 0x00},
+		'+':  {0x00, 0x04, 0x04, 0x1f, 0x04, 0x04, 0x00},
+		',':  {0x00, 0x00, 0x00, 0x00, 0x0c, 0x04, 0x08},
+		'-':  {0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00},
+		'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+		'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+		'0':  {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+		'1':  {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+		'2':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+		'3':  {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+		'4':  {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+		'5':  {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+		'6':  {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+		'7':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+		'8':  {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+		'9':  {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+		':':  {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},
+		';':  {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x04, 0x08},
+		'<':  {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},
+		'=':  {0x00, 0x00, 0x1f, 0x00, 0x1f, 0x00, 0x00},
+		'>':  {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
+		'?':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+		'@':  {0x0e, 0x11, 0x01, 0x0d, 0x15, 0x15, 0x0e},
+		'A':  {0x0e, 0x11, 0x11, 0x11, 0x1f, 0x11, 0x11},
+		'B':  {0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e},
+		'C':  {0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e},
+		'D':  {0x1c, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1c},
+		'E':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f},
+		'F':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10},
+		'G':  {0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f},
+		'H':  {0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+		'I':  {0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},
+		'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c},
+		'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+		'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f},
+		'M':  {0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11},
+		'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+		'O':  {0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+		'P':  {0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10},
+		'Q':  {0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d},
+		'R':  {0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11},
+		'S':  {0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e},
+		'T':  {0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+		'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+		'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04},
+		'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a},
+		'X':  {0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11},
+		'Y':  {0x11, 0x11, 0x11, 0x0a, 0x04, 0x04, 0x04},
+		'Z':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f},
+		'_':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f},
+	},
+}
+
+// glyph returns the rows of the character.
+func (f *VoxelFont) glyph(c int) []uint32 {
+	if g, ok := f.Glyphs[c]; ok {
+		return g
+	}
+	if 'a' <= c && c <= 'z' {
+		if g, ok := f.Glyphs[c-'a'+'A']; ok {
+			return g
+		}
+	}
+	return f.Glyphs['?']
+}
+
+// RenderText returns a schematic of the text written with the font in
+// blocks of the material. The text reads from west to east along the x
+// axis, so that it faces south, and may have several lines separated by
+// '\n'. The letters are Depth blocks thick along the z axis.
+func RenderText(text string, font VoxelFont, material uint16) *Schematic {
+	lines := strings.Split(text, "\n")
+	cols := 0
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > cols {
+			cols = n
+		}
+	}
+	depth := imax(font.Depth, 1)
+	if cols == 0 {
+		return NewSchematic(0, 0, 0)
+	}
+	w := cols*(font.Width+font.Spacing) - font.Spacing
+	h := len(lines)*(font.Height+font.LineSpacing) - font.LineSpacing
+	s := NewSchematic(w, h, depth)
+	b := Block{material, 0}
+	for i, line := range lines {
+		top := h - 1 - i*(font.Height+font.LineSpacing)
+		left := 0
+		for _, c := range line {
+			for row, bits := range font.glyph(int(c)) {
+				if row >= font.Height {
+					break
+				}
+				for col := 0; col < font.Width; col++ {
+					if bits&(1<<uint(font.Width-1-col)) == 0 {
+						continue
+					}
+					for z := 0; z < depth; z++ {
+						s.SetBlock(left+col, top-row, z, b)
+					}
+				}
+			}
+			left += font.Width + font.Spacing
+		}
+	}
+	return s
+}
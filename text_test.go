@@ -0,0 +1,38 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestRenderText(t *testing.T) {
+	s := RenderText("Hi", Font5x7, 35)
+	if s.Width != 11 || s.Height != 7 || s.Length != 1 {
+		t.Fatalf("RenderText: want 11x7x1, got %dx%dx%d", s.Width, s.Height, s.Length)
+	}
+	// The left leg of H and the top of I.
+	for y := 0; y < 7; y++ {
+		if s.GetV(0, y, 0) != 35 {
+			t.Errorf("(0, %d, 0): want wool, got %v", y, s.Block(0, y, 0))
+		}
+	}
+	if s.GetV(7, 6, 0) != 35 || s.GetV(6, 6, 0) != 0 || s.GetV(5, 3, 0) != 0 {
+		t.Errorf("RenderText: wrong blocks %v", s.Blocks)
+	}
+
+	f := Font5x7
+	f.Depth, f.Spacing, f.LineSpacing = 3, 0, 2
+	s = RenderText("AB\n~", f, 1)
+	if s.Width != 10 || s.Height != 16 || s.Length != 3 {
+		t.Fatalf("RenderText: want 10x16x3, got %dx%dx%d", s.Width, s.Height, s.Length)
+	}
+	// The unknown character is drawn as the question mark.
+	if s.GetV(3, 15, 2) != 1 || s.GetV(4, 15, 2) != 0 || s.GetV(5, 15, 0) != 1 || s.GetV(2, 0, 1) != 1 || s.GetV(2, 1, 1) != 0 {
+		t.Errorf("RenderText: wrong blocks %v", s.Blocks)
+	}
+	if Font5x7.Depth != 1 {
+		t.Errorf("Changing a copy changed Font5x7")
+	}
+	if s = RenderText("", Font5x7, 1); s.Width != 0 {
+		t.Errorf("RenderText of empty text: got width %d", s.Width)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// A TileEntity holds the data of a block which does not fit into its id and
+// data value, such as the items of a chest or the text of a sign. The
+// position is relative to the schematic.
+type TileEntity struct {
+	X, Y, Z int
+	Id      string
+	NBT     *nbt.Compound // the complete tile entity compound, including the id and the position
+}
+
+func newTileEntity(c *nbt.Compound) TileEntity {
+	id, _ := c.Get("id").(nbt.String)
+	return TileEntity{
+		X:   intField(c, "x"),
+		Y:   intField(c, "y"),
+		Z:   intField(c, "z"),
+		Id:  string(id),
+		NBT: c,
+	}
+}
+
+// compound returns the NBT representation of t with the fields of t applied.
+func (t *TileEntity) compound() *nbt.Compound {
+	c := new(nbt.Compound)
+	if t.NBT != nil {
+		c.Fields = append(c.Fields, t.NBT.Fields...)
+	}
+	if t.Id != "" {
+		c.Set("id", nbt.String(t.Id))
+	}
+	c.Set("x", nbt.Int(t.X))
+	c.Set("y", nbt.Int(t.Y))
+	c.Set("z", nbt.Int(t.Z))
+	return c
+}
+
+// The positions of the tile entities are indexed, so that TileEntityAt does
+// not scan the list for every block. The index is built by ReadSchematic or
+// on the first lookup and kept up to date by the methods of Schematic. It is
+// rebuilt when the length of TileEntities changes, so tile entities may be
+// appended directly; to move one, use RemoveTileEntity and SetTileEntity.
+
+// indexTileEntities rebuilds the index of the tile entity positions.
+// The tile entities outside of s are not indexed; of those sharing a
+// position, the last one is.
+func (s *Schematic) indexTileEntities() {
+	s.tileIndex = make(map[int]int)
+	for i, t := range s.TileEntities {
+		if t.X >= 0 && t.Y >= 0 && t.Z >= 0 && t.X < s.Width && t.Y < s.Height && t.Z < s.Length {
+			s.tileIndex[s.index(t.X, t.Y, t.Z)] = i
+		}
+	}
+	s.tileCount = len(s.TileEntities)
+}
+
+// tileEntityIndex returns the position in TileEntities of the tile entity
+// at (x, y, z), or -1 if there is none.
+func (s *Schematic) tileEntityIndex(x, y, z int) int {
+	if x < 0 || y < 0 || z < 0 || x >= s.Width || y >= s.Height || z >= s.Length {
+		return -1
+	}
+	if s.tileIndex == nil || s.tileCount != len(s.TileEntities) {
+		s.indexTileEntities()
+	}
+	i, ok := s.tileIndex[s.index(x, y, z)]
+	if !ok {
+		return -1
+	}
+	if t := &s.TileEntities[i]; t.X != x || t.Y != y || t.Z != z {
+		// The tile entity was moved behind our back.
+		s.indexTileEntities()
+		if i, ok = s.tileIndex[s.index(x, y, z)]; !ok {
+			return -1
+		}
+	}
+	return i
+}
+
+// TileEntityAt returns the tile entity of the block at (x, y, z), or nil
+// if there is none. The returned tile entity may be modified in place,
+// except for its position.
+func (s *Schematic) TileEntityAt(x, y, z int) *TileEntity {
+	if i := s.tileEntityIndex(x, y, z); i >= 0 {
+		return &s.TileEntities[i]
+	}
+	return nil
+}
+
+// SetTileEntity adds t to the schematic, replacing the tile entity at the
+// same position. It panics if the position is outside of the schematic.
+func (s *Schematic) SetTileEntity(t TileEntity) {
+	if t.X < 0 || t.Y < 0 || t.Z < 0 || t.X >= s.Width || t.Y >= s.Height || t.Z >= s.Length {
+		panic(fmt.Sprintf("schematic: SetTileEntity(%d, %d, %d) out of range", t.X, t.Y, t.Z))
+	}
+	if i := s.tileEntityIndex(t.X, t.Y, t.Z); i >= 0 {
+		s.TileEntities[i] = t
+		return
+	}
+	s.TileEntities = append(s.TileEntities, t)
+	s.tileIndex[s.index(t.X, t.Y, t.Z)] = len(s.TileEntities) - 1
+	s.tileCount = len(s.TileEntities)
+}
+
+// RemoveTileEntity removes the tile entity at (x, y, z) and reports
+// whether there was one.
+func (s *Schematic) RemoveTileEntity(x, y, z int) bool {
+	i := s.tileEntityIndex(x, y, z)
+	if i < 0 {
+		return false
+	}
+	s.TileEntities = append(s.TileEntities[:i], s.TileEntities[i+1:]...)
+	s.indexTileEntities()
+	return true
+}
+
+// pasteTileEntities copies the tile entities of src to the positions
+// returned by f. Those for which f reports false are dropped.
+func (s *Schematic) pasteTileEntities(src *Schematic, f func(x, y, z int) (int, int, int, bool)) {
+	for _, t := range src.TileEntities {
+		if x, y, z, ok := f(t.X, t.Y, t.Z); ok {
+			s.SetTileEntity(t.moveTo(x, y, z))
+		}
+	}
+}
+
+// moveTo returns a copy of t at the position.
+func (t *TileEntity) moveTo(x, y, z int) TileEntity {
+	c := *t
+	c.X, c.Y, c.Z = x, y, z
+	if c.NBT != nil {
+		c.NBT = nbt.Clone(c.NBT).(*nbt.Compound)
+	}
+	return c
+}
@@ -0,0 +1,97 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// newChestVolume returns newTestVolume with a chest at (2, 1, 1).
+func newChestVolume() *Schematic {
+	s := newTestVolume()
+	s.SetBlock(2, 1, 1, Block{54, 2})
+	items := nbt.NewList(nbt.TagCompound, nbt.NewCompound().Set("id", nbt.Short(1)).Set("Count", nbt.Byte(64)))
+	s.SetTileEntity(TileEntity{X: 2, Y: 1, Z: 1, Id: "Chest", NBT: nbt.NewCompound().Set("Items", items)})
+	return s
+}
+
+func TestTileEntityAt(t *testing.T) {
+	s := newChestVolume()
+	var buf bytes.Buffer
+	if err := WriteSchematic(&buf, s); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	s, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	te := s.TileEntityAt(2, 1, 1)
+	if te == nil || te.Id != "Chest" || te.NBT.Get("Items") == nil {
+		t.Fatalf("TileEntityAt(2, 1, 1): got %+v", te)
+	}
+	if te := s.TileEntityAt(2, 0, 1); te != nil {
+		t.Errorf("TileEntityAt(2, 0, 1): want nil, got %+v", te)
+	}
+
+	// Appending directly updates the index.
+	s.TileEntities = append(s.TileEntities, TileEntity{X: 0, Y: 0, Z: 0, Id: "Sign"})
+	if te := s.TileEntityAt(0, 0, 0); te == nil || te.Id != "Sign" {
+		t.Errorf("TileEntityAt(0, 0, 0): got %+v", te)
+	}
+	s.SetTileEntity(TileEntity{X: 0, Y: 0, Z: 0, Id: "Furnace"})
+	if len(s.TileEntities) != 2 || s.TileEntityAt(0, 0, 0).Id != "Furnace" {
+		t.Errorf("SetTileEntity did not replace the sign: %+v", s.TileEntities)
+	}
+
+	// Changing the block removes its tile entity.
+	s.SetBlock(2, 1, 1, Block{54, 3})
+	if s.TileEntityAt(2, 1, 1) == nil {
+		t.Errorf("SetBlock of a chest removed the tile entity")
+	}
+	s.SetBlock(2, 1, 1, Block{1, 0})
+	if s.TileEntityAt(2, 1, 1) != nil || len(s.TileEntities) != 1 {
+		t.Errorf("SetBlock of stone kept the tile entity: %+v", s.TileEntities)
+	}
+	if s.Replace(Block{1, 0}, Block{4, 0}, true); s.TileEntityAt(0, 0, 0) != nil {
+		t.Errorf("Replace kept the tile entity: %+v", s.TileEntities)
+	}
+	if s.RemoveTileEntity(0, 0, 0) {
+		t.Errorf("RemoveTileEntity of a missing tile entity: want false")
+	}
+}
+
+func TestTransformTileEntities(t *testing.T) {
+	s := newChestVolume()
+	r, err := s.Rotate(90)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	// (x, z) -> (length-1-z, x)
+	if te := r.TileEntityAt(0, 1, 2); te == nil || te.Id != "Chest" {
+		t.Errorf("Rotate: TileEntityAt(0, 1, 2): got %+v, all %+v", te, r.TileEntities)
+	}
+	if r.TileEntityAt(0, 1, 2).NBT == s.TileEntityAt(2, 1, 1).NBT {
+		t.Errorf("Rotate: the NBT of the tile entity is shared")
+	}
+	c, err := s.Crop(Box{1, 0, 0, 3, 2, 2})
+	if err != nil {
+		t.Fatalf("Crop: %v", err)
+	}
+	if te := c.TileEntityAt(1, 1, 1); te == nil || len(c.TileEntities) != 1 {
+		t.Errorf("Crop: got %+v", c.TileEntities)
+	}
+	if c, _ = s.Crop(Box{0, 0, 0, 2, 2, 2}); len(c.TileEntities) != 0 {
+		t.Errorf("Crop without the chest: got %+v", c.TileEntities)
+	}
+
+	d := NewSchematic(4, 2, 2)
+	d.Paste(s, 1, 0, 0, &PasteOptions{SkipAir: true})
+	if te := d.TileEntityAt(3, 1, 1); te == nil || te.Id != "Chest" {
+		t.Errorf("Paste: got %+v", d.TileEntities)
+	}
+	d.Paste(s, 2, 0, 0, nil)
+	if len(d.TileEntities) != 0 {
+		t.Errorf("Paste: the overwritten chest kept its tile entity: %+v", d.TileEntities)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// TileOptions control RenderTiles. A nil *TileOptions selects the
+// defaults.
+type TileOptions struct {
+	// Size is the width and the height of a tile in pixels. Default: 256.
+	Size int
+
+	// Scale is the size of a block in pixels at the highest zoom level.
+	// Default: 1.
+	Scale int
+}
+
+// A TileFunc receives the tiles rendered by RenderTiles.
+type TileFunc func(zoom, x, y int, m *image.RGBA) os.Error
+
+// tiler renders the tiles of a schematic.
+type tiler struct {
+	v           *rotatedView
+	size, scale int
+	maxZoom     int
+	f           TileFunc
+}
+
+// RenderTiles renders the top view of s as a pyramid of square tiles, like
+// the maps browsed with Leaflet, and returns the highest zoom level. A
+// single tile shows the whole schematic at the zoom level 0, and every
+// next level doubles the resolution, up to the level at which a block is
+// Scale pixels. The tile (x, y) of a level is the part of the view of the
+// level starting at the pixel (x*Size, y*Size), with x growing to the
+// east and y to the south.
+//
+// The tiles of the highest level are drawn from the blocks and the others
+// are scaled down from the four tiles below them, so that the memory used
+// does not depend on the size of the view, and a schematic of 10000 by
+// 10000 blocks can be rendered. The tiles are passed to f once they are
+// complete, and those with no blocks are skipped. RenderTiles stops at
+// the first error of f.
+func RenderTiles(s *Schematic, opt *TileOptions, f TileFunc) (maxZoom int, err os.Error) {
+	o := TileOptions{Size: 256, Scale: 1}
+	if opt != nil {
+		o = *opt
+		if o.Size == 0 {
+			o.Size = 256
+		}
+		if o.Scale == 0 {
+			o.Scale = 1
+		}
+	}
+	if o.Size < 1 || o.Scale < 1 {
+		return 0, fmt.Errorf("Invalid tile size %d or scale %d", o.Size, o.Scale)
+	}
+	t := &tiler{size: o.Size, scale: o.Scale, f: f}
+	if t.v, err = newRotatedView(s, new(RenderOptions)); err != nil {
+		return
+	}
+	for o.Size<<uint(t.maxZoom) < imax(s.Width, s.Length)*o.Scale {
+		t.maxZoom++
+	}
+	_, err = t.tile(0, 0, 0)
+	return t.maxZoom, err
+}
+
+// levelSize returns the width and the height of the view at the zoom
+// level in pixels.
+func (t *tiler) levelSize(zoom int) (w, h int) {
+	d := 1 << uint(t.maxZoom-zoom)
+	return (t.v.xlen*t.scale + d - 1) / d, (t.v.zlen*t.scale + d - 1) / d
+}
+
+// tile renders the tile and the tiles below it, and returns the tile, or
+// nil if it has no blocks.
+func (t *tiler) tile(zoom, x, y int) (m *image.RGBA, err os.Error) {
+	if w, h := t.levelSize(zoom); x*t.size >= w || y*t.size >= h {
+		return nil, nil
+	}
+	if zoom == t.maxZoom {
+		m = t.render(x, y)
+	} else {
+		var children [4]*image.RGBA
+		for i := range children {
+			if children[i], err = t.tile(zoom+1, 2*x+i%2, 2*y+i/2); err != nil {
+				return
+			}
+		}
+		m = t.merge(children)
+	}
+	if m == nil {
+		return
+	}
+	return m, t.f(zoom, x, y, m)
+}
+
+// render draws the tile of the highest zoom level from the blocks.
+func (t *tiler) render(x, y int) *image.RGBA {
+	m := image.NewRGBA(t.size, t.size)
+	empty := true
+	for py := 0; py < t.size; py++ {
+		for px := 0; px < t.size; px++ {
+			c, ok := t.v.topColor((x*t.size+px)/t.scale, (y*t.size+py)/t.scale)
+			if ok {
+				m.Set(px, py, c)
+				empty = false
+			}
+		}
+	}
+	if empty {
+		return nil
+	}
+	return m
+}
+
+// merge scales down the four tiles, ordered from the north-west to the
+// south-east, into one. Missing tiles are transparent.
+func (t *tiler) merge(children [4]*image.RGBA) *image.RGBA {
+	if children[0] == nil && children[1] == nil && children[2] == nil && children[3] == nil {
+		return nil
+	}
+	m := image.NewRGBA(t.size, t.size)
+	for py := 0; py < t.size; py++ {
+		for px := 0; px < t.size; px++ {
+			cx, cy := 2*px, 2*py
+			child := children[cy/t.size*2+cx/t.size]
+			if child == nil {
+				continue
+			}
+			cx, cy = cx%t.size, cy%t.size
+			var r, g, b, a int
+			for i := 0; i < 4; i++ {
+				var c image.RGBAColor
+				if cx+i%2 < t.size && cy+i/2 < t.size {
+					c = child.At(cx+i%2, cy+i/2).(image.RGBAColor)
+				}
+				r, g, b, a = r+int(c.R), g+int(c.G), b+int(c.B), a+int(c.A)
+			}
+			m.Set(px, py, image.RGBAColor{uint8(r / 4), uint8(g / 4), uint8(b / 4), uint8(a / 4)})
+		}
+	}
+	return m
+}
+
+// WriteTiles writes the tiles of RenderTiles to dir as PNG files named
+// zoom/x/y.png, the layout expected by Leaflet, and returns the highest
+// zoom level.
+func WriteTiles(dir string, s *Schematic, opt *TileOptions) (int, os.Error) {
+	return RenderTiles(s, opt, func(zoom, x, y int, m *image.RGBA) os.Error {
+		col := filepath.Join(dir, strconv.Itoa(zoom), strconv.Itoa(x))
+		if err := os.MkdirAll(col, 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(filepath.Join(col, strconv.Itoa(y)+".png"))
+		if err != nil {
+			return err
+		}
+		if err = png.Encode(f, m); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
@@ -0,0 +1,87 @@
+package schematic
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderTiles(t *testing.T) {
+	// Stone along the north edge and one block of wool in the south-west
+	// corner; the rest is air.
+	s := NewSchematic(5, 1, 3)
+	for x := 0; x < 5; x++ {
+		s.SetBlock(x, 0, 0, Block{1, 0})
+	}
+	s.SetBlock(0, 0, 2, Block{35, 14})
+	tiles := make(map[string]*image.RGBA)
+	max, err := RenderTiles(s, &TileOptions{Size: 2}, func(zoom, x, y int, m *image.RGBA) os.Error {
+		tiles[fmt.Sprintf("%d/%d/%d", zoom, x, y)] = m
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RenderTiles: %v", err)
+	}
+	if max != 2 {
+		t.Errorf("RenderTiles: want max zoom 2, got %d", max)
+	}
+	// The tiles 2/1/1 and 2/2/1 are air.
+	for _, name := range []string{"0/0/0", "1/0/0", "1/1/0", "2/0/0", "2/1/0", "2/2/0", "2/0/1"} {
+		if tiles[name] == nil {
+			t.Errorf("Tile %s is missing", name)
+		}
+	}
+	if len(tiles) != 7 {
+		t.Errorf("RenderTiles: want 7 tiles, got %d", len(tiles))
+	}
+	top, _ := RenderTop(s, 1)
+	if c := tiles["2/2/0"].At(0, 0); c != top.At(4, 0) {
+		t.Errorf("Tile 2/2/0: want %v, got %v", top.At(4, 0), c)
+	}
+	if c := tiles["2/0/1"].At(0, 0); c != top.At(0, 2) {
+		t.Errorf("Tile 2/0/1: want %v, got %v", top.At(0, 2), c)
+	}
+	// A pixel of the level 1 is the average of four pixels of the level 2.
+	stone := top.At(0, 0).(image.RGBAColor)
+	want := image.RGBAColor{stone.R / 2, stone.G / 2, stone.B / 2, 127}
+	if c := tiles["1/0/0"].At(0, 0); c != want {
+		t.Errorf("Tile 1/0/0: want %v, got %v", want, c)
+	}
+
+	errStop := os.NewError("stop")
+	n := 0
+	if _, err = RenderTiles(s, nil, func(zoom, x, y int, m *image.RGBA) os.Error {
+		n++
+		return errStop
+	}); err != errStop || n != 1 {
+		t.Errorf("RenderTiles: want to stop at the error, got %v after %d tiles", err, n)
+	}
+	if _, err = RenderTiles(s, &TileOptions{Scale: -1}, nil); err == nil {
+		t.Errorf("RenderTiles with a negative scale: want error, got nil")
+	}
+}
+
+func TestWriteTiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewSchematic(3, 1, 3)
+	s.SetBlock(2, 0, 2, Block{1, 0})
+	max, err := WriteTiles(dir, s, &TileOptions{Size: 2, Scale: 2})
+	if err != nil || max != 2 {
+		t.Fatalf("WriteTiles: %d, %v", max, err)
+	}
+	for _, name := range []string{"0/0/0.png", "1/1/1.png", "2/2/2.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Tile %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2/0/0.png")); err == nil {
+		t.Errorf("Tile 2/0/0 of air is written")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"github.com/krasin/schematic/nbt"
+)
+
+// A TileTick is a scheduled block update, such as a pending redstone
+// repeater or a flowing liquid. The position is relative to the schematic.
+type TileTick struct {
+	X, Y, Z  int
+	Delay    int           // ticks until the update ("t")
+	Priority int           // order of updates scheduled for the same tick ("p")
+	NBT      *nbt.Compound // the complete tick compound, including the block ("i")
+}
+
+func newTileTick(c *nbt.Compound) TileTick {
+	return TileTick{
+		X:        intField(c, "x"),
+		Y:        intField(c, "y"),
+		Z:        intField(c, "z"),
+		Delay:    intField(c, "t"),
+		Priority: intField(c, "p"),
+		NBT:      c,
+	}
+}
+
+// intField returns the value of an integer tag or 0 if there is no such tag.
+func intField(c *nbt.Compound, name string) int {
+	switch v := c.Get(name).(type) {
+	case nbt.Byte:
+		return int(v)
+	case nbt.Short:
+		return int(v)
+	case nbt.Int:
+		return int(v)
+	case nbt.Long:
+		return int(v)
+	}
+	return 0
+}
+
+// compound returns the NBT representation of t with the fields of t applied.
+func (t *TileTick) compound() *nbt.Compound {
+	c := new(nbt.Compound)
+	if t.NBT != nil {
+		c.Fields = append(c.Fields, t.NBT.Fields...)
+	}
+	c.Set("x", nbt.Int(t.X))
+	c.Set("y", nbt.Int(t.Y))
+	c.Set("z", nbt.Int(t.Z))
+	c.Set("t", nbt.Int(t.Delay))
+	c.Set("p", nbt.Int(t.Priority))
+	return c
+}
+
+// mapTileTicks moves every tile tick to the position returned by f.
+// Ticks for which f reports false are dropped. Transformations use it
+// to keep scheduled updates attached to their blocks.
+func (s *Schematic) mapTileTicks(f func(x, y, z int) (int, int, int, bool)) {
+	var ticks []TileTick
+	for _, t := range s.TileTicks {
+		var ok bool
+		if t.X, t.Y, t.Z, ok = f(t.X, t.Y, t.Z); ok {
+			ticks = append(ticks, t)
+		}
+	}
+	s.TileTicks = ticks
+}
@@ -0,0 +1,45 @@
+package schematic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestTileTicks(t *testing.T) {
+	tick := new(nbt.Compound)
+	tick.Set("i", nbt.String("minecraft:unpowered_repeater"))
+	tick.Set("t", nbt.Int(2))
+	tick.Set("x", nbt.Int(1))
+	tick.Set("y", nbt.Int(0))
+	tick.Set("z", nbt.Int(1))
+	vol := &Schematic{Width: 2, Height: 1, Length: 2, Blocks: make([]byte, 4), Data: make([]byte, 4)}
+	vol.TileTicks = []TileTick{newTileTick(tick), {X: 0, Y: 0, Z: 1, Delay: 5}}
+
+	// Mirror along X, dropping the ticks outside of the first column.
+	vol.mapTileTicks(func(x, y, z int) (int, int, int, bool) {
+		return 1 - x, y, z, x == 1
+	})
+	var buf bytes.Buffer
+	if err := WriteSchematic(&buf, vol); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	if len(got.TileTicks) != 1 {
+		t.Fatalf("TileTicks: want 1 tick, got %d", len(got.TileTicks))
+	}
+	tt := got.TileTicks[0]
+	if tt.X != 0 || tt.Y != 0 || tt.Z != 1 || tt.Delay != 2 {
+		t.Errorf("TileTick: want (0,0,1) delay 2, got (%d,%d,%d) delay %d", tt.X, tt.Y, tt.Z, tt.Delay)
+	}
+	if tt.NBT.Get("i") != nbt.String("minecraft:unpowered_repeater") {
+		t.Errorf("TileTick block: got %v", tt.NBT.Get("i"))
+	}
+	if got.Extra.Get("TileTicks") != nil {
+		t.Errorf("TileTicks must not be stored in Extra")
+	}
+}
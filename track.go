@@ -0,0 +1,77 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"sort"
+)
+
+// sectionSize is the edge of the sections reported by ChangeTracker.
+const sectionSize = 16
+
+// A ChangeTracker records the sections of a schematic in which blocks
+// were changed, so that a renderer or a paster can update only those. A
+// section is a cube of 16x16x16 blocks aligned to the origin of the
+// schematic, so the sections match the sections of Minecraft chunks if
+// the schematic is pasted at a chunk boundary.
+type ChangeTracker struct {
+	s     *Schematic
+	dirty map[int]bool // section index, see mark
+}
+
+// Track returns a ChangeTracker recording the changes of the blocks of s
+// from now on. Like Snapshot, it sees the changes made with the methods
+// of Schematic only. Any number of trackers may be used at once.
+func (s *Schematic) Track() *ChangeTracker {
+	t := &ChangeTracker{s: s, dirty: make(map[int]bool)}
+	s.trackers = append(s.trackers, t)
+	return t
+}
+
+// sections returns the number of sections of s along the axes.
+func (t *ChangeTracker) sections() (nx, ny, nz int) {
+	s := t.s
+	return (s.Width + sectionSize - 1) / sectionSize, (s.Height + sectionSize - 1) / sectionSize, (s.Length + sectionSize - 1) / sectionSize
+}
+
+// mark records the change of the block i.
+func (t *ChangeTracker) mark(i int) {
+	s := t.s
+	x, y, z := s.coords(i)
+	nx, _, nz := t.sections()
+	t.dirty[(y/sectionSize*nz+z/sectionSize)*nx+x/sectionSize] = true
+}
+
+// Changed reports whether any block was changed since the last Flush.
+func (t *ChangeTracker) Changed() bool {
+	return len(t.dirty) > 0
+}
+
+// Flush returns the boxes of the sections changed since the last Flush,
+// clipped to the schematic and ordered by y, z and x, and forgets them.
+func (t *ChangeTracker) Flush() (boxes []Box) {
+	var keys []int
+	for k := range t.dirty {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	nx, _, nz := t.sections()
+	s := t.s
+	for _, k := range keys {
+		x, z, y := k%nx*sectionSize, k/nx%nz*sectionSize, k/(nx*nz)*sectionSize
+		boxes = append(boxes, Box{x, y, z, imin(x+sectionSize, s.Width), imin(y+sectionSize, s.Height), imin(z+sectionSize, s.Length)})
+	}
+	t.dirty = make(map[int]bool)
+	return
+}
+
+// Stop ends the tracking. The changes recorded before can still be flushed.
+func (t *ChangeTracker) Stop() {
+	for i, u := range t.s.trackers {
+		if u == t {
+			t.s.trackers = append(t.s.trackers[:i], t.s.trackers[i+1:]...)
+			return
+		}
+	}
+}
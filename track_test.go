@@ -0,0 +1,33 @@
+package schematic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangeTracker(t *testing.T) {
+	s := NewSchematic(40, 20, 20)
+	tr := s.Track()
+	if tr.Changed() || tr.Flush() != nil {
+		t.Errorf("New tracker reports changes")
+	}
+	s.SetBlock(1, 1, 1, Block{1, 0})
+	s.SetBlock(2, 2, 2, Block{1, 0})
+	s.SetData(39, 19, 0, 3)
+	s.Fill(RegionMask(Box{20, 0, 17, 21, 1, 18}), Block{5, 0})
+	if !tr.Changed() {
+		t.Errorf("Changed: want true")
+	}
+	want := []Box{{0, 0, 0, 16, 16, 16}, {16, 0, 16, 32, 16, 20}, {32, 16, 0, 40, 20, 16}}
+	if got := tr.Flush(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Flush: want %v, got %v", want, got)
+	}
+	if tr.Changed() {
+		t.Errorf("Changed after Flush: want false")
+	}
+	tr.Stop()
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	if tr.Changed() {
+		t.Errorf("Changed after Stop: want false")
+	}
+}
@@ -0,0 +1,428 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// A Block is a block id with its data value.
+type Block struct {
+	Id   uint16
+	Data byte
+}
+
+// Axis identifies a coordinate axis.
+type Axis int
+
+const (
+	AxisX Axis = iota
+	AxisY
+	AxisZ
+)
+
+func (a Axis) String() string {
+	switch a {
+	case AxisX:
+		return "x"
+	case AxisY:
+		return "y"
+	case AxisZ:
+		return "z"
+	}
+	return fmt.Sprintf("Axis(%d)", int(a))
+}
+
+// A Box is the region of blocks MinX <= x < MaxX, MinY <= y < MaxY, MinZ <= z < MaxZ.
+type Box struct {
+	MinX, MinY, MinZ int
+	MaxX, MaxY, MaxZ int
+}
+
+// Empty reports whether the box contains no blocks.
+func (b Box) Empty() bool {
+	return b.MinX >= b.MaxX || b.MinY >= b.MaxY || b.MinZ >= b.MaxZ
+}
+
+func (b Box) String() string {
+	return fmt.Sprintf("[%d,%d,%d]-[%d,%d,%d]", b.MinX, b.MinY, b.MinZ, b.MaxX, b.MaxY, b.MaxZ)
+}
+
+// NewSchematic returns a schematic of the given size filled with air.
+func NewSchematic(width, height, length int) *Schematic {
+	n := width * height * length
+	return &Schematic{
+		Width:  width,
+		Height: height,
+		Length: length,
+		Blocks: make([]byte, n),
+		Data:   make([]byte, n),
+		Extra:  new(nbt.Compound),
+	}
+}
+
+// Block returns the block at the specified position.
+// The blocks outside of the schematic are air.
+func (s *Schematic) Block(x, y, z int) Block {
+	if !s.Inside(x, y, z) {
+		return Block{}
+	}
+	i := s.index(x, y, z)
+	return Block{s.id(i), s.Data[i]}
+}
+
+// SetBlock sets the block at the specified position.
+// It panics if the position is outside of the schematic or the id is
+// above MaxId.
+func (s *Schematic) SetBlock(x, y, z int, b Block) {
+	if !s.Inside(x, y, z) {
+		panic(fmt.Sprintf("schematic: SetBlock(%d, %d, %d) out of range", x, y, z))
+	}
+	i := s.index(x, y, z)
+	if len(s.TileEntities) > 0 && s.id(i) != b.Id {
+		s.RemoveTileEntity(x, y, z)
+	}
+	s.set(i, b)
+}
+
+// Bounds returns the smallest box containing all non-air blocks.
+// The box is empty if there are no such blocks.
+func (s *Schematic) Bounds() (b Box) {
+	b = Box{s.Width, s.Height, s.Length, 0, 0, 0}
+	for y := 0; y < s.YLen(); y++ {
+		for z := 0; z < s.ZLen(); z++ {
+			for x := 0; x < s.XLen(); x++ {
+				if s.GetV(x, y, z) == 0 {
+					continue
+				}
+				b.MinX, b.MaxX = imin(b.MinX, x), imax(b.MaxX, x+1)
+				b.MinY, b.MaxY = imin(b.MinY, y), imax(b.MaxY, y+1)
+				b.MinZ, b.MaxZ = imin(b.MinZ, z), imax(b.MaxZ, z+1)
+			}
+		}
+	}
+	if b.Empty() {
+		return Box{}
+	}
+	return
+}
+
+// The transformations below return new schematics and do not modify s.
+// Entities and tile ticks move together with the blocks; those outside of
+// the resulting schematic are dropped. Block data values, such as the
+// direction of stairs, and the WorldEdit offset are not changed.
+
+// pointFunc maps a point of the source schematic to the resulting one.
+type pointFunc func(x, y, z float64) (float64, float64, float64)
+
+// Rotate returns s rotated clockwise around the vertical axis, as seen
+// from above. The angle must be a multiple of 90 degrees.
+func (s *Schematic) Rotate(angle int) (*Schematic, os.Error) {
+	w, l := float64(s.Width), float64(s.Length)
+	switch (angle%360 + 360) % 360 {
+	case 0:
+		return s.transform(s.Width, s.Height, s.Length, func(x, y, z float64) (float64, float64, float64) {
+			return x, y, z
+		}), nil
+	case 90:
+		return s.transform(s.Length, s.Height, s.Width, func(x, y, z float64) (float64, float64, float64) {
+			return l - z, y, x
+		}), nil
+	case 180:
+		return s.transform(s.Width, s.Height, s.Length, func(x, y, z float64) (float64, float64, float64) {
+			return w - x, y, l - z
+		}), nil
+	case 270:
+		return s.transform(s.Length, s.Height, s.Width, func(x, y, z float64) (float64, float64, float64) {
+			return z, y, w - x
+		}), nil
+	}
+	return nil, fmt.Errorf("Angle must be a multiple of 90 degrees. Got: %d", angle)
+}
+
+// Flip returns s mirrored along the axis.
+func (s *Schematic) Flip(axis Axis) *Schematic {
+	w, h, l := float64(s.Width), float64(s.Height), float64(s.Length)
+	return s.transform(s.Width, s.Height, s.Length, func(x, y, z float64) (float64, float64, float64) {
+		switch axis {
+		case AxisX:
+			x = w - x
+		case AxisY:
+			y = h - y
+		case AxisZ:
+			z = l - z
+		}
+		return x, y, z
+	})
+}
+
+// Crop returns the part of s inside the box. The box must be a non-empty
+// part of the schematic. The WorldEdit offset is moved by the box corner,
+// so the blocks keep their positions relative to the player.
+func (s *Schematic) Crop(b Box) (*Schematic, os.Error) {
+	if b.Empty() || b.MinX < 0 || b.MinY < 0 || b.MinZ < 0 || b.MaxX > s.Width || b.MaxY > s.Height || b.MaxZ > s.Length {
+		return nil, fmt.Errorf("Invalid crop box %v for size %dx%dx%d", b, s.Width, s.Height, s.Length)
+	}
+	c := s.transform(b.MaxX-b.MinX, b.MaxY-b.MinY, b.MaxZ-b.MinZ, translate(-b.MinX, -b.MinY, -b.MinZ))
+	c.WEOffsetX += b.MinX
+	c.WEOffsetY += b.MinY
+	c.WEOffsetZ += b.MinZ
+	return c, nil
+}
+
+// Trim returns s cropped to the bounds of its non-air blocks.
+// Trimming a schematic without such blocks returns an empty schematic.
+func (s *Schematic) Trim() *Schematic {
+	b := s.Bounds()
+	if b.Empty() {
+		t := NewSchematic(0, 0, 0)
+		t.Materials = s.Materials
+		t.Extra = nbt.Clone(s.Extra).(*nbt.Compound)
+		return t
+	}
+	t, _ := s.Crop(b)
+	return t
+}
+
+// Stack returns a schematic with n copies of s placed next to each other along the axis.
+// It fails if the result is larger than a schematic file can hold.
+func (s *Schematic) Stack(axis Axis, n int) (*Schematic, os.Error) {
+	if n < 1 || n > MaxDimension {
+		return nil, fmt.Errorf("Invalid number of copies: %d", n)
+	}
+	w, h, l := s.Width, s.Height, s.Length
+	var dx, dy, dz int
+	switch axis {
+	case AxisX:
+		w, dx = w*n, s.Width
+	case AxisY:
+		h, dy = h*n, s.Height
+	case AxisZ:
+		l, dz = l*n, s.Length
+	default:
+		return nil, fmt.Errorf("Unknown axis: %v", axis)
+	}
+	if err := checkDimensions(w, h, l); err != nil {
+		return nil, err
+	}
+	t := s.transform(w, h, l, translate(0, 0, 0))
+	for i := 1; i < n; i++ {
+		t.paste(s, translate(i*dx, i*dy, i*dz))
+	}
+	return t, nil
+}
+
+// PasteOptions control how Paste combines the blocks of two schematics.
+// A nil *PasteOptions is equivalent to the zero value.
+type PasteOptions struct {
+	// SkipAir keeps the blocks where the pasted schematic has air, like
+	// the -a flag of the WorldEdit //paste command. By default air
+	// overwrites them.
+	SkipAir bool
+
+	// KeepVoid copies the structure voids like the other blocks. By
+	// default they keep the blocks under them, as in structure blocks.
+	KeepVoid bool
+}
+
+// Paste copies src into s with its minimum corner at (x, y, z). The tile
+// entities are copied with their blocks. The blocks, entities and tile
+// ticks outside of s are dropped.
+func (s *Schematic) Paste(src *Schematic, x, y, z int, opt *PasteOptions) {
+	if opt == nil {
+		opt = new(PasteOptions)
+	}
+	for sy := 0; sy < src.Height; sy++ {
+		for sz := 0; sz < src.Length; sz++ {
+			for sx := 0; sx < src.Width; sx++ {
+				b := src.Block(sx, sy, sz)
+				if b.Id == 0 && opt.SkipAir || b == StructureVoid && !opt.KeepVoid {
+					continue
+				}
+				if s.setInside(sx+x, sy+y, sz+z, b) {
+					if t := src.TileEntityAt(sx, sy, sz); t != nil {
+						s.SetTileEntity(t.moveTo(sx+x, sy+y, sz+z))
+					}
+				}
+			}
+		}
+	}
+	move := translate(x, y, z)
+	for _, e := range src.Entities {
+		if e, ok := s.moveEntity(e, move); ok {
+			s.Entities = append(s.Entities, e)
+		}
+	}
+	ticks := s.TileTicks
+	s.TileTicks = append([]TileTick(nil), src.TileTicks...)
+	s.mapTileTicks(func(tx, ty, tz int) (int, int, int, bool) {
+		tx, ty, tz = tx+x, ty+y, tz+z
+		return tx, ty, tz, tx >= 0 && ty >= 0 && tz >= 0 && tx < s.Width && ty < s.Height && tz < s.Length
+	})
+	s.TileTicks = append(ticks, s.TileTicks...)
+}
+
+// Replace changes the blocks equal to from into to and returns the number
+// of changed blocks. If anyData is true, the data value of from is ignored
+// and all blocks with the id of from are replaced.
+func (s *Schematic) Replace(from, to Block, anyData bool) int {
+	return s.ReplaceMask(from, to, anyData, nil)
+}
+
+// ReplaceMask is like Replace but changes only the blocks selected by m.
+// A nil mask selects all blocks. The mask is tested before the change, as
+// with Fill.
+func (s *Schematic) ReplaceMask(from, to Block, anyData bool, m Mask) (n int) {
+	var sel []bool
+	if m != nil {
+		sel = make([]bool, len(s.Blocks))
+		for _, i := range s.selected(m) {
+			sel[i] = true
+		}
+	}
+	for i := range s.Blocks {
+		id := s.id(i)
+		if id != from.Id || !anyData && s.Data[i] != from.Data || sel != nil && !sel[i] {
+			continue
+		}
+		if len(s.TileEntities) > 0 && id != to.Id {
+			s.RemoveTileEntity(s.coords(i))
+		}
+		s.set(i, Block{to.Id, to.Data})
+		n++
+	}
+	return
+}
+
+func translate(dx, dy, dz int) pointFunc {
+	fx, fy, fz := float64(dx), float64(dy), float64(dz)
+	return func(x, y, z float64) (float64, float64, float64) {
+		return x + fx, y + fy, z + fz
+	}
+}
+
+// transform returns a new schematic of the given size with the contents
+// of s moved by f.
+func (s *Schematic) transform(width, height, length int, f pointFunc) *Schematic {
+	t := NewSchematic(width, height, length)
+	t.Materials = s.Materials
+	t.WEOffsetX, t.WEOffsetY, t.WEOffsetZ = s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ
+	if s.Extra != nil {
+		t.Extra = nbt.Clone(s.Extra).(*nbt.Compound)
+	}
+	t.paste(s, f)
+	return t
+}
+
+// blockPos maps the block at (x, y, z) by f and reports whether it is inside s.
+func (s *Schematic) blockPos(f pointFunc, x, y, z int) (int, int, int, bool) {
+	fx, fy, fz := f(float64(x)+0.5, float64(y)+0.5, float64(z)+0.5)
+	nx, ny, nz := int(math.Floor(fx)), int(math.Floor(fy)), int(math.Floor(fz))
+	ok := nx >= 0 && ny >= 0 && nz >= 0 && nx < s.Width && ny < s.Height && nz < s.Length
+	return nx, ny, nz, ok
+}
+
+// paste copies the blocks, the entities, the tile ticks and the tile
+// entities of src moved by f into s.
+func (s *Schematic) paste(src *Schematic, f pointFunc) {
+	for y := 0; y < src.YLen(); y++ {
+		for z := 0; z < src.ZLen(); z++ {
+			for x := 0; x < src.XLen(); x++ {
+				if nx, ny, nz, ok := s.blockPos(f, x, y, z); ok {
+					i, j := src.index(x, y, z), s.index(nx, ny, nz)
+					s.setId(j, src.id(i))
+					s.Data[j] = src.Data[i]
+				}
+			}
+		}
+	}
+	for _, e := range src.Entities {
+		if e, ok := s.moveEntity(e, f); ok {
+			s.Entities = append(s.Entities, e)
+		}
+	}
+	ticks := s.TileTicks
+	s.TileTicks = append([]TileTick(nil), src.TileTicks...)
+	s.mapTileTicks(func(x, y, z int) (int, int, int, bool) {
+		return s.blockPos(f, x, y, z)
+	})
+	s.TileTicks = append(ticks, s.TileTicks...)
+	s.pasteTileEntities(src, func(x, y, z int) (int, int, int, bool) {
+		return s.blockPos(f, x, y, z)
+	})
+}
+
+// moveEntity returns a copy of e moved by f and reports whether it is inside s.
+// Besides the position, the direction the entity looks in (Rotation), and
+// the block a painting or an item frame hangs on (TileX, TileY and TileZ)
+// and the side it faces (Facing and Direction, from 0 for south to 3 for
+// east) are moved. Entities without a position are kept as is.
+func (s *Schematic) moveEntity(e Entity, f pointFunc) (Entity, bool) {
+	if e.NBT == nil {
+		return e, true
+	}
+	c := nbt.Clone(e.NBT).(*nbt.Compound)
+	pos, ok := c.Get("Pos").(*nbt.List)
+	if !ok || pos.Len() != 3 || pos.ElemType != nbt.TagDouble {
+		return Entity{Id: e.Id, NBT: c}, true
+	}
+	px, py, pz := float64(pos.Tags[0].(nbt.Double)), float64(pos.Tags[1].(nbt.Double)), float64(pos.Tags[2].(nbt.Double))
+	x, y, z := f(px, py, pz)
+	if x < 0 || y < 0 || z < 0 || x > float64(s.Width) || y > float64(s.Height) || z > float64(s.Length) {
+		return e, false
+	}
+	c.Set("Pos", nbt.NewList(nbt.TagDouble, nbt.Double(x), nbt.Double(y), nbt.Double(z)))
+	if rot, ok := c.Get("Rotation").(*nbt.List); ok && rot.Len() == 2 && rot.ElemType == nbt.TagFloat {
+		yaw, pitch := rotateLook(f, px, py, pz, float64(rot.Tags[0].(nbt.Float)), float64(rot.Tags[1].(nbt.Float)))
+		c.Set("Rotation", nbt.NewList(nbt.TagFloat, nbt.Float(yaw), nbt.Float(pitch)))
+	}
+	for _, name := range []string{"Facing", "Direction"} {
+		if d, ok := c.Get(name).(nbt.Byte); ok && d >= 0 && d < 4 {
+			// The sides are numbered like the yaw in quarter turns.
+			yaw, _ := rotateLook(f, px, py, pz, float64(d)*90, 0)
+			c.Set(name, nbt.Byte(int(math.Floor(yaw/90+0.5))%4))
+		}
+	}
+	if _, ok := c.Get("TileX").(nbt.Int); ok {
+		tx, ty, tz := f(float64(intField(c, "TileX"))+0.5, float64(intField(c, "TileY"))+0.5, float64(intField(c, "TileZ"))+0.5)
+		c.Set("TileX", nbt.Int(math.Floor(tx)))
+		c.Set("TileY", nbt.Int(math.Floor(ty)))
+		c.Set("TileZ", nbt.Int(math.Floor(tz)))
+	}
+	return Entity{Id: e.Id, NBT: c}, true
+}
+
+// rotateLook maps by f the yaw and pitch of an entity at (x, y, z), in
+// degrees. Yaw 0 looks south (+z) and 90 west; positive pitch looks down.
+func rotateLook(f pointFunc, x, y, z, yaw, pitch float64) (float64, float64) {
+	x0, y0, z0 := f(x, y, z)
+	r := yaw * math.Pi / 180
+	x1, _, z1 := f(x-math.Sin(r), y, z+math.Cos(r))
+	yaw = math.Atan2(x0-x1, z1-z0) * 180 / math.Pi
+	if yaw = math.Floor(yaw*1000+0.5) / 1000; yaw < 0 {
+		yaw += 360
+	}
+	if _, y1, _ := f(x, y+1, z); y1 < y0 {
+		pitch = -pitch
+	}
+	return yaw, pitch
+}
+
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,272 @@
+package schematic
+
+import (
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// newTestVolume returns a 3x2x2 schematic with a few distinct blocks, an
+// entity and a tile tick at the block (2, 0, 1).
+func newTestVolume() *Schematic {
+	s := NewSchematic(3, 2, 2)
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	s.SetBlock(2, 0, 1, Block{35, 14})
+	s.SetBlock(1, 1, 0, Block{4, 0})
+	pos := nbt.NewList(nbt.TagDouble, nbt.Double(2.5), nbt.Double(0), nbt.Double(1.5))
+	s.Entities = []Entity{{Id: "Pig", NBT: nbt.NewCompound(nbt.Field{Name: "Pos", Tag: pos})}}
+	s.TileTicks = []TileTick{{X: 2, Y: 0, Z: 1}}
+	return s
+}
+
+func entityPos(e Entity) [3]float64 {
+	pos := e.NBT.Get("Pos").(*nbt.List)
+	return [3]float64{float64(pos.Tags[0].(nbt.Double)), float64(pos.Tags[1].(nbt.Double)), float64(pos.Tags[2].(nbt.Double))}
+}
+
+func TestRotate(t *testing.T) {
+	s := newTestVolume()
+	r, err := s.Rotate(90)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if r.Width != 2 || r.Height != 2 || r.Length != 3 {
+		t.Fatalf("Size: want 2x2x3, got %dx%dx%d", r.Width, r.Height, r.Length)
+	}
+	// Clockwise: (x, z) -> (length-1-z, x).
+	for _, tt := range []struct {
+		x, y, z int
+		want    Block
+	}{
+		{1, 0, 0, Block{1, 0}},
+		{0, 0, 2, Block{35, 14}},
+		{1, 1, 1, Block{4, 0}},
+	} {
+		if got := r.Block(tt.x, tt.y, tt.z); got != tt.want {
+			t.Errorf("Block(%d, %d, %d): want %v, got %v", tt.x, tt.y, tt.z, tt.want, got)
+		}
+	}
+	if tt := r.TileTicks[0]; tt.X != 0 || tt.Y != 0 || tt.Z != 2 {
+		t.Errorf("TileTick: want (0, 0, 2), got (%d, %d, %d)", tt.X, tt.Y, tt.Z)
+	}
+	if pos := entityPos(r.Entities[0]); pos != [3]float64{0.5, 0, 2.5} {
+		t.Errorf("Entity Pos: want [0.5 0 2.5], got %v", pos)
+	}
+	if pos := entityPos(s.Entities[0]); pos != [3]float64{2.5, 0, 1.5} {
+		t.Errorf("Rotate modified the source entity: %v", pos)
+	}
+	full, _ := r.Rotate(270)
+	if full.Fingerprint() != s.Fingerprint() {
+		t.Errorf("Rotate(90) + Rotate(270) is not an identity")
+	}
+	if _, err = s.Rotate(45); err == nil {
+		t.Errorf("Rotate(45): want error, got nil")
+	}
+}
+
+func TestFlip(t *testing.T) {
+	s := newTestVolume()
+	f := s.Flip(AxisY)
+	if got := f.Block(0, 1, 0); got != (Block{1, 0}) {
+		t.Errorf("Flip(y).Block(0, 1, 0): want stone, got %v", got)
+	}
+	if f = s.Flip(AxisX).Flip(AxisX); f.Fingerprint() != s.Fingerprint() {
+		t.Errorf("Flip(x) twice is not an identity")
+	}
+}
+
+func TestCropTrim(t *testing.T) {
+	s := newTestVolume()
+	c, err := s.Crop(Box{1, 0, 1, 3, 1, 2})
+	if err != nil {
+		t.Fatalf("Crop: %v", err)
+	}
+	if c.Width != 2 || c.Height != 1 || c.Length != 1 || c.Block(1, 0, 0) != (Block{35, 14}) {
+		t.Errorf("Crop: got %dx%dx%d, blocks %v", c.Width, c.Height, c.Length, c.Blocks)
+	}
+	if c.WEOffsetX != 1 || c.WEOffsetY != 0 || c.WEOffsetZ != 1 {
+		t.Errorf("Crop offset: want 1 0 1, got %d %d %d", c.WEOffsetX, c.WEOffsetY, c.WEOffsetZ)
+	}
+	if len(c.Entities) != 1 || len(c.TileTicks) != 1 {
+		t.Errorf("Crop: want 1 entity and 1 tile tick, got %d and %d", len(c.Entities), len(c.TileTicks))
+	}
+	if c, _ = s.Crop(Box{0, 0, 0, 1, 1, 1}); len(c.Entities) != 0 || len(c.TileTicks) != 0 {
+		t.Errorf("Crop: want no entities and tile ticks, got %d and %d", len(c.Entities), len(c.TileTicks))
+	}
+	for _, b := range []Box{{0, 0, 0, 0, 1, 1}, {0, 0, 0, 4, 1, 1}, {-1, 0, 0, 1, 1, 1}} {
+		if _, err = s.Crop(b); err == nil {
+			t.Errorf("Crop(%v): want error, got nil", b)
+		}
+	}
+
+	s.SetBlock(0, 0, 0, Block{})
+	if b := s.Bounds(); b != (Box{1, 0, 0, 3, 2, 2}) {
+		t.Errorf("Bounds: want [1,0,0]-[3,2,2], got %v", b)
+	}
+	if tr := s.Trim(); tr.Width != 2 || tr.Height != 2 || tr.Length != 2 {
+		t.Errorf("Trim: want 2x2x2, got %dx%dx%d", tr.Width, tr.Height, tr.Length)
+	}
+	if tr := NewSchematic(2, 2, 2).Trim(); tr.Width != 0 || len(tr.Blocks) != 0 {
+		t.Errorf("Trim of air: want an empty schematic, got %dx%dx%d", tr.Width, tr.Height, tr.Length)
+	}
+}
+
+func TestStack(t *testing.T) {
+	s := newTestVolume()
+	st, err := s.Stack(AxisZ, 3)
+	if err != nil {
+		t.Fatalf("Stack: %v", err)
+	}
+	if st.Length != 6 || st.Block(2, 0, 5) != (Block{35, 14}) || st.Block(0, 0, 4) != (Block{1, 0}) {
+		t.Errorf("Stack: wrong size or blocks: %dx%dx%d", st.Width, st.Height, st.Length)
+	}
+	if len(st.Entities) != 3 || len(st.TileTicks) != 3 || st.TileTicks[2].Z != 5 {
+		t.Errorf("Stack: want 3 entities and 3 tile ticks, got %d and %d", len(st.Entities), len(st.TileTicks))
+	}
+	if _, err = s.Stack(AxisX, 0); err == nil {
+		t.Errorf("Stack(x, 0): want error, got nil")
+	}
+	if _, err = s.Stack(AxisZ, MaxDimension/2+1); err == nil {
+		t.Errorf("Stack beyond %d blocks: want error, got nil", MaxDimension)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	s := newTestVolume()
+	if n := s.Replace(Block{35, 1}, Block{1, 0}, false); n != 0 {
+		t.Errorf("Replace orange wool: want 0, got %d", n)
+	}
+	if n := s.Replace(Block{35, 1}, Block{20, 0}, true); n != 1 || s.Block(2, 0, 1) != (Block{20, 0}) {
+		t.Errorf("Replace any wool: want 1 glass block, got %d, %v", n, s.Block(2, 0, 1))
+	}
+	if n := s.Replace(Block{}, Block{9, 0}, false); n != 9 {
+		t.Errorf("Replace air: want 9, got %d", n)
+	}
+}
+
+func TestReplaceMask(t *testing.T) {
+	s := newTestVolume()
+	if n := s.ReplaceMask(Block{}, Block{9, 0}, false, RegionMask(Box{0, 0, 0, 3, 1, 2})); n != 4 {
+		t.Errorf("ReplaceMask air in the bottom layer: want 4, got %d", n)
+	}
+	if s.GetV(1, 0, 0) != 9 || s.GetV(0, 1, 0) != 0 || s.GetV(0, 0, 0) != 1 {
+		t.Errorf("ReplaceMask: got %v", s.Blocks)
+	}
+	// The mask sees the blocks before the change.
+	if n := s.ReplaceMask(Block{9, 0}, Block{0, 0}, false, AdjacentMask(BlockMask(Block{9, 0}))); n != 4 {
+		t.Errorf("ReplaceMask water next to water: want 4, got %d", n)
+	}
+}
+
+func TestPaste(t *testing.T) {
+	glass := Block{20, 0}
+	src := newTestVolume()
+	src.SetBlock(1, 0, 0, StructureVoid)
+	paste := func(x int, opt *PasteOptions) *Schematic {
+		s := NewSchematic(4, 2, 2)
+		s.Replace(Block{}, glass, false)
+		s.Paste(src, x, 0, 0, opt)
+		return s
+	}
+
+	s := paste(1, nil)
+	if s.Block(0, 0, 0) != glass || s.Block(1, 0, 0) != (Block{1, 0}) || s.Block(3, 0, 1) != (Block{35, 14}) {
+		t.Errorf("Paste: wrong blocks %v", s.Blocks)
+	}
+	if s.Block(3, 1, 1) != (Block{}) {
+		t.Errorf("Paste: air did not overwrite glass")
+	}
+	if s.Block(2, 0, 0) != glass {
+		t.Errorf("Paste: structure void overwrote glass")
+	}
+	if len(s.Entities) != 1 || entityPos(s.Entities[0])[0] != 3.5 || len(s.TileTicks) != 1 || s.TileTicks[0].X != 3 {
+		t.Errorf("Paste: got entities %v and tile ticks %v", s.Entities, s.TileTicks)
+	}
+
+	s = paste(1, &PasteOptions{SkipAir: true, KeepVoid: true})
+	if s.Block(3, 1, 1) != glass || s.Block(2, 0, 0) != StructureVoid {
+		t.Errorf("Paste with SkipAir and KeepVoid: got %v and %v", s.Block(3, 1, 1), s.Block(2, 0, 0))
+	}
+
+	// The last column of src is outside.
+	s = paste(2, nil)
+	if s.Block(3, 0, 0) != glass || len(s.Entities) != 0 || len(s.TileTicks) != 0 {
+		t.Errorf("Paste clipped: got %v, %d entities and %d tile ticks", s.Block(3, 0, 0), len(s.Entities), len(s.TileTicks))
+	}
+}
+
+func TestTransformEntities(t *testing.T) {
+	s := newTestVolume()
+	pos := nbt.NewList(nbt.TagDouble, nbt.Double(2.5), nbt.Double(1), nbt.Double(1.5))
+	rot := nbt.NewList(nbt.TagFloat, nbt.Float(0), nbt.Float(30))
+	s.Entities = []Entity{{Id: "Painting", NBT: nbt.NewCompound().
+		Set("Pos", pos).
+		Set("Rotation", rot).
+		Set("TileX", nbt.Int(2)).Set("TileY", nbt.Int(1)).Set("TileZ", nbt.Int(1))}}
+
+	r, err := s.Rotate(90)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	c := r.Entities[0].NBT
+	if got := entityPos(r.Entities[0]); got != [3]float64{0.5, 1, 2.5} {
+		t.Errorf("Rotate: Pos: got %v", got)
+	}
+	if rot := c.Get("Rotation").(*nbt.List); rot.Tags[0] != nbt.Float(90) || rot.Tags[1] != nbt.Float(30) {
+		t.Errorf("Rotate: Rotation: want [90 30], got %v", rot.Tags)
+	}
+	if x, y, z := intField(c, "TileX"), intField(c, "TileY"), intField(c, "TileZ"); x != 0 || y != 1 || z != 2 {
+		t.Errorf("Rotate: Tile: want 0 1 2, got %d %d %d", x, y, z)
+	}
+
+	c = s.Flip(AxisY).Entities[0].NBT
+	if rot := c.Get("Rotation").(*nbt.List); rot.Tags[0] != nbt.Float(0) || rot.Tags[1] != nbt.Float(-30) {
+		t.Errorf("Flip: Rotation: want [0 -30], got %v", rot.Tags)
+	}
+	c = s.Flip(AxisX).Entities[0].NBT
+	if rot := c.Get("Rotation").(*nbt.List); rot.Tags[0] != nbt.Float(0) || intField(c, "TileX") != 0 {
+		t.Errorf("Flip: want yaw 0 and TileX 0, got %v and %d", rot.Tags, intField(c, "TileX"))
+	}
+}
+
+func TestTransformHangingEntities(t *testing.T) {
+	s := newTestVolume()
+	pos := nbt.NewList(nbt.TagDouble, nbt.Double(2.5), nbt.Double(1.5), nbt.Double(1.03))
+	s.Entities = []Entity{{Id: "ItemFrame", NBT: nbt.NewCompound().
+		Set("Pos", pos).
+		Set("Facing", nbt.Byte(0)).Set("Direction", nbt.Byte(0)).
+		Set("TileX", nbt.Int(2)).Set("TileY", nbt.Int(1)).Set("TileZ", nbt.Int(0))}}
+	facing := func(s *Schematic) (int, int) {
+		c := s.Entities[0].NBT
+		return intField(c, "Facing"), intField(c, "Direction")
+	}
+
+	tests := []struct {
+		angle int
+		want  int
+	}{
+		{90, 1},
+		{180, 2},
+		{270, 3},
+		{-90, 3},
+	}
+	for _, tt := range tests {
+		r, err := s.Rotate(tt.angle)
+		if err != nil {
+			t.Fatalf("Rotate(%d): %v", tt.angle, err)
+		}
+		if f, d := facing(r); f != tt.want || d != tt.want {
+			t.Errorf("Rotate(%d): want Facing and Direction %d, got %d and %d", tt.angle, tt.want, f, d)
+		}
+	}
+	if f, _ := facing(s.Flip(AxisZ)); f != 2 {
+		t.Errorf("Flip(z): want Facing 2, got %d", f)
+	}
+	if f, _ := facing(s.Flip(AxisX)); f != 0 {
+		t.Errorf("Flip(x): want Facing 0, got %d", f)
+	}
+	r, _ := s.Rotate(90)
+	if f, _ := facing(r.Flip(AxisX)); f != 3 {
+		t.Errorf("Flip(x) of a frame facing west: want Facing 3, got %d", f)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"io"
+	"json"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A TransportNode is a block holding items in a TransportGraph.
+type TransportNode struct {
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Z      int    `json:"z"`
+	Name   string `json:"name"`             // the block state name without the namespace, such as "hopper"
+	Facing string `json:"facing,omitempty"` // the direction in which a hopper or a dropper pushes the items
+}
+
+// A TransportEdge is a move of the items from one node to another, by
+// their indexes in Nodes.
+type TransportEdge struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// A TransportGraph is the network of the blocks moving and holding the
+// items in a schematic, such as a storage system.
+type TransportGraph struct {
+	Nodes []TransportNode `json:"nodes"` // in the order of the blocks
+	Edges []TransportEdge `json:"edges"`
+}
+
+// inventories are the blocks which hold items and take them from hoppers.
+var inventories = map[uint16]bool{23: true, 54: true, 61: true, 62: true, 117: true, 146: true, 154: true, 158: true}
+
+func isInventory(id uint16) bool {
+	return inventories[id] || id >= 219 && id <= 234
+}
+
+// dataFacings are the directions of the data values of the hoppers and
+// the droppers.
+var dataFacings = []Direction{Down, Up, North, South, West, East}
+
+// NewTransportGraph finds the hoppers, the droppers, the dispensers, the
+// chests, the furnaces, the brewing stands and the shulker boxes of s and
+// how the items move between them: a hopper pushes them into the block
+// it faces and pulls them from the block above it, and a dropper pushes
+// them into the block it faces. The facing is taken from the data value.
+// The halves of a double chest are separate nodes, and the hoppers locked
+// by redstone are taken as unlocked.
+func (s *Schematic) NewTransportGraph() *TransportGraph {
+	g := new(TransportGraph)
+	nodes := make(map[int]int) // the node of a block
+	for i := range s.Blocks {
+		b := Block{s.id(i), s.Data[i]}
+		if !isInventory(b.Id) {
+			continue
+		}
+		x, y, z := s.coords(i)
+		name, _ := LegacyState(b)
+		n := TransportNode{X: x, Y: y, Z: z, Name: strings.Replace(name, "minecraft:", "", 1)}
+		if b.Id == 154 || b.Id == 158 {
+			n.Facing = s.facing(i).String()
+		}
+		nodes[i] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, n)
+	}
+	seen := make(map[TransportEdge]bool)
+	add := func(from, to int) {
+		if e := (TransportEdge{from, to}); !seen[e] {
+			seen[e] = true
+			g.Edges = append(g.Edges, e)
+		}
+	}
+	for i, k := range nodes {
+		x, y, z := s.coords(i)
+		if id := s.id(i); id == 154 || id == 158 {
+			p := BlockPos{x, y, z}.Step(s.facing(i))
+			if s.Inside(p.X, p.Y, p.Z) {
+				if to, ok := nodes[s.index(p.X, p.Y, p.Z)]; ok {
+					add(k, to)
+				}
+			}
+		}
+		if s.id(i) == 154 && s.Inside(x, y+1, z) {
+			if from, ok := nodes[s.index(x, y+1, z)]; ok {
+				add(from, k)
+			}
+		}
+	}
+	sort.Sort(edgeSlice(g.Edges))
+	return g
+}
+
+// facing returns the direction of the hopper or the dropper i. Hoppers
+// cannot face up, and those with the data value of up face down.
+func (s *Schematic) facing(i int) Direction {
+	d := dataFacings[0]
+	if f := int(s.Data[i] & 7); f < len(dataFacings) {
+		d = dataFacings[f]
+	}
+	if d == Up && s.id(i) == 154 {
+		d = Down
+	}
+	return d
+}
+
+type edgeSlice []TransportEdge
+
+func (p edgeSlice) Len() int { return len(p) }
+func (p edgeSlice) Less(i, j int) bool {
+	return p[i].From < p[j].From || p[i].From == p[j].From && p[i].To < p[j].To
+}
+func (p edgeSlice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+// DeadEnds returns the indexes of the hoppers and the droppers which pass
+// their items nowhere, so that the items pile up in them.
+func (g *TransportGraph) DeadEnds() (ends []int) {
+	out := make([]bool, len(g.Nodes))
+	for _, e := range g.Edges {
+		out[e.From] = true
+	}
+	for i, n := range g.Nodes {
+		if n.Facing != "" && !out[i] {
+			ends = append(ends, i)
+		}
+	}
+	return
+}
+
+// WriteDOT writes g in the DOT language of Graphviz. The nodes are
+// labelled with their names and positions, and the dead ends are red.
+func (g *TransportGraph) WriteDOT(w io.Writer) os.Error {
+	dead := make(map[int]bool)
+	for _, i := range g.DeadEnds() {
+		dead[i] = true
+	}
+	if _, err := fmt.Fprintln(w, "digraph transport {"); err != nil {
+		return err
+	}
+	for i, n := range g.Nodes {
+		attrs := ""
+		if dead[i] {
+			attrs = ", color=red"
+		}
+		if _, err := fmt.Fprintf(w, "\tn%d [label=\"%s (%d, %d, %d)\"%s];\n", i, n.Name, n.X, n.Y, n.Z, attrs); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "\tn%d -> n%d;\n", e.From, e.To); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// WriteJSON writes g as a JSON object with the nodes and the edges.
+func (g *TransportGraph) WriteJSON(w io.Writer) os.Error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
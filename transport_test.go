@@ -0,0 +1,61 @@
+package schematic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTransportGraph(t *testing.T) {
+	// A chest above a hopper facing east into a dropper facing down into
+	// another hopper facing west into nothing.
+	s := NewSchematic(3, 3, 1)
+	s.SetBlock(0, 2, 0, Block{54, 2})
+	s.SetBlock(0, 1, 0, Block{154, 5})
+	s.SetBlock(1, 1, 0, Block{158, 0})
+	s.SetBlock(1, 0, 0, Block{154, 4})
+	g := s.NewTransportGraph()
+	want := []TransportNode{
+		{1, 0, 0, "hopper", "west"},
+		{0, 1, 0, "hopper", "east"},
+		{1, 1, 0, "dropper", "down"},
+		{0, 2, 0, "chest", ""},
+	}
+	if len(g.Nodes) != len(want) {
+		t.Fatalf("Nodes: want %v, got %v", want, g.Nodes)
+	}
+	for i := range want {
+		if g.Nodes[i] != want[i] {
+			t.Errorf("Node %d: want %v, got %v", i, want[i], g.Nodes[i])
+		}
+	}
+	edges := []TransportEdge{{1, 2}, {2, 0}, {3, 1}}
+	if len(g.Edges) != len(edges) {
+		t.Fatalf("Edges: want %v, got %v", edges, g.Edges)
+	}
+	for i := range edges {
+		if g.Edges[i] != edges[i] {
+			t.Errorf("Edge %d: want %v, got %v", i, edges[i], g.Edges[i])
+		}
+	}
+	if ends := g.DeadEnds(); len(ends) != 1 || ends[0] != 0 {
+		t.Errorf("DeadEnds: want [0], got %v", ends)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT: %v", err)
+	}
+	for _, line := range []string{"\tn0 [label=\"hopper (1, 0, 0)\", color=red];", "\tn3 -> n1;"} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("WriteDOT: no %q in\n%s", line, buf.String())
+		}
+	}
+	buf.Reset()
+	if err := g.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if !strings.Contains(buf.String(), `{"x":0,"y":2,"z":0,"name":"chest"}`) || !strings.Contains(buf.String(), `"edges":[{"from":1,"to":2}`) {
+		t.Errorf("WriteJSON: got %s", buf.String())
+	}
+}
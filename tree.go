@@ -0,0 +1,261 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"math"
+	"rand"
+)
+
+// A TreeStyle describes a tree grown by an L-system: the Axiom is rewritten
+// Iterations times by the Rules, and the resulting string is drawn by a
+// turtle which starts at the base of the trunk heading up. The symbols are
+//
+//	F       draw a log segment of Length blocks
+//	G       move Length blocks without drawing
+//	L       draw a blob of leaves
+//	+ -     turn left, right by Angle degrees
+//	& ^     pitch down, up
+//	\ /     roll left, right
+//	|       turn around
+//	[ ]     start and end a branch; segments are Shrink times shorter
+//	        and thinner in every nested branch
+//
+// Other symbols, usually those rewritten by the rules, are ignored.
+type TreeStyle struct {
+	Axiom      string
+	Rules      map[byte]string
+	Iterations int
+
+	Length      float64 // the length of the F segments of the trunk
+	Angle       float64 // the turning angle in degrees
+	Jitter      float64 // the largest random deviation of turns in degrees
+	Shrink      float64 // the scale of each nested branch
+	TrunkRadius float64 // the radius of the trunk; below 0.5 it is one block thick
+	LeafRadius  float64
+
+	// Log and Leaves are the blocks of the tree. The direction of the logs
+	// is set from the segments for the ids 17 and 162.
+	Log, Leaves Block
+}
+
+// The preset tree styles.
+var (
+	OakTree = &TreeStyle{
+		Axiom:       "FFFA",
+		Rules:       map[byte]string{'A': "F[&&FAL]/////[&&FAL]///////[&&FAL]"},
+		Iterations:  3,
+		Length:      2,
+		Angle:       22.5,
+		Jitter:      8,
+		Shrink:      0.8,
+		TrunkRadius: 0.7,
+		LeafRadius:  2.5,
+		Log:         Block{17, 0},
+		Leaves:      Block{18, 0},
+	}
+	BirchTree = &TreeStyle{
+		Axiom:      "FFFFA",
+		Rules:      map[byte]string{'A': "F[&FL]///[&FL]////[&FL]A"},
+		Iterations: 3,
+		Length:     1.5,
+		Angle:      30,
+		Jitter:     10,
+		Shrink:     0.6,
+		LeafRadius: 1.8,
+		Log:        Block{17, 2},
+		Leaves:     Block{18, 2},
+	}
+	SpruceTree = &TreeStyle{
+		Axiom:      "FFA",
+		Rules:      map[byte]string{'A': "F[&&&&GL]//[&&&&GL]//[&&&&GL]//[&&&&GL]FA"},
+		Iterations: 5,
+		Length:     1.5,
+		Angle:      18,
+		Jitter:     5,
+		Shrink:     0.9,
+		LeafRadius: 1.5,
+		Log:        Block{17, 1},
+		Leaves:     Block{18, 1},
+	}
+	AcaciaTree = &TreeStyle{
+		Axiom:      "FFF[&&&FFF^^FL]/////////[&&&FF^^FFL]",
+		Iterations: 0,
+		Length:     1.5,
+		Angle:      20,
+		Jitter:     10,
+		Shrink:     1,
+		LeafRadius: 2.5,
+		Log:        Block{162, 0},
+		Leaves:     Block{161, 0},
+	}
+)
+
+// expand rewrites the axiom by the rules.
+func (st *TreeStyle) expand() string {
+	str := st.Axiom
+	for i := 0; i < st.Iterations; i++ {
+		var next []byte
+		for j := 0; j < len(str); j++ {
+			if r, ok := st.Rules[str[j]]; ok {
+				next = append(next, r...)
+			} else {
+				next = append(next, str[j])
+			}
+		}
+		str = string(next)
+	}
+	return str
+}
+
+type vec [3]float64
+
+func (a vec) add(b vec, f float64) vec {
+	return vec{a[0] + b[0]*f, a[1] + b[1]*f, a[2] + b[2]*f}
+}
+
+// rotate returns v rotated around the unit vector axis by the angle in radians.
+func (v vec) rotate(axis vec, angle float64) vec {
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	dot := v[0]*axis[0] + v[1]*axis[1] + v[2]*axis[2]
+	cross := vec{axis[1]*v[2] - axis[2]*v[1], axis[2]*v[0] - axis[0]*v[2], axis[0]*v[1] - axis[1]*v[0]}
+	var r vec
+	for i := range r {
+		r[i] = v[i]*cos + cross[i]*sin + axis[i]*dot*(1-cos)
+	}
+	return r
+}
+
+// treeTurtle is the state of the turtle drawing a tree.
+type treeTurtle struct {
+	pos     vec
+	h, l, u vec // heading, left and up
+	scale   float64
+}
+
+// GrowTree grows a tree of the style with the base of the trunk at
+// (x, y, z). The parts of the tree outside of the schematic are cut off.
+// Leaves replace only air. The seed selects the random deviations of the
+// branches.
+func (s *Schematic) GrowTree(x, y, z int, style *TreeStyle, seed int64) {
+	rnd := rand.New(rand.NewSource(seed))
+	t := treeTurtle{
+		pos:   vec{float64(x) + 0.5, float64(y), float64(z) + 0.5},
+		h:     vec{0, 1, 0},
+		l:     vec{1, 0, 0},
+		u:     vec{0, 0, -1},
+		scale: 1,
+	}
+	var stack []treeTurtle
+	turn := func(v1, v2 *vec, axis vec, sign float64) {
+		a := (sign*style.Angle + (rnd.Float64()*2-1)*style.Jitter) * math.Pi / 180
+		*v1, *v2 = v1.rotate(axis, a), v2.rotate(axis, a)
+	}
+	for _, c := range style.expand() {
+		switch c {
+		case 'F', 'G':
+			end := t.pos.add(t.h, style.Length*t.scale)
+			if c == 'F' {
+				s.drawBranch(t.pos, end, style.TrunkRadius*t.scale, style.logBlock(t.h))
+			}
+			t.pos = end
+		case 'L':
+			s.drawLeaves(t.pos, style.LeafRadius, style.Leaves, rnd)
+		case '+', '-':
+			sign := 1.0
+			if c == '-' {
+				sign = -1
+			}
+			turn(&t.h, &t.l, t.u, sign)
+		case '&', '^':
+			sign := 1.0
+			if c == '^' {
+				sign = -1
+			}
+			turn(&t.h, &t.u, t.l, sign)
+		case '\\', '/':
+			sign := 1.0
+			if c == '/' {
+				sign = -1
+			}
+			turn(&t.l, &t.u, t.h, sign)
+		case '|':
+			t.h, t.l = t.h.add(t.h, -2), t.l.add(t.l, -2)
+		case '[':
+			stack = append(stack, t)
+			t.scale *= style.Shrink
+		case ']':
+			if len(stack) > 0 {
+				t = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// logBlock returns the log lying along the direction.
+func (st *TreeStyle) logBlock(dir vec) Block {
+	b := st.Log
+	if b.Id != 17 && b.Id != 162 {
+		return b
+	}
+	b.Data &= 3
+	switch ax, ay, az := abs(dir[0]), abs(dir[1]), abs(dir[2]); {
+	case ax > ay && ax >= az:
+		b.Data |= 4
+	case az > ay:
+		b.Data |= 8
+	}
+	return b
+}
+
+// setInside sets the block if the position is inside the schematic and
+// reports whether it is.
+func (s *Schematic) setInside(x, y, z int, b Block) bool {
+	if x < 0 || y < 0 || z < 0 || x >= s.Width || y >= s.Height || z >= s.Length {
+		return false
+	}
+	s.SetBlock(x, y, z, b)
+	return true
+}
+
+// drawBranch fills the blocks within the radius of the segment from a to b.
+func (s *Schematic) drawBranch(a, b vec, radius float64, log Block) {
+	d := vec{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+	steps := int(math.Ceil(math.Sqrt(d[0]*d[0]+d[1]*d[1]+d[2]*d[2]) * 2))
+	r := int(math.Ceil(radius))
+	for i := 0; i <= steps; i++ {
+		p := a.add(d, float64(i)/float64(imax(steps, 1)))
+		cx, cy, cz := int(math.Floor(p[0])), int(math.Floor(p[1])), int(math.Floor(p[2]))
+		for dy := -r; dy <= r; dy++ {
+			for dz := -r; dz <= r; dz++ {
+				for dx := -r; dx <= r; dx++ {
+					if float64(dx*dx+dy*dy+dz*dz) <= radius*radius || dx == 0 && dy == 0 && dz == 0 {
+						s.setInside(cx+dx, cy+dy, cz+dz, log)
+					}
+				}
+			}
+		}
+	}
+}
+
+// drawLeaves fills the air within the radius of the center with leaves,
+// leaving out some of the blocks on the edge.
+func (s *Schematic) drawLeaves(center vec, radius float64, leaves Block, rnd *rand.Rand) {
+	r := int(math.Ceil(radius))
+	cx, cy, cz := int(math.Floor(center[0])), int(math.Floor(center[1])), int(math.Floor(center[2]))
+	for dy := -r; dy <= r; dy++ {
+		for dz := -r; dz <= r; dz++ {
+			for dx := -r; dx <= r; dx++ {
+				d := math.Sqrt(float64(dx*dx + dy*dy + dz*dz))
+				if d > radius || d > radius-1 && rnd.Float64() < 0.3 {
+					continue
+				}
+				if x, y, z := cx+dx, cy+dy, cz+dz; s.GetV(x, y, z) == 0 {
+					s.setInside(x, y, z, leaves)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	st := &TreeStyle{Axiom: "FA", Rules: map[byte]string{'A': "[+FA]"}, Iterations: 2}
+	if got := st.expand(); got != "F[+F[+FA]]" {
+		t.Errorf("expand: want F[+F[+FA]], got %s", got)
+	}
+}
+
+func TestGrowTree(t *testing.T) {
+	for _, style := range []*TreeStyle{OakTree, BirchTree, SpruceTree, AcaciaTree} {
+		s := NewSchematic(24, 32, 24)
+		s.GrowTree(12, 0, 12, style, 1)
+		if s.GetV(12, 0, 12) != style.Log.Id || s.Block(12, 1, 12).Data&12 != 0 {
+			t.Errorf("%v: want an upright log at the base, got %v", style.Log, s.Block(12, 0, 12))
+		}
+		logs, leaves := len(s.selected(IdMask(style.Log.Id))), len(s.selected(IdMask(style.Leaves.Id)))
+		if logs < 4 || leaves < 20 {
+			t.Errorf("%v: got %d logs and %d leaves", style.Log, logs, leaves)
+		}
+		if b := s.Bounds(); b.MaxY < 6 || b.MinY != 0 {
+			t.Errorf("%v: bounds %v", style.Log, b)
+		}
+		again := NewSchematic(24, 32, 24)
+		again.GrowTree(12, 0, 12, style, 1)
+		if again.Fingerprint() != s.Fingerprint() {
+			t.Errorf("%v: the same seed gives another tree", style.Log)
+		}
+	}
+
+	// A tree at the edge is cut off.
+	s := NewSchematic(4, 8, 4)
+	s.GrowTree(0, 0, 0, OakTree, 2)
+	if s.GetV(0, 0, 0) != 17 {
+		t.Errorf("Tree at the edge: want a log at the base, got %v", s.Block(0, 0, 0))
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+)
+
+// A Turtle builds in a schematic by moving around it, like the turtle of
+// Logo. It faces one of the four horizontal directions and places blocks
+// of its current pattern. The methods return the turtle, so that the
+// calls can be chained:
+//
+//	t := NewTurtle(s)
+//	t.Use(Block{98, 0}).PenDown()
+//	for i := 0; i < 4; i++ {
+//		t.Forward(9).Turn(90)
+//	}
+//
+// Blocks placed outside of the schematic are dropped.
+type Turtle struct {
+	s     *Schematic
+	state turtleState
+	stack []turtleState
+}
+
+type turtleState struct {
+	x, y, z int
+	dir     int // 0 is east (+x), 1 south (+z), 2 west, 3 north
+	pattern Pattern
+	pen     bool
+}
+
+// turtleDirs are the unit steps of the directions.
+var turtleDirs = [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// NewTurtle returns a turtle at the minimum corner of s facing east, with
+// the pen up and stone as the pattern.
+func NewTurtle(s *Schematic) *Turtle {
+	return &Turtle{s: s, state: turtleState{pattern: Block{1, 0}}}
+}
+
+// Position returns the position of the turtle.
+func (t *Turtle) Position() (x, y, z int) {
+	return t.state.x, t.state.y, t.state.z
+}
+
+// MoveTo moves the turtle to the position without placing blocks.
+func (t *Turtle) MoveTo(x, y, z int) *Turtle {
+	t.state.x, t.state.y, t.state.z = x, y, z
+	return t
+}
+
+// Use sets the pattern of the placed blocks. A Block is a pattern.
+func (t *Turtle) Use(p Pattern) *Turtle {
+	t.state.pattern = p
+	return t
+}
+
+// PenDown makes the turtle place a block at every position it moves to.
+func (t *Turtle) PenDown() *Turtle {
+	t.state.pen = true
+	return t
+}
+
+// PenUp makes the turtle move without placing blocks.
+func (t *Turtle) PenUp() *Turtle {
+	t.state.pen = false
+	return t
+}
+
+// Turn turns the turtle clockwise as seen from above. The angle must be
+// a multiple of 90 degrees.
+func (t *Turtle) Turn(angle int) *Turtle {
+	if angle%90 != 0 {
+		panic(fmt.Sprintf("schematic: Turn(%d) is not a multiple of 90 degrees", angle))
+	}
+	t.state.dir = ((t.state.dir+angle/90)%4 + 4) % 4
+	return t
+}
+
+// Forward moves the turtle n blocks in the direction it faces, or back
+// if n is negative.
+func (t *Turtle) Forward(n int) *Turtle {
+	d := turtleDirs[t.state.dir]
+	return t.move(n, d[0], 0, d[1])
+}
+
+// Right moves the turtle n blocks to its right, or left if n is negative,
+// without turning.
+func (t *Turtle) Right(n int) *Turtle {
+	d := turtleDirs[(t.state.dir+1)%4]
+	return t.move(n, d[0], 0, d[1])
+}
+
+// Up moves the turtle n blocks up, or down if n is negative.
+func (t *Turtle) Up(n int) *Turtle {
+	return t.move(n, 0, 1, 0)
+}
+
+// move makes n steps of (dx, dy, dz), placing blocks if the pen is down.
+func (t *Turtle) move(n, dx, dy, dz int) *Turtle {
+	if n < 0 {
+		n, dx, dy, dz = -n, -dx, -dy, -dz
+	}
+	for i := 0; i < n; i++ {
+		t.state.x += dx
+		t.state.y += dy
+		t.state.z += dz
+		if t.state.pen {
+			t.Place()
+		}
+	}
+	return t
+}
+
+// Place places a block at the position of the turtle.
+func (t *Turtle) Place() *Turtle {
+	x, y, z := t.state.x, t.state.y, t.state.z
+	t.s.setInside(x, y, z, t.state.pattern.BlockAt(x, y, z))
+	return t
+}
+
+// Fill fills the cuboid extending from the position of the turtle forward
+// by forward blocks, up by up blocks and to the right by right blocks.
+// The turtle does not move.
+func (t *Turtle) Fill(forward, up, right int) *Turtle {
+	return t.cuboid(forward, up, right, false)
+}
+
+// Box is like Fill, but places only the faces of the cuboid.
+func (t *Turtle) Box(forward, up, right int) *Turtle {
+	return t.cuboid(forward, up, right, true)
+}
+
+func (t *Turtle) cuboid(forward, up, right int, hollow bool) *Turtle {
+	f, r := turtleDirs[t.state.dir], turtleDirs[(t.state.dir+1)%4]
+	for i := 0; i < forward; i++ {
+		for j := 0; j < up; j++ {
+			for k := 0; k < right; k++ {
+				if hollow && i > 0 && i < forward-1 && j > 0 && j < up-1 && k > 0 && k < right-1 {
+					continue
+				}
+				x := t.state.x + i*f[0] + k*r[0]
+				y := t.state.y + j
+				z := t.state.z + i*f[1] + k*r[1]
+				t.s.setInside(x, y, z, t.state.pattern.BlockAt(x, y, z))
+			}
+		}
+	}
+	return t
+}
+
+// Push saves the position, the direction, the pattern and the pen of the
+// turtle.
+func (t *Turtle) Push() *Turtle {
+	t.stack = append(t.stack, t.state)
+	return t
+}
+
+// Pop restores the state saved by the last Push. It panics if there is
+// no such state.
+func (t *Turtle) Pop() *Turtle {
+	if len(t.stack) == 0 {
+		panic("schematic: Pop without Push")
+	}
+	t.state = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	return t
+}
@@ -0,0 +1,49 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestTurtle(t *testing.T) {
+	s := NewSchematic(10, 6, 10)
+	tt := NewTurtle(s)
+	// A ring of stone bricks.
+	tt.Use(Block{98, 0}).PenDown()
+	for i := 0; i < 4; i++ {
+		tt.Forward(9).Turn(90)
+	}
+	if x, y, z := tt.Position(); x != 0 || y != 0 || z != 0 {
+		t.Errorf("Position after the ring: want 0, 0, 0, got %d, %d, %d", x, y, z)
+	}
+	if n := len(s.selected(IdMask(98))); n != 36 {
+		t.Errorf("Ring: want 36 blocks, got %d", n)
+	}
+
+	// A hollow box on the ring and a pillar, restoring the state after it.
+	tt.PenUp().Up(1).Box(10, 3, 10)
+	tt.Push().Use(Block{17, 0}).MoveTo(5, 1, 5).PenDown().Place().Up(4).Pop()
+	// The floor and the ceiling of the box, the walls between them, and
+	// two blocks of the box replaced by the pillar.
+	if n := len(s.selected(IdMask(98))); n != 36+100+36+100-2 {
+		t.Errorf("Box: want %d blocks, got %d", 36+100+36+100-2, n)
+	}
+	if s.GetV(5, 5, 5) != 17 || s.GetV(5, 1, 5) != 17 {
+		t.Errorf("Pillar: got %v and %v", s.Block(5, 5, 5), s.Block(5, 1, 5))
+	}
+	if x, y, z := tt.Position(); x != 0 || y != 1 || z != 0 {
+		t.Errorf("Position after Pop: want 0, 1, 0, got %d, %d, %d", x, y, z)
+	}
+
+	// Facing north, the right is east and forward is -z.
+	tt.MoveTo(2, 4, 9).Turn(-90).Use(Block{5, 0}).Fill(3, 1, 2).Right(-2).Place()
+	if n := len(s.selected(IdMask(5))); n != 7 || s.GetV(3, 4, 7) != 5 || s.GetV(0, 4, 9) != 5 {
+		t.Errorf("Fill: got %d planks", n)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Turn(45): want panic")
+		}
+	}()
+	tt.Turn(45)
+}
@@ -0,0 +1,586 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Data versions of the Minecraft releases which changed block state names.
+// Formats with block states record the version in a DataVersion tag.
+const (
+	DataVersion1_12   = 1343 // 1.12.2, the last version with numeric ids
+	DataVersion1_13   = 1631 // 1.13.2, the names returned by LegacyState
+	DataVersion1_14   = 1952 // sign became oak_sign, stone_slab smooth_stone_slab
+	DataVersion1_16   = 2586 // 1.16.5
+	DataVersion1_17   = 2724 // grass_path became dirt_path
+	DataVersion1_20   = 3700 // 1.20.4, grass became short_grass
+	DataVersion1_21   = 3953
+	LatestDataVersion = DataVersion1_21
+)
+
+// A stateRename is a block renamed in a Minecraft release.
+type stateRename struct {
+	version  int // the first data version with the new name
+	from, to string
+}
+
+// stateRenames are ordered by version. A name may be reused for a new block,
+// like stone_slab in 1.14, so the renames are applied one version at a time.
+var stateRenames = []stateRename{
+	{DataVersion1_14, "minecraft:sign", "minecraft:oak_sign"},
+	{DataVersion1_14, "minecraft:wall_sign", "minecraft:oak_wall_sign"},
+	{DataVersion1_14, "minecraft:stone_slab", "minecraft:smooth_stone_slab"},
+	{DataVersion1_17, "minecraft:grass_path", "minecraft:dirt_path"},
+	{3698, "minecraft:grass", "minecraft:short_grass"}, // 1.20.3
+}
+
+// TranslateState returns the name of a block state of the data version from
+// in the data version to. Both versions must be 1.13 or later. ok is false
+// if the block does not exist in the target version. The renames, the
+// first versions of the blocks added since 1.13 and the property changes
+// of walls (1.16) and cauldrons (1.17) are known; the other blocks and
+// properties are returned unchanged.
+func TranslateState(state string, from, to int) (res string, ok bool) {
+	name, props := state, ""
+	if i := strings.Index(state, "["); i >= 0 {
+		name, props = state[:i], state[i:]
+	}
+	if from <= to {
+		for _, r := range stateRenames {
+			if from < r.version && r.version <= to && name == r.from {
+				name = r.to
+			}
+		}
+		return translateProps(name, props, from, to)
+	}
+	for i := len(stateRenames) - 1; i >= 0; i-- {
+		r := stateRenames[i]
+		if to < r.version && r.version <= from {
+			switch name {
+			case r.to:
+				name = r.from
+			case r.from:
+				// A new block reusing the old name.
+				return "", false
+			}
+		}
+	}
+	if name, props, ok = translatePropsDown(name, props, from, to); !ok {
+		return "", false
+	}
+	if v := addedIn(name); v > to {
+		return "", false
+	}
+	return name + props, true
+}
+
+// translateProps applies the property changes between the versions from
+// and to, from <= to, to a block and returns the state.
+func translateProps(name, props string, from, to int) (string, bool) {
+	if props == "" {
+		return name, true
+	}
+	n, p, ok := splitState(name + props)
+	if !ok {
+		return name + props, true
+	}
+	if from < wallSidesVersion && wallSidesVersion <= to && isWall(n) {
+		for _, side := range horizontalNames {
+			switch p[side] {
+			case "true":
+				p[side] = "low"
+			case "false":
+				p[side] = "none"
+			}
+		}
+	}
+	if from < DataVersion1_17 && DataVersion1_17 <= to && n == "minecraft:cauldron" {
+		if level, ok := p["level"]; ok {
+			p["level"] = "", false
+			if level != "0" {
+				n = "minecraft:water_cauldron"
+				p["level"] = level
+			}
+		}
+	}
+	return joinState(n, p), true
+}
+
+// translatePropsDown is the reverse of translateProps for from > to. ok is
+// false for the cauldrons of lava and powder snow, which were added in 1.17.
+func translatePropsDown(name, props string, from, to int) (string, string, bool) {
+	if from >= DataVersion1_17 && DataVersion1_17 > to {
+		switch name {
+		case "minecraft:cauldron":
+			props = "[level=0]"
+		case "minecraft:water_cauldron":
+			name = "minecraft:cauldron"
+		}
+	}
+	if props == "" || from < wallSidesVersion || wallSidesVersion <= to || !isWall(name) {
+		return name, props, true
+	}
+	n, p, ok := splitState(name + props)
+	if !ok {
+		return name, props, true
+	}
+	for _, side := range horizontalNames {
+		switch p[side] {
+		case "low", "tall":
+			p[side] = "true"
+		case "none":
+			p[side] = "false"
+		}
+	}
+	return n, joinState(n, p)[len(n):], true
+}
+
+// wallSidesVersion is 1.16, where the sides of walls changed from true and
+// false to none, low and tall.
+const wallSidesVersion = 2566
+
+// isWall reports whether the block is a wall, such as cobblestone_wall.
+func isWall(name string) bool {
+	return strings.HasSuffix(name, "_wall")
+}
+
+// A blockAddition is a block, or a family of blocks, added after 1.13.
+// The pattern is a block name, "prefix*", "*suffix" or "*part*".
+type blockAddition struct {
+	version int // the first data version with the block
+	pattern string
+}
+
+// blockAdditions are tried in order, so the exceptions to a family, like
+// the copper doors of 1.21, come before it.
+var blockAdditions = []blockAddition{
+	// 1.14
+	{DataVersion1_14, "bamboo"},
+	{DataVersion1_14, "bamboo_sapling"},
+	{DataVersion1_14, "potted_bamboo"},
+	{DataVersion1_14, "barrel"},
+	{DataVersion1_14, "bell"},
+	{DataVersion1_14, "blast_furnace"},
+	{DataVersion1_14, "smoker"},
+	{DataVersion1_14, "campfire"},
+	{DataVersion1_14, "lantern"},
+	{DataVersion1_14, "composter"},
+	{DataVersion1_14, "grindstone"},
+	{DataVersion1_14, "lectern"},
+	{DataVersion1_14, "stonecutter"},
+	{DataVersion1_14, "cartography_table"},
+	{DataVersion1_14, "fletching_table"},
+	{DataVersion1_14, "smithing_table"},
+	{DataVersion1_14, "scaffolding"},
+	{DataVersion1_14, "sweet_berry_bush"},
+	{DataVersion1_14, "jigsaw"},
+	{DataVersion1_14, "cornflower"},
+	{DataVersion1_14, "lily_of_the_valley"},
+	{DataVersion1_14, "wither_rose"},
+	{DataVersion1_14, "potted_cornflower"},
+	{DataVersion1_14, "potted_lily_of_the_valley"},
+	{DataVersion1_14, "potted_wither_rose"},
+	{DataVersion1_14, "spruce_sign"},
+	{DataVersion1_14, "spruce_wall_sign"},
+	{DataVersion1_14, "birch_sign"},
+	{DataVersion1_14, "birch_wall_sign"},
+	{DataVersion1_14, "jungle_sign"},
+	{DataVersion1_14, "jungle_wall_sign"},
+	{DataVersion1_14, "acacia_sign"},
+	{DataVersion1_14, "acacia_wall_sign"},
+	{DataVersion1_14, "dark_oak_sign"},
+	{DataVersion1_14, "dark_oak_wall_sign"},
+	{DataVersion1_14, "stone_stairs"},
+	{DataVersion1_14, "granite_*"},
+	{DataVersion1_14, "polished_granite_*"},
+	{DataVersion1_14, "diorite_*"},
+	{DataVersion1_14, "polished_diorite_*"},
+	{DataVersion1_14, "andesite_*"},
+	{DataVersion1_14, "polished_andesite_*"},
+	{DataVersion1_14, "mossy_stone_brick_*"},
+	{DataVersion1_14, "mossy_cobblestone_stairs"},
+	{DataVersion1_14, "mossy_cobblestone_slab"},
+	{DataVersion1_14, "end_stone_brick_*"},
+	{DataVersion1_14, "smooth_sandstone_*"},
+	{DataVersion1_14, "smooth_red_sandstone_*"},
+	{DataVersion1_14, "smooth_quartz_*"},
+	{DataVersion1_14, "red_nether_brick_*"},
+	{DataVersion1_14, "brick_wall"},
+	{DataVersion1_14, "prismarine_wall"},
+	{DataVersion1_14, "red_sandstone_wall"},
+	{DataVersion1_14, "stone_brick_wall"},
+	{DataVersion1_14, "nether_brick_wall"},
+	{DataVersion1_14, "sandstone_wall"},
+
+	// 1.15
+	{2225, "bee_nest"},
+	{2225, "beehive"},
+	{2225, "honey_block"},
+	{2225, "honeycomb_block"},
+
+	// 1.16
+	{2566, "crimson_*"},
+	{2566, "warped_*"},
+	{2566, "potted_crimson_*"},
+	{2566, "potted_warped_*"},
+	{2566, "stripped_crimson_*"},
+	{2566, "stripped_warped_*"},
+	{2566, "ancient_debris"},
+	{2566, "basalt"},
+	{2566, "polished_basalt"},
+	{2566, "*blackstone*"},
+	{2566, "chain"},
+	{2566, "crying_obsidian"},
+	{2566, "lodestone"},
+	{2566, "netherite_block"},
+	{2566, "nether_gold_ore"},
+	{2566, "nether_sprouts"},
+	{2566, "quartz_bricks"},
+	{2566, "respawn_anchor"},
+	{2566, "shroomlight"},
+	{2566, "soul_campfire"},
+	{2566, "soul_fire"},
+	{2566, "soul_lantern"},
+	{2566, "soul_soil"},
+	{2566, "soul_torch"},
+	{2566, "soul_wall_torch"},
+	{2566, "target"},
+	{2566, "twisting_vines"},
+	{2566, "twisting_vines_plant"},
+	{2566, "weeping_vines"},
+	{2566, "weeping_vines_plant"},
+	{2566, "chiseled_nether_bricks"},
+	{2566, "cracked_nether_bricks"},
+
+	// 1.19, before the deepslate of 1.17
+	{3105, "reinforced_deepslate"},
+
+	// 1.21, before the copper of 1.17
+	{DataVersion1_21, "crafter"},
+	{DataVersion1_21, "trial_spawner"},
+	{DataVersion1_21, "vault"},
+	{DataVersion1_21, "heavy_core"},
+	{DataVersion1_21, "*copper_door"},
+	{DataVersion1_21, "*copper_trapdoor"},
+	{DataVersion1_21, "*copper_grate"},
+	{DataVersion1_21, "*copper_bulb"},
+	{DataVersion1_21, "*chiseled_copper"},
+	{DataVersion1_21, "tuff_*"},
+	{DataVersion1_21, "polished_tuff*"},
+	{DataVersion1_21, "chiseled_tuff*"},
+
+	// 1.17
+	{DataVersion1_17, "*copper*"},
+	{DataVersion1_17, "*deepslate*"},
+	{DataVersion1_17, "*amethyst*"},
+	{DataVersion1_17, "*candle*"},
+	{DataVersion1_17, "calcite"},
+	{DataVersion1_17, "tuff"},
+	{DataVersion1_17, "tinted_glass"},
+	{DataVersion1_17, "powder_snow"},
+	{DataVersion1_17, "sculk_sensor"},
+	{DataVersion1_17, "dripstone_block"},
+	{DataVersion1_17, "pointed_dripstone"},
+	{DataVersion1_17, "cave_vines"},
+	{DataVersion1_17, "cave_vines_plant"},
+	{DataVersion1_17, "spore_blossom"},
+	{DataVersion1_17, "*azalea*"},
+	{DataVersion1_17, "moss_block"},
+	{DataVersion1_17, "moss_carpet"},
+	{DataVersion1_17, "big_dripleaf"},
+	{DataVersion1_17, "big_dripleaf_stem"},
+	{DataVersion1_17, "small_dripleaf"},
+	{DataVersion1_17, "hanging_roots"},
+	{DataVersion1_17, "rooted_dirt"},
+	{DataVersion1_17, "glow_lichen"},
+	{DataVersion1_17, "lightning_rod"},
+	{DataVersion1_17, "smooth_basalt"},
+	{DataVersion1_17, "raw_iron_block"},
+	{DataVersion1_17, "raw_gold_block"},
+	{DataVersion1_17, "light"},
+	{DataVersion1_17, "water_cauldron"},
+	{DataVersion1_17, "lava_cauldron"},
+	{DataVersion1_17, "powder_snow_cauldron"},
+
+	// 1.19
+	{3105, "mangrove_*"},
+	{3105, "stripped_mangrove_*"},
+	{3105, "potted_mangrove_propagule"},
+	{3105, "muddy_mangrove_roots"},
+	{3105, "mud"},
+	{3105, "mud_brick*"},
+	{3105, "packed_mud"},
+	{3105, "sculk"},
+	{3105, "sculk_vein"},
+	{3105, "sculk_catalyst"},
+	{3105, "sculk_shrieker"},
+	{3105, "frogspawn"},
+	{3105, "ochre_froglight"},
+	{3105, "verdant_froglight"},
+	{3105, "pearlescent_froglight"},
+
+	// 1.20
+	{3463, "bamboo_*"},
+	{3463, "stripped_bamboo_block"},
+	{3463, "cherry_*"},
+	{3463, "stripped_cherry_*"},
+	{3463, "potted_cherry_sapling"},
+	{3463, "*_hanging_sign"},
+	{3463, "chiseled_bookshelf"},
+	{3463, "decorated_pot"},
+	{3463, "suspicious_sand"},
+	{3463, "suspicious_gravel"},
+	{3463, "sniffer_egg"},
+	{3463, "torchflower"},
+	{3463, "torchflower_crop"},
+	{3463, "potted_torchflower"},
+	{3463, "pitcher_plant"},
+	{3463, "pitcher_crop"},
+	{3463, "pink_petals"},
+	{3463, "calibrated_sculk_sensor"},
+}
+
+// addedIn returns the first data version with the block, or 0 for the
+// blocks of 1.13 and of other namespaces.
+func addedIn(name string) int {
+	if !strings.HasPrefix(name, "minecraft:") {
+		return 0
+	}
+	name = name[len("minecraft:"):]
+	for _, a := range blockAdditions {
+		p := a.pattern
+		var match bool
+		switch {
+		case len(p) > 2 && p[0] == '*' && p[len(p)-1] == '*':
+			match = strings.Contains(name, p[1:len(p)-1])
+		case p[0] == '*':
+			match = strings.HasSuffix(name, p[1:])
+		case p[len(p)-1] == '*':
+			match = strings.HasPrefix(name, p[:len(p)-1])
+		default:
+			match = name == p
+		}
+		if match {
+			return a.version
+		}
+	}
+	return 0
+}
+
+// A StateVolume is a box of block states stored as indexes into a palette.
+// It is the version independent representation of a build: the names of
+// the states are translated to any Minecraft version with Translate, down
+// to the numeric ids of 1.12 with Schematic.
+type StateVolume struct {
+	Width, Height, Length int
+
+	// DataVersion is the Minecraft version of the state names.
+	DataVersion int
+
+	// Palette lists the block states, such as
+	// "minecraft:oak_stairs[facing=east,half=bottom]". The first one is air.
+	Palette []string
+
+	// States are the indexes into Palette of all blocks, ordered by y, z and x
+	// like Schematic.Blocks.
+	States []int
+
+	// Entities have positions relative to the minimum corner of the volume.
+	Entities []Entity
+}
+
+// NewStateVolume converts a schematic to block states of Minecraft 1.13
+// with LegacyState. It fails if a block id has no state.
+func NewStateVolume(s *Schematic) (v *StateVolume, err os.Error) {
+	v = &StateVolume{
+		Width:       s.Width,
+		Height:      s.Height,
+		Length:      s.Length,
+		DataVersion: DataVersion1_13,
+		Palette:     []string{"minecraft:air"},
+		States:      make([]int, len(s.Blocks)),
+		Entities:    s.Entities,
+	}
+	index := map[Block]int{Block{}: 0}
+	for i := range s.Blocks {
+		b := Block{s.id(i), s.Data[i]}
+		n, ok := index[b]
+		if !ok {
+			state, ok := LegacyState(b)
+			if !ok {
+				return nil, fmt.Errorf("No block state for %d:%d", b.Id, b.Data)
+			}
+			n = v.paletteIndex(state)
+			index[b] = n
+		}
+		v.States[i] = n
+	}
+	return
+}
+
+// paletteIndex returns the index of the state, adding it to the palette if needed.
+func (v *StateVolume) paletteIndex(state string) int {
+	for i, p := range v.Palette {
+		if p == state {
+			return i
+		}
+	}
+	v.Palette = append(v.Palette, state)
+	return len(v.Palette) - 1
+}
+
+// State returns the block state at the specified position.
+// The blocks outside of the volume are air.
+func (v *StateVolume) State(x, y, z int) string {
+	if x < 0 || y < 0 || z < 0 || x >= v.Width || y >= v.Height || z >= v.Length {
+		return "minecraft:air"
+	}
+	return v.Palette[v.States[y*v.Width*v.Length+z*v.Width+x]]
+}
+
+// Translate renames the block states to the data version, which must be
+// 1.13 or later. It fails without changes if a state does not exist in
+// the target version.
+func (v *StateVolume) Translate(version int) os.Error {
+	if version < DataVersion1_13 {
+		return fmt.Errorf("Block states need data version %d or later. Got: %d", DataVersion1_13, version)
+	}
+	palette := make([]string, len(v.Palette))
+	for i, state := range v.Palette {
+		var ok bool
+		if palette[i], ok = TranslateState(state, v.DataVersion, version); !ok {
+			return fmt.Errorf("Block %s does not exist in data version %d", state, version)
+		}
+	}
+	v.Palette, v.DataVersion = palette, version
+	return nil
+}
+
+// Schematic converts the volume to legacy blocks with FromState, after
+// translating the states to Minecraft 1.13. The properties of the states
+// are lost. It fails if a state has no legacy block; see Legacy.
+func (v *StateVolume) Schematic() (s *Schematic, err os.Error) {
+	return v.Legacy(FallbackError)
+}
+
+// Legacy is like Schematic, but the states without a legacy block are
+// handled according to fallback.
+func (v *StateVolume) Legacy(fallback Fallback) (s *Schematic, err os.Error) {
+	blocks := make([]Block, len(v.Palette))
+	for i, state := range v.Palette {
+		if blocks[i], err = LegacyBlock(state, v.DataVersion, fallback); err != nil {
+			return nil, err
+		}
+	}
+	s = NewSchematic(v.Width, v.Height, v.Length)
+	for i, n := range v.States {
+		s.setId(i, blocks[n].Id)
+		s.Data[i] = blocks[n].Data
+	}
+	s.Entities = v.Entities
+	return
+}
+
+// A Fallback selects what happens to the block states which have no
+// equivalent among the numeric ids of Minecraft 1.12.
+type Fallback int
+
+const (
+	FallbackError   Fallback = iota // fail the conversion
+	FallbackAir                     // replace the block with air
+	FallbackClosest                 // use the most similar legacy block, or air if none is similar
+)
+
+// closestStates are the replacements of the blocks added after 1.12 whose
+// names do not resemble an older block.
+var closestStates = map[string]string{
+	"amethyst_block":    "purpur_block",
+	"basalt":            "stone",
+	"blackstone":        "cobblestone",
+	"bubble_column":     "water",
+	"calcite":           "diorite",
+	"cobbled_deepslate": "cobblestone",
+	"copper_block":      "iron_block",
+	"crimson_nylium":    "netherrack",
+	"deepslate":         "stone",
+	"dripstone_block":   "granite",
+	"honey_block":       "slime_block",
+	"kelp":              "water",
+	"kelp_plant":        "water",
+	"moss_block":        "grass_block",
+	"mud":               "dirt",
+	"mud_bricks":        "bricks",
+	"rooted_dirt":       "dirt",
+	"seagrass":          "water",
+	"soul_soil":         "soul_sand",
+	"tall_seagrass":     "water",
+	"tuff":              "andesite",
+	"warped_nylium":     "netherrack",
+}
+
+// LegacyBlock returns the legacy block of a block state of the given data
+// version. The properties of the state are ignored. If the block did not
+// exist in Minecraft 1.12, the result depends on fallback.
+func LegacyBlock(state string, version int, fallback Fallback) (b Block, err os.Error) {
+	name, ok := TranslateState(state, version, DataVersion1_13)
+	if ok {
+		if b, ok = FromState(name); ok {
+			return
+		}
+	} else {
+		// The block was added after 1.13, or its name was reused
+		// for a new block, which is likely similar to the old one.
+		name = state
+	}
+	switch fallback {
+	case FallbackAir:
+		return Block{}, nil
+	case FallbackClosest:
+		b, _ = closestLegacy(name)
+		return b, nil
+	}
+	return Block{}, fmt.Errorf("No legacy block for %s", state)
+}
+
+// closestLegacy guesses the legacy block most similar to a block state of
+// Minecraft 1.13 or later. It looks up closestStates, and then the legacy
+// blocks named like the state with the leading words removed, so that
+// cherry_planks becomes oak_planks and polished_deepslate_slab a stone slab.
+func closestLegacy(state string) (b Block, ok bool) {
+	if i := strings.Index(state, "["); i >= 0 {
+		state = state[:i]
+	}
+	if strings.HasPrefix(state, "minecraft:") {
+		state = state[len("minecraft:"):]
+	} else if strings.Index(state, ":") >= 0 {
+		return Block{}, false
+	}
+	if name, ok := closestStates[state]; ok {
+		return FromState(name)
+	}
+	words := strings.Split(state, "_")
+	for i := 1; i < len(words); i++ {
+		suffix := strings.Join(words[i:], "_")
+		if name, ok := closestStates[suffix]; ok {
+			return FromState(name)
+		}
+		if b, ok = stateBlocks[suffix]; ok {
+			return
+		}
+		// The first legacy block of the same kind, such as oak_stairs
+		// for stairs.
+		for name, c := range stateBlocks {
+			if strings.HasSuffix(name, "_"+suffix) && (!ok || c.Id < b.Id || c.Id == b.Id && c.Data < b.Data) {
+				b, ok = c, true
+			}
+		}
+		if ok {
+			return
+		}
+	}
+	return Block{}, false
+}
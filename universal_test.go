@@ -0,0 +1,142 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestTranslateState(t *testing.T) {
+	tests := []struct {
+		state    string
+		from, to int
+		want     string
+		ok       bool
+	}{
+		{"minecraft:grass_path", DataVersion1_13, DataVersion1_16, "minecraft:grass_path", true},
+		{"minecraft:grass_path", DataVersion1_13, DataVersion1_20, "minecraft:dirt_path", true},
+		{"minecraft:dirt_path", DataVersion1_20, DataVersion1_16, "minecraft:grass_path", true},
+		{"minecraft:sign[rotation=4]", DataVersion1_13, LatestDataVersion, "minecraft:oak_sign[rotation=4]", true},
+		{"minecraft:grass", DataVersion1_13, DataVersion1_20, "minecraft:short_grass", true},
+		{"minecraft:short_grass", DataVersion1_21, DataVersion1_13, "minecraft:grass", true},
+		{"minecraft:stone_slab[type=top]", DataVersion1_13, DataVersion1_16, "minecraft:smooth_stone_slab[type=top]", true},
+		{"minecraft:smooth_stone_slab", DataVersion1_16, DataVersion1_13, "minecraft:stone_slab", true},
+		// Plain stone slabs were added in 1.14.
+		{"minecraft:stone_slab", DataVersion1_16, DataVersion1_13, "", false},
+		{"minecraft:stone_slab", DataVersion1_16, DataVersion1_20, "minecraft:stone_slab", true},
+		// Walls have none, low and tall sides since 1.16.
+		{"minecraft:cobblestone_wall[east=false,north=true,up=true]", DataVersion1_13, DataVersion1_20, "minecraft:cobblestone_wall[east=none,north=low,up=true]", true},
+		{"minecraft:cobblestone_wall[east=tall,north=none,up=false]", DataVersion1_20, DataVersion1_14, "minecraft:cobblestone_wall[east=true,north=false,up=false]", true},
+		// The cauldrons of water are separate blocks since 1.17.
+		{"minecraft:cauldron[level=2]", DataVersion1_13, DataVersion1_20, "minecraft:water_cauldron[level=2]", true},
+		{"minecraft:cauldron[level=0]", DataVersion1_16, DataVersion1_17, "minecraft:cauldron", true},
+		{"minecraft:water_cauldron[level=3]", DataVersion1_21, DataVersion1_16, "minecraft:cauldron[level=3]", true},
+		{"minecraft:cauldron", DataVersion1_21, DataVersion1_13, "minecraft:cauldron[level=0]", true},
+		{"minecraft:lava_cauldron", DataVersion1_21, DataVersion1_16, "", false},
+		// The blocks added after the target version.
+		{"minecraft:barrel[facing=up,open=false]", DataVersion1_16, DataVersion1_13, "", false},
+		{"minecraft:barrel[facing=up,open=false]", DataVersion1_16, DataVersion1_14, "minecraft:barrel[facing=up,open=false]", true},
+		{"minecraft:crimson_planks", DataVersion1_20, DataVersion1_16, "minecraft:crimson_planks", true},
+		{"minecraft:crimson_planks", DataVersion1_20, DataVersion1_14, "", false},
+		{"minecraft:deepslate_tiles", DataVersion1_21, DataVersion1_17, "minecraft:deepslate_tiles", true},
+		{"minecraft:reinforced_deepslate", DataVersion1_21, DataVersion1_17, "", false},
+		{"minecraft:waxed_copper_door[half=lower]", DataVersion1_21, DataVersion1_20, "", false},
+		{"minecraft:cut_copper", DataVersion1_21, DataVersion1_20, "minecraft:cut_copper", true},
+		{"minecraft:cherry_log[axis=y]", DataVersion1_21, DataVersion1_17, "", false},
+		{"minecraft:polished_andesite", DataVersion1_21, DataVersion1_13, "minecraft:polished_andesite", true},
+		{"minecraft:polished_andesite_stairs", DataVersion1_21, DataVersion1_13, "", false},
+		{"mymod:machine", DataVersion1_21, DataVersion1_13, "mymod:machine", true},
+	}
+	for _, tt := range tests {
+		if got, ok := TranslateState(tt.state, tt.from, tt.to); got != tt.want || ok != tt.ok {
+			t.Errorf("TranslateState(%s, %d, %d): want %q, %v, got %q, %v", tt.state, tt.from, tt.to, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestStateVolume(t *testing.T) {
+	s := NewSchematic(3, 1, 1)
+	s.SetBlock(0, 0, 0, Block{208, 0}) // grass path
+	s.SetBlock(1, 0, 0, Block{31, 1})  // tall grass
+	s.SetBlock(2, 0, 0, Block{208, 0})
+	v, err := NewStateVolume(s)
+	if err != nil {
+		t.Fatalf("NewStateVolume: %v", err)
+	}
+	if len(v.Palette) != 3 || v.State(2, 0, 0) != "minecraft:grass_path" || v.State(5, 0, 0) != "minecraft:air" {
+		t.Fatalf("NewStateVolume: palette %v, states %v", v.Palette, v.States)
+	}
+	if err = v.Translate(LatestDataVersion); err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if v.State(0, 0, 0) != "minecraft:dirt_path" || v.State(1, 0, 0) != "minecraft:short_grass" || v.DataVersion != LatestDataVersion {
+		t.Errorf("Translate: got palette %v, version %d", v.Palette, v.DataVersion)
+	}
+	back, err := v.Schematic()
+	if err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	if back.Fingerprint() != s.Fingerprint() {
+		t.Errorf("Schematic: want blocks %v, got %v", s.Blocks, back.Blocks)
+	}
+
+	v.Palette = append(v.Palette, "minecraft:stone_slab")
+	if err = v.Translate(DataVersion1_13); err == nil {
+		t.Errorf("Translate of stone_slab to 1.13: want error, got nil")
+	}
+	if v.DataVersion != LatestDataVersion || v.Palette[1] != "minecraft:dirt_path" {
+		t.Errorf("Failed Translate changed the volume: %v", v.Palette)
+	}
+	if err = v.Translate(DataVersion1_12); err == nil {
+		t.Errorf("Translate to 1.12: want error, got nil")
+	}
+	if _, err = NewStateVolume(&Schematic{Width: 1, Height: 1, Length: 1, Blocks: []byte{253}, Data: []byte{0}}); err == nil {
+		t.Errorf("NewStateVolume with id 253: want error, got nil")
+	}
+}
+
+func TestLegacyBlock(t *testing.T) {
+	tests := []struct {
+		state    string
+		fallback Fallback
+		b        Block
+		ok       bool
+	}{
+		{"minecraft:dirt_path", FallbackError, Block{208, 0}, true},
+		{"minecraft:oak_sign[rotation=2]", FallbackError, Block{63, 0}, true},
+		{"minecraft:deepslate", FallbackError, Block{}, false},
+		{"minecraft:deepslate", FallbackAir, Block{0, 0}, true},
+		{"minecraft:deepslate", FallbackClosest, Block{1, 0}, true},
+		{"minecraft:tuff", FallbackClosest, Block{1, 5}, true},
+		{"minecraft:cherry_planks", FallbackClosest, Block{5, 0}, true},
+		{"minecraft:mangrove_stairs[facing=west]", FallbackClosest, Block{53, 0}, true},
+		{"minecraft:polished_deepslate_slab", FallbackClosest, Block{44, 0}, true},
+		{"minecraft:deepslate_bricks", FallbackClosest, Block{45, 0}, true},
+		{"minecraft:cherry_wall_sign", FallbackClosest, Block{68, 0}, true},
+		// Plain stone slabs were added in 1.14.
+		{"minecraft:stone_slab", FallbackClosest, Block{44, 0}, true},
+		{"minecraft:sculk", FallbackClosest, Block{0, 0}, true},
+		{"mod:machine", FallbackClosest, Block{0, 0}, true},
+	}
+	for _, tt := range tests {
+		b, err := LegacyBlock(tt.state, LatestDataVersion, tt.fallback)
+		if b != tt.b || (err == nil) != tt.ok {
+			t.Errorf("LegacyBlock(%s, %d): want %v, ok %v, got %v, %v", tt.state, tt.fallback, tt.b, tt.ok, b, err)
+		}
+	}
+
+	v := &StateVolume{
+		Width: 2, Height: 1, Length: 1,
+		DataVersion: LatestDataVersion,
+		Palette:     []string{"minecraft:air", "minecraft:cherry_log[axis=y]"},
+		States:      []int{1, 0},
+	}
+	if _, err := v.Schematic(); err == nil {
+		t.Errorf("Schematic with cherry_log: want error, got nil")
+	}
+	s, err := v.Legacy(FallbackClosest)
+	if err != nil {
+		t.Fatalf("Legacy: %v", err)
+	}
+	if s.Block(0, 0, 0) != (Block{17, 0}) || s.Block(1, 0, 0) != (Block{}) {
+		t.Errorf("Legacy: got blocks %v, data %v", s.Blocks, s.Data)
+	}
+}
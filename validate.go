@@ -0,0 +1,351 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// ProblemKind classifies the problems found by Validate.
+type ProblemKind int
+
+const (
+	// SizeMismatch: Blocks or Data do not match Width*Height*Length.
+	SizeMismatch ProblemKind = iota
+	// UnknownBlock: no block has the id in the Materials of the schematic.
+	UnknownBlock
+	// WrongTileEntity: a tile entity is on a block which cannot have it.
+	WrongTileEntity
+	// DuplicateTileEntity: two tile entities are on the same block.
+	DuplicateTileEntity
+	// OutsideTileEntity: a tile entity is outside of the schematic.
+	OutsideTileEntity
+	// OutsideEntity: an entity is outside of the schematic.
+	OutsideEntity
+	// OutsideTileTick: a tile tick is outside of the schematic.
+	OutsideTileTick
+	// DuplicateMapping: the SchematicaMapping tag maps two names to an id.
+	DuplicateMapping
+)
+
+var problemKindNames = []string{
+	SizeMismatch:        "size mismatch",
+	UnknownBlock:        "unknown block",
+	WrongTileEntity:     "wrong tile entity",
+	DuplicateTileEntity: "duplicate tile entity",
+	OutsideTileEntity:   "tile entity outside",
+	OutsideEntity:       "entity outside",
+	OutsideTileTick:     "tile tick outside",
+	DuplicateMapping:    "duplicate mapping",
+}
+
+func (k ProblemKind) String() string {
+	if k < 0 || int(k) >= len(problemKindNames) {
+		return fmt.Sprintf("ProblemKind(%d)", int(k))
+	}
+	return problemKindNames[k]
+}
+
+// A Problem is an inconsistency of a schematic found by Validate.
+type Problem struct {
+	Kind ProblemKind
+
+	// X, Y and Z are the position of the problem, if Pos is true.
+	X, Y, Z int
+	Pos     bool
+
+	// Index is the position in Entities, TileEntities or TileTicks of the
+	// object with the problem, or -1.
+	Index int
+
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Pos {
+		return fmt.Sprintf("%v at %d,%d,%d: %s", p.Kind, p.X, p.Y, p.Z, p.Message)
+	}
+	return fmt.Sprintf("%v: %s", p.Kind, p.Message)
+}
+
+// tileEntityBlocks lists the block ids which may have the tile entity,
+// by the names used before and after Minecraft 1.11.
+var tileEntityBlocks = map[string][]uint16{
+	"chest":             {54, 146},
+	"furnace":           {61, 62},
+	"sign":              {63, 68},
+	"mobspawner":        {52},
+	"mob_spawner":       {52},
+	"trap":              {23},
+	"dispenser":         {23},
+	"dropper":           {158},
+	"music":             {25},
+	"noteblock":         {25},
+	"recordplayer":      {84},
+	"jukebox":           {84},
+	"piston":            {36},
+	"cauldron":          {117},
+	"brewing_stand":     {117},
+	"enchanttable":      {116},
+	"enchanting_table":  {116},
+	"airportal":         {119},
+	"end_portal":        {119},
+	"beacon":            {138},
+	"skull":             {144},
+	"dldetector":        {151, 178},
+	"daylight_detector": {151, 178},
+	"hopper":            {154},
+	"comparator":        {149, 150},
+	"flowerpot":         {140},
+	"flower_pot":        {140},
+	"banner":            {176, 177},
+	"endgateway":        {209},
+	"end_gateway":       {209},
+	"control":           {137, 210, 211},
+	"command_block":     {137, 210, 211},
+	"structure":         {255},
+	"structure_block":   {255},
+	"enderchest":        {130},
+	"ender_chest":       {130},
+	"bed":               {26},
+	"shulker_box":       {219, 220, 221, 222, 223, 224, 225, 226, 227, 228, 229, 230, 231, 232, 233, 234},
+}
+
+// tileEntityIds returns the blocks which may have the tile entity with the
+// id. ok is false for the tile entities unknown to the package.
+func tileEntityIds(id string) (ids []uint16, ok bool) {
+	id = strings.ToLower(strings.Replace(id, " ", "_", -1))
+	if strings.HasPrefix(id, "minecraft:") {
+		id = id[len("minecraft:"):]
+	}
+	ids, ok = tileEntityBlocks[id]
+	return
+}
+
+// Validate checks s for inconsistencies which the readers and writers of
+// schematics accept, but Minecraft or other tools may not. The problems are
+// ordered by kind. If the sizes of Blocks and Data are wrong, the checks
+// involving blocks are skipped. Unknown blocks are only reported for the
+// Alpha materials, once per id.
+func (s *Schematic) Validate() (problems []Problem) {
+	add := func(kind ProblemKind, index int, format string, args ...interface{}) {
+		problems = append(problems, Problem{Kind: kind, Index: index, Message: fmt.Sprintf(format, args...)})
+	}
+	addAt := func(kind ProblemKind, x, y, z, index int, format string, args ...interface{}) {
+		problems = append(problems, Problem{Kind: kind, X: x, Y: y, Z: z, Pos: true, Index: index, Message: fmt.Sprintf(format, args...)})
+	}
+	inside := func(x, y, z int) bool {
+		return x >= 0 && y >= 0 && z >= 0 && x < s.Width && y < s.Height && z < s.Length
+	}
+	sizeOk := true
+	if s.Width < 0 || s.Height < 0 || s.Length < 0 {
+		add(SizeMismatch, -1, "negative size %dx%dx%d", s.Width, s.Height, s.Length)
+		sizeOk = false
+	} else if err := s.checkSize(); err != nil {
+		add(SizeMismatch, -1, "%v", err)
+		sizeOk = false
+	}
+
+	mapping, hasMapping := s.Mapping()
+	if sizeOk && s.Materials == Alpha {
+		count := make([]int, MaxId+1)
+		for i := range s.Blocks {
+			count[s.id(i)]++
+		}
+		known := make(map[uint16]bool)
+		for _, id := range mapping {
+			known[id] = true
+		}
+		for id, n := range count {
+			if n == 0 || id < len(registryNames) && registryNames[id] != "" || known[uint16(id)] {
+				continue
+			}
+			i := 0
+			for s.id(i) != uint16(id) {
+				i++
+			}
+			x, y, z := s.coords(i)
+			addAt(UnknownBlock, x, y, z, -1, "id %d is used by %d blocks", id, n)
+		}
+	}
+
+	seen := make(map[[3]int]int)
+	for i, t := range s.TileEntities {
+		if !inside(t.X, t.Y, t.Z) {
+			addAt(OutsideTileEntity, t.X, t.Y, t.Z, i, "%s", t.Id)
+			continue
+		}
+		pos := [3]int{t.X, t.Y, t.Z}
+		if j, ok := seen[pos]; ok {
+			addAt(DuplicateTileEntity, t.X, t.Y, t.Z, i, "%s and %s", s.TileEntities[j].Id, t.Id)
+		}
+		seen[pos] = i
+		if !sizeOk {
+			continue
+		}
+		ids, ok := tileEntityIds(t.Id)
+		if !ok || hasMapping {
+			// Mods may remap the blocks of the known tile entities.
+			continue
+		}
+		b := s.Block(t.X, t.Y, t.Z)
+		found := false
+		for _, id := range ids {
+			found = found || b.Id == id
+		}
+		if !found {
+			addAt(WrongTileEntity, t.X, t.Y, t.Z, i, "%s on block %d:%d", t.Id, b.Id, b.Data)
+		}
+	}
+
+	for i, e := range s.Entities {
+		if e.NBT == nil {
+			continue
+		}
+		pos, ok := e.NBT.Get("Pos").(*nbt.List)
+		if !ok || pos.Len() != 3 || pos.ElemType != nbt.TagDouble {
+			continue
+		}
+		x, y, z := float64(pos.Tags[0].(nbt.Double)), float64(pos.Tags[1].(nbt.Double)), float64(pos.Tags[2].(nbt.Double))
+		if x < 0 || y < 0 || z < 0 || x > float64(s.Width) || y > float64(s.Height) || z > float64(s.Length) {
+			add(OutsideEntity, i, "%s at %g,%g,%g", e.Id, x, y, z)
+		}
+	}
+
+	for i, t := range s.TileTicks {
+		if !inside(t.X, t.Y, t.Z) {
+			addAt(OutsideTileTick, t.X, t.Y, t.Z, i, "delay %d", t.Delay)
+		}
+	}
+
+	if hasMapping {
+		names := make(map[uint16][]string)
+		for name, id := range mapping {
+			names[id] = append(names[id], name)
+		}
+		var dups []string
+		for id, list := range names {
+			if len(list) > 1 {
+				sort.Strings(list)
+				dups = append(dups, fmt.Sprintf("id %d: %s", id, strings.Join(list, ", ")))
+			}
+		}
+		sort.Strings(dups)
+		for _, d := range dups {
+			add(DuplicateMapping, -1, "%s", d)
+		}
+	}
+	sort.Stable(byKind(problems))
+	return
+}
+
+type byKind []Problem
+
+func (p byKind) Len() int           { return len(p) }
+func (p byKind) Less(i, j int) bool { return p[i].Kind < p[j].Kind }
+func (p byKind) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// Repair fixes the problems reported by Validate which do not need a
+// decision about the contents of the schematic and returns a description
+// of every fix:
+//
+//	Blocks and Data are truncated or padded with air to the dimensions;
+//	tile entities outside of the schematic, on the wrong blocks or sharing
+//	a block with a later one are removed;
+//	entities outside of the schematic are moved to its nearest side;
+//	tile ticks outside of the schematic are removed.
+//
+// Unknown blocks, duplicate mappings and negative dimensions are left.
+func (s *Schematic) Repair() (log []string) {
+	logf := func(format string, args ...interface{}) {
+		log = append(log, fmt.Sprintf(format, args...))
+	}
+	if s.Width < 0 || s.Height < 0 || s.Length < 0 {
+		return
+	}
+	n := s.Width * s.Height * s.Length
+	fit := func(name string, a []byte) []byte {
+		switch {
+		case len(a) > n:
+			logf("truncated %s from %d to %d bytes", name, len(a), n)
+			return a[:n]
+		case len(a) < n:
+			logf("padded %s from %d to %d bytes", name, len(a), n)
+			return append(a, make([]byte, n-len(a))...)
+		}
+		return a
+	}
+	s.Blocks = fit("Blocks", s.Blocks)
+	s.Data = fit("Data", s.Data)
+	if s.add != nil {
+		s.add = fit("AddBlocks", s.add)
+	}
+
+	dropTiles := make(map[int]bool)
+	dropTicks := make(map[int]bool)
+	for _, p := range s.Validate() {
+		switch p.Kind {
+		case WrongTileEntity, DuplicateTileEntity, OutsideTileEntity:
+			// A duplicate is reported for the later tile entity; keep it
+			// and drop the earlier one, as Minecraft does.
+			i := p.Index
+			if p.Kind == DuplicateTileEntity {
+				for j := 0; j < p.Index; j++ {
+					if t := s.TileEntities[j]; t.X == p.X && t.Y == p.Y && t.Z == p.Z && !dropTiles[j] {
+						i = j
+						break
+					}
+				}
+			}
+			if !dropTiles[i] {
+				t := s.TileEntities[i]
+				dropTiles[i] = true
+				logf("removed %v: %s at %d,%d,%d", p.Kind, t.Id, t.X, t.Y, t.Z)
+			}
+		case OutsideEntity:
+			e := &s.Entities[p.Index]
+			c := nbt.Clone(e.NBT).(*nbt.Compound)
+			pos := c.Get("Pos").(*nbt.List)
+			max := []float64{float64(s.Width), float64(s.Height), float64(s.Length)}
+			for k, tag := range pos.Tags {
+				v := float64(tag.(nbt.Double))
+				if v < 0 {
+					v = 0
+				} else if v > max[k] {
+					v = max[k]
+				}
+				pos.Tags[k] = nbt.Double(v)
+			}
+			e.NBT = c
+			logf("moved %v: %s to %g,%g,%g", p.Kind, e.Id, pos.Tags[0], pos.Tags[1], pos.Tags[2])
+		case OutsideTileTick:
+			dropTicks[p.Index] = true
+			logf("removed %v: %d,%d,%d", p.Kind, p.X, p.Y, p.Z)
+		}
+	}
+	if len(dropTiles) > 0 {
+		var tiles []TileEntity
+		for i, t := range s.TileEntities {
+			if !dropTiles[i] {
+				tiles = append(tiles, t)
+			}
+		}
+		s.TileEntities = tiles
+		s.indexTileEntities()
+	}
+	if len(dropTicks) > 0 {
+		var ticks []TileTick
+		for i, t := range s.TileTicks {
+			if !dropTicks[i] {
+				ticks = append(ticks, t)
+			}
+		}
+		s.TileTicks = ticks
+	}
+	return
+}
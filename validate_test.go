@@ -0,0 +1,101 @@
+package schematic
+
+import (
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestValidate(t *testing.T) {
+	s := newChestVolume()
+	if problems := s.Validate(); len(problems) != 0 {
+		t.Fatalf("Validate of a valid schematic: got %v", problems)
+	}
+
+	s.SetBlock(1, 0, 1, Block{253, 0})
+	s.SetBlock(0, 1, 1, Block{253, 0})
+	s.TileEntities = append(s.TileEntities,
+		TileEntity{X: 0, Y: 0, Z: 0, Id: "minecraft:sign"},
+		TileEntity{X: 2, Y: 1, Z: 1, Id: "Chest"},
+		TileEntity{X: 5, Y: 0, Z: 0, Id: "Furnace"})
+	pos := nbt.NewList(nbt.TagDouble, nbt.Double(1), nbt.Double(-0.5), nbt.Double(1))
+	s.Entities = append(s.Entities, Entity{Id: "Cow", NBT: nbt.NewCompound().Set("Pos", pos)})
+	s.TileTicks = append(s.TileTicks, TileTick{X: 0, Y: 2, Z: 0})
+	want := []struct {
+		kind    ProblemKind
+		x, y, z int
+		index   int
+	}{
+		{UnknownBlock, 1, 0, 1, -1},
+		{WrongTileEntity, 0, 0, 0, 1},
+		{DuplicateTileEntity, 2, 1, 1, 2},
+		{OutsideTileEntity, 5, 0, 0, 3},
+		{OutsideEntity, 0, 0, 0, 1},
+		{OutsideTileTick, 0, 2, 0, 1},
+	}
+	problems := s.Validate()
+	if len(problems) != len(want) {
+		t.Fatalf("Validate: want %d problems, got %v", len(want), problems)
+	}
+	for i, w := range want {
+		p := problems[i]
+		if p.Kind != w.kind || p.Index != w.index || p.Pos && (p.X != w.x || p.Y != w.y || p.Z != w.z) {
+			t.Errorf("Problem %d: want %v at %d,%d,%d (index %d), got %v (index %d)", i, w.kind, w.x, w.y, w.z, w.index, p, p.Index)
+		}
+	}
+	if got := problems[0].String(); got != "unknown block at 1,0,1: id 253 is used by 2 blocks" {
+		t.Errorf("String: got %q", got)
+	}
+
+	s = newTestVolume()
+	s.setMapping(IdMapping{"minecraft:stone": 1, "mod:stone": 1, "minecraft:wool": 35})
+	s.Data = s.Data[1:]
+	problems = s.Validate()
+	if len(problems) != 2 || problems[0].Kind != SizeMismatch || problems[1].Kind != DuplicateMapping {
+		t.Fatalf("Validate: want size and mapping problems, got %v", problems)
+	}
+	if got := problems[1].Message; got != "id 1: minecraft:stone, mod:stone" {
+		t.Errorf("Duplicate mapping: got %q", got)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	s := newChestVolume()
+	s.Blocks = append(s.Blocks, 1, 1)
+	s.Data = s.Data[:10]
+	s.TileEntities = append(s.TileEntities,
+		TileEntity{X: 0, Y: 0, Z: 0, Id: "Sign"},
+		TileEntity{X: 2, Y: 1, Z: 1, Id: "Chest", NBT: nbt.NewCompound().Set("Lock", nbt.String("key"))},
+		TileEntity{X: 5, Y: 0, Z: 0, Id: "Furnace"})
+	pos := nbt.NewList(nbt.TagDouble, nbt.Double(1), nbt.Double(-0.5), nbt.Double(7))
+	s.Entities = append(s.Entities, Entity{Id: "Cow", NBT: nbt.NewCompound().Set("Pos", pos)})
+	s.TileTicks = append(s.TileTicks, TileTick{X: 0, Y: 2, Z: 0})
+
+	log := s.Repair()
+	want := []string{
+		"truncated Blocks from 14 to 12 bytes",
+		"padded Data from 10 to 12 bytes",
+		"removed wrong tile entity: Sign at 0,0,0",
+		"removed duplicate tile entity: Chest at 2,1,1",
+		"removed tile entity outside: Furnace at 5,0,0",
+		"moved entity outside: Cow to 1,0,2",
+		"removed tile tick outside: 0,2,0",
+	}
+	if len(log) != len(want) {
+		t.Fatalf("Repair: want %d fixes, got %q", len(want), log)
+	}
+	for i, w := range want {
+		if log[i] != w {
+			t.Errorf("Repair fix %d: want %q, got %q", i, w, log[i])
+		}
+	}
+	if problems := s.Validate(); len(problems) != 0 {
+		t.Errorf("Validate after Repair: got %v", problems)
+	}
+	if te := s.TileEntityAt(2, 1, 1); te == nil || te.NBT.Get("Lock") == nil {
+		t.Errorf("Repair did not keep the last chest: %+v", s.TileEntities)
+	}
+	if log = s.Repair(); len(log) != 0 {
+		t.Errorf("Repair of a valid schematic: got %q", log)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+)
+
+// A Volume is a box of blocks which can be read and changed, such as a
+// Schematic or a BoxView of a part of one. Positions start at 0 in every
+// axis; Block returns air outside of the volume and SetBlock panics.
+type Volume interface {
+	XLen() int
+	YLen() int
+	ZLen() int
+	Block(x, y, z int) Block
+	SetBlock(x, y, z int, b Block)
+}
+
+// A BoxView is a window into a box of a schematic. It reads and changes the
+// blocks of the schematic directly, so algorithms written for a Volume can
+// work on a part of a large schematic without copying it. Positions are
+// relative to the minimum corner of the box. Entities, tile entities and
+// tile ticks are not part of the view, but SetBlock removes the tile
+// entity of a replaced block like Schematic.SetBlock.
+type BoxView struct {
+	s   *Schematic
+	box Box
+}
+
+// View returns a view of the blocks of s in the box, which must be inside
+// of s and not empty.
+func (s *Schematic) View(b Box) (*BoxView, os.Error) {
+	if b.Empty() || b.MinX < 0 || b.MinY < 0 || b.MinZ < 0 || b.MaxX > s.Width || b.MaxY > s.Height || b.MaxZ > s.Length {
+		return nil, fmt.Errorf("Invalid view box %v for size %dx%dx%d", b, s.Width, s.Height, s.Length)
+	}
+	return &BoxView{s, b}, nil
+}
+
+// Box returns the box of the schematic seen by v.
+func (v *BoxView) Box() Box {
+	return v.box
+}
+
+// XLen is the number of blocks by X axis.
+func (v *BoxView) XLen() int {
+	return v.box.MaxX - v.box.MinX
+}
+
+// YLen is the number of blocks by Y axis.
+func (v *BoxView) YLen() int {
+	return v.box.MaxY - v.box.MinY
+}
+
+// ZLen is the number of blocks by Z axis.
+func (v *BoxView) ZLen() int {
+	return v.box.MaxZ - v.box.MinZ
+}
+
+// Inside reports whether the position is inside the view.
+func (v *BoxView) Inside(x, y, z int) bool {
+	return x >= 0 && y >= 0 && z >= 0 && x < v.XLen() && y < v.YLen() && z < v.ZLen()
+}
+
+// Block returns the block at the specified position.
+// The blocks outside of the view are air, even if they are in the schematic.
+func (v *BoxView) Block(x, y, z int) Block {
+	if !v.Inside(x, y, z) {
+		return Block{}
+	}
+	return v.s.Block(x+v.box.MinX, y+v.box.MinY, z+v.box.MinZ)
+}
+
+// SetBlock sets the block at the specified position.
+// It panics if the position is outside of the view.
+func (v *BoxView) SetBlock(x, y, z int, b Block) {
+	if !v.Inside(x, y, z) {
+		panic(fmt.Sprintf("schematic: BoxView.SetBlock(%d, %d, %d) out of range", x, y, z))
+	}
+	v.s.SetBlock(x+v.box.MinX, y+v.box.MinY, z+v.box.MinZ, b)
+}
+
+// View returns a view of a box of v, relative to v.
+func (v *BoxView) View(b Box) (*BoxView, os.Error) {
+	if b.Empty() || b.MinX < 0 || b.MinY < 0 || b.MinZ < 0 || b.MaxX > v.XLen() || b.MaxY > v.YLen() || b.MaxZ > v.ZLen() {
+		return nil, fmt.Errorf("Invalid view box %v for size %dx%dx%d", b, v.XLen(), v.YLen(), v.ZLen())
+	}
+	o := v.box
+	return &BoxView{v.s, Box{b.MinX + o.MinX, b.MinY + o.MinY, b.MinZ + o.MinZ, b.MaxX + o.MinX, b.MaxY + o.MinY, b.MaxZ + o.MinZ}}, nil
+}
+
+// Schematic returns a copy of the part of the schematic seen by v, like
+// Schematic.Crop.
+func (v *BoxView) Schematic() *Schematic {
+	c, _ := v.s.Crop(v.box)
+	return c
+}
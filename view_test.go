@@ -0,0 +1,66 @@
+package schematic
+
+import (
+	"testing"
+)
+
+// fillVolume sets every block of v, as an algorithm written for a Volume would.
+func fillVolume(v Volume, b Block) {
+	for y := 0; y < v.YLen(); y++ {
+		for z := 0; z < v.ZLen(); z++ {
+			for x := 0; x < v.XLen(); x++ {
+				v.SetBlock(x, y, z, b)
+			}
+		}
+	}
+}
+
+func TestView(t *testing.T) {
+	s := newChestVolume()
+	if _, err := s.View(Box{0, 0, 0, 4, 1, 1}); err == nil {
+		t.Errorf("View of a box larger than the schematic: want error, got nil")
+	}
+	v, err := s.View(Box{1, 0, 0, 3, 2, 2})
+	if err != nil {
+		t.Fatalf("View: %v", err)
+	}
+	if v.XLen() != 2 || v.YLen() != 2 || v.ZLen() != 2 {
+		t.Errorf("View size: got %dx%dx%d", v.XLen(), v.YLen(), v.ZLen())
+	}
+	if b := v.Block(1, 0, 1); b != (Block{35, 14}) {
+		t.Errorf("Block(1, 0, 1): want 35:14, got %v", b)
+	}
+	if b := v.Block(-1, 0, 0); b != (Block{}) {
+		t.Errorf("Block outside of the view: want air, got %v", b)
+	}
+
+	top, err := v.View(Box{0, 1, 0, 2, 2, 2})
+	if err != nil {
+		t.Fatalf("View of a view: %v", err)
+	}
+	if top.Box() != (Box{1, 1, 0, 3, 2, 2}) {
+		t.Errorf("Box: got %v", top.Box())
+	}
+	fillVolume(top, Block{20, 0})
+	for x := 0; x < 3; x++ {
+		want := Block{20, 0}
+		if x == 0 {
+			want = Block{}
+		}
+		if b := s.Block(x, 1, 1); b != want {
+			t.Errorf("Block(%d, 1, 1) after filling the view: want %v, got %v", x, want, b)
+		}
+	}
+	if s.TileEntityAt(2, 1, 1) != nil {
+		t.Errorf("The chest replaced through the view kept its tile entity")
+	}
+	if c := top.Schematic(); c.Width != 2 || c.Block(1, 0, 1) != (Block{20, 0}) {
+		t.Errorf("Schematic: got %+v", c)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SetBlock outside of the view did not panic")
+		}
+	}()
+	top.SetBlock(0, 1, 0, Block{1, 0})
+}
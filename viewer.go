@@ -0,0 +1,136 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"json"
+	"os"
+)
+
+// viewerChunk is the edge of the cubes of blocks written by WriteViewerJSON.
+const viewerChunk = 16
+
+// viewerVersion is the version of the documents written by WriteViewerJSON.
+const viewerVersion = 1
+
+// ViewerBlock is an entry of the palette of a viewer document.
+type ViewerBlock struct {
+	Id    uint16 `json:"id"`
+	Data  byte   `json:"data"`
+	Name  string `json:"name,omitempty"` // the block state name returned by LegacyState
+	Color string `json:"color"`          // the color of BlockColor as #rrggbbaa
+}
+
+// ViewerChunk is a cube of blocks of a viewer document.
+type ViewerChunk struct {
+	X      int    `json:"x"` // the minimum corner in blocks
+	Y      int    `json:"y"`
+	Z      int    `json:"z"`
+	Size   [3]int `json:"size"`   // the width, the height and the length, clipped to the schematic
+	Blocks string `json:"blocks"` // the base64 encoded palette indexes
+}
+
+// ViewerDocument is the JSON document written by WriteViewerJSON.
+type ViewerDocument struct {
+	Version   int            `json:"version"`
+	Size      [3]int         `json:"size"`
+	Offset    [3]int         `json:"offset"` // the WorldEdit offset
+	Materials string         `json:"materials"`
+	Metadata  Metadata       `json:"metadata"`
+	IndexType string         `json:"index_type"` // "uint8" or "uint16"
+	Palette   []ViewerBlock  `json:"palette"`
+	Chunks    []*ViewerChunk `json:"chunks"`
+}
+
+// WriteViewerJSON writes s as a JSON document for the viewers running in
+// a browser, such as those built on three.js, which then need not parse
+// NBT. Blocks are listed once in the palette, with their names and
+// colors, and air is the first entry. The blocks are split into cubes of
+// 16, and those of air are left out. The blocks of a cube are the base64
+// encoded indexes into the palette with x changing fastest, then z, then
+// y, like in a schematic. They are single bytes for up to 256 entries
+// and little-endian pairs of bytes otherwise, as told by index_type, so
+// that they can be wrapped by a Uint8Array or a Uint16Array. Entities,
+// tile entities and tile ticks are not written.
+func WriteViewerJSON(w io.Writer, s *Schematic) os.Error {
+	doc, err := NewViewerDocument(s)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// NewViewerDocument returns the document written by WriteViewerJSON.
+func NewViewerDocument(s *Schematic) (doc *ViewerDocument, err os.Error) {
+	if err = s.checkSize(); err != nil {
+		return
+	}
+	doc = &ViewerDocument{
+		Version:   viewerVersion,
+		Size:      [3]int{s.Width, s.Height, s.Length},
+		Offset:    [3]int{s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ},
+		Materials: s.Materials.String(),
+		Metadata:  s.Metadata(),
+		IndexType: "uint8",
+	}
+	index := make(map[Block]int)
+	paletteIndex := func(b Block) int {
+		i, ok := index[b]
+		if !ok {
+			i = len(doc.Palette)
+			index[b] = i
+			name, _ := LegacyState(b)
+			c := BlockColor(b.Id, b.Data)
+			doc.Palette = append(doc.Palette, ViewerBlock{b.Id, b.Data, name,
+				fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)})
+		}
+		return i
+	}
+	paletteIndex(Block{})
+	for i := range s.Blocks {
+		paletteIndex(Block{s.id(i), s.Data[i]})
+	}
+	wide := len(doc.Palette) > 256
+	if wide {
+		doc.IndexType = "uint16"
+	}
+	for cy := 0; cy < s.Height; cy += viewerChunk {
+		for cz := 0; cz < s.Length; cz += viewerChunk {
+			for cx := 0; cx < s.Width; cx += viewerChunk {
+				c := &ViewerChunk{X: cx, Y: cy, Z: cz, Size: [3]int{
+					imin(viewerChunk, s.Width-cx), imin(viewerChunk, s.Height-cy), imin(viewerChunk, s.Length-cz)}}
+				var idx []byte
+				air := true
+				for y := cy; y < cy+c.Size[1]; y++ {
+					for z := cz; z < cz+c.Size[2]; z++ {
+						for x := cx; x < cx+c.Size[0]; x++ {
+							k := index[s.Block(x, y, z)]
+							air = air && k == 0
+							idx = append(idx, byte(k))
+							if wide {
+								idx = append(idx, byte(k>>8))
+							}
+						}
+					}
+				}
+				if air {
+					continue
+				}
+				enc := make([]byte, base64.StdEncoding.EncodedLen(len(idx)))
+				base64.StdEncoding.Encode(enc, idx)
+				c.Blocks = string(enc)
+				doc.Chunks = append(doc.Chunks, c)
+			}
+		}
+	}
+	return
+}
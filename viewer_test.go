@@ -0,0 +1,82 @@
+package schematic
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"json"
+	"testing"
+)
+
+func TestWriteViewerJSON(t *testing.T) {
+	s := NewSchematic(20, 2, 3)
+	s.SetBlock(0, 0, 0, Block{1, 0})
+	s.SetBlock(1, 1, 2, Block{35, 14})
+	s.SetMetadata(Metadata{Name: "Tower"})
+	var buf bytes.Buffer
+	if err := WriteViewerJSON(&buf, s); err != nil {
+		t.Fatalf("WriteViewerJSON: %v", err)
+	}
+	var doc ViewerDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if doc.Version != 1 || doc.Size != [3]int{20, 2, 3} || doc.IndexType != "uint8" || doc.Metadata.Name != "Tower" {
+		t.Errorf("Document: got %+v", doc)
+	}
+	want := []ViewerBlock{
+		{0, 0, "minecraft:air", "#00000000"},
+		{1, 0, "minecraft:stone", ""},
+		{35, 14, "minecraft:red_wool", ""},
+	}
+	if len(doc.Palette) != len(want) {
+		t.Fatalf("Palette: want %v, got %v", want, doc.Palette)
+	}
+	for i, w := range want {
+		p := doc.Palette[i]
+		if w.Color == "" {
+			c := BlockColor(w.Id, w.Data)
+			w.Color = fmt.Sprintf("#%02x%02x%02xff", c.R, c.G, c.B)
+		}
+		if p != w {
+			t.Errorf("Palette[%d]: want %+v, got %+v", i, w, p)
+		}
+	}
+	// The second cube of the 16 blocks along x is air.
+	if len(doc.Chunks) != 1 {
+		t.Fatalf("Chunks: want 1, got %d", len(doc.Chunks))
+	}
+	c := doc.Chunks[0]
+	if c.X != 0 || c.Y != 0 || c.Z != 0 || c.Size != [3]int{16, 2, 3} {
+		t.Errorf("Chunk: got %+v", c)
+	}
+	idx := make([]byte, base64.StdEncoding.DecodedLen(len(c.Blocks)))
+	n, err := base64.StdEncoding.Decode(idx, []byte(c.Blocks))
+	if err != nil || n != 16*2*3 {
+		t.Fatalf("Decode: %v, %d bytes", err, n)
+	}
+	if idx[0] != 1 || idx[16*3+2*16+1] != 2 || idx[1] != 0 {
+		t.Errorf("Blocks: got %v", idx[:n])
+	}
+}
+
+func TestViewerWideIndexes(t *testing.T) {
+	s := NewSchematic(300, 1, 1)
+	for x := 0; x < 300; x++ {
+		s.SetBlock(x, 0, 0, Block{uint16(x + 1), 0})
+	}
+	doc, err := NewViewerDocument(s)
+	if err != nil {
+		t.Fatalf("NewViewerDocument: %v", err)
+	}
+	if doc.IndexType != "uint16" || len(doc.Palette) != 301 || len(doc.Chunks) != 19 {
+		t.Fatalf("Document: got %s, %d entries, %d chunks", doc.IndexType, len(doc.Palette), len(doc.Chunks))
+	}
+	last := doc.Chunks[18]
+	idx := make([]byte, base64.StdEncoding.DecodedLen(len(last.Blocks)))
+	n, _ := base64.StdEncoding.Decode(idx, []byte(last.Blocks))
+	// The chunk holds the blocks 288 to 299, at the palette indexes 289 to 300.
+	if n != 2*12 || int(idx[22])|int(idx[23])<<8 != 300 {
+		t.Errorf("Wide blocks: got %v", idx[:n])
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"strings"
+)
+
+// A POIKind is a kind of the points of interest of the villagers.
+type POIKind int
+
+const (
+	BedPOI POIKind = iota
+	WorkstationPOI
+	BellPOI
+)
+
+// A POI is a point of interest of the villagers: a bed, a workstation or
+// a bell.
+type POI struct {
+	Pos        BlockPos
+	Kind       POIKind
+	Profession string // the profession given by a workstation, such as "librarian"
+}
+
+// workstations are the professions given by the workstations of
+// Minecraft 1.14 and later.
+var workstations = map[string]string{
+	"blast_furnace":        "armorer",
+	"smoker":               "butcher",
+	"cartography_table":    "cartographer",
+	"brewing_stand":        "cleric",
+	"composter":            "farmer",
+	"barrel":               "fisherman",
+	"fletching_table":      "fletcher",
+	"cauldron":             "leatherworker",
+	"water_cauldron":       "leatherworker",
+	"lava_cauldron":        "leatherworker",
+	"powder_snow_cauldron": "leatherworker",
+	"lectern":              "librarian",
+	"stonecutter":          "mason",
+	"loom":                 "shepherd",
+	"smithing_table":       "toolsmith",
+	"grindstone":           "weaponsmith",
+}
+
+// VillageOptions control Village. A nil *VillageOptions selects the
+// defaults.
+type VillageOptions struct {
+	// Range is the longest walk in blocks from a bell to a bed or a
+	// workstation linked to it. Default: 48, the distance at which the
+	// villagers look for points of interest.
+	Range int
+}
+
+// A POILink is the walk from a bed or a workstation to the nearest bell.
+type POILink struct {
+	POI      POI
+	Bell     BlockPos
+	Distance int // the number of steps, or -1 if no bell is within Range
+}
+
+// A VillageReport is the outcome of Village.
+type VillageReport struct {
+	POIs         []POI          // in the order of the blocks
+	Beds         int            // the number of the beds
+	Workstations int            // the number of the workstations
+	Bells        int            // the number of the bells
+	Professions  map[string]int // the number of the workstations by profession
+	Links        []POILink      // a link for every bed and workstation
+}
+
+// Village finds the beds, the workstations and the bells of a village or
+// of a villager based farm and links every bed and workstation to the
+// nearest bell by walking. A bed is counted by its head; the beds without
+// the part property, such as those converted from legacy blocks, are
+// counted by the block. The villagers walk on Solid blocks through two
+// blocks of free space, that is air or blocks which are not Solid, step
+// up by one block and drop by up to three; they reach a point of interest
+// from the free blocks next to it. Fences and closed trapdoors are no
+// obstacles, and the block states unknown to Minecraft 1.12 other than
+// air are Solid.
+func (v *StateVolume) Village(opt *VillageOptions) *VillageReport {
+	o := VillageOptions{Range: 48}
+	if opt != nil && opt.Range > 0 {
+		o.Range = opt.Range
+	}
+	r := &VillageReport{Professions: make(map[string]int)}
+	var bells []BlockPos
+	for i, n := range v.States {
+		poi, ok := statePOI(v.Palette[n])
+		if !ok {
+			continue
+		}
+		layer := v.Width * v.Length
+		poi.Pos = BlockPos{i % v.Width, i / layer, i % layer / v.Width}
+		r.POIs = append(r.POIs, poi)
+		switch poi.Kind {
+		case BedPOI:
+			r.Beds++
+		case WorkstationPOI:
+			r.Workstations++
+			r.Professions[poi.Profession]++
+		case BellPOI:
+			r.Bells++
+			bells = append(bells, poi.Pos)
+		}
+	}
+	free := make([]bool, len(v.Palette))
+	for n, state := range v.Palette {
+		free[n] = freeState(state)
+	}
+	w := &walker{v: v, free: free}
+	dists := make([]map[BlockPos]int, len(bells))
+	for k, bell := range bells {
+		dists[k] = w.walk(bell, o.Range)
+	}
+	for _, poi := range r.POIs {
+		if poi.Kind == BellPOI {
+			continue
+		}
+		link := POILink{POI: poi, Distance: -1}
+		for k, bell := range bells {
+			for _, p := range w.around(poi.Pos) {
+				if d, ok := dists[k][p]; ok && (link.Distance < 0 || d < link.Distance) {
+					link.Bell, link.Distance = bell, d
+				}
+			}
+		}
+		r.Links = append(r.Links, link)
+	}
+	return r
+}
+
+// statePOI returns the point of interest of the block state without its
+// position. ok is false if the block is none.
+func statePOI(state string) (poi POI, ok bool) {
+	name, props, ok := splitState(state)
+	if !ok {
+		return POI{}, false
+	}
+	if strings.HasPrefix(name, "minecraft:") {
+		name = name[len("minecraft:"):]
+	} else if strings.Index(name, ":") >= 0 {
+		return POI{}, false
+	}
+	switch {
+	case name == "bell":
+		return POI{Kind: BellPOI}, true
+	case strings.HasSuffix(name, "_bed"):
+		return POI{Kind: BedPOI}, props["part"] != "foot"
+	}
+	if p, ok := workstations[name]; ok {
+		return POI{Kind: WorkstationPOI, Profession: p}, true
+	}
+	return POI{}, false
+}
+
+// freeState reports whether the villagers pass through the block state.
+func freeState(state string) bool {
+	if i := strings.Index(state, "["); i >= 0 {
+		state = state[:i]
+	}
+	switch state {
+	case "minecraft:air", "minecraft:cave_air", "minecraft:void_air":
+		return true
+	}
+	b, ok := FromState(state)
+	return ok && !b.Is(Solid)
+}
+
+// A walker finds the walks of the villagers in a StateVolume.
+type walker struct {
+	v    *StateVolume
+	free []bool // by palette index
+}
+
+func (w *walker) isFree(x, y, z int) bool {
+	v := w.v
+	if x < 0 || y < 0 || z < 0 || x >= v.Width || y >= v.Height || z >= v.Length {
+		// The space around the volume is open, but has no floor.
+		return true
+	}
+	return w.free[v.States[(y*v.Length+z)*v.Width+x]]
+}
+
+// stands reports whether a villager stands with its feet at the position.
+func (w *walker) stands(p BlockPos) bool {
+	if p.X < 0 || p.Y < 1 || p.Z < 0 || p.X >= w.v.Width || p.Y >= w.v.Height || p.Z >= w.v.Length {
+		return false
+	}
+	return w.isFree(p.X, p.Y, p.Z) && w.isFree(p.X, p.Y+1, p.Z) && !w.isFree(p.X, p.Y-1, p.Z)
+}
+
+// around returns the positions from which a villager reaches the block
+// at p: those next to it sideways, with the feet one block below it to one
+// block above it.
+func (w *walker) around(p BlockPos) []BlockPos {
+	var res []BlockPos
+	for _, d := range []Direction{East, South, West, North} {
+		q := p.Step(d)
+		for dy := -1; dy <= 1; dy++ {
+			if s := (BlockPos{q.X, q.Y + dy, q.Z}); w.stands(s) {
+				res = append(res, s)
+			}
+		}
+	}
+	if s := p.Step(Up); w.stands(s) {
+		res = append(res, s)
+	}
+	return res
+}
+
+// walk returns the number of steps from the positions around the block
+// at start to the positions reached within max steps.
+func (w *walker) walk(start BlockPos, max int) map[BlockPos]int {
+	dist := make(map[BlockPos]int)
+	queue := w.around(start)
+	for _, p := range queue {
+		dist[p] = 0
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		d := dist[p]
+		if d >= max {
+			continue
+		}
+		for _, dir := range []Direction{East, South, West, North} {
+			q, ok := w.step(p, dir)
+			if _, seen := dist[q]; ok && !seen {
+				dist[q] = d + 1
+				queue = append(queue, q)
+			}
+		}
+	}
+	return dist
+}
+
+// step returns where a villager standing at p gets by walking in the
+// direction: the next block, one up or up to three down.
+func (w *walker) step(p BlockPos, dir Direction) (BlockPos, bool) {
+	q := p.Step(dir)
+	if w.stands(q) {
+		return q, true
+	}
+	if !w.isFree(q.X, q.Y+1, q.Z) {
+		return BlockPos{}, false
+	}
+	if up := q.Step(Up); w.isFree(p.X, p.Y+2, p.Z) && w.stands(up) {
+		return up, true
+	}
+	for h := 1; h <= 3; h++ {
+		if !w.isFree(q.X, q.Y-h+1, q.Z) {
+			break
+		}
+		if down := (BlockPos{q.X, q.Y - h, q.Z}); w.stands(down) {
+			return down, true
+		}
+	}
+	return BlockPos{}, false
+}
@@ -0,0 +1,49 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func TestVillage(t *testing.T) {
+	// A corridor with a bell at one end, a lectern at the other, a bed
+	// next to the bell and a stone block to step over.
+	v := &StateVolume{
+		Width:   12,
+		Height:  4,
+		Length:  1,
+		Palette: []string{"minecraft:air", "minecraft:stone", "minecraft:bell[attachment=floor,facing=north]", "minecraft:lectern[facing=west]", "minecraft:red_bed[facing=east,part=head]", "minecraft:red_bed[facing=east,part=foot]"},
+		States:  make([]int, 12*4),
+	}
+	for x := 0; x < 12; x++ {
+		v.States[x] = 1
+	}
+	v.States[12+0] = 2
+	v.States[12+11] = 3
+	v.States[12+3] = 4
+	v.States[12+4] = 5
+	v.States[12+6] = 1
+	r := v.Village(nil)
+	if r.Beds != 1 || r.Workstations != 1 || r.Bells != 1 || r.Professions["librarian"] != 1 {
+		t.Fatalf("Village: want a bed, a librarian workstation and a bell, got %+v", r)
+	}
+	want := []POILink{
+		{POI{BlockPos{3, 1, 0}, BedPOI, ""}, BlockPos{0, 1, 0}, 1},
+		{POI{BlockPos{11, 1, 0}, WorkstationPOI, "librarian"}, BlockPos{0, 1, 0}, 9},
+	}
+	if len(r.Links) != len(want) {
+		t.Fatalf("Links: want %v, got %v", want, r.Links)
+	}
+	for i := range want {
+		if r.Links[i] != want[i] {
+			t.Errorf("Link %d: want %v, got %v", i, want[i], r.Links[i])
+		}
+	}
+	if r = v.Village(&VillageOptions{Range: 5}); r.Links[1].Distance != -1 {
+		t.Errorf("Village within 5 blocks: want the lectern unlinked, got %v", r.Links[1])
+	}
+	// A wall two blocks high cuts the corridor.
+	v.States[24+6] = 1
+	if r = v.Village(nil); r.Links[1].Distance != -1 {
+		t.Errorf("Village behind a wall: want the lectern unlinked, got %v", r.Links[1])
+	}
+}
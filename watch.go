@@ -0,0 +1,137 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// A WatchEvent reports a change of a schematic file.
+type WatchEvent struct {
+	Path      string
+	Schematic *Schematic // the new contents, nil if the file was removed or is broken
+	Err       os.Error   // the parse error, if any
+	Removed   bool
+}
+
+// A Watcher polls a directory tree and parses with ReadFile the schematic
+// files which were added or modified since the previous poll. The changes
+// are sent to all subscribers. The first poll reports every existing file.
+type Watcher struct {
+	root     string
+	interval int64 // nanoseconds
+	mtimes   map[string]int64
+	stop     chan bool // closed by Close
+	done     chan bool // closed when the polling stops
+	stopOnce sync.Once
+
+	mu      sync.Mutex
+	started bool
+	subs    []chan *WatchEvent
+}
+
+// NewWatcher returns a Watcher of the directory tree rooted at root,
+// polling it every interval nanoseconds once started.
+func NewWatcher(root string, interval int64) *Watcher {
+	return &Watcher{
+		root:     root,
+		interval: interval,
+		mtimes:   make(map[string]int64),
+		stop:     make(chan bool),
+		done:     make(chan bool),
+	}
+}
+
+// Start begins polling. Subscribe before Start to receive the initial events.
+func (w *Watcher) Start() {
+	w.mu.Lock()
+	w.started = true
+	w.mu.Unlock()
+	go w.loop()
+}
+
+// Subscribe returns a channel receiving all subsequent events.
+// Subscribers should keep receiving from the channel until Close: the
+// Watcher waits for a slow subscriber, but a Close drops the pending
+// events.
+func (w *Watcher) Subscribe() <-chan *WatchEvent {
+	c := make(chan *WatchEvent, 16)
+	w.mu.Lock()
+	w.subs = append(w.subs, c)
+	w.mu.Unlock()
+	return c
+}
+
+// Close stops the Watcher and closes the subscriber channels. It may be
+// called more than once.
+func (w *Watcher) Close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	w.mu.Lock()
+	started := w.started
+	w.mu.Unlock()
+	if started {
+		<-w.done
+	}
+	w.mu.Lock()
+	for _, c := range w.subs {
+		close(c)
+	}
+	w.subs = nil
+	w.mu.Unlock()
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+	for {
+		w.poll()
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+func (w *Watcher) notify(e *WatchEvent) {
+	w.mu.Lock()
+	subs := w.subs
+	w.mu.Unlock()
+	for _, c := range subs {
+		select {
+		case c <- e:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// poll compares the files on disk with the ones seen previously.
+func (w *Watcher) poll() {
+	seen := make(map[string]bool)
+	walkFiles(w.root, func(path string, err os.Error) os.Error {
+		if err != nil {
+			return nil
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		seen[path] = true
+		if mtime, ok := w.mtimes[path]; ok && mtime == fi.Mtime_ns {
+			return nil
+		}
+		w.mtimes[path] = fi.Mtime_ns
+		s, err := ReadFile(path)
+		w.notify(&WatchEvent{Path: path, Schematic: s, Err: err})
+		return nil
+	})
+	for path := range w.mtimes {
+		if !seen[path] {
+			w.mtimes[path] = 0, false
+			w.notify(&WatchEvent{Path: path, Removed: true})
+		}
+	}
+}
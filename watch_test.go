@@ -0,0 +1,83 @@
+package schematic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, c <-chan *WatchEvent) *WatchEvent {
+	select {
+	case e := <-c:
+		return e
+	case <-time.After(5e9):
+	}
+	t.Fatalf("No event within 5 seconds")
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a"+Ext)
+	small := &Schematic{Width: 1, Height: 1, Length: 1, Blocks: []byte{1}, Data: []byte{0}}
+	if err = WriteSchematicFile(path, small, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	w := NewWatcher(dir, 1e6)
+	c := w.Subscribe()
+	w.Start()
+	defer w.Close()
+
+	e := nextEvent(t, c)
+	if e.Path != path || e.Err != nil || e.Schematic.Width != 1 {
+		t.Fatalf("Initial event: got %+v", e)
+	}
+	os.Remove(path)
+	if e = nextEvent(t, c); e.Path != path || !e.Removed {
+		t.Fatalf("Remove event: got %+v", e)
+	}
+	small.Width, small.Blocks, small.Data = 2, []byte{1, 2}, []byte{0, 0}
+	if err = WriteSchematicFile(path, small, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	if e = nextEvent(t, c); e.Path != path || e.Schematic == nil || e.Schematic.Width != 2 {
+		t.Fatalf("Create event: got %+v", e)
+	}
+}
+
+func TestWatcherCloseIdle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schematic")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	small := &Schematic{Width: 1, Height: 1, Length: 1, Blocks: []byte{1}, Data: []byte{0}}
+	for i := 0; i < 40; i++ {
+		if err = WriteSchematicFile(filepath.Join(dir, fmt.Sprintf("f%d%s", i, Ext)), small, nil); err != nil {
+			t.Fatalf("WriteSchematicFile: %v", err)
+		}
+	}
+	// The subscriber never reads, so the Watcher blocks after 16 events.
+	w := NewWatcher(dir, 1e6)
+	w.Subscribe()
+	w.Start()
+	time.Sleep(1e8)
+	closed := make(chan bool)
+	go func() {
+		w.Close()
+		w.Close()
+		closed <- true
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5e9):
+		t.Fatalf("Close blocked with a subscriber not reading")
+	}
+}
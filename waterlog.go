@@ -0,0 +1,77 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"fmt"
+	"os"
+)
+
+// Since Minecraft 1.13, blocks which do not fill their space, such as
+// slabs, stairs or fences, may contain water. Such blocks have the
+// property waterlogged=true; removing them leaves the water in place.
+
+// stillWater is the state of a water source block.
+const stillWater = "minecraft:water[level=0]"
+
+// IsWaterlogged reports whether the block state contains water.
+func IsWaterlogged(state string) bool {
+	_, props, ok := splitState(state)
+	return ok && props["waterlogged"] == "true"
+}
+
+// Dewaterlog removes the water from all waterlogged blocks and returns the
+// number of changed blocks.
+func (v *StateVolume) Dewaterlog() int {
+	return v.Rewrite(Rewrite{
+		Match: BlockMatcher{Props: map[string]string{"waterlogged": "true"}},
+		Set:   map[string]string{"waterlogged": "false"},
+	})
+}
+
+// Replace changes the blocks matching m into the state and returns the
+// number of changed blocks. If keepWater is true, the water of replaced
+// waterlogged blocks stays: the new state is waterlogged if it has the
+// waterlogged property, and air is replaced by water. Otherwise the water
+// is removed with the blocks.
+func (v *StateVolume) Replace(m BlockMatcher, state string, keepWater bool) (n int, err os.Error) {
+	name, _, ok := splitState(state)
+	if !ok || name == "" {
+		return 0, fmt.Errorf("Invalid block state: %s", state)
+	}
+	return v.mapPalette(func(old string) string {
+		if !m.Match(old) {
+			return old
+		}
+		return waterlog(old, state, keepWater)
+	}), nil
+}
+
+// waterlog returns the state which replaces old. If keepWater is true and
+// old is waterlogged, the state is waterlogged if it has the property, and
+// air becomes water.
+func waterlog(old, state string, keepWater bool) string {
+	if !keepWater || !IsWaterlogged(old) {
+		return state
+	}
+	name, props, ok := splitState(state)
+	if !ok {
+		return state
+	}
+	if _, has := props["waterlogged"]; has {
+		props["waterlogged"] = "true"
+		return joinState(name, props)
+	}
+	if qualifiedName(name) == "minecraft:air" {
+		return stillWater
+	}
+	return state
+}
+
+// dryState sets waterlogged=false in a waterlogged state.
+func dryState(props map[string]string, keepWater bool) {
+	if !keepWater && props["waterlogged"] == "true" {
+		props["waterlogged"] = "false"
+	}
+}
@@ -0,0 +1,84 @@
+package schematic
+
+import (
+	"testing"
+)
+
+func newWaterlogVolume() *StateVolume {
+	return &StateVolume{
+		Width: 4, Height: 1, Length: 1,
+		DataVersion: LatestDataVersion,
+		Palette: []string{
+			"minecraft:air",
+			"minecraft:oak_slab[type=bottom,waterlogged=true]",
+			"minecraft:oak_fence[waterlogged=false]",
+			"minecraft:stone",
+		},
+		States: []int{1, 2, 3, 0},
+	}
+}
+
+func TestIsWaterlogged(t *testing.T) {
+	tests := []struct {
+		state string
+		want  bool
+	}{
+		{"minecraft:oak_slab[type=bottom,waterlogged=true]", true},
+		{"minecraft:oak_fence[waterlogged=false]", false},
+		{"minecraft:water[level=0]", false},
+		{"minecraft:stone", false},
+		{"minecraft:oak_slab[waterlogged=true", false},
+	}
+	for _, tt := range tests {
+		if got := IsWaterlogged(tt.state); got != tt.want {
+			t.Errorf("IsWaterlogged(%s): want %v, got %v", tt.state, tt.want, got)
+		}
+	}
+}
+
+func TestDewaterlog(t *testing.T) {
+	v := newWaterlogVolume()
+	if n := v.Dewaterlog(); n != 1 {
+		t.Errorf("Dewaterlog: want 1 changed block, got %d", n)
+	}
+	if got := v.State(0, 0, 0); got != "minecraft:oak_slab[type=bottom,waterlogged=false]" {
+		t.Errorf("State(0, 0, 0): got %s", got)
+	}
+}
+
+func TestReplaceWaterlogged(t *testing.T) {
+	tests := []struct {
+		state     string
+		keepWater bool
+		want      string
+	}{
+		{"minecraft:air", true, "minecraft:water[level=0]"},
+		{"minecraft:air", false, "minecraft:air"},
+		{"minecraft:spruce_slab[type=top,waterlogged=false]", true, "minecraft:spruce_slab[type=top,waterlogged=true]"},
+		{"minecraft:spruce_slab[type=top,waterlogged=false]", false, "minecraft:spruce_slab[type=top,waterlogged=false]"},
+		{"minecraft:glass", true, "minecraft:glass"},
+	}
+	slabs := BlockMatcher{Name: "oak_slab"}
+	for _, tt := range tests {
+		v := newWaterlogVolume()
+		n, err := v.Replace(slabs, tt.state, tt.keepWater)
+		if err != nil || n != 1 {
+			t.Errorf("Replace(%s, %v): want 1 changed block, got %d, %v", tt.state, tt.keepWater, n, err)
+			continue
+		}
+		if got := v.State(0, 0, 0); got != tt.want {
+			t.Errorf("Replace(%s, %v): want %s, got %s", tt.state, tt.keepWater, tt.want, got)
+		}
+		if v.Palette[0] != "minecraft:air" || v.State(3, 0, 0) != "minecraft:air" {
+			t.Errorf("Replace(%s, %v): air moved: %v", tt.state, tt.keepWater, v.Palette)
+		}
+	}
+
+	v := newWaterlogVolume()
+	if n, err := v.Replace(BlockMatcher{Name: "air"}, "minecraft:dirt", true); err != nil || n != 1 || v.Palette[0] != "minecraft:air" {
+		t.Errorf("Replace air: got %d, %v, palette %v", n, err, v.Palette)
+	}
+	if _, err := v.Replace(slabs, "stone[", true); err == nil {
+		t.Errorf("Replace with an invalid state: want error, got nil")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+
+// Package web implements an HTTP service converting and previewing
+// schematics. A conversion service takes a few lines:
+//
+//	http.Handle("/", web.NewHandler())
+//	http.ListenAndServe(":8080", nil)
+//
+// All endpoints accept POST requests with the schematic either as the
+// request body or as the "file" field of a multipart form:
+//
+//	/convert?format=schematic|json|snbt|viewer&compression=gzip|zlib|zstd|none&level=0-9
+//	/stats                 JSON summary: dimensions, block counts, fingerprint
+//	/preview?scale=4       PNG top-down view
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"http"
+	"image/png"
+	"io"
+	"json"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/krasin/schematic"
+	"github.com/krasin/schematic/nbt"
+)
+
+// DefaultMaxSize is the default limit of the upload size in bytes.
+const DefaultMaxSize = 64 << 20
+
+// DefaultMaxNBTSize is the default limit of the decompressed upload in bytes.
+const DefaultMaxNBTSize = 256 << 20
+
+// maxPreviewSize limits the width and the height of preview images.
+const maxPreviewSize = 8192
+
+// A Handler serves the conversion endpoints.
+type Handler struct {
+	// MaxSize is the maximum size of an uploaded schematic in bytes.
+	MaxSize int64
+
+	// MaxNBTSize is the maximum size of an uploaded schematic after
+	// decompression, so that a small upload cannot expand into a huge one.
+	MaxNBTSize int64
+
+	mux *http.ServeMux
+}
+
+// NewHandler returns a Handler with the default upload limits.
+func NewHandler() *Handler {
+	h := &Handler{MaxSize: DefaultMaxSize, MaxNBTSize: DefaultMaxNBTSize, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/convert", h.post(h.convert))
+	h.mux.HandleFunc("/stats", h.post(h.stats))
+	h.mux.HandleFunc("/preview", h.post(h.preview))
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// A request is a parsed upload.
+type request struct {
+	*http.Request
+	name string
+	s    *schematic.Schematic
+}
+
+// A bufferedResponse keeps the body until the endpoint succeeds, so that
+// an error is not appended to a partial response.
+type bufferedResponse struct {
+	http.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferedResponse) Write(p []byte) (int, os.Error) {
+	return w.buf.Write(p)
+}
+
+// post wraps an endpoint, parsing the uploaded schematic.
+func (h *Handler) post(fn func(w http.ResponseWriter, r *request) os.Error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "POST a schematic to this URL", http.StatusMethodNotAllowed)
+			return
+		}
+		req, err := h.read(r)
+		if err != nil {
+			http.Error(w, err.String(), http.StatusBadRequest)
+			return
+		}
+		bw := &bufferedResponse{ResponseWriter: w}
+		if err = fn(bw, req); err != nil {
+			http.Error(w, err.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write(bw.buf.Bytes())
+	}
+}
+
+func (h *Handler) read(r *http.Request) (req *request, err os.Error) {
+	req = &request{Request: r, name: "upload" + schematic.Ext}
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		body, req.name = f, fh.Filename
+	}
+	opt := &schematic.ReadOptions{MaxSize: h.MaxNBTSize}
+	if req.s, err = schematic.ReadSchematicOptions(io.LimitReader(body, h.MaxSize), opt); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// intParam returns the value of an integer query parameter.
+func (r *request) intParam(name string, def int) (int, os.Error) {
+	str := r.FormValue(name)
+	if str == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s: %s", name, str)
+	}
+	return v, nil
+}
+
+var compressions = map[string]schematic.Compression{
+	"":     schematic.Gzip,
+	"gzip": schematic.Gzip,
+	"zlib": schematic.Zlib,
+	"zstd": schematic.Zstd,
+	"none": schematic.None,
+}
+
+func (h *Handler) convert(w http.ResponseWriter, r *request) (err os.Error) {
+	opt := new(schematic.WriteOptions)
+	var ok bool
+	if opt.Compression, ok = compressions[r.FormValue("compression")]; !ok {
+		return fmt.Errorf("Unknown compression: %s", r.FormValue("compression"))
+	}
+	if opt.Level, err = r.intParam("level", 0); err != nil {
+		return
+	}
+	switch format := r.FormValue("format"); format {
+	case "", "schematic":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		return schematic.WriteSchematicOptions(w, r.s, opt)
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		return nbt.EncodeJSON(w, "Schematic", r.s.NBT())
+	case "viewer":
+		w.Header().Set("Content-Type", "application/json")
+		return schematic.WriteViewerJSON(w, r.s)
+	case "snbt":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err = io.WriteString(w, nbt.FormatSNBT(r.s.NBT(), "  ")+"\n")
+		return
+	default:
+		return fmt.Errorf("Unknown format: %s", format)
+	}
+	panic("unreachable")
+}
+
+func (h *Handler) stats(w http.ResponseWriter, r *request) os.Error {
+	e := schematic.NewCatalogEntry(r.name, r.s)
+	// The uploads are read by ReadSchematic whatever their extension.
+	e.Format = "schematic"
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	return err
+}
+
+func (h *Handler) preview(w http.ResponseWriter, r *request) os.Error {
+	scale, err := r.intParam("scale", 4)
+	if err != nil {
+		return err
+	}
+	if scale < 1 || r.s.XLen()*scale > maxPreviewSize || r.s.ZLen()*scale > maxPreviewSize {
+		return fmt.Errorf("Invalid scale: %d", scale)
+	}
+	m, err := schematic.RenderTop(r.s, scale)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "image/png")
+	return png.Encode(w, m)
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"bytes"
+	"http"
+	"http/httptest"
+	"image/png"
+	"io"
+	"json"
+	"mime/multipart"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/krasin/schematic"
+	"github.com/krasin/schematic/nbt"
+)
+
+func testUpload(t *testing.T) []byte {
+	s := &schematic.Schematic{Width: 2, Height: 1, Length: 3, Blocks: []byte{1, 2, 0, 3, 4, 5}, Data: make([]byte, 6)}
+	var buf bytes.Buffer
+	if err := schematic.WriteSchematic(&buf, s); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func serve(t *testing.T, method, url string, body []byte) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	NewHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConvert(t *testing.T) {
+	rec := serve(t, "POST", "/convert?compression=zlib&level=9", testUpload(t))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/convert: status %d: %s", rec.Code, rec.Body)
+	}
+	s, err := schematic.ReadSchematic(rec.Body)
+	if err != nil || s.Width != 2 || s.Length != 3 {
+		t.Errorf("/convert: ReadSchematic: %v, %+v", err, s)
+	}
+
+	rec = serve(t, "POST", "/convert?format=json", testUpload(t))
+	name, tag, err := nbt.DecodeJSON(rec.Body)
+	if err != nil || name != "Schematic" {
+		t.Fatalf("/convert?format=json: DecodeJSON: %v, %s", err, name)
+	}
+	if s, err = schematic.FromNBT(tag.(*nbt.Compound)); err != nil || s.Blocks[5] != 5 {
+		t.Errorf("FromNBT: %v, %+v", err, s)
+	}
+
+	rec = serve(t, "POST", "/convert?format=snbt", testUpload(t))
+	if !strings.Contains(rec.Body.String(), "Width: 2s") {
+		t.Errorf("/convert?format=snbt: got %s", rec.Body)
+	}
+
+	rec = serve(t, "POST", "/convert?format=viewer", testUpload(t))
+	var doc schematic.ViewerDocument
+	if err = json.Unmarshal(rec.Body.Bytes(), &doc); err != nil || doc.Size != [3]int{2, 1, 3} || len(doc.Palette) != 6 {
+		t.Errorf("/convert?format=viewer: %v, %+v", err, doc)
+	}
+	for _, url := range []string{"/convert?format=bogus", "/convert?compression=bogus", "/convert?level=11"} {
+		if rec = serve(t, "POST", url, testUpload(t)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: want status %d, got %d", url, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "house.schematic")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	fw.Write(testUpload(t))
+	mw.Close()
+	req, _ := http.NewRequest("POST", "/stats", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	NewHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/stats: status %d: %s", rec.Code, rec.Body)
+	}
+	var e schematic.CatalogEntry
+	if err = json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if e.Path != "house.schematic" || e.Blocks != 5 || e.Width != 2 {
+		t.Errorf("/stats: got %+v", e)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	rec := serve(t, "POST", "/preview?scale=3", testUpload(t))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/preview: status %d: %s", rec.Code, rec.Body)
+	}
+	m, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("png.Decode: %v", err)
+	}
+	if b := m.Bounds(); b.Dx() != 6 || b.Dy() != 9 {
+		t.Errorf("Preview size: want 6x9, got %dx%d", b.Dx(), b.Dy())
+	}
+	if rec = serve(t, "GET", "/preview", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /preview: want status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+	if rec = serve(t, "POST", "/preview", []byte("junk")); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST junk: want status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	var buf bytes.Buffer
+	if err = schematic.WriteSchematic(&buf, schematic.NewSchematic(2, 0, 2)); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	if rec = serve(t, "POST", "/preview", buf.Bytes()); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST an empty schematic: want status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestMaxNBTSize(t *testing.T) {
+	// A large schematic of air compresses to a few kilobytes.
+	var buf bytes.Buffer
+	if err := schematic.WriteSchematic(&buf, schematic.NewSchematic(256, 64, 256)); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	h := NewHandler()
+	h.MaxNBTSize = 1 << 20
+	req, err := http.NewRequest("POST", "/stats", bytes.NewBuffer(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "larger than") {
+		t.Errorf("POST a large schematic: want status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body)
+	}
+	if rec = serve(t, "POST", "/stats", buf.Bytes()); rec.Code != http.StatusOK {
+		t.Errorf("POST with the default limit: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPartialResponse(t *testing.T) {
+	h := NewHandler()
+	fn := h.post(func(w http.ResponseWriter, r *request) os.Error {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, "partial")
+		return os.NewError("broken")
+	})
+	req, err := http.NewRequest("POST", "/", bytes.NewBuffer(testUpload(t)))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	fn(rec, req)
+	if rec.Code != http.StatusBadRequest || strings.Contains(rec.Body.String(), "partial") {
+		t.Errorf("failed endpoint: got status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); strings.HasPrefix(ct, "image/png") {
+		t.Errorf("failed endpoint: Content-Type %s", ct)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// SpongeExt is the file name extension of the Sponge schematics saved by
+// recent versions of WorldEdit. They are read by ReadSponge.
+const SpongeExt = ".schem"
+
+// worldEditDirs lists the schematic directories of WorldEdit relative to
+// the game or server directory: the Forge and Fabric mods, then the Bukkit plugin.
+var worldEditDirs = []string{
+	filepath.Join("config", "worldedit", "schematics"),
+	filepath.Join("plugins", "WorldEdit", "schematics"),
+}
+
+// WorldEditDirs returns the directories where WorldEdit saves schematics
+// for the game or server installed in root, such as ~/.minecraft. If player
+// is not empty, it is the UUID of a player and the directories used by the
+// per-player-schematics option are returned.
+func WorldEditDirs(root, player string) []string {
+	var dirs []string
+	for _, dir := range worldEditDirs {
+		dir = filepath.Join(root, dir)
+		if player != "" {
+			dir = filepath.Join(dir, player)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// FindClipboard returns the schematic most recently saved by WorldEdit
+// in the directories returned by WorldEditDirs, which is what the player
+// copied and saved last with //schem save. Sponge files are included.
+func FindClipboard(root, player string) (path string, err os.Error) {
+	var mtime int64
+	for _, dir := range WorldEditDirs(root, player) {
+		var f *os.File
+		if f, err = os.Open(dir); err != nil {
+			continue
+		}
+		names, _ := f.Readdirnames(-1)
+		f.Close()
+		for _, name := range names {
+			lower := strings.ToLower(name)
+			if !strings.HasSuffix(lower, Ext) && !strings.HasSuffix(lower, SpongeExt) {
+				continue
+			}
+			name = filepath.Join(dir, name)
+			fi, err := os.Stat(name)
+			if err != nil || !fi.IsRegular() {
+				continue
+			}
+			if path == "" || fi.Mtime_ns > mtime {
+				path, mtime = name, fi.Mtime_ns
+			}
+		}
+	}
+	if path == "" {
+		return "", os.NewError("No WorldEdit schematics found in " + root)
+	}
+	return path, nil
+}
+
+// ReadClipboard reads the schematic returned by FindClipboard.
+// Sponge schematics are converted by ReadFile.
+func ReadClipboard(root, player string) (s *Schematic, path string, err os.Error) {
+	if path, err = FindClipboard(root, player); err != nil {
+		return
+	}
+	s, err = ReadFile(path)
+	return
+}
+
+// WorldEdit records where the blocks were copied from in two points: the
+// origin is the position of the player running //copy and the offset is
+// the position of the minimum corner of the schematic relative to the origin.
+// Pasting with the player at p puts the corner at p + offset.
+
+// Origin returns the world position of the player who copied the blocks,
+// stored by WorldEdit in the WEOriginX, WEOriginY and WEOriginZ tags.
+// ok is false if the schematic has no such tags.
+func (s *Schematic) Origin() (x, y, z int, ok bool) {
+	if s.Extra == nil || s.Extra.Get("WEOriginX") == nil {
+		return 0, 0, 0, false
+	}
+	return intField(s.Extra, "WEOriginX"), intField(s.Extra, "WEOriginY"), intField(s.Extra, "WEOriginZ"), true
+}
+
+// SetOrigin sets the origin returned by Origin.
+func (s *Schematic) SetOrigin(x, y, z int) {
+	if s.Extra == nil {
+		s.Extra = new(nbt.Compound)
+	}
+	s.Extra.Set("WEOriginX", nbt.Int(x)).Set("WEOriginY", nbt.Int(y)).Set("WEOriginZ", nbt.Int(z))
+}
+
+// PastePosition returns the world position of the block (0, 0, 0) when the
+// schematic is pasted by a player standing at (px, py, pz).
+func (s *Schematic) PastePosition(px, py, pz int) (x, y, z int) {
+	return px + s.WEOffsetX, py + s.WEOffsetY, pz + s.WEOffsetZ
+}
+
+// PlayerPosition is the inverse of PastePosition: it returns where the
+// player must stand for the block (0, 0, 0) to be pasted at (x, y, z).
+func (s *Schematic) PlayerPosition(x, y, z int) (px, py, pz int) {
+	return x - s.WEOffsetX, y - s.WEOffsetY, z - s.WEOffsetZ
+}
+
+// CopyPosition returns the world position the block (0, 0, 0) was copied
+// from: the origin plus the offset. ok is false if the origin is unknown.
+func (s *Schematic) CopyPosition() (x, y, z int, ok bool) {
+	if x, y, z, ok = s.Origin(); ok {
+		x, y, z = s.PastePosition(x, y, z)
+	}
+	return
+}
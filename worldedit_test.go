@@ -0,0 +1,106 @@
+package schematic
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func TestReadClipboard(t *testing.T) {
+	root, err := ioutil.TempDir("", "schematic-worldedit")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(root)
+	if _, err = FindClipboard(root, ""); err == nil {
+		t.Errorf("FindClipboard without WorldEdit: want error, got nil")
+	}
+
+	player := "069a79f4-44e9-4726-a5be-fca90e38aaf5"
+	dirs := WorldEditDirs(root, player)
+	for _, dir := range dirs {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+	}
+	old := NewSchematic(1, 1, 1)
+	s := NewSchematic(2, 1, 1)
+	s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ = -1, 0, 2
+	s.Extra.Set("WEOriginX", nbt.Int(100)).Set("WEOriginY", nbt.Int(64)).Set("WEOriginZ", nbt.Int(-5))
+	if err = WriteSchematicFile(filepath.Join(dirs[0], "old.schematic"), old, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	want := filepath.Join(dirs[1], "house.schematic")
+	if err = WriteSchematicFile(want, s, nil); err != nil {
+		t.Fatalf("WriteSchematicFile: %v", err)
+	}
+	// Make sure the second file is newer.
+	now := time.Nanoseconds()
+	os.Chtimes(filepath.Join(dirs[0], "old.schematic"), now-2e9, now-2e9)
+	os.Chtimes(want, now, now)
+
+	got, path, err := ReadClipboard(root, player)
+	if err != nil {
+		t.Fatalf("ReadClipboard: %v", err)
+	}
+	if path != want || got.Width != 2 {
+		t.Errorf("ReadClipboard: want %s, got %s (width %d)", want, path, got.Width)
+	}
+	if x, y, z, ok := got.Origin(); !ok || x != 100 || y != 64 || z != -5 {
+		t.Errorf("Origin: want 100 64 -5 true, got %d %d %d %v", x, y, z, ok)
+	}
+	if x, y, z := got.PastePosition(10, 70, 10); x != 9 || y != 70 || z != 12 {
+		t.Errorf("PastePosition: want 9 70 12, got %d %d %d", x, y, z)
+	}
+	if _, _, _, ok := old.Origin(); ok {
+		t.Errorf("Origin of a schematic without WEOrigin tags: want false")
+	}
+}
+
+func TestSponge(t *testing.T) {
+	var buf bytes.Buffer
+	w := nbt.NewWriter(&buf)
+	c := nbt.NewCompound().Set("Version", nbt.Int(2)).Set("Palette", nbt.NewCompound()).Set("BlockData", nbt.ByteArray{})
+	if err := w.WriteTag("Schematic", c); err != nil {
+		t.Fatalf("WriteTag: %v", err)
+	}
+	w.Flush()
+	if _, err := ReadSchematic(&buf); err != ErrSponge {
+		t.Errorf("ReadSchematic: want ErrSponge, got %v", err)
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	// WorldEdit writes the world position of the minimum corner to the
+	// Offset of a Sponge schematic and the offset to Metadata.
+	sp := &Sponge{
+		StateVolume: StateVolume{Width: 1, Height: 1, Length: 1, States: []int{0}, Palette: []string{"minecraft:air"}},
+		Version:     2,
+		OffsetX:     98, OffsetY: 64, OffsetZ: -3,
+		Metadata: nbt.NewCompound().Set("WEOffsetX", nbt.Int(-2)).Set("WEOffsetY", nbt.Int(0)).Set("WEOffsetZ", nbt.Int(2)),
+	}
+	s, err := sp.Schematic()
+	if err != nil {
+		t.Fatalf("Schematic: %v", err)
+	}
+	if s.WEOffsetX != -2 || s.WEOffsetY != 0 || s.WEOffsetZ != 2 {
+		t.Errorf("Offset: want -2 0 2, got %d %d %d", s.WEOffsetX, s.WEOffsetY, s.WEOffsetZ)
+	}
+	if x, y, z, ok := s.Origin(); !ok || x != 100 || y != 64 || z != -5 {
+		t.Errorf("Origin: want 100 64 -5 true, got %d %d %d %v", x, y, z, ok)
+	}
+	if x, y, z, ok := s.CopyPosition(); !ok || x != 98 || y != 64 || z != -3 {
+		t.Errorf("CopyPosition: want 98 64 -3 true, got %d %d %d %v", x, y, z, ok)
+	}
+	if px, py, pz := s.PlayerPosition(s.PastePosition(7, 8, 9)); px != 7 || py != 8 || pz != 9 {
+		t.Errorf("PlayerPosition(PastePosition(7, 8, 9)): got %d %d %d", px, py, pz)
+	}
+	if _, _, _, ok := NewSchematic(1, 1, 1).CopyPosition(); ok {
+		t.Errorf("CopyPosition without an origin: want false")
+	}
+}
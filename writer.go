@@ -0,0 +1,135 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+// WriteSchematic writes s to w in .schematic format. The tags from s.Extra
+// are written after the ones represented by the fields of Schematic.
+func WriteSchematic(w io.Writer, s *Schematic) os.Error {
+	return WriteSchematicOptions(w, s, nil)
+}
+
+// WriteSchematicOptions is like WriteSchematic but allows to choose the
+// compression of the output and the encoding of air.
+func WriteSchematicOptions(w io.Writer, s *Schematic, opt *WriteOptions) (err os.Error) {
+	if err = checkDimensions(s.Width, s.Height, s.Length); err != nil {
+		return
+	}
+	if err = s.checkSize(); err != nil {
+		return
+	}
+	c := s.NBT()
+	if opt != nil && opt.VoidAir {
+		blocks := make(nbt.ByteArray, len(s.Blocks))
+		for i, id := range s.Blocks {
+			if blocks[i] = id; s.id(i) == 0 {
+				blocks[i] = byte(StructureVoid.Id)
+			}
+		}
+		c.Set("Blocks", blocks)
+	}
+	return writeRoot(w, "Schematic", c, opt)
+}
+
+// WriteTo writes s to w like WriteSchematic and returns the number of
+// bytes written. It implements io.WriterTo.
+func (s *Schematic) WriteTo(w io.Writer) (n int64, err os.Error) {
+	cw := &countWriter{w: w}
+	err = WriteSchematic(cw, s)
+	return cw.n, err
+}
+
+// MarshalBinary returns s in .schematic format, as written by
+// WriteSchematic.
+func (s *Schematic) MarshalBinary() (data []byte, err os.Error) {
+	var buf bytes.Buffer
+	if err = WriteSchematic(&buf, s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (n int, err os.Error) {
+	n, err = w.w.Write(p)
+	w.n += int64(n)
+	return
+}
+
+// writeRoot writes the root compound compressed according to opt.
+func writeRoot(w io.Writer, name string, c *nbt.Compound, opt *WriteOptions) (err os.Error) {
+	var zw io.WriteCloser
+	if zw, err = opt.compressor(w); err != nil {
+		return
+	}
+	nw := nbt.NewWriter(zw)
+	if err = nw.WriteTag(name, c); err != nil {
+		return
+	}
+	if err = nw.Flush(); err != nil {
+		return
+	}
+	return zw.Close()
+}
+
+// NBT returns the contents of the Schematic tag representing s.
+func (s *Schematic) NBT() *nbt.Compound {
+	c := new(nbt.Compound)
+	c.Set("Width", nbt.Short(s.Width))
+	c.Set("Height", nbt.Short(s.Height))
+	c.Set("Length", nbt.Short(s.Length))
+	c.Set("Materials", nbt.String(s.Materials.String()))
+	c.Set("Blocks", nbt.ByteArray(s.Blocks))
+	c.Set("Data", nbt.ByteArray(s.Data))
+	if add := s.packAdd(); add != nil {
+		c.Set("AddBlocks", add)
+	}
+	c.Set("WEOffsetX", nbt.Int(s.WEOffsetX))
+	c.Set("WEOffsetY", nbt.Int(s.WEOffsetY))
+	c.Set("WEOffsetZ", nbt.Int(s.WEOffsetZ))
+	entities := &nbt.List{ElemType: nbt.TagCompound}
+	for _, e := range s.Entities {
+		entities.Tags = append(entities.Tags, e.compound())
+	}
+	c.Set("Entities", entities)
+	tiles := &nbt.List{ElemType: nbt.TagCompound}
+	for _, t := range s.TileEntities {
+		tiles.Tags = append(tiles.Tags, t.compound())
+	}
+	c.Set("TileEntities", tiles)
+	if len(s.TileTicks) > 0 {
+		ticks := &nbt.List{ElemType: nbt.TagCompound}
+		for _, t := range s.TileTicks {
+			ticks.Tags = append(ticks.Tags, t.compound())
+		}
+		c.Set("TileTicks", ticks)
+	}
+	if s.Extra != nil {
+		for _, f := range s.Extra.Fields {
+			c.Set(f.Name, f.Tag)
+		}
+	}
+	return c
+}
+
+// compound returns the NBT representation of e with the id set to e.Id.
+func (e *Entity) compound() *nbt.Compound {
+	c := new(nbt.Compound)
+	if e.NBT != nil {
+		c.Fields = append(c.Fields, e.NBT.Fields...)
+	}
+	c.Set("id", nbt.String(e.Id))
+	return c
+}
@@ -0,0 +1,128 @@
+package schematic
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/krasin/schematic/nbt"
+)
+
+func readTestSchematic(t *testing.T, filename string) *Schematic {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Open(\"%s\"): %v", filename, err)
+	}
+	defer f.Close()
+	vol, err := ReadSchematic(f)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	return vol
+}
+
+func TestWriteSchematic(t *testing.T) {
+	vol := readTestSchematic(t, "testdata/cylinder.schematic")
+	vol.Extra.
+		Set("SchematicaMapping", nbt.NewCompound().Set("minecraft:stone", nbt.Short(1))).
+		Set("Biomes", nbt.IntArray{1, 2, 3})
+	vol.Entities = append(vol.Entities, Entity{Id: "Pig"})
+	var buf bytes.Buffer
+	if err := WriteSchematic(&buf, vol); err != nil {
+		t.Fatalf("WriteSchematic: %v", err)
+	}
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	if got.Width != vol.Width || got.Height != vol.Height || got.Length != vol.Length {
+		t.Errorf("Dimensions: want %dx%dx%d, got %dx%dx%d", vol.Width, vol.Height, vol.Length, got.Width, got.Height, got.Length)
+	}
+	if !bytes.Equal(got.Blocks, vol.Blocks) || !bytes.Equal(got.Data, vol.Data) {
+		t.Errorf("Blocks or Data differ after round trip")
+	}
+	if len(got.Entities) != len(vol.Entities) || got.Entities[len(got.Entities)-1].Id != "Pig" {
+		t.Errorf("Entities: want %v, got %v", vol.Entities, got.Entities)
+	}
+	if got.Extra.Len() != vol.Extra.Len() {
+		t.Fatalf("Extra: want %d tags, got %d", vol.Extra.Len(), got.Extra.Len())
+	}
+	biomes, ok := got.Extra.Get("Biomes").(nbt.IntArray)
+	if !ok || len(biomes) != 3 || biomes[2] != 3 {
+		t.Errorf("Extra Biomes: want [1 2 3], got %v", got.Extra.Get("Biomes"))
+	}
+	mapping, ok := got.Extra.Get("SchematicaMapping").(*nbt.Compound)
+	if !ok || mapping.Get("minecraft:stone") != nbt.Short(1) {
+		t.Errorf("Extra SchematicaMapping: got %v", got.Extra.Get("SchematicaMapping"))
+	}
+}
+
+func TestWriteTooLarge(t *testing.T) {
+	s := NewSchematic(MaxDimension+1, 1, 1)
+	if err := WriteSchematic(ioutil.Discard, s); err == nil {
+		t.Errorf("WriteSchematic of width %d: want error, got nil", s.Width)
+	}
+	s = NewSchematic(MaxDimension, 1, 1)
+	if err := WriteSchematic(ioutil.Discard, s); err != nil {
+		t.Errorf("WriteSchematic of width %d: %v", s.Width, err)
+	}
+}
+
+func TestWriteVoidAir(t *testing.T) {
+	s := newTestVolume()
+	var buf bytes.Buffer
+	if err := WriteSchematicOptions(&buf, s, &WriteOptions{VoidAir: true}); err != nil {
+		t.Fatalf("WriteSchematicOptions: %v", err)
+	}
+	got, err := ReadSchematic(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchematic: %v", err)
+	}
+	if got.Block(0, 0, 0) != (Block{1, 0}) || got.Block(1, 0, 0) != StructureVoid {
+		t.Errorf("VoidAir: got %v", got.Blocks)
+	}
+	if s.Block(1, 0, 0) != (Block{}) {
+		t.Errorf("VoidAir changed the schematic")
+	}
+}
+
+func TestWriterToReaderFrom(t *testing.T) {
+	s := newChestVolume()
+	var buf bytes.Buffer
+	var wt io.WriterTo = s
+	n, err := wt.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo: returned %d, wrote %d bytes", n, buf.Len())
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Errorf("MarshalBinary and WriteTo differ")
+	}
+
+	got := new(Schematic)
+	var rf io.ReaderFrom = got
+	if n, err = rf.ReadFrom(&buf); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if n != int64(len(data)) || !bytes.Equal(got.Blocks, s.Blocks) || got.TileEntityAt(2, 1, 1) == nil {
+		t.Errorf("ReadFrom: read %d of %d bytes, got %+v", n, len(data), got)
+	}
+	got = new(Schematic)
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if !bytes.Equal(got.Data, s.Data) || len(got.Entities) != 1 {
+		t.Errorf("UnmarshalBinary: got %+v", got)
+	}
+	if err = got.UnmarshalBinary(data[:10]); err == nil || got.Width != s.Width {
+		t.Errorf("UnmarshalBinary of truncated data: err %v, width %d", err, got.Width)
+	}
+}
@@ -0,0 +1,981 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// The zstd container is described in RFC 8878. The reader decodes any
+// frame without a dictionary; zstdWriter in zstdwriter.go writes a subset
+// of the format which the reference implementation reads.
+
+const (
+	zstdMagic          = 0xfd2fb528
+	zstdSkippableMagic = 0x184d2a50 // the low 4 bits are free
+	zstdBlockMax       = 1 << 17
+	zstdMaxWindowLog   = 27
+)
+
+var errZstdCorrupt = os.NewError("Corrupt zstd stream")
+
+// The literal and match lengths and the offsets of the sequences are
+// coded as symbols followed by extra bits.
+var (
+	zstdLLBase = []int{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512,
+		1024, 2048, 4096, 8192, 16384, 32768, 65536,
+	}
+	zstdLLBits = []uint{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9,
+		10, 11, 12, 13, 14, 15, 16,
+	}
+	zstdMLBase = []int{
+		3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515,
+		1027, 2051, 4099, 8195, 16387, 32771, 65539,
+	}
+	zstdMLBits = []uint{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9,
+		10, 11, 12, 13, 14, 15, 16,
+	}
+)
+
+// The predefined distributions of the sequence symbols.
+var (
+	zstdLLDefault = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+	zstdMLDefault = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+	zstdOFDefault = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+)
+
+// The kinds of the sequence symbols, in the order of their tables.
+const (
+	zstdLL = iota
+	zstdOF
+	zstdML
+)
+
+var (
+	zstdMaxSymbol = [3]int{35, 31, 52}
+	zstdMaxLog    = [3]uint{9, 8, 9}
+	zstdDefault   = [3]*fseTable{
+		mustFSETable(zstdLLDefault, 6),
+		mustFSETable(zstdOFDefault, 5),
+		mustFSETable(zstdMLDefault, 6),
+	}
+)
+
+// highBit returns the position of the highest set bit of v, which must
+// not be zero.
+func highBit(v uint32) (n uint) {
+	for v > 1 {
+		v >>= 1
+		n++
+	}
+	return
+}
+
+// zstdReader decodes a stream of zstd frames. Skippable frames are
+// skipped.
+type zstdReader struct {
+	r   *bufio.Reader
+	err os.Error
+
+	inFrame  bool
+	last     bool  // the last block of the frame was decoded
+	window   int   // the size of the history kept for the matches
+	size     int64 // the content size of the frame, or -1 if unknown
+	produced int64
+	checksum bool
+	hash     xxh64
+
+	hist  []byte // the decoded data of the frame, the last window bytes at least
+	out   []byte // the part of hist not returned yet
+	block []byte
+	lits  []byte
+	rep   [3]int
+	huff  *huffTable
+	seq   [3]*fseTable // the last tables, for the repeat mode
+}
+
+func newZstdReader(r *bufio.Reader) *zstdReader {
+	return &zstdReader{r: r}
+}
+
+func (z *zstdReader) Read(p []byte) (n int, err os.Error) {
+	for len(z.out) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.next()
+	}
+	n = copy(p, z.out)
+	z.out = z.out[n:]
+	return
+}
+
+// next decodes the next block, starting or ending a frame as needed.
+func (z *zstdReader) next() os.Error {
+	switch {
+	case !z.inFrame:
+		return z.startFrame()
+	case z.last:
+		return z.endFrame()
+	}
+	return z.readBlock()
+}
+
+// readFull is like io.ReadFull but reports the end of the input inside
+// a frame as corruption.
+func (z *zstdReader) readFull(p []byte) os.Error {
+	if _, err := io.ReadFull(z.r, p); err != nil {
+		if err == os.EOF || err == io.ErrUnexpectedEOF {
+			return errZstdCorrupt
+		}
+		return err
+	}
+	return nil
+}
+
+func (z *zstdReader) startFrame() os.Error {
+	var b [14]byte
+	if n, err := io.ReadFull(z.r, b[:4]); err != nil {
+		if n == 0 && err == os.EOF {
+			return os.EOF
+		}
+		return z.readFull(b[n:4])
+	}
+	magic := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+	if magic&^15 == zstdSkippableMagic {
+		if err := z.readFull(b[:4]); err != nil {
+			return err
+		}
+		n := int64(b[0]) | int64(b[1])<<8 | int64(b[2])<<16 | int64(b[3])<<24
+		if k, err := io.Copy(ioutil.Discard, io.LimitReader(z.r, n)); err != nil || k != n {
+			return errZstdCorrupt
+		}
+		return nil
+	}
+	if magic != zstdMagic {
+		return os.NewError("Invalid zstd frame header")
+	}
+	if err := z.readFull(b[:1]); err != nil {
+		return err
+	}
+	desc := b[0]
+	if desc&8 != 0 {
+		return errZstdCorrupt
+	}
+	single := desc&0x20 != 0
+	dictLen := []int{0, 1, 2, 4}[desc&3]
+	sizeLen := []int{0, 2, 4, 8}[desc>>6]
+	if sizeLen == 0 && single {
+		sizeLen = 1
+	}
+	n := dictLen + sizeLen
+	if !single {
+		n++
+	}
+	if err := z.readFull(b[:n]); err != nil {
+		return err
+	}
+	p := b[:n]
+	if !single {
+		exp, mantissa := uint(p[0]>>3), int64(p[0]&7)
+		if exp+10 > zstdMaxWindowLog {
+			return os.NewError("Zstd window is too large")
+		}
+		base := int64(1) << (exp + 10)
+		z.window = int(base + base/8*mantissa)
+		p = p[1:]
+	}
+	for _, c := range p[:dictLen] {
+		if c != 0 {
+			return os.NewError("Zstd dictionaries are not supported")
+		}
+	}
+	p = p[dictLen:]
+	z.size = -1
+	if sizeLen > 0 {
+		z.size = 0
+		for i := sizeLen - 1; i >= 0; i-- {
+			z.size = z.size<<8 | int64(p[i])
+		}
+		if sizeLen == 2 {
+			z.size += 256
+		}
+		if z.size < 0 {
+			return errZstdCorrupt
+		}
+	}
+	if single {
+		// The whole content is the window.
+		z.window = int(z.size)
+		if int64(z.window) != z.size {
+			return os.NewError("Zstd window is too large")
+		}
+	}
+	z.inFrame, z.last = true, false
+	z.checksum = desc&4 != 0
+	z.hash.Reset()
+	z.produced = 0
+	z.hist = z.hist[:0]
+	z.rep = [3]int{1, 4, 8}
+	z.huff = nil
+	z.seq = [3]*fseTable{}
+	return nil
+}
+
+func (z *zstdReader) endFrame() os.Error {
+	if z.size >= 0 && z.produced != z.size {
+		return errZstdCorrupt
+	}
+	if z.checksum {
+		var b [4]byte
+		if err := z.readFull(b[:]); err != nil {
+			return err
+		}
+		sum := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+		if sum != uint32(z.hash.Sum64()) {
+			return os.NewError("Zstd checksum mismatch")
+		}
+	}
+	z.inFrame = false
+	return nil
+}
+
+func (z *zstdReader) readBlock() os.Error {
+	var b [3]byte
+	if err := z.readFull(b[:]); err != nil {
+		return err
+	}
+	v := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	z.last = v&1 != 0
+	size := v >> 3
+	max := imin(z.window, zstdBlockMax)
+	if size > max {
+		return errZstdCorrupt
+	}
+	// Keep the last window bytes for the matches.
+	if n := len(z.hist) - z.window; n > z.window && n > zstdBlockMax {
+		z.hist = z.hist[:copy(z.hist, z.hist[n:])]
+	}
+	start := len(z.hist)
+	switch v >> 1 & 3 {
+	case 0:
+		z.hist = grow(z.hist, size)
+		if err := z.readFull(z.hist[start:]); err != nil {
+			return err
+		}
+	case 1:
+		c, err := z.r.ReadByte()
+		if err != nil {
+			return errZstdCorrupt
+		}
+		z.hist = grow(z.hist, size)
+		for i := start; i < len(z.hist); i++ {
+			z.hist[i] = c
+		}
+	case 2:
+		if cap(z.block) < size {
+			z.block = make([]byte, size)
+		}
+		data := z.block[:size]
+		if err := z.readFull(data); err != nil {
+			return err
+		}
+		if err := z.decodeBlock(data, max); err != nil {
+			return err
+		}
+	default:
+		return errZstdCorrupt
+	}
+	data := z.hist[start:]
+	if z.produced += int64(len(data)); z.size >= 0 && z.produced > z.size {
+		return errZstdCorrupt
+	}
+	if z.checksum {
+		z.hash.Write(data)
+	}
+	z.out = data
+	return nil
+}
+
+// grow extends b by n bytes.
+func grow(b []byte, n int) []byte {
+	if len(b)+n > cap(b) {
+		nb := make([]byte, len(b), 2*cap(b)+n)
+		copy(nb, b)
+		b = nb
+	}
+	return b[:len(b)+n]
+}
+
+// decodeBlock decodes a compressed block of at most max bytes.
+func (z *zstdReader) decodeBlock(data []byte, max int) os.Error {
+	lits, data, err := z.literals(data)
+	if err != nil {
+		return err
+	}
+	return z.sequences(data, lits, max)
+}
+
+// literals decodes the literals section at the start of data.
+func (z *zstdReader) literals(data []byte) (lits, rest []byte, err os.Error) {
+	if len(data) < 1 {
+		return nil, nil, errZstdCorrupt
+	}
+	typ, format := data[0]&3, data[0]>>2&3
+	if typ < 2 {
+		var size, n int
+		switch format {
+		case 0, 2:
+			size, n = int(data[0]>>3), 1
+		case 1:
+			if len(data) >= 2 {
+				size, n = int(data[0]>>4)|int(data[1])<<4, 2
+			}
+		case 3:
+			if len(data) >= 3 {
+				size, n = int(data[0]>>4)|int(data[1])<<4|int(data[2])<<12, 3
+			}
+		}
+		if n == 0 || size > zstdBlockMax {
+			return nil, nil, errZstdCorrupt
+		}
+		data = data[n:]
+		if typ == 0 {
+			if len(data) < size {
+				return nil, nil, errZstdCorrupt
+			}
+			return data[:size], data[size:], nil
+		}
+		if len(data) < 1 {
+			return nil, nil, errZstdCorrupt
+		}
+		lits = z.litBuffer(size)
+		for i := range lits {
+			lits[i] = data[0]
+		}
+		return lits, data[1:], nil
+	}
+
+	n := []int{3, 3, 4, 5}[format]
+	if len(data) < n {
+		return nil, nil, errZstdCorrupt
+	}
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(data[i])
+	}
+	bits := uint(n*8-4) / 2
+	size := int(v >> 4 & (1<<bits - 1))
+	compSize := int(v >> (4 + bits) & (1<<bits - 1))
+	if len(data) < n+compSize || size > zstdBlockMax {
+		return nil, nil, errZstdCorrupt
+	}
+	src, rest := data[n:n+compSize], data[n+compSize:]
+	if typ == 2 {
+		var used int
+		if z.huff, used, err = readHuffTable(src); err != nil {
+			return
+		}
+		src = src[used:]
+	} else if z.huff == nil {
+		return nil, nil, errZstdCorrupt
+	}
+	lits = z.litBuffer(size)
+	if format == 0 {
+		err = z.huff.decode(lits, src)
+		return lits, rest, err
+	}
+	if len(src) < 6 {
+		return nil, nil, errZstdCorrupt
+	}
+	seg := (size + 3) / 4
+	if 3*seg > size {
+		return nil, nil, errZstdCorrupt
+	}
+	src, jump := src[6:], src[:6]
+	for i := 0; i < 4; i++ {
+		streamLen := len(src)
+		if i < 3 {
+			streamLen = int(jump[2*i]) | int(jump[2*i+1])<<8
+		}
+		if streamLen > len(src) {
+			return nil, nil, errZstdCorrupt
+		}
+		dst := lits[i*seg:]
+		if i < 3 {
+			dst = dst[:seg]
+		}
+		if err = z.huff.decode(dst, src[:streamLen]); err != nil {
+			return
+		}
+		src = src[streamLen:]
+	}
+	return lits, rest, nil
+}
+
+func (z *zstdReader) litBuffer(size int) []byte {
+	if cap(z.lits) < size {
+		z.lits = make([]byte, zstdBlockMax)
+	}
+	return z.lits[:size]
+}
+
+// sequences decodes the sequences section and appends the block to hist.
+func (z *zstdReader) sequences(data, lits []byte, max int) os.Error {
+	if len(data) < 1 {
+		return errZstdCorrupt
+	}
+	n, i := int(data[0]), 1
+	switch {
+	case n == 255:
+		if len(data) < 3 {
+			return errZstdCorrupt
+		}
+		n, i = int(data[1])|int(data[2])<<8+0x7f00, 3
+	case n >= 128:
+		if len(data) < 2 {
+			return errZstdCorrupt
+		}
+		n, i = (n-128)<<8|int(data[1]), 2
+	}
+	start := len(z.hist)
+	if n == 0 {
+		if i != len(data) || len(lits) > max {
+			return errZstdCorrupt
+		}
+		z.hist = append(z.hist, lits...)
+		return nil
+	}
+	if len(data) <= i {
+		return errZstdCorrupt
+	}
+	modes := data[i]
+	i++
+	if modes&3 != 0 {
+		return errZstdCorrupt
+	}
+	var tables [3]*fseTable
+	for k := range tables {
+		switch modes >> uint(6-2*k) & 3 {
+		case 0:
+			tables[k] = zstdDefault[k]
+		case 1:
+			if i >= len(data) || int(data[i]) > zstdMaxSymbol[k] {
+				return errZstdCorrupt
+			}
+			tables[k] = &fseTable{e: []fseEntry{{sym: data[i]}}}
+			i++
+		case 2:
+			norm, log, used, err := readFSENorm(data[i:], zstdMaxSymbol[k], zstdMaxLog[k])
+			if err != nil {
+				return err
+			}
+			if tables[k], err = newFSETable(norm, log); err != nil {
+				return err
+			}
+			i += used
+		case 3:
+			if tables[k] = z.seq[k]; tables[k] == nil {
+				return errZstdCorrupt
+			}
+		}
+		z.seq[k] = tables[k]
+	}
+	var br backBits
+	if err := br.init(data[i:]); err != nil {
+		return err
+	}
+	ll, of, ml := tables[zstdLL], tables[zstdOF], tables[zstdML]
+	llState, ofState, mlState := br.read(ll.log), br.read(of.log), br.read(ml.log)
+	rep := &z.rep
+	for k := 0; k < n; k++ {
+		llCode, ofCode, mlCode := ll.e[llState].sym, of.e[ofState].sym, ml.e[mlState].sym
+		offset := 1<<ofCode + int(br.read(uint(ofCode)))
+		matchLen := zstdMLBase[mlCode] + int(br.read(zstdMLBits[mlCode]))
+		litLen := zstdLLBase[llCode] + int(br.read(zstdLLBits[llCode]))
+		if k < n-1 {
+			llState = ll.next(llState, &br)
+			mlState = ml.next(mlState, &br)
+			ofState = of.next(ofState, &br)
+		}
+		if offset > 3 {
+			offset -= 3
+			rep[2], rep[1], rep[0] = rep[1], rep[0], offset
+		} else {
+			idx := offset - 1
+			if litLen == 0 {
+				idx++
+			}
+			switch idx {
+			case 0:
+				offset = rep[0]
+			case 1:
+				offset = rep[1]
+				rep[1], rep[0] = rep[0], offset
+			case 2:
+				offset = rep[2]
+				rep[2], rep[1], rep[0] = rep[1], rep[0], offset
+			case 3:
+				offset = rep[0] - 1
+				rep[2], rep[1], rep[0] = rep[1], rep[0], offset
+			}
+		}
+		if litLen > len(lits) || len(z.hist)-start+litLen+matchLen > max {
+			return errZstdCorrupt
+		}
+		z.hist = append(z.hist, lits[:litLen]...)
+		lits = lits[litLen:]
+		if offset < 1 || offset > len(z.hist) {
+			return errZstdCorrupt
+		}
+		from, to := len(z.hist)-offset, len(z.hist)
+		z.hist = grow(z.hist, matchLen)
+		// The match may overlap the bytes it produces.
+		for j := 0; j < matchLen; j++ {
+			z.hist[to+j] = z.hist[from+j]
+		}
+	}
+	if br.pos != 0 || len(z.hist)-start+len(lits) > max {
+		return errZstdCorrupt
+	}
+	z.hist = append(z.hist, lits...)
+	return nil
+}
+
+// backBits reads a bit stream backwards, from the last written bit to
+// the first, as the entropy coders of zstd store their output. Reading
+// past the start yields zero bits and makes pos negative.
+type backBits struct {
+	data []byte
+	pos  int // the number of bits left
+}
+
+func (b *backBits) init(data []byte) os.Error {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return errZstdCorrupt
+	}
+	// The highest set bit of the last byte marks the end of the stream.
+	b.data = data
+	b.pos = (len(data)-1)*8 + int(highBit(uint32(data[len(data)-1])))
+	return nil
+}
+
+// peek returns the next n bits, at most 56, without consuming them.
+func (b *backBits) peek(n uint) uint64 {
+	lo := b.pos - int(n)
+	if lo < 0 {
+		if b.pos <= 0 {
+			return 0
+		}
+		return b.peek(uint(b.pos)) << uint(-lo)
+	}
+	var v uint64
+	for i := (b.pos - 1) >> 3; i >= lo>>3; i-- {
+		v = v<<8 | uint64(b.data[i])
+	}
+	return v >> uint(lo&7) & (1<<n - 1)
+}
+
+func (b *backBits) read(n uint) uint64 {
+	v := b.peek(n)
+	b.pos -= int(n)
+	return v
+}
+
+// An fseTable decodes the symbols of a finite state entropy stream.
+type fseTable struct {
+	log uint
+	e   []fseEntry
+}
+
+type fseEntry struct {
+	sym  byte
+	bits byte   // the number of bits read for the next state
+	base uint16 // the next state before adding the bits
+}
+
+func (t *fseTable) next(state uint64, b *backBits) uint64 {
+	e := &t.e[state]
+	return uint64(e.base) + b.read(uint(e.bits))
+}
+
+// readFSENorm reads the normalized distribution of the symbols which
+// starts a finite state entropy stream. A count of -1 denotes a symbol
+// with a probability below one.
+func readFSENorm(src []byte, maxSymbol int, maxLog uint) (norm []int16, log uint, used int, err os.Error) {
+	bit := 0
+	peek := func(n uint) (v int) {
+		for i := uint(0); i < n; i++ {
+			p := bit + int(i)
+			if p>>3 < len(src) && src[p>>3]>>uint(p&7)&1 != 0 {
+				v |= 1 << i
+			}
+		}
+		return
+	}
+	log = uint(peek(4)) + 5
+	bit += 4
+	if log > maxLog {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	remaining := 1<<log + 1
+	threshold := 1 << log
+	bits := log + 1
+	prev0 := false
+	for remaining > 1 && len(norm) <= maxSymbol {
+		if prev0 {
+			// Runs of zero counts are coded as repeat flags.
+			n := len(norm)
+			for peek(2) == 3 && bit <= len(src)*8 {
+				n += 3
+				bit += 2
+			}
+			n += peek(2)
+			bit += 2
+			if n > maxSymbol || bit > len(src)*8 {
+				return nil, 0, 0, errZstdCorrupt
+			}
+			for len(norm) < n {
+				norm = append(norm, 0)
+			}
+		}
+		max := 2*threshold - 1 - remaining
+		count := peek(bits)
+		if count&(threshold-1) < max {
+			count &= threshold - 1
+			bit += int(bits) - 1
+		} else {
+			if count &= 2*threshold - 1; count >= threshold {
+				count -= max
+			}
+			bit += int(bits)
+		}
+		count--
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		if remaining < 1 {
+			return nil, 0, 0, errZstdCorrupt
+		}
+		norm = append(norm, int16(count))
+		prev0 = count == 0
+		for remaining < threshold {
+			bits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 || bit > len(src)*8 {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	return norm, log, (bit + 7) / 8, nil
+}
+
+// fseSpread returns the symbol of every state of the table with the
+// normalized distribution.
+func fseSpread(norm []int16, log uint) (syms []byte, err os.Error) {
+	size := 1 << log
+	syms = make([]byte, size)
+	high := size - 1
+	for s, c := range norm {
+		if c == -1 {
+			if high < 0 {
+				return nil, errZstdCorrupt
+			}
+			syms[high] = byte(s)
+			high--
+		}
+	}
+	pos, step, mask := 0, size>>1+size>>3+3, size-1
+	for s, c := range norm {
+		for i := 0; i < int(c); i++ {
+			syms[pos] = byte(s)
+			for pos = (pos + step) & mask; pos > high; pos = (pos + step) & mask {
+			}
+		}
+	}
+	if pos != 0 {
+		return nil, errZstdCorrupt
+	}
+	return
+}
+
+func newFSETable(norm []int16, log uint) (t *fseTable, err os.Error) {
+	syms, err := fseSpread(norm, log)
+	if err != nil {
+		return
+	}
+	size := len(syms)
+	next := make([]int, len(norm))
+	for s, c := range norm {
+		if next[s] = int(c); c == -1 {
+			next[s] = 1
+		}
+	}
+	t = &fseTable{log: log, e: make([]fseEntry, size)}
+	for u, s := range syms {
+		state := next[s]
+		next[s]++
+		bits := log - highBit(uint32(state))
+		t.e[u] = fseEntry{sym: s, bits: byte(bits), base: uint16(state<<bits - size)}
+	}
+	return
+}
+
+func mustFSETable(norm []int16, log uint) *fseTable {
+	t, err := newFSETable(norm, log)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// A huffTable decodes the Huffman coded literals.
+type huffTable struct {
+	bits uint // the length of the longest code
+	e    []huffEntry
+}
+
+type huffEntry struct {
+	sym, bits byte
+}
+
+// readHuffTable reads the description of a Huffman table: the weights of
+// the symbols, stored directly or compressed as a finite state entropy
+// stream. The weight of the last symbol is implied.
+func readHuffTable(src []byte) (t *huffTable, used int, err os.Error) {
+	if len(src) < 1 {
+		return nil, 0, errZstdCorrupt
+	}
+	var weights [256]byte
+	n := 0
+	if h := int(src[0]); h >= 128 {
+		n = h - 127
+		used = 1 + (n+1)/2
+		if len(src) < used {
+			return nil, 0, errZstdCorrupt
+		}
+		for i := 0; i < n; i++ {
+			weights[i] = src[1+i/2] >> uint(4*(1-i%2)) & 15
+		}
+	} else {
+		used = 1 + h
+		if len(src) < used {
+			return nil, 0, errZstdCorrupt
+		}
+		if n, err = readHuffWeights(src[1:used], weights[:]); err != nil {
+			return
+		}
+	}
+	total := uint32(0)
+	for _, w := range weights[:n] {
+		if w > 11 {
+			return nil, 0, errZstdCorrupt
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+	if total == 0 {
+		return nil, 0, errZstdCorrupt
+	}
+	maxBits := highBit(total) + 1
+	rest := uint32(1)<<maxBits - total
+	if maxBits > 11 || rest&(rest-1) != 0 || n >= 256 {
+		return nil, 0, errZstdCorrupt
+	}
+	weights[n] = byte(highBit(rest) + 1)
+	n++
+
+	// The codes are assigned from the lowest weight, and by the symbol
+	// within a weight.
+	var start [13]int
+	for _, w := range weights[:n] {
+		if w > 0 {
+			start[w] += 1 << (w - 1)
+		}
+	}
+	pos := 0
+	for w := 1; w <= int(maxBits); w++ {
+		pos, start[w] = pos+start[w], pos
+	}
+	t = &huffTable{bits: maxBits, e: make([]huffEntry, 1<<maxBits)}
+	for s, w := range weights[:n] {
+		if w == 0 {
+			continue
+		}
+		e := huffEntry{sym: byte(s), bits: byte(maxBits + 1 - uint(w))}
+		for i := 0; i < 1<<(w-1); i++ {
+			t.e[start[w]+i] = e
+		}
+		start[w] += 1 << (w - 1)
+	}
+	return
+}
+
+// readHuffWeights decodes the weights compressed with two interleaved
+// finite state entropy states.
+func readHuffWeights(src []byte, weights []byte) (n int, err os.Error) {
+	norm, log, used, err := readFSENorm(src, 255, 6)
+	if err != nil {
+		return
+	}
+	t, err := newFSETable(norm, log)
+	if err != nil {
+		return
+	}
+	var br backBits
+	if err = br.init(src[used:]); err != nil {
+		return
+	}
+	states := [2]uint64{br.read(log), br.read(log)}
+	for i := 0; ; i ^= 1 {
+		if n > 253 {
+			return 0, errZstdCorrupt
+		}
+		weights[n] = t.e[states[i]].sym
+		n++
+		states[i] = t.next(states[i], &br)
+		if br.pos < 0 {
+			weights[n] = t.e[states[i^1]].sym
+			n++
+			return
+		}
+	}
+	panic("unreachable")
+}
+
+// decode fills dst with the symbols of a single Huffman stream.
+func (t *huffTable) decode(dst, src []byte) os.Error {
+	var br backBits
+	if err := br.init(src); err != nil {
+		return err
+	}
+	for i := range dst {
+		e := t.e[br.peek(t.bits)]
+		dst[i] = e.sym
+		br.pos -= int(e.bits)
+	}
+	if br.pos != 0 {
+		return errZstdCorrupt
+	}
+	return nil
+}
+
+// xxh64 computes the XXH64 hash with seed 0, which zstd uses as the
+// content checksum.
+type xxh64 struct {
+	v     [4]uint64
+	mem   [32]byte
+	n     int // the bytes in mem
+	total uint64
+}
+
+const (
+	xxhPrime1 uint64 = 11400714785074694791
+	xxhPrime2 uint64 = 14029467366897019727
+	xxhPrime3 uint64 = 1609587929392839161
+	xxhPrime4 uint64 = 9650029242287828579
+	xxhPrime5 uint64 = 2870177450012600261
+)
+
+func (h *xxh64) Reset() {
+	p1 := xxhPrime1
+	h.v = [4]uint64{p1 + xxhPrime2, xxhPrime2, 0, -p1}
+	h.n, h.total = 0, 0
+}
+
+func rotl64(x uint64, r uint) uint64 {
+	return x<<r | x>>(64-r)
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	return rotl64(acc+input*xxhPrime2, 31) * xxhPrime1
+}
+
+func le64(b []byte) uint64 {
+	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+}
+
+func (h *xxh64) Write(p []byte) {
+	h.total += uint64(len(p))
+	if h.n > 0 {
+		k := copy(h.mem[h.n:], p)
+		if h.n += k; h.n < 32 {
+			return
+		}
+		p = p[k:]
+		h.stripe(h.mem[:])
+		h.n = 0
+	}
+	for ; len(p) >= 32; p = p[32:] {
+		h.stripe(p)
+	}
+	h.n = copy(h.mem[:], p)
+}
+
+func (h *xxh64) stripe(p []byte) {
+	for i := range h.v {
+		h.v[i] = xxhRound(h.v[i], le64(p[8*i:]))
+	}
+}
+
+func (h *xxh64) Sum64() uint64 {
+	var s uint64
+	if h.total >= 32 {
+		s = rotl64(h.v[0], 1) + rotl64(h.v[1], 7) + rotl64(h.v[2], 12) + rotl64(h.v[3], 18)
+		for _, v := range h.v {
+			s = (s^xxhRound(0, v))*xxhPrime1 + xxhPrime4
+		}
+	} else {
+		s = xxhPrime5
+	}
+	s += h.total
+	p := h.mem[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		s ^= xxhRound(0, le64(p))
+		s = rotl64(s, 27)*xxhPrime1 + xxhPrime4
+	}
+	if len(p) >= 4 {
+		s ^= uint64(uint32(p[0])|uint32(p[1])<<8|uint32(p[2])<<16|uint32(p[3])<<24) * xxhPrime1
+		s = rotl64(s, 23)*xxhPrime2 + xxhPrime3
+		p = p[4:]
+	}
+	for _, c := range p {
+		s ^= uint64(c) * xxhPrime5
+		s = rotl64(s, 11) * xxhPrime1
+	}
+	s ^= s >> 33
+	s *= xxhPrime2
+	s ^= s >> 29
+	s *= xxhPrime3
+	s ^= s >> 32
+	return s
+}
@@ -0,0 +1,755 @@
+// Copyright 2011 Ivan Krasin. All rights reserved.
+// Use of this source code is governed by
+// MIT license that can be found in the LICENSE file.
+package schematic
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// zstdWriter writes a single zstd frame with a content checksum. The
+// blocks are compressed by matching against the last megabyte of input;
+// the literals are Huffman coded and the sequences use the predefined or
+// fitted finite state entropy tables. Blocks which do not shrink are
+// stored raw.
+type zstdWriter struct {
+	w      io.Writer
+	err    os.Error
+	depth  int // the number of candidates tried for each match
+	header bool
+	hash   xxh64
+
+	hist  []byte   // the window and the input not compressed yet
+	done  int      // the compressed part of hist
+	base  int      // the position of hist[0] in the input
+	head  []int    // the last position plus one with the hash
+	chain []uint32 // the distance to the previous position with the hash
+	rep   [3]int
+}
+
+const (
+	zstdWindowLog = 20
+	zstdHashLog   = 16
+	zstdMinMatch  = 4
+)
+
+// newZstdWriter returns a writer compressing at the level from 1 to 9,
+// or the default level if level is below 1.
+func newZstdWriter(w io.Writer, level int) *zstdWriter {
+	if level < 1 {
+		level = 6
+	}
+	z := &zstdWriter{w: w, depth: 1 << uint(level-1), head: make([]int, 1<<zstdHashLog)}
+	z.rep = [3]int{1, 4, 8}
+	z.hash.Reset()
+	return z
+}
+
+func (z *zstdWriter) Write(p []byte) (n int, err os.Error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	z.hash.Write(p)
+	z.hist = append(z.hist, p...)
+	// The last block is written by Close.
+	for len(z.hist)-z.done > zstdBlockMax && z.err == nil {
+		z.writeBlock(zstdBlockMax, false)
+	}
+	return len(p), z.err
+}
+
+// Close writes the last block and the checksum. It does not close the
+// underlying writer.
+func (z *zstdWriter) Close() os.Error {
+	if z.err != nil {
+		return z.err
+	}
+	z.writeBlock(len(z.hist)-z.done, true)
+	sum := uint32(z.hash.Sum64())
+	z.write([]byte{byte(sum), byte(sum >> 8), byte(sum >> 16), byte(sum >> 24)})
+	if z.err == nil {
+		z.err = os.NewError("Write to a closed zstd writer")
+		return nil
+	}
+	return z.err
+}
+
+func (z *zstdWriter) write(p []byte) {
+	if z.err == nil {
+		_, z.err = z.w.Write(p)
+	}
+}
+
+// writeBlock compresses the next n bytes of hist.
+func (z *zstdWriter) writeBlock(n int, last bool) {
+	if !z.header {
+		// No content size, a checksum and the window descriptor.
+		z.write([]byte{0x28, 0xb5, 0x2f, 0xfd, 4, (zstdWindowLog - 10) << 3})
+		z.header = true
+	}
+	z.trim()
+	end := z.done + n
+	src := z.hist[z.done:end]
+	rep := z.rep
+	body := z.compress(z.done, end, &rep)
+	typ := 2
+	switch {
+	case len(src) > 0 && isRun(src):
+		typ, body = 1, src[:1]
+	case len(body) >= len(src):
+		typ, body = 0, src
+	default:
+		z.rep = rep
+	}
+	size := len(body)
+	if typ == 1 {
+		size = len(src)
+	}
+	h := size<<3 | typ<<1
+	if last {
+		h |= 1
+	}
+	z.write([]byte{byte(h), byte(h >> 8), byte(h >> 16)})
+	z.write(body)
+	z.done = end
+}
+
+// isRun reports whether all bytes of p are equal.
+func isRun(p []byte) bool {
+	for _, c := range p {
+		if c != p[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// trim drops the input which is too old to be matched.
+func (z *zstdWriter) trim() {
+	const window = 1 << zstdWindowLog
+	if n := z.done - window; n > window {
+		z.hist = z.hist[:copy(z.hist, z.hist[n:])]
+		z.chain = z.chain[:copy(z.chain, z.chain[n:])]
+		z.done -= n
+		z.base += n
+	}
+}
+
+func (z *zstdWriter) hashAt(i int) int {
+	h := z.hist[i:]
+	v := uint32(h[0]) | uint32(h[1])<<8 | uint32(h[2])<<16 | uint32(h[3])<<24
+	return int(v * 2654435761 >> (32 - zstdHashLog))
+}
+
+// insert adds the position i of hist to the hash chains and returns the
+// previous position with the same hash, or -1.
+func (z *zstdWriter) insert(i int) int {
+	for len(z.chain) <= i {
+		z.chain = append(z.chain, 0)
+	}
+	h := z.hashAt(i)
+	prev := z.head[h] - 1 - z.base
+	z.head[h] = z.base + i + 1
+	z.chain[i] = 0
+	if prev >= 0 && i-prev <= 1<<zstdWindowLog {
+		z.chain[i] = uint32(i - prev)
+		return prev
+	}
+	return -1
+}
+
+func (z *zstdWriter) matchLen(a, b, end int) int {
+	n := 0
+	for b+n < end && z.hist[a+n] == z.hist[b+n] {
+		n++
+	}
+	return n
+}
+
+// A zstdSeq copies lit literals and then match bytes from the offset
+// value: the repeated offset 1 or the distance plus 3.
+type zstdSeq struct {
+	lit, match, offset int
+}
+
+// compress returns the compressed block of hist[start:end] and updates
+// the repeated offsets in rep.
+func (z *zstdWriter) compress(start, end int, rep *[3]int) []byte {
+	var seqs []zstdSeq
+	var lits []byte
+	anchor := start
+	for i := start; i+zstdMinMatch <= end; {
+		best, dist := 0, 0
+		if r := rep[0]; r <= i && i > anchor {
+			best, dist = z.matchLen(i-r, i, end), r
+		}
+		cand := z.insert(i)
+		for d := z.depth; d > 0 && cand >= 0 && best < end-i; d-- {
+			if n := z.matchLen(cand, i, end); n > best {
+				best, dist = n, i-cand
+			}
+			step := int(z.chain[cand])
+			if step == 0 || i-(cand-step) > 1<<zstdWindowLog {
+				break
+			}
+			cand -= step
+		}
+		if best < zstdMinMatch {
+			i++
+			continue
+		}
+		s := zstdSeq{lit: i - anchor, match: best}
+		if dist == rep[0] && s.lit > 0 {
+			s.offset = 1
+		} else {
+			s.offset = dist + 3
+			rep[2], rep[1], rep[0] = rep[1], rep[0], dist
+		}
+		seqs = append(seqs, s)
+		lits = append(lits, z.hist[anchor:i]...)
+		for j := i + 1; j < i+best && j+zstdMinMatch <= end; j++ {
+			z.insert(j)
+		}
+		i += best
+		anchor = i
+	}
+	lits = append(lits, z.hist[anchor:end]...)
+
+	out := appendLiterals(nil, lits)
+	return appendSequences(out, seqs)
+}
+
+// appendLiterals appends the literals section, Huffman coded if that is
+// shorter.
+func appendLiterals(out, lits []byte) []byte {
+	if len(lits) > 64 {
+		if h := huffCompress(lits); h != nil && len(h) < len(lits) {
+			return append(out, h...)
+		}
+	}
+	n := len(lits)
+	switch {
+	case n < 32:
+		out = append(out, byte(n<<3))
+	case n < 1<<12:
+		out = append(out, byte(1<<2|n<<4), byte(n>>4))
+	default:
+		out = append(out, byte(3<<2|n<<4), byte(n>>4), byte(n>>12))
+	}
+	return append(out, lits...)
+}
+
+// appendSequences appends the sequences section.
+func appendSequences(out []byte, seqs []zstdSeq) []byte {
+	n := len(seqs)
+	switch {
+	case n < 128:
+		out = append(out, byte(n))
+	case n < 0x7f00:
+		out = append(out, byte(n>>8+128), byte(n))
+	default:
+		out = append(out, 255, byte(n-0x7f00), byte((n-0x7f00)>>8))
+	}
+	if n == 0 {
+		return out
+	}
+	var codes [3][]byte
+	var extra [3][]uint32
+	for k := range codes {
+		codes[k] = make([]byte, n)
+		extra[k] = make([]uint32, n)
+	}
+	for i, s := range seqs {
+		c := zstdCode(zstdLLBase, s.lit)
+		codes[zstdLL][i], extra[zstdLL][i] = byte(c), uint32(s.lit-zstdLLBase[c])
+		c = zstdCode(zstdMLBase, s.match)
+		codes[zstdML][i], extra[zstdML][i] = byte(c), uint32(s.match-zstdMLBase[c])
+		c = int(highBit(uint32(s.offset)))
+		codes[zstdOF][i], extra[zstdOF][i] = byte(c), uint32(s.offset-1<<uint(c))
+	}
+	modes := len(out)
+	out = append(out, 0)
+	var enc [3]*fseEncoder
+	for k := range enc {
+		var mode byte
+		enc[k], mode, out = chooseFSE(out, codes[k], k)
+		out[modes] |= mode << uint(6-2*k)
+	}
+
+	var bw bitWriter
+	bw.buf = out
+	bits := func(i int) {
+		bw.add(extra[zstdLL][i], zstdLLBits[codes[zstdLL][i]])
+		bw.add(extra[zstdML][i], zstdMLBits[codes[zstdML][i]])
+		bw.add(extra[zstdOF][i], uint(codes[zstdOF][i]))
+	}
+	enc[zstdML].init(codes[zstdML][n-1])
+	enc[zstdOF].init(codes[zstdOF][n-1])
+	enc[zstdLL].init(codes[zstdLL][n-1])
+	bits(n - 1)
+	for i := n - 2; i >= 0; i-- {
+		enc[zstdOF].encode(&bw, codes[zstdOF][i])
+		enc[zstdML].encode(&bw, codes[zstdML][i])
+		enc[zstdLL].encode(&bw, codes[zstdLL][i])
+		bits(i)
+	}
+	enc[zstdML].flush(&bw)
+	enc[zstdOF].flush(&bw)
+	enc[zstdLL].flush(&bw)
+	return bw.close()
+}
+
+// zstdCode returns the code of the length with the bases.
+func zstdCode(base []int, v int) int {
+	c := len(base) - 1
+	for base[c] > v {
+		c--
+	}
+	return c
+}
+
+// chooseFSE picks the coding of the symbols, appends its description to
+// out and returns the encoder and the mode of the sequences section.
+func chooseFSE(out, codes []byte, kind int) (e *fseEncoder, mode byte, res []byte) {
+	counts := make([]int, zstdMaxSymbol[kind]+1)
+	distinct := 0
+	for _, c := range codes {
+		if counts[c] == 0 {
+			distinct++
+		}
+		counts[c]++
+	}
+	if distinct == 1 {
+		return &fseEncoder{rle: true}, 1, append(out, codes[0])
+	}
+	if len(codes) < 64 {
+		// A table of its own does not pay off.
+		return zstdDefaultEncoder[kind], 0, out
+	}
+	for counts[len(counts)-1] == 0 {
+		counts = counts[:len(counts)-1]
+	}
+	log := highBit(uint32(len(codes))) + 1
+	if log > zstdMaxLog[kind] {
+		log = zstdMaxLog[kind]
+	}
+	if log < 5 {
+		log = 5
+	}
+	for 1<<log < 2*distinct && log < zstdMaxLog[kind] {
+		log++
+	}
+	norm := normalizeCounts(counts, len(codes), log)
+	var bw bitWriter
+	bw.buf = out
+	writeFSENorm(&bw, norm, log)
+	return newFSEEncoder(norm, log), 2, bw.flush()
+}
+
+var zstdDefaultEncoder = [3]*fseEncoder{
+	newFSEEncoder(zstdLLDefault, 6),
+	newFSEEncoder(zstdOFDefault, 5),
+	newFSEEncoder(zstdMLDefault, 6),
+}
+
+// normalizeCounts scales the counts of the symbols to sum to 1<<log,
+// keeping every present symbol.
+func normalizeCounts(counts []int, total int, log uint) []int16 {
+	size := 1 << log
+	norm := make([]int16, len(counts))
+	sum, largest := 0, 0
+	for s, c := range counts {
+		if c == 0 {
+			continue
+		}
+		n := c * size / total
+		if n == 0 {
+			n = 1
+		}
+		norm[s] = int16(n)
+		sum += n
+		if norm[s] > norm[largest] {
+			largest = s
+		}
+	}
+	for sum > size {
+		// Take the excess from the largest counts.
+		for s := range norm {
+			if norm[s] > norm[largest] {
+				largest = s
+			}
+		}
+		d := imin(int(norm[largest])-1, sum-size)
+		norm[largest] -= int16(d)
+		sum -= d
+	}
+	norm[largest] += int16(size - sum)
+	return norm
+}
+
+// writeFSENorm writes the normalized distribution as read by readFSENorm.
+func writeFSENorm(bw *bitWriter, norm []int16, log uint) {
+	bw.add(uint32(log-5), 4)
+	remaining := 1<<log + 1
+	threshold := 1 << log
+	bits := log + 1
+	prev0 := false
+	for s := 0; s < len(norm) && remaining > 1; {
+		if prev0 {
+			start := s
+			for norm[s] == 0 {
+				s++
+			}
+			for ; s >= start+3; start += 3 {
+				bw.add(3, 2)
+			}
+			bw.add(uint32(s-start), 2)
+		}
+		count := int(norm[s])
+		s++
+		max := 2*threshold - 1 - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		if count++; count >= threshold {
+			count += max
+		}
+		if count < max {
+			bw.add(uint32(count), bits-1)
+		} else {
+			bw.add(uint32(count), bits)
+		}
+		prev0 = count == 1
+		for remaining < threshold {
+			bits--
+			threshold >>= 1
+		}
+	}
+}
+
+// An fseEncoder codes symbols with a finite state entropy table, or
+// nothing for the single symbol of the RLE mode.
+type fseEncoder struct {
+	rle    bool
+	log    uint
+	states []uint16
+	sym    []fseTransform
+	state  uint32
+}
+
+type fseTransform struct {
+	deltaBits uint32
+	deltaFind int32
+}
+
+func newFSEEncoder(norm []int16, log uint) *fseEncoder {
+	syms, err := fseSpread(norm, log)
+	if err != nil {
+		panic(err)
+	}
+	size := 1 << log
+	e := &fseEncoder{log: log, states: make([]uint16, size), sym: make([]fseTransform, len(norm))}
+	cumul := make([]int, len(norm)+1)
+	for s, c := range norm {
+		if c == -1 {
+			c = 1
+		}
+		cumul[s+1] = cumul[s] + int(c)
+	}
+	for u, s := range syms {
+		e.states[cumul[s]] = uint16(size + u)
+		cumul[s]++
+	}
+	total := 0
+	for s, c := range norm {
+		switch c {
+		case 0:
+			e.sym[s].deltaBits = uint32((log+1)<<16 - uint(size))
+		case -1, 1:
+			e.sym[s] = fseTransform{uint32(log<<16 - uint(size)), int32(total - 1)}
+			total++
+		default:
+			maxBits := log - highBit(uint32(c-1))
+			e.sym[s] = fseTransform{uint32(maxBits<<16) - uint32(int(c)<<maxBits), int32(total - int(c))}
+			total += int(c)
+		}
+	}
+	return e
+}
+
+// init sets the state to one which decodes to the symbol.
+func (e *fseEncoder) init(s byte) {
+	if e.rle {
+		return
+	}
+	t := e.sym[s]
+	bits := (t.deltaBits + 1<<15) >> 16
+	v := bits<<16 - t.deltaBits
+	e.state = uint32(e.states[int(v>>bits)+int(t.deltaFind)])
+}
+
+func (e *fseEncoder) encode(bw *bitWriter, s byte) {
+	if e.rle {
+		return
+	}
+	t := e.sym[s]
+	bits := (e.state + t.deltaBits) >> 16
+	bw.add(e.state, uint(bits))
+	e.state = uint32(e.states[int(e.state>>bits)+int(t.deltaFind)])
+}
+
+func (e *fseEncoder) flush(bw *bitWriter) {
+	if !e.rle {
+		bw.add(e.state, e.log)
+	}
+}
+
+// bitWriter appends bits to buf from the lowest bit of every byte, as
+// read by backBits and readFSENorm.
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+// add appends the low n bits of v, at most 32.
+func (b *bitWriter) add(v uint32, n uint) {
+	b.acc |= uint64(v) & (1<<n - 1) << b.n
+	for b.n += n; b.n >= 8; b.n -= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+	}
+}
+
+// flush pads the last byte with zero bits and returns the buffer.
+func (b *bitWriter) flush() []byte {
+	if b.n > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.n = 0, 0
+	}
+	return b.buf
+}
+
+// close marks the end of a stream read by backBits and returns the buffer.
+func (b *bitWriter) close() []byte {
+	b.add(1, 1)
+	return b.flush()
+}
+
+// huffCompress returns the literals section of the Huffman coded lits, or
+// nil if they cannot be Huffman coded.
+func huffCompress(lits []byte) []byte {
+	counts := make([]int, 256)
+	for _, c := range lits {
+		counts[c]++
+	}
+	last, distinct := 0, 0
+	for s, c := range counts {
+		if c > 0 {
+			last = s
+			distinct++
+		}
+	}
+	if distinct < 2 {
+		return nil
+	}
+	lens := huffLengths(counts[:last+1], 11)
+	maxBits := 0
+	for _, l := range lens {
+		maxBits = imax(maxBits, l)
+	}
+	weights := make([]byte, last+1)
+	for s, l := range lens {
+		if l > 0 {
+			weights[s] = byte(maxBits + 1 - l)
+		}
+	}
+	desc := huffDescription(weights[:last])
+	if desc == nil {
+		return nil
+	}
+
+	// The codes are the table positions which readHuffTable assigns.
+	var start [13]int
+	for _, w := range weights {
+		if w > 0 {
+			start[w] += 1 << (w - 1)
+		}
+	}
+	pos := 0
+	for w := 1; w <= maxBits; w++ {
+		pos, start[w] = pos+start[w], pos
+	}
+	codes := make([]uint32, last+1)
+	for s, w := range weights {
+		if w > 0 {
+			codes[s] = uint32(start[w] >> (w - 1))
+			start[w] += 1 << (w - 1)
+		}
+	}
+	stream := func(out, p []byte) []byte {
+		bw := bitWriter{buf: out}
+		for i := len(p) - 1; i >= 0; i-- {
+			bw.add(codes[p[i]], uint(lens[p[i]]))
+		}
+		return bw.close()
+	}
+
+	n := len(lits)
+	body := desc
+	format, hlen := 0, 3
+	if n < 1<<10 {
+		body = stream(body, lits)
+	} else {
+		// Four streams, with the sizes of the first three in a jump table.
+		format, hlen = 2, 4
+		if n >= 1<<14 {
+			format, hlen = 3, 5
+		}
+		seg := (n + 3) / 4
+		jump := len(body)
+		body = append(body, 0, 0, 0, 0, 0, 0)
+		for i := 0; i < 4; i++ {
+			from := len(body)
+			body = stream(body, lits[i*seg:imin((i+1)*seg, n)])
+			if i < 3 {
+				size := len(body) - from
+				body[jump+2*i], body[jump+2*i+1] = byte(size), byte(size>>8)
+			}
+		}
+	}
+	bits := uint(hlen*8-4) / 2
+	if len(body) >= 1<<bits {
+		return nil
+	}
+	v := uint64(2) | uint64(format)<<2 | uint64(n)<<4 | uint64(len(body))<<(4+bits)
+	out := make([]byte, hlen, hlen+len(body))
+	for i := range out {
+		out[i] = byte(v >> uint(8*i))
+	}
+	return append(out, body...)
+}
+
+// huffLengths returns the lengths of the Huffman codes of the symbols
+// with the counts, at most limit bits long.
+func huffLengths(counts []int, limit int) []int {
+	counts = append([]int(nil), counts...)
+	for {
+		lens := huffTree(counts)
+		max := 0
+		for _, l := range lens {
+			max = imax(max, l)
+		}
+		if max <= limit {
+			return lens
+		}
+		// Flatten the distribution until the codes are short enough.
+		for s, c := range counts {
+			counts[s] = (c + 1) / 2
+		}
+	}
+	panic("unreachable")
+}
+
+// huffTree builds a Huffman tree and returns the depths of the symbols.
+func huffTree(counts []int) []int {
+	var keys []int
+	for s, c := range counts {
+		if c > 0 {
+			keys = append(keys, c<<8|s)
+		}
+	}
+	sort.Ints(keys)
+	n := len(keys)
+	weight := make([]int, 2*n-1)
+	parent := make([]int, 2*n-1)
+	for i, k := range keys {
+		weight[i] = k >> 8
+	}
+	// The leaves and the inner nodes are both taken in increasing weight.
+	leaf, inner := 0, n
+	least := func(end int) (i int) {
+		if leaf < n && (inner == end || weight[leaf] <= weight[inner]) {
+			leaf++
+			return leaf - 1
+		}
+		inner++
+		return inner - 1
+	}
+	for k := n; k < 2*n-1; k++ {
+		a, b := least(k), least(k)
+		weight[k] = weight[a] + weight[b]
+		parent[a], parent[b] = k, k
+	}
+	depth := make([]int, 2*n-1)
+	lens := make([]int, len(counts))
+	for k := 2*n - 3; k >= 0; k-- {
+		depth[k] = depth[parent[k]] + 1
+		if k < n {
+			lens[keys[k]&255] = depth[k]
+		}
+	}
+	return lens
+}
+
+// huffDescription returns the weights of all symbols but the last, as
+// read by readHuffTable: directly for up to 128 symbols, or compressed
+// with two interleaved finite state entropy states.
+func huffDescription(weights []byte) []byte {
+	k := len(weights)
+	if k <= 128 {
+		out := make([]byte, 1+(k+1)/2)
+		out[0] = byte(127 + k)
+		for i, w := range weights {
+			out[1+i/2] |= w << uint(4*(1-i%2))
+		}
+		return out
+	}
+	counts := make([]int, 12)
+	for _, w := range weights {
+		counts[w]++
+	}
+	for counts[len(counts)-1] == 0 {
+		counts = counts[:len(counts)-1]
+	}
+	const log = 6
+	norm := normalizeCounts(counts, k, log)
+	bw := bitWriter{buf: []byte{0}}
+	writeFSENorm(&bw, norm, log)
+	bw.flush()
+	s1 := newFSEEncoder(norm, log)
+	s2 := *s1
+	i := k
+	if k&1 != 0 {
+		s1.init(weights[k-1])
+		s2.init(weights[k-2])
+		s1.encode(&bw, weights[k-3])
+		i = k - 3
+	} else {
+		s2.init(weights[k-1])
+		s1.init(weights[k-2])
+		i = k - 2
+	}
+	for ; i > 0; i -= 2 {
+		s2.encode(&bw, weights[i-1])
+		s1.encode(&bw, weights[i-2])
+	}
+	s2.flush(&bw)
+	s1.flush(&bw)
+	out := bw.close()
+	if len(out) > 128 {
+		return nil
+	}
+	out[0] = byte(len(out) - 1)
+	// The decoder stops when the bits run out, which may be a symbol too
+	// early or late for some distributions.
+	var got [256]byte
+	if n, err := readHuffWeights(out[1:], got[:]); err != nil || n != k || string(got[:k]) != string(weights) {
+		return nil
+	}
+	return out
+}